@@ -43,7 +43,7 @@ const archive = "clt.zip"
 const moniker = "resharper-clt"
 
 func (l CdnetLinter) RunAnalysis(c thirdpartyscan.Context) error {
-	utils.Bootstrap(c.QodanaYamlConfig().Bootstrap, c.ProjectDir())
+	utils.Bootstrap(c.QodanaYamlConfig().BootstrapSteps(), c.ProjectDir(), c.LogDir())
 	args, err := l.computeCdnetArgs(c)
 	if err != nil {
 		return err
@@ -83,7 +83,10 @@ func (l CdnetLinter) MountTools(path string) (map[string]string, error) {
 
 	if _, err := os.Stat(val["clt"]); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			archivePath := platform.ProcessAuxiliaryTool(archive, moniker, path, CltArchive)
+			archivePath, err := platform.ProcessAuxiliaryTool(archive, moniker, path, CltArchive)
+			if err != nil {
+				return nil, err
+			}
 			if err := platform.Decompress(archivePath, path); err != nil {
 				return nil, fmt.Errorf("failed to decompress %s archive: %w", moniker, err)
 			}
@@ -131,7 +131,7 @@ func processConfig(c thirdpartyscan.Context) (checks string, configFile string,
 	var includeRules []string
 
 	yaml := c.QodanaYamlConfig()
-	utils.Bootstrap(yaml.Bootstrap, c.ProjectDir())
+	utils.Bootstrap(yaml.BootstrapSteps(), c.ProjectDir(), c.LogDir())
 	if yaml.Version != "" || len(yaml.Includes) > 0 || len(yaml.Excludes) > 0 {
 		fmt.Println("Found qodana.yaml. Note that only bootstrap command and inspection names from include and exclude sections are supported.")
 		for _, include := range yaml.Includes {
@@ -114,7 +114,10 @@ func (l ClangLinter) MountTools(path string) (map[string]string, error) {
 		}
 
 		clangArchive := clang + extension
-		clangArchivePath := platform.ProcessAuxiliaryTool(clangArchive, clang, path, ClangTidyArchive)
+		clangArchivePath, err := platform.ProcessAuxiliaryTool(clangArchive, clang, path, ClangTidyArchive)
+		if err != nil {
+			return nil, err
+		}
 		if err := platform.Decompress(clangArchivePath, path); err != nil {
 			return nil, fmt.Errorf("failed to decompress clang archive: %w", err)
 		}
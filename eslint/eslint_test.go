@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEslintOutput(t *testing.T) {
+	output := `[
+  {
+    "filePath": "/project/src/index.ts",
+    "messages": [
+      {"ruleId": "@typescript-eslint/no-unused-vars", "severity": 2, "message": "'a' is defined but never used.", "line": 1, "column": 7},
+      {"ruleId": "eqeqeq", "severity": 1, "message": "Expected '===' and instead saw '=='.", "line": 3, "column": 9}
+    ],
+    "errorCount": 1,
+    "warningCount": 1
+  },
+  {
+    "filePath": "/project/src/broken.js",
+    "messages": [
+      {"ruleId": null, "fatal": true, "severity": 2, "message": "Parsing error: Unexpected token", "line": 2, "column": 1}
+    ]
+  },
+  {"filePath": "/project/src/clean.js", "messages": []}
+]`
+	problems, err := parseEslintOutput([]byte(output), "/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(
+		t, []platform.ThirdPartyProblem{
+			{
+				RuleId:   "@typescript-eslint/no-unused-vars",
+				Category: "@typescript-eslint",
+				Message:  "'a' is defined but never used.",
+				File:     filepath.Join("src", "index.ts"),
+				Line:     1,
+				Column:   7,
+				Level:    "error",
+			},
+			{
+				RuleId:   "eqeqeq",
+				Category: "ESLint",
+				Message:  "Expected '===' and instead saw '=='.",
+				File:     filepath.Join("src", "index.ts"),
+				Line:     3,
+				Column:   9,
+				Level:    "warning",
+			},
+			{
+				RuleId:   "parsing-error",
+				Category: "ESLint",
+				Message:  "Parsing error: Unexpected token",
+				File:     filepath.Join("src", "broken.js"),
+				Line:     2,
+				Column:   1,
+				Level:    "error",
+			},
+		}, problems,
+	)
+
+	_, err = parseEslintOutput([]byte("Oops! Something went wrong!"), "/project")
+	assert.Error(t, err)
+}
+
+func TestFindEslintConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	_, err := findEslintConfig(projectDir)
+	assert.Error(t, err)
+
+	packageJson := filepath.Join(projectDir, "package.json")
+	if err := os.WriteFile(packageJson, []byte(`{"eslintConfig": {"root": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := findEslintConfig(projectDir)
+	assert.NoError(t, err)
+	assert.Equal(t, packageJson, config)
+
+	flatConfig := filepath.Join(projectDir, "eslint.config.mjs")
+	if err := os.WriteFile(flatConfig, []byte("export default [];"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = findEslintConfig(projectDir)
+	assert.NoError(t, err)
+	assert.Equal(t, flatConfig, config)
+}
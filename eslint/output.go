@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+)
+
+const (
+	eslintCoreCategory  = "ESLint"
+	eslintParsingError  = "parsing-error"
+	eslintSeverityError = 2
+)
+
+// eslintFileResult is an element of the `eslint --format json` output.
+type eslintFileResult struct {
+	FilePath string `json:"filePath"`
+	Messages []struct {
+		RuleId   *string `json:"ruleId"`
+		Severity int     `json:"severity"`
+		Message  string  `json:"message"`
+		Line     int     `json:"line"`
+		Column   int     `json:"column"`
+	} `json:"messages"`
+}
+
+func parseEslintOutput(output []byte, projectDir string) ([]platform.ThirdPartyProblem, error) {
+	var results []eslintFileResult
+	if err := json.Unmarshal(output, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse ESLint output: %w", err)
+	}
+
+	problems := make([]platform.ThirdPartyProblem, 0)
+	for _, result := range results {
+		file := result.FilePath
+		if rel, err := filepath.Rel(projectDir, file); err == nil && filepath.IsAbs(file) {
+			file = rel
+		}
+		for _, m := range result.Messages {
+			ruleId := eslintParsingError
+			if m.RuleId != nil {
+				ruleId = *m.RuleId
+			}
+			level := "warning"
+			if m.Severity >= eslintSeverityError {
+				level = "error"
+			}
+			problems = append(
+				problems, platform.ThirdPartyProblem{
+					RuleId:   ruleId,
+					Category: eslintCategory(ruleId),
+					Message:  m.Message,
+					File:     file,
+					Line:     m.Line,
+					Column:   m.Column,
+					Level:    level,
+				},
+			)
+		}
+	}
+	return problems, nil
+}
+
+// eslintCategory returns the plugin of the rule (e.g. @typescript-eslint for @typescript-eslint/no-unused-vars),
+// core rules belong to the ESLint category.
+func eslintCategory(ruleId string) string {
+	if i := strings.LastIndex(ruleId, "/"); i > 0 {
+		return ruleId[:i]
+	}
+	return eslintCoreCategory
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	fexec "github.com/JetBrains/qodana-cli/internal/foundation/exec"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+type EslintLinter struct {
+}
+
+const (
+	eslint = "eslint"
+	// eslintFatalExitCode is returned by ESLint on configuration problems or an internal error, 1 means problems are found.
+	eslintFatalExitCode = 2
+)
+
+// eslintConfigs are the ESLint configuration files, both flat and legacy ones.
+var eslintConfigs = []string{
+	"eslint.config.js",
+	"eslint.config.mjs",
+	"eslint.config.cjs",
+	"eslint.config.ts",
+	"eslint.config.mts",
+	"eslint.config.cts",
+	".eslintrc.js",
+	".eslintrc.cjs",
+	".eslintrc.yaml",
+	".eslintrc.yml",
+	".eslintrc.json",
+	".eslintrc",
+}
+
+func (l EslintLinter) RunAnalysis(c thirdpartyscan.Context) error {
+	utils.Bootstrap(c.QodanaYamlConfig().BootstrapSteps(), c.ProjectDir(), c.LogDir())
+
+	config, err := findEslintConfig(c.ProjectDir())
+	if err != nil {
+		return err
+	}
+	log.Debugf("Using ESLint configuration %s", config)
+	binary, err := findEslintBinary(c.ProjectDir())
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--format", "json", "--no-error-on-unmatched-pattern", "."}
+	log.Debugf("Running %s %v", binary, args)
+	stdout, stderr, ret, err := fexec.ExecRedirectOutput(c.ProjectDir(), binary, args...)
+	if err != nil {
+		return err
+	}
+	if stderr != "" {
+		log.Debug(stderr)
+	}
+	if err = os.WriteFile(filepath.Join(c.LogDir(), "eslint.json"), []byte(stdout), 0o644); err != nil {
+		log.Warnf("Failed to save ESLint output: %s", err)
+	}
+	if ret >= eslintFatalExitCode {
+		return fmt.Errorf("eslint exited with code: %d\n%s", ret, stderr)
+	}
+
+	problems, err := parseEslintOutput([]byte(stdout), c.ProjectDir())
+	if err != nil {
+		return err
+	}
+	report := platform.ConvertToSarif(eslint, "", problems)
+
+	tmpResultsDir := platform.GetTmpResultsDir(c.ResultsDir())
+	if err = os.MkdirAll(tmpResultsDir, os.ModePerm); err != nil {
+		return err
+	}
+	if err = platform.WriteReport(filepath.Join(tmpResultsDir, "eslint.sarif.json"), report); err != nil {
+		return err
+	}
+	_, err = platform.MergeSarifReports(c, platform.GetDeviceIdSalt()[0])
+	return err
+}
+
+// MountTools does nothing: ESLint and its plugins are used from the project dependencies.
+func (l EslintLinter) MountTools(_ string) (map[string]string, error) {
+	return nil, nil
+}
+
+// findEslintConfig returns the ESLint configuration of the project, package.json with eslintConfig is also accepted.
+func findEslintConfig(projectDir string) (string, error) {
+	for _, config := range eslintConfigs {
+		path := filepath.Join(projectDir, config)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	packageJson := filepath.Join(projectDir, "package.json")
+	if data, err := os.ReadFile(packageJson); err == nil {
+		var pkg struct {
+			EslintConfig json.RawMessage `json:"eslintConfig"`
+		}
+		if json.Unmarshal(data, &pkg) == nil && len(pkg.EslintConfig) > 0 {
+			return packageJson, nil
+		}
+	}
+	return "", fmt.Errorf("no ESLint configuration found in %s", projectDir)
+}
+
+// findEslintBinary looks ESLint up in node_modules/.bin of the project first, then in PATH.
+func findEslintBinary(projectDir string) (string, error) {
+	localBinary := filepath.Join(projectDir, "node_modules", ".bin", eslint)
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		localBinary += ".cmd"
+	}
+	if _, err := os.Stat(localBinary); err == nil {
+		return localBinary, nil
+	}
+	binary, err := exec.LookPath(eslint)
+	if err != nil {
+		return "", errors.Join(
+			fmt.Errorf("eslint is not found in node_modules/.bin or PATH, install the project dependencies first"),
+			err,
+		)
+	}
+	return binary, nil
+}
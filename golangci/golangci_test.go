@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGolangciOutput(t *testing.T) {
+	output := `{"Issues":[` +
+		`{"FromLinter":"errcheck","Text":"Error return value of ` + "`f.Close`" + ` is not checked","Severity":"","Pos":{"Filename":"main.go","Offset":120,"Line":10,"Column":2}},` +
+		`{"FromLinter":"staticcheck","Text":"SA4006: this value of err is never used","Severity":"error","Pos":{"Filename":"/project/pkg/util.go","Offset":40,"Line":3,"Column":5}}` +
+		`],"Report":{"Linters":[{"Name":"errcheck","Enabled":true}]}}
+2 issues:
+* errcheck: 1
+* staticcheck: 1
+`
+	problems, err := parseGolangciOutput([]byte(output), "/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(
+		t, []platform.ThirdPartyProblem{
+			{
+				RuleId:   "errcheck",
+				Category: "errcheck",
+				Message:  "Error return value of `f.Close` is not checked",
+				File:     "main.go",
+				Line:     10,
+				Column:   2,
+				Level:    "warning",
+			},
+			{
+				RuleId:   "SA4006",
+				Category: "staticcheck",
+				Message:  "this value of err is never used",
+				File:     filepath.Join("pkg", "util.go"),
+				Line:     3,
+				Column:   5,
+				Level:    "error",
+			},
+		}, problems,
+	)
+
+	problems, err = parseGolangciOutput([]byte("\n"), "/project")
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+
+	_, err = parseGolangciOutput([]byte("level=error msg=\"Running error\""), "/project")
+	assert.Error(t, err)
+}
+
+func TestGolangciArgs(t *testing.T) {
+	assert.Contains(t, golangciArgs("1.64.8"), "--out-format=json")
+	assert.Contains(t, golangciArgs("2.1.6"), "--output.json.path=stdout")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	log "github.com/sirupsen/logrus"
+)
+
+// golangciOutput is the JSON output of golangci-lint, the same in v1 and v2.
+type golangciOutput struct {
+	Issues []struct {
+		FromLinter string `json:"FromLinter"`
+		Text       string `json:"Text"`
+		Severity   string `json:"Severity"`
+		Pos        struct {
+			Filename string `json:"Filename"`
+			Line     int    `json:"Line"`
+			Column   int    `json:"Column"`
+		} `json:"Pos"`
+	} `json:"Issues"`
+	Report *struct {
+		Error string `json:"Error"`
+	} `json:"Report"`
+}
+
+// golangciCheckPattern matches the check code some linters start the message with, e.g. SA4006: ... of staticcheck
+// or G104: ... of gosec.
+var golangciCheckPattern = regexp.MustCompile(`^([A-Z]+\d+): (.*)$`)
+
+func parseGolangciOutput(output []byte, projectDir string) ([]platform.ThirdPartyProblem, error) {
+	problems := make([]platform.ThirdPartyProblem, 0)
+	// v2 prints the text summary after the JSON line, and nothing is printed when there are no Go files
+	line, _, _ := bytes.Cut(bytes.TrimSpace(output), []byte("\n"))
+	if len(line) == 0 {
+		return problems, nil
+	}
+
+	var result golangciOutput
+	if err := json.Unmarshal(line, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse golangci-lint output: %w", err)
+	}
+	if result.Report != nil && result.Report.Error != "" {
+		log.Warnf("golangci-lint: %s", result.Report.Error)
+	}
+
+	for _, issue := range result.Issues {
+		ruleId, message := golangciRule(issue.FromLinter, issue.Text)
+		file := issue.Pos.Filename
+		if rel, err := filepath.Rel(projectDir, file); err == nil && filepath.IsAbs(file) {
+			file = rel
+		}
+		problems = append(
+			problems, platform.ThirdPartyProblem{
+				RuleId:   ruleId,
+				Category: issue.FromLinter,
+				Message:  message,
+				File:     file,
+				Line:     issue.Pos.Line,
+				Column:   issue.Pos.Column,
+				Level:    golangciLevel(issue.Severity),
+			},
+		)
+	}
+	return problems, nil
+}
+
+// golangciRule returns the check code as the rule ID if the message starts with it, otherwise the linter name.
+func golangciRule(linter string, text string) (ruleId string, message string) {
+	if match := golangciCheckPattern.FindStringSubmatch(text); match != nil {
+		return match[1], match[2]
+	}
+	return linter, text
+}
+
+// golangciLevel maps the severity configured in golangci-lint, issues without one are warnings.
+func golangciLevel(severity string) string {
+	switch severity {
+	case "error", "high", "critical":
+		return "error"
+	case "info", "note", "low":
+		return "note"
+	default:
+		return "warning"
+	}
+}
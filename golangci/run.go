@@ -0,0 +1,143 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	fexec "github.com/JetBrains/qodana-cli/internal/foundation/exec"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+type GolangciLinter struct {
+}
+
+const golangciLint = "golangci-lint"
+
+// golangciExitCodes are the exit codes of a successful run: no issues, issues found and no Go files to analyze.
+var golangciExitCodes = []int{0, 1, 5}
+
+// golangciVersionPattern matches the output of `golangci-lint --version`, e.g. golangci-lint has version 2.1.6 built with...
+var golangciVersionPattern = regexp.MustCompile(`version v?(\d+)\.(\d+)\.(\d+)`)
+
+func (l GolangciLinter) RunAnalysis(c thirdpartyscan.Context) error {
+	utils.Bootstrap(c.QodanaYamlConfig().BootstrapSteps(), c.ProjectDir(), c.LogDir())
+
+	binary, err := findGolangciBinary()
+	if err != nil {
+		return err
+	}
+	toolVersion, err := golangciVersion(c.ProjectDir(), binary)
+	if err != nil {
+		return err
+	}
+
+	args := golangciArgs(toolVersion)
+	log.Debugf("Running %s %v", binary, args)
+	stdout, stderr, ret, err := fexec.ExecRedirectOutput(c.ProjectDir(), binary, args...)
+	if err != nil {
+		return err
+	}
+	if stderr != "" {
+		log.Debug(stderr)
+	}
+	if err = os.WriteFile(filepath.Join(c.LogDir(), "golangci-lint.json"), []byte(stdout), 0o644); err != nil {
+		log.Warnf("Failed to save golangci-lint output: %s", err)
+	}
+	if !slices.Contains(golangciExitCodes, ret) {
+		return fmt.Errorf("golangci-lint exited with code: %d\n%s", ret, stderr)
+	}
+
+	problems, err := parseGolangciOutput([]byte(stdout), c.ProjectDir())
+	if err != nil {
+		return err
+	}
+	report := platform.ConvertToSarif(golangciLint, toolVersion, problems)
+
+	tmpResultsDir := platform.GetTmpResultsDir(c.ResultsDir())
+	if err = os.MkdirAll(tmpResultsDir, os.ModePerm); err != nil {
+		return err
+	}
+	if err = platform.WriteReport(filepath.Join(tmpResultsDir, "golangci-lint.sarif.json"), report); err != nil {
+		return err
+	}
+	_, err = platform.MergeSarifReports(c, platform.GetDeviceIdSalt()[0])
+	return err
+}
+
+// MountTools does nothing: golangci-lint is used from PATH or GOPATH/bin.
+func (l GolangciLinter) MountTools(_ string) (map[string]string, error) {
+	return nil, nil
+}
+
+// golangciArgs returns the arguments printing all issues as JSON to stdout, the flag differs between v1 and v2.
+func golangciArgs(toolVersion string) []string {
+	args := []string{"run", "--max-issues-per-linter=0", "--max-same-issues=0"}
+	if strings.HasPrefix(toolVersion, "1.") {
+		return append(args, "--out-format=json")
+	}
+	return append(args, "--output.json.path=stdout", "--show-stats=false")
+}
+
+func golangciVersion(projectDir string, binary string) (string, error) {
+	stdout, stderr, _, err := fexec.ExecRedirectOutput(projectDir, binary, "--version")
+	if err != nil {
+		return "", err
+	}
+	match := golangciVersionPattern.FindStringSubmatch(stdout + stderr)
+	if match == nil {
+		return "", fmt.Errorf("failed to get golangci-lint version from %q", strings.TrimSpace(stdout+stderr))
+	}
+	return strings.Join(match[1:], "."), nil
+}
+
+// findGolangciBinary looks golangci-lint up in PATH first, then in GOPATH/bin where `go install` puts it.
+func findGolangciBinary() (string, error) {
+	binary, err := exec.LookPath(golangciLint)
+	if err == nil {
+		return binary, nil
+	}
+	goPath := os.Getenv("GOPATH")
+	if goPath == "" {
+		if home, homeErr := os.UserHomeDir(); homeErr == nil {
+			goPath = filepath.Join(home, "go")
+		}
+	}
+	goPathBinary := filepath.Join(goPath, "bin", golangciLint)
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		goPathBinary += ".exe"
+	}
+	if _, statErr := os.Stat(goPathBinary); goPath != "" && statErr == nil {
+		return goPathBinary, nil
+	}
+	return "", errors.Join(
+		fmt.Errorf("golangci-lint is not found in PATH or GOPATH/bin, see https://golangci-lint.run/welcome/install/"),
+		err,
+	)
+}
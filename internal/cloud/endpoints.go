@@ -100,11 +100,9 @@ func parseRawURL(rawUrl string) (host string, err error) {
 
 func (endpoints *QdApiEndpoints) NewCloudApiClient(token string) *QdClient {
 	return &QdClient{
-		httpClient: &http.Client{
-			Timeout: getRequestTimeout(),
-		},
-		apiUrl: endpoints.CloudApiUrl,
-		token:  token,
+		httpClient: newHttpClient(getRequestTimeout()),
+		apiUrl:     endpoints.CloudApiUrl,
+		token:      token,
 	}
 }
 
@@ -114,11 +112,9 @@ func getRequestTimeout() time.Duration {
 
 func (endpoints *QdApiEndpoints) NewLintersApiClient(token string) *QdClient {
 	return &QdClient{
-		httpClient: &http.Client{
-			Timeout: getRequestTimeout(),
-		},
-		apiUrl: endpoints.LintersApiUrl,
-		token:  token,
+		httpClient: newHttpClient(getRequestTimeout()),
+		apiUrl:     endpoints.LintersApiUrl,
+		token:      token,
 	}
 }
 
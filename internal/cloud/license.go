@@ -137,9 +137,7 @@ func (endpoints *QdApiEndpoints) RequestLicenseData(token string) ([]byte, error
 func requestLicenseDataAttempt(endpoint string, token string) ([]byte, error) {
 	timeout := getTimeout()
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := newHttpClient(time.Duration(timeout) * time.Second)
 
 	url := fmt.Sprintf("%s%s", endpoint, qodanaLicenseUri)
 	req, err := http.NewRequest("GET", url, nil)
@@ -173,6 +171,19 @@ func requestLicenseDataAttempt(endpoint string, token string) ([]byte, error) {
 	)
 }
 
+// CheckToken requests the license data once, the error is ErrTokenDeclined if Qodana Cloud doesn't accept the token.
+func (endpoints *QdApiEndpoints) CheckToken(token string) (LicenseData, error) {
+	var ld LicenseData
+	data, err := requestLicenseDataAttempt(endpoints.LintersApiUrl, token)
+	if err != nil {
+		return ld, err
+	}
+	if err = json.Unmarshal(data, &ld); err != nil {
+		return ld, fmt.Errorf("license deserialization failed: %w", err)
+	}
+	return ld, nil
+}
+
 func getTimeout() int {
 	return GetEnvWithDefaultInt(QodanaLicenseRequestTimeoutEnv, qodanaLicenseRequestTimeout)
 }
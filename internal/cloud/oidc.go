@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+)
+
+type oidcExchangeRequest struct {
+	IdToken string `json:"idToken"`
+}
+
+type oidcExchangeResponse struct {
+	Token string `json:"token"`
+}
+
+type gitHubOidcResponse struct {
+	Value string `json:"value"`
+}
+
+// GetCiOidcToken returns the OIDC ID token issued by the CI for the job, it is empty if the CI doesn't issue one.
+// The token is taken from QODANA_OIDC_TOKEN (e.g. declared with id_tokens in GitLab CI), or requested from
+// GitHub Actions for GetOidcAudience when the workflow has the id-token: write permission.
+func GetCiOidcToken() (string, error) {
+	if token := os.Getenv(qdenv.QodanaOidcToken); token != "" {
+		return token, nil
+	}
+	requestUrl, requestToken := os.Getenv(qdenv.GitHubOidcRequestUrl), os.Getenv(qdenv.GitHubOidcRequestToken)
+	if requestUrl == "" || requestToken == "" {
+		return "", nil
+	}
+	return requestGitHubOidcToken(
+		&http.Client{Timeout: getRequestTimeout()},
+		requestUrl,
+		requestToken,
+		GetOidcAudience(),
+	)
+}
+
+// GetOidcAudience returns the audience the CI OIDC token is requested for, Qodana Cloud checks it on the exchange.
+func GetOidcAudience() string {
+	if audience := os.Getenv(qdenv.QodanaOidcAudience); audience != "" {
+		return audience
+	}
+	return GetCloudRootEndpoint().Url
+}
+
+func requestGitHubOidcToken(
+	httpClient *http.Client,
+	requestUrl string,
+	requestToken string,
+	audience string,
+) (string, error) {
+	separator := "?"
+	if strings.Contains(requestUrl, "?") {
+		separator = "&"
+	}
+	req, err := http.NewRequest(http.MethodGet, requestUrl+separator+"audience="+url.QueryEscape(audience), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &APIError{StatusCode: resp.StatusCode, Message: string(body)}
+	}
+	var response gitHubOidcResponse
+	if err = json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("response '%s': %w", string(body), err)
+	}
+	if response.Value == "" {
+		return "", errors.New("empty OIDC token in the GitHub Actions response")
+	}
+	return response.Value, nil
+}
+
+// ExchangeOidcToken exchanges the CI OIDC ID token for a short-lived Qodana Cloud token of the project
+// trusting the CI identity.
+func (client *QdClient) ExchangeOidcToken(idToken string) (string, error) {
+	body, err := json.Marshal(oidcExchangeRequest{IdToken: idToken})
+	if err != nil {
+		return "", err
+	}
+	request := NewCloudRequest("/oidc/token/")
+	request.Method = http.MethodPost
+	request.Body = body
+	result, err := client.doRequest(&request)
+	if err != nil {
+		return "", err
+	}
+	var response oidcExchangeResponse
+	if err = json.Unmarshal(result, &response); err != nil {
+		return "", fmt.Errorf("response '%s': %w", string(result), err)
+	}
+	if response.Token == "" {
+		return "", errors.New("empty token in the Qodana Cloud response")
+	}
+	return response.Token, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+)
+
+func TestGetCiOidcToken(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer request-token" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				if r.URL.Query().Get("api-version") != "2.0" || r.URL.Query().Get("audience") != "https://qodana.cloud" {
+					t.Errorf("unexpected OIDC token request %s", r.URL)
+				}
+				_, _ = w.Write([]byte(`{"value":"github-id-token"}`))
+			},
+		),
+	)
+	defer server.Close()
+
+	t.Setenv(qdenv.QodanaOidcToken, "")
+	t.Setenv(qdenv.GitHubOidcRequestUrl, "")
+	t.Setenv(qdenv.GitHubOidcRequestToken, "")
+	t.Setenv(qdenv.QodanaOidcAudience, "https://qodana.cloud")
+	token, err := GetCiOidcToken()
+	if err != nil || token != "" {
+		t.Errorf("expected no OIDC token outside CI, got %q, %v", token, err)
+	}
+
+	t.Setenv(qdenv.GitHubOidcRequestUrl, server.URL+"/token?api-version=2.0")
+	t.Setenv(qdenv.GitHubOidcRequestToken, "request-token")
+	token, err = GetCiOidcToken()
+	if err != nil || token != "github-id-token" {
+		t.Errorf("expected the GitHub Actions OIDC token, got %q, %v", token, err)
+	}
+
+	t.Setenv(qdenv.QodanaOidcToken, "gitlab-id-token")
+	token, err = GetCiOidcToken()
+	if err != nil || token != "gitlab-id-token" {
+		t.Errorf("expected %s to be preferred, got %q, %v", qdenv.QodanaOidcToken, token, err)
+	}
+
+	t.Setenv(qdenv.QodanaOidcToken, "")
+	t.Setenv(qdenv.GitHubOidcRequestToken, "expired")
+	if _, err = GetCiOidcToken(); err == nil {
+		t.Error("expected an error for the declined OIDC token request")
+	}
+}
+
+func TestExchangeOidcToken(t *testing.T) {
+	t.Setenv(qdenv.QodanaCloudRequestCooldownEnv, "0")
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var request oidcExchangeRequest
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/oidc/token/" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.IdToken != "trusted" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				_, _ = w.Write([]byte(`{"token":"qodana-token"}`))
+			},
+		),
+	)
+	defer server.Close()
+	client := &QdClient{apiUrl: server.URL + "/v1", httpClient: server.Client()}
+
+	token, err := client.ExchangeOidcToken("trusted")
+	if err != nil || token != "qodana-token" {
+		t.Errorf("expected the exchanged token, got %q, %v", token, err)
+	}
+	if _, err = client.ExchangeOidcToken("untrusted"); err == nil {
+		t.Error("expected an error for the untrusted OIDC token")
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EndpointProfilesFileName is the file in the Qodana user configuration directory with the endpoint profiles:
+//
+//	profiles:
+//	  on-premise:
+//	    url: https://qodana.example.com
+//	    token: <project token>
+//	    tls:
+//	      caFile: /etc/ssl/example-ca.pem
+const EndpointProfilesFileName = "endpoints.yaml"
+
+// EndpointProfile is a named Qodana Cloud installation, e.g. a self-hosted one, with the token of the project
+// analyzed against it.
+type EndpointProfile struct {
+	Url   string      `yaml:"url"`
+	Token string      `yaml:"token"`
+	Tls   EndpointTls `yaml:"tls"`
+}
+
+// EndpointTls are the TLS settings for the installations with a certificate issued by a private CA.
+type EndpointTls struct {
+	// CaFile is the PEM file with the certificates trusted in addition to the system ones.
+	CaFile             string `yaml:"caFile"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+}
+
+type endpointProfiles struct {
+	Profiles map[string]EndpointProfile `yaml:"profiles"`
+}
+
+// tlsConfig is the TLS configuration of the requests to Qodana Cloud, nil for the default one.
+var tlsConfig *tls.Config
+
+// EndpointProfilesPath returns the path of the endpoint profiles file, empty if there is no user configuration
+// directory.
+func EndpointProfilesPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "JetBrains", "Qodana", EndpointProfilesFileName)
+}
+
+// LoadEndpointProfile reads the profile with the given name from the endpoint profiles file.
+func LoadEndpointProfile(path string, name string) (EndpointProfile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return EndpointProfile{}, fmt.Errorf("endpoint profile %s not found: %s doesn't exist", name, path)
+	}
+	if err != nil {
+		return EndpointProfile{}, err
+	}
+	var profiles endpointProfiles
+	if err = yaml.Unmarshal(data, &profiles); err != nil {
+		return EndpointProfile{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	profile, ok := profiles.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles.Profiles))
+		for profileName := range profiles.Profiles {
+			names = append(names, profileName)
+		}
+		slices.Sort(names)
+		return EndpointProfile{}, fmt.Errorf(
+			"endpoint profile %s not found in %s, the available profiles: %s",
+			name,
+			path,
+			strings.Join(names, ", "),
+		)
+	}
+	if profile.Url == "" {
+		return EndpointProfile{}, fmt.Errorf("endpoint profile %s in %s has no url", name, path)
+	}
+	if _, err = parseRawURL(profile.Url); err != nil {
+		return EndpointProfile{}, fmt.Errorf("endpoint profile %s in %s has an invalid url: %w", name, path, err)
+	}
+	return profile, nil
+}
+
+// SetEndpointTls applies the TLS settings of the profile to the following requests to Qodana Cloud.
+func SetEndpointTls(settings EndpointTls) error {
+	if settings.CaFile == "" && !settings.InsecureSkipVerify {
+		tlsConfig = nil
+		return nil
+	}
+	config := &tls.Config{InsecureSkipVerify: settings.InsecureSkipVerify}
+	if settings.CaFile != "" {
+		pem, err := os.ReadFile(settings.CaFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in the CA file %s", settings.CaFile)
+		}
+		config.RootCAs = pool
+	}
+	tlsConfig = config
+	return nil
+}
+
+// newHttpClient returns the client for the requests to Qodana Cloud and its storage.
+func newHttpClient(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+	return client
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testEndpointProfiles = `profiles:
+  on-premise:
+    url: https://qodana.example.com
+    token: secret
+    tls:
+      caFile: /etc/ssl/example-ca.pem
+  broken:
+    token: secret
+`
+
+func TestLoadEndpointProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), EndpointProfilesFileName)
+	if err := os.WriteFile(path, []byte(testEndpointProfiles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := LoadEndpointProfile(path, "on-premise")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := EndpointProfile{
+		Url:   "https://qodana.example.com",
+		Token: "secret",
+		Tls:   EndpointTls{CaFile: "/etc/ssl/example-ca.pem"},
+	}
+	if profile != expected {
+		t.Errorf("expected %+v, got %+v", expected, profile)
+	}
+
+	if _, err = LoadEndpointProfile(path, "missing"); err == nil || !strings.Contains(err.Error(), "broken, on-premise") {
+		t.Errorf("expected the available profiles in the error, got %v", err)
+	}
+	if _, err = LoadEndpointProfile(path, "broken"); err == nil {
+		t.Error("expected an error for the profile without url")
+	}
+	if _, err = LoadEndpointProfile(filepath.Join(t.TempDir(), EndpointProfilesFileName), "on-premise"); err == nil {
+		t.Error("expected an error for the missing profiles file")
+	}
+}
+
+func TestSetEndpointTls(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	t.Cleanup(func() { tlsConfig = nil })
+
+	if _, err := newHttpClient(time.Second).Get(server.URL); err == nil {
+		t.Fatal("expected the certificate of the private CA to be rejected by default")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEndpointTls(EndpointTls{CaFile: caFile}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := newHttpClient(time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected the certificate to be trusted with the CA file: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if err = SetEndpointTls(EndpointTls{CaFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("expected an error for the missing CA file")
+	}
+	if err = SetEndpointTls(EndpointTls{}); err != nil || tlsConfig != nil {
+		t.Errorf("expected the default TLS settings, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// UploadStateFileName is the file in the results directory keeping the progress of the report upload,
+	// the next upload of the same analysis resumes from it.
+	UploadStateFileName = "upload-state.json"
+
+	defaultUploadTimeoutSeconds  = 300
+	defaultUploadConcurrency     = 4
+	uploadSkippedResultsDirEntry = "log"
+)
+
+// UploadState is the progress of the report upload.
+type UploadState struct {
+	AnalysisId string         `json:"analysisId"`
+	ReportId   string         `json:"reportId"`
+	Files      []UploadedFile `json:"files"`
+}
+
+// UploadedFile is a file of the report, its upload link and whether Qodana Cloud has accepted it.
+type UploadedFile struct {
+	// Name is the slash-separated path of the file in the results directory.
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Url      string `json:"url"`
+	Uploaded bool   `json:"uploaded"`
+}
+
+type reportFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+type createReportRequest struct {
+	AnalysisId string       `json:"analysisId"`
+	Type       string       `json:"type"`
+	Files      []reportFile `json:"files"`
+}
+
+type createReportResponse struct {
+	ReportId  string            `json:"reportId"`
+	FileLinks map[string]string `json:"fileLinks"`
+}
+
+type finishReportResponse struct {
+	Url string `json:"url"`
+}
+
+// UploadReport uploads the files of the results directory to their links given by Qodana Cloud, it returns the report
+// URL. A failed file upload is retried, and the uploaded files are saved to UploadStateFileName, so the upload of the
+// same analysis interrupted by a network failure skips them instead of starting over.
+func (client *QdClient) UploadReport(resultsDir string, analysisId string) (string, error) {
+	files, err := listReportFiles(resultsDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the report files: %w", err)
+	}
+	statePath := filepath.Join(resultsDir, UploadStateFileName)
+	state, err := ReadUploadState(statePath)
+	if err != nil {
+		log.Warnf("Failed to read the upload state, the upload starts over: %s", err)
+	}
+	if state == nil || !state.matches(analysisId, files) {
+		if state, err = client.createReport(analysisId, files); err != nil {
+			return "", fmt.Errorf("failed to start the report upload: %w", err)
+		}
+	} else {
+		log.Infof("Resuming the upload of report %s", state.ReportId)
+	}
+	if err = state.save(statePath); err != nil {
+		return "", err
+	}
+
+	if err = uploadFiles(resultsDir, state, statePath); err != nil {
+		return "", err
+	}
+
+	reportUrl, err := client.finishReport(state.ReportId)
+	if err != nil {
+		return "", fmt.Errorf("failed to finish the report upload: %w", err)
+	}
+	if err = os.Remove(statePath); err != nil {
+		log.Warnf("Failed to remove %s: %s", statePath, err)
+	}
+	return reportUrl, nil
+}
+
+// uploadFiles uploads the files not uploaded yet by QODANA_CLOUD_UPLOAD_CONCURRENCY files at a time, every uploaded
+// file is saved to the state. All files are tried, the error of the first failed one is returned.
+func uploadFiles(resultsDir string, state *UploadState, statePath string) error {
+	uploader := newFileUploader()
+	concurrency := max(GetEnvWithDefaultInt(qdenv.QodanaCloudUploadConcurrency, defaultUploadConcurrency), 1)
+	semaphore := make(chan struct{}, concurrency)
+	errs := make([]error, len(state.Files))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := range state.Files {
+		file := &state.Files[i]
+		if file.Uploaded {
+			continue
+		}
+		// the uploader gets its own copy of the file, the state is only changed under the lock
+		upload := *file
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() { <-semaphore; wg.Done() }()
+			path := filepath.Join(resultsDir, filepath.FromSlash(upload.Name))
+			if err := uploader.upload(path, &upload); err != nil {
+				errs[i] = fmt.Errorf("failed to upload %s: %w", upload.Name, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			file.Uploaded = true
+			errs[i] = state.save(statePath)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadUploadState reads the progress of the interrupted upload, nil if there is none.
+func ReadUploadState(path string) (*UploadState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &UploadState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func (s *UploadState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save the upload state: %w", err)
+	}
+	return nil
+}
+
+// matches tells if the saved upload is of the same analysis and the report files didn't change since.
+func (s *UploadState) matches(analysisId string, files []reportFile) bool {
+	if s.AnalysisId != analysisId || s.ReportId == "" || len(s.Files) != len(files) {
+		return false
+	}
+	for i, file := range files {
+		if s.Files[i].Name != file.Name || s.Files[i].Size != file.Size || s.Files[i].Url == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (client *QdClient) createReport(analysisId string, files []reportFile) (*UploadState, error) {
+	body, err := json.Marshal(createReportRequest{AnalysisId: analysisId, Type: "sarif", Files: files})
+	if err != nil {
+		return nil, err
+	}
+	request := NewCloudRequest("/reports/")
+	request.Method = http.MethodPost
+	request.Body = body
+	result, err := client.doRequest(&request)
+	if err != nil {
+		return nil, err
+	}
+	var response createReportResponse
+	if err = json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("response '%s': %w", string(result), err)
+	}
+	state := &UploadState{AnalysisId: analysisId, ReportId: response.ReportId}
+	for _, file := range files {
+		link, ok := response.FileLinks[file.Name]
+		if !ok {
+			return nil, fmt.Errorf("no upload link for %s", file.Name)
+		}
+		state.Files = append(state.Files, UploadedFile{Name: file.Name, Size: file.Size, Url: link})
+	}
+	return state, nil
+}
+
+func (client *QdClient) finishReport(reportId string) (string, error) {
+	request := NewCloudRequest(fmt.Sprintf("/reports/%s/finish/", reportId))
+	request.Method = http.MethodPost
+	result, err := client.doRequest(&request)
+	if err != nil {
+		return "", err
+	}
+	var response finishReportResponse
+	if err = json.Unmarshal(result, &response); err != nil {
+		return "", fmt.Errorf("response '%s': %w", string(result), err)
+	}
+	return response.Url, nil
+}
+
+// listReportFiles returns the files of the results directory to upload, sorted by name.
+func listReportFiles(resultsDir string) ([]reportFile, error) {
+	var files []reportFile
+	err := filepath.WalkDir(
+		resultsDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name, err := filepath.Rel(resultsDir, path)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+			if d.IsDir() {
+				if name == uploadSkippedResultsDirEntry {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if name == UploadStateFileName || !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files = append(files, reportFile{Name: name, Size: info.Size()})
+			return nil
+		},
+	)
+	slices.SortFunc(files, func(a, b reportFile) int { return strings.Compare(a.Name, b.Name) })
+	return files, err
+}
+
+// fileUploader uploads a file to its upload link with PUT, the failed upload is retried.
+type fileUploader struct {
+	httpClient *http.Client
+	retries    int
+	cooldown   time.Duration
+}
+
+func newFileUploader() *fileUploader {
+	timeout := GetEnvWithDefaultInt(qdenv.QodanaCloudUploadTimeoutEnv, defaultUploadTimeoutSeconds)
+	return &fileUploader{
+		httpClient: newHttpClient(time.Duration(timeout) * time.Second),
+		retries:    max(GetEnvWithDefaultInt(qdenv.QodanaCloudRequestRetriesEnv, defaultNumberOfRetries), 1),
+		cooldown:   time.Duration(GetEnvWithDefaultInt(qdenv.QodanaCloudRequestCooldownEnv, defaultCooldownTimeSeconds)) * time.Second,
+	}
+}
+
+// upload sends the file until it is accepted or the retries run out.
+func (u *fileUploader) upload(path string, file *UploadedFile) error {
+	var err error
+	for i := 1; i <= u.retries; i++ {
+		if err = u.put(path, file); err == nil {
+			return nil
+		}
+		log.Errorf("Attempt #%d of %d to upload %s failed. Error: %v", i, u.retries, file.Name, err)
+		if i < u.retries {
+			time.Sleep(u.cooldown)
+		}
+	}
+	return err
+}
+
+func (u *fileUploader) put(path string, file *UploadedFile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	var body io.Reader = f
+	if file.Size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(http.MethodPut, file.Url, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = file.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	responseBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &APIError{StatusCode: resp.StatusCode, Message: string(responseBody)}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+)
+
+// fakeStorage accepts the uploads of the report files to their links the way Qodana Cloud does.
+type fakeStorage struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	puts     map[string]int
+	failPuts map[string]int
+	created  int
+	finished bool
+}
+
+func newFakeStorage(t *testing.T) (*fakeStorage, *httptest.Server) {
+	storage := &fakeStorage{files: map[string][]byte{}, puts: map[string]int{}, failPuts: map[string]int{}}
+	var server *httptest.Server
+	server = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				storage.mu.Lock()
+				defer storage.mu.Unlock()
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/v1/reports/":
+					storage.created++
+					_, _ = fmt.Fprintf(
+						w,
+						`{"reportId":"r1","fileLinks":{"qodana.sarif.json":"%[1]s/storage/sarif","report/result.json":"%[1]s/storage/result"}}`,
+						server.URL,
+					)
+				case r.Method == http.MethodPost && r.URL.Path == "/v1/reports/r1/finish/":
+					storage.finished = true
+					_, _ = w.Write([]byte(`{"url":"https://qodana.cloud/reports/r1"}`))
+				case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/storage/"):
+					storage.put(t, w, r)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		),
+	)
+	return storage, server
+}
+
+func (s *fakeStorage) put(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/storage/")
+	s.puts[name]++
+	if s.failPuts[name] > 0 {
+		s.failPuts[name]--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || int64(len(body)) != r.ContentLength {
+		t.Errorf("incomplete upload of %s: %d of %d bytes, %v", name, len(body), r.ContentLength, err)
+	}
+	s.files[name] = body
+	w.WriteHeader(http.StatusOK)
+}
+
+func prepareUploadResults(t *testing.T) (string, []byte) {
+	t.Setenv(qdenv.QodanaCloudRequestCooldownEnv, "0")
+	resultsDir := t.TempDir()
+	sarif := bytes.Repeat([]byte("0123456789abcdef"), 160*1024)
+	writeUploadFile(t, filepath.Join(resultsDir, "qodana.sarif.json"), sarif)
+	writeUploadFile(t, filepath.Join(resultsDir, "report", "result.json"), []byte(`{}`))
+	writeUploadFile(t, filepath.Join(resultsDir, "log", "idea.log"), []byte("not uploaded"))
+	return resultsDir, sarif
+}
+
+func writeUploadFile(t *testing.T, path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadReport(t *testing.T) {
+	resultsDir, sarif := prepareUploadResults(t)
+	storage, server := newFakeStorage(t)
+	defer server.Close()
+	storage.failPuts["sarif"] = 2 // retried
+	client := &QdClient{apiUrl: server.URL + "/v1", httpClient: server.Client()}
+
+	reportUrl, err := client.UploadReport(resultsDir, "analysis")
+	if err != nil {
+		t.Fatalf("UploadReport failed: %v", err)
+	}
+	if reportUrl != "https://qodana.cloud/reports/r1" {
+		t.Errorf("unexpected report URL %s", reportUrl)
+	}
+	if !bytes.Equal(storage.files["sarif"], sarif) || string(storage.files["result"]) != `{}` {
+		t.Error("the uploaded files differ from the report")
+	}
+	if !storage.finished {
+		t.Error("the upload is not finished")
+	}
+	if _, err = os.Stat(filepath.Join(resultsDir, UploadStateFileName)); !os.IsNotExist(err) {
+		t.Error("the upload state is not removed after the upload")
+	}
+}
+
+func TestUploadReportResume(t *testing.T) {
+	resultsDir, _ := prepareUploadResults(t)
+	t.Setenv(qdenv.QodanaCloudRequestRetriesEnv, "2")
+	storage, server := newFakeStorage(t)
+	defer server.Close()
+	client := &QdClient{apiUrl: server.URL + "/v1", httpClient: server.Client()}
+
+	// the network failed after the SARIF was uploaded
+	state := &UploadState{
+		AnalysisId: "analysis",
+		ReportId:   "r1",
+		Files: []UploadedFile{
+			{Name: "qodana.sarif.json", Size: 160 * 1024 * 16, Url: server.URL + "/storage/sarif", Uploaded: true},
+			{Name: "report/result.json", Size: 2, Url: server.URL + "/storage/result"},
+		},
+	}
+	if err := state.save(filepath.Join(resultsDir, UploadStateFileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.UploadReport(resultsDir, "analysis"); err != nil {
+		t.Fatalf("UploadReport failed: %v", err)
+	}
+	if storage.created != 0 {
+		t.Error("the report is created again instead of resuming the upload")
+	}
+	if storage.puts["sarif"] != 0 {
+		t.Error("the uploaded file is uploaded again")
+	}
+	if string(storage.files["result"]) != `{}` || !storage.finished {
+		t.Error("the rest of the report is not uploaded")
+	}
+}
+
+func TestUploadReportFailureKeepsState(t *testing.T) {
+	resultsDir, _ := prepareUploadResults(t)
+	t.Setenv(qdenv.QodanaCloudRequestRetriesEnv, "2")
+	storage, server := newFakeStorage(t)
+	defer server.Close()
+	client := &QdClient{apiUrl: server.URL + "/v1", httpClient: server.Client()}
+	storage.failPuts["result"] = 2
+
+	if _, err := client.UploadReport(resultsDir, "analysis"); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+	state, err := ReadUploadState(filepath.Join(resultsDir, UploadStateFileName))
+	if err != nil || state == nil {
+		t.Fatalf("the upload state is not saved: %v", err)
+	}
+	if state.ReportId != "r1" || !state.Files[0].Uploaded || state.Files[1].Uploaded {
+		t.Errorf("unexpected upload state %+v", state)
+	}
+}
@@ -101,11 +101,7 @@ func selectSupportedVersion(apiDescriptions []ApiVersionDescription) string {
 }
 
 func (endpoint *QdRootEndpoint) requestApiEndpoints() (*QdApiEndpoints, error) {
-	httpClient := &http.Client{
-		Timeout: getRequestTimeout(),
-	}
-
-	return endpoint.requestApiEndpointsCustomClient(httpClient)
+	return endpoint.requestApiEndpointsCustomClient(newHttpClient(getRequestTimeout()))
 }
 
 func (endpoint *QdRootEndpoint) requestApiEndpointsCustomClient(httpClient *http.Client) (*QdApiEndpoints, error) {
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// attachOptions represents attach command options.
+type attachOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+}
+
+// newAttachCommand returns a new instance of the attach command.
+func newAttachCommand() *cobra.Command {
+	cliOptions := &attachOptions{}
+	cmd := &cobra.Command{
+		Use:   "attach",
+		Short: "Attach to a running Qodana analysis",
+		Long: `Attach to a Qodana container analysis that was started by another CLI process (e.g. the one that was killed by a runner reboot).
+
+The command streams the analysis logs written from the moment it attaches (the earlier ones are in the logs of the container), waits for the analysis to finish and processes the results the same way "qodana scan" does.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+
+			resultsDir := cliOptions.ResultsDir
+			if resultsDir == "" {
+				commonCtx := commoncontext.Compute(
+					cliOptions.Linter,
+					"",
+					"",
+					"",
+					"",
+					"",
+					"",
+					qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+					false,
+					cliOptions.ProjectDir,
+					"",
+					cliOptions.ConfigName,
+				)
+				resultsDir = commonCtx.ResultsDir
+			}
+
+			state, err := core.ReadScanState(resultsDir)
+			if errors.Is(err, os.ErrNotExist) {
+				msg.ErrorMessage("No running Qodana analysis is recorded in %s", resultsDir)
+				exit(1)
+			} else if err != nil {
+				log.Fatal(err)
+			}
+
+			oldReportUrl := cloud.GetReportUrl(state.ResultsDir)
+			exitCode, err := core.AttachToContainer(cmd.Context(), state)
+			if err != nil {
+				msg.ErrorMessage("%s", err)
+				core.RemoveScanState(state.ResultsDir)
+				exit(exitCode)
+			}
+			if exitCode != exitcodes.QodanaSuccessExitCode && exitCode != exitcodes.QodanaFailThresholdExitCode {
+				msg.ErrorMessage("Qodana exited with code %d", exitCode)
+				msg.WarningMessage("Check ./logs/ in the results directory for more information")
+				exit(exitCode)
+			}
+
+			newReportUrl := cloud.GetReportUrl(state.ResultsDir)
+			platform.ProcessSarif(
+				filepath.Join(state.ResultsDir, commoncontext.QodanaSarifName),
+				state.AnalysisId,
+				newReportUrl,
+				state.PrintProblems,
+				state.GenerateCodeClimateReport,
+				state.SendBitBucketInsights,
+			)
+			platform.PublishSarif(filepath.Join(state.ResultsDir, commoncontext.QodanaSarifName), state.Publish)
+			platform.ReportToCi(filepath.Join(state.ResultsDir, commoncontext.QodanaSarifName), state.Ci)
+			if newReportUrl != oldReportUrl && newReportUrl != "" {
+				msg.SuccessMessage("Report is successfully uploaded to %s", newReportUrl)
+			}
+			platform.NotifyWebhooks(
+				state.QodanaSystemDir,
+				state.Webhooks,
+				filepath.Join(state.ResultsDir, commoncontext.QodanaSarifName),
+				state.AnalysisId,
+				newReportUrl,
+				exitCode,
+				platform.ObserveMode{},
+			)
+			platform.SendNotifications(
+				state.QodanaSystemDir,
+				state.Notifications,
+				filepath.Join(state.ResultsDir, commoncontext.QodanaSarifName),
+				state.AnalysisId,
+				newReportUrl,
+				exitCode,
+				platform.ObserveMode{},
+			)
+			if exitCode == exitcodes.QodanaFailThresholdExitCode {
+				msg.EmptyMessage()
+				msg.ErrorMessage("The number of problems exceeds the fail threshold")
+				exit(exitCode)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&cliOptions.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with the results of the running analysis (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	return cmd
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// benchOptions represents bench command options.
+type benchOptions struct {
+	Linter       string
+	WithinDocker string
+	Image        string
+	Runs         int
+	Output       string
+}
+
+// newBenchCommand returns a new instance of the bench command.
+func newBenchCommand() *cobra.Command {
+	cliOptions := &benchOptions{}
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure Qodana performance on a standard project",
+		Long: `Run the analysis of a small benchmark project bundled with the CLI and report how long each stage takes.
+
+The project is the same for every machine, so the timings can be used to compare Docker, Podman and native runs or different machine configurations.
+The first run includes pulling the linter image, use --runs to get the timings of warm runs.
+Nothing is sent anywhere: the report contains only the timings, the linter, the container engine, OS, architecture and number of CPUs, it can be saved with --output and shared.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if cliOptions.Runs < 1 {
+				log.Fatal("--runs should be a positive number")
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				log.Fatal(err)
+			}
+			benchDir, err := os.MkdirTemp("", "qodana-bench")
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer func() {
+				if err := os.RemoveAll(benchDir); err != nil {
+					log.Warnf("Failed to remove %s: %s", benchDir, err)
+				}
+			}()
+			projectDir := filepath.Join(benchDir, "project")
+			if err = core.ExtractBenchProject(projectDir); err != nil {
+				log.Fatal(err)
+			}
+			logFile, err := os.Create(filepath.Join(benchDir, "bench.log"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer func() {
+				_ = logFile.Close()
+			}()
+
+			linter := cliOptions.Linter
+			if cliOptions.Image != "" {
+				linter = cliOptions.Image
+			}
+			report := core.NewBenchReport(version.Version, linter, benchEngine(cmd.Context(), cliOptions))
+			for i := 0; i < cliOptions.Runs; i++ {
+				msg.SuccessMessage("Running benchmark %d/%d", i+1, cliOptions.Runs)
+				run, err := core.RunBenchmark(executable, benchScanArgs(cliOptions, benchDir, i), logFile)
+				if err != nil {
+					log.Fatal(err)
+				}
+				report.Runs = append(report.Runs, run)
+				if run.ExitCode != exitcodes.QodanaSuccessExitCode && run.ExitCode != exitcodes.QodanaFailThresholdExitCode {
+					msg.ErrorMessage("Qodana exited with code %d", run.ExitCode)
+					msg.PrintFile(logFile.Name())
+					os.Exit(run.ExitCode)
+				}
+			}
+
+			msg.EmptyMessage()
+			core.PrintBenchReport(report)
+			if cliOptions.Output != "" {
+				if err = core.WriteBenchReport(cliOptions.Output, report); err != nil {
+					log.Fatal(err)
+				}
+				msg.SuccessMessage("Benchmark report is saved to %s", cliOptions.Output)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", product.JvmCommunityLinter.Name, "Linter to benchmark")
+	flags.StringVar(
+		&cliOptions.WithinDocker,
+		"within-docker",
+		"true",
+		"Set to 'false' to benchmark the native mode, the linter should be installed then",
+	)
+	flags.StringVar(
+		&cliOptions.Image,
+		"image",
+		"",
+		"Override the image to benchmark, sets --within-docker=true and the linter preinstalled in the image",
+	)
+	flags.IntVar(&cliOptions.Runs, "runs", 1, "Number of benchmark runs, the image is pulled only before the first one")
+	flags.StringVar(&cliOptions.Output, "output", "", "Save the benchmark report as JSON to the given file")
+	return cmd
+}
+
+// benchScanArgs returns the arguments of the scan of the benchmark project, every run gets a clean cache.
+func benchScanArgs(options *benchOptions, benchDir string, run int) []string {
+	runDir := filepath.Join(benchDir, "run-"+strconv.Itoa(run+1))
+	args := []string{
+		"--project-dir", filepath.Join(benchDir, "project"),
+		"--results-dir", filepath.Join(runDir, "results"),
+		"--cache-dir", filepath.Join(runDir, "cache"),
+		"--print-problems=false",
+		"--no-statistics",
+	}
+	if options.Image != "" {
+		args = append(args, "--image", options.Image)
+	} else {
+		args = append(args, "--linter", options.Linter, "--within-docker", options.WithinDocker)
+	}
+	if run > 0 && benchInContainer(options) {
+		args = append(args, "--skip-pull")
+	}
+	return args
+}
+
+func benchInContainer(options *benchOptions) bool {
+	return options.Image != "" || options.WithinDocker != "false"
+}
+
+// benchEngine returns the name of the engine the benchmark runs on: docker, podman or native.
+func benchEngine(ctx context.Context, options *benchOptions) string {
+	if !benchInContainer(options) {
+		return "native"
+	}
+	apiClient, err := qdcontainer.NewContainerClient(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	engine, err := qdcontainer.ContainerEngineName(ctx, apiClient)
+	if err != nil {
+		log.Warn(err)
+		return "unknown"
+	}
+	return engine
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newBundleCommand returns a new instance of the bundle command.
+func newBundleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Create and load air-gapped bundles for the analysis without network access",
+		Long: `Package everything the analysis downloads into a single archive on a machine with network access, and load
+it on a machine without one, so "qodana scan --offline" runs there with zero network access.`,
+	}
+	cmd.AddCommand(newBundleCreateCommand(), newBundleLoadCommand())
+	return cmd
+}
+
+func newBundleCreateCommand() *cobra.Command {
+	cliOptions := &pullOptions{}
+	cmd := &cobra.Command{
+		Use:   "create <archive> [linter or image...]",
+		Short: "Package the linters into a .tar.zst, .tar.gz or .tar archive",
+		Long: `Package the linters into a .tar.zst, .tar.gz or .tar archive to be loaded with "qodana bundle load".
+
+The linter images are pulled and saved from the container engine, or with --native the IDE distributions of the
+linters are downloaded with their plugins. The running Qodana CLI is added too, the tools it runs are embedded into it.
+The linters are selected like in "qodana pull": the linter of the project by default, the linters or images given as
+arguments, --all-free or --for-project. The IDE distributions only run on the OS and architecture of this machine.`,
+		Example: `  # bundle the linter image of the project in the current directory
+  qodana bundle create qodana-bundle.tar.zst
+  # bundle several linter images
+  qodana bundle create qodana-bundle.tar.zst qodana-jvm qodana-python
+  # bundle the IDE of the native mode
+  qodana bundle create qodana-bundle.tar.zst qodana-jvm --native`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+			ctx := cmd.Context()
+			archivePath := args[0]
+			opts := core.BundleOptions{
+				Targets:   resolvePullTargets(cliOptions, args[1:]),
+				Native:    cliOptions.Native,
+				Parallel:  cliOptions.Parallel,
+				SystemDir: commoncontext.ComputeQodanaSystemDir(""),
+			}
+			var docker client.APIClient
+			if !opts.Native {
+				platform.SetupRegistryCredentialsOrFatal(
+					cliOptions.Registry,
+					cliOptions.Image,
+					cliOptions.RegistryUsername,
+					cliOptions.RegistryPasswordStdin,
+				)
+				qdcontainer.PrepareContainerEnvSettings()
+				var err error
+				if docker, err = qdcontainer.NewContainerClient(ctx); err != nil {
+					log.Fatalf("Failed to initialize Docker API: %s", err)
+				}
+				for _, image := range opts.Targets.AllImages() {
+					core.CheckImage(image)
+				}
+			}
+			manifest, err := core.CreateBundle(ctx, docker, archivePath, opts)
+			if err != nil {
+				log.Fatalf("Failed to create the bundle %s: %s", archivePath, err)
+			}
+			msg.SuccessMessage("Created the bundle %s (%s) with %s", archivePath, formatSize(bundleFileSize(archivePath)), bundleContents(manifest))
+		},
+	}
+	addPullTargetFlags(cmd, cliOptions)
+	cmd.Flags().BoolVar(&cliOptions.Native, "native", false, "Bundle the IDE distributions of the linters for the native mode instead of the images")
+	cmd.MarkFlagsMutuallyExclusive("image", "native")
+	return cmd
+}
+
+func newBundleLoadCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "load <archive>",
+		Short: "Install the linters from a bundle",
+		Long: `Install the linters from an archive created by "qodana bundle create": the images are loaded to the container
+engine, the IDE distributions are installed to <userCacheDir>/JetBrains/Qodana, where "qodana scan --offline" finds
+them. The archive is checked against its manifest before anything is installed.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+			systemDir := commoncontext.ComputeQodanaSystemDir("")
+			manifest, err := core.LoadBundle(
+				cmd.Context(),
+				func(ctx context.Context) (client.APIClient, error) {
+					qdcontainer.PrepareContainerEnvSettings()
+					return qdcontainer.NewContainerClient(ctx)
+				},
+				args[0],
+				systemDir,
+			)
+			if err != nil {
+				log.Fatalf("Failed to load the bundle: %s", err)
+			}
+			msg.SuccessMessage("Loaded %s, run \"qodana scan --offline\" to analyze the projects without network access", bundleContents(manifest))
+			if manifest.CliVersion != version.Version {
+				cli, err := os.Executable()
+				if err == nil {
+					cli = filepath.Join(systemDir, "qodana-cli-"+manifest.CliVersion, filepath.Base(cli))
+				}
+				msg.WarningMessage(
+					"The bundle was created by Qodana CLI %s, this is %s. Run %s to use the bundled CLI",
+					manifest.CliVersion,
+					version.Version,
+					cli,
+				)
+			}
+		},
+	}
+}
+
+// bundleContents describes the linters of the bundle.
+func bundleContents(manifest commoncontext.BundleManifest) string {
+	var contents []string
+	if len(manifest.Images) > 0 {
+		contents = append(contents, "the images "+strings.Join(manifest.Images, ", "))
+	}
+	if len(manifest.Ides) > 0 {
+		linters := make([]string, 0, len(manifest.Ides))
+		for linter := range manifest.Ides {
+			linters = append(linters, linter)
+		}
+		slices.Sort(linters)
+		contents = append(contents, "the IDEs of "+strings.Join(linters, ", "))
+	}
+	return strings.Join(append(contents, "Qodana CLI "+manifest.CliVersion), " and ")
+}
+
+func bundleFileSize(archivePath string) int64 {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
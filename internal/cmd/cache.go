@@ -0,0 +1,395 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// cacheOptions represents cache command options.
+type cacheOptions struct {
+	CacheDir  string
+	OlderThan string
+	DryRun    bool
+	Volumes   bool
+}
+
+// cacheArchiveOptions represents cache export and import command options.
+type cacheArchiveOptions struct {
+	Linter     string
+	ProjectDir string
+	ConfigName string
+	Force      bool
+}
+
+// newCacheCommand returns a new instance of the cache command.
+func newCacheCommand() *cobra.Command {
+	cliOptions := &cacheOptions{}
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the Qodana caches",
+		Long: `Manage the caches Qodana keeps for every linter and project in <userCacheDir>/JetBrains/Qodana.
+
+Each directory there holds the cache, the results and the configuration of one linter run on one project,
+they are not removed automatically and can take tens of gigabytes over time.`,
+	}
+	cmd.PersistentFlags().StringVar(
+		&cliOptions.CacheDir,
+		"cache-dir",
+		"",
+		"Cache directory of a project, if it was overridden in the scan, to manage the caches next to it",
+	)
+	cmd.AddCommand(
+		newCacheListCommand(cliOptions),
+		newCacheSizeCommand(cliOptions),
+		newCachePruneCommand(cliOptions),
+		newCacheExportCommand(cliOptions),
+		newCacheImportCommand(cliOptions),
+	)
+	return cmd
+}
+
+func newCacheListCommand(cliOptions *cacheOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the cache directories of the linters and projects",
+		Run: func(cmd *cobra.Command, args []string) {
+			dirs := listLinterDirs(cliOptions)
+			if msg.IsJsonLog() {
+				msg.PrintJsonLog("cache", map[string]any{"dirs": dirs})
+				return
+			}
+			if len(dirs) == 0 {
+				msg.SuccessMessage("No caches found in %s", commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir))
+				return
+			}
+			tableData := pterm.TableData{
+				[]string{
+					msg.PrimaryBold("ID"),
+					msg.PrimaryBold("Project"),
+					msg.PrimaryBold("Linter"),
+					msg.PrimaryBold("Size"),
+					msg.PrimaryBold("Last used"),
+				},
+			}
+			for _, dir := range dirs {
+				tableData = append(
+					tableData,
+					[]string{dir.Id, unknownIfEmpty(dir.ProjectDir), unknownIfEmpty(dir.Analyzer), formatSize(dir.Size), formatAge(dir.LastUsed)},
+				)
+			}
+			printTable(tableData)
+		},
+	}
+}
+
+func newCacheSizeCommand(cliOptions *cacheOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "size",
+		Short: "Print the disk space taken by the caches",
+		Run: func(cmd *cobra.Command, args []string) {
+			systemDir := commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir)
+			dirs := listLinterDirs(cliOptions)
+			total, _, err := commoncontext.DirUsage(systemDir)
+			if err != nil {
+				log.Fatalf("Failed to compute the size of %s: %s", systemDir, err)
+			}
+			var linterDirsSize int64
+			for _, dir := range dirs {
+				linterDirsSize += dir.Size
+			}
+			if msg.IsJsonLog() {
+				msg.PrintJsonLog(
+					"cacheSize",
+					map[string]any{"path": systemDir, "linterDirs": linterDirsSize, "other": total - linterDirsSize, "total": total},
+				)
+				return
+			}
+			printTable(
+				pterm.TableData{
+					[]string{msg.PrimaryBold("Path"), systemDir},
+					[]string{fmt.Sprintf("Linter caches (%d)", len(dirs)), formatSize(linterDirsSize)},
+					[]string{"IDE distributions and tools", formatSize(total - linterDirsSize)},
+					[]string{msg.PrimaryBold("Total"), msg.PrimaryBold(formatSize(total))},
+				},
+			)
+		},
+	}
+}
+
+func newCachePruneCommand(cliOptions *cacheOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove the caches not used for a while",
+		Long: `Remove the cache directories of the linters and projects not used for the time given by --older-than.
+
+IDE distributions are kept, they are reused by all projects. With --volumes, the Docker volumes created by
+"qodana scan --cache-volume" are removed with the cache directories of their projects.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			age, err := parseCacheAge(cliOptions.OlderThan)
+			if err != nil {
+				log.Fatal(err)
+			}
+			dirs := listLinterDirs(cliOptions)
+			removed, err := commoncontext.PruneLinterDirs(dirs, time.Now().Add(-age), cliOptions.DryRun)
+			var freed int64
+			for _, dir := range removed {
+				freed += dir.Size
+				if cliOptions.DryRun {
+					msg.SuccessMessage("Would remove %s (%s, %s)", dir.Path, unknownIfEmpty(dir.ProjectDir), formatSize(dir.Size))
+				} else {
+					log.Debugf("Removed %s", dir.Path)
+				}
+			}
+			if err != nil {
+				log.Fatalf("Failed to remove the cache: %s", err)
+			}
+			if cliOptions.Volumes {
+				pruneCacheVolumes(cmd.Context(), removed, cliOptions.DryRun)
+			}
+			if cliOptions.DryRun {
+				msg.SuccessMessage("%d cache directories taking %s would be removed", len(removed), formatSize(freed))
+			} else {
+				msg.SuccessMessage("Removed %d cache directories, freed %s", len(removed), formatSize(freed))
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(
+		&cliOptions.OlderThan,
+		"older-than",
+		"30d",
+		"Remove the caches not used for this time, in days (30d), weeks (2w) or a Go duration (12h)",
+	)
+	flags.BoolVar(&cliOptions.DryRun, "dry-run", false, "Print the caches to remove without removing them")
+	flags.BoolVar(
+		&cliOptions.Volumes,
+		"volumes",
+		false,
+		"Also remove the Docker volumes of --cache-volume of the removed caches and of the projects without a cache directory",
+	)
+	return cmd
+}
+
+func pruneCacheVolumes(ctx context.Context, removed []commoncontext.LinterDir, dryRun bool) {
+	docker, err := qdcontainer.NewContainerClient(ctx)
+	if err != nil {
+		log.Fatalf("Cannot remove the cache volumes: %s", err)
+	}
+	removedDirs := make([]string, 0, len(removed))
+	for _, dir := range removed {
+		removedDirs = append(removedDirs, dir.Path)
+	}
+	volumes, err := core.PruneCacheVolumes(ctx, docker, removedDirs, dryRun)
+	for _, name := range volumes {
+		if dryRun {
+			msg.SuccessMessage("Would remove the cache volume %s", name)
+		} else {
+			msg.SuccessMessage("Removed the cache volume %s", name)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func newCacheExportCommand(cliOptions *cacheOptions) *cobra.Command {
+	archiveOptions := &cacheArchiveOptions{}
+	cmd := &cobra.Command{
+		Use:   "export <archive>",
+		Short: "Save the cache of the project to an archive",
+		Long: `Save the cache of the linter run on the project to a .tar.zst, .tar.gz or .tar archive, to be restored
+with "qodana cache import" on another machine, e.g. a CI artifact keeping the caches of ephemeral runners warm.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := computeCacheArchiveContext(cliOptions, archiveOptions)
+			manifest, err := commoncontext.ExportCache(
+				commonCtx.CacheDir,
+				args[0],
+				commoncontext.CacheManifest{
+					CliVersion: version.Version,
+					Analyzer:   commonCtx.Analyzer.Name(),
+					ProjectId:  commonCtx.Id,
+					CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+				},
+			)
+			if err != nil {
+				log.Fatalf("Failed to export the cache %s: %s", commonCtx.CacheDir, err)
+			}
+			msg.SuccessMessage("Exported %d files (%s) of the cache to %s", manifest.Files, formatSize(manifest.Size), args[0])
+		},
+	}
+	addCacheArchiveFlags(cmd, archiveOptions)
+	return cmd
+}
+
+func newCacheImportCommand(cliOptions *cacheOptions) *cobra.Command {
+	archiveOptions := &cacheArchiveOptions{}
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Restore the cache of the project from an archive",
+		Long: `Restore the cache of the linter run on the project from an archive written by "qodana cache export".
+
+The archive is checked against its manifest before it replaces the current cache: the files must be complete and
+the cache must be of the same linter, unless --force is set.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := computeCacheArchiveContext(cliOptions, archiveOptions)
+			analyzer := commonCtx.Analyzer.Name()
+			manifest, err := commoncontext.ImportCache(
+				args[0],
+				commonCtx.CacheDir,
+				func(manifest commoncontext.CacheManifest) error {
+					if manifest.Analyzer != analyzer && !archiveOptions.Force {
+						return fmt.Errorf("the cache is of %s, not of %s, use --force to import it anyway", manifest.Analyzer, analyzer)
+					}
+					if manifest.CliVersion != version.Version {
+						log.Debugf("The cache was exported by Qodana CLI %s", manifest.CliVersion)
+					}
+					return nil
+				},
+			)
+			if err != nil {
+				log.Fatalf("Failed to import the cache: %s", err)
+			}
+			msg.SuccessMessage("Imported %d files (%s) of the cache to %s", manifest.Files, formatSize(manifest.Size), commonCtx.CacheDir)
+		},
+	}
+	addCacheArchiveFlags(cmd, archiveOptions)
+	cmd.Flags().BoolVar(&archiveOptions.Force, "force", false, "Import the cache of another linter")
+	return cmd
+}
+
+func addCacheArchiveFlags(cmd *cobra.Command, archiveOptions *cacheArchiveOptions) {
+	flags := cmd.Flags()
+	flags.StringVarP(&archiveOptions.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&archiveOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(
+		&archiveOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+}
+
+// computeCacheArchiveContext finds the cache directory of the linter run on the project, the way the scan does.
+func computeCacheArchiveContext(cliOptions *cacheOptions, archiveOptions *cacheArchiveOptions) commoncontext.Context {
+	qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+	return commoncontext.Compute(
+		archiveOptions.Linter,
+		"",
+		"",
+		"",
+		cliOptions.CacheDir,
+		"",
+		"",
+		qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+		false,
+		archiveOptions.ProjectDir,
+		"",
+		archiveOptions.ConfigName,
+	)
+}
+
+func listLinterDirs(cliOptions *cacheOptions) []commoncontext.LinterDir {
+	systemDir := commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir)
+	dirs, err := commoncontext.ListLinterDirs(systemDir)
+	if err != nil {
+		log.Fatalf("Failed to list the caches in %s: %s", systemDir, err)
+	}
+	return dirs
+}
+
+func printTable(tableData pterm.TableData) {
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print the table: %s", err)
+	}
+}
+
+// parseCacheAge parses the number of days (30d) or weeks (2w), or a Go duration.
+func parseCacheAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid --older-than value %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid --older-than value %q, use e.g. 30d, 2w or 12h", value)
+	}
+	return age, nil
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TB", value)
+}
+
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	age := time.Since(t)
+	switch {
+	case age < time.Hour:
+		return "less than an hour ago"
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%d days ago", int(age.Hours()/24))
+	}
+}
+
+func unknownIfEmpty(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
@@ -26,20 +26,28 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/internal/platform/version"
 	"github.com/JetBrains/qodana-cli/internal/testutil/needs"
 	imagetypes "github.com/docker/docker/api/types/image"
 	cp "github.com/otiai10/copy"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 )
 
 func TestIsHelpOrVersion(t *testing.T) {
@@ -588,3 +596,474 @@ func runNativeScan(t *testing.T, projectPath string) {
 		t.Fatal(err)
 	}
 }
+
+func TestReadToken(t *testing.T) {
+	for input, expected := range map[string]string{
+		"token\n":      "token",
+		"  token \r\n": "token",
+		"":             "",
+	} {
+		token, err := readToken(strings.NewReader(input))
+		if err != nil || token != expected {
+			t.Errorf("readToken(%q) = %q, %v, want %q", input, token, err, expected)
+		}
+	}
+}
+
+func TestParseCacheAge(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"0d":  0,
+	} {
+		age, err := parseCacheAge(value)
+		if err != nil || age != expected {
+			t.Errorf("parseCacheAge(%q) = %v, %v, want %v", value, age, err, expected)
+		}
+	}
+	for _, value := range []string{"", "d", "-1d", "30days", "-2h"} {
+		if _, err := parseCacheAge(value); err == nil {
+			t.Errorf("parseCacheAge(%q) expected an error", value)
+		}
+	}
+}
+
+func TestToScanProjects(t *testing.T) {
+	projects, err := toScanProjects(
+		[]qdyaml.Project{
+			{Path: "services/api/", Linter: "qodana-jvm"},
+			{Path: "services/api", Linter: "qodana-jvm-community"},
+			{Path: "web"},
+			{Path: "."},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, project := range projects {
+		names = append(names, project.Name)
+	}
+	expected := []string{"services-api", "services-api-qodana-jvm-community", "web", "root"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("toScanProjects names = %v, want %v", names, expected)
+	}
+	if projects[0].Path != filepath.Join("services", "api") {
+		t.Errorf("toScanProjects path = %q", projects[0].Path)
+	}
+
+	for _, path := range []string{"", "..", "../other", "/abs"} {
+		if _, err := toScanProjects([]qdyaml.Project{{Path: path}}); err == nil {
+			t.Errorf("toScanProjects(%q) expected an error", path)
+		}
+	}
+}
+
+func TestProjectScanArgs(t *testing.T) {
+	command := newScanCommand()
+	err := command.ParseFlags(
+		[]string{
+			"--results-dir", "/tmp/results",
+			"--fail-threshold", "10",
+			"--property", "idea.log=debug",
+			"--property", "a=b,c",
+			"--print-problems",
+			"--publish", "github-code-scanning",
+			"--observe",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := scanProject{Project: qdyaml.Project{Path: "api", Linter: "qodana-jvm"}, Name: "api"}
+	args := projectScanArgs(command.Flags(), project, "/repo/api", "/tmp/results/api", "")
+	expected := []string{
+		"scan", "--project-dir", "/repo/api", "--results-dir", "/tmp/results/api", "--linter", "qodana-jvm",
+		"--fail-threshold=10", "--print-problems=true", "--property=idea.log=debug", "--property=a=b,c",
+	}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("projectScanArgs = %v, want %v", args, expected)
+	}
+}
+
+func TestFallbackScanArgs(t *testing.T) {
+	command := newScanCommand()
+	err := command.ParseFlags(
+		[]string{
+			"--linter", "qodana-cdnet",
+			"--fallback-linter", "qodana-dotnet",
+			"--results-dir", "/tmp/results",
+			"--property", "a=b",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := fallbackScanArgs(command.Flags(), "qodana-dotnet")
+	expected := []string{"scan", "--linter", "qodana-dotnet", "--property=a=b", "--results-dir=/tmp/results"}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("fallbackScanArgs = %v, want %v", args, expected)
+	}
+}
+
+func TestFailedScanWritesRunMetadata(t *testing.T) {
+	resultsDir := t.TempDir()
+	command := newScanCommand()
+	if err := command.ParseFlags([]string{"--results-dir", resultsDir}); err != nil {
+		t.Fatal(err)
+	}
+	scanContext := corescan.ContextBuilder{ProjectDir: t.TempDir(), ResultsDir: resultsDir}.Build()
+
+	exitCode, failed := failedScanExitCode(
+		command, &platformcmd.CliOptions{}, scanContext, exitcodes.QodanaOutOfMemoryExitCode, platform.ExitCodePolicy{},
+	)
+	if !failed || exitCode != exitcodes.QodanaOutOfMemoryExitCode {
+		t.Fatalf("failedScanExitCode = %d, %t, want %d, true", exitCode, failed, exitcodes.QodanaOutOfMemoryExitCode)
+	}
+	metadata, err := platform.ReadRunMetadata(filepath.Join(resultsDir, platform.RunMetadataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ExitCode != exitcodes.QodanaOutOfMemoryExitCode {
+		t.Errorf("run metadata exit code = %d, want %d", metadata.ExitCode, exitcodes.QodanaOutOfMemoryExitCode)
+	}
+
+	if _, failed = failedScanExitCode(
+		command, &platformcmd.CliOptions{}, scanContext, exitcodes.QodanaFailThresholdExitCode, platform.ExitCodePolicy{},
+	); failed {
+		t.Error("the analysis exceeding the fail threshold is processed")
+	}
+}
+
+func TestReplayScanArgs(t *testing.T) {
+	command := newScanCommand()
+	err := command.ParseFlags(
+		[]string{
+			"--linter", "qodana-jvm",
+			"--results-dir", "/ci/results",
+			"--fail-threshold", "10",
+			"-e", "API_TOKEN=ci-token",
+			"-e", "MODE=ci",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := recordedScanArgs(command.Flags())
+	expected := []string{
+		"--env=API_TOKEN=***",
+		"--env=MODE=ci",
+		"--fail-threshold=10",
+		"--linter=qodana-jvm",
+		"--results-dir=/ci/results",
+	}
+	if strings.Join(recorded, " ") != strings.Join(expected, " ") {
+		t.Errorf("recordedScanArgs = %v, want %v", recorded, expected)
+	}
+
+	t.Setenv("API_TOKEN", "local-token")
+	metadata := platform.RunMetadata{ImageDigest: "jetbrains/qodana-jvm@sha256:0123", Args: recorded}
+	args := replayScanArgs(metadata, "/project", "/results", ".qodana/local/replay-qodana.yaml")
+	expected = []string{
+		"scan",
+		"--project-dir=/project",
+		"--results-dir=/results",
+		"--config=.qodana/local/replay-qodana.yaml",
+		"--image=jetbrains/qodana-jvm@sha256:0123",
+		"--env=API_TOKEN=local-token",
+		"--env=MODE=ci",
+		"--fail-threshold=10",
+	}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("replayScanArgs = %v, want %v", args, expected)
+	}
+
+	metadata.ImageDigest = ""
+	args = replayScanArgs(metadata, "/project", "/results", ".qodana/local/replay-qodana.yaml")
+	if !slices.Contains(args, "--linter=qodana-jvm") {
+		t.Errorf("replayScanArgs without the image digest = %v, want the recorded linter", args)
+	}
+}
+
+func TestPrecommitDefaults(t *testing.T) {
+	flags := newPrecommitCommand().Flags()
+	for name, expected := range map[string]string{
+		"staged":            "true",
+		"print-problems":    "true",
+		"save-report":       "false",
+		"timeout":           "180000",
+		"timeout-exit-code": "0",
+	} {
+		flag := flags.Lookup(name)
+		if flag.Value.String() != expected || flag.DefValue != expected {
+			t.Errorf("expected --%s=%s got %s (default %s)", name, expected, flag.Value, flag.DefValue)
+		}
+		if flag.Changed {
+			t.Errorf("--%s is marked as set on the command line", name)
+		}
+	}
+	if staged := newScanCommand().Flags().Lookup("staged").Value.String(); staged != "false" {
+		t.Errorf("expected --staged=false for qodana scan got %s", staged)
+	}
+}
+
+func TestDepsAudit(t *testing.T) {
+	command := newDepsAuditCommand()
+	flags := command.Flags()
+	for name, expected := range map[string]string{
+		"disable-sanity": "true",
+		"run-promo":      "false",
+		"print-problems": "true",
+	} {
+		if flag := flags.Lookup(name); flag.Value.String() != expected || flag.Changed {
+			t.Errorf("expected the default --%s=%s got %s", name, expected, flag.Value)
+		}
+	}
+
+	projectDir := t.TempDir()
+	if err := flags.Parse([]string{"--project-dir", projectDir}); err != nil {
+		t.Fatal(err)
+	}
+	command.PreRun(command, nil)
+	profilePath := flags.Lookup("profile-path").Value.String()
+	if profilePath != filepath.Join(".qodana", "local", "dependencies-profile.yaml") {
+		t.Fatalf("unexpected profile path %s", profilePath)
+	}
+	data, err := os.ReadFile(filepath.Join(projectDir, profilePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "baseProfile: empty") || !strings.Contains(string(data), "inspection: VulnerableLibrariesLocal") {
+		t.Errorf("unexpected dependency profile:\n%s", data)
+	}
+}
+
+func TestEffectiveConfigOverrides(t *testing.T) {
+	t.Setenv(qdenv.QodanaDistEnv, "")
+	var options platformcmd.CliOptions
+	command := &cobra.Command{Use: "effective"}
+	if err := platformcmd.ComputeFlags(command, &options); err != nil {
+		t.Fatal(err)
+	}
+	err := command.ParseFlags(
+		[]string{
+			"--linter", "qodana-jvm",
+			"--profile-name", "qodana.starter",
+			"--fail-threshold", "10",
+			"--property", "idea.log=debug",
+			"--apply-fixes",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := effectiveConfigOverrides(command.Flags(), &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, override := range overrides {
+		actual = append(actual, fmt.Sprintf("%s=%v (%s)", strings.Join(override.Path, "."), override.Value, override.Source))
+	}
+	expected := []string{
+		"linter=qodana-jvm (--linter)",
+		"profile=map[name:qodana.starter] (--profile-name)",
+		"failThreshold=10 (--fail-threshold)",
+		"fixesStrategy=apply (--apply-fixes)",
+		"properties.idea.log=debug (--property)",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("effectiveConfigOverrides = %v, want %v", actual, expected)
+	}
+}
+
+func TestInitChoices(t *testing.T) {
+	projectDir := t.TempDir()
+	baseline := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := os.WriteFile(baseline, []byte(`{"runs":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	choices := initChoices{Profile: qdyaml.Profile{Name: "qodana.recommended"}, BaselinePath: baseline}
+
+	q := choices.apply(qdyaml.QodanaYaml{Linter: product.JvmLinter.Name, Profile: qdyaml.Profile{Name: "qodana.starter"}})
+	if q.Profile.Name != "qodana.recommended" || q.Linter != product.JvmLinter.Name {
+		t.Errorf("unexpected qodana.yaml after init: %+v", q)
+	}
+	if q = (initChoices{}).apply(q); q.Profile.Name != "qodana.recommended" {
+		t.Errorf("the profile is reset without a choice: %+v", q.Profile)
+	}
+
+	if err := choices.saveBaseline(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(projectDir, ".qodana", "baseline.sarif.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"runs":[]}` {
+		t.Errorf("unexpected baseline %s", data)
+	}
+}
+
+func TestInitCiWorkflows(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := validateCiWorkflows([]string{"github", "jenkins"}); err == nil {
+		t.Errorf("jenkins is accepted by --ci")
+	}
+	if err := os.MkdirAll(filepath.Join(projectDir, ".qodana"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, ".qodana", "baseline.sarif.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gitlabCi := filepath.Join(projectDir, ".gitlab-ci.yml")
+	if err := os.WriteFile(gitlabCi, []byte("existing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	params, err := newCiWorkflowParams(projectDir, qdyaml.QodanaYaml{Linter: product.JvmLinter.Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Image != product.JvmLinter.Image() || params.Baseline != ".qodana/baseline.sarif.json" {
+		t.Errorf("unexpected CI parameters %+v", params)
+	}
+	if err = writeCiWorkflows(projectDir, ciWorkflowNames(), params, false); err != nil {
+		t.Fatal(err)
+	}
+
+	github, err := os.ReadFile(filepath.Join(projectDir, ".github", "workflows", "qodana_code_quality.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"uses: JetBrains/qodana-action@v" + product.ReleaseVersion,
+		"args: --baseline,.qodana/baseline.sarif.json",
+		"QODANA_TOKEN: ${{ secrets.QODANA_TOKEN }}",
+	} {
+		if !strings.Contains(string(github), expected) {
+			t.Errorf("the GitHub workflow doesn't contain %q:\n%s", expected, github)
+		}
+	}
+	azure, err := os.ReadFile(filepath.Join(projectDir, "azure-pipelines.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(azure), "task: QodanaScan@"+params.TaskVersion) {
+		t.Errorf("the Azure pipeline doesn't run QodanaScan:\n%s", azure)
+	}
+	if data, _ := os.ReadFile(gitlabCi); string(data) != "existing\n" {
+		t.Errorf(".gitlab-ci.yml is replaced without force")
+	}
+
+	if err = writeCiWorkflows(projectDir, []string{"gitlab"}, params, true); err != nil {
+		t.Fatal(err)
+	}
+	gitlab, err := os.ReadFile(gitlabCi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gitlab), "name: "+params.Image) || !strings.Contains(string(gitlab), "--baseline=.qodana/baseline.sarif.json") {
+		t.Errorf("unexpected GitLab job:\n%s", gitlab)
+	}
+}
+
+func TestProfileInitCommand(t *testing.T) {
+	projectPath := t.TempDir()
+	err := os.WriteFile(filepath.Join(projectPath, "qodana.yaml"), []byte("version: \"1.0\"\nlinter: jetbrains/qodana-python:latest\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := newProfileCommand()
+	command.SetOut(bytes.NewBufferString(""))
+	command.SetArgs([]string{"init", "-i", projectPath, "--name", "team"})
+	if err = command.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectPath, ".qodana", "profiles", "team.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "#- inspection: PyUnresolvedReferences") {
+		t.Errorf("the profile doesn't list the Python inspections:\n%s", data)
+	}
+}
+
+func TestApplyScanPreset(t *testing.T) {
+	for _, env := range []string{"CI_MERGE_REQUEST_DIFF_BASE_SHA", "GITHUB_BASE_REF", "BITBUCKET_PR_DESTINATION_BRANCH", "CHANGE_TARGET", "SYSTEM_PULLREQUEST_TARGETBRANCH"} {
+		t.Setenv(env, "")
+	}
+	projectDir := t.TempDir()
+	qodanaYaml := "version: \"1.0\"\npreset: quick\nproperties:\n  idea.max.intellisense.filesize: 5000\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte(qodanaYaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cliOptions := &platformcmd.CliOptions{}
+	command := newScanCommandWithOptions(cliOptions)
+	if err := command.ParseFlags([]string{"--project-dir", projectDir, "--timeout", "60000"}); err != nil {
+		t.Fatal(err)
+	}
+	applyScanPresetOrFatal(command.Flags(), cliOptions)
+	if cliOptions.Preset != "quick" || !command.Flags().Changed("preset") {
+		t.Errorf("the preset of qodana.yaml is not selected: %q", cliOptions.Preset)
+	}
+	if !cliOptions.DisableSanity || cliOptions.RunPromo != "false" {
+		t.Errorf("the quick preset options are not applied: %+v", cliOptions)
+	}
+	if cliOptions.AnalysisTimeoutMs != 60000 {
+		t.Errorf("--timeout was overridden by the preset: %d", cliOptions.AnalysisTimeoutMs)
+	}
+	if len(cliOptions.Property) != 0 {
+		t.Errorf("the property of qodana.yaml was overridden by the preset: %v", cliOptions.Property)
+	}
+	expectedProfile := filepath.Join(".qodana", "local", "quick-profile.yaml")
+	if cliOptions.ProfilePath != expectedProfile {
+		t.Errorf("ProfilePath = %q, want %q", cliOptions.ProfilePath, expectedProfile)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, expectedProfile)); err != nil {
+		t.Errorf("the quick profile is not written: %s", err)
+	}
+	if cliOptions.DiffStart != "" {
+		t.Errorf("the scope is limited outside a pull request: %q", cliOptions.DiffStart)
+	}
+
+	cliOptions = &platformcmd.CliOptions{}
+	command = newScanCommandWithOptions(cliOptions)
+	err := command.ParseFlags([]string{"--project-dir", projectDir, "--preset", "full", "--profile-name", "custom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyScanPresetOrFatal(command.Flags(), cliOptions)
+	if cliOptions.ProfileName != "custom" || cliOptions.ProfilePath != "" || cliOptions.RunPromo != "true" {
+		t.Errorf("the full preset is not applied on top of the options: %+v", cliOptions)
+	}
+
+	if err = os.WriteFile(filepath.Join(projectDir, "qodana.yaml"), []byte("version: \"1.0\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cliOptions = &platformcmd.CliOptions{}
+	command = newScanCommandWithOptions(cliOptions)
+	if err = command.ParseFlags([]string{"--project-dir", projectDir, "--preset", "security"}); err != nil {
+		t.Fatal(err)
+	}
+	applyScanPresetOrFatal(command.Flags(), cliOptions)
+	_, enabled, err := inspections.ProfileInspections(filepath.Join(projectDir, cliOptions.ProfilePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(enabled, "JvmTaintAnalysis") || slices.Contains(enabled, "unused") {
+		t.Errorf("the security profile enables %v", enabled)
+	}
+}
+
+func TestPresetProperties(t *testing.T) {
+	preset := map[string]string{"a": "1", "b": "2", "c": "3"}
+	properties := presetProperties(preset, []string{"a=10"}, map[string]string{"b": "20"})
+	expected := []string{"a=10", "c=3"}
+	if strings.Join(properties, " ") != strings.Join(expected, " ") {
+		t.Errorf("presetProperties = %v, want %v", properties, expected)
+	}
+}
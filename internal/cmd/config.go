@@ -0,0 +1,221 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/effectiveconfig"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configOptions represents config validate command options.
+type configOptions struct {
+	ProjectDir string
+	ConfigName string
+}
+
+const (
+	yamlConfigFormat = "yaml"
+	jsonConfigFormat = "json"
+)
+
+// newConfigCommand returns a new instance of the config command.
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with the qodana.yaml configuration",
+	}
+	cmd.AddCommand(newConfigValidateCommand(), newConfigEffectiveCommand())
+	return cmd
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	cliOptions := &configOptions{}
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate qodana.yaml against its schema",
+		Long: `Validate qodana.yaml against the JSON schema of its version embedded into the CLI (supported versions: ` +
+			strings.Join(qdyaml.SchemaVersions, ", ") + `).
+
+Unknown keys and values of a wrong type are reported as errors, deprecated keys as warnings, with their line numbers.
+All documents of a multi-document file are validated. The command exits with code 1 if any error was found.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			path := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(cliOptions.ProjectDir, cliOptions.ConfigName)
+			if path == "" {
+				log.Fatalf("No qodana.yaml found in %s", cliOptions.ProjectDir)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatalf("Failed to read %s: %s", path, err)
+			}
+			problems, err := qdyaml.ValidateQodanaYaml(data)
+			if err != nil {
+				log.Fatalf("Failed to parse %s: %s", path, err)
+			}
+
+			errors := 0
+			for _, problem := range problems {
+				if problem.Severity == qdyaml.SchemaError {
+					errors++
+				}
+			}
+			if msg.IsJsonLog() {
+				msg.PrintJsonLog("configProblems", map[string]any{"path": path, "problems": problems})
+			} else {
+				for _, problem := range problems {
+					if problem.Severity == qdyaml.SchemaError {
+						msg.ErrorMessage("%s: %s", path, problem)
+					} else {
+						msg.WarningMessage("%s: %s", path, problem)
+					}
+				}
+				if errors == 0 {
+					msg.SuccessMessage("%s is valid", path)
+				}
+			}
+			if errors > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the project")
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	return cmd
+}
+
+func newConfigEffectiveCommand() *cobra.Command {
+	cliOptions := &platformcmd.CliOptions{}
+	format := yamlConfigFormat
+	cmd := &cobra.Command{
+		Use:   "effective",
+		Short: "Print the effective configuration with the source of every value",
+		Long: `Print the configuration a scan with the same options would use: the qodana.yaml of the global configuration,
+the files from "imports" and qodana.yaml merged in this order, with the values set by the command line options and
+the environment variables on top. Every value is annotated with its source: the file and the line, the option or the variable.
+
+Accepts the options of qodana scan. Use it to find out why a profile, an exclude or a threshold was not applied.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if format != yamlConfigFormat && format != jsonConfigFormat {
+				log.Fatalf("Unsupported format %q, the supported formats are %q and %q", format, yamlConfigFormat, jsonConfigFormat)
+			}
+			var names []string
+			for _, name := range []string{cliOptions.Linter, cliOptions.Ide, cliOptions.Image} {
+				if name != "" {
+					names = append(names, name)
+				}
+			}
+			overrides, err := effectiveConfigOverrides(cmd.Flags(), cliOptions)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fetchGlobalConfigurationsOrFatal(cliOptions)
+			annotated, err := effectiveconfig.Resolve(
+				cliOptions.ProjectDir,
+				qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(cliOptions.ProjectDir, cliOptions.ConfigName),
+				cliOptions.GlobalConfigurationsDir,
+				cliOptions.GlobalConfigurationId,
+				names,
+				overrides,
+			)
+			if err != nil {
+				log.Fatalf("Failed to resolve the effective configuration: %s", err)
+			}
+			var data []byte
+			if format == jsonConfigFormat {
+				data, err = annotated.Json()
+			} else {
+				data, err = annotated.Yaml()
+			}
+			if err != nil {
+				log.Fatalf("Failed to print the effective configuration: %s", err)
+			}
+			fmt.Println(strings.TrimSuffix(string(data), "\n"))
+		},
+	}
+	if err := platformcmd.ComputeFlags(cmd, cliOptions); err != nil {
+		return nil
+	}
+	cmd.Flags().StringVar(&format, "format", yamlConfigFormat, "Output format, can be yaml or json")
+	return cmd
+}
+
+// effectiveConfigOverrides returns the qodana.yaml values set by the options given on the command line and the
+// environment variables.
+func effectiveConfigOverrides(flags *pflag.FlagSet, o *platformcmd.CliOptions) ([]effectiveconfig.Override, error) {
+	var overrides []effectiveconfig.Override
+	set := func(flag string, value any, path ...string) {
+		if f := flags.Lookup(flag); f != nil && f.Changed {
+			overrides = append(overrides, effectiveconfig.Override{Path: path, Value: value, Source: "--" + flag})
+		}
+	}
+	set("linter", o.Linter, "linter")
+	set("image", o.Image, "image")
+	set("within-docker", o.WithinDocker, "withinDocker")
+	set("fallback-linter", o.FallbackLinter, "fallbackLinter")
+	if f := flags.Lookup("ide"); f != nil && !f.Changed && o.Ide != "" {
+		overrides = append(overrides, effectiveconfig.Override{Path: []string{"ide"}, Value: o.Ide, Source: qdenv.QodanaDistEnv})
+	}
+	set("ide", o.Ide, "ide")
+	set("profile-name", map[string]string{"name": o.ProfileName}, "profile")
+	set("profile-path", map[string]string{"path": o.ProfilePath}, "profile")
+	set("preset", o.Preset, "preset")
+	var failThreshold any = o.FailThreshold
+	if n, err := strconv.Atoi(o.FailThreshold); err == nil {
+		failThreshold = n
+	}
+	set("fail-threshold", failThreshold, "failThreshold")
+	if o.FailOn != "" {
+		thresholds, err := qdyaml.ParseSeverityThresholds(o.FailOn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --fail-on value: %w", err)
+		}
+		set("fail-on", thresholds, "failureConditions", "severityThresholds")
+	}
+	set("script", o.Script, "script", "name")
+	set("disable-sanity", o.DisableSanity, "disableSanityInspections")
+	set("run-promo", o.RunPromo, "runPromoInspections")
+	set("baseline-include-absent", o.BaselineIncludeAbsent, "includeAbsent")
+	set("fixes-strategy", o.FixesStrategy, "fixesStrategy")
+	set("apply-fixes", "apply", "fixesStrategy")
+	set("cleanup", "cleanup", "fixesStrategy")
+	set("solution", o.CdnetSolution, "dotnet", "solution")
+	set("project", o.CdnetProject, "dotnet", "project")
+	set("configuration", o.CdnetConfiguration, "dotnet", "configuration")
+	set("platform", o.CdnetPlatform, "dotnet", "platform")
+	for _, property := range o.Property {
+		if name, value, ok := strings.Cut(property, "="); ok {
+			set("property", value, "properties", name)
+		}
+	}
+	return overrides, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// dependenciesProfileFileName is the profile of qodana deps audit, written to the local state directory of the
+// project to be available to the linter in the container too.
+const dependenciesProfileFileName = "dependencies-profile.yaml"
+
+// newDepsCommand returns a new instance of the deps command.
+func newDepsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps",
+		Short: "Check the dependencies of the project",
+	}
+	if audit := newDepsAuditCommand(); audit != nil {
+		cmd.AddCommand(audit)
+	}
+	return cmd
+}
+
+// newDepsAuditCommand returns the scan command running only the vulnerability and the license checks of the
+// dependencies.
+func newDepsAuditCommand() *cobra.Command {
+	cliOptions := &platformcmd.CliOptions{}
+	c := newScanCommandWithOptions(cliOptions)
+	if c == nil {
+		return nil
+	}
+	c.Use = "audit"
+	c.Short = "Check the dependencies for known vulnerabilities and prohibited licenses"
+	c.Long = `Run only the dependency checks, the vulnerable dependencies and the license audit, without the inspection of the code:
+a fast dependency gate for every pull request. The results are written to qodana.sarif.json like the ones of "qodana scan".
+
+It is "qodana scan" with a profile enabling only the dependency inspections (written to .qodana/local/` + dependenciesProfileFileName + `),
+without the sanity checks and the promo inspections. All the options of "qodana scan" are accepted: the linter runs in a container
+or natively (--within-docker false), --profile-name and --profile-path replace the dependency profile, and --fail-on-license
+and --sbom-format work on the dependencies found.
+`
+	c.Example = `  # check the dependencies with the linter of qodana.yaml
+  qodana deps audit
+  # fail on the new vulnerable dependencies and the prohibited licenses
+  qodana deps audit --fail-threshold 0 --fail-on-license denylist.yaml`
+	defaults := map[string]string{
+		"disable-sanity": "true",
+		"run-promo":      "false",
+		"print-problems": "true",
+	}
+	for name, value := range defaults {
+		setFlagDefault(c.Flags(), name, value)
+	}
+	c.PreRun = func(cmd *cobra.Command, args []string) {
+		if cliOptions.ProfileName != "" || cliOptions.ProfilePath != "" {
+			return
+		}
+		profilePath, err := writeDependenciesProfile(cliOptions.ProjectDir)
+		if err != nil {
+			log.Fatalf("Failed to write the dependency profile: %s", err)
+		}
+		cliOptions.ProfilePath = profilePath
+	}
+	return c
+}
+
+// writeDependenciesProfile writes the profile of the dependency inspections to the project, it returns its path
+// relative to the project directory, the way --profile-path is resolved by the linters.
+func writeDependenciesProfile(projectDir string) (string, error) {
+	data, err := inspections.DependenciesProfile()
+	if err != nil {
+		return "", err
+	}
+	relativePath := filepath.Join(projectstate.DirName, projectstate.LocalDirName, dependenciesProfileFileName)
+	path := filepath.Join(projectDir, relativePath)
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	return relativePath, os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// diagnosticsOptions represents diagnostics command options.
+type diagnosticsOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	Follow     bool
+	Listen     string
+	Framing    string
+}
+
+// newDiagnosticsCommand returns a new instance of the diagnostics command.
+func newDiagnosticsCommand() *cobra.Command {
+	cliOptions := &diagnosticsOptions{}
+	cmd := &cobra.Command{
+		Use:   "diagnostics",
+		Short: "Stream the Qodana problems as Language Server Protocol diagnostics",
+		Long: `Print the problems of the latest Qodana results as Language Server Protocol textDocument/publishDiagnostics
+notifications, one per file with the file URI, the range, the severity and the inspection as the code, so the editors
+can show them inline without a Qodana plugin: e.g. a VS Code task or a Neovim job reading the output.
+
+The messages are written one per line, or with the Content-Length headers of LSP with --framing lsp. With --follow,
+the changes of the report written by a running analysis are published until Ctrl+C, and the files without problems
+anymore get empty diagnostics. With --listen, the diagnostics are served to the clients connected to the local
+address instead of stdout, every client gets the current ones first.`,
+		Example: `  qodana diagnostics --follow
+  qodana diagnostics --listen localhost:4711 --framing lsp`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := platform.ValidateDiagnosticsFraming(cliOptions.Framing); err != nil {
+				log.Fatal(err)
+			}
+			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+
+			commonCtx := commoncontext.Compute(
+				cliOptions.Linter,
+				"",
+				"",
+				"",
+				"",
+				cliOptions.ResultsDir,
+				"",
+				qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+				false,
+				cliOptions.ProjectDir,
+				"",
+				cliOptions.ConfigName,
+			)
+			stopGracefully := commoncontext.StopGracefullyOnInterrupt()
+			defer stopGracefully()
+			err := platform.StreamDiagnostics(
+				cmd.Context(),
+				os.Stdout,
+				platform.DiagnosticsOptions{
+					SarifPath:  platform.GetSarifPath(commonCtx.ResultsDir),
+					ProjectDir: commonCtx.ProjectDir,
+					Follow:     cliOptions.Follow,
+					Listen:     cliOptions.Listen,
+					Framing:    cliOptions.Framing,
+				},
+			)
+			if err != nil {
+				log.Fatalf("Cannot read the Qodana results from %s: %s", commonCtx.ResultsDir, err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&cliOptions.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.BoolVarP(
+		&cliOptions.Follow,
+		"follow",
+		"f",
+		false,
+		"Publish the changes of the report written by a running analysis until Ctrl+C",
+	)
+	flags.StringVar(
+		&cliOptions.Listen,
+		"listen",
+		"",
+		"Serve the diagnostics on the local address instead of stdout: localhost:<port> or unix:<socket path>",
+	)
+	flags.StringVar(
+		&cliOptions.Framing,
+		"framing",
+		platform.DiagnosticsFramingLines,
+		"Framing of the messages: lines (one JSON message per line) or lsp (Content-Length headers)",
+	)
+	return cmd
+}
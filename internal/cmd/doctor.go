@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/spf13/cobra"
+)
+
+// doctorOptions represents doctor command options.
+type doctorOptions struct {
+	CacheDir string
+}
+
+// newDoctorCommand returns a new instance of the doctor command.
+func newDoctorCommand() *cobra.Command {
+	cliOptions := &doctorOptions{}
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment Qodana runs in",
+		Long: `Check the environment Qodana runs in and print how to fix the found problems.
+
+The checks are: Docker or Podman availability and its memory limit, free disk space for the linters and caches,
+the Java runtime bundled into the CLI, the connection to Qodana Cloud (through the proxy from HTTPS_PROXY, if set) and the validity of QODANA_TOKEN.
+Attach the output to a support request, it contains no secrets. The command exits with code 1 if any check failed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+			token := qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken)
+			if token == "" {
+				token = os.Getenv(qdenv.QodanaLicenseOnlyToken)
+			}
+
+			checks := core.RunDoctor(cmd.Context(), commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir), token)
+			core.PrintDoctorChecks(checks)
+			if core.HasFailedDoctorChecks(checks) {
+				os.Exit(1)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&cliOptions.CacheDir, "cache-dir", "", "Override cache directory to check the free disk space of")
+	return cmd
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newEngineCommand returns a new instance of the engine command.
+func newEngineCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "engine",
+		Short: "Manage the container engine Qodana runs the linters in",
+	}
+	cmd.AddCommand(newEngineSetupCommand())
+	return cmd
+}
+
+func newEngineSetupCommand() *cobra.Command {
+	cliOptions := qdcontainer.DefaultLimaVmOptions()
+	cmd := &cobra.Command{
+		Use:   "setup",
+		Short: "Provision a Lima VM for the Qodana containers on macOS without Docker Desktop",
+		Long: `Provision a lightweight Lima VM with colima to run the Qodana containers on macOS without Docker Desktop.
+
+colima is installed with Homebrew if it's missing, after the confirmation. The VM runs Docker and is started with the given
+resources, or restarted if it has less CPUs or memory. Qodana connects to the VM socket by itself, set DOCKER_HOST to the
+printed socket for other tools. Run the command again with --memory to give the analysis more memory.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if runtime.GOOS != "darwin" {
+				msg.WarningMessage("The Lima VM is meant for macOS, Docker, Podman or containerd can run natively on %s", runtime.GOOS)
+			}
+			ctx := cmd.Context()
+			if err := qdcontainer.SetupLimaVm(ctx, cliOptions); err != nil {
+				log.Fatal(err)
+			}
+			printEngine(ctx)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&cliOptions.Profile, "profile", cliOptions.Profile, "colima profile of the VM, Qodana connects to the default one by itself")
+	flags.IntVar(&cliOptions.Cpus, "cpus", cliOptions.Cpus, "Number of CPUs of the VM")
+	flags.IntVar(&cliOptions.MemoryGb, "memory", cliOptions.MemoryGb, "Memory of the VM in GB, at least 4 GB are recommended for the analysis")
+	flags.IntVar(&cliOptions.DiskGb, "disk", cliOptions.DiskGb, "Disk size of the VM in GB, it can't be reduced later")
+	flags.StringVar(&cliOptions.VmType, "vm-type", "", "colima VM type: vz for Apple Virtualization on macOS 13 and later, or qemu")
+	return cmd
+}
+
+// printEngine prints the engine Qodana connects to and warns if it has too little memory for the analysis.
+func printEngine(ctx context.Context) {
+	docker, err := qdcontainer.NewContainerClient(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	info, err := docker.Info(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	msg.SuccessMessage("Qodana runs the containers at %s with %d CPUs and %d MB of memory", docker.DaemonHost(), info.NCPU, info.MemTotal/1024/1024)
+	if info.MemTotal < qdcontainer.RecommendedEngineMemory {
+		msg.WarningMessage("The container engine has less than 4 GB of memory. %s", qdcontainer.EngineMemoryHelp(info, runtime.GOOS))
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/internal/core"
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Modes of the fix command.
+const (
+	fixModeCleanup = "cleanup"
+	fixModeApply   = "apply"
+)
+
+// fixOptions represents fix command options.
+type fixOptions struct {
+	Mode   string
+	DryRun bool
+	Diff   string
+}
+
+// newFixCommand returns the scan command applying the quick-fixes.
+func newFixCommand() *cobra.Command {
+	cliOptions := &platformcmd.CliOptions{}
+	options := &fixOptions{}
+	c := newScanCommandWithOptions(cliOptions)
+	if c == nil {
+		return nil
+	}
+	c.Use = "fix"
+	c.Short = "Apply the Qodana quick-fixes to the project"
+	c.Long = `Run the analysis applying the quick-fixes of the found problems to the project: the cleanup fixes only (--mode cleanup)
+or all the available fixes (--mode apply). All the options of "qodana scan" are accepted.
+
+With --dry-run the changes made by the fixes are printed as a unified diff, with --diff they are saved to the file to be applied
+later with "git apply". In both cases the project is left unchanged: it must be in a git repository without uncommitted changes.
+Otherwise the changes are also saved to fixes.patch in the results directory, with --fixes-branch they are committed to a
+new branch.
+`
+	c.Example = `  # apply the cleanup fixes to the project
+  qodana fix
+  # preview all the available fixes
+  qodana fix --mode apply --dry-run
+  # save the fixes to a patch for review
+  qodana fix --mode apply --diff qodana-fixes.patch`
+	c.PreRun = func(cmd *cobra.Command, args []string) {
+		switch options.Mode {
+		case fixModeCleanup:
+			cliOptions.Cleanup = true
+		case fixModeApply:
+			cliOptions.ApplyFixes = true
+		default:
+			log.Fatalf("Unknown fix mode %s, supported modes: %s, %s", options.Mode, fixModeCleanup, fixModeApply)
+		}
+		if options.DryRun {
+			cliOptions.FixesDiff = core.FixesDiffStdout
+		} else if options.Diff != "" {
+			cliOptions.FixesDiff = options.Diff
+		}
+	}
+
+	flags := c.Flags()
+	flags.StringVar(
+		&options.Mode,
+		"mode",
+		fixModeCleanup,
+		"Quick-fixes to apply: cleanup (the cleanup fixes only) or apply (all the available fixes)",
+	)
+	flags.BoolVar(&options.DryRun, "dry-run", false, "Print the changes made by the quick-fixes as a diff, the project is left unchanged")
+	flags.StringVar(
+		&options.Diff,
+		"diff",
+		"",
+		"Save the changes made by the quick-fixes as a diff to the file, the project is left unchanged",
+	)
+	c.MarkFlagsMutuallyExclusive("dry-run", "diff", "fixes-branch")
+	for _, name := range []string{"apply-fixes", "cleanup"} {
+		if err := flags.MarkHidden(name); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return c
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// historyOptions represents history command options.
+type historyOptions struct {
+	ResultsDir string
+	CacheDir   string
+	Linter     string
+	ProjectDir string
+	ConfigName string
+}
+
+// newHistoryCommand returns a new instance of the history command.
+func newHistoryCommand() *cobra.Command {
+	cliOptions := &historyOptions{}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Inspect the runs kept with --keep-runs",
+		Long: `Inspect the runs of a project kept in the results directory by "qodana scan --keep-runs N".
+
+Each run is saved to <results-dir>/<time> and <results-dir>/latest links to the last one,
+the commands read the SARIF reports of the runs, so the trends are available without Qodana Cloud.
+
+"trend" prints a sparkline of the problems of each severity (or of the inspections with the most problems, --by inspection)
+from the first kept run to the last one and a bar of the total of each run, or saves an HTML page with a chart (--html).`,
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(
+		&cliOptions.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Results directory of the scans (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(&cliOptions.CacheDir, "cache-dir", "", "Cache directory of the scans, if it was overridden")
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	cmd.AddCommand(newHistoryListCommand(cliOptions), newHistoryTrendCommand(cliOptions))
+	return cmd
+}
+
+func newHistoryListCommand(cliOptions *historyOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the kept runs and the number of their problems by severity",
+		Run: func(cmd *cobra.Command, args []string) {
+			resultsDir := historyResultsDir(cliOptions)
+			runs, err := platform.LoadHistoryRuns(resultsDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if msg.IsJsonLog() {
+				msg.PrintJsonLog("history", map[string]any{"resultsDir": resultsDir, "runs": runs})
+				return
+			}
+			if len(runs) == 0 {
+				msg.SuccessMessage(
+					"No runs found in %s, run %s to keep them",
+					resultsDir,
+					msg.PrimaryBold("qodana scan --keep-runs N"),
+				)
+				return
+			}
+			latest := latestHistoryRun(resultsDir)
+			header := []string{msg.PrimaryBold("Run"), msg.PrimaryBold("Linter"), msg.PrimaryBold("Total")}
+			for _, severity := range platform.HistorySeverities {
+				header = append(header, msg.PrimaryBold(severity))
+			}
+			tableData := pterm.TableData{append(header, msg.PrimaryBold("New"))}
+			for _, run := range runs {
+				name := run.Name
+				if name == latest {
+					name += " (" + platform.LatestRunLink + ")"
+				}
+				row := []string{name, unknownIfEmpty(run.Linter)}
+				if !run.Complete {
+					row = append(row, "no report")
+					for range platform.HistorySeverities {
+						row = append(row, "")
+					}
+					tableData = append(tableData, append(row, ""))
+					continue
+				}
+				row = append(row, strconv.Itoa(run.Total()))
+				newProblems := 0
+				for _, severity := range platform.HistorySeverities {
+					row = append(row, strconv.Itoa(run.Problems[severity]))
+					newProblems += run.NewProblems[severity]
+				}
+				tableData = append(tableData, append(row, strconv.Itoa(newProblems)))
+			}
+			printTable(tableData)
+			msg.EmptyMessage()
+			fmt.Printf("%d runs in %s\n", len(runs), resultsDir)
+		},
+	}
+}
+
+func newHistoryTrendCommand(cliOptions *historyOptions) *cobra.Command {
+	by, top, htmlPath := platform.TrendBySeverity, 10, ""
+	cmd := &cobra.Command{
+		Use:   "trend",
+		Short: "Show the number of the problems across the kept runs by severity or by inspection",
+		Run: func(cmd *cobra.Command, args []string) {
+			resultsDir := historyResultsDir(cliOptions)
+			runs, err := platform.LoadHistoryRuns(resultsDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			trend, err := platform.BuildTrend(runs, by, top)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if htmlPath != "" {
+				if err = writeTrendHtml(trend, htmlPath); err != nil {
+					log.Fatalf("Failed to write %s: %s", htmlPath, err)
+				}
+				msg.SuccessMessage("Saved the trend of %d runs to %s", len(trend.Runs), htmlPath)
+				return
+			}
+			if msg.IsJsonLog() {
+				msg.PrintJsonLog("historyTrend", map[string]any{"resultsDir": resultsDir, "trend": trend})
+				return
+			}
+			if len(trend.Runs) == 0 {
+				msg.SuccessMessage(
+					"No runs with a report found in %s, run %s to keep them",
+					resultsDir,
+					msg.PrimaryBold("qodana scan --keep-runs N"),
+				)
+				return
+			}
+			fmt.Print(trend.Text())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&by, "by", by, "Group the problems by severity or by inspection")
+	flags.IntVar(
+		&top,
+		"top",
+		top,
+		"Number of the inspections with the most problems shown with --by inspection, the rest are shown as 'other'",
+	)
+	flags.StringVar(
+		&htmlPath,
+		"html",
+		"",
+		"Save the trend as an HTML page with a chart to the given file instead of printing it",
+	)
+	return cmd
+}
+
+func writeTrendHtml(trend platform.Trend, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = trend.WriteHtml(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// historyResultsDir finds the results directory of the linter run on the project, the way the scan does.
+func historyResultsDir(cliOptions *historyOptions) string {
+	if cliOptions.ResultsDir != "" {
+		return cliOptions.ResultsDir
+	}
+	qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+	return commoncontext.Compute(
+		cliOptions.Linter,
+		"",
+		"",
+		"",
+		cliOptions.CacheDir,
+		"",
+		"",
+		qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+		false,
+		cliOptions.ProjectDir,
+		"",
+		cliOptions.ConfigName,
+	).ResultsDir
+}
+
+// latestHistoryRun returns the name of the run the latest symlink points to.
+func latestHistoryRun(resultsDir string) string {
+	target, err := os.Readlink(filepath.Join(resultsDir, platform.LatestRunLink))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
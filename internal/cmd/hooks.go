@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// hookScripts are the bodies of the hooks written by qodana hooks install.
+var hookScripts = map[string]string{
+	// the staged changes are the content of the commit
+	"pre-commit": "exec qodana precommit\n",
+	// the commits not pushed yet, nothing is checked when the branch has no upstream
+	"pre-push": `upstream=$(git rev-parse --verify --quiet '@{push}') || exit 0
+exec qodana scan --diff-start "$upstream" --print-problems --save-report=false
+`,
+}
+
+// hooksOptions represents hooks command options.
+type hooksOptions struct {
+	ProjectDir string
+	Hooks      []string
+	Force      bool
+}
+
+// newHooksCommand returns a new instance of the hooks command.
+func newHooksCommand() *cobra.Command {
+	cliOptions := &hooksOptions{}
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage the git hooks running Qodana",
+	}
+	cmd.AddCommand(newHooksInstallCommand(cliOptions))
+	return cmd
+}
+
+func newHooksInstallCommand(cliOptions *hooksOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Write the git hooks running Qodana on commit or push",
+		Long: `Write the git hooks running Qodana to the hooks directory of the repository (core.hooksPath is respected).
+
+The pre-commit hook runs "qodana precommit" on the staged changes, the pre-push hook runs "qodana scan" on the changes
+not pushed to the upstream branch yet. An existing hook not written by this command is overwritten only with --force.`,
+		Example: `  # check the staged changes before every commit
+  qodana hooks install
+  # check the commits before they are pushed as well
+  qodana hooks install --hook pre-commit,pre-push`,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, hook := range cliOptions.Hooks {
+				script, ok := hookScripts[hook]
+				if !ok {
+					log.Fatalf("Unknown hook %s, supported hooks: %s", hook, strings.Join(hookNames(), ", "))
+				}
+				path, err := git.InstallHook(cliOptions.ProjectDir, hook, script, cliOptions.Force, "")
+				if err != nil {
+					log.Fatalf("Failed to install the %s hook: %s", hook, err)
+				}
+				msg.SuccessMessage("Installed the %s hook to %s", hook, path)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the project")
+	flags.StringSliceVar(
+		&cliOptions.Hooks,
+		"hook",
+		[]string{"pre-commit"},
+		fmt.Sprintf("Hooks to install: %s", strings.Join(hookNames(), ", ")),
+	)
+	flags.BoolVar(&cliOptions.Force, "force", false, "Overwrite the existing hooks")
+	return cmd
+}
+
+func hookNames() []string {
+	names := make([]string, 0, len(hookScripts))
+	for name := range hookScripts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
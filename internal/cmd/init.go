@@ -19,6 +19,7 @@ package cmd
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
@@ -37,8 +38,18 @@ func newInitCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Configure a project for Qodana",
-		Long:  `Configure a project for Qodana: prepare Qodana configuration file by analyzing the project structure and generating a default configuration qodana.yaml file.`,
+		Long: `Configure a project for Qodana: prepare Qodana configuration file by analyzing the project structure and generating a default configuration qodana.yaml file.
+
+In an interactive terminal, the detected languages are shown with the linters supporting them, and after the linter is selected
+you are asked for the inspection profile, a SARIF report to use as the baseline (saved to .qodana/baseline.sarif.json)
+and whether to link the project to Qodana Cloud. The written qodana.yaml explains its keys in comments.
+
+With --ci, the CI pipeline running Qodana with the configured linter, the caches and the baseline is also generated:
+a GitHub Actions workflow (github), a .gitlab-ci.yml job (gitlab) or an azure-pipelines.yml task (azure).`,
 		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateCiWorkflows(cliOptions.CiWorkflows); err != nil {
+				log.Fatal(err)
+			}
 			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
 
 			localQodanaYamlFullPath := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(
@@ -68,13 +79,18 @@ func newInitCommand() *cobra.Command {
 				}
 				token := qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken)
 				analyzer := commoncontext.SelectAnalyzerForPath(cliOptions.ProjectDir, token)
+				choices := askInitChoices(cliOptions.ProjectDir)
 
 				writeQodanaLinterToYamlFileWithWarning(
 					localQodanaYamlFullPath,
 					analyzer,
+					choices,
 				)
+				if err := choices.saveBaseline(cliOptions.ProjectDir); err != nil {
+					msg.ErrorMessage("%s", err)
+				}
 
-				checkToken(analyzer, cliOptions)
+				checkToken(analyzer, cliOptions, choices.LinkCloud)
 			} else {
 				msg.EmptyMessage()
 				var analyzer string
@@ -94,6 +110,9 @@ func newInitCommand() *cobra.Command {
 					msg.SuccessMessage("The .NET configuration was successfully set")
 				}
 			}
+			if len(cliOptions.CiWorkflows) > 0 {
+				writeCiWorkflowsOrFatal(cliOptions, localQodanaYamlFullPath)
+			}
 			msg.PrintFile(localQodanaYamlFullPath)
 		},
 	}
@@ -112,36 +131,57 @@ func newInitCommand() *cobra.Command {
 		"",
 		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
 	)
+	flags.StringSliceVar(
+		&cliOptions.CiWorkflows,
+		"ci",
+		nil,
+		fmt.Sprintf("Generate the CI pipeline running Qodana, one or more of: %s", strings.Join(ciWorkflowNames(), ", ")),
+	)
 	return cmd
 }
 
-func checkToken(analyser product.Analyzer, cliOptions *initOptions) {
+// checkToken validates the Qodana Cloud token when the linter requires it or the project is linked to Qodana Cloud.
+func checkToken(analyser product.Analyzer, cliOptions *initOptions, linkCloud bool) {
 	commonCtx := commoncontext.Context{
 		Analyzer:    analyser,
 		ProjectDir:  cliOptions.ProjectDir,
 		QodanaToken: qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
 	}
-	if tokenloader.IsCloudTokenRequired(commonCtx) {
+	if linkCloud || tokenloader.IsCloudTokenRequired(commonCtx) {
 		tokenloader.ValidateCloudToken(commonCtx, cliOptions.Force)
 	}
 }
 
-// WriteQodanaLinterToYamlFile adds the linter to the qodana.yaml file, also adds warning about sensetive information
-func writeQodanaLinterToYamlFileWithWarning(qodanaYamlFullPath string, analyser product.Analyzer) {
+// WriteQodanaLinterToYamlFile adds the linter and the answers of the interactive init to the qodana.yaml file, also
+// adds warning about sensetive information and comments explaining the keys
+func writeQodanaLinterToYamlFileWithWarning(qodanaYamlFullPath string, analyser product.Analyzer, choices initChoices) {
 	q := qdyaml.LoadQodanaYamlByFullPath(qodanaYamlFullPath)
 	if q.Version == "" {
 		q.Version = "1.0"
 	}
 	q.Sort()
 	q = analyser.InitYaml(q)
-	err := q.WriteConfigWithWarning(qodanaYamlFullPath)
+	q = choices.apply(q)
+	err := q.WriteConfigWithComments(qodanaYamlFullPath)
 	if err != nil {
 		log.Fatalf("writeConfig: %v", err)
 	}
 }
 
+// writeCiWorkflowsOrFatal generates the CI pipelines requested with --ci for the linter configured in qodana.yaml.
+func writeCiWorkflowsOrFatal(cliOptions *initOptions, qodanaYamlFullPath string) {
+	params, err := newCiWorkflowParams(cliOptions.ProjectDir, qdyaml.LoadQodanaYamlByFullPath(qodanaYamlFullPath))
+	if err != nil {
+		log.Fatalf("Failed to generate the CI pipelines: %s", err)
+	}
+	if err = writeCiWorkflows(cliOptions.ProjectDir, cliOptions.CiWorkflows, params, cliOptions.Force); err != nil {
+		log.Fatalf("Failed to generate the CI pipelines: %s", err)
+	}
+}
+
 type initOptions struct {
-	ProjectDir string
-	ConfigName string
-	Force      bool
+	ProjectDir  string
+	ConfigName  string
+	Force       bool
+	CiWorkflows []string
 }
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+)
+
+const githubWorkflowTemplate = `# Generated by qodana init --ci github, the linter ({{.Linter}}) and the inspections are configured in qodana.yaml
+# Add the project token from Qodana Cloud as the QODANA_TOKEN repository secret
+name: Qodana
+on:
+  workflow_dispatch:
+  pull_request:
+  push:
+    branches:
+      - main
+
+jobs:
+  qodana:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+      pull-requests: write
+      checks: write
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          ref: ${{"{{"}} github.event.pull_request.head.sha {{"}}"}}
+          fetch-depth: 0
+      - name: Qodana Scan
+        uses: JetBrains/qodana-action@v{{.Version}}
+        with:
+          use-caches: true
+{{- if .Baseline}}
+          args: --baseline,{{.Baseline}}
+{{- end}}
+        env:
+          QODANA_TOKEN: ${{"{{"}} secrets.QODANA_TOKEN {{"}}"}}
+`
+
+const gitlabJobTemplate = `# Generated by qodana init --ci gitlab, the inspections are configured in qodana.yaml
+# Add the project token from Qodana Cloud as the masked qodana_token CI/CD variable
+qodana:
+  image:
+    name: {{.Image}}
+    entrypoint: [""]
+  cache:
+    - key: qodana-{{.Version}}-$CI_DEFAULT_BRANCH-$CI_COMMIT_REF_SLUG
+      fallback_keys:
+        - qodana-{{.Version}}-$CI_DEFAULT_BRANCH-
+        - qodana-{{.Version}}-
+      paths:
+        - .qodana/cache
+  variables:
+    QODANA_TOKEN: $qodana_token
+  script:
+    - qodana --cache-dir=$CI_PROJECT_DIR/.qodana/cache --results-dir=$CI_PROJECT_DIR/.qodana/results{{if .Baseline}} --baseline={{.Baseline}}{{end}}
+  artifacts:
+    paths:
+      - .qodana/results
+    expose_as: Qodana report
+`
+
+const azureTaskTemplate = `# Generated by qodana init --ci azure, the linter ({{.Linter}}) and the inspections are configured in qodana.yaml
+# Add the project token from Qodana Cloud as the secret QODANA_TOKEN pipeline variable
+trigger:
+  - main
+
+pool:
+  vmImage: ubuntu-latest
+
+variables:
+  - name: QODANA_CACHE
+    value: $(Pipeline.Workspace)/.qodana/cache
+
+steps:
+  - task: Cache@2
+    displayName: Cache Qodana
+    inputs:
+      key: '"qodana-{{.Version}}" | "$(Build.SourceBranch)" | "$(Build.SourceVersion)"'
+      restoreKeys: |
+        "qodana-{{.Version}}" | "$(Build.SourceBranch)"
+        "qodana-{{.Version}}"
+      path: $(QODANA_CACHE)
+  - task: QodanaScan@{{.TaskVersion}}
+    inputs:
+      uploadResult: true
+{{- if .Baseline}}
+      args: --baseline,{{.Baseline}}
+{{- end}}
+    env:
+      QODANA_TOKEN: $(QODANA_TOKEN)
+`
+
+// ciWorkflow is a CI pipeline file generated by qodana init --ci.
+type ciWorkflow struct {
+	path     string
+	template string
+}
+
+// ciWorkflows are the CI pipeline files qodana init --ci can generate, by the CI name.
+var ciWorkflows = map[string]ciWorkflow{
+	"github": {filepath.Join(".github", "workflows", "qodana_code_quality.yml"), githubWorkflowTemplate},
+	"gitlab": {".gitlab-ci.yml", gitlabJobTemplate},
+	"azure":  {"azure-pipelines.yml", azureTaskTemplate},
+}
+
+// ciWorkflowNames returns the names accepted by --ci.
+func ciWorkflowNames() []string {
+	var names []string
+	for name := range ciWorkflows {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ciWorkflowParams are the values the CI pipeline files are generated with.
+type ciWorkflowParams struct {
+	Linter      string
+	Image       string
+	Version     string
+	TaskVersion string
+	Baseline    string
+}
+
+// validateCiWorkflows returns an error if any of the CI names is unknown.
+func validateCiWorkflows(names []string) error {
+	for _, name := range names {
+		if _, ok := ciWorkflows[name]; !ok {
+			return fmt.Errorf("unknown CI %q, supported: %s", name, strings.Join(ciWorkflowNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// newCiWorkflowParams returns the parameters of the CI pipelines for the linter configured in qodana.yaml, the
+// baseline is passed when the project has .qodana/baseline.sarif.json.
+func newCiWorkflowParams(projectDir string, q qdyaml.QodanaYaml) (ciWorkflowParams, error) {
+	linter := configuredLinter(q)
+	if linter == product.UnknownLinter {
+		return ciWorkflowParams{}, errors.New("no linter is configured in qodana.yaml")
+	}
+	params := ciWorkflowParams{
+		Linter:      linter.Name,
+		Image:       linter.Image(),
+		Version:     product.ReleaseVersion,
+		TaskVersion: strings.Split(product.ReleaseVersion, ".")[0],
+	}
+	if strings.Contains(q.Linter, ":") {
+		params.Image = q.Linter
+	}
+	baseline := filepath.Join(projectstate.DirName, projectstate.BaselineFileName)
+	if _, err := os.Stat(filepath.Join(projectDir, baseline)); err == nil {
+		params.Baseline = filepath.ToSlash(baseline)
+	}
+	return params, nil
+}
+
+// configuredLinter returns the linter configured in qodana.yaml by the linter name, the image or the ide product code.
+func configuredLinter(q qdyaml.QodanaYaml) product.Linter {
+	linter := findLinter(q.Linter)
+	if linter == product.UnknownLinter && q.Ide != "" {
+		linter = product.FindLinterByProductCode(q.Ide)
+	}
+	return linter
+}
+
+// findLinter returns the linter by its name or its image.
+func findLinter(nameOrImage string) product.Linter {
+	linter := product.FindLinterByName(nameOrImage)
+	if linter == product.UnknownLinter {
+		linter = product.FindLinterByImage(nameOrImage)
+	}
+	return linter
+}
+
+// writeCiWorkflows generates the pipeline files of the CIs in the project, the existing files are only replaced
+// when force is set.
+func writeCiWorkflows(projectDir string, names []string, params ciWorkflowParams, force bool) error {
+	for _, name := range names {
+		workflow := ciWorkflows[name]
+		path := filepath.Join(projectDir, workflow.path)
+		if _, err := os.Stat(path); err == nil && !force {
+			msg.WarningMessage(
+				"%s already exists, run the command with %s to replace it",
+				workflow.path,
+				msg.PrimaryBold("-f"),
+			)
+			continue
+		}
+		tmpl, err := template.New(name).Parse(workflow.template)
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err = tmpl.Execute(&out, params); err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+			return err
+		}
+		msg.SuccessMessage("The %s pipeline is written to %s", name, workflow.path)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+)
+
+const (
+	starterProfileChoice     = "qodana.starter - the most important checks, a good start"
+	recommendedProfileChoice = "qodana.recommended - the checks of the default IDE profile"
+	customProfileChoice      = "A custom profile file"
+)
+
+// initChoices are the answers given in the interactive qodana init.
+type initChoices struct {
+	Profile      qdyaml.Profile
+	BaselinePath string
+	LinkCloud    bool
+}
+
+// askInitChoices asks for the profile, the baseline and whether to link the project to Qodana Cloud, after the
+// linter was selected. Nothing is asked in a non-interactive terminal.
+func askInitChoices(projectDir string) initChoices {
+	choices := initChoices{}
+	if !msg.IsInteractive() {
+		return choices
+	}
+
+	switch msg.AskUserSelect(
+		"Select the inspection profile",
+		[]string{starterProfileChoice, recommendedProfileChoice, customProfileChoice},
+	) {
+	case starterProfileChoice:
+		choices.Profile.Name = "qodana.starter"
+	case recommendedProfileChoice:
+		choices.Profile.Name = "qodana.recommended"
+	case customProfileChoice:
+		choices.Profile.Path = msg.AskUserInput("Path to the profile (.xml or .yaml), relative to the project directory", "")
+	}
+
+	for {
+		choices.BaselinePath = msg.AskUserInput(
+			"Path to a SARIF report to use as the baseline, the problems from it are not reported as new (leave empty to skip)",
+			"",
+		)
+		if choices.BaselinePath == "" || isFile(resolveInitPath(projectDir, choices.BaselinePath)) {
+			break
+		}
+		msg.ErrorMessage("%s is not a file", choices.BaselinePath)
+	}
+
+	choices.LinkCloud = msg.AskUserConfirm("Do you want to link the project to Qodana Cloud to see the reports there")
+	return choices
+}
+
+// apply sets the answers in qodana.yaml.
+func (c initChoices) apply(q qdyaml.QodanaYaml) qdyaml.QodanaYaml {
+	if c.Profile.Name != "" || c.Profile.Path != "" {
+		q.Profile = c.Profile
+	}
+	return q
+}
+
+// saveBaseline copies the baseline to .qodana/baseline.sarif.json, the baseline used when --baseline isn't given.
+func (c initChoices) saveBaseline(projectDir string) error {
+	if c.BaselinePath == "" {
+		return nil
+	}
+	if err := projectstate.Init(projectDir); err != nil {
+		return err
+	}
+	target := filepath.Join(projectstate.Dir(projectDir), projectstate.BaselineFileName)
+	if err := fs.CopyFile(resolveInitPath(projectDir, c.BaselinePath), target); err != nil {
+		return fmt.Errorf("failed to copy the baseline to %s: %w", target, err)
+	}
+	msg.SuccessMessage("The baseline is saved to %s, commit it with qodana.yaml", target)
+	return nil
+}
+
+func resolveInitPath(projectDir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(projectDir, path)
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
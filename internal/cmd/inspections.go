@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// inspectionsOptions represents inspections command options.
+type inspectionsOptions struct {
+	ProjectDir string
+	ConfigName string
+	Linter     string
+	Image      string
+	Ide        string
+	Json       bool
+	Output     string
+}
+
+// newInspectionsCommand returns a new instance of the inspections command.
+func newInspectionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspections",
+		Short: "Work with the inspections of the linters",
+	}
+	cmd.AddCommand(newInspectionsListCommand())
+	return cmd
+}
+
+func newInspectionsListCommand() *cobra.Command {
+	cliOptions := &inspectionsOptions{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the inspections of the linter with their IDs, names, severities and descriptions",
+		Long: `List all inspections of the linter, extracted from the IDE in the linter image (pulled if needed) or from the
+IDE installed in --ide: the inspection IDs to use in profiles and in include/exclude of qodana.yaml, their names,
+default severities, categories and descriptions.
+
+The linter is the one configured in qodana.yaml, or set with --linter or --image. Use --json to print the catalog as JSON,
+or --output to export it to a file.`,
+		Example: `  # export the inspections of the linter of qodana.yaml
+  qodana inspections list --output inspections.json
+  # list the inspections of a local IDE installation
+  qodana inspections list --ide /opt/idea --linter qodana-jvm`,
+		Run: func(cmd *cobra.Command, args []string) {
+			linter, image := inspectionsLinter(cliOptions)
+			var catalog inspections.Catalog
+			var err error
+			if cliOptions.Ide != "" {
+				msg.PrintProcess(
+					func(_ *pterm.SpinnerPrinter) {
+						catalog, err = inspections.CatalogFromIde(linter, cliOptions.Ide)
+					},
+					fmt.Sprintf("Reading the inspections of %s", msg.PrimaryBold(cliOptions.Ide)),
+					"",
+				)
+			} else {
+				if image == "" {
+					log.Fatalf("Unknown linter, set it in qodana.yaml or with --linter (one of %s)", strings.Join(product.AllNames, ", "))
+				}
+				msg.PrintProcess(
+					func(_ *pterm.SpinnerPrinter) {
+						catalog, err = core.ImageInspections(context.Background(), linter, image)
+					},
+					fmt.Sprintf("Reading the inspections of %s", msg.PrimaryBold(image)),
+					"",
+				)
+			}
+			if err != nil {
+				log.Fatalf("Failed to read the inspections: %s", err)
+			}
+
+			if cliOptions.Json || cliOptions.Output != "" {
+				data, err := json.MarshalIndent(catalog, "", "  ")
+				if err != nil {
+					log.Fatal(err)
+				}
+				if cliOptions.Output == "" {
+					fmt.Println(string(data))
+					return
+				}
+				if err = os.WriteFile(cliOptions.Output, append(data, '\n'), 0o644); err != nil {
+					log.Fatalf("Failed to write %s: %s", cliOptions.Output, err)
+				}
+				msg.SuccessMessage("%d inspections are written to %s", len(catalog.Inspections), cliOptions.Output)
+				return
+			}
+			tableData := pterm.TableData{[]string{"ID", "NAME", "SEVERITY", "CATEGORY"}}
+			for _, inspection := range catalog.Inspections {
+				tableData = append(
+					tableData,
+					[]string{inspection.Id, inspection.Name, inspection.Severity, inspection.Category},
+				)
+			}
+			printTable(tableData)
+			msg.SuccessMessage("%s inspections", strconv.Itoa(len(catalog.Inspections)))
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the project")
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Linter name or image, the linter from qodana.yaml is used by default")
+	flags.StringVar(&cliOptions.Image, "image", "", "Linter image to read the inspections from")
+	flags.StringVar(&cliOptions.Ide, "ide", "", "Path to an installed IDE to read the inspections from instead of the image")
+	flags.BoolVar(&cliOptions.Json, "json", false, "Print the result as JSON")
+	flags.StringVarP(&cliOptions.Output, "output", "o", "", "Write the result as JSON to the file")
+	return cmd
+}
+
+// inspectionsLinter returns the linter and its image: set by --image or --linter, or configured in qodana.yaml.
+func inspectionsLinter(cliOptions *inspectionsOptions) (product.Linter, string) {
+	if cliOptions.Image != "" {
+		return product.FindLinterByImage(cliOptions.Image), cliOptions.Image
+	}
+	nameOrImage := cliOptions.Linter
+	if nameOrImage == "" {
+		path := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(cliOptions.ProjectDir, cliOptions.ConfigName)
+		if path != "" {
+			q := qdyaml.LoadQodanaYamlByFullPath(path)
+			nameOrImage = q.Linter
+			if nameOrImage == "" && q.Ide != "" {
+				linter := product.FindLinterByProductCode(q.Ide)
+				return linter, linter.Image()
+			}
+		}
+	}
+	linter := findLinter(nameOrImage)
+	if strings.Contains(nameOrImage, ":") || strings.Contains(nameOrImage, "/") {
+		return linter, nameOrImage
+	}
+	if linter == product.UnknownLinter {
+		return linter, ""
+	}
+	return linter, linter.Image()
+}
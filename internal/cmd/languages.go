@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// languagesOptions represents languages command options.
+type languagesOptions struct {
+	ProjectDir string
+	Json       bool
+}
+
+// newLanguagesCommand returns a new instance of the languages command.
+func newLanguagesCommand() *cobra.Command {
+	options := &languagesOptions{}
+	cmd := &cobra.Command{
+		Use:   "languages",
+		Short: "Show the languages, frameworks and recommended linters detected in the project",
+		Long: `Show what "qodana init" detects in the project to select the linter: the programming languages with their files and lines,
+the build systems and frameworks, and the recommended linters.
+
+The vendored, generated, documentation and configuration files are not counted. Use --json to process the output with other tools.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			languages, err := commoncontext.DetectProjectLanguages(options.ProjectDir)
+			if err != nil {
+				log.Fatalf("Failed to detect the languages of %s: %s", options.ProjectDir, err)
+			}
+			if options.Json {
+				data, err := json.MarshalIndent(languages, "", "  ")
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(string(data))
+				return
+			}
+			if len(languages.Languages) == 0 {
+				msg.WarningMessage("No technologies detected (no source code files?)")
+				return
+			}
+			tableData := pterm.TableData{[]string{"LANGUAGE", "FILES", "LINES"}}
+			for _, language := range languages.Languages {
+				tableData = append(
+					tableData,
+					[]string{language.Language, strconv.Itoa(language.Files), strconv.Itoa(language.Lines)},
+				)
+			}
+			printTable(tableData)
+			if len(languages.Frameworks) > 0 {
+				msg.SuccessMessage("Frameworks: %s", strings.Join(languages.Frameworks, ", "))
+			}
+			linters := make([]string, 0, len(languages.Linters))
+			for _, linter := range languages.Linters {
+				linters = append(linters, fmt.Sprintf("%s (%s)", linter.PresentableName, linter.Name))
+			}
+			msg.SuccessMessage("Recommended linters: %s", strings.Join(linters, ", "))
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project")
+	flags.BoolVar(&options.Json, "json", false, "Print the result as JSON")
+	return cmd
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// mergeSarifOptions represents merge-sarif command options.
+type mergeSarifOptions struct {
+	Output string
+}
+
+// newMergeSarifCommand returns a new instance of the merge-sarif command.
+func newMergeSarifCommand() *cobra.Command {
+	options := &mergeSarifOptions{}
+	cmd := &cobra.Command{
+		Use:   "merge-sarif <sarif-file>... -o <output>",
+		Short: "Merge SARIF files into one",
+		Long: `Merge the SARIF files of several scans (e.g. of the projects in a monorepo) into a single report.
+
+The runs of the same linter are merged into one run with the rules of all reports, the runs of different linters are kept.
+Every result gets a "mergedIndicator/v1" partial fingerprint computed across the reports, the results found by several scans are kept once.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			report, duplicates, err := platform.MergeSarifFiles(args)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err = platform.WriteReport(options.Output, report); err != nil {
+				log.Fatal(err)
+			}
+			problems := 0
+			for _, run := range report.Runs {
+				problems += len(run.Results)
+			}
+			msg.SuccessMessage(
+				"Merged %d reports into %s: %d runs, %d problems, %d duplicates removed",
+				len(args),
+				options.Output,
+				len(report.Runs),
+				problems,
+				duplicates,
+			)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Output, "output", "o", "", "Path to the merged SARIF file")
+	if err := cmd.MarkFlagRequired("output"); err != nil {
+		log.Fatal(err)
+	}
+	return cmd
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// notifyOptions represents notify command options.
+type notifyOptions struct {
+	CacheDir string
+	Flush    bool
+}
+
+// newNotifyCommand returns a new instance of the notify command.
+func newNotifyCommand() *cobra.Command {
+	cliOptions := &notifyOptions{}
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Show or deliver the queued webhook notifications",
+		Long: `Show the webhook notifications (see "qodana scan --webhook") that were not delivered, or deliver them with --flush.
+
+Undelivered notifications are kept for 7 days and retried on every run with webhooks, at most 100 of them are kept.
+With --flush, the command exits with code 1 if some notifications are still not delivered.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			systemDir := commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir)
+			if !cliOptions.Flush {
+				queue, err := platform.ReadWebhookQueue(systemDir)
+				if err != nil {
+					log.Fatalf("Failed to read the webhook queue: %s", err)
+				}
+				if len(queue) == 0 {
+					msg.SuccessMessage("No undelivered webhook notifications")
+					return
+				}
+				for _, event := range queue {
+					msg.WarningMessage(
+						"%s, created at %s, %d attempts: %s",
+						event.Url,
+						event.CreatedAt.Format("2006-01-02 15:04:05"),
+						event.Attempts,
+						event.LastError,
+					)
+				}
+				return
+			}
+
+			delivered, pending, err := platform.FlushWebhooks(systemDir)
+			if err != nil {
+				log.Fatalf("Failed to save the webhook queue: %s", err)
+			}
+			msg.SuccessMessage("Delivered %d webhook notifications", delivered)
+			if pending > 0 {
+				msg.ErrorMessage("%d webhook notifications are still not delivered", pending)
+				os.Exit(1)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVar(&cliOptions.Flush, "flush", false, "Deliver the queued notifications now")
+	flags.StringVar(&cliOptions.CacheDir, "cache-dir", "", "Override cache directory the queue is kept in")
+	return cmd
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// precommitTimeoutMs is the default time budget of qodana precommit.
+const precommitTimeoutMs = 3 * 60 * 1000
+
+// newPrecommitCommand returns the scan command with the defaults of the fast pre-commit check.
+func newPrecommitCommand() *cobra.Command {
+	c := newScanCommand()
+	if c == nil {
+		return nil
+	}
+	c.Use = "precommit"
+	c.Short = "Scan the changes staged for commit"
+	c.Long = `Scan only the files with the changes staged in git, the fast check run by the hook written with "qodana hooks install".
+
+It is "qodana scan" with different defaults: --staged, the problems are printed, no HTML report is generated and the analysis
+is limited to 3 minutes. If the time limit is reached, the commit isn't blocked (--timeout-exit-code 0).
+All the options of "qodana scan" are accepted. The staged changes are analysed only in the native mode.
+`
+	defaults := map[string]string{
+		"staged":            "true",
+		"print-problems":    "true",
+		"save-report":       "false",
+		"timeout":           strconv.Itoa(precommitTimeoutMs),
+		"timeout-exit-code": "0",
+	}
+	for name, value := range defaults {
+		setFlagDefault(c.Flags(), name, value)
+	}
+	return c
+}
+
+// setFlagDefault changes the default value of the flag, the flag isn't marked as set on the command line.
+func setFlagDefault(flags *pflag.FlagSet, name string, value string) {
+	flag := flags.Lookup(name)
+	if flag == nil {
+		log.Fatalf("Unknown flag %s", name)
+	}
+	if err := flag.Value.Set(value); err != nil {
+		log.Fatalf("Invalid default %s of the flag %s: %s", value, name, err)
+	}
+	flag.DefValue = value
+}
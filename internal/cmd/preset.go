@@ -0,0 +1,204 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// scanPreset bundles the options of a scan tuned for speed or depth.
+type scanPreset struct {
+	description string
+	// flags are the values of the options not given on the command line.
+	flags map[string]string
+	// properties are the linter properties not set with --property or in qodana.yaml.
+	properties map[string]string
+	// profileName is the profile used when neither the options nor qodana.yaml set one.
+	profileName string
+	// profile returns the profile.yaml written to the project when neither the options nor qodana.yaml set one.
+	profile func() ([]byte, error)
+	// pullRequestScope limits the analysis to the changes of the pull request when no scope is given.
+	pullRequestScope bool
+}
+
+// scanPresets are the presets selected with --preset or "preset:" in qodana.yaml.
+var scanPresets = map[string]scanPreset{
+	"quick": {
+		description: "the starter profile without the slow global inspections on the changes of the pull request",
+		flags: map[string]string{
+			"disable-sanity": "true",
+			"run-promo":      "false",
+			"timeout":        "900000",
+		},
+		properties: map[string]string{
+			"idea.max.intellisense.filesize": "1000",
+		},
+		profile:          inspections.QuickProfile,
+		pullRequestScope: true,
+	},
+	"full": {
+		description: "the recommended profile with the promo inspections on the whole project",
+		flags: map[string]string{
+			"run-promo": "true",
+		},
+		profileName: "qodana.recommended",
+	},
+	"security": {
+		description: "the security inspections on the whole project, classified by CWE and OWASP Top 10",
+		flags: map[string]string{
+			"disable-sanity": "true",
+			"run-promo":      "false",
+		},
+		profile: inspections.SecurityProfile,
+	},
+}
+
+// scopeFlags are the options selecting the files to analyze, the quick preset doesn't change the scope given by them.
+var scopeFlags = []string{"commit", "diff-start", "diff-end", "staged", "full-history", "script", "force-local-changes-script"}
+
+// scanPresetNames returns the names of the presets in the alphabetical order.
+func scanPresetNames() []string {
+	var names []string
+	for name := range scanPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyScanPresetOrFatal applies the preset of --preset, or of "preset:" in qodana.yaml, to the options not given on
+// the command line. The profile, the properties and the script of qodana.yaml are kept too.
+func applyScanPresetOrFatal(flags *pflag.FlagSet, cliOptions *platformcmd.CliOptions) {
+	qodanaYaml := qdyaml.LoadQodanaYamlByFullPath(
+		qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(cliOptions.ProjectDir, cliOptions.ConfigName),
+	)
+	name := cliOptions.Preset
+	if name == "" {
+		name = qodanaYaml.Preset
+	}
+	if name == "" {
+		return
+	}
+	preset, ok := scanPresets[name]
+	if !ok {
+		log.Fatalf("Unknown preset %q, use one of %s", name, strings.Join(scanPresetNames(), ", "))
+	}
+	if !flags.Changed("preset") {
+		// the projects of a monorepo and the reruns get the preset of the root qodana.yaml on the command line
+		if err := flags.Set("preset", name); err != nil {
+			log.Fatal(err)
+		}
+	}
+	msg.SuccessMessage("Using the %s preset: %s", name, preset.description)
+
+	for flagName, value := range preset.flags {
+		if !flags.Changed(flagName) {
+			setFlagDefault(flags, flagName, value)
+		}
+	}
+	cliOptions.Property = presetProperties(preset.properties, cliOptions.Property, qodanaYaml.Properties)
+
+	if cliOptions.ProfileName == "" && cliOptions.ProfilePath == "" && qodanaYaml.Profile.Name == "" && qodanaYaml.Profile.Path == "" {
+		if preset.profile != nil {
+			profilePath, err := writePresetProfile(cliOptions.ProjectDir, name, preset.profile)
+			if err != nil {
+				log.Fatalf("Failed to write the profile of the %s preset: %s", name, err)
+			}
+			cliOptions.ProfilePath = profilePath
+		} else {
+			cliOptions.ProfileName = preset.profileName
+		}
+	}
+
+	if preset.pullRequestScope && !slices.ContainsFunc(scopeFlags, flags.Changed) && qodanaYaml.Script.Name == "" {
+		if base := pullRequestBase(cliOptions.ProjectDir); base != "" {
+			log.Debugf("The %s preset analyzes the changes since %s", name, base)
+			cliOptions.DiffStart = base
+		}
+	}
+}
+
+// presetProperties returns the properties with the ones of the preset not set on the command line or in qodana.yaml.
+func presetProperties(preset map[string]string, properties []string, yamlProperties map[string]string) []string {
+	var names []string
+	for name := range preset {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if _, ok := yamlProperties[name]; ok {
+			continue
+		}
+		if slices.ContainsFunc(properties, func(p string) bool { return strings.HasPrefix(p, name+"=") }) {
+			continue
+		}
+		properties = append(properties, name+"="+preset[name])
+	}
+	return properties
+}
+
+// writePresetProfile writes the profile of the preset to the local state directory of the project, it returns its
+// path relative to the project directory.
+func writePresetProfile(projectDir string, name string, profile func() ([]byte, error)) (string, error) {
+	data, err := profile()
+	if err != nil {
+		return "", err
+	}
+	relativePath := filepath.Join(projectstate.DirName, projectstate.LocalDirName, fmt.Sprintf("%s-profile.yaml", name))
+	path := filepath.Join(projectDir, relativePath)
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	return relativePath, os.WriteFile(path, data, 0o644)
+}
+
+// pullRequestBase returns the commit the pull request built on CI started from, empty outside a pull request or if
+// the target branch is not fetched.
+func pullRequestBase(projectDir string) string {
+	if sha := os.Getenv("CI_MERGE_REQUEST_DIFF_BASE_SHA"); sha != "" {
+		return sha
+	}
+	target := ""
+	for _, env := range []string{"GITHUB_BASE_REF", "BITBUCKET_PR_DESTINATION_BRANCH", "CHANGE_TARGET", "SYSTEM_PULLREQUEST_TARGETBRANCH"} {
+		if target = os.Getenv(env); target != "" {
+			break
+		}
+	}
+	if target == "" {
+		return ""
+	}
+	ref := "origin/" + strings.TrimPrefix(target, "refs/heads/")
+	base, err := git.MergeBase(projectDir, ref, "HEAD", "")
+	if err != nil {
+		log.Warnf("The whole project is analyzed, %s is not fetched: %s", ref, err)
+		return ""
+	}
+	return base
+}
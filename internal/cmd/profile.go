@@ -0,0 +1,251 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// profileOptions represents profile command options.
+type profileOptions struct {
+	ProjectDir string
+	ConfigName string
+	Linter     string
+	Report     string
+}
+
+// newProfileCommand returns a new instance of the profile command.
+func newProfileCommand() *cobra.Command {
+	cliOptions := &profileOptions{}
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Create, list and check inspection profiles",
+		Long: `Work with the inspection profiles of the project.
+
+The inspections of the linter (configured in qodana.yaml or set with --linter) are taken from --report, a qodana.sarif.json
+produced by the linter that lists all its inspections, or from the catalog bundled into the CLI with the most common ones.`,
+		Example: `  # generate .qodana/profiles/qodana-profile.yaml extending qodana.starter
+  qodana profile init
+  # list the inspections of the last run of the linter
+  qodana profile list --report .qodana/results/qodana.sarif.json
+  # check the profile and the include/exclude inspections of qodana.yaml
+  qodana profile show --report .qodana/results/qodana.sarif.json`,
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the project")
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Linter name or image, the linter from qodana.yaml is used by default")
+	flags.StringVar(&cliOptions.Report, "report", "", "qodana.sarif.json of the linter to take the inspections from")
+	cmd.AddCommand(
+		newProfileInitCommand(cliOptions),
+		newProfileListCommand(cliOptions),
+		newProfileShowCommand(cliOptions),
+	)
+	return cmd
+}
+
+func newProfileInitCommand(cliOptions *profileOptions) *cobra.Command {
+	format := inspections.YamlFormat
+	name := "qodana-profile"
+	baseProfile := "qodana.starter"
+	output := ""
+	force := false
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Generate a starter profile.yaml or XML inspection profile",
+		Long: `Generate a starter inspection profile in .qodana/profiles: a profile.yaml extending --base with the inspections
+of the linter listed to be enabled or disabled, or (with --format xml) an XML profile enabling them.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			catalog := loadInspectionsCatalogOrFatal(cliOptions)
+			data, err := inspections.NewProfile(format, name, baseProfile, catalog)
+			if err != nil {
+				log.Fatal(err)
+			}
+			path := output
+			if path == "" {
+				path = filepath.Join(projectstate.DirName, "profiles", name+"."+format)
+			}
+			fullPath := resolveInitPath(cliOptions.ProjectDir, path)
+			if _, err = os.Stat(fullPath); err == nil && !force {
+				log.Fatalf("%s already exists, run the command with -f to replace it", fullPath)
+			}
+			if err = os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+				log.Fatal(err)
+			}
+			if err = os.WriteFile(fullPath, data, 0o644); err != nil {
+				log.Fatalf("Failed to write %s: %s", fullPath, err)
+			}
+			msg.SuccessMessage(
+				"The profile is written to %s, reference it in qodana.yaml with %s",
+				fullPath,
+				msg.PrimaryBold(fmt.Sprintf("profile.path: %s", filepath.ToSlash(path))),
+			)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&format, "format", format, "Profile format: yaml or xml")
+	flags.StringVar(&name, "name", name, "Profile name")
+	flags.StringVar(&baseProfile, "base", baseProfile, "Profile the yaml profile extends")
+	flags.StringVarP(&output, "output", "o", "", "Profile path relative to the project directory (default .qodana/profiles/<name>.<format>)")
+	flags.BoolVarP(&force, "force", "f", false, "Replace the existing profile")
+	return cmd
+}
+
+func newProfileListCommand(cliOptions *profileOptions) *cobra.Command {
+	asJson := false
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the inspections available to the linter",
+		Run: func(cmd *cobra.Command, args []string) {
+			catalog := loadInspectionsCatalogOrFatal(cliOptions)
+			if asJson {
+				data, err := json.MarshalIndent(catalog, "", "  ")
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(string(data))
+				return
+			}
+			tableData := pterm.TableData{[]string{"ID", "NAME", "SEVERITY", "CATEGORY"}}
+			for _, inspection := range catalog.Inspections {
+				tableData = append(
+					tableData,
+					[]string{inspection.Id, inspection.Name, inspection.Severity, inspection.Category},
+				)
+			}
+			printTable(tableData)
+			if !catalog.Complete {
+				msg.WarningMessage(
+					"The bundled catalog lists the most common inspections of %s only, use %s for the full list",
+					catalog.Linter,
+					msg.PrimaryBold("--report <qodana.sarif.json>"),
+				)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&asJson, "json", false, "Print the result as JSON")
+	return cmd
+}
+
+func newProfileShowCommand(cliOptions *profileOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show the profile configured in qodana.yaml and check its references",
+		Long: `Show the profile configured in qodana.yaml and check it: a profile name must be built-in (` +
+			strings.Join(inspections.BuiltinProfiles, ", ") + `) or defined in .idea/inspectionProfiles,
+a profile path must be a readable .yaml or .xml profile. With --report, the inspections referenced by the profile and
+by include/exclude are checked against the inspections of the linter. The command exits with code 1 if any error was found.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			path := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(cliOptions.ProjectDir, cliOptions.ConfigName)
+			if path == "" {
+				log.Fatalf("No qodana.yaml found in %s", cliOptions.ProjectDir)
+			}
+			q := qdyaml.LoadQodanaYamlByFullPath(path)
+			catalog, err := loadInspectionsCatalog(cliOptions)
+			if err != nil {
+				if cliOptions.Report != "" {
+					log.Fatal(err)
+				}
+				log.Debugf("The inspections are not checked: %s", err)
+			}
+			problems := inspections.ValidateProfile(cliOptions.ProjectDir, q, catalog)
+
+			errors := 0
+			for _, problem := range problems {
+				if problem.Error {
+					errors++
+				}
+			}
+			if msg.IsJsonLog() {
+				msg.PrintJsonLog("profile", map[string]any{"path": path, "profile": q.Profile, "problems": problems})
+			} else {
+				switch {
+				case q.Profile.Path != "":
+					msg.SuccessMessage("Profile: %s", msg.PrimaryBold(q.Profile.Path))
+				case q.Profile.Name != "":
+					msg.SuccessMessage("Profile: %s", msg.PrimaryBold(q.Profile.Name))
+				default:
+					msg.SuccessMessage("No profile is set in %s, the linter uses qodana.starter", path)
+				}
+				for _, problem := range problems {
+					if problem.Error {
+						msg.ErrorMessage("%s: %s", path, problem.Message)
+					} else {
+						msg.WarningMessage("%s: %s", path, problem.Message)
+					}
+				}
+			}
+			if errors > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// loadInspectionsCatalogOrFatal returns the inspections of the linter from --report or the bundled catalog.
+func loadInspectionsCatalogOrFatal(cliOptions *profileOptions) inspections.Catalog {
+	catalog, err := loadInspectionsCatalog(cliOptions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return catalog
+}
+
+func loadInspectionsCatalog(cliOptions *profileOptions) (inspections.Catalog, error) {
+	linter := findLinter(cliOptions.Linter)
+	if cliOptions.Linter == "" {
+		path := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(cliOptions.ProjectDir, cliOptions.ConfigName)
+		if path != "" {
+			linter = configuredLinter(qdyaml.LoadQodanaYamlByFullPath(path))
+		}
+	}
+	if linter == product.UnknownLinter {
+		return inspections.Catalog{}, fmt.Errorf(
+			"unknown linter, set it in qodana.yaml or with --linter (one of %s)",
+			strings.Join(product.AllNames, ", "),
+		)
+	}
+	var catalog inspections.Catalog
+	var err error
+	if cliOptions.Report != "" {
+		catalog, err = inspections.CatalogFromSarif(linter, cliOptions.Report)
+	} else {
+		catalog, err = inspections.BundledCatalog(linter)
+	}
+	if err != nil {
+		return inspections.Catalog{}, fmt.Errorf("failed to read the inspections of %s: %w", linter.Name, err)
+	}
+	return catalog, nil
+}
@@ -18,7 +18,10 @@ package cmd
 
 import (
 	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/core/startup"
+	"github.com/JetBrains/qodana-cli/internal/platform"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
@@ -30,41 +33,69 @@ import (
 func newPullCommand() *cobra.Command {
 	cliOptions := &pullOptions{}
 	cmd := &cobra.Command{
-		Use:   "pull",
+		Use:   "pull [linter or image...]",
 		Short: "Pull latest version of linter",
-		Long:  `An alternative to pull an image.`,
+		Long: `Pull the linter images ahead of the analysis, e.g. to bake them into a CI image or to prepare an air-gapped bundle.
+
+Without arguments, the linter of the project is pulled: the one from qodana.yaml, --linter or --image, or the detected one.
+The arguments are linter names (qodana-jvm) or images (jetbrains/qodana-jvm:2024.3), --all-free adds all free linters
+and --for-project the linters recommended for the languages of the project. Several images are pulled in parallel.
+With --native, the IDE distributions of the linters are downloaded for the native mode instead of the images.`,
+		Example: `  # pull the linter of the project in the current directory
+  qodana pull
+  # pull several linters
+  qodana pull qodana-jvm qodana-python jetbrains/qodana-go:2024.3
+  # pull all free linters for a CI image, 4 at a time
+  qodana pull --all-free --parallel 4
+  # pull the linters for every language of the project
+  qodana pull --for-project -i ./project
+  # download the IDE of the native mode
+  qodana pull qodana-jvm --native`,
 		Run: func(cmd *cobra.Command, args []string) {
 			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
 
-			commonCtx := commoncontext.Compute(
-				cliOptions.Linter,
-				"",
+			targets := resolvePullTargets(cliOptions, args)
+			if cliOptions.Native {
+				downloadNativeIdes(targets)
+				return
+			}
+			images := targets.AllImages()
+			if len(images) == 0 {
+				log.Println("Native mode is used, skipping pull")
+				return
+			}
+			platform.SetupRegistryCredentialsOrFatal(
+				cliOptions.Registry,
 				cliOptions.Image,
-				"true",
-				"",
-				"",
-				"",
-				qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
-				false,
-				cliOptions.ProjectDir,
-				"",
-				cliOptions.ConfigName,
+				cliOptions.RegistryUsername,
+				cliOptions.RegistryPasswordStdin,
 			)
-			analyzer, ok := commonCtx.Analyzer.(*product.DockerAnalyzer)
-			if !ok {
-				log.Println("Native mode is used, skipping pull")
+			qdcontainer.PrepareContainerEnvSettings()
+			client, err := qdcontainer.NewContainerClient(cmd.Context())
+			if err != nil {
+				log.Fatalf("Failed to initialize Docker API: %s", err)
+			}
+			for _, image := range images {
+				core.CheckImage(image)
+			}
+			if len(images) == 1 {
+				err = core.PullImage(cmd.Context(), client, images[0])
 			} else {
-				qdcontainer.PrepareContainerEnvSettings()
-				client, err := qdcontainer.NewContainerClient(cmd.Context())
-				if err != nil {
-					log.Fatalf("Failed to initialize Docker API: %s", err)
-				}
-
-				core.CheckImage(analyzer.Image)
-				core.PullImage(client, analyzer.Image)
+				err = core.PullImages(cmd.Context(), client, images, cliOptions.Parallel)
+			}
+			if err != nil {
+				log.Fatal(err)
 			}
 		},
 	}
+	addPullTargetFlags(cmd, cliOptions)
+	cmd.Flags().BoolVar(&cliOptions.Native, "native", false, "Download the IDE distributions of the linters for the native mode instead of the images")
+	cmd.MarkFlagsMutuallyExclusive("image", "native")
+	return cmd
+}
+
+// addPullTargetFlags adds the flags selecting the linters and images of qodana pull and qodana bundle create.
+func addPullTargetFlags(cmd *cobra.Command, cliOptions *pullOptions) {
 	flags := cmd.Flags()
 	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Override linter to use")
 	flags.StringVarP(&cliOptions.Image, "image", "", "", "Image to pull")
@@ -75,7 +106,27 @@ func newPullCommand() *cobra.Command {
 		"",
 		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
 	)
-	return cmd
+	flags.BoolVar(&cliOptions.AllFree, "all-free", false, "Pull the images of all free linters")
+	flags.BoolVar(&cliOptions.ForProject, "for-project", false, "Pull the images of all linters recommended for the languages of the project")
+	flags.IntVar(&cliOptions.Parallel, "parallel", 3, "Number of images pulled at the same time")
+	flags.StringVar(
+		&cliOptions.Registry,
+		"registry",
+		"",
+		"Registry host the credentials of --registry-username are sent to, QODANA_REGISTRY or the registry of --image by default",
+	)
+	flags.StringVar(
+		&cliOptions.RegistryUsername,
+		"registry-username",
+		"",
+		"Username of the registry the images are pulled from, QODANA_REGISTRY_USERNAME by default",
+	)
+	flags.BoolVar(
+		&cliOptions.RegistryPasswordStdin,
+		"registry-password-stdin",
+		false,
+		"Read the password or the token of --registry-username from stdin, QODANA_REGISTRY_PASSWORD is used otherwise",
+	)
 }
 
 type pullOptions struct {
@@ -83,4 +134,81 @@ type pullOptions struct {
 	Image      string
 	ProjectDir string
 	ConfigName string
+	AllFree    bool
+	ForProject bool
+	Native     bool
+	Parallel   int
+	// Registry, RegistryUsername and RegistryPasswordStdin are the credentials of the registry the images are pulled from.
+	Registry              string
+	RegistryUsername      string
+	RegistryPasswordStdin bool
+}
+
+// resolvePullTargets returns the linters and images of the arguments and the flags, the linter of the project if none
+// are given.
+func resolvePullTargets(cliOptions *pullOptions, args []string) core.PullTargets {
+	if len(args) == 0 && !cliOptions.AllFree && !cliOptions.ForProject {
+		return projectPullTargets(cliOptions)
+	}
+	projectDir := ""
+	if cliOptions.ForProject {
+		projectDir = cliOptions.ProjectDir
+	}
+	targets, err := core.ResolvePullTargets(args, cliOptions.AllFree, projectDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return targets
+}
+
+// projectPullTargets returns the linter of the project: from qodana.yaml, the options or the detected one. Nothing
+// is returned for a project analyzed in the native mode, unless its IDE is downloaded with --native.
+func projectPullTargets(cliOptions *pullOptions) core.PullTargets {
+	commonCtx := commoncontext.Compute(
+		cliOptions.Linter,
+		"",
+		cliOptions.Image,
+		"true",
+		"",
+		"",
+		"",
+		qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+		false,
+		cliOptions.ProjectDir,
+		"",
+		cliOptions.ConfigName,
+	)
+	switch analyzer := commonCtx.Analyzer.(type) {
+	case *product.DockerAnalyzer:
+		if cliOptions.Native {
+			return core.PullTargets{Linters: []product.Linter{analyzer.Linter}}
+		}
+		return core.PullTargets{Images: []string{analyzer.Image}}
+	case *product.NativeAnalyzer:
+		if cliOptions.Native {
+			return core.PullTargets{Linters: []product.Linter{analyzer.Linter}}
+		}
+		return core.PullTargets{}
+	default:
+		return core.PullTargets{}
+	}
+}
+
+// downloadNativeIdes downloads the IDE distributions of the linters supporting the native mode.
+func downloadNativeIdes(targets core.PullTargets) {
+	if qdenv.IsOffline() {
+		log.Fatal("The IDE distributions can't be downloaded in the offline mode")
+	}
+	for _, image := range targets.Images {
+		msg.WarningMessage("Skipping %s, only the IDEs of the linters can be downloaded with --native", image)
+	}
+	systemDir := commoncontext.ComputeQodanaSystemDir("")
+	for _, linter := range targets.Linters {
+		if !linter.SupportNative {
+			msg.WarningMessage("Skipping %s, it doesn't support the native mode", linter.Name)
+			continue
+		}
+		ideDir := startup.DownloadIde(linter.NativeAnalyzer(), systemDir)
+		msg.SuccessMessage("Downloaded %s to %s", linter.Name, ideDir)
+	}
 }
@@ -0,0 +1,257 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/redact"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// replayedScanCommand is the command of the scans qodana replay can run again.
+const replayedScanCommand = "qodana scan"
+
+// replayQodanaYamlFileName is the qodana.yaml of the replayed scan, written to the local state directory of the
+// project to be available to the linter in the container too.
+const replayQodanaYamlFileName = "replay-qodana.yaml"
+
+// replaySkippedFlags are the paths of the machine the scan ran on, the replay uses its own ones.
+var replaySkippedFlags = map[string]bool{
+	"project-dir":     true,
+	"repository-root": true,
+	"results-dir":     true,
+	"cache-dir":       true,
+	"report-dir":      true,
+	"config":          true,
+}
+
+// replayImageFlags select the linter, they are replaced with the image digest of the scan.
+var replayImageFlags = map[string]bool{
+	"linter":          true,
+	"ide":             true,
+	"image":           true,
+	"fallback-linter": true,
+}
+
+// replayOptions represents replay command options.
+type replayOptions struct {
+	ProjectDir string
+	ResultsDir string
+}
+
+// newReplayCommand returns a new instance of the replay command.
+func newReplayCommand() *cobra.Command {
+	cliOptions := &replayOptions{}
+	cmd := &cobra.Command{
+		Use:   "replay <run-metadata.json>",
+		Short: "Run a previous scan again from its run-metadata.json",
+		Long: `Run a scan again with the image digest, the qodana.yaml and the options recorded in its run-metadata.json,
+e.g. downloaded from the CI artifacts, to reproduce and debug the differences between the CI and the local results.
+
+The project in the project directory should be checked out at the revision of the scan, a warning is printed otherwise.
+The masked secrets are taken from the environment. When the scan finishes, the differences from the recorded scan
+(the CLI and linter versions, the image, the revision, the configuration, the environment and the exit code) are printed.
+The command exits with the exit code of the replayed scan.`,
+		Example: `  qodana replay ./ci-results/run-metadata.json
+  qodana replay run-metadata.json --project-dir ~/src/project --results-dir /tmp/replay`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			metadata, err := platform.ReadRunMetadata(args[0])
+			if err != nil {
+				log.Fatalf("Failed to read the run metadata: %s", err)
+			}
+			if metadata.Command != replayedScanCommand {
+				log.Fatalf("%s was not written by %s, it can't be replayed", args[0], replayedScanCommand)
+			}
+			resultsDir := cliOptions.ResultsDir
+			if resultsDir == "" {
+				resultsDir = filepath.Join(filepath.Dir(args[0]), "replay")
+			}
+			if resultsDir, err = filepath.Abs(resultsDir); err != nil {
+				log.Fatal(err)
+			}
+			projectDir, err := filepath.Abs(cliOptions.ProjectDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			checkReplayRevision(projectDir, metadata.Revision)
+
+			configPath, err := writeReplayQodanaYaml(projectDir, replayQodanaYaml(metadata.QodanaYaml))
+			if err != nil {
+				log.Fatalf("Failed to write the configuration of the scan: %s", err)
+			}
+
+			exitCode := runReplay(replayScanArgs(metadata, projectDir, resultsDir, configPath))
+			if err = os.Remove(filepath.Join(projectDir, configPath)); err != nil {
+				log.Debugf("Failed to remove %s: %s", configPath, err)
+			}
+			current, err := platform.ReadRunMetadata(filepath.Join(resultsDir, platform.RunMetadataFileName))
+			if err != nil {
+				msg.WarningMessage("The replayed scan didn't write %s: %s", platform.RunMetadataFileName, err)
+			} else if changes := platform.RunMetadataDrift(metadata, current); len(changes) > 0 {
+				msg.WarningMessage("The replayed scan differs from the recorded one:\n  %s", strings.Join(changes, "\n  "))
+			} else {
+				msg.SuccessMessage("The scan was replayed with the same versions, image, revision, configuration and environment")
+			}
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the project to analyze")
+	flags.StringVarP(
+		&cliOptions.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Directory to save the results of the replayed scan to (default <run-metadata.json directory>/replay)",
+	)
+	return cmd
+}
+
+// recordedScanArgs returns the options of qodana scan for run-metadata.json, the secrets masked.
+func recordedScanArgs(flags *pflag.FlagSet) []string {
+	args := setFlagArgs(flags, nil)
+	for i, arg := range args {
+		if variable, ok := strings.CutPrefix(arg, "--env="); ok {
+			args[i] = "--env=" + redact.Env(variable)
+		} else {
+			args[i] = redact.String(arg)
+		}
+	}
+	return args
+}
+
+// recordedQodanaYaml returns the qodana.yaml of the project for run-metadata.json, the secrets masked.
+func recordedQodanaYaml(projectDir string, configName string) string {
+	path := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(projectDir, configName)
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debugf("Failed to read %s for %s: %s", path, platform.RunMetadataFileName, err)
+		return ""
+	}
+	return redact.String(string(data))
+}
+
+// replayQodanaYaml returns the qodana.yaml for the replayed scan, the scan without one is replayed with an empty
+// configuration rather than with the qodana.yaml of the project.
+func replayQodanaYaml(recorded string) string {
+	if recorded == "" {
+		return "version: \"1.0\"\n"
+	}
+	if strings.Contains(recorded, redact.Mask) {
+		msg.WarningMessage("qodana.yaml of the scan has masked secrets, the replayed scan can differ")
+	}
+	return recorded
+}
+
+// writeReplayQodanaYaml writes the qodana.yaml of the replayed scan to the project, it returns its path relative to
+// the project directory, the way --config is resolved.
+func writeReplayQodanaYaml(projectDir string, data string) (string, error) {
+	relativePath := filepath.Join(projectstate.DirName, projectstate.LocalDirName, replayQodanaYamlFileName)
+	path := filepath.Join(projectDir, relativePath)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	return relativePath, os.WriteFile(path, []byte(data), 0o600)
+}
+
+// replayScanArgs returns the arguments of qodana scan running the recorded scan again: the paths are replaced with
+// the local ones, the linter with the image digest of the scan, and the masked environment variables are taken from
+// the environment.
+func replayScanArgs(metadata platform.RunMetadata, projectDir string, resultsDir string, configPath string) []string {
+	args := []string{"scan", "--project-dir=" + projectDir, "--results-dir=" + resultsDir, "--config=" + configPath}
+	pinned := strings.Contains(metadata.ImageDigest, "@")
+	if pinned {
+		args = append(args, "--image="+metadata.ImageDigest)
+	} else if metadata.ImageDigest != "" {
+		msg.WarningMessage("The image of the scan %s was never pushed, the linter is selected as in the scan", metadata.ImageDigest)
+	}
+	for _, arg := range metadata.Args {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if replaySkippedFlags[name] || (pinned && replayImageFlags[name]) {
+			continue
+		}
+		if name == "env" {
+			if variable, masked := strings.CutSuffix(value, "="+redact.Mask); masked {
+				env, ok := os.LookupEnv(variable)
+				if !ok {
+					msg.WarningMessage("%s is masked in the run metadata and not set, it's not passed to the scan", variable)
+					continue
+				}
+				arg = "--env=" + variable + "=" + env
+			}
+		} else if strings.Contains(value, redact.Mask) {
+			msg.WarningMessage("--%s is masked in the run metadata, it's not passed to the scan", name)
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// checkReplayRevision warns if the project is not checked out at the revision of the scan.
+func checkReplayRevision(projectDir string, revision string) {
+	if revision == "" {
+		return
+	}
+	current, err := git.CurrentRevision(projectDir, "")
+	if err != nil {
+		msg.WarningMessage("Failed to get the revision of %s, the scan was run on %s: %s", projectDir, revision, err)
+		return
+	}
+	if current != revision {
+		msg.WarningMessage("The scan was run on revision %s, run 'git checkout %s' to analyze the same code", revision, revision)
+	}
+}
+
+// runReplay runs qodana scan with the arguments, it returns its exit code.
+func runReplay(args []string) int {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the qodana executable: %s", err)
+	}
+	log.Debugf("Running %s %s", executable, redact.String(strings.Join(args, " ")))
+	command := exec.Command(executable, args...)
+	command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		msg.ErrorMessage("Failed to replay the scan: %s", fmt.Sprint(err))
+		return 1
+	}
+	return 0
+}
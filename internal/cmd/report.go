@@ -0,0 +1,238 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	markdownReportFormat = "markdown"
+	csvReportFormat      = "csv"
+	xlsxReportFormat     = "xlsx"
+	pdfReportFormat      = "pdf"
+)
+
+// reportFileNames are the default names of the exported reports in the results directory.
+var reportFileNames = map[string]string{
+	markdownReportFormat: "qodana-report.md",
+	csvReportFormat:      "qodana-report.csv",
+	xlsxReportFormat:     "qodana-report.xlsx",
+	pdfReportFormat:      "qodana-report.pdf",
+}
+
+// reportExportOptions represents report export command options.
+type reportExportOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	Format     string
+	DiffWith   string
+	Output     string
+	Top        int
+}
+
+// newReportCommand returns a new instance of the report command.
+func newReportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Convert the Qodana results to other formats",
+	}
+	cmd.AddCommand(newReportExportCommand(), newReportIndexCommand())
+	return cmd
+}
+
+func newReportExportCommand() *cobra.Command {
+	cliOptions := &reportExportOptions{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the problems of the latest Qodana results",
+		Long: `Export the problems of the latest Qodana results to a file that can be posted as a pull request comment
+by any CI, independently of the Qodana integrations, or triaged in a spreadsheet.
+
+The markdown format groups the new problems by file and links them to the analyzed revision on GitHub, GitLab or
+Bitbucket. The csv and xlsx formats list all the problems, one per row, with the rule, severity, file, line, message
+and baseline state (new, unchanged, absent or suppressed) columns. The pdf format is a paginated document with the
+summary of the analysis and the most severe problems, rendered the same way for the same results, e.g. to be signed
+and archived. With --diff-with, only the problems on the lines changed since the merge base of the given ref and HEAD
+are exported.`,
+		Example: `  qodana report export --format markdown --diff-with origin/main
+  qodana report export --format xlsx --output problems.xlsx
+  qodana report export --format pdf --top 50`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, ok := reportFileNames[cliOptions.Format]; !ok {
+				log.Fatalf(
+					"Unsupported report format %q, the supported formats are %s, %s, %s and %s",
+					cliOptions.Format,
+					markdownReportFormat,
+					csvReportFormat,
+					xlsxReportFormat,
+					pdfReportFormat,
+				)
+			}
+			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+
+			commonCtx := commoncontext.Compute(
+				cliOptions.Linter,
+				"",
+				"",
+				"",
+				"",
+				cliOptions.ResultsDir,
+				"",
+				qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+				false,
+				cliOptions.ProjectDir,
+				"",
+				cliOptions.ConfigName,
+			)
+			report, err := platform.ReadReport(platform.GetSarifPath(commonCtx.ResultsDir))
+			if err != nil {
+				log.Fatalf("Cannot read the Qodana results from %s: %s", commonCtx.ResultsDir, err)
+			}
+			var changed platform.ChangedLines
+			if cliOptions.DiffWith != "" {
+				changed, err = platform.ChangedLinesSince(commonCtx.ProjectDir, cliOptions.DiffWith, commonCtx.LogDir())
+				if err != nil {
+					log.Fatalf("Cannot compute the changes since %s: %s", cliOptions.DiffWith, err)
+				}
+			}
+
+			output := cliOptions.Output
+			if output == "" {
+				output = filepath.Join(commonCtx.ResultsDir, reportFileNames[cliOptions.Format])
+			}
+			if err = writeReport(output, cliOptions, report, changed); err != nil {
+				log.Fatalf("Cannot write the report to %s: %s", output, err)
+			}
+			msg.SuccessMessage("The report is saved to %s", output)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&cliOptions.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVar(&cliOptions.Format, "format", markdownReportFormat, "Format of the exported report: markdown, csv, xlsx or pdf")
+	flags.StringVar(
+		&cliOptions.DiffWith,
+		"diff-with",
+		"",
+		"Export only the problems on the lines changed since the merge base of this ref and HEAD, e.g. origin/main",
+	)
+	flags.StringVar(
+		&cliOptions.Output,
+		"output",
+		"",
+		"File to save the report to (default <results-dir>/qodana-report.<md|csv|xlsx|pdf>)",
+	)
+	flags.IntVar(&cliOptions.Top, "top", 100, "Number of the problems listed in the pdf report, the most severe first, 0 for all")
+	return cmd
+}
+
+func newReportIndexCommand() *cobra.Command {
+	title, output := "Qodana reports", ""
+	cmd := &cobra.Command{
+		Use:   "index <dir-with-runs>",
+		Short: "Generate a static page linking the reports of several runs",
+		Long: `Generate a static index.html listing the runs in the subdirectories of the given directory, the newest first,
+with the problems of each run by severity, the number of the new ones and the link to its HTML report, so an archive
+of the reports can be published on GitHub Pages or in an S3 bucket.
+
+A run is a results directory, e.g. the runs kept with "qodana scan --keep-runs N", or a report directory saved with
+--save-report. The directories without a SARIF report are skipped.`,
+		Example: `  qodana report index public/qodana --title "ACME code quality"`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if output == "" {
+				output = filepath.Join(args[0], "index.html")
+			}
+			runs, err := platform.LoadReportIndexRuns(args[0], filepath.Dir(output))
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(runs) == 0 {
+				log.Fatalf("No runs with a SARIF report found in %s", args[0])
+			}
+			file, err := os.Create(output)
+			if err != nil {
+				log.Fatalf("Cannot write the index to %s: %s", output, err)
+			}
+			err = platform.WriteReportIndex(file, title, runs)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				log.Fatalf("Cannot write the index to %s: %s", output, err)
+			}
+			msg.SuccessMessage("The index of %d runs is saved to %s", len(runs), output)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&title, "title", title, "Title of the index page")
+	flags.StringVar(&output, "output", "", "File to save the index to (default <dir-with-runs>/index.html)")
+	return cmd
+}
+
+// writeReport writes the report in the format of the options to the output file.
+func writeReport(output string, cliOptions *reportExportOptions, report *sarif.Report, changed platform.ChangedLines) error {
+	format := cliOptions.Format
+	if format == markdownReportFormat {
+		return os.WriteFile(output, []byte(platform.MarkdownReport(report, changed)), 0o644)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	problems := platform.SpreadsheetProblems(report, changed)
+	switch format {
+	case csvReportFormat:
+		err = platform.WriteCsvReport(file, problems)
+	case xlsxReportFormat:
+		err = platform.WriteXlsxReport(file, problems)
+	case pdfReportFormat:
+		err = platform.WritePdfReport(file, report, problems, cliOptions.Top)
+	default:
+		err = fmt.Errorf("unsupported report format %q", format)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
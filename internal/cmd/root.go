@@ -21,9 +21,16 @@ import (
 	"os"
 	"slices"
 
+	"github.com/JetBrains/qodana-cli/internal/cloud"
 	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/ideintegration"
+	"github.com/JetBrains/qodana-cli/internal/platform/logging"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
+	"github.com/JetBrains/qodana-cli/internal/platform/tracing"
 	"github.com/JetBrains/qodana-cli/internal/platform/version"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -60,18 +67,26 @@ func setDefaultCommandIfNeeded(rootCmd *cobra.Command, args []string) {
 	}
 }
 
+// setOfflineIfRequested enables the offline mode before the flags are parsed, the update check is started before that.
+func setOfflineIfRequested(args []string) {
+	if slices.Contains(args[1:], "--offline") || slices.Contains(args[1:], "--offline=true") {
+		qdenv.SetEnv(qdenv.QodanaOffline, "true")
+	}
+}
+
 // Execute is a main CLI entrypoint: handles user interrupt, CLI start and everything else.
 func Execute() {
 	if !qdenv.IsContainer() && os.Geteuid() == 0 {
 		msg.WarningMessage("Running the tool as root is dangerous: please run it as a regular user")
 	}
+	setOfflineIfRequested(os.Args)
 	go core.CheckForUpdates(version.Version)
 	if !msg.IsInteractive() || os.Getenv("NO_COLOR") != "" { // http://no-color.org
 		msg.DisableColor()
 	}
 
 	setDefaultCommandIfNeeded(rootCommand, os.Args)
-	if err := rootCommand.Execute(); err != nil {
+	if err := rootCommand.ExecuteContext(commoncontext.InterruptContext()); err != nil {
 		core.CheckForUpdates(version.Version)
 		_, err = fmt.Fprintf(os.Stderr, "error running command: %s\n", err)
 		if err != nil {
@@ -83,6 +98,15 @@ func Execute() {
 	core.CheckForUpdates(version.Version)
 }
 
+// exit exports the trace of the command, tells the IDE the exit code and releases the locks of the run before exiting,
+// os.Exit skips PersistentPostRun.
+func exit(code int) {
+	ideintegration.Finish(code)
+	tracing.Finish()
+	commoncontext.ReleaseRunLocks()
+	os.Exit(code)
+}
+
 // newRootCommand constructs root command.
 func newRootCommand() *cobra.Command {
 	rootCmd := &cobra.Command{
@@ -91,11 +115,20 @@ func newRootCommand() *cobra.Command {
 		Long:    msg.InfoString(version.Version),
 		Version: version.Version,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			logLevel, err := log.ParseLevel(viper.GetString("log-level"))
-			if err != nil {
+			if err := logging.Setup(viper.GetString("log-level"), viper.GetString("log-format")); err != nil {
 				log.Fatal(err)
 			}
-			log.SetLevel(logLevel)
+			if viper.GetBool("timings") {
+				timings.Enable()
+			}
+			useEndpointProfile(viper.GetString("endpoint-profile"))
+			tracing.Setup(cmd.Context(), cmd.CommandPath())
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			timings.Finish("")
+			ideintegration.Finish(0)
+			tracing.Finish()
+			commoncontext.ReleaseRunLocks()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
@@ -107,18 +140,67 @@ func newRootCommand() *cobra.Command {
 		},
 	}
 	rootCmd.PersistentFlags().String("log-level", "error", "Set log-level for output")
+	rootCmd.PersistentFlags().String("log-format", "text", "Set log format for output: text or json (the analysis progress and the linter output are printed as JSON lines)")
+	rootCmd.PersistentFlags().Bool(
+		"timings",
+		false,
+		"Print the time spent in each phase of the command and write it to scan-metadata.json in the results directory",
+	)
 	rootCmd.PersistentFlags().BoolVar(
 		&core.DisableCheckUpdates,
 		"disable-update-checks",
 		false,
 		"Disable check for updates",
 	)
+	rootCmd.PersistentFlags().String(
+		"endpoint-profile",
+		os.Getenv(qdenv.QodanaEndpointProfile),
+		"Use the Qodana Cloud endpoint, token and TLS settings of the profile from <userConfigDir>/JetBrains/Qodana/endpoints.yaml instead of QODANA_ENDPOINT and QODANA_TOKEN",
+	)
 	if err := viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level")); err != nil {
 		log.Fatal(err)
 	}
+	if err := viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format")); err != nil {
+		log.Fatal(err)
+	}
+	if err := viper.BindPFlag("timings", rootCmd.PersistentFlags().Lookup("timings")); err != nil {
+		log.Fatal(err)
+	}
+	if err := viper.BindPFlag("endpoint-profile", rootCmd.PersistentFlags().Lookup("endpoint-profile")); err != nil {
+		log.Fatal(err)
+	}
 	return rootCmd
 }
 
+// useEndpointProfile makes the commands use the Qodana Cloud installation of the endpoint profile, the endpoint and
+// the token are passed through the environment like QODANA_ENDPOINT and QODANA_TOKEN, so they reach the linters
+// run in a container as well.
+func useEndpointProfile(name string) {
+	if name == "" {
+		return
+	}
+	path := cloud.EndpointProfilesPath()
+	profile, err := cloud.LoadEndpointProfile(path, name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = cloud.SetEndpointTls(profile.Tls); err != nil {
+		log.Fatalf("Failed to apply the TLS settings of endpoint profile %s: %s", name, err)
+	}
+	if endpoint := os.Getenv(qdenv.QodanaEndpointEnv); endpoint != "" && endpoint != profile.Url {
+		msg.WarningMessage("%s=%s is overridden by endpoint profile %s", qdenv.QodanaEndpointEnv, endpoint, name)
+	}
+	if err = os.Setenv(qdenv.QodanaEndpointEnv, profile.Url); err != nil {
+		log.Fatal(err)
+	}
+	if profile.Token != "" {
+		if err = os.Setenv(qdenv.QodanaToken, profile.Token); err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Debugf("Using endpoint profile %s from %s: %s", name, path, profile.Url)
+}
+
 var rootCommand = newRootCommand()
 
 // GetRootCommand returns the root command for documentation generation.
@@ -130,13 +212,37 @@ func GetRootCommand() *cobra.Command {
 func InitCli() {
 	rootCommand.AddCommand(
 		newInitCommand(),
+		newConfigCommand(),
 		newScanCommand(),
+		newPrecommitCommand(),
+		newFixCommand(),
+		newDepsCommand(),
 		newShowCommand(),
 		newSendCommand(),
+		newUploadCommand(),
+		newReportCommand(),
+		newDiagnosticsCommand(),
+		newTokenCommand(),
 		newPullCommand(),
 		newViewCommand(),
 		newContributorsCommand(),
 		newClocCommand(),
+		newAttachCommand(),
+		newBenchCommand(),
+		newDoctorCommand(),
+		newEngineCommand(),
+		newBundleCommand(),
+		newCacheCommand(),
+		newHistoryCommand(),
+		newStateCommand(),
+		newHooksCommand(),
+		newNotifyCommand(),
+		newReplayCommand(),
+		newMergeSarifCommand(),
+		newLanguagesCommand(),
+		newProfileCommand(),
+		newInspectionsCommand(),
+		platform.NewExternalLinterScanCommand(),
 	)
 }
 
@@ -18,21 +18,24 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/JetBrains/qodana-cli/internal/cloud"
 	"github.com/JetBrains/qodana-cli/internal/core/corescan"
 	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
-	"github.com/JetBrains/qodana-cli/internal/core/startup"
 	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
 	"github.com/JetBrains/qodana-cli/internal/platform"
 	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/internal/platform/effectiveconfig"
+	"github.com/JetBrains/qodana-cli/internal/platform/fingerprint"
+	"github.com/JetBrains/qodana-cli/internal/platform/ideintegration"
+	"github.com/JetBrains/qodana-cli/internal/platform/logging"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
-	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/JetBrains/qodana-cli/internal/core"
@@ -41,7 +44,11 @@ import (
 
 // newScanCommand returns a new instance of the scan command.
 func newScanCommand() *cobra.Command {
-	cliOptions := &platformcmd.CliOptions{}
+	return newScanCommandWithOptions(&platformcmd.CliOptions{})
+}
+
+// newScanCommandWithOptions returns a new instance of the scan command parsing its flags to the given options.
+func newScanCommandWithOptions(cliOptions *platformcmd.CliOptions) *cobra.Command {
 	c := &cobra.Command{
 		Use:   "scan",
 		Short: "Scan project with Qodana",
@@ -49,9 +56,58 @@ func newScanCommand() *cobra.Command {
 
 Note that most options can be configured via qodana.yaml (https://www.jetbrains.com/help/qodana/qodana-yaml.html) file.
 But you can always override qodana.yaml options with the following command-line options.
+
+If qodana.yaml lists the projects of a monorepo in "projects:" (the path and the linter of each project), every project is scanned
+with its linter and the results are reported together: the results of each project are saved to its subdirectory of the results directory,
+with the merged report and projects-summary.json at the top level. The projects are not scanned when the linter is given on the command line.
+
+If the linter fails to build the project model (e.g. qodana-cdnet on an unsupported project type), the analysis is run again with
+the linter from --fallback-linter or "fallbackLinter:" in qodana.yaml, and the substitution is recorded in the report.
+
+--preset, or "preset:" in qodana.yaml, selects a bundle of options tuned for speed or depth. "quick" runs the starter profile
+without the slow global inspections (duplicates, unused declarations), without the sanity and promo inspections, with a 15 minute
+timeout, and only on the changes of the pull request when run on CI. "full" runs the recommended profile with the promo inspections on
+the whole project, "security" runs only the security inspections of the linter (taint analysis, vulnerable dependencies, injections,
+insecure randomness and deserialization) on the whole project. The options given on the command line, and the profile and
+properties of qodana.yaml, take precedence over the preset.
+
+The problems of the security inspections are classified in qodana.sarif.json with any preset: their rules reference the CWE and
+OWASP Top 10 taxonomies of the report and are tagged for GitHub code scanning and DefectDojo, e.g. "security", "external/cwe/cwe-089"
+and "external/owasp/a03:2021".
+
+Ctrl+C or SIGTERM stops the analysis gracefully: the linter is stopped (the container gets --stop-timeout seconds to save the partial results),
+the partial results are kept and uploaded to Qodana Cloud when the token is set, and the command exits with code 130.
+Press Ctrl+C again to kill the container and exit immediately.
 `,
 		Run: func(cmd *cobra.Command, args []string) {
 			qdenv.InitializeQodanaGlobalEnv(cliOptions)
+			applyScanPresetOrFatal(cmd.Flags(), cliOptions)
+			platform.ParseFailOnOrFatal(cliOptions.FailOn)
+			platform.LoadLicenseDenylistOrFatal(cliOptions.FailOnLicense)
+			exitCodePolicy := platform.ParseExitCodePolicyOrFatal(cliOptions.ExitCodePolicy)
+			platform.SetupOfflineModeOrFatal(*cliOptions)
+			platform.SetupDownloadVerification(*cliOptions)
+			platform.SetupRegistryCredentialsOrFatal(
+				cliOptions.Registry,
+				cliOptions.Image,
+				cliOptions.RegistryUsername,
+				cliOptions.RegistryPasswordStdin,
+			)
+			if err := ideintegration.Start(cliOptions.IdeIntegration, commoncontext.Interrupt); err != nil {
+				log.Fatal(err)
+			}
+			platform.SetupMetricsOrFatal(cliOptions.MetricsFormat)
+			platform.ValidateSbomFormatOrFatal(cliOptions.SbomFormat)
+			platform.ValidateOutputOrFatal(cliOptions.Output)
+			fetchGlobalConfigurationsOrFatal(cliOptions)
+
+			projects, err := loadScanProjects(cliOptions)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(projects) > 0 {
+				exit(scanProjects(cmd.Flags(), cliOptions, projects, exitCodePolicy))
+			}
 
 			ctx := cmd.Context()
 
@@ -69,58 +125,96 @@ But you can always override qodana.yaml options with the following command-line
 				cliOptions.RepositoryRoot,
 				cliOptions.ConfigName,
 			)
+			commonCtx = commoncontext.LockRunDirsOrFatal(commonCtx, cliOptions.ConcurrentRun)
+			defer commoncontext.ReleaseRunLocks()
+			commonCtx = platform.StartHistoryRunOrFatal(commonCtx, cliOptions.KeepRuns)
+			logging.StartRunLog(commonCtx.LogDir())
 			oldReportUrl := cloud.GetReportUrl(commonCtx.ResultsDir)
 			checkProjectDir(commonCtx.ProjectDir)
 
-			preparedHost := startup.PrepareHost(commonCtx)
+			scanContext, cleanup, err := core.PrepareScan(*cliOptions, commonCtx)
+			defer cleanup()
+			if err != nil {
+				log.Fatal(err)
+			}
+			qodanaYamlConfig := scanContext.QodanaYamlConfig()
+			observeMode := platform.ComputeObserveModeOrFatal(cliOptions.Observe, qodanaYamlConfig.EnforceAfter)
 
-			effectiveConfigFiles := effectiveconfig.Files{}
-			qodanaYamlConfig := corescan.QodanaYamlConfig{}
-			if !commonCtx.Analyzer.IsContainer() {
-				var err error
-				localQodanaYamlFullPath := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(
-					commonCtx.ProjectDir,
-					cliOptions.ConfigName,
-				)
+			remoteCacheProject := platform.RemoteCacheProject{
+				Linter: scanContext.Analyser().Name(),
+				Path:   scanContext.ProjectDirPathRelativeToRepositoryRoot(),
+				Branch: fingerprint.Branch(scanContext.RepositoryRoot(), scanContext.LogDir()),
+			}
+			if qodanaYamlConfig.RemoteCache != nil && scanContext.CacheVolume() != "" {
+				msg.WarningMessage("The remote cache is not used with --cache-volume, the cache is kept in the volume")
+				qodanaYamlConfig.RemoteCache = nil
+			}
+			platform.RestoreRemoteCache(ctx, qodanaYamlConfig.RemoteCache, scanContext.CacheDir(), remoteCacheProject)
 
-				effectiveConfigDir, cleanup, err := fs.CreateTempDir("qd-effective-config")
-				if err != nil {
-					log.Fatalf("Failed to create effective config directory: %v", err)
-				}
-				defer cleanup()
-
-				effectiveConfigFiles, err = effectiveconfig.CreateEffectiveConfigFiles(
-					commonCtx.CacheDir,
-					localQodanaYamlFullPath,
-					cliOptions.GlobalConfigurationsDir,
-					cliOptions.GlobalConfigurationId,
-					effectiveConfigDir,
-					commonCtx.LogDir(),
-				)
-				if err != nil {
-					log.Fatalf("Failed to load Qodana configuration %s", err)
-				}
-				if effectiveConfigFiles.EffectiveQodanaYamlPath != "" {
-					yaml := qdyaml.LoadQodanaYamlByFullPath(effectiveConfigFiles.EffectiveQodanaYamlPath)
-					qodanaYamlConfig = corescan.YamlConfig(yaml)
-				}
+			stopGracefully := commoncontext.StopGracefullyOnInterrupt()
+			exitCode, err := core.RunAnalysis(ctx, scanContext)
+			stopGracefully()
+			if ctx.Err() != nil {
+				core.FinishInterruptedAnalysis(scanContext)
+				cleanup()
+				exit(exitcodes.QodanaInterruptedExitCode)
 			}
-			scanContext := corescan.CreateContext(
-				*cliOptions,
-				commonCtx,
-				preparedHost,
-				qodanaYamlConfig,
-				effectiveConfigFiles.ConfigDir,
+			if err != nil {
+				msg.ErrorMessage("%s", err)
+				cleanup()
+				exit(1)
+			}
+			if exitCode == exitcodes.QodanaSuccessExitCode || exitCode == exitcodes.QodanaFailThresholdExitCode {
+				platform.SaveRemoteCache(ctx, qodanaYamlConfig.RemoteCache, scanContext.CacheDir(), remoteCacheProject)
+			}
+			platform.ApplySecurityTaxonomies(filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName))
+			timings.Finish(scanContext.ResultsDir())
+			platform.WriteScanMetrics(
+				scanContext.ResultsDir(),
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+				cliOptions.MetricsFormat,
+			)
+			platform.WriteScanSbom(
+				scanContext.ResultsDir(),
+				scanContext.ProjectDir(),
+				cliOptions.SbomFormat,
+				qodanaYamlConfig.SbomExclude,
 			)
-
-			exitCode := core.RunAnalysis(ctx, scanContext)
 			if qdenv.IsContainer() {
 				err := platform.ChangeResultsPermissionsRecursively(scanContext.ResultsDir())
 				if err != nil {
 					msg.ErrorMessage("Unable to change permissions in %s: %s", scanContext.ResultsDir(), err)
 				}
 			}
-			checkExitCode(exitCode, scanContext)
+			if fallbackLinter := fallbackLinterFor(cliOptions, scanContext, exitCode); fallbackLinter != "" {
+				exit(scanWithFallbackLinter(cmd.Flags(), cliOptions, scanContext, fallbackLinter, exitCode))
+			}
+			if failedExitCode, failed := failedScanExitCode(cmd, cliOptions, scanContext, exitCode, exitCodePolicy); failed {
+				exit(failedExitCode)
+			}
+			recordLinterFallback(scanContext.ResultsDir())
+			if !qdenv.IsContainer() {
+				projectstate.RecordLastScan(
+					scanContext.ProjectDir(),
+					projectstate.LastScan{
+						Linter:     scanContext.Analyser().Name(),
+						ResultsDir: scanContext.ResultsDir(),
+						ExitCode:   exitCode,
+						FinishedAt: time.Now(),
+					},
+				)
+			}
+			exitCode = platform.ApplyFailOn(
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+				scanContext.FailOn(),
+				exitCode,
+			)
+			exitCode = platform.ApplyLicenseGate(
+				scanContext.ResultsDir(),
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+				cliOptions.FailOnLicense,
+				exitCode,
+			)
 			newReportUrl := cloud.GetReportUrl(scanContext.ResultsDir())
 			platform.ProcessSarif(
 				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
@@ -130,11 +224,24 @@ But you can always override qodana.yaml options with the following command-line
 				scanContext.GenerateCodeClimateReport(),
 				scanContext.SendBitBucketInsights(),
 			)
+			platform.PublishSarif(
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+				scanContext.Publish(),
+			)
+			platform.ReportToCi(filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName), scanContext.Ci())
+			platform.ReportToOutput(
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+				scanContext.ProjectDir(),
+				cliOptions.Output,
+			)
 
 			if newReportUrl != oldReportUrl && newReportUrl != "" && !qdenv.IsContainer() {
 				msg.SuccessMessage("Report is successfully uploaded to %s", newReportUrl)
 			}
 
+			if scanContext.SaveReport() || scanContext.ShowReport() {
+				platform.ApplyReportBranding(scanContext.ReportDir(), scanContext.ProjectDir(), qodanaYamlConfig.Report)
+			}
 			commoncontext.InteractiveShowReport(
 				scanContext.ShowReport(),
 				scanContext.CacheDir(),
@@ -142,10 +249,36 @@ But you can always override qodana.yaml options with the following command-line
 				scanContext.ReportDir(),
 				scanContext.ShowReportPort(),
 			)
+			platform.NotifyWebhooks(
+				scanContext.QodanaSystemDir(),
+				scanContext.Webhooks(),
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+				scanContext.AnalysisId(),
+				newReportUrl,
+				exitCode,
+				observeMode,
+			)
+			platform.SendNotifications(
+				scanContext.QodanaSystemDir(),
+				qodanaYamlConfig.Notifications,
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+				scanContext.AnalysisId(),
+				newReportUrl,
+				exitCode,
+				observeMode,
+			)
+			exitCode = platform.ObservedExitCode(exitCode, observeMode)
 			if exitCode == exitcodes.QodanaFailThresholdExitCode {
 				msg.EmptyMessage()
 				msg.ErrorMessage("The number of problems exceeds the fail threshold")
-				os.Exit(exitCode)
+			}
+			exitCode = exitCodePolicy.ExitCode(
+				exitCode,
+				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+			)
+			writeScanRunMetadata(cmd, cliOptions, scanContext, exitCode)
+			if exitCode != exitcodes.QodanaSuccessExitCode {
+				exit(exitCode)
 			}
 		},
 	}
@@ -164,29 +297,63 @@ func checkProjectDir(projectDir string) {
 			fmt.Sprintf("Project directory (%s) is the $HOME directory", projectDir),
 		)
 		if !msg.AskUserConfirm(msg.DefaultPromptText) {
-			os.Exit(0)
+			exit(0)
 		}
 	}
 	if !fs.CheckDirFiles(projectDir) {
 		msg.ErrorMessage("No files to check with Qodana found in %s", projectDir)
-		os.Exit(1)
+		exit(1)
 	}
 }
 
-func checkExitCode(exitCode int, c corescan.Context) {
+// failedScanExitCode returns the exit code of the analysis that didn't produce the results to process, after writing
+// its run metadata, and false for the finished analysis.
+func failedScanExitCode(
+	cmd *cobra.Command,
+	cliOptions *platformcmd.CliOptions,
+	c corescan.Context,
+	exitCode int,
+	policy platform.ExitCodePolicy,
+) (int, bool) {
+	failedExitCode, failed := checkExitCode(exitCode, c, policy)
+	if failed {
+		writeScanRunMetadata(cmd, cliOptions, c, failedExitCode)
+	}
+	return failedExitCode, failed
+}
+
+// writeScanRunMetadata writes the run metadata of the scan with its final exit code.
+func writeScanRunMetadata(cmd *cobra.Command, cliOptions *platformcmd.CliOptions, c corescan.Context, exitCode int) {
+	platform.WriteRunMetadata(
+		c.ResultsDir(),
+		filepath.Join(c.ResultsDir(), commoncontext.QodanaSarifName),
+		c.QodanaYamlConfig(),
+		platform.RunMetadata{
+			AnalysisId: c.AnalysisId(),
+			ExitCode:   exitCode,
+			Command:    cmd.CommandPath(),
+			Args:       recordedScanArgs(cmd.Flags()),
+			QodanaYaml: recordedQodanaYaml(c.ProjectDir(), cliOptions.ConfigName),
+		},
+	)
+}
+
+// checkExitCode reports the analysis that failed or had nothing to analyse and returns the exit code of the run for
+// it, false if the results are to be processed.
+func checkExitCode(exitCode int, c corescan.Context, policy platform.ExitCodePolicy) (int, bool) {
 	if exitCode == exitcodes.QodanaEapLicenseExpiredExitCode && msg.IsInteractive() {
 		msg.EmptyMessage()
 		msg.ErrorMessage(
 			"Your license expired: update your license or token. If you are using EAP, make sure you are using the latest CLI version and update to the latest linter by running %s ",
 			msg.PrimaryBold("qodana init"),
 		)
-		os.Exit(exitCode)
+		return policy.ExitCode(exitCode, ""), true
 	} else if exitCode == exitcodes.QodanaTimeoutExitCodePlaceholder {
 		msg.ErrorMessage("Qodana analysis reached timeout %s", c.GetAnalysisTimeout())
-		os.Exit(c.AnalysisTimeoutExitCode())
+		return c.AnalysisTimeoutExitCode(), true
 	} else if exitCode == exitcodes.QodanaEmptyChangesetExitCodePlaceholder {
 		msg.ErrorMessage("Nothing to analyse. Exiting with %s", exitcodes.QodanaSuccessExitCode)
-		os.Exit(exitcodes.QodanaSuccessExitCode)
+		return exitcodes.QodanaSuccessExitCode, true
 	} else if exitCode != exitcodes.QodanaSuccessExitCode && exitCode != exitcodes.QodanaFailThresholdExitCode {
 		msg.ErrorMessage("Qodana exited with code %d", exitCode)
 		msg.WarningMessage("Check ./logs/ in the results directory for more information")
@@ -198,6 +365,21 @@ func checkExitCode(exitCode int, c corescan.Context) {
 				log.Fatalf("Error while opening directory: %s", err)
 			}
 		}
-		os.Exit(exitCode)
+		return policy.ExitCode(exitCode, ""), true
+	}
+	return exitCode, false
+}
+
+// fetchGlobalConfigurationsOrFatal replaces a git URL or an archive URL given by --global-config-dir with the local
+// copy of the global configurations, it's mounted into the container like a local directory.
+func fetchGlobalConfigurationsOrFatal(cliOptions *platformcmd.CliOptions) {
+	dir, err := effectiveconfig.FetchGlobalConfigurations(
+		cliOptions.GlobalConfigurationsDir,
+		commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir),
+		"",
+	)
+	if err != nil {
+		log.Fatal(err)
 	}
+	cliOptions.GlobalConfigurationsDir = dir
 }
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// fallbackScanSkippedFlags select the linter of the failed analysis, they are not passed to the fallback analysis.
+var fallbackScanSkippedFlags = map[string]bool{
+	"linter":          true,
+	"ide":             true,
+	"image":           true,
+	"fallback-linter": true,
+}
+
+// fallbackLinterFor returns the linter to rerun the analysis with, empty if the failed analysis is not retried:
+// no fallback linter is configured, the analysis is already the fallback one, or it didn't fail on the project model.
+func fallbackLinterFor(cliOptions *platformcmd.CliOptions, c corescan.Context, exitCode int) string {
+	if qdenv.IsContainer() || os.Getenv(qdenv.QodanaLinterFallback) != "" {
+		return ""
+	}
+	fallbackLinter := cliOptions.FallbackLinter
+	if fallbackLinter == "" {
+		fallbackLinter = qdyaml.LoadQodanaYamlByFullPath(
+			qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(c.ProjectDir(), cliOptions.ConfigName),
+		).FallbackLinter
+	}
+	if fallbackLinter == "" || fallbackLinter == linterName(c.Analyser()) || fallbackLinter == c.Analyser().Name() {
+		return ""
+	}
+	if !platform.IsProjectModelFailure(exitCode, c.ResultsDir()) {
+		return ""
+	}
+	return fallbackLinter
+}
+
+// scanWithFallbackLinter reruns qodana scan with the fallback linter, it returns the exit code of the rerun.
+func scanWithFallbackLinter(
+	flags *pflag.FlagSet,
+	cliOptions *platformcmd.CliOptions,
+	c corescan.Context,
+	fallbackLinter string,
+	exitCode int,
+) int {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the qodana executable: %s", err)
+	}
+	fallback := platform.LinterFallback{
+		Linter:         linterName(c.Analyser()),
+		ExitCode:       exitCode,
+		FallbackLinter: fallbackLinter,
+	}
+	msg.EmptyMessage()
+	msg.WarningMessageCI(
+		"%s failed to build the project model (exit code %d), running the analysis with %s",
+		fallback.Linter,
+		exitCode,
+		fallbackLinter,
+	)
+	if cliOptions.ResultsDir != "" {
+		// the fallback analysis writes to the same results directory, keep the logs of the failed one
+		failedLogDir := filepath.Join(c.ResultsDir(), "log-"+unsafeProjectNameChars.ReplaceAllString(fallback.Linter, "-"))
+		if err = os.Rename(c.LogDir(), failedLogDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Could not keep the logs of the failed analysis: %s", err)
+		}
+	}
+
+	args := fallbackScanArgs(flags, fallbackLinter)
+	log.Debugf("Running %s %s", executable, strings.Join(args, " "))
+	command := exec.Command(executable, args...)
+	command.Env = append(os.Environ(), qdenv.QodanaLinterFallback+"="+fallback.Encode())
+	command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		msg.ErrorMessage("Failed to run the analysis with %s: %s", fallbackLinter, err)
+		return exitCode
+	}
+	return 0
+}
+
+// fallbackScanArgs returns the arguments of qodana scan with the fallback linter: the flags are passed as is,
+// except the ones selecting the linter.
+func fallbackScanArgs(flags *pflag.FlagSet, fallbackLinter string) []string {
+	return append([]string{"scan", "--linter", fallbackLinter}, setFlagArgs(flags, fallbackScanSkippedFlags)...)
+}
+
+// setFlagArgs returns the flags set in the command line as --name=value sorted by the name, a slice flag is repeated
+// for every item.
+func setFlagArgs(flags *pflag.FlagSet, skipped map[string]bool) []string {
+	var set []*pflag.Flag
+	flags.Visit(
+		func(flag *pflag.Flag) {
+			if !skipped[flag.Name] {
+				set = append(set, flag)
+			}
+		},
+	)
+	slices.SortStableFunc(set, func(a, b *pflag.Flag) int { return strings.Compare(a.Name, b.Name) })
+
+	var args []string
+	for _, flag := range set {
+		if value, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, item := range value.GetSlice() {
+				args = append(args, "--"+flag.Name+"="+item)
+			}
+			continue
+		}
+		args = append(args, "--"+flag.Name+"="+flag.Value.String())
+	}
+	return args
+}
+
+// recordLinterFallback records in the report that the analysis was done by the fallback linter.
+func recordLinterFallback(resultsDir string) {
+	value := os.Getenv(qdenv.QodanaLinterFallback)
+	if value == "" || qdenv.IsContainer() {
+		return
+	}
+	fallback, err := platform.ParseLinterFallback(value)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+	if err = platform.RecordLinterFallback(resultsDir, fallback); err != nil {
+		log.Warnf("Could not record the linter fallback in the report: %s", err)
+	}
+}
+
+func linterName(analyzer product.Analyzer) string {
+	if name := analyzer.GetLinter().Name; name != "" {
+		return name
+	}
+	return analyzer.Name()
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// projectScanSkippedFlags are not passed to the scans of the projects: they are set per project, or applied once
+// to the merged results.
+var projectScanSkippedFlags = map[string]bool{
+	"project-dir":      true,
+	"results-dir":      true,
+	"report-dir":       true,
+	"cache-dir":        true,
+	"config":           true,
+	"linter":           true,
+	"ide":              true,
+	"image":            true,
+	"show-report":      true,
+	"show-report-port": true,
+	"port":             true,
+	"publish":          true,
+	"webhook":          true,
+	"observe":          true,
+	"exit-code-policy": true,
+}
+
+var unsafeProjectNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// scanProject is a project from qodana.yaml with the name of its results directory.
+type scanProject struct {
+	qdyaml.Project
+	Name string
+}
+
+// loadScanProjects returns the projects to scan from the qodana.yaml in the project directory, nil if the directory
+// is a single project: the projects are not scanned when the linter is given on the command line, in the container,
+// or in the scan of one of the projects.
+func loadScanProjects(cliOptions *platformcmd.CliOptions) ([]scanProject, error) {
+	if cliOptions.Linter != "" || cliOptions.Ide != "" || cliOptions.Image != "" {
+		return nil, nil
+	}
+	if qdenv.IsContainer() || os.Getenv(qdenv.QodanaProjectsScan) != "" {
+		return nil, nil
+	}
+	yaml := qdyaml.LoadQodanaYamlByFullPath(
+		qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(cliOptions.ProjectDir, cliOptions.ConfigName),
+	)
+	return toScanProjects(yaml.Projects)
+}
+
+// toScanProjects checks the project paths and names the results directories after them.
+func toScanProjects(projects []qdyaml.Project) ([]scanProject, error) {
+	result := make([]scanProject, 0, len(projects))
+	names := make(map[string]bool, len(projects))
+	for _, project := range projects {
+		path := filepath.Clean(filepath.FromSlash(project.Path))
+		if project.Path == "" || filepath.IsAbs(path) || path == ".." ||
+			strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf(
+				"invalid project path %q in qodana.yaml, it must be relative to the root directory",
+				project.Path,
+			)
+		}
+		name := unsafeProjectNameChars.ReplaceAllString(filepath.ToSlash(path), "-")
+		if name == "." {
+			name = "root"
+		}
+		if names[name] && project.Linter != "" {
+			name += "-" + unsafeProjectNameChars.ReplaceAllString(project.Linter, "-")
+		}
+		for base, i := name, 2; names[name]; i++ {
+			name = base + "-" + strconv.Itoa(i)
+		}
+		names[name] = true
+		project.Path = path
+		result = append(result, scanProject{Project: project, Name: name})
+	}
+	return result, nil
+}
+
+// scanProjects runs qodana scan for every project and reports the results together, it returns the exit code of the run.
+func scanProjects(
+	flags *pflag.FlagSet,
+	cliOptions *platformcmd.CliOptions,
+	projects []scanProject,
+	exitCodePolicy platform.ExitCodePolicy,
+) int {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the qodana executable: %s", err)
+	}
+	rootDir := cliOptions.ProjectDir
+	resultsDir := commoncontext.ComputeProjectsResultsDir(cliOptions.ResultsDir, cliOptions.CacheDir, rootDir)
+	msg.SuccessMessage(
+		"Scanning %d projects from qodana.yaml, the results are saved to %s",
+		len(projects),
+		msg.PrimaryBold(resultsDir),
+	)
+
+	results := make([]platform.ProjectResult, 0, len(projects))
+	for _, project := range projects {
+		result := platform.ProjectResult{
+			Name:       project.Name,
+			Path:       filepath.ToSlash(project.Path),
+			Linter:     project.Linter,
+			ResultsDir: filepath.Join(resultsDir, project.Name),
+		}
+		cacheDir := ""
+		if cliOptions.CacheDir != "" {
+			cacheDir = filepath.Join(cliOptions.CacheDir, project.Name)
+		}
+		args := projectScanArgs(flags, project, filepath.Join(rootDir, project.Path), result.ResultsDir, cacheDir)
+
+		msg.EmptyMessage()
+		msg.SuccessMessage("Scanning %s", msg.PrimaryBold(result.Path))
+		log.Debugf("Running %s %s", executable, strings.Join(args, " "))
+		command := exec.Command(executable, args...)
+		command.Env = append(os.Environ(), qdenv.QodanaProjectsScan+"="+project.Name)
+		command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err = command.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				result.ExitCode = exitErr.ExitCode()
+			} else {
+				result.ExitCode = 1
+				result.Error = err.Error()
+			}
+		}
+		results = append(results, result)
+	}
+
+	summary, err := platform.SummarizeProjects(resultsDir, results)
+	if err != nil {
+		msg.ErrorMessage("Failed to summarize the project results: %s", err)
+	}
+	printProjectsSummary(summary)
+
+	exitCode := summary.ExitCode
+	if summary.Sarif != "" {
+		platform.PublishSarif(summary.Sarif, cliOptions.Publish)
+		platform.ReportToCi(summary.Sarif, cliOptions.Ci)
+		platform.ReportToOutput(summary.Sarif, rootDir, cliOptions.Output)
+	}
+	rootYaml := qdyaml.LoadQodanaYamlByFullPath(
+		qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(rootDir, cliOptions.ConfigName),
+	)
+	observeMode := platform.ComputeObserveModeOrFatal(cliOptions.Observe, rootYaml.EnforceAfter)
+	platform.NotifyWebhooks(
+		commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir),
+		cliOptions.Webhooks,
+		summary.Sarif,
+		"",
+		"",
+		exitCode,
+		observeMode,
+	)
+	platform.SendNotifications(
+		commoncontext.ComputeQodanaSystemDir(cliOptions.CacheDir),
+		rootYaml.Notifications,
+		summary.Sarif,
+		"",
+		"",
+		exitCode,
+		observeMode,
+	)
+	return exitCodePolicy.ExitCode(platform.ObservedExitCode(exitCode, observeMode), summary.Sarif)
+}
+
+// projectScanArgs returns the arguments of qodana scan for the project: the flags set for the root directory
+// are passed as is, except projectScanSkippedFlags.
+func projectScanArgs(flags *pflag.FlagSet, project scanProject, projectDir, resultsDir, cacheDir string) []string {
+	args := []string{"scan", "--project-dir", projectDir, "--results-dir", resultsDir}
+	if project.Linter != "" {
+		args = append(args, "--linter", project.Linter)
+	}
+	if cacheDir != "" {
+		args = append(args, "--cache-dir", cacheDir)
+	}
+	return append(args, setFlagArgs(flags, projectScanSkippedFlags)...)
+}
+
+func printProjectsSummary(summary platform.ProjectsSummary) {
+	tableData := pterm.TableData{[]string{"PROJECT", "LINTER", "PROBLEMS", "STATUS"}}
+	for _, project := range summary.Projects {
+		tableData = append(
+			tableData,
+			[]string{project.Path, unknownIfEmpty(project.Linter), strconv.Itoa(project.Problems), projectStatus(project)},
+		)
+	}
+	msg.EmptyMessage()
+	printTable(tableData)
+	if summary.Sarif != "" {
+		msg.SuccessMessage(
+			"%d problems in %d projects, the merged report is saved to %s",
+			summary.Problems,
+			len(summary.Projects),
+			summary.Sarif,
+		)
+	}
+}
+
+func projectStatus(project platform.ProjectResult) string {
+	switch {
+	case project.Error != "":
+		return "failed: " + project.Error
+	case project.ExitCode == exitcodes.QodanaSuccessExitCode:
+		return "passed"
+	case project.ExitCode == exitcodes.QodanaFailThresholdExitCode:
+		return "fail threshold exceeded"
+	default:
+		return fmt.Sprintf("failed with exit code %d", project.ExitCode)
+	}
+}
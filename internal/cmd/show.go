@@ -17,7 +17,10 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/platform"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	log "github.com/sirupsen/logrus"
@@ -93,6 +96,90 @@ This command serves the Qodana report locally and opens a browser to it.`,
 		"",
 		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
 	)
+	cmd.AddCommand(newShowSarifCommand())
+	return cmd
+}
+
+// newShowSarifCommand returns a new instance of the show sarif command.
+func newShowSarifCommand() *cobra.Command {
+	cliOptions := &showSarifOptions{}
+	cmd := &cobra.Command{
+		Use:   "sarif [report]",
+		Short: "Print the problems of a SARIF report matching the filters",
+		Long: `Print the problems of a SARIF report, qodana.sarif.json of the latest Qodana results by default, matching all the
+filters, from the most severe.
+
+A filter compares a field of the problems with the values: <field>=<values> matches any of the comma-separated
+values, <field>!=<values> none of them, and severity>=<severity> the severity and the more severe ones. The fields are
+severity (critical, high, moderate, low or info), inspection (an ID or a glob), path (a glob where ** matches any
+number of directories, a directory matches the files inside it) and state (new, unchanged, absent or suppressed).`,
+		Example: `  qodana show sarif --filter 'severity>=high' --filter state=new
+  qodana show sarif qodana.sarif.json --filter 'path=src/**/*.kt' --filter 'inspection!=Kotlin*' --format csv`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			filters, err := platform.ParseSarifFilters(cliOptions.Filters)
+			if err != nil {
+				log.Fatal(err)
+			}
+			sarifPath := ""
+			if len(args) > 0 {
+				sarifPath = args[0]
+			} else {
+				qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+				commonCtx := commoncontext.Compute(
+					cliOptions.Linter,
+					"",
+					"",
+					"",
+					"",
+					cliOptions.ResultsDir,
+					"",
+					qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+					false,
+					cliOptions.ProjectDir,
+					"",
+					cliOptions.ConfigName,
+				)
+				sarifPath = platform.GetSarifPath(commonCtx.ResultsDir)
+			}
+			report, err := platform.ReadReport(sarifPath)
+			if err != nil {
+				log.Fatalf("Cannot read the SARIF report %s: %s", sarifPath, err)
+			}
+			err = platform.WriteSarifProblems(os.Stdout, platform.QuerySarif(report, filters), cliOptions.Format)
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&cliOptions.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&cliOptions.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&cliOptions.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringArrayVar(
+		&cliOptions.Filters,
+		"filter",
+		nil,
+		"Print only the problems matching the filter (can be repeated), e.g. severity>=high, inspection=Kotlin*, path=src/**/*.kt or state=new",
+	)
+	flags.StringVar(
+		&cliOptions.Format,
+		"format",
+		platform.SarifQueryFormatTable,
+		"Output format: table, json or csv",
+	)
 	return cmd
 }
 
@@ -105,3 +192,12 @@ type showOptions struct {
 	OpenDir    bool
 	ConfigName string
 }
+
+type showSarifOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	Filters    []string
+	Format     string
+}
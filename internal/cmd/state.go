@@ -0,0 +1,166 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// stateOptions represents state command options.
+type stateOptions struct {
+	ProjectDir string
+	DryRun     bool
+}
+
+// newStateCommand returns a new instance of the state command.
+func newStateCommand() *cobra.Command {
+	cliOptions := &stateOptions{}
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Manage the Qodana state of the project in .qodana",
+		Long: `Manage the .qodana directory of the project, the Qodana state kept next to the code.
+
+The baseline (baseline.sarif.json, used when --baseline isn't given), the triage state (triage) and the suppressions
+(suppressions) are shared with the team through the repository. The local overrides (local), the coverage data
+(code-coverage) and the pointer to the last scan (last-scan.json) stay on the machine, they are git-ignored by the
+.gitignore generated in .qodana.`,
+	}
+	cmd.PersistentFlags().StringVarP(&cliOptions.ProjectDir, "project-dir", "i", ".", "Root directory of the project")
+	cmd.AddCommand(newStateInitCommand(cliOptions), newStateShowCommand(cliOptions), newStateCleanCommand(cliOptions))
+	return cmd
+}
+
+func newStateInitCommand(cliOptions *stateOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "init",
+		Short: "Create the .qodana directory or update its layout and .gitignore",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := projectstate.Init(cliOptions.ProjectDir); err != nil {
+				log.Fatalf("Failed to initialize %s: %s", projectstate.Dir(cliOptions.ProjectDir), err)
+			}
+			msg.SuccessMessage(
+				"Initialized %s, commit it to share the baseline, the triage state and the suppressions",
+				projectstate.Dir(cliOptions.ProjectDir),
+			)
+		},
+	}
+}
+
+func newStateShowCommand(cliOptions *stateOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Print the content of the .qodana directory and the last scan",
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := projectstate.Dir(cliOptions.ProjectDir)
+			version, err := projectstate.ReadVersion(cliOptions.ProjectDir)
+			if err != nil {
+				log.Fatalf("Failed to read %s: %s", dir, err)
+			}
+			entries := projectstate.Entries(cliOptions.ProjectDir)
+			gitignoreUpToDate := projectstate.IsGitignoreUpToDate(cliOptions.ProjectDir)
+			lastScan, err := projectstate.ReadLastScan(cliOptions.ProjectDir)
+			if err != nil {
+				log.Warnf("Failed to read the last scan: %s", err)
+			}
+			if msg.IsJsonLog() {
+				msg.PrintJsonLog(
+					"state",
+					map[string]any{
+						"path":              dir,
+						"version":           version,
+						"entries":           entries,
+						"gitignoreUpToDate": gitignoreUpToDate,
+						"lastScan":          lastScan,
+					},
+				)
+				return
+			}
+			if version == 0 {
+				msg.WarningMessage("%s is not initialized, run %s", dir, msg.PrimaryBold("qodana state init"))
+			} else if version > projectstate.Version {
+				msg.WarningMessage(
+					"%s has the layout version %d, this CLI supports up to version %d",
+					dir,
+					version,
+					projectstate.Version,
+				)
+			}
+			tableData := pterm.TableData{
+				[]string{msg.PrimaryBold("Entry"), msg.PrimaryBold("Kept in"), msg.PrimaryBold("Present")},
+			}
+			for _, entry := range entries {
+				keptIn := "repository"
+				if entry.Local {
+					keptIn = "machine"
+				}
+				tableData = append(tableData, []string{entry.Name, keptIn, strconv.FormatBool(entry.Exists)})
+			}
+			printTable(tableData)
+			if version != 0 && !gitignoreUpToDate {
+				msg.WarningMessage(
+					"%s doesn't ignore all the local entries, run %s to update it",
+					filepath.Join(dir, ".gitignore"),
+					msg.PrimaryBold("qodana state init"),
+				)
+			}
+			if lastScan != nil {
+				msg.SuccessMessage(
+					"Last scan: %s, %s, exit code %d, the results are in %s",
+					lastScan.Linter,
+					formatAge(lastScan.FinishedAt),
+					lastScan.ExitCode,
+					lastScan.ResultsDir,
+				)
+			}
+		},
+	}
+}
+
+func newStateCleanCommand(cliOptions *stateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove the local state from the .qodana directory",
+		Long: `Remove the entries of the .qodana directory local to the machine: the local overrides, the coverage data and the
+pointer to the last scan. The shared baseline, triage state and suppressions are kept.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			removed, err := projectstate.Clean(cliOptions.ProjectDir, cliOptions.DryRun)
+			for _, name := range removed {
+				path := filepath.Join(projectstate.Dir(cliOptions.ProjectDir), name)
+				if cliOptions.DryRun {
+					msg.SuccessMessage("Would remove %s", path)
+				} else {
+					log.Debugf("Removed %s", path)
+				}
+			}
+			if err != nil {
+				log.Fatalf("Failed to clean %s: %s", projectstate.Dir(cliOptions.ProjectDir), err)
+			}
+			if !cliOptions.DryRun {
+				msg.SuccessMessage("Removed %d local entries from %s", len(removed), projectstate.Dir(cliOptions.ProjectDir))
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&cliOptions.DryRun, "dry-run", false, "Print the entries to remove without removing them")
+	return cmd
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/tokenloader"
+	"github.com/mattn/go-isatty"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newTokenCommand returns a new instance of the token command.
+func newTokenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the Qodana Cloud token saved in the system keyring",
+		Long: fmt.Sprintf(
+			`Manage the Qodana Cloud token saved in the system keyring (Keychain on macOS, Credential Manager on Windows,
+Secret Service on Linux) instead of the %s environment variable.
+
+The saved token is used by qodana scan, init and upload when %s is not declared
+and the project has no token of its own.`,
+			msg.PrimaryBold(qdenv.QodanaToken),
+			msg.PrimaryBold(qdenv.QodanaToken),
+		),
+	}
+	cmd.AddCommand(newTokenSetCommand(), newTokenGetCommand(), newTokenRemoveCommand())
+	return cmd
+}
+
+func newTokenSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set",
+		Short: "Save the token to the system keyring",
+		Long: `Save the Qodana Cloud token to the system keyring, replacing the saved one.
+
+The token is asked for interactively or read from the standard input, e.g. echo "$TOKEN" | qodana token set,
+so it doesn't end up in the shell history.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			token, err := readToken(cmd.InOrStdin())
+			if err != nil {
+				log.Fatalf("Failed to read the token: %s", err)
+			}
+			if token == "" {
+				log.Fatal("Token cannot be empty")
+			}
+			client := cloud.GetCloudApiEndpoints().NewCloudApiClient(token)
+			projectName, err := client.RequestProjectName()
+			if err != nil {
+				msg.ErrorMessage(cloud.InvalidTokenMessage)
+				os.Exit(1)
+			}
+			if err = tokenloader.SaveGlobalToken(token); err != nil {
+				log.Fatalf("Failed to save the token to the system keyring: %s", err)
+			}
+			msg.SuccessMessage(
+				"Saved the token of %s project %s to the system keyring",
+				cloud.GetCloudRootEndpoint().Url,
+				projectName,
+			)
+		},
+	}
+}
+
+func newTokenGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get",
+		Short: "Print the token saved in the system keyring",
+		Long: fmt.Sprintf(
+			`Print the Qodana Cloud token saved in the system keyring, e.g. to pass it to other tools:
+export %s=$(qodana token get)`,
+			qdenv.QodanaToken,
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			token, err := tokenloader.GetGlobalToken()
+			if err != nil {
+				log.Fatalf("Failed to get the token from the system keyring: %s", err)
+			}
+			if token == "" {
+				msg.ErrorMessage("No token is saved, run %s to save it", msg.PrimaryBold("qodana token set"))
+				os.Exit(1)
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), token)
+		},
+	}
+}
+
+func newTokenRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove",
+		Short: "Remove the token from the system keyring",
+		Run: func(cmd *cobra.Command, args []string) {
+			removed, err := tokenloader.RemoveGlobalToken()
+			if err != nil {
+				log.Fatalf("Failed to remove the token from the system keyring: %s", err)
+			}
+			if removed {
+				msg.SuccessMessage("Removed the token from the system keyring")
+			} else {
+				msg.SuccessMessage("No token is saved in the system keyring")
+			}
+		},
+	}
+}
+
+// readToken asks for the token in the terminal or reads it from the piped input.
+func readToken(in io.Reader) (string, error) {
+	if f, ok := in.(*os.File); ok && msg.IsInteractive() && isatty.IsTerminal(f.Fd()) {
+		token, err := pterm.DefaultInteractiveTextInput.WithMask("*").WithTextStyle(msg.PrimaryStyle).Show(
+			">  Enter the Qodana Cloud token",
+		)
+		return strings.TrimSpace(token), err
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/tokenloader"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// uploadOptions represents upload command options.
+type uploadOptions struct {
+	ResultsDir   string
+	CacheDir     string
+	AnalysisId   string
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// newUploadCommand returns a new instance of the upload command.
+func newUploadCommand() *cobra.Command {
+	cliOptions := &uploadOptions{}
+	cmd := &cobra.Command{
+		Use:   "upload",
+		Short: "Upload the results of a finished analysis to Qodana Cloud",
+		Long: fmt.Sprintf(
+			`Upload the results of an analysis run earlier with "qodana scan --results-dir <dir>" to Qodana Cloud.
+
+The analysis and the upload can be done on different machines, e.g. the scan runs in an isolated network segment
+and only its results directory is copied to a machine with access to Qodana Cloud. The project and the linter
+are not needed for the upload, the token is read from the %s environment variable or the one saved
+with qodana token set.
+
+A failed upload is retried with the backoff doubled after each attempt, and the command can be rerun later.
+If you are using other Qodana Cloud instance than https://qodana.cloud/, override it by declaring the %s environment variable.`,
+			msg.PrimaryBold(qdenv.QodanaToken),
+			msg.PrimaryBold(qdenv.QodanaEndpointEnv),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+
+			resultsDir, err := filepath.Abs(cliOptions.ResultsDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if _, err = os.Stat(platform.GetSarifPath(resultsDir)); errors.Is(err, os.ErrNotExist) {
+				log.Fatalf("No report found in %s, it should contain the results of qodana scan", resultsDir)
+			}
+			token := qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken)
+			if token == "" {
+				if token, err = tokenloader.GetGlobalToken(); err != nil {
+					log.Debugf("Failed to get the token from the system keyring: %s", err)
+				}
+			}
+			if token == "" {
+				log.Fatalf(
+					"%s is required to upload the report to Qodana Cloud, declare it or save it with qodana token set",
+					qdenv.QodanaToken,
+				)
+			}
+			tokenloader.ValidateTokenPrintProject(token)
+			if !cmd.Flags().Changed("analysis-id") {
+				// resume the interrupted upload of the same analysis
+				state, err := cloud.ReadUploadState(filepath.Join(resultsDir, cloud.UploadStateFileName))
+				if err == nil && state != nil {
+					cliOptions.AnalysisId = state.AnalysisId
+				}
+			}
+
+			cacheDir := cliOptions.CacheDir
+			if cacheDir == "" {
+				cacheDir = filepath.Join(commoncontext.ComputeQodanaSystemDir(""), "upload")
+			}
+			if err = os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+				log.Fatalf("Failed to create the cache directory %s: %s", cacheDir, err)
+			}
+			publisher := platform.Publisher{
+				ResultsDir: resultsDir,
+				LogDir:     filepath.Join(resultsDir, "log"),
+				AnalysisId: cliOptions.AnalysisId,
+			}
+			if err = os.MkdirAll(publisher.LogDir, os.ModePerm); err != nil {
+				log.Fatalf("Failed to create the log directory %s: %s", publisher.LogDir, err)
+			}
+
+			res, err := platform.UploadReportWithRetries(
+				cacheDir,
+				publisher,
+				token,
+				cliOptions.Retries,
+				cliOptions.RetryBackoff,
+			)
+			if res != 0 || err != nil {
+				if err != nil {
+					msg.ErrorMessage("%s", err)
+				}
+				msg.ErrorMessage("Failed to upload the report from %s, rerun the command to try again", resultsDir)
+				exit(max(res, 1))
+			}
+			msg.SuccessMessage("Uploaded the report from %s", resultsDir)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(
+		&cliOptions.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Directory with the results of qodana scan to upload",
+	)
+	flags.StringVar(
+		&cliOptions.CacheDir,
+		"cache-dir",
+		"",
+		"Override directory the upload tools are extracted to (default <userCacheDir>/JetBrains/Qodana/upload)",
+	)
+	flags.StringVarP(
+		&cliOptions.AnalysisId,
+		"analysis-id",
+		"a",
+		uuid.New().String(),
+		"Unique report identifier (GUID) to be used by Qodana Cloud",
+	)
+	flags.IntVar(&cliOptions.Retries, "retries", 3, "Number of retries of a failed upload")
+	flags.DurationVar(
+		&cliOptions.RetryBackoff,
+		"retry-backoff",
+		10*time.Second,
+		"Delay before the first retry of a failed upload, doubled before each next one",
+	)
+	if err := cmd.MarkFlagRequired("results-dir"); err != nil {
+		log.Fatal(err)
+	}
+	return cmd
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/pterm/pterm"
+)
+
+// benchProject is the standardized project analyzed by `qodana bench`, it is the same for every CLI build.
+//
+//go:embed benchproject
+var benchProject embed.FS
+
+const benchProjectRoot = "benchproject"
+
+// BenchStage is the wall time spent in one of the scan stages.
+type BenchStage struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// BenchRun is the result of a single benchmark run.
+type BenchRun struct {
+	Seconds  float64      `json:"seconds"`
+	ExitCode int          `json:"exitCode"`
+	Stages   []BenchStage `json:"stages"`
+}
+
+// BenchReport is the benchmark report, it contains only the machine configuration and timings.
+type BenchReport struct {
+	CliVersion string     `json:"cliVersion"`
+	Linter     string     `json:"linter"`
+	Engine     string     `json:"engine"`
+	Os         string     `json:"os"`
+	Arch       string     `json:"arch"`
+	Cpus       int        `json:"cpus"`
+	Runs       []BenchRun `json:"runs"`
+}
+
+// NewBenchReport returns an empty benchmark report for the current machine.
+func NewBenchReport(cliVersion string, linter string, engine string) *BenchReport {
+	return &BenchReport{
+		CliVersion: cliVersion,
+		Linter:     linter,
+		Engine:     engine,
+		Os:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Cpus:       runtime.NumCPU(),
+		Runs:       make([]BenchRun, 0),
+	}
+}
+
+// ExtractBenchProject writes the bundled benchmark project to dir.
+func ExtractBenchProject(dir string) error {
+	return fs.WalkDir(
+		benchProject, benchProjectRoot, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(benchProjectRoot, filepath.FromSlash(path))
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dir, rel)
+			if d.IsDir() {
+				return os.MkdirAll(target, os.ModePerm)
+			}
+			data, err := benchProject.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, data, 0o644)
+		},
+	)
+}
+
+// RunBenchmark runs `qodana scan` with the given arguments in a separate process and measures its stages.
+// The output of the process is written to output.
+func RunBenchmark(executable string, args []string, output io.Writer) (BenchRun, error) {
+	cmd := exec.Command(executable, append([]string{"scan"}, args...)...)
+	cmd.Env = append(os.Environ(), "NONINTERACTIVE=1")
+	cmd.Stderr = output
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return BenchRun{}, err
+	}
+
+	start := time.Now()
+	if err = cmd.Start(); err != nil {
+		return BenchRun{}, fmt.Errorf("failed to start %s: %w", executable, err)
+	}
+	timer := newBenchTimer(start)
+	scanner := bufio.NewScanner(io.TeeReader(stdout, output))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	for scanner.Scan() {
+		timer.handleLine(scanner.Text(), time.Now())
+	}
+	_, _ = io.Copy(output, stdout)
+
+	err = cmd.Wait()
+	end := time.Now()
+	run := BenchRun{
+		Seconds: end.Sub(start).Seconds(),
+		Stages:  timer.finish(end),
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		run.ExitCode = exitErr.ExitCode()
+		return run, nil
+	}
+	return run, err
+}
+
+// benchTimer measures how long the scan stays in each stage, using the same markers as the progress output.
+type benchTimer struct {
+	stageNames []string
+	current    scanProgress
+	stageStart time.Time
+	stages     []BenchStage
+}
+
+func newBenchTimer(start time.Time) *benchTimer {
+	return &benchTimer{
+		stageNames: scanStageNames(),
+		current:    scanProgress{Stage: 1},
+		stageStart: start,
+		stages:     make([]BenchStage, 0),
+	}
+}
+
+func (t *benchTimer) handleLine(line string, now time.Time) {
+	next, ok := parseProgressLine(line, t.current)
+	if !ok {
+		return
+	}
+	if next.Stage != t.current.Stage {
+		t.finishStage(now)
+	}
+	t.current = next
+}
+
+func (t *benchTimer) finishStage(now time.Time) {
+	t.stages = append(
+		t.stages, BenchStage{
+			Name:    t.stageNames[min(t.current.Stage, len(t.stageNames)-1)],
+			Seconds: now.Sub(t.stageStart).Seconds(),
+		},
+	)
+	t.stageStart = now
+}
+
+// finish closes the current stage and returns the measured stages.
+func (t *benchTimer) finish(now time.Time) []BenchStage {
+	t.finishStage(now)
+	return t.stages
+}
+
+// WriteBenchReport saves the report as JSON, so it can be shared and compared with other machines.
+func WriteBenchReport(path string, report *BenchReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// PrintBenchReport prints the timings of every run, stages are listed in the order they were reached.
+func PrintBenchReport(report *BenchReport) {
+	if msg.IsJsonLog() {
+		msg.PrintJsonLog("bench", report)
+		return
+	}
+	msg.SuccessMessage(
+		"Benchmark of %s (%s) on %s/%s, %d CPUs",
+		msg.PrimaryBold(report.Linter),
+		report.Engine,
+		report.Os,
+		report.Arch,
+		report.Cpus,
+	)
+	tableData := pterm.TableData{
+		[]string{
+			msg.PrimaryBold("Run"),
+			msg.PrimaryBold("Stage"),
+			msg.PrimaryBold("Time"),
+		},
+	}
+	for i, run := range report.Runs {
+		for _, stage := range run.Stages {
+			tableData = append(tableData, []string{fmt.Sprint(i + 1), stage.Name, formatBenchSeconds(stage.Seconds)})
+		}
+		tableData = append(
+			tableData, []string{
+				fmt.Sprint(i + 1),
+				msg.PrimaryBold("Total (exit code %d)", run.ExitCode),
+				msg.PrimaryBold(formatBenchSeconds(run.Seconds)),
+			},
+		)
+	}
+
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	err := table.Render()
+	if err != nil {
+		return
+	}
+}
+
+func formatBenchSeconds(seconds float64) string {
+	return fmt.Sprintf("%.1fs", seconds)
+}
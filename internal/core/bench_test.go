@@ -0,0 +1,68 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBenchTimer(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timer := newBenchTimer(start)
+	lines := []struct {
+		line    string
+		seconds int
+	}{
+		{"Starting up IntelliJ IDEA", 20},
+		{"Loading plugins", 25},
+		{"The Project opening stage completed in 30 sec", 50},
+		{"The Project configuration stage completed in 5 sec", 55},
+		{"##qodana[progress files='10' total='20']", 60},
+		{"##qodana[progress files='20' total='20']", 70},
+		{"Detailed summary", 90},
+	}
+	for _, l := range lines {
+		timer.handleLine(l.line, start.Add(time.Duration(l.seconds)*time.Second))
+	}
+
+	stages := timer.finish(start.Add(100 * time.Second))
+	assert.Equal(
+		t, []BenchStage{
+			{Name: "Starting the analysis engine", Seconds: 20},
+			{Name: "Opening the project", Seconds: 30},
+			{Name: "Configuring the project", Seconds: 5},
+			{Name: "Analyzing the project", Seconds: 35},
+			{Name: "Preparing the report", Seconds: 10},
+		}, stages,
+	)
+}
+
+func TestExtractBenchProject(t *testing.T) {
+	dir := t.TempDir()
+	err := ExtractBenchProject(dir)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "qodana.yaml"))
+	assert.FileExists(t, filepath.Join(dir, "src", "main", "java", "bench", "Main.java"))
+
+	_, err = os.Stat(filepath.Join(dir, benchProjectRoot))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,35 @@
+'use strict';
+
+class Inventory {
+    constructor() {
+        this.items = new Map();
+    }
+
+    add(name, count) {
+        this.items.set(name, (this.items.get(name) || 0) + count);
+    }
+
+    remove(name, count) {
+        const current = this.items.get(name);
+        if (current == undefined || current < count) {
+            return false;
+        }
+        this.items.set(name, current - count);
+        return true;
+    }
+
+    lowStock(threshold) {
+        var result = [];
+        for (const [name, count] of this.items) {
+            if (count < threshold) {
+                result.push(name);
+            }
+        }
+        return result;
+    }
+}
+
+const inventory = new Inventory();
+inventory.add('apple', 10);
+inventory.add('pear', 2);
+console.log(inventory.lowStock(5));
@@ -0,0 +1,26 @@
+import os
+
+
+class Inventory:
+    def __init__(self):
+        self.items = {}
+
+    def add(self, name, count):
+        self.items[name] = self.items.get(name, 0) + count
+
+    def remove(self, name, count):
+        current = self.items.get(name)
+        if current == None or current < count:
+            return False
+        self.items[name] = current - count
+        return True
+
+    def low_stock(self, threshold=[]):
+        return [name for name, count in self.items.items() if count < threshold]
+
+
+if __name__ == "__main__":
+    inventory = Inventory()
+    inventory.add("apple", 10)
+    inventory.add("pear", 2)
+    print(inventory.low_stock(5))
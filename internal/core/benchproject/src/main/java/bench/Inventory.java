@@ -0,0 +1,46 @@
+package bench;
+
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+
+public class Inventory {
+    private final Map<String, Integer> items = new HashMap<>();
+
+    public void add(String name, int count) {
+        Integer current = items.get(name);
+        if (current == null) {
+            items.put(name, count);
+        } else {
+            items.put(name, current + count);
+        }
+    }
+
+    public boolean remove(String name, int count) {
+        Integer current = items.get(name);
+        if (current == null || current < count) {
+            return false;
+        }
+        items.put(name, current - count);
+        return true;
+    }
+
+    public List<String> lowStock(int threshold) {
+        List<String> result = new ArrayList<>();
+        for (Map.Entry<String, Integer> entry : items.entrySet()) {
+            if (entry.getValue() < threshold) {
+                result.add(entry.getKey());
+            }
+        }
+        return result;
+    }
+
+    public String describe(String name) {
+        String description = null;
+        if (items.containsKey(name)) {
+            description = name + ": " + items.get(name);
+        }
+        return description.trim();
+    }
+}
@@ -0,0 +1,14 @@
+package bench;
+
+public class Main {
+    public static void main(String[] args) {
+        Inventory inventory = new Inventory();
+        inventory.add("apple", 10);
+        inventory.add("pear", 2);
+        inventory.remove("apple", 3);
+        int unused = 42;
+        for (String name : inventory.lowStock(5)) {
+            System.out.println(inventory.describe(name));
+        }
+    }
+}
@@ -0,0 +1,305 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core/startup"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	cp "github.com/otiai10/copy"
+	log "github.com/sirupsen/logrus"
+)
+
+// BundleOptions are the contents of an air-gapped bundle created by CreateBundle.
+type BundleOptions struct {
+	Targets PullTargets
+	// Native bundles the IDE distributions of the linters and their plugins for the native mode instead of the images.
+	Native bool
+	// Parallel is the number of images pulled at the same time before they are saved.
+	Parallel  int
+	SystemDir string
+}
+
+// executablePath returns the path of the running CLI, it's bundled with its embedded tools.
+var executablePath = os.Executable
+
+// CreateBundle packages everything the analysis downloads into a single archive for the machines without network
+// access: the linter images saved from Docker or the IDE distributions with their plugins, and the running CLI with
+// the tools embedded into it. The archive is loaded with LoadBundle.
+func CreateBundle(
+	ctx context.Context,
+	docker client.APIClient,
+	archivePath string,
+	opts BundleOptions,
+) (commoncontext.BundleManifest, error) {
+	manifest := commoncontext.BundleManifest{
+		CliVersion: version.Version,
+		Os:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(archivePath), ".qodana-bundle-")
+	if err != nil {
+		return manifest, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	var sources []commoncontext.BundleSource
+	if opts.Native {
+		sources, err = bundleIdes(opts, &manifest)
+	} else {
+		sources, err = bundleImages(ctx, docker, opts, tmpDir, &manifest)
+	}
+	if err != nil {
+		return manifest, err
+	}
+	cli, err := executablePath()
+	if err != nil {
+		return manifest, fmt.Errorf("failed to find the CLI executable: %w", err)
+	}
+	cliDir := path.Join(commoncontext.BundleSystemDir, "qodana-cli-"+version.Version)
+	sources = append(sources, commoncontext.BundleSource{Name: path.Join(cliDir, filepath.Base(cli)), Path: cli})
+	manifest.Dirs = append(manifest.Dirs, cliDir)
+	return commoncontext.ExportBundle(archivePath, sources, manifest)
+}
+
+// bundleImages pulls the images and saves them to a single archive, the layers shared by the images are saved once.
+func bundleImages(
+	ctx context.Context,
+	docker client.APIClient,
+	opts BundleOptions,
+	tmpDir string,
+	manifest *commoncontext.BundleManifest,
+) ([]commoncontext.BundleSource, error) {
+	images := opts.Targets.AllImages()
+	if len(images) == 0 {
+		return nil, errors.New("no images to bundle, the linters run in the native mode, use --native")
+	}
+	if qdenv.IsOffline() {
+		log.Debug("The images are not pulled in the offline mode, the local ones are bundled")
+	} else if err := PullImages(ctx, docker, images, opts.Parallel); err != nil {
+		return nil, err
+	}
+	imagesPath := filepath.Join(tmpDir, filepath.FromSlash(commoncontext.BundleImagesArchive))
+	if err := saveImages(ctx, docker, images, imagesPath); err != nil {
+		return nil, err
+	}
+	manifest.Images = images
+	return []commoncontext.BundleSource{{Name: commoncontext.BundleImagesArchive, Path: imagesPath}}, nil
+}
+
+func saveImages(ctx context.Context, docker client.APIClient, images []string, imagesPath string) error {
+	if err := os.MkdirAll(filepath.Dir(imagesPath), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(imagesPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	reader, err := docker.ImageSave(ctx, images)
+	if err != nil {
+		return fmt.Errorf("failed to save the images: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+	if _, err = io.Copy(file, reader); err != nil {
+		return fmt.Errorf("failed to save the images: %w", err)
+	}
+	return file.Close()
+}
+
+// bundleIdes downloads the IDE distributions of the native linters and their custom plugins to bundle them.
+func bundleIdes(opts BundleOptions, manifest *commoncontext.BundleManifest) ([]commoncontext.BundleSource, error) {
+	if qdenv.IsOffline() {
+		return nil, errors.New("the IDE distributions can't be downloaded in the offline mode")
+	}
+	var sources []commoncontext.BundleSource
+	addDir := func(name string, dir string) {
+		sources = append(sources, commoncontext.BundleSource{Name: name, Path: dir})
+		manifest.Dirs = append(manifest.Dirs, name)
+	}
+	for _, image := range opts.Targets.Images {
+		msg.WarningMessage("Skipping %s, only the IDEs of the linters can be bundled with --native", image)
+	}
+	manifest.Ides = map[string]string{}
+	for _, linter := range opts.Targets.Linters {
+		if !linter.SupportNative {
+			msg.WarningMessage("Skipping %s, it doesn't support the native mode", linter.Name)
+			continue
+		}
+		analyzer := linter.NativeAnalyzer()
+		ideDir := startup.DownloadIde(analyzer, opts.SystemDir)
+		ideRel, err := filepath.Rel(opts.SystemDir, ideDir)
+		if err != nil {
+			return nil, err
+		}
+		ideRel = filepath.ToSlash(ideRel)
+		manifest.Ides[linter.Name] = ideRel
+		// the IDE directory is inside the installation directory on macOS and Windows
+		installDir, _, _ := strings.Cut(ideRel, "/")
+		addDir(path.Join(commoncontext.BundleSystemDir, installDir), filepath.Join(opts.SystemDir, installDir))
+
+		pluginsDir, err := startup.DownloadCustomPlugins(analyzer, ideDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download the custom plugins of %s: %w", linter.Name, err)
+		}
+		if pluginsDir != "" {
+			configDir, err := qodanaConfigDir()
+			if err != nil {
+				return nil, err
+			}
+			pluginsRel, err := filepath.Rel(configDir, pluginsDir)
+			if err != nil {
+				return nil, err
+			}
+			addDir(path.Join(commoncontext.BundleConfigDir, filepath.ToSlash(pluginsRel)), pluginsDir)
+		}
+	}
+	if len(manifest.Ides) == 0 {
+		return nil, errors.New("no IDE distributions to bundle")
+	}
+	return sources, nil
+}
+
+// LoadBundle installs the bundle created by CreateBundle: the images are loaded to Docker, the IDE distributions
+// and the CLI are installed to the system directory and the plugins to the configuration directory, so the offline
+// analysis finds them. The client is created only if the bundle has images.
+func LoadBundle(
+	ctx context.Context,
+	newClient func(ctx context.Context) (client.APIClient, error),
+	archivePath string,
+	systemDir string,
+) (commoncontext.BundleManifest, error) {
+	tmpDir := filepath.Clean(systemDir) + ".bundle"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return commoncontext.BundleManifest{}, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	manifest, err := commoncontext.ExtractBundle(archivePath, tmpDir)
+	if err != nil {
+		return manifest, err
+	}
+	if len(manifest.Ides) > 0 && (manifest.Os != runtime.GOOS || manifest.Arch != runtime.GOARCH) {
+		return manifest, fmt.Errorf(
+			"the bundle was created on %s/%s, its IDE distributions can't run on %s/%s",
+			manifest.Os,
+			manifest.Arch,
+			runtime.GOOS,
+			runtime.GOARCH,
+		)
+	}
+	if len(manifest.Images) > 0 {
+		docker, err := newClient(ctx)
+		if err != nil {
+			return manifest, fmt.Errorf("failed to initialize Docker API: %w", err)
+		}
+		if err = loadImages(ctx, docker, filepath.Join(tmpDir, filepath.FromSlash(commoncontext.BundleImagesArchive))); err != nil {
+			return manifest, err
+		}
+	}
+	for _, dir := range manifest.Dirs {
+		if err = installBundleDir(tmpDir, dir, systemDir); err != nil {
+			return manifest, fmt.Errorf("failed to install %s: %w", dir, err)
+		}
+	}
+	if len(manifest.Ides) > 0 {
+		if err = startup.RegisterBundledIdes(systemDir, manifest.Ides); err != nil {
+			return manifest, fmt.Errorf("failed to register the bundled IDEs: %w", err)
+		}
+	}
+	return manifest, nil
+}
+
+func loadImages(ctx context.Context, docker client.APIClient, imagesPath string) error {
+	file, err := os.Open(imagesPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	response, err := docker.ImageLoad(ctx, file)
+	if err != nil {
+		return fmt.Errorf("failed to load the images: %w", err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.JSON {
+		err = jsonmessage.DisplayJSONMessagesStream(response.Body, io.Discard, 0, false, nil)
+	} else {
+		_, err = io.Copy(io.Discard, response.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load the images: %w", err)
+	}
+	return nil
+}
+
+// installBundleDir replaces the installed directory with the extracted one of the bundle.
+func installBundleDir(extractedDir string, dir string, systemDir string) error {
+	root, rel, _ := strings.Cut(path.Clean(dir), "/")
+	if rel == "" || rel == ".." || strings.HasPrefix(rel, "../") {
+		return errors.New("illegal directory path")
+	}
+	var baseDir string
+	switch root {
+	case commoncontext.BundleSystemDir:
+		baseDir = systemDir
+	case commoncontext.BundleConfigDir:
+		configDir, err := qodanaConfigDir()
+		if err != nil {
+			return err
+		}
+		baseDir = configDir
+	default:
+		return fmt.Errorf("unexpected directory %s", root)
+	}
+	target := filepath.Join(baseDir, filepath.FromSlash(rel))
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	source := filepath.Join(extractedDir, filepath.FromSlash(path.Clean(dir)))
+	if err := os.Rename(source, target); err != nil {
+		log.Debugf("Failed to move %s, copying it: %s", source, err)
+		return cp.Copy(source, target)
+	}
+	return nil
+}
+
+// qodanaConfigDir returns <userConfigDir>/JetBrains/Qodana, the custom plugins of the native mode on macOS are there.
+func qodanaConfigDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "JetBrains", "Qodana"), nil
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// savedImagesClient is a Docker client saving the images to a fake archive, it records the loaded archives.
+type savedImagesClient struct {
+	client.APIClient
+	saved  [][]string
+	loaded []string
+}
+
+func (c *savedImagesClient) ImageSave(_ context.Context, images []string, _ ...client.ImageSaveOption) (io.ReadCloser, error) {
+	c.saved = append(c.saved, images)
+	return io.NopCloser(strings.NewReader("layers of " + strings.Join(images, ","))), nil
+}
+
+func (c *savedImagesClient) ImageLoad(_ context.Context, input io.Reader, _ ...client.ImageLoadOption) (image.LoadResponse, error) {
+	data, err := io.ReadAll(input)
+	c.loaded = append(c.loaded, string(data))
+	return image.LoadResponse{
+		Body: io.NopCloser(strings.NewReader(`{"stream":"Loaded image"}`)),
+		JSON: true,
+	}, err
+}
+
+func TestCreateAndLoadBundle(t *testing.T) {
+	t.Setenv(qdenv.QodanaOffline, "true")
+	dir := t.TempDir()
+	cli := filepath.Join(dir, "qodana")
+	require.NoError(t, os.WriteFile(cli, []byte("cli"), 0o755))
+	executablePath = func() (string, error) { return cli, nil }
+	defer func() { executablePath = os.Executable }()
+
+	docker := &savedImagesClient{}
+	archivePath := filepath.Join(dir, "bundle.tar.zst")
+	images := []string{"jetbrains/qodana-jvm:2024.3", "jetbrains/qodana-go:2024.3"}
+	manifest, err := CreateBundle(
+		context.Background(),
+		docker,
+		archivePath,
+		BundleOptions{Targets: PullTargets{Images: images}, Parallel: 1},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{images}, docker.saved)
+	assert.Equal(t, images, manifest.Images)
+	assert.Equal(t, []string{"system/qodana-cli-" + version.Version}, manifest.Dirs)
+	assert.Equal(t, 2, manifest.Files)
+
+	systemDir := filepath.Join(dir, "cache", "JetBrains", "Qodana")
+	loaded, err := LoadBundle(
+		context.Background(),
+		func(context.Context) (client.APIClient, error) { return docker, nil },
+		archivePath,
+		systemDir,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, loaded)
+	assert.Equal(t, []string{"layers of " + strings.Join(images, ",")}, docker.loaded)
+	installedCli, err := os.ReadFile(filepath.Join(systemDir, "qodana-cli-"+version.Version, "qodana"))
+	require.NoError(t, err)
+	assert.Equal(t, "cli", string(installedCli))
+	assert.NoDirExists(t, systemDir+".bundle")
+}
+
+func TestCreateBundleWithoutImages(t *testing.T) {
+	_, err := CreateBundle(
+		context.Background(),
+		&savedImagesClient{},
+		filepath.Join(t.TempDir(), "bundle.tar"),
+		BundleOptions{},
+	)
+	assert.ErrorContains(t, err, "no images to bundle")
+}
+
+func TestLoadImagesFailure(t *testing.T) {
+	imagesPath := filepath.Join(t.TempDir(), "images.tar")
+	require.NoError(t, os.WriteFile(imagesPath, []byte("layers"), 0o644))
+	docker := &failedLoadClient{}
+	assert.ErrorContains(t, loadImages(context.Background(), docker, imagesPath), "invalid tar header")
+}
+
+// failedLoadClient is a Docker client reporting an error in the output of the image load.
+type failedLoadClient struct {
+	client.APIClient
+}
+
+func (failedLoadClient) ImageLoad(context.Context, io.Reader, ...client.ImageLoadOption) (image.LoadResponse, error) {
+	return image.LoadResponse{
+		Body: io.NopCloser(bytes.NewReader([]byte(`{"errorDetail":{"message":"invalid tar header"}}`))),
+		JSON: true,
+	}, nil
+}
+
+func TestInstallBundleDir(t *testing.T) {
+	extractedDir := t.TempDir()
+	systemDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(extractedDir, "system", "ideaIC-2024.3", "bin"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(systemDir, "ideaIC-2024.3", "old"), 0o755))
+
+	require.NoError(t, installBundleDir(extractedDir, "system/ideaIC-2024.3", systemDir))
+	assert.DirExists(t, filepath.Join(systemDir, "ideaIC-2024.3", "bin"))
+	assert.NoDirExists(t, filepath.Join(systemDir, "ideaIC-2024.3", "old"))
+
+	for _, dir := range []string{"system", "system/../..", "images/x", "system/.."} {
+		assert.Error(t, installBundleDir(extractedDir, dir, systemDir), dir)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// cacheVolumeLabel marks the volumes created for --cache-volume, its value is the cache directory of the project on
+// the host: the volume is pruned with it.
+const cacheVolumeLabel = "com.jetbrains.qodana.cache"
+
+// ensureCacheVolume creates the named volume keeping the cache of the container, or reuses the existing one.
+func ensureCacheVolume(ctx context.Context, docker client.APIClient, name string, cacheDir string) error {
+	if !namedVolumeRegexp.MatchString(name) {
+		return fmt.Errorf("invalid cache volume name %q (letters, digits, '_', '.' and '-')", name)
+	}
+	volumes, err := docker.VolumeList(ctx, volume.ListOptions{Filters: filters.NewArgs(filters.Arg("name", name))})
+	if err != nil {
+		return fmt.Errorf("failed to list the volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		if v.Name == name {
+			log.Debugf("Reusing the cache volume %s", name)
+			return nil
+		}
+	}
+	if _, err = docker.VolumeCreate(
+		ctx,
+		volume.CreateOptions{Name: name, Labels: map[string]string{cacheVolumeLabel: cacheDir}},
+	); err != nil {
+		return fmt.Errorf("failed to create the cache volume %s: %w", name, err)
+	}
+	msg.SuccessMessage("Created the cache volume %s", name)
+	return nil
+}
+
+// PruneCacheVolumes removes the volumes created for --cache-volume whose cache directory on the host is one of the
+// removed ones or is already gone. The names of the (in dry run, to be) removed volumes are returned.
+func PruneCacheVolumes(ctx context.Context, docker client.APIClient, removedDirs []string, dryRun bool) ([]string, error) {
+	volumes, err := docker.VolumeList(ctx, volume.ListOptions{Filters: filters.NewArgs(filters.Arg("label", cacheVolumeLabel))})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the cache volumes: %w", err)
+	}
+	removed := make([]string, 0)
+	for _, name := range cacheVolumesToRemove(volumes.Volumes, removedDirs) {
+		if !dryRun {
+			if err := docker.VolumeRemove(ctx, name, false); err != nil {
+				return removed, fmt.Errorf("failed to remove the cache volume %s: %w", name, err)
+			}
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// cacheVolumesToRemove returns the names of the cache volumes of the removed directories, or of the directories that
+// don't exist anymore.
+func cacheVolumesToRemove(volumes []*volume.Volume, removedDirs []string) []string {
+	names := make([]string, 0)
+	for _, v := range volumes {
+		cacheDir := v.Labels[cacheVolumeLabel]
+		if cacheDir == "" {
+			continue
+		}
+		remove := false
+		for _, dir := range removedDirs {
+			if cacheDir == dir || strings.HasPrefix(cacheDir, dir+string(filepath.Separator)) {
+				remove = true
+				break
+			}
+		}
+		if _, err := os.Stat(cacheDir); errors.Is(err, os.ErrNotExist) {
+			remove = true
+		}
+		if remove {
+			names = append(names, v.Name)
+		}
+	}
+	return names
+}
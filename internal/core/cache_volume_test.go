@@ -0,0 +1,46 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheVolumesToRemove(t *testing.T) {
+	systemDir := t.TempDir()
+	kept, pruned := filepath.Join(systemDir, "kept"), filepath.Join(systemDir, "pruned")
+	writeTestFiles(
+		t, systemDir, map[string]string{
+			filepath.Join("kept", "cache", ".keep"):   "",
+			filepath.Join("pruned", "cache", ".keep"): "",
+		},
+	)
+	volumes := []*volume.Volume{
+		{Name: "kept-cache", Labels: map[string]string{cacheVolumeLabel: filepath.Join(kept, "cache")}},
+		{Name: "pruned-cache", Labels: map[string]string{cacheVolumeLabel: filepath.Join(pruned, "cache")}},
+		{Name: "gone-cache", Labels: map[string]string{cacheVolumeLabel: filepath.Join(systemDir, "gone", "cache")}},
+		{Name: "user-volume"},
+	}
+
+	assert.Equal(t, []string{"pruned-cache", "gone-cache"}, cacheVolumesToRemove(volumes, []string{pruned}))
+	assert.Equal(t, []string{"gone-cache"}, cacheVolumesToRemove(volumes, nil))
+	assert.Equal(t, []string{"gone-cache"}, cacheVolumesToRemove(volumes, []string{filepath.Join(systemDir, "kep")}))
+}
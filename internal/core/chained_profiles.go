@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// runQodanaWithChainedProfiles runs the analysis with the main profile and then with every chained profile against
+// the same caches, so the container is started and the project is indexed once for all of them.
+func runQodanaWithChainedProfiles(ctx context.Context, c corescan.Context) (int, error) {
+	exitCode, err := runQodana(ctx, c)
+	if err != nil || len(c.ChainProfiles()) == 0 || c.Analyser().IsContainer() {
+		// the CLI in the container runs the chained profiles itself
+		return exitCode, err
+	}
+	if exitCode != exitcodes.QodanaSuccessExitCode && exitCode != exitcodes.QodanaFailThresholdExitCode {
+		msg.WarningMessage("The chained profiles are skipped because the main analysis failed")
+		return exitCode, nil
+	}
+
+	restoreToken, err := withoutCloudUpload()
+	if err != nil {
+		return 1, err
+	}
+	defer restoreToken()
+	for i, profile := range c.ChainProfiles() {
+		chained := c.ChainedProfileRun(profile)
+		msg.WarningMessage("[%d/%d] Running analysis with profile %s", i+1, len(c.ChainProfiles()), profile)
+		res, err := runQodana(ctx, chained)
+		if err != nil {
+			return 1, err
+		}
+		switch res {
+		case exitcodes.QodanaSuccessExitCode, exitcodes.QodanaFailThresholdExitCode:
+			msg.SuccessMessage("The results of profile %s are saved to %s", profile, chained.ResultsDir())
+		default:
+			msg.ErrorMessage("The analysis with profile %s failed with exit code %d", profile, res)
+		}
+		if exitCode == exitcodes.QodanaSuccessExitCode {
+			exitCode = res
+		}
+	}
+	return exitCode, nil
+}
+
+// withoutCloudUpload hides QODANA_TOKEN from the chained runs, the IDE uploads the report when it's set and the
+// report of the main profile must stay the latest one in Qodana Cloud. The license is passed in QODANA_LICENSE.
+func withoutCloudUpload() (func(), error) {
+	token, isSet := os.LookupEnv(qdenv.QodanaToken)
+	if !isSet {
+		return func() {}, nil
+	}
+	if err := os.Unsetenv(qdenv.QodanaToken); err != nil {
+		return nil, fmt.Errorf("failed to hide %s from the chained profiles: %w", qdenv.QodanaToken, err)
+	}
+	return func() {
+		if err := os.Setenv(qdenv.QodanaToken, token); err != nil {
+			log.Warnf("Failed to restore %s: %s", qdenv.QodanaToken, err)
+		}
+	}, nil
+}
@@ -29,18 +29,25 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
 
 	"github.com/JetBrains/qodana-cli/internal/core/corescan"
 	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
 	"github.com/JetBrains/qodana-cli/internal/foundation/str"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/ideintegration"
+	"github.com/JetBrains/qodana-cli/internal/platform/metrics"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/redact"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	"github.com/JetBrains/qodana-cli/internal/platform/version"
 	"github.com/docker/docker/api/types/backend"
@@ -71,54 +78,115 @@ var (
 		Timestamps: false,
 	}
 	containerName = "qodana-cli"
+	// containerStopTimeout is how many seconds the interrupted linter has to save the partial results before the
+	// container is killed, set from --stop-timeout.
+	containerStopTimeout = 30
 )
 
-// runQodanaContainer runs the analysis in a Docker container from a Qodana image.
-func runQodanaContainer(ctx context.Context, c corescan.Context) int {
+// runQodanaContainer runs the analysis in a Docker container from a Qodana image. The container is stopped and the
+// spinner is stopped when the analysis fails, the error is returned to the caller.
+func runQodanaContainer(ctx context.Context, c corescan.Context) (int, error) {
 	dockerAnalyzer, ok := c.Analyser().(*product.DockerAnalyzer)
 	if !ok {
-		log.Fatalf("Context is not a DockerAnalyzer")
+		return 1, errors.New("context is not a DockerAnalyzer")
 	}
 	docker, err := qdcontainer.NewContainerClient(ctx)
 	if err != nil {
-		log.Fatal("Couldn't retrieve Docker daemon information", err)
+		return 1, fmt.Errorf("couldn't retrieve Docker daemon information: %w", err)
 	}
 
 	info, err := docker.Info(ctx)
 	if err != nil {
-		log.Fatal("Couldn't retrieve Docker daemon information", err)
+		return 1, fmt.Errorf("couldn't retrieve Docker daemon information: %w", err)
 	}
 	if info.OSType != "linux" {
 		msg.ErrorMessage("Container engine is not running a Linux platform, other platforms are not supported by Qodana")
-		return 1
+		return 1, nil
 	}
 	fixDarwinCaches(c.CacheDir())
+	if c.CacheVolume() != "" {
+		if err := ensureCacheVolume(ctx, docker, c.CacheVolume(), c.CacheDir()); err != nil {
+			msg.ErrorMessage("%s", err)
+			return 1, nil
+		}
+	}
 
 	scanStages := getScanStages()
 
 	dockerImage := dockerAnalyzer.Image
 	CheckImage(dockerImage)
-	if !c.SkipPull() {
-		PullImage(docker, dockerImage)
+	if qdenv.IsOffline() {
+		if _, err := docker.ImageInspect(ctx, dockerImage); err != nil {
+			msg.ErrorMessage(
+				"The image %s is not available locally, it can't be pulled in the offline mode: %s",
+				dockerImage,
+				err,
+			)
+			return 1, nil
+		}
+		metrics.CacheHit(metrics.ImageCache)
+	} else if !c.SkipPull() {
+		if err := PullImage(ctx, docker, dockerImage); err != nil {
+			return 1, err
+		}
+	}
+	if err := CheckImageVulnerabilities(
+		dockerImage,
+		c.ImageVulnCheck(),
+		c.ImageVulnLevel(),
+	); err != nil {
+		msg.ErrorMessage("%s", err)
+		return 1, nil
 	}
+	recordEnvironment(c, containerEnvironment(ctx, docker, info, dockerImage))
 	progress, _ := msg.StartQodanaSpinner(scanStages[0])
+	defer func() {
+		if progress != nil {
+			_ = progress.Stop()
+		}
+	}()
 
-	dockerConfig := getDockerOptions(c, dockerImage)
+	dockerConfig, err := getDockerOptions(c, dockerImage, detectUserNamespace(info.SecurityOptions))
+	if err != nil {
+		return 1, err
+	}
+	redact.AddEnv(dockerConfig.Config.Env)
 	log.Debugf("docker command to run: %s", generateDebugDockerRunCommand(dockerConfig))
 
 	msg.UpdateText(progress, scanStages[1])
 
-	runContainer(ctx, docker, dockerConfig)
-	go followLinter(docker, dockerConfig.Name, progress, scanStages)
+	containerId, err := runContainer(ctx, docker, dockerConfig)
+	if err != nil {
+		ContainerCleanup()
+		return 1, err
+	}
+	state := newScanState(c, containerId, dockerConfig.Name, dockerImage)
+	state.Stage = 1
+	if err := WriteScanState(c.ResultsDir(), state); err != nil {
+		log.Warnf("Could not persist scan state: %s", err)
+	}
+	followErr := make(chan error, 1)
+	go func() {
+		followErr <- followLinter(docker, dockerConfig.Name, containerLogsOptions, progress, scanStages, &state)
+	}()
+	liveProblemsCtx, stopLiveProblems := context.WithCancel(ctx)
+	if c.LiveProblems() || ideintegration.IsEnabled() {
+		go platform.FollowSarifProblems(liveProblemsCtx, platform.GetSarifPath(c.ResultsDir()), c.LiveProblems())
+	}
 
-	exitCode := getContainerExitCode(ctx, docker, dockerConfig.Name)
+	exitCode, err := getContainerExitCode(ctx, docker, dockerConfig.Name)
+	stopLiveProblems()
+	if err != nil {
+		ContainerCleanup()
+		return 1, err
+	}
+	if err = <-followErr; err != nil {
+		return 1, err
+	}
+	RemoveScanState(c.ResultsDir())
 
 	fixDarwinCaches(c.CacheDir())
-
-	if progress != nil {
-		_ = progress.Stop()
-	}
-	return int(exitCode)
+	return int(exitCode), nil
 }
 
 // isUnofficialLinter checks if the linter is unofficial.
@@ -142,6 +210,7 @@ func CheckImage(linter string) {
 		return
 	}
 
+	linter = qdcontainer.UnmirrorImage(linter)
 	if isUnofficialLinter(linter) {
 		msg.WarningMessageCI("You are using an unofficial Qodana linter: %s\n", linter)
 	}
@@ -202,29 +271,98 @@ func encodeAuthToBase64(authConfig registry.AuthConfig) (string, error) {
 	return base64.URLEncoding.EncodeToString(buf), nil
 }
 
-// PullImage pulls docker image and prints the process.
-func PullImage(client client.APIClient, image string) {
-	ctx := context.Background()
-	var pullErr error
+// PullImage pulls docker image and prints the process, the local image is used if the pull fails. The pull is
+// stopped when the context is cancelled.
+func PullImage(ctx context.Context, client client.APIClient, image string) error {
+	defer timings.Start(timings.ImagePull)()
+	var warning, err error
 	msg.PrintProcess(
 		func(_ *pterm.SpinnerPrinter) {
-			pullErr = pullImage(ctx, client, image)
+			warning, err = pullLatestImage(ctx, client, image)
 		},
 		fmt.Sprintf("Pulling the image %s", msg.PrimaryBold(image)),
 		"",
 	)
-	if pullErr != nil {
+	if err != nil {
+		return err
+	}
+	if warning != nil {
+		msg.WarningMessage(
+			"Could not pull the latest image %s, using the local image instead: %s",
+			msg.PrimaryBold(image),
+			warning,
+		)
+		return nil
+	}
+	msg.SuccessMessage("Finished pulling the latest version of linter")
+	return nil
+}
+
+// PullImages pulls the images by parallel images at a time like PullImage. All images are tried, the errors of the
+// failed ones are returned.
+func PullImages(ctx context.Context, client client.APIClient, images []string, parallel int) error {
+	defer timings.Start(timings.ImagePull)()
+	warnings := make([]error, len(images))
+	errs := make([]error, len(images))
+	msg.PrintProcess(
+		func(_ *pterm.SpinnerPrinter) {
+			semaphore := make(chan struct{}, max(parallel, 1))
+			var wg sync.WaitGroup
+			for i, image := range images {
+				wg.Add(1)
+				semaphore <- struct{}{}
+				go func() {
+					defer func() { <-semaphore; wg.Done() }()
+					warnings[i], errs[i] = pullLatestImage(ctx, client, image)
+				}()
+			}
+			wg.Wait()
+		},
+		fmt.Sprintf("Pulling %d images", len(images)),
+		"",
+	)
+	for i, image := range images {
+		switch {
+		case errs[i] != nil:
+			errs[i] = fmt.Errorf("%s: %w", image, errs[i])
+			msg.ErrorMessage("Could not pull the image %s: %s", msg.PrimaryBold(image), errs[i])
+		case warnings[i] != nil:
+			msg.WarningMessage("Could not pull the latest image %s, using the local image instead: %s", msg.PrimaryBold(image), warnings[i])
+		default:
+			msg.SuccessMessage("Pulled the image %s", msg.PrimaryBold(image))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pullLatestImage pulls the image, the local image is used if the pull fails: the pull error is returned as the warning.
+func pullLatestImage(ctx context.Context, client client.APIClient, image string) (warning error, err error) {
+	localImageId := imageId(ctx, client, image)
+	if pullErr := pullImage(ctx, client, image); pullErr != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if _, err := client.ImageInspect(ctx, image); err == nil {
-			msg.WarningMessage(
-				"Could not pull the latest image %s, using the local image instead: %s",
-				msg.PrimaryBold(image),
-				pullErr,
-			)
-			return
+			metrics.CacheHit(metrics.ImageCache)
+			return pullErr, nil
 		}
-		log.Fatal(pullErr)
+		return nil, pullErr
 	}
-	msg.SuccessMessage("Finished pulling the latest version of linter")
+	if localImageId != "" && localImageId == imageId(ctx, client, image) {
+		metrics.CacheHit(metrics.ImageCache)
+	} else {
+		metrics.CacheMiss(metrics.ImageCache)
+	}
+	return nil, nil
+}
+
+// imageId returns the ID of the local image, empty if there is no such image.
+func imageId(ctx context.Context, client client.APIClient, image string) string {
+	inspect, err := client.ImageInspect(ctx, image)
+	if err != nil {
+		return ""
+	}
+	return inspect.ID
 }
 
 func isDockerUnauthorizedError(errMsg string) bool {
@@ -235,16 +373,42 @@ func isDockerUnauthorizedError(errMsg string) bool {
 	)
 }
 
-// pullImage pulls docker image.
-func pullImage(ctx context.Context, client client.APIClient, ref string) (err error) {
-	reader, err := client.ImagePull(ctx, ref, image.PullOptions{})
+// pullImage pulls docker image, through the registry mirror from the user configuration if there is one. The image
+// pulled from the mirror is tagged with the original name, the analysis runs it as usual.
+func pullImage(ctx context.Context, client client.APIClient, ref string) error {
+	source := qdcontainer.ResolveImage(ref)
+	if err := pullImageFrom(ctx, client, source); err != nil {
+		if source != ref {
+			return fmt.Errorf("%w (pulled from the mirror %s)", err, source)
+		}
+		return err
+	}
+	if source != ref {
+		if err := client.ImageTag(ctx, source, ref); err != nil {
+			return fmt.Errorf("can't tag %s as %s: %w", source, ref, err)
+		}
+	}
+	return nil
+}
+
+// pullImageFrom pulls the image with the credentials given explicitly or from the credential helper of the cloud
+// registry, or anonymously with a retry with the credentials from the Docker config if the registry requires them.
+func pullImageFrom(ctx context.Context, client client.APIClient, ref string) (err error) {
+	options := image.PullOptions{}
+	auth, hasAuth := qdcontainer.RegistryAuth(ctx, qdcontainer.RegistryHost(ref))
+	if hasAuth {
+		if options.RegistryAuth, err = encodeAuthToBase64(auth); err != nil {
+			return fmt.Errorf("can't encode auth to base64: %w", err)
+		}
+	}
+	reader, err := client.ImagePull(ctx, ref, options)
 	defer func() {
 		if reader != nil {
 			err = errors.Join(err, reader.Close())
 		}
 		reader = nil
 	}()
-	if err != nil && isDockerUnauthorizedError(err.Error()) {
+	if err != nil && !hasAuth && isDockerUnauthorizedError(err.Error()) {
 		if reader != nil {
 			_ = reader.Close()
 			reader = nil
@@ -277,32 +441,65 @@ func pullImage(ctx context.Context, client client.APIClient, ref string) (err er
 	return nil
 }
 
-// ContainerCleanup cleans up Qodana containers.
+// ContainerCleanup stops the Qodana container of the run, the linter gets --stop-timeout seconds to save the partial
+// results. The container that was created but not started is removed unless QODANA_CLI_CONTAINER_KEEP is set, the
+// started one is removed by Docker. It's called on the way out, so the failures are only logged.
 func ContainerCleanup() {
+	cleanupContainer(
+		func(docker client.APIClient, id string) error {
+			timeout := containerStopTimeout
+			return docker.ContainerStop(context.Background(), id, container.StopOptions{Timeout: &timeout})
+		},
+	)
+}
+
+// ContainerKill kills the Qodana container of the run without waiting for the linter, e.g. on the second interrupt.
+func ContainerKill() {
+	cleanupContainer(
+		func(docker client.APIClient, id string) error {
+			return docker.ContainerKill(context.Background(), id, "SIGKILL")
+		},
+	)
+}
+
+// cleanupContainer stops the running Qodana container of the run with stop and removes the one that wasn't started.
+func cleanupContainer(stop func(docker client.APIClient, id string) error) {
 	if containerName != "qodana-cli" { // if containerName is not set, it means that the container was not created!
 		ctx := context.Background()
 		docker, err := qdcontainer.NewContainerClient(ctx)
 		if err != nil {
-			log.Fatal("failed to initialize Docker API:", err)
+			log.Errorf("Failed to initialize Docker API: %s", err)
+			return
 		}
+		removeContainer(ctx, docker, stop)
+	}
+}
 
-		containers, err := docker.ContainerList(ctx, container.ListOptions{})
-		if err != nil {
-			log.Fatal("couldn't get the running containers ", err)
+func removeContainer(ctx context.Context, docker client.APIClient, stop func(docker client.APIClient, id string) error) {
+	containers, err := docker.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		log.Errorf("Couldn't get the containers: %s", err)
+		return
+	}
+	for _, c := range containers {
+		if len(c.Names) == 0 || c.Names[0] != fmt.Sprintf("/%s", containerName) {
+			continue
 		}
-		for _, c := range containers {
-			if c.Names[0] == fmt.Sprintf("/%s", containerName) {
-				err = docker.ContainerStop(context.Background(), c.Names[0], container.StopOptions{})
-				if err != nil {
-					log.Fatal("couldn't stop the container ", err)
-				}
+		switch {
+		case c.State == container.StateRunning:
+			if err = stop(docker, c.ID); err != nil {
+				log.Errorf("Couldn't stop the container %s: %s", containerName, err)
+			}
+		case c.State == container.StateCreated && os.Getenv(qdenv.QodanaCliContainerKeep) == "":
+			if err = docker.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+				log.Errorf("Couldn't remove the container %s: %s", containerName, err)
 			}
 		}
 	}
 }
 
 // getDockerOptions returns qodana docker container options.
-func getDockerOptions(c corescan.Context, image string) *backend.ContainerCreateConfig {
+func getDockerOptions(c corescan.Context, image string, userns userNamespace) (*backend.ContainerCreateConfig, error) {
 	cmdOpts := GetIdeArgs(c)
 
 	updateScanContextEnv := func(key string, value string) { c = c.WithEnvExtractedFromOsEnv(key, value) }
@@ -313,6 +510,9 @@ func getDockerOptions(c corescan.Context, image string) *backend.ContainerCreate
 	if qodanaCloudUploadToken != "" {
 		dockerEnv = append(dockerEnv, fmt.Sprintf("%s=%s", qdenv.QodanaToken, qodanaCloudUploadToken))
 	}
+	if c.QodanaYamlConfig().RemoteCache != nil {
+		dockerEnv = append(dockerEnv, qdenv.QodanaRemoteCacheOnHost+"=true")
+	}
 	qodanaLicenseOnlyToken := os.Getenv(qdenv.QodanaLicenseOnlyToken)
 	if qodanaLicenseOnlyToken != "" && qodanaCloudUploadToken == "" {
 		dockerEnv = append(dockerEnv, fmt.Sprintf("%s=%s", qdenv.QodanaLicenseOnlyToken, qodanaLicenseOnlyToken))
@@ -320,35 +520,48 @@ func getDockerOptions(c corescan.Context, image string) *backend.ContainerCreate
 
 	cachePath, err := fs.Canonical(c.CacheDir())
 	if err != nil {
-		log.Fatal("couldn't get canonical path for cache", err)
+		return nil, fmt.Errorf("couldn't get canonical path for cache: %w", err)
 	}
 	repositoryRootPath, err := fs.Canonical(c.RepositoryRoot())
 	if err != nil {
-		log.Fatal("couldn't get canonical path for project", err)
+		return nil, fmt.Errorf("couldn't get canonical path for project: %w", err)
 	}
 	resultsPath, err := fs.Canonical(c.ResultsDir())
 	if err != nil {
-		log.Fatal("couldn't get canonical path for results", err)
+		return nil, fmt.Errorf("couldn't get canonical path for results: %w", err)
 	}
 	reportPath, err := fs.Canonical(c.ReportDir())
 	if err != nil {
-		log.Fatal("couldn't get canonical path for report", err)
+		return nil, fmt.Errorf("couldn't get canonical path for report: %w", err)
 	}
 	containerName = os.Getenv(qdenv.QodanaCliContainerName)
 	if containerName == "" {
 		containerName = fmt.Sprintf("qodana-cli-%s", c.Id())
 	}
+	containerStopTimeout = c.StopTimeout()
+	stopTimeout := containerStopTimeout
+	projectMount := mount.Mount{
+		Type:   mount.TypeBind,
+		Source: repositoryRootPath,
+		Target: qdcontainer.MountDir,
+	}
+	if c.ReadOnlyProject() {
+		projectMount, err = projectOverlayMount(repositoryRootPath, projectOverlayDir(c))
+		if err != nil {
+			return nil, fmt.Errorf("cannot mount the project read-only: %w", err)
+		}
+	}
+	cacheMount := mount.Mount{
+		Type:   mount.TypeBind,
+		Source: cachePath,
+		Target: qdcontainer.DataCacheDir,
+	}
+	if c.CacheVolume() != "" {
+		cacheMount = mount.Mount{Type: mount.TypeVolume, Source: c.CacheVolume(), Target: qdcontainer.DataCacheDir}
+	}
 	volumes := []mount.Mount{
-		{
-			Type:   mount.TypeBind,
-			Source: cachePath,
-			Target: qdcontainer.DataCacheDir,
-		},
-		{
-			Type:   mount.TypeBind,
-			Source: repositoryRootPath,
-			Target: qdcontainer.MountDir,
-		},
+		cacheMount,
+		projectMount,
 		{
 			Type:   mount.TypeBind,
 			Source: resultsPath,
@@ -363,8 +576,8 @@ func getDockerOptions(c corescan.Context, image string) *backend.ContainerCreate
 	if c.GlobalConfigurationsDir() != "" {
 		globalConfigDirAbsPath, err := fs.Canonical(c.GlobalConfigurationsDir())
 		if err != nil {
-			log.Fatalf(
-				"Failed to get absolute path for global configurations file %s: %s",
+			return nil, fmt.Errorf(
+				"failed to get absolute path for global configurations file %s: %w",
 				c.GlobalConfigurationsDir(),
 				err,
 			)
@@ -377,24 +590,37 @@ func getDockerOptions(c corescan.Context, image string) *backend.ContainerCreate
 			},
 		)
 	}
+	var binds []string
 	for _, volume := range c.Volumes() {
-		source, target := extractDockerVolumes(volume)
-		if source != "" && target != "" {
-			volumes = append(
-				volumes, mount.Mount{
-					Type:   mount.TypeBind,
-					Source: source,
-					Target: target,
-				},
-			)
+		v, err := parseDockerVolume(volume)
+		if err != nil {
+			return nil, err
+		}
+		if v.SELinuxLabel != "" {
+			binds = append(binds, v.bind())
 		} else {
-			log.Fatal("couldn't parse volume ", volume)
+			volumes = append(volumes, v.Mount)
+		}
+	}
+	for _, tmpfs := range c.Tmpfs() {
+		m, err := parseTmpfsMount(tmpfs)
+		if err != nil {
+			return nil, err
 		}
+		volumes = append(volumes, m)
+	}
+	for _, yamlMount := range c.QodanaYamlConfig().Mounts {
+		m, err := qodanaYamlMount(yamlMount)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, m)
 	}
 	log.Debugf("image: %s", image)
 	log.Debugf("container name: %s", containerName)
 	log.Debugf("user: %s", c.User())
 	log.Debugf("volumes: %v", volumes)
+	log.Debugf("binds: %v", binds)
 	log.Debugf("cmd: %v", cmdOpts)
 
 	portBindings := make(nat.PortMap)
@@ -431,9 +657,12 @@ func getDockerOptions(c corescan.Context, image string) *backend.ContainerCreate
 		networkMode = network.NetworkHost
 	}
 
+	user, usernsMode := selectContainerUser(image, c.User(), userns)
 	var hostConfig = &container.HostConfig{
 		AutoRemove:   os.Getenv(qdenv.QodanaCliContainerKeep) == "",
+		UsernsMode:   usernsMode,
 		Mounts:       volumes,
+		Binds:        binds,
 		CapAdd:       capAdd,
 		SecurityOpt:  securityOpt,
 		PortBindings: portBindings,
@@ -449,11 +678,12 @@ func getDockerOptions(c corescan.Context, image string) *backend.ContainerCreate
 			AttachStdout: true,
 			AttachStderr: true,
 			Env:          dockerEnv,
-			User:         selectUser(image, c.User()),
+			User:         user,
 			ExposedPorts: exposedPorts,
+			StopTimeout:  &stopTimeout,
 		},
 		HostConfig: hostConfig,
-	}
+	}, nil
 }
 
 var rePrivilegedImage = regexp.MustCompile(`^(jetbrains|registry.jetbrains.team)/.+-privileged.*$`)
@@ -470,6 +700,59 @@ func selectUser(image string, userFromContext string) string {
 	return userFromContext // Do not modify explicit user input
 }
 
+// userNamespace is the user namespace mode of the Docker daemon: how the users of the container map to the host ones.
+type userNamespace int
+
+const (
+	// userNamespaceHost is the default mode, the users of the container are the users of the host.
+	userNamespaceHost userNamespace = iota
+	// userNamespaceRemap is the userns-remap mode, the users of the container are shifted to the subordinate IDs of
+	// the remap user.
+	userNamespaceRemap
+	// userNamespaceRootless is a rootless daemon, root of the container is the user running the daemon.
+	userNamespaceRootless
+)
+
+// detectUserNamespace returns the user namespace mode from the security options of the daemon info,
+// e.g. "name=seccomp,profile=builtin" or "name=userns".
+func detectUserNamespace(securityOptions []string) userNamespace {
+	userns := userNamespaceHost
+	for _, option := range securityOptions {
+		for _, field := range strings.Split(option, ",") {
+			switch field {
+			case "name=rootless":
+				return userNamespaceRootless
+			case "name=userns":
+				userns = userNamespaceRemap
+			}
+		}
+	}
+	return userns
+}
+
+// selectContainerUser returns the user of the container and its user namespace mode, for the files written to the
+// mounted directories to be owned by the user running the CLI:
+//   - with a rootless daemon, the container runs as root, which is the user running the daemon on the host;
+//   - with userns-remap, the host user namespace is used for the container, the IDs would be shifted otherwise.
+//
+// An explicit --user is never changed.
+func selectContainerUser(image string, userFromContext string, userns userNamespace) (string, container.UsernsMode) {
+	user := selectUser(image, userFromContext)
+	if userFromContext != "auto" || user == "" || runtime.GOOS == "windows" {
+		return user, ""
+	}
+	switch userns {
+	case userNamespaceRootless:
+		log.Debugf("Rootless Docker daemon detected, running the container as root mapped to the current user")
+		return "0:0", ""
+	case userNamespaceRemap:
+		log.Debugf("Docker daemon with userns-remap detected, running the container in the host user namespace")
+		return user, "host"
+	default:
+		return user, ""
+	}
+}
+
 func generateDebugDockerRunCommand(cfg *backend.ContainerCreateConfig) string {
 	var cmdBuilder strings.Builder
 	cmdBuilder.WriteString("docker run ")
@@ -488,17 +771,26 @@ func generateDebugDockerRunCommand(cfg *backend.ContainerCreateConfig) string {
 	if cfg.Config.User != "" {
 		cmdBuilder.WriteString(fmt.Sprintf("-u %s ", cfg.Config.User))
 	}
+	if cfg.Config.StopTimeout != nil {
+		cmdBuilder.WriteString(fmt.Sprintf("--stop-timeout %d ", *cfg.Config.StopTimeout))
+	}
 	for _, env := range cfg.Config.Env {
-		if !strings.Contains(env, qdenv.QodanaToken) || strings.Contains(
-			env,
-			qdenv.QodanaLicense,
-		) || strings.Contains(env, qdenv.QodanaLicenseOnlyToken) {
-			cmdBuilder.WriteString(fmt.Sprintf("-e %s ", env))
-		}
+		cmdBuilder.WriteString(fmt.Sprintf("-e %s ", redact.Env(env)))
 	}
 	if cfg.HostConfig != nil {
 		for _, m := range cfg.HostConfig.Mounts {
-			cmdBuilder.WriteString(fmt.Sprintf("-v %s:%s ", m.Source, m.Target))
+			if m.VolumeOptions != nil && m.VolumeOptions.DriverConfig != nil {
+				cmdBuilder.WriteString(fmt.Sprintf("--mount %s ", volumeDriverMount(m)))
+				continue
+			}
+			if m.Type == mount.TypeTmpfs {
+				cmdBuilder.WriteString(fmt.Sprintf("--tmpfs %s ", tmpfs(m)))
+				continue
+			}
+			cmdBuilder.WriteString(fmt.Sprintf("-v %s ", dockerVolume{Mount: m}.bind()))
+		}
+		for _, bind := range cfg.HostConfig.Binds {
+			cmdBuilder.WriteString(fmt.Sprintf("-v %s ", bind))
 		}
 		for _, capAdd := range cfg.HostConfig.CapAdd {
 			cmdBuilder.WriteString(fmt.Sprintf("--cap-add %s ", capAdd))
@@ -506,6 +798,9 @@ func generateDebugDockerRunCommand(cfg *backend.ContainerCreateConfig) string {
 		for _, secOpt := range cfg.HostConfig.SecurityOpt {
 			cmdBuilder.WriteString(fmt.Sprintf("--security-opt %s ", secOpt))
 		}
+		if cfg.HostConfig.UsernsMode != "" {
+			cmdBuilder.WriteString(fmt.Sprintf("--userns %s ", cfg.HostConfig.UsernsMode))
+		}
 	}
 	cmdBuilder.WriteString(cfg.Config.Image + " ")
 	for _, arg := range cfg.Config.Cmd {
@@ -515,22 +810,33 @@ func generateDebugDockerRunCommand(cfg *backend.ContainerCreateConfig) string {
 	return cmdBuilder.String()
 }
 
-// getContainerExitCode returns the exit code of the docker container.
-func getContainerExitCode(ctx context.Context, client client.APIClient, id string) int64 {
-	statusCh, errCh := client.ContainerWait(ctx, id, container.WaitConditionNextExit)
-	select {
-	case err := <-errCh:
-		if err != nil {
-			log.Fatal("container hasn't finished ", err)
+// getContainerExitCode returns the exit code of the docker container. When the context is cancelled, the container
+// is stopped: the linter gets containerStopTimeout seconds to save the partial results, and its exit code is returned.
+func getContainerExitCode(ctx context.Context, client client.APIClient, id string) (int64, error) {
+	statusCh, errCh := client.ContainerWait(context.WithoutCancel(ctx), id, container.WaitConditionNextExit)
+	done := ctx.Done()
+	for {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				return 0, fmt.Errorf("container hasn't finished: %w", err)
+			}
+			return 0, nil
+		case status := <-statusCh:
+			return status.StatusCode, nil
+		case <-done:
+			done = nil
+			timeout := containerStopTimeout
+			log.Debugf("Stopping the container %s with the timeout %ds", id, timeout)
+			if err := client.ContainerStop(context.Background(), id, container.StopOptions{Timeout: &timeout}); err != nil {
+				log.Warnf("Couldn't stop the container %s: %s", id, err)
+			}
 		}
-	case status := <-statusCh:
-		return status.StatusCode
 	}
-	return 0
 }
 
-// runContainer runs the container.
-func runContainer(ctx context.Context, client client.APIClient, opts *backend.ContainerCreateConfig) {
+// runContainer runs the container and returns its ID.
+func runContainer(ctx context.Context, client client.APIClient, opts *backend.ContainerCreateConfig) (string, error) {
 	createResp, err := client.ContainerCreate(
 		ctx,
 		opts.Config,
@@ -540,27 +846,64 @@ func runContainer(ctx context.Context, client client.APIClient, opts *backend.Co
 		opts.Name,
 	)
 	if err != nil {
-		log.Fatal("couldn't create the container ", err)
+		return "", fmt.Errorf("couldn't create the container: %w", err)
 	}
 	if err = client.ContainerStart(ctx, createResp.ID, container.StartOptions{}); err != nil {
-		log.Fatal("couldn't bootstrap the container ", err)
+		return "", fmt.Errorf("couldn't bootstrap the container: %w", err)
 	}
+	return createResp.ID, nil
 }
 
-// extractDockerVolumes extracts the source and target of the volume to mount.
-func extractDockerVolumes(volume string) (string, string) {
-	if //goland:noinspection GoBoolExpressions
-	runtime.GOOS == "windows" {
-		parts := strings.Split(volume, ":")
-		if len(parts) >= 3 {
-			return fmt.Sprintf("%s:%s", parts[0], parts[1]), parts[2]
-		}
-	} else {
-		source := str.SafeSplit(volume, ":", 0)
-		target := str.SafeSplit(volume, ":", 1)
-		if source != "" && target != "" {
-			return source, target
+// attachLogsOptions follows the logs written after the attach, the ones before it were printed by the process that
+// started the analysis.
+func attachLogsOptions() container.LogsOptions {
+	options := containerLogsOptions
+	options.Tail = "0"
+	return options
+}
+
+// AttachToContainer reattaches to the container analysis recorded by a previous CLI process,
+// resumes log streaming and waits for the container to finish. Returns the container exit code.
+func AttachToContainer(ctx context.Context, state ScanState) (int, error) {
+	docker, err := qdcontainer.NewContainerClient(ctx)
+	if err != nil {
+		return 1, fmt.Errorf("couldn't connect to the container engine: %w", err)
+	}
+	info, err := docker.ContainerInspect(ctx, state.ContainerId)
+	if err != nil {
+		return 1, fmt.Errorf("container %s of the recorded analysis is not found: %w", state.ContainerName, err)
+	}
+	if info.State == nil || !info.State.Running {
+		return 1, fmt.Errorf("container %s of the recorded analysis is not running anymore", state.ContainerName)
+	}
+	containerName = state.ContainerName
+
+	scanStages := getScanStages()
+	stage := min(max(state.Stage, 0), len(scanStages)-1)
+	msg.SuccessMessage(
+		"Attaching to %s started at %s",
+		msg.PrimaryBold(state.ContainerName),
+		state.StartTime.Format(time.RFC3339),
+	)
+	progress, _ := msg.StartQodanaSpinner(scanStages[stage])
+	defer func() {
+		if progress != nil {
+			_ = progress.Stop()
 		}
+	}()
+	followErr := make(chan error, 1)
+	go func() {
+		followErr <- followLinter(docker, state.ContainerId, attachLogsOptions(), progress, scanStages, &state)
+	}()
+
+	exitCode, err := getContainerExitCode(ctx, docker, state.ContainerId)
+	if err != nil {
+		return 1, err
+	}
+	if err = <-followErr; err != nil {
+		return 1, err
 	}
-	return "", ""
+	RemoveScanState(state.ResultsDir)
+	fixDarwinCaches(state.CacheDir)
+	return int(exitCode), nil
 }
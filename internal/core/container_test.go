@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -14,8 +15,10 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
 
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -99,6 +102,45 @@ func TestSelectUser(t *testing.T) {
 	)
 }
 
+func TestDetectUserNamespace(t *testing.T) {
+	assert.Equal(t, userNamespaceHost, detectUserNamespace(nil))
+	assert.Equal(
+		t,
+		userNamespaceHost,
+		detectUserNamespace([]string{"name=apparmor", "name=seccomp,profile=builtin", "name=cgroupns"}),
+	)
+	assert.Equal(t, userNamespaceRemap, detectUserNamespace([]string{"name=seccomp,profile=builtin", "name=userns"}))
+	assert.Equal(t, userNamespaceRootless, detectUserNamespace([]string{"name=seccomp,profile=default", "name=rootless"}))
+}
+
+func TestSelectContainerUser(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The user namespaces are not applicable on Windows")
+	}
+	image := "jetbrains/qodana-jvm:2025.2"
+	privilegedImage := "jetbrains/qodana-cpp:2025.2-eap-clang18-privileged"
+
+	user, usernsMode := selectContainerUser(image, "auto", userNamespaceHost)
+	assert.Equal(t, utils.GetDefaultUser(), user)
+	assert.Equal(t, container.UsernsMode(""), usernsMode)
+
+	user, usernsMode = selectContainerUser(image, "auto", userNamespaceRootless)
+	assert.Equal(t, "0:0", user)
+	assert.Equal(t, container.UsernsMode(""), usernsMode)
+
+	user, usernsMode = selectContainerUser(image, "auto", userNamespaceRemap)
+	assert.Equal(t, utils.GetDefaultUser(), user)
+	assert.Equal(t, container.UsernsMode("host"), usernsMode)
+
+	// Privileged images and explicit users are unaffected
+	user, usernsMode = selectContainerUser(privilegedImage, "auto", userNamespaceRemap)
+	assert.Equal(t, "", user)
+	assert.Equal(t, container.UsernsMode(""), usernsMode)
+	user, usernsMode = selectContainerUser(image, "1337", userNamespaceRootless)
+	assert.Equal(t, "1337", user)
+	assert.Equal(t, container.UsernsMode(""), usernsMode)
+}
+
 func TestEncodeAuthToBase64(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -231,84 +273,6 @@ func TestFixDarwinCaches(t *testing.T) {
 	fixDarwinCaches(dir)
 }
 
-func TestExtractDockerVolumes(t *testing.T) {
-	if runtime.GOOS == "windows" {
-		// Windows expects volumes like C:\host\path:/container/path (3 parts when split by :)
-		tests := []struct {
-			name           string
-			volume         string
-			expectedSource string
-			expectedTarget string
-		}{
-			{
-				name:           "windows volume",
-				volume:         "C:\\host\\path:/container/path",
-				expectedSource: "C:\\host\\path",
-				expectedTarget: "/container/path",
-			},
-			{
-				name:           "empty volume",
-				volume:         "",
-				expectedSource: "",
-				expectedTarget: "",
-			},
-			{
-				name:           "missing target",
-				volume:         "C:\\host\\path",
-				expectedSource: "",
-				expectedTarget: "",
-			},
-		}
-		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				source, target := extractDockerVolumes(tt.volume)
-				assert.Equal(t, tt.expectedSource, source)
-				assert.Equal(t, tt.expectedTarget, target)
-			})
-		}
-	} else {
-		// Unix-style volumes
-		tests := []struct {
-			name           string
-			volume         string
-			expectedSource string
-			expectedTarget string
-		}{
-			{
-				name:           "simple volume",
-				volume:         "/host/path:/container/path",
-				expectedSource: "/host/path",
-				expectedTarget: "/container/path",
-			},
-			{
-				name:           "empty volume",
-				volume:         "",
-				expectedSource: "",
-				expectedTarget: "",
-			},
-			{
-				name:           "missing target",
-				volume:         "/host/path",
-				expectedSource: "",
-				expectedTarget: "",
-			},
-			{
-				name:           "with spaces in path",
-				volume:         "/host/path with spaces:/container/path",
-				expectedSource: "/host/path with spaces",
-				expectedTarget: "/container/path",
-			},
-		}
-		for _, tt := range tests {
-			t.Run(tt.name, func(t *testing.T) {
-				source, target := extractDockerVolumes(tt.volume)
-				assert.Equal(t, tt.expectedSource, source)
-				assert.Equal(t, tt.expectedTarget, target)
-			})
-		}
-	}
-}
-
 func TestGenerateDebugDockerRunCommand(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -449,6 +413,100 @@ func TestGenerateDebugDockerRunCommand_FiltersTokens(t *testing.T) {
 
 	result := generateDebugDockerRunCommand(cfg)
 	assert.Contains(t, result, "-e SAFE_VAR=value")
-	// QODANA_TOKEN should be filtered out
+	// QODANA_TOKEN should be masked
 	assert.NotContains(t, result, "secret_token")
+	assert.Contains(t, result, "-e QODANA_TOKEN=***")
+}
+
+// stoppedContainerClient is a Docker client of a container that exits once it's stopped.
+type stoppedContainerClient struct {
+	client.APIClient
+	status  chan container.WaitResponse
+	stopped []int
+}
+
+func (c *stoppedContainerClient) ContainerWait(
+	ctx context.Context,
+	_ string,
+	_ container.WaitCondition,
+) (<-chan container.WaitResponse, <-chan error) {
+	if ctx.Err() != nil {
+		panic("the wait of the exit code is cancelled with the analysis")
+	}
+	return c.status, make(chan error)
+}
+
+func (c *stoppedContainerClient) ContainerStop(_ context.Context, _ string, options container.StopOptions) error {
+	c.stopped = append(c.stopped, *options.Timeout)
+	c.status <- container.WaitResponse{StatusCode: 143}
+	return nil
+}
+
+func TestGetContainerExitCode_StopsContainerOnCancel(t *testing.T) {
+	docker := &stoppedContainerClient{status: make(chan container.WaitResponse, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	exitCode, err := getContainerExitCode(ctx, docker, "container")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(143), exitCode)
+	assert.Equal(t, []int{containerStopTimeout}, docker.stopped)
+}
+
+// listedContainersClient is a Docker client with the given containers, it records the removed ones.
+type listedContainersClient struct {
+	client.APIClient
+	containers []container.Summary
+	removed    []string
+}
+
+func (c *listedContainersClient) ContainerList(_ context.Context, options container.ListOptions) ([]container.Summary, error) {
+	if !options.All {
+		panic("the containers that weren't started are listed too")
+	}
+	return c.containers, nil
+}
+
+func (c *listedContainersClient) ContainerRemove(_ context.Context, id string, _ container.RemoveOptions) error {
+	c.removed = append(c.removed, id)
+	return nil
+}
+
+func TestRemoveContainer(t *testing.T) {
+	previousName := containerName
+	containerName = "qodana-cli-run"
+	defer func() { containerName = previousName }()
+	other := container.Summary{ID: "other", Names: []string{"/other"}, State: container.StateCreated}
+
+	for _, tt := range []struct {
+		name    string
+		state   container.ContainerState
+		keep    string
+		stopped []string
+		removed []string
+	}{
+		{name: "running", state: container.StateRunning, stopped: []string{"qodana"}},
+		{name: "created", state: container.StateCreated, removed: []string{"qodana"}},
+		{name: "created and kept", state: container.StateCreated, keep: "true"},
+		{name: "exited", state: container.StateExited},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(qdenv.QodanaCliContainerKeep, tt.keep)
+			docker := &listedContainersClient{
+				containers: []container.Summary{
+					{ID: "qodana", Names: []string{"/qodana-cli-run"}, State: tt.state},
+					other,
+				},
+			}
+			var stopped []string
+			removeContainer(
+				context.Background(), docker, func(_ client.APIClient, id string) error {
+					stopped = append(stopped, id)
+					return nil
+				},
+			)
+			assert.Equal(t, tt.stopped, stopped)
+			assert.Equal(t, tt.removed, docker.removed)
+		})
+	}
 }
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-units"
+)
+
+// namedVolumeRegexp matches the names of Docker volumes, the same way the Docker daemon validates them.
+var namedVolumeRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// windowsPathRegexp matches the Windows paths starting with a drive, e.g. C:\host\path or C:/host/path.
+var windowsPathRegexp = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// dockerVolume is a --volume of the Qodana container.
+type dockerVolume struct {
+	mount.Mount
+	// SELinuxLabel is z (the content shared between containers) or Z (private to the container), empty for no relabeling.
+	// The Docker mounts API doesn't support the labels, such volumes are passed as binds.
+	SELinuxLabel string
+}
+
+// parseDockerVolume parses a volume in the docker run -v format: source:target[:options], where source is a host path
+// or a volume name, and options is a comma-separated list of
+//   - ro or rw: the access mode,
+//   - z or Z: the SELinux label of a host path,
+//   - consistent, cached or delegated: the consistency on Docker Desktop,
+//   - shared, slave, private, rshared, rslave or rprivate: the bind propagation of a host path,
+//   - nocopy: don't copy the image content of the target to a new named volume.
+func parseDockerVolume(volume string) (dockerVolume, error) {
+	parts := strings.Split(volume, ":")
+	if len(parts) > 1 && windowsPathRegexp.MatchString(parts[0]+":"+parts[1]) {
+		parts = append([]string{parts[0] + ":" + parts[1]}, parts[2:]...)
+	}
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return dockerVolume{}, fmt.Errorf("invalid volume %q, expected source:target[:options]", volume)
+	}
+	source, target := parts[0], parts[1]
+	if !strings.HasPrefix(target, "/") {
+		return dockerVolume{}, fmt.Errorf("invalid volume %q, the target must be an absolute path in the container", volume)
+	}
+
+	v := dockerVolume{Mount: mount.Mount{Source: source, Target: target}}
+	switch {
+	case namedVolumeRegexp.MatchString(source):
+		v.Type = mount.TypeVolume
+	case isHostPath(source):
+		v.Type = mount.TypeBind
+		if !filepath.IsAbs(source) && !strings.HasPrefix(source, "/") && !windowsPathRegexp.MatchString(source) {
+			abs, err := filepath.Abs(source)
+			if err != nil {
+				return dockerVolume{}, fmt.Errorf("invalid volume %q: %w", volume, err)
+			}
+			v.Source = abs
+		}
+	default:
+		return dockerVolume{}, fmt.Errorf(
+			"invalid volume %q, the source must be a host path or a volume name (letters, digits, '_', '.' and '-')",
+			volume,
+		)
+	}
+	if len(parts) == 3 {
+		if err := v.applyOptions(parts[2]); err != nil {
+			return dockerVolume{}, fmt.Errorf("invalid volume %q: %w", volume, err)
+		}
+	}
+	return v, nil
+}
+
+// isHostPath tells if the volume source is a path: absolute (also a Windows path on any OS), or relative to the current
+// directory like ./cache.
+func isHostPath(source string) bool {
+	return filepath.IsAbs(source) || strings.HasPrefix(source, "/") || windowsPathRegexp.MatchString(source) ||
+		source == "." || source == ".." ||
+		strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") ||
+		strings.HasPrefix(source, `.\`) || strings.HasPrefix(source, `..\`)
+}
+
+func (v *dockerVolume) applyOptions(options string) error {
+	mode, propagation := "", mount.Propagation("")
+	for _, option := range strings.Split(options, ",") {
+		switch {
+		case option == "ro" || option == "rw":
+			if mode != "" {
+				return errors.New("the access mode is set twice")
+			}
+			mode = option
+			v.ReadOnly = option == "ro"
+		case option == "z" || option == "Z":
+			if v.Type != mount.TypeBind {
+				return fmt.Errorf("the SELinux label %s is only supported for host paths", option)
+			}
+			if v.SELinuxLabel != "" {
+				return errors.New("the SELinux label is set twice")
+			}
+			v.SELinuxLabel = option
+		case option == string(mount.ConsistencyFull) ||
+			option == string(mount.ConsistencyCached) ||
+			option == string(mount.ConsistencyDelegated):
+			if v.Consistency != "" {
+				return errors.New("the consistency is set twice")
+			}
+			v.Consistency = mount.Consistency(option)
+		case slices.Contains(mount.Propagations, mount.Propagation(option)):
+			if v.Type != mount.TypeBind {
+				return fmt.Errorf("the propagation %s is only supported for host paths", option)
+			}
+			if propagation != "" {
+				return errors.New("the propagation is set twice")
+			}
+			propagation = mount.Propagation(option)
+			v.BindOptions = &mount.BindOptions{Propagation: propagation}
+		case option == "nocopy":
+			if v.Type != mount.TypeVolume {
+				return errors.New("nocopy is only supported for named volumes")
+			}
+			v.VolumeOptions = &mount.VolumeOptions{NoCopy: true}
+		default:
+			return fmt.Errorf("unknown option %q", option)
+		}
+	}
+	return nil
+}
+
+// bind returns the volume in the format of the Docker binds, used for the volumes with SELinux labels.
+func (v dockerVolume) bind() string {
+	options := make([]string, 0)
+	if v.ReadOnly {
+		options = append(options, "ro")
+	}
+	if v.SELinuxLabel != "" {
+		options = append(options, v.SELinuxLabel)
+	}
+	if v.Consistency != "" {
+		options = append(options, string(v.Consistency))
+	}
+	if v.BindOptions != nil && v.BindOptions.Propagation != "" {
+		options = append(options, string(v.BindOptions.Propagation))
+	}
+	bind := v.Source + ":" + v.Target
+	if len(options) > 0 {
+		bind += ":" + strings.Join(options, ",")
+	}
+	return bind
+}
+
+// volumeDriverMount returns the volume created by a driver, like the overlay of the read-only project, in the format
+// of docker run --mount.
+func volumeDriverMount(m mount.Mount) string {
+	fields := []string{"type=volume", "dst=" + m.Target, "volume-driver=" + m.VolumeOptions.DriverConfig.Name}
+	if m.Source != "" {
+		fields = append(fields, "src="+m.Source)
+	}
+	options := m.VolumeOptions.DriverConfig.Options
+	for _, name := range slices.Sorted(maps.Keys(options)) {
+		field := fmt.Sprintf("volume-opt=%s=%s", name, options[name])
+		if strings.Contains(field, ",") {
+			field = `"` + field + `"`
+		}
+		fields = append(fields, field)
+	}
+	return strings.Join(fields, ",")
+}
+
+// parseTmpfsMount parses a --tmpfs in the docker run --tmpfs format: target[:options], where options is
+// a comma-separated list of size=<size> (e.g. 512m or 2g) and mode=<octal permissions>.
+func parseTmpfsMount(tmpfs string) (mount.Mount, error) {
+	target, options, _ := strings.Cut(tmpfs, ":")
+	if !strings.HasPrefix(target, "/") {
+		return mount.Mount{}, fmt.Errorf("invalid tmpfs %q, the target must be an absolute path in the container", tmpfs)
+	}
+	m := mount.Mount{Type: mount.TypeTmpfs, Target: target}
+	if options == "" {
+		return m, nil
+	}
+	m.TmpfsOptions = &mount.TmpfsOptions{}
+	for _, option := range strings.Split(options, ",") {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "size":
+			size, err := units.RAMInBytes(value)
+			if err != nil || size <= 0 {
+				return mount.Mount{}, fmt.Errorf("invalid tmpfs %q: invalid size %q", tmpfs, value)
+			}
+			m.TmpfsOptions.SizeBytes = size
+		case "mode":
+			mode, err := strconv.ParseUint(value, 8, 32)
+			if err != nil {
+				return mount.Mount{}, fmt.Errorf("invalid tmpfs %q: invalid mode %q", tmpfs, value)
+			}
+			m.TmpfsOptions.Mode = os.FileMode(mode)
+		default:
+			return mount.Mount{}, fmt.Errorf("invalid tmpfs %q: unknown option %q", tmpfs, option)
+		}
+	}
+	return m, nil
+}
+
+// tmpfs returns the tmpfs mount in the format of docker run --tmpfs.
+func tmpfs(m mount.Mount) string {
+	options := make([]string, 0)
+	if m.ReadOnly {
+		options = append(options, "ro")
+	}
+	if m.TmpfsOptions != nil && m.TmpfsOptions.SizeBytes > 0 {
+		options = append(options, fmt.Sprintf("size=%d", m.TmpfsOptions.SizeBytes))
+	}
+	if m.TmpfsOptions != nil && m.TmpfsOptions.Mode != 0 {
+		options = append(options, fmt.Sprintf("mode=%o", m.TmpfsOptions.Mode))
+	}
+	if len(options) == 0 {
+		return m.Target
+	}
+	return m.Target + ":" + strings.Join(options, ",")
+}
+
+// qodanaYamlMount returns the mount of the Qodana container for a mount of qodana.yaml.
+func qodanaYamlMount(m qdyaml.Mount) (mount.Mount, error) {
+	if !strings.HasPrefix(m.Target, "/") {
+		return mount.Mount{}, fmt.Errorf(
+			"invalid mount %q in qodana.yaml, the target must be an absolute path in the container",
+			m.Target,
+		)
+	}
+	switch m.Type {
+	case string(mount.TypeTmpfs):
+		if m.Source != "" {
+			return mount.Mount{}, fmt.Errorf("invalid mount %q in qodana.yaml, tmpfs has no source", m.Target)
+		}
+		result := mount.Mount{Type: mount.TypeTmpfs, Target: m.Target, ReadOnly: m.ReadOnly}
+		if m.Size != "" {
+			size, err := units.RAMInBytes(m.Size)
+			if err != nil || size <= 0 {
+				return mount.Mount{}, fmt.Errorf("invalid mount %q in qodana.yaml: invalid size %q", m.Target, m.Size)
+			}
+			result.TmpfsOptions = &mount.TmpfsOptions{SizeBytes: size}
+		}
+		return result, nil
+	case string(mount.TypeVolume):
+		if !namedVolumeRegexp.MatchString(m.Source) {
+			return mount.Mount{}, fmt.Errorf(
+				"invalid mount %q in qodana.yaml, the source must be a volume name (letters, digits, '_', '.' and '-')",
+				m.Target,
+			)
+		}
+		if m.Size != "" {
+			return mount.Mount{}, fmt.Errorf("invalid mount %q in qodana.yaml, size is supported only for tmpfs", m.Target)
+		}
+		return mount.Mount{Type: mount.TypeVolume, Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly}, nil
+	default:
+		return mount.Mount{}, fmt.Errorf(
+			"invalid mount %q in qodana.yaml, unknown type %q, expected tmpfs or volume",
+			m.Target,
+			m.Type,
+		)
+	}
+}
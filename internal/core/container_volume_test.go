@@ -0,0 +1,195 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDockerVolume(t *testing.T) {
+	cwd, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		volume   string
+		expected dockerVolume
+	}{
+		{
+			name:     "simple volume",
+			volume:   "/host/path:/container/path",
+			expected: dockerVolume{Mount: mount.Mount{Type: mount.TypeBind, Source: "/host/path", Target: "/container/path"}},
+		},
+		{
+			name:   "with spaces in path",
+			volume: "/host/path with spaces:/container/path",
+			expected: dockerVolume{
+				Mount: mount.Mount{Type: mount.TypeBind, Source: "/host/path with spaces", Target: "/container/path"},
+			},
+		},
+		{
+			name:     "windows volume",
+			volume:   "C:\\host\\path:/container/path",
+			expected: dockerVolume{Mount: mount.Mount{Type: mount.TypeBind, Source: "C:\\host\\path", Target: "/container/path"}},
+		},
+		{
+			name:   "windows read-only volume",
+			volume: "C:/host/path:/container/path:ro",
+			expected: dockerVolume{
+				Mount: mount.Mount{Type: mount.TypeBind, Source: "C:/host/path", Target: "/container/path", ReadOnly: true},
+			},
+		},
+		{
+			name:   "relative path",
+			volume: "./cache:/data/cache",
+			expected: dockerVolume{
+				Mount: mount.Mount{Type: mount.TypeBind, Source: filepath.Join(cwd, "cache"), Target: "/data/cache"},
+			},
+		},
+		{
+			name:   "read-only with propagation and consistency",
+			volume: "/host/path:/container/path:ro,rslave,cached",
+			expected: dockerVolume{
+				Mount: mount.Mount{
+					Type:        mount.TypeBind,
+					Source:      "/host/path",
+					Target:      "/container/path",
+					ReadOnly:    true,
+					Consistency: mount.ConsistencyCached,
+					BindOptions: &mount.BindOptions{Propagation: mount.PropagationRSlave},
+				},
+			},
+		},
+		{
+			name:   "SELinux label",
+			volume: "/host/path:/container/path:Z",
+			expected: dockerVolume{
+				Mount:        mount.Mount{Type: mount.TypeBind, Source: "/host/path", Target: "/container/path"},
+				SELinuxLabel: "Z",
+			},
+		},
+		{
+			name:   "named volume",
+			volume: "gradle-cache:/root/.gradle:nocopy",
+			expected: dockerVolume{
+				Mount: mount.Mount{
+					Type:          mount.TypeVolume,
+					Source:        "gradle-cache",
+					Target:        "/root/.gradle",
+					VolumeOptions: &mount.VolumeOptions{NoCopy: true},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				v, err := parseDockerVolume(tt.volume)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, v)
+			},
+		)
+	}
+}
+
+func TestParseDockerVolumeErrors(t *testing.T) {
+	for _, volume := range []string{
+		"",
+		"/host/path",
+		"C:\\host\\path",
+		":/container/path",
+		"/host/path:container/path",
+		"/host/path:/container/path:ro:z",
+		"/host/path:/container/path:ro,rw",
+		"/host/path:/container/path:exec",
+		"/host/path:/container/path:nocopy",
+		"/host/path:/container/path:shared,private",
+		"gradle-cache:/root/.gradle:Z",
+		"gradle-cache:/root/.gradle:rshared",
+		"cache dir:/data/cache",
+	} {
+		_, err := parseDockerVolume(volume)
+		assert.Error(t, err, volume)
+	}
+}
+
+func TestDockerVolumeBind(t *testing.T) {
+	v, err := parseDockerVolume("/host/path:/container/path:z,ro,rshared")
+	assert.NoError(t, err)
+	assert.Equal(t, "/host/path:/container/path:ro,z,rshared", v.bind())
+
+	v, err = parseDockerVolume("/host/path:/container/path")
+	assert.NoError(t, err)
+	assert.Equal(t, "/host/path:/container/path", v.bind())
+}
+
+func TestParseTmpfsMount(t *testing.T) {
+	m, err := parseTmpfsMount("/data/cache/idea:size=2g,mode=1777")
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		mount.Mount{
+			Type:         mount.TypeTmpfs,
+			Target:       "/data/cache/idea",
+			TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 2 << 30, Mode: 0o1777},
+		},
+		m,
+	)
+	assert.Equal(t, "/data/cache/idea:size=2147483648,mode=1777", tmpfs(m))
+
+	m, err = parseTmpfsMount("/tmp")
+	assert.NoError(t, err)
+	assert.Equal(t, mount.Mount{Type: mount.TypeTmpfs, Target: "/tmp"}, m)
+	assert.Equal(t, "/tmp", tmpfs(m))
+
+	for _, value := range []string{"", "tmp", "/tmp:size=big", "/tmp:size=0", "/tmp:mode=rwx", "/tmp:exec"} {
+		_, err = parseTmpfsMount(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestQodanaYamlMount(t *testing.T) {
+	m, err := qodanaYamlMount(qdyaml.Mount{Type: "tmpfs", Target: "/data/cache/idea", Size: "512m"})
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		mount.Mount{Type: mount.TypeTmpfs, Target: "/data/cache/idea", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 512 << 20}},
+		m,
+	)
+
+	m, err = qodanaYamlMount(qdyaml.Mount{Type: "volume", Source: "gradle-cache", Target: "/root/.gradle", ReadOnly: true})
+	assert.NoError(t, err)
+	assert.Equal(t, mount.Mount{Type: mount.TypeVolume, Source: "gradle-cache", Target: "/root/.gradle", ReadOnly: true}, m)
+
+	for _, invalid := range []qdyaml.Mount{
+		{Type: "bind", Source: "/host", Target: "/data"},
+		{Type: "tmpfs", Target: "data"},
+		{Type: "tmpfs", Source: "cache", Target: "/data"},
+		{Type: "tmpfs", Target: "/data", Size: "big"},
+		{Type: "volume", Source: "/host/path", Target: "/data"},
+		{Type: "volume", Source: "cache", Target: "/data", Size: "1g"},
+	} {
+		_, err = qodanaYamlMount(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -879,9 +879,13 @@ func Test_Bootstrap(t *testing.T) {
 		t.Fatal(err)
 	}
 	projectDir := tmpDir
-	utils.Bootstrap("echo bootstrap: touch qodana.yml > qodana.yaml", projectDir)
+	utils.Bootstrap(
+		qdyaml.BootstrapSteps(nil, "echo bootstrap: touch qodana.yml > qodana.yaml", qdyaml.Retry{}),
+		projectDir,
+		"",
+	)
 	config := qdyaml.TestOnlyLoadLocalNotEffectiveQodanaYaml(projectDir, "qodana.yaml")
-	utils.Bootstrap(config.Bootstrap, projectDir)
+	utils.Bootstrap(qdyaml.BootstrapSteps(config.Prepare, config.Bootstrap, config.BootstrapRetry), projectDir, "")
 	if _, err := os.Stat(filepath.Join(projectDir, "qodana.yml")); errors.Is(err, os.ErrNotExist) {
 		t.Fatalf("No qodana.yml created by the bootstrap command in qodana.yaml")
 	}
@@ -946,7 +950,10 @@ func Test_ideaExitCode(t *testing.T) {
 				if err != nil {
 					t.Fatal(err)
 				}
-				got := getIdeExitCode(tmpDir, tc.c)
+				got, err := getIdeExitCode(tmpDir, tc.c)
+				if err != nil {
+					t.Fatal(err)
+				}
 				if got != tc.result {
 					t.Errorf("Got: %d, Expected: %d", got, tc.result)
 				}
@@ -36,6 +36,7 @@ const (
 	RunScenarioLocalChanges   = "local-changes"
 	RunScenarioScoped         = "scope"
 	RunScenarioReversedScoped = "reversed-scope"
+	RunScenarioStaged         = "staged"
 )
 
 type RunScenario = string
@@ -79,10 +80,12 @@ type Context struct {
 	reportDir                 string
 	coverageDir               string
 	onlyDirectory             string
+	inputsManifest            string
 	_env                      []string
 	disableSanity             bool
 	profileName               string
 	profilePath               string
+	chainProfiles             []string
 	runPromo                  string
 	baseline                  string
 	baselineIncludeAbsent     bool
@@ -92,23 +95,37 @@ type Context struct {
 	_property                 []string
 	script                    string
 	failThreshold             string
+	failOn                    string
 	commit                    string
 	diffStart                 string
 	diffEnd                   string
 	forceLocalChangesScript   bool
 	reversePrAnalysis         bool
+	staged                    bool
 	reducedScopePath          string
 	analysisId                string
 	_volumes                  []string
+	_tmpfs                    []string
+	cacheVolume               string
 	user                      string
 	printProblems             bool
 	generateCodeClimateReport bool
 	sendBitBucketInsights     bool
+	ci                        string
+	publish                   []string
+	webhooks                  []string
 	skipPull                  bool
+	imageVulnCheck            string
+	imageVulnLevel            string
+	liveProblems              bool
+	stopTimeout               int
+	readOnlyProject           bool
 	fullHistory               bool
 	applyFixes                bool
 	cleanup                   bool
 	fixesStrategy             string
+	fixesDiff                 string
+	fixesBranch               string
 	noStatistics              bool
 	cdnetSolution             string
 	cdnetProject              string
@@ -124,21 +141,48 @@ type Context struct {
 
 // QodanaYamlConfig fields from qodana.yaml used in CLI for core linters (also `linter` and `ide`)
 type QodanaYamlConfig struct {
-	Bootstrap  string
-	Plugins    []qdyaml.Plugin
-	Properties map[string]string
-	DotNet     qdyaml.DotNet
+	Prepare           []qdyaml.PrepareStep
+	Bootstrap         string
+	BootstrapRetry    qdyaml.Retry
+	Plugins           []qdyaml.Plugin
+	Properties        map[string]string
+	DotNet            qdyaml.DotNet
+	Excludes          []qdyaml.Clude
+	SbomExclude       []qdyaml.DependencyIgnore
+	Mounts            []qdyaml.Mount
+	RemoteCache       *qdyaml.RemoteCache
+	FailThreshold     *int
+	FailureConditions qdyaml.FailureConditions
+	EnforceAfter      string
+	Report            *qdyaml.Report
+	Notifications     *qdyaml.Notifications
 }
 
 func YamlConfig(yaml qdyaml.QodanaYaml) QodanaYamlConfig {
 	return QodanaYamlConfig{
-		Bootstrap:  yaml.Bootstrap,
-		Plugins:    yaml.Plugins,
-		Properties: yaml.Properties,
-		DotNet:     yaml.DotNet,
+		Prepare:           yaml.Prepare,
+		Bootstrap:         yaml.Bootstrap,
+		BootstrapRetry:    yaml.BootstrapRetry,
+		Plugins:           yaml.Plugins,
+		Properties:        yaml.Properties,
+		DotNet:            yaml.DotNet,
+		Excludes:          yaml.Excludes,
+		SbomExclude:       yaml.DependencySbomExclude,
+		Mounts:            yaml.Mounts,
+		RemoteCache:       yaml.RemoteCache,
+		FailThreshold:     yaml.FailThreshold,
+		FailureConditions: yaml.FailureConditions,
+		EnforceAfter:      yaml.EnforceAfter,
+		Report:            yaml.Report,
+		Notifications:     yaml.Notifications,
 	}
 }
 
+// BootstrapSteps returns the prepare steps and the bootstrap command to run before the analysis.
+func (y QodanaYamlConfig) BootstrapSteps() []qdyaml.PrepareStep {
+	return qdyaml.BootstrapSteps(y.Prepare, y.Bootstrap, y.BootstrapRetry)
+}
+
 func (c Context) Analyser() product.Analyzer         { return c.analyser }
 func (c Context) Id() string                         { return c.id }
 func (c Context) IdeDir() string                     { return c.ideDir }
@@ -159,9 +203,11 @@ func (c Context) CacheDir() string                   { return c.cacheDir }
 func (c Context) ReportDir() string                  { return c.reportDir }
 func (c Context) CoverageDir() string                { return c.coverageDir }
 func (c Context) OnlyDirectory() string              { return c.onlyDirectory }
+func (c Context) InputsManifest() string             { return c.inputsManifest }
 func (c Context) DisableSanity() bool                { return c.disableSanity }
 func (c Context) ProfileName() string                { return c.profileName }
 func (c Context) ProfilePath() string                { return c.profilePath }
+func (c Context) ChainProfiles() []string            { return c.chainProfiles }
 func (c Context) RunPromo() string                   { return c.runPromo }
 func (c Context) Baseline() string                   { return c.baseline }
 func (c Context) BaselineIncludeAbsent() bool        { return c.baselineIncludeAbsent }
@@ -170,22 +216,34 @@ func (c Context) ShowReport() bool                   { return c.showReport }
 func (c Context) ShowReportPort() int                { return c.showReportPort }
 func (c Context) Script() string                     { return c.script }
 func (c Context) FailThreshold() string              { return c.failThreshold }
+func (c Context) FailOn() string                     { return c.failOn }
 func (c Context) Commit() string                     { return c.commit }
 func (c Context) DiffStart() string                  { return c.diffStart }
 func (c Context) DiffEnd() string                    { return c.diffEnd }
 func (c Context) ForceLocalChangesScript() bool      { return c.forceLocalChangesScript }
 func (c Context) ReducedScopePath() string           { return c.reducedScopePath }
 func (c Context) ReversePrAnalysis() bool            { return c.reversePrAnalysis }
+func (c Context) Staged() bool                       { return c.staged }
 func (c Context) AnalysisId() string                 { return c.analysisId }
 func (c Context) User() string                       { return c.user }
 func (c Context) PrintProblems() bool                { return c.printProblems }
 func (c Context) GenerateCodeClimateReport() bool    { return c.generateCodeClimateReport }
 func (c Context) SendBitBucketInsights() bool        { return c.sendBitBucketInsights }
+func (c Context) Ci() string                         { return c.ci }
+func (c Context) Publish() []string                  { return c.publish }
+func (c Context) Webhooks() []string                 { return c.webhooks }
 func (c Context) SkipPull() bool                     { return c.skipPull }
+func (c Context) ImageVulnCheck() string             { return c.imageVulnCheck }
+func (c Context) ImageVulnLevel() string             { return c.imageVulnLevel }
+func (c Context) LiveProblems() bool                 { return c.liveProblems }
+func (c Context) StopTimeout() int                   { return c.stopTimeout }
+func (c Context) ReadOnlyProject() bool              { return c.readOnlyProject }
 func (c Context) FullHistory() bool                  { return c.fullHistory }
 func (c Context) ApplyFixes() bool                   { return c.applyFixes }
 func (c Context) Cleanup() bool                      { return c.cleanup }
 func (c Context) FixesStrategy() string              { return c.fixesStrategy }
+func (c Context) FixesDiff() string                  { return c.fixesDiff }
+func (c Context) FixesBranch() string                { return c.fixesBranch }
 func (c Context) NoStatistics() bool                 { return c.noStatistics }
 func (c Context) CdnetSolution() string              { return c.cdnetSolution }
 func (c Context) CdnetProject() string               { return c.cdnetProject }
@@ -200,6 +258,8 @@ func (c Context) JvmDebugPort() int                  { return c.jvmDebugPort }
 func (c Context) Env() []string                      { return arrayCopy(c._env) }
 func (c Context) Property() []string                 { return arrayCopy(c._property) }
 func (c Context) Volumes() []string                  { return arrayCopy(c._volumes) }
+func (c Context) Tmpfs() []string                    { return arrayCopy(c._tmpfs) }
+func (c Context) CacheVolume() string                { return c.cacheVolume }
 
 type ContextBuilder struct {
 	Analyser                  product.Analyzer
@@ -218,10 +278,12 @@ type ContextBuilder struct {
 	ReportDir                 string
 	CoverageDir               string
 	OnlyDirectory             string
+	InputsManifest            string
 	Env                       []string
 	DisableSanity             bool
 	ProfileName               string
 	ProfilePath               string
+	ChainProfiles             []string
 	RunPromo                  string
 	Baseline                  string
 	BaselineIncludeAbsent     bool
@@ -231,22 +293,36 @@ type ContextBuilder struct {
 	Property                  []string
 	Script                    string
 	FailThreshold             string
+	FailOn                    string
 	Commit                    string
 	DiffStart                 string
 	DiffEnd                   string
 	ForceLocalChangesScript   bool
 	ReversePrAnalysis         bool
+	Staged                    bool
 	AnalysisId                string
 	Volumes                   []string
+	Tmpfs                     []string
+	CacheVolume               string
 	User                      string
 	PrintProblems             bool
 	GenerateCodeClimateReport bool
 	SendBitBucketInsights     bool
+	Ci                        string
+	Publish                   []string
+	Webhooks                  []string
 	SkipPull                  bool
+	ImageVulnCheck            string
+	ImageVulnLevel            string
+	LiveProblems              bool
+	StopTimeout               int
+	ReadOnlyProject           bool
 	FullHistory               bool
 	ApplyFixes                bool
 	Cleanup                   bool
 	FixesStrategy             string
+	FixesDiff                 string
+	FixesBranch               string
 	NoStatistics              bool
 	CdnetSolution             string
 	CdnetProject              string
@@ -282,10 +358,12 @@ func (b ContextBuilder) Build() Context {
 		reportDir:                 b.ReportDir,
 		coverageDir:               b.CoverageDir,
 		onlyDirectory:             b.OnlyDirectory,
+		inputsManifest:            b.InputsManifest,
 		_env:                      b.Env,
 		disableSanity:             b.DisableSanity,
 		profileName:               b.ProfileName,
 		profilePath:               b.ProfilePath,
+		chainProfiles:             b.ChainProfiles,
 		runPromo:                  b.RunPromo,
 		baseline:                  b.Baseline,
 		baselineIncludeAbsent:     b.BaselineIncludeAbsent,
@@ -295,22 +373,36 @@ func (b ContextBuilder) Build() Context {
 		_property:                 b.Property,
 		script:                    b.Script,
 		failThreshold:             b.FailThreshold,
+		failOn:                    b.FailOn,
 		commit:                    b.Commit,
 		diffStart:                 b.DiffStart,
 		diffEnd:                   b.DiffEnd,
 		forceLocalChangesScript:   b.ForceLocalChangesScript,
 		reversePrAnalysis:         b.ReversePrAnalysis,
+		staged:                    b.Staged,
 		analysisId:                b.AnalysisId,
 		_volumes:                  b.Volumes,
+		_tmpfs:                    b.Tmpfs,
+		cacheVolume:               b.CacheVolume,
 		user:                      b.User,
 		printProblems:             b.PrintProblems,
 		generateCodeClimateReport: b.GenerateCodeClimateReport,
 		sendBitBucketInsights:     b.SendBitBucketInsights,
+		ci:                        b.Ci,
+		publish:                   b.Publish,
+		webhooks:                  b.Webhooks,
 		skipPull:                  b.SkipPull,
+		imageVulnCheck:            b.ImageVulnCheck,
+		imageVulnLevel:            b.ImageVulnLevel,
+		liveProblems:              b.LiveProblems,
+		stopTimeout:               b.StopTimeout,
+		readOnlyProject:           b.ReadOnlyProject,
 		fullHistory:               b.FullHistory,
 		applyFixes:                b.ApplyFixes,
 		cleanup:                   b.Cleanup,
 		fixesStrategy:             b.FixesStrategy,
+		fixesDiff:                 b.FixesDiff,
+		fixesBranch:               b.FixesBranch,
 		noStatistics:              b.NoStatistics,
 		cdnetSolution:             b.CdnetSolution,
 		cdnetProject:              b.CdnetProject,
@@ -352,9 +444,14 @@ func (c Context) DetermineRunScenario(hasStartHash bool) RunScenario {
 	if c.ForceLocalChangesScript() || c.Script() == "local-changes" {
 		msg.WarningMessage("Using local-changes script is deprecated, please switch to other mechanisms of incremental analysis. Further information - https://www.jetbrains.com/help/qodana/analyze-pr.html")
 	}
+	if c.Staged() && c.analyser.IsContainer() {
+		msg.WarningMessage("--staged is not supported for container runs, the whole project is analysed")
+	}
 	switch {
 	case c.FullHistory():
 		return RunScenarioFullHistory
+	case c.Staged() && !c.analyser.IsContainer():
+		return RunScenarioStaged
 	case !hasStartHash:
 		return RunScenarioDefault
 	case c.ForceLocalChangesScript():
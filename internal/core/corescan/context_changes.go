@@ -145,6 +145,44 @@ func (c Context) ThirdStageOfReverseScopedScript(scopeFile string, startSarif st
 	return c
 }
 
+// StagedChangesRun is the single analysis of the current working tree scoped to the files with the staged changes,
+// the fast path for the pre-commit checks: no HTML report and no quick-fixes.
+func (c Context) StagedChangesRun(scopeFile string) Context {
+	c.script = "scoped:" + scopeFile
+	return c.prepareContext(true)
+}
+
+// ChainedProfileRun is the analysis with a profile chained after the main one: it reuses the caches and indexes
+// warmed by the previous runs and saves its results to ChainedProfileResultsDir. The baseline belongs to the main
+// profile and isn't applied.
+func (c Context) ChainedProfileRun(profile string) Context {
+	c = c.prepareContext(
+		true,
+		"-Dqodana.skip.preamble=true", // don't print the QD logo again
+		"-Didea.headless.enable.statistics=false", // the statistics are sent by the main run
+	)
+	if IsProfilePath(profile) {
+		c.profileName = ""
+		c.profilePath = profile
+	} else {
+		c.profileName = profile
+		c.profilePath = ""
+	}
+	c.chainProfiles = nil
+	c.baseline = ""
+	c.baselineIncludeAbsent = false
+	c.resultsDir = ChainedProfileResultsDir(c.ResultsDir(), profile)
+	startup.MakeDirAll(c.LogDir()) // need to prepare new result and log dir
+	return c
+}
+
+// WithBaselineRemappedForRenames replaces the baseline with its copy where the results in the renamed files are moved
+// to the current paths of the files.
+func (c Context) WithBaselineRemappedForRenames(baseline string) Context {
+	c.baseline = baseline
+	return c
+}
+
 func (c Context) ForcedLocalChanges() Context {
 	c.script = "local-changes"
 	return c
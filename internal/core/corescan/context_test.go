@@ -234,6 +234,7 @@ func TestContext_DetermineRunScenario(t *testing.T) {
 		forceLocal   bool
 		isContainer  bool
 		reversePr    bool
+		staged       bool
 		expected     RunScenario
 	}{
 		{
@@ -281,6 +282,19 @@ func TestContext_DetermineRunScenario(t *testing.T) {
 			reversePr:    false,
 			expected:     RunScenarioScoped,
 		},
+		{
+			name:         "staged changes",
+			hasStartHash: false,
+			staged:       true,
+			expected:     RunScenarioStaged,
+		},
+		{
+			name:         "staged changes in container mode",
+			hasStartHash: false,
+			isContainer:  true,
+			staged:       true,
+			expected:     RunScenarioDefault,
+		},
 	}
 
 	for _, tt := range tests {
@@ -296,6 +310,7 @@ func TestContext_DetermineRunScenario(t *testing.T) {
 				FullHistory:             tt.fullHistory,
 				ForceLocalChangesScript: tt.forceLocal,
 				ReversePrAnalysis:       tt.reversePr,
+				Staged:                  tt.staged,
 				Analyser:                analyser,
 			}.Build()
 
@@ -469,6 +484,7 @@ func TestIsScopedScenario(t *testing.T) {
 		{RunScenarioDefault, false},
 		{RunScenarioFullHistory, false},
 		{RunScenarioLocalChanges, false},
+		{RunScenarioStaged, false},
 	}
 
 	for _, tt := range tests {
@@ -537,3 +553,46 @@ func TestProjectDirPathRelativeToRepositoryRoot_Symlink(t *testing.T) {
 	rel := ctx.ProjectDirPathRelativeToRepositoryRoot()
 	assert.Equal(t, "sub", rel)
 }
+
+func TestContext_ChainedProfileRun(t *testing.T) {
+	resultsDir := t.TempDir()
+	c := ContextBuilder{
+		ResultsDir:    resultsDir,
+		LogDir:        filepath.Join(resultsDir, "log"),
+		ProfileName:   "qodana.recommended",
+		ChainProfiles: []string{"security", ".qodana/quality.yaml"},
+		Baseline:      "qodana.sarif.json",
+		SaveReport:    true,
+		ApplyFixes:    true,
+	}.Build()
+
+	byName := c.ChainedProfileRun("security")
+	assert.Equal(t, "security", byName.ProfileName())
+	assert.Equal(t, "", byName.ProfilePath())
+	assert.Equal(t, filepath.Join(resultsDir, ChainedProfilesDirName, "security"), byName.ResultsDir())
+	assert.Empty(t, byName.ChainProfiles())
+	assert.Equal(t, "", byName.Baseline())
+	assert.False(t, byName.SaveReport())
+	assert.False(t, byName.ApplyFixes())
+	assert.Contains(t, byName.Property(), "-Dqodana.skip.preamble=true")
+
+	byPath := c.ChainedProfileRun(".qodana/quality.yaml")
+	assert.Equal(t, "", byPath.ProfileName())
+	assert.Equal(t, ".qodana/quality.yaml", byPath.ProfilePath())
+	assert.Equal(t, filepath.Join(resultsDir, ChainedProfilesDirName, "quality"), byPath.ResultsDir())
+
+	assert.Equal(t, "qodana.recommended", c.ProfileName(), "the main context is not changed")
+	assert.Equal(t, resultsDir, c.ResultsDir())
+}
+
+func TestChainedProfileResultsDir(t *testing.T) {
+	for profile, expected := range map[string]string{
+		"security":              "security",
+		"qodana.starter":        "qodana.starter",
+		"My Profile":            "My-Profile",
+		"profiles/security.xml": "security",
+		"..":                    "profile",
+	} {
+		assert.Equal(t, filepath.Join("results", ChainedProfilesDirName, expected), ChainedProfileResultsDir("results", profile), profile)
+	}
+}
@@ -17,12 +17,12 @@
 package corescan
 
 import (
-	"path/filepath"
 	"strings"
 
 	"github.com/JetBrains/qodana-cli/internal/core/startup"
 	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 )
@@ -39,9 +39,13 @@ func CreateContext(
 		if qdenv.IsContainer() {
 			coverageDir = qdcontainer.DataCoverageDir
 		} else {
-			coverageDir = filepath.Join(commonCtx.ProjectDir, ".qodana", "code-coverage")
+			coverageDir = projectstate.CoverageDir(commonCtx.ProjectDir)
 		}
 	}
+	baseline := cliOptions.Baseline
+	if baseline == "" {
+		baseline = projectstate.Baseline(commonCtx.ProjectDir)
+	}
 
 	commit := strings.TrimPrefix(cliOptions.Commit, "CI")
 
@@ -62,12 +66,14 @@ func CreateContext(
 		ReportDir:                 commonCtx.ReportDir,
 		CoverageDir:               coverageDir,
 		OnlyDirectory:             cliOptions.OnlyDirectory,
+		InputsManifest:            cliOptions.InputsManifest,
 		Env:                       cliOptions.Env_,
 		DisableSanity:             cliOptions.DisableSanity,
 		ProfileName:               cliOptions.ProfileName,
 		ProfilePath:               cliOptions.ProfilePath,
+		ChainProfiles:             cliOptions.ChainProfiles,
 		RunPromo:                  cliOptions.RunPromo,
-		Baseline:                  cliOptions.Baseline,
+		Baseline:                  baseline,
 		BaselineIncludeAbsent:     cliOptions.BaselineIncludeAbsent,
 		SaveReport:                cliOptions.SaveReport,
 		ShowReport:                cliOptions.ShowReport,
@@ -75,23 +81,37 @@ func CreateContext(
 		Property:                  cliOptions.Property,
 		Script:                    cliOptions.Script,
 		FailThreshold:             cliOptions.FailThreshold,
+		FailOn:                    cliOptions.FailOn,
 		Commit:                    commit,
 		DiffStart:                 cliOptions.DiffStart,
 		DiffEnd:                   cliOptions.DiffEnd,
 		ForceLocalChangesScript:   cliOptions.ForceLocalChangesScript,
 		ReversePrAnalysis:         cliOptions.ReversePrAnalysis,
+		Staged:                    cliOptions.Staged,
 		AnalysisId:                cliOptions.AnalysisId,
 		Volumes:                   cliOptions.Volumes,
+		Tmpfs:                     cliOptions.Tmpfs,
+		CacheVolume:               cliOptions.CacheVolume,
 		User:                      cliOptions.User,
 		PrintProblems:             cliOptions.PrintProblems,
 		GenerateCodeClimateReport: cliOptions.GenerateCodeClimateReport,
-		SendBitBucketInsights:     cliOptions.SendBitBucketInsights,
+		SendBitBucketInsights:     cliOptions.SendBitBucketInsights && !cliOptions.Offline,
+		Ci:                        cliOptions.Ci,
+		Publish:                   cliOptions.Publish,
+		Webhooks:                  cliOptions.Webhooks,
 		SkipPull:                  cliOptions.SkipPull,
+		ImageVulnCheck:            cliOptions.ImageVulnCheck,
+		ImageVulnLevel:            cliOptions.ImageVulnLevel,
+		LiveProblems:              cliOptions.LiveProblems,
+		StopTimeout:               cliOptions.StopTimeout,
+		ReadOnlyProject:           cliOptions.ReadOnlyProject,
 		FullHistory:               cliOptions.FullHistory,
 		ApplyFixes:                cliOptions.ApplyFixes,
 		Cleanup:                   cliOptions.Cleanup,
 		FixesStrategy:             cliOptions.FixesStrategy,
-		NoStatistics:              cliOptions.NoStatistics,
+		FixesDiff:                 cliOptions.FixesDiff,
+		FixesBranch:               cliOptions.FixesBranch,
+		NoStatistics:              cliOptions.NoStatistics || cliOptions.Offline,
 		CdnetSolution:             cliOptions.CdnetSolution,
 		CdnetProject:              cliOptions.CdnetProject,
 		CdnetConfiguration:        cliOptions.CdnetConfiguration,
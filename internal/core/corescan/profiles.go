@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package corescan
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ChainedProfilesDirName is the directory in the results directory with the results of the chained profiles.
+const ChainedProfilesDirName = "profiles"
+
+var unsafeDirNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// IsProfilePath tells whether the profile given to --chain-profile is a profile file rather than a profile name.
+func IsProfilePath(profile string) bool {
+	if strings.ContainsAny(profile, `/\`) {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(profile)) {
+	case ".xml", ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// ChainedProfileResultsDir returns the directory the results of the chained profile are saved to, named after the
+// profile name or the profile file name.
+func ChainedProfileResultsDir(resultsDir string, profile string) string {
+	name := profile
+	if IsProfilePath(profile) {
+		name = strings.TrimSuffix(filepath.Base(profile), filepath.Ext(profile))
+	}
+	name = strings.Trim(unsafeDirNameChars.ReplaceAllString(name, "-"), "-.")
+	if name == "" {
+		name = "profile"
+	}
+	return filepath.Join(resultsDir, ChainedProfilesDirName, name)
+}
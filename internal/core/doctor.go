@@ -0,0 +1,304 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/internal/tooling"
+	"github.com/docker/docker/api/types/system"
+	"github.com/pterm/pterm"
+	"github.com/shirou/gopsutil/v3/disk"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoctorStatus is the outcome of a doctor check.
+type DoctorStatus string
+
+const (
+	DoctorOk      DoctorStatus = "ok"
+	DoctorWarning DoctorStatus = "warning"
+	DoctorFailed  DoctorStatus = "failed"
+	DoctorSkipped DoctorStatus = "skipped"
+)
+
+// DoctorCheck is the result of checking one part of the environment, Fix tells how to resolve the found problem.
+type DoctorCheck struct {
+	Name    string       `json:"name"`
+	Status  DoctorStatus `json:"status"`
+	Details string       `json:"details"`
+	Fix     string       `json:"fix,omitempty"`
+}
+
+const (
+	doctorEngineCheck       = "Container engine"
+	doctorEngineMemoryCheck = "Container engine memory"
+	doctorDiskSpaceCheck    = "Disk space"
+	doctorJavaCheck         = "Bundled Java runtime"
+	doctorCloudCheck        = "Qodana Cloud"
+	doctorTokenCheck        = "QODANA_TOKEN"
+
+	// doctorMinDiskSpace is enough for an IDE distribution, a linter image and the caches of a project
+	doctorMinDiskSpace      = 10 * 1024 * 1024 * 1024
+	doctorCriticalDiskSpace = 2 * 1024 * 1024 * 1024
+	doctorRequestTimeout    = 30 * time.Second
+)
+
+// RunDoctor checks the environment Qodana runs in: the container engine, the disk space in systemDir,
+// the Java runtime used by the CLI tools and the connection to Qodana Cloud with the given token.
+func RunDoctor(ctx context.Context, systemDir string, token string) []DoctorCheck {
+	checks := checkContainerEngine(ctx)
+	checks = append(checks, checkDiskSpace(systemDir), checkBundledJava())
+	return append(checks, checkCloud(token)...)
+}
+
+// HasFailedDoctorChecks returns true if any of the checks failed.
+func HasFailedDoctorChecks(checks []DoctorCheck) bool {
+	for _, check := range checks {
+		if check.Status == DoctorFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintDoctorChecks prints the results of the checks with the fixes for the found problems.
+func PrintDoctorChecks(checks []DoctorCheck) {
+	if msg.IsJsonLog() {
+		msg.PrintJsonLog("doctor", map[string]any{"checks": checks})
+		return
+	}
+	for _, check := range checks {
+		line := fmt.Sprintf("%s: %s", msg.PrimaryBold(check.Name), check.Details)
+		switch check.Status {
+		case DoctorOk:
+			msg.SuccessMessage("%s", line)
+		case DoctorWarning:
+			msg.WarningMessage("%s", line)
+		case DoctorFailed:
+			msg.ErrorMessage("%s", line)
+		default:
+			pterm.Println(pterm.Gray("- "), msg.Primary(line))
+		}
+		if check.Fix != "" {
+			pterm.Println("   " + check.Fix)
+		}
+	}
+}
+
+func checkContainerEngine(ctx context.Context) []DoctorCheck {
+	apiClient, err := qdcontainer.NewContainerClient(ctx)
+	if err != nil {
+		return []DoctorCheck{
+			{
+				Name:    doctorEngineCheck,
+				Status:  DoctorFailed,
+				Details: err.Error(),
+				Fix: "Install and start Docker or Podman, or set DOCKER_HOST to the socket of a running daemon, or install nerdctl to use containerd. " +
+					"It's not needed if you run the linters natively with --within-docker=false.",
+			},
+			{Name: doctorEngineMemoryCheck, Status: DoctorSkipped, Details: "no container engine available"},
+		}
+	}
+	defer func() {
+		if err := apiClient.Close(); err != nil {
+			log.Debugf("Failed to close the container client: %s", err)
+		}
+	}()
+
+	engine := DoctorCheck{Name: doctorEngineCheck, Status: DoctorOk}
+	engine.Details, err = qdcontainer.ContainerEngineName(ctx, apiClient)
+	if err != nil {
+		engine.Status = DoctorWarning
+		engine.Details = err.Error()
+	} else if serverVersion, err := apiClient.ServerVersion(ctx); err == nil {
+		engine.Details += " " + serverVersion.Version
+	}
+	info, err := apiClient.Info(ctx)
+	if err != nil {
+		return []DoctorCheck{
+			engine,
+			{Name: doctorEngineMemoryCheck, Status: DoctorWarning, Details: err.Error()},
+		}
+	}
+	return []DoctorCheck{engine, engineMemoryCheck(info, runtime.GOOS)}
+}
+
+func engineMemoryCheck(info system.Info, goos string) DoctorCheck {
+	check := DoctorCheck{Name: doctorEngineMemoryCheck, Status: DoctorOk, Details: formatDoctorBytes(uint64(info.MemTotal))}
+	if info.MemTotal < qdcontainer.RecommendedEngineMemory {
+		check.Status = DoctorWarning
+		check.Fix = "Increase the memory limit of the container engine to at least 4 GB, the analysis can run out of memory otherwise."
+		if help := qdcontainer.EngineMemoryHelp(info, goos); help != "" {
+			check.Fix += " " + help
+		}
+	}
+	return check
+}
+
+func checkDiskSpace(systemDir string) DoctorCheck {
+	// the system directory doesn't exist before the first run, check the disk it will be created on
+	dir := systemDir
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	usage, err := disk.Usage(dir)
+	if err != nil {
+		return DoctorCheck{Name: doctorDiskSpaceCheck, Status: DoctorWarning, Details: err.Error()}
+	}
+	return diskSpaceCheck(systemDir, usage.Free)
+}
+
+func diskSpaceCheck(systemDir string, free uint64) DoctorCheck {
+	check := DoctorCheck{
+		Name:    doctorDiskSpaceCheck,
+		Status:  DoctorOk,
+		Details: fmt.Sprintf("%s free in %s", formatDoctorBytes(free), systemDir),
+	}
+	if free < doctorMinDiskSpace {
+		check.Status = DoctorWarning
+		if free < doctorCriticalDiskSpace {
+			check.Status = DoctorFailed
+		}
+		check.Fix = "Free up at least 10 GB for the linter distributions and caches, or move them to another disk with --cache-dir. " +
+			"The cache of a project can be removed with `qodana scan --clear-cache`."
+	}
+	return check
+}
+
+// checkBundledJava runs the Java runtime embedded into the CLI, it runs the configuration loader and third-party linters.
+func checkBundledJava() DoctorCheck {
+	check := DoctorCheck{Name: doctorJavaCheck, Status: DoctorOk}
+	tmpDir, err := os.MkdirTemp("", "qodana-doctor")
+	if err != nil {
+		check.Status = DoctorFailed
+		check.Details = err.Error()
+		check.Fix = "Make sure the temporary directory is writable or set TMPDIR to a writable directory."
+		return check
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	check.Details, err = tooling.QodanaJBRVersion(tmpDir)
+	if err != nil {
+		check.Status = DoctorFailed
+		check.Details = err.Error()
+		check.Fix = "Make sure the temporary directory allows running executables (it's not mounted with noexec) " +
+			"and the system libraries required by Java are installed, e.g. glibc on Alpine Linux."
+	}
+	return check
+}
+
+func checkCloud(token string) []DoctorCheck {
+	root := cloud.GetCloudRootEndpoint()
+	reachability := DoctorCheck{Name: doctorCloudCheck, Status: DoctorOk, Details: root.Url + doctorProxyDetails(root.Url)}
+	if err := pingCloud(root.Url); err != nil {
+		reachability.Status = DoctorFailed
+		reachability.Details += ": " + err.Error()
+		reachability.Fix = fmt.Sprintf(
+			"Make sure %s is reachable from this machine. If the network requires a proxy, set HTTPS_PROXY (and NO_PROXY for the hosts to reach directly). "+
+				"For a self-hosted Qodana Cloud set QODANA_ENDPOINT.",
+			root.Url,
+		)
+		return []DoctorCheck{
+			reachability,
+			{Name: doctorTokenCheck, Status: DoctorSkipped, Details: "Qodana Cloud is not reachable"},
+		}
+	}
+	return []DoctorCheck{reachability, checkToken(cloud.GetCloudApiEndpoints(), token)}
+}
+
+// pingCloud requests the API versions once, without the retries of the Qodana Cloud client, to report the actual error.
+func pingCloud(rootUrl string) error {
+	client := &http.Client{Timeout: doctorRequestTimeout}
+	resp, err := client.Get(rootUrl + cloud.VersionsURI)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+// doctorProxyDetails describes the proxy requests to the endpoint go through, if any.
+func doctorProxyDetails(endpoint string) string {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	proxyUrl, err := http.ProxyFromEnvironment(&http.Request{URL: endpointUrl})
+	if err != nil {
+		return fmt.Sprintf(" (invalid proxy configuration: %s)", err)
+	}
+	if proxyUrl == nil {
+		return ""
+	}
+	proxyUrl.User = nil
+	return fmt.Sprintf(" (via proxy %s)", proxyUrl)
+}
+
+func checkToken(endpoints *cloud.QdApiEndpoints, token string) DoctorCheck {
+	check := DoctorCheck{Name: doctorTokenCheck, Status: DoctorOk}
+	if token == "" {
+		check.Status = DoctorWarning
+		check.Details = "not set"
+		check.Fix = fmt.Sprintf(
+			"Community linters run without a token. For the other linters and uploading the results, "+
+				"set QODANA_TOKEN to the project token from %s",
+			endpoints.RootEndpoint.Url,
+		)
+		return check
+	}
+	licenseData, err := endpoints.CheckToken(token)
+	if errors.Is(err, cloud.ErrTokenDeclined) {
+		check.Status = DoctorFailed
+		check.Details = err.Error()
+		check.Fix = "Copy the token again from the project settings in Qodana Cloud and make sure the license of the organization is valid."
+		return check
+	}
+	if err != nil {
+		check.Status = DoctorWarning
+		check.Details = err.Error()
+		return check
+	}
+	check.Details = fmt.Sprintf("valid, license plan %s", licenseData.LicensePlan)
+	if licenseData.ExpirationDate != "" {
+		check.Details += ", expires " + licenseData.ExpirationDate
+	}
+	return check
+}
+
+func formatDoctorBytes(size uint64) string {
+	return fmt.Sprintf("%.1f GB", float64(size)/(1024*1024*1024))
+}
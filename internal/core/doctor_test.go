@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/docker/docker/api/types/system"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineMemoryCheck(t *testing.T) {
+	check := engineMemoryCheck(system.Info{MemTotal: 8 * 1024 * 1024 * 1024}, "darwin")
+	assert.Equal(t, DoctorOk, check.Status)
+	assert.Equal(t, "8.0 GB", check.Details)
+	assert.Empty(t, check.Fix)
+
+	check = engineMemoryCheck(system.Info{MemTotal: 2 * 1024 * 1024 * 1024}, "darwin")
+	assert.Equal(t, DoctorWarning, check.Status)
+	assert.Contains(t, check.Fix, "https://docs.docker.com/desktop/settings/mac/")
+
+	check = engineMemoryCheck(system.Info{MemTotal: 2 * 1024 * 1024 * 1024}, "linux")
+	assert.Equal(t, DoctorWarning, check.Status)
+	assert.NotContains(t, check.Fix, "https://")
+
+	check = engineMemoryCheck(system.Info{Name: "colima", MemTotal: 2 * 1024 * 1024 * 1024}, "darwin")
+	assert.Equal(t, DoctorWarning, check.Status)
+	assert.Contains(t, check.Fix, "qodana engine setup --memory")
+}
+
+func TestDiskSpaceCheck(t *testing.T) {
+	assert.Equal(t, DoctorOk, diskSpaceCheck("/cache", 50*1024*1024*1024).Status)
+	assert.Equal(t, DoctorWarning, diskSpaceCheck("/cache", 5*1024*1024*1024).Status)
+
+	check := diskSpaceCheck("/cache", 1024*1024*1024)
+	assert.Equal(t, DoctorFailed, check.Status)
+	assert.Equal(t, "1.0 GB free in /cache", check.Details)
+	assert.NotEmpty(t, check.Fix)
+	assert.True(t, HasFailedDoctorChecks([]DoctorCheck{{Status: DoctorWarning}, check}))
+	assert.False(t, HasFailedDoctorChecks([]DoctorCheck{{Status: DoctorWarning}, {Status: DoctorSkipped}}))
+}
+
+func TestCheckToken(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer valid" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				_, _ = w.Write([]byte(`{"licensePlan":"ULTIMATE_PLUS","expirationDate":"2030-01-01"}`))
+			},
+		),
+	)
+	defer server.Close()
+	endpoints := &cloud.QdApiEndpoints{
+		RootEndpoint:  &cloud.QdRootEndpoint{Url: server.URL},
+		LintersApiUrl: server.URL,
+	}
+
+	check := checkToken(endpoints, "valid")
+	assert.Equal(t, DoctorOk, check.Status)
+	assert.Equal(t, "valid, license plan ULTIMATE_PLUS, expires 2030-01-01", check.Details)
+
+	check = checkToken(endpoints, "invalid")
+	assert.Equal(t, DoctorFailed, check.Status)
+	assert.NotEmpty(t, check.Fix)
+
+	check = checkToken(endpoints, "")
+	assert.Equal(t, DoctorWarning, check.Status)
+	assert.Contains(t, check.Fix, server.URL)
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/platform/fingerprint"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// containerEnvironment returns the environment of the scan in a container of the image, the CPUs and the memory
+// are the ones available to the container engine rather than to the host.
+func containerEnvironment(
+	ctx context.Context,
+	docker client.APIClient,
+	info system.Info,
+	image string,
+) fingerprint.Environment {
+	environment := fingerprint.Environment{
+		Engine:        "unknown",
+		EngineVersion: info.ServerVersion,
+		Os:            info.OSType,
+		Arch:          info.Architecture,
+		Kernel:        info.KernelVersion,
+		Cpus:          info.NCPU,
+		MemoryBytes:   info.MemTotal,
+	}
+	if engine, err := qdcontainer.ContainerEngineName(ctx, docker); err != nil {
+		log.Debug(err)
+	} else {
+		environment.Engine = engine
+	}
+	if inspect, err := docker.ImageInspect(ctx, image); err != nil {
+		log.Debugf("Failed to inspect image %s: %s", image, err)
+	} else if len(inspect.RepoDigests) > 0 {
+		environment.ImageDigest = inspect.RepoDigests[0]
+	} else {
+		// the image is built locally and was never pushed
+		environment.ImageDigest = inspect.ID
+	}
+	return environment
+}
+
+// recordEnvironment records the fingerprint of the scan environment, see fingerprint.Record.
+func recordEnvironment(c corescan.Context, environment fingerprint.Environment) {
+	fingerprint.Record(environment, c.ResultsDir(), c.CacheDir(), fingerprint.Branch(c.RepositoryRoot(), c.LogDir()))
+}
@@ -15,6 +15,8 @@ const (
 	QodanaOutOfMemoryExitCode = exec.OomExitCode
 	// QodanaEapLicenseExpiredExitCode reports an expired license.
 	QodanaEapLicenseExpiredExitCode = 7
+	// QodanaInterruptedExitCode is returned when the analysis is interrupted with Ctrl+C or SIGTERM (128 + SIGINT).
+	QodanaInterruptedExitCode = 130
 	// QodanaTimeoutExitCodePlaceholder is not a real exit code (it is not obtained from IDE process! and not returned from CLI)
 	// Placeholder used to identify the case when the analysis reached timeout
 	QodanaTimeoutExitCodePlaceholder = 1000
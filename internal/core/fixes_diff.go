@@ -0,0 +1,146 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// FixesDiffStdout is the corescan.Context FixesDiff printing the changes made by the quick-fixes instead of saving them.
+const FixesDiffStdout = "-"
+
+// FixesPatchName is the file in the results directory with the changes made by the quick-fixes.
+const FixesPatchName = "fixes.patch"
+
+// fixesCommitMessage is the message of the commit made with corescan.Context FixesBranch.
+const fixesCommitMessage = "Apply Qodana quick-fixes"
+
+// startFixesDiff checks that the changes made by the quick-fixes can be told apart from the changes of the user and
+// returns the function to call after the analysis: it saves the changes as a diff and reverts them.
+func startFixesDiff(c corescan.Context) (func() error, error) {
+	if !utils.IsInstalled("git") {
+		return nil, errors.New("cannot collect the changes made by the quick-fixes without a git executable")
+	}
+	if !c.Analyser().GetLinter().SupportFixes {
+		msg.WarningMessage("%s doesn't support quick-fixes, nothing will be changed", c.Analyser().Name())
+	}
+	hasChanges, err := git.HasChanges(c.ProjectDir(), c.LogDir())
+	if err != nil {
+		return nil, fmt.Errorf("cannot collect the changes made by the quick-fixes outside of a git repository: %w", err)
+	}
+	if hasChanges {
+		return nil, errors.New("the project has uncommitted changes, commit or stash them to preview the quick-fixes")
+	}
+	return func() error {
+		diff, err := git.Diff(c.ProjectDir(), "HEAD", c.LogDir())
+		if err != nil {
+			return fmt.Errorf("failed to collect the changes made by the quick-fixes: %w", err)
+		}
+		if err = git.Restore(c.ProjectDir(), c.LogDir()); err != nil {
+			return fmt.Errorf("failed to revert the changes made by the quick-fixes: %w", err)
+		}
+		return writeFixesDiff(c.FixesDiff(), diff)
+	}, nil
+}
+
+func writeFixesDiff(target string, diff string) error {
+	switch {
+	case diff == "":
+		msg.SuccessMessage("The quick-fixes didn't change the project")
+	case target == FixesDiffStdout:
+		fmt.Print(diff)
+	default:
+		if err := os.WriteFile(target, []byte(diff), 0o644); err != nil {
+			return fmt.Errorf("failed to write the changes made by the quick-fixes to %s: %w", target, err)
+		}
+		msg.SuccessMessage("Saved the changes made by the quick-fixes to %s, apply them with %s", target, msg.PrimaryBold("git apply "+target))
+	}
+	return nil
+}
+
+// fixesRequested reports whether the analysis applies any quick-fixes to the project.
+func fixesRequested(c corescan.Context) bool {
+	switch strings.ToLower(c.FixesStrategy()) {
+	case "apply", "cleanup":
+		return true
+	}
+	return c.ApplyFixes() || c.Cleanup()
+}
+
+// startFixesPatch remembers the state of the project before the quick-fixes are applied and returns the function to
+// call after the analysis: it saves the changes made by the fixes to fixes.patch in the results directory and commits
+// them to corescan.Context FixesBranch if it's set. Nothing is collected outside of a git repository.
+func startFixesPatch(c corescan.Context) (func() error, error) {
+	if !utils.IsInstalled("git") {
+		if c.FixesBranch() != "" {
+			return nil, errors.New("cannot commit the changes made by the quick-fixes without a git executable")
+		}
+		return nil, nil
+	}
+	snapshot, err := git.Snapshot(c.ProjectDir(), c.LogDir())
+	if err != nil {
+		if c.FixesBranch() != "" {
+			return nil, fmt.Errorf("cannot commit the changes made by the quick-fixes outside of a git repository: %w", err)
+		}
+		log.Debugf("The changes made by the quick-fixes are not collected: %s", err)
+		return nil, nil
+	}
+	if c.FixesBranch() != "" {
+		hasChanges, err := git.HasChanges(c.ProjectDir(), c.LogDir())
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the uncommitted changes of the project: %w", err)
+		}
+		if hasChanges {
+			return nil, errors.New("the project has uncommitted changes, commit or stash them to commit the quick-fixes to a branch")
+		}
+	}
+	return func() error {
+		diff, err := git.Diff(c.ProjectDir(), snapshot, c.LogDir())
+		if err != nil {
+			log.Errorf("Failed to collect the changes made by the quick-fixes: %s", err)
+			return nil
+		}
+		if diff == "" {
+			log.Debug("The quick-fixes didn't change the project")
+			return nil
+		}
+		patch := filepath.Join(c.ResultsDir(), FixesPatchName)
+		if err = os.WriteFile(patch, []byte(diff), 0o644); err != nil {
+			log.Errorf("Failed to write the changes made by the quick-fixes to %s: %s", patch, err)
+		} else {
+			msg.SuccessMessage("Saved the changes made by the quick-fixes to %s", patch)
+		}
+		if c.FixesBranch() == "" {
+			return nil
+		}
+		if err = git.CommitToNewBranch(c.ProjectDir(), c.FixesBranch(), fixesCommitMessage, c.LogDir()); err != nil {
+			return fmt.Errorf("failed to commit the changes made by the quick-fixes to the branch %s: %w", c.FixesBranch(), err)
+		}
+		msg.SuccessMessage("Committed the changes made by the quick-fixes to the branch %s", msg.PrimaryBold(c.FixesBranch()))
+		return nil
+	}, nil
+}
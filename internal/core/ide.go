@@ -17,8 +17,11 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +32,8 @@ import (
 	"github.com/JetBrains/qodana-cli/internal/foundation/exec"
 	"github.com/JetBrains/qodana-cli/internal/platform"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/ideintegration"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
@@ -38,53 +43,62 @@ import (
 )
 
 // getIdeExitCode gets IDEA "exitCode" from SARIF.
-func getIdeExitCode(resultsDir string, c int) (res int) {
+func getIdeExitCode(resultsDir string, c int) (int, error) {
 	if c != 0 {
-		return c
+		return c, nil
 	}
 	s, err := platform.ReadReport(platform.GetShortSarifPath(resultsDir))
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
 	if len(s.Runs) > 0 && len(s.Runs[0].Invocations) > 0 {
 		res := int(s.Runs[0].Invocations[0].ExitCode)
 		if res < exitcodes.QodanaSuccessExitCode || res > exitcodes.QodanaFailThresholdExitCode {
 			log.Printf("Wrong exitCode in sarif: %d", res)
-			return 1
+			return 1, nil
 		}
 		log.Printf("IDE exit code: %d", res)
-		return res
+		return res, nil
 	}
 	log.Printf("IDE process exit code: %d", c)
-	return c
+	return c, nil
 }
 
 // getInvocationProperties gets invocation properties from SARIF.
-func getInvocationProperties(resultsDir string) *sarif.PropertyBag {
+func getInvocationProperties(resultsDir string) (*sarif.PropertyBag, error) {
 	s, err := platform.ReadReport(platform.GetShortSarifPath(resultsDir))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	if len(s.Runs) > 0 && len(s.Runs[0].Invocations) > 0 {
 		if s.Runs[0].Invocations[0].Properties == nil {
-			return &sarif.PropertyBag{}
+			return &sarif.PropertyBag{}, nil
 		}
-		return s.Runs[0].Invocations[0].Properties
+		return s.Runs[0].Invocations[0].Properties, nil
 	}
-	return &sarif.PropertyBag{}
+	return &sarif.PropertyBag{}, nil
 }
 
-func runQodanaLocal(c corescan.Context) (int, error) {
+// runQodanaLocal runs the analysis with the IDE installed on the host, the IDE is stopped when ctx is cancelled and
+// its partial results are kept.
+func runQodanaLocal(ctx context.Context, c corescan.Context) (int, error) {
 	writeProperties(c)
 	args := getIdeRunCommand(c)
-	ideProcess, err := exec.ExecWithTimeout(
+	stopIdeIntegration := followLocalAnalysis(c)
+	ideProcess, err := exec.ExecWithContext(
+		ctx,
 		".",
-		os.Stdout, os.Stderr,
+		msg.LinterLogWriter(os.Stdout), msg.LinterLogWriter(os.Stderr),
 		c.GetAnalysisTimeout(),
 		exitcodes.QodanaTimeoutExitCodePlaceholder,
 		args[0], args[1:]...,
 	)
-	res := getIdeExitCode(c.ResultsDir(), ideProcess)
+	stopIdeIntegration()
+	res, sarifErr := getIdeExitCode(c.ResultsDir(), ideProcess)
+	if sarifErr != nil {
+		postAnalysis(c)
+		return res, errors.Join(err, fmt.Errorf("failed to read the IDE exit code: %w", sarifErr))
+	}
 	if res > exitcodes.QodanaSuccessExitCode && res != exitcodes.QodanaFailThresholdExitCode {
 		postAnalysis(c)
 		return res, err
@@ -97,6 +111,18 @@ func runQodanaLocal(c corescan.Context) (int, error) {
 	return res, err
 }
 
+// followLocalAnalysis sends the analysis stage and the problems found by the local IDE to the IDE integration clients,
+// the native IDE output has no progress markers.
+func followLocalAnalysis(c corescan.Context) func() {
+	if !ideintegration.IsEnabled() {
+		return func() {}
+	}
+	ideintegration.Progress(stageProgress(stageAnalyzing))
+	ctx, cancel := context.WithCancel(context.Background())
+	go platform.FollowSarifProblems(ctx, platform.GetSarifPath(c.ResultsDir()), false)
+	return cancel
+}
+
 func getIdeRunCommand(c corescan.Context) []string {
 	args := []string{c.Prod().IdeScript}
 	if !c.Prod().Is242orNewer() {
@@ -137,7 +163,7 @@ func GetIdeArgs(c corescan.Context) []string {
 		arguments = append(arguments, "--script", c.Script())
 	}
 	if c.Baseline() != "" {
-		arguments = append(arguments, "--baseline", c.Baseline())
+		arguments = append(arguments, "--baseline", baselinePath(c))
 	}
 	if c.BaselineIncludeAbsent() {
 		arguments = append(arguments, "--baseline-include-absent")
@@ -231,6 +257,10 @@ func GetIdeArgs(c corescan.Context) []string {
 			arguments = append(arguments, "--analysis-id", c.AnalysisId())
 		}
 
+		for _, profile := range c.ChainProfiles() {
+			arguments = append(arguments, "--chain-profile", profile)
+		}
+
 		if c.CoverageDir() != "" {
 			arguments = append(arguments, "--coverage-dir", c.CoverageDir())
 		}
@@ -256,6 +286,20 @@ func GetIdeArgs(c corescan.Context) []string {
 	return arguments
 }
 
+// baselinePath returns the baseline path as the linter sees it, the baseline with the renames applied is
+// in the cache directory, which is mounted to the container.
+func baselinePath(c corescan.Context) string {
+	if !c.Analyser().IsContainer() {
+		return c.Baseline()
+	}
+	rel, err := filepath.Rel(c.CacheDir(), c.Baseline())
+	if err != nil || !filepath.IsAbs(c.Baseline()) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return c.Baseline()
+	}
+	// it is safe to use / here because it's a path inside the container
+	return qdcontainer.DataCacheDir + "/" + filepath.ToSlash(rel)
+}
+
 // postAnalysis post-analysis stage: wait for FUS stats to upload
 func postAnalysis(c corescan.Context) {
 	err := startup.SyncIdeaCache(c.ProjectDir(), c.CacheDir(), true)
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -125,7 +126,7 @@ func TestRunQodanaLocal(t *testing.T) {
 		LogDir:     filepath.Join(tmpDir, "log"),
 	}.Build()
 
-	exitCode, err := runQodanaLocal(ctx)
+	exitCode, err := runQodanaLocal(context.Background(), ctx)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, exitCode)
 }
@@ -163,7 +164,7 @@ func TestRunQodanaLocal_PathWithSpaces(t *testing.T) {
 		LogDir:     filepath.Join(tmpDir, "my log"),
 	}.Build()
 
-	exitCode, err := runQodanaLocal(ctx)
+	exitCode, err := runQodanaLocal(context.Background(), ctx)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, exitCode)
 }
@@ -0,0 +1,343 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+)
+
+// The policies of --image-vuln-check.
+const (
+	ImageVulnerabilitiesOff  = "off"
+	ImageVulnerabilitiesWarn = "warn"
+	ImageVulnerabilitiesFail = "fail"
+
+	// vulnAttestationType is the predicate type of the vulnerability attestations created by cosign attest --type vuln
+	vulnAttestationType = "https://cosign.sigstore.dev/attestation/vuln/v1"
+	// imageVulnerabilitiesShown is the number of vulnerabilities listed in the output
+	imageVulnerabilitiesShown = 10
+)
+
+// vulnerabilitySeverities are the severities of the scanners from the lowest to the highest.
+var vulnerabilitySeverities = []string{"UNKNOWN", "NEGLIGIBLE", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// ImageVulnerability is a known vulnerability of a package in the image.
+type ImageVulnerability struct {
+	Id       string
+	Package  string
+	Severity string
+}
+
+// imageVulnerabilitiesSource finds the vulnerabilities of the image, ok is false if the source is not available.
+type imageVulnerabilitiesSource struct {
+	name string
+	find func(image string) (vulnerabilities []ImageVulnerability, ok bool, err error)
+}
+
+// imageVulnerabilitiesSources are tried in order, the attestation isn't verified by cosign download attestation, so it's
+// only used when the image can't be scanned.
+var imageVulnerabilitiesSources = []imageVulnerabilitiesSource{
+	{"trivy", findTrivyImageVulnerabilities},
+	{"grype", findGrypeImageVulnerabilities},
+	{"cosign attestation (unverified)", findAttestedImageVulnerabilities},
+}
+
+// CheckImageVulnerabilities reports the vulnerabilities of the image with the given severity or higher, it returns an
+// error if the image must not be run by the policy: the vulnerabilities are found or can't be checked.
+func CheckImageVulnerabilities(image string, policy string, severity string) error {
+	policy = strings.ToLower(policy)
+	if policy == "" || policy == ImageVulnerabilitiesOff {
+		return nil
+	}
+	if policy != ImageVulnerabilitiesWarn && policy != ImageVulnerabilitiesFail {
+		return fmt.Errorf("unknown --image-vuln-check policy %q, use off, warn or fail", policy)
+	}
+	minSeverity := severityRank(severity)
+	if minSeverity < 0 {
+		return fmt.Errorf("unknown --image-vuln-level %q, use low, medium, high or critical", severity)
+	}
+
+	var vulnerabilities []ImageVulnerability
+	source := ""
+	var sourceErr error
+	msg.PrintProcess(
+		func(_ *pterm.SpinnerPrinter) {
+			source, vulnerabilities, sourceErr = findImageVulnerabilities(image)
+		},
+		fmt.Sprintf("Checking the image %s for vulnerabilities", msg.PrimaryBold(image)),
+		"",
+	)
+	if sourceErr != nil {
+		return imageVulnerabilitiesResult(policy, sourceErr)
+	}
+
+	found := filterImageVulnerabilities(vulnerabilities, minSeverity)
+	if len(found) == 0 {
+		msg.SuccessMessage(
+			"No vulnerabilities with %s or higher severity found in the image (checked with %s)",
+			strings.ToLower(severity),
+			source,
+		)
+		return nil
+	}
+	lines := make([]string, 0, imageVulnerabilitiesShown)
+	for _, v := range found[:min(len(found), imageVulnerabilitiesShown)] {
+		lines = append(lines, fmt.Sprintf("   %s %s (%s)", v.Severity, v.Id, v.Package))
+	}
+	if len(found) > imageVulnerabilitiesShown {
+		lines = append(lines, fmt.Sprintf("   and %d more", len(found)-imageVulnerabilitiesShown))
+	}
+	return imageVulnerabilitiesResult(
+		policy,
+		fmt.Errorf(
+			"the image %s has %d vulnerabilities with %s or higher severity (checked with %s):\n%s",
+			image,
+			len(found),
+			strings.ToLower(severity),
+			source,
+			strings.Join(lines, "\n"),
+		),
+	)
+}
+
+// imageVulnerabilitiesResult returns the problem as an error for the fail policy, for the warn policy it's only printed.
+func imageVulnerabilitiesResult(policy string, problem error) error {
+	if policy == ImageVulnerabilitiesFail {
+		return problem
+	}
+	msg.WarningMessageCI("%s", problem)
+	return nil
+}
+
+// findImageVulnerabilities returns the vulnerabilities from the first available source.
+func findImageVulnerabilities(image string) (string, []ImageVulnerability, error) {
+	for _, source := range imageVulnerabilitiesSources {
+		vulnerabilities, ok, err := source.find(image)
+		if err != nil {
+			return source.name, nil, fmt.Errorf("failed to check the image %s with %s: %w", image, source.name, err)
+		}
+		if ok {
+			return source.name, vulnerabilities, nil
+		}
+		log.Debugf("Image vulnerabilities source %s is not available", source.name)
+	}
+	return "", nil, errors.New(
+		"can't check the image for vulnerabilities: install trivy or grype, or cosign to use the vulnerability attestation of the image",
+	)
+}
+
+// filterImageVulnerabilities returns the unique vulnerabilities with the given severity rank or higher, the most severe first.
+func filterImageVulnerabilities(vulnerabilities []ImageVulnerability, minSeverity int) []ImageVulnerability {
+	seen := make(map[ImageVulnerability]bool)
+	result := make([]ImageVulnerability, 0)
+	for _, v := range vulnerabilities {
+		v.Severity = strings.ToUpper(v.Severity)
+		if severityRank(v.Severity) < minSeverity || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	sort.SliceStable(
+		result, func(i, j int) bool {
+			return severityRank(result[i].Severity) > severityRank(result[j].Severity)
+		},
+	)
+	return result
+}
+
+// severityRank returns the position of the severity in vulnerabilitySeverities, -1 for an unknown value.
+func severityRank(severity string) int {
+	return slices.Index(vulnerabilitySeverities, strings.ToUpper(severity))
+}
+
+// findAttestedImageVulnerabilities reads the vulnerability attestation attached to the image (cosign attest --type vuln),
+// the attestation holds the report of the scanner run when the image was published. Its signature isn't verified.
+func findAttestedImageVulnerabilities(image string) ([]ImageVulnerability, bool, error) {
+	cosign, err := exec.LookPath("cosign")
+	if err != nil {
+		return nil, false, nil
+	}
+	out, err := runVulnerabilityScanner(cosign, "download", "attestation", "--predicate-type", "vuln", image)
+	if err != nil {
+		log.Debugf("No vulnerability attestation found for %s: %s", image, err)
+		return nil, false, nil
+	}
+	return parseVulnAttestations(out)
+}
+
+func findTrivyImageVulnerabilities(image string) ([]ImageVulnerability, bool, error) {
+	trivy, err := exec.LookPath("trivy")
+	if err != nil {
+		return nil, false, nil
+	}
+	out, err := runVulnerabilityScanner(trivy, "image", "--quiet", "--scanners", "vuln", "--format", "json", image)
+	if err != nil {
+		return nil, false, err
+	}
+	vulnerabilities, err := parseTrivyReport(out)
+	return vulnerabilities, true, err
+}
+
+func findGrypeImageVulnerabilities(image string) ([]ImageVulnerability, bool, error) {
+	grype, err := exec.LookPath("grype")
+	if err != nil {
+		return nil, false, nil
+	}
+	out, err := runVulnerabilityScanner(grype, "--quiet", "--output", "json", image)
+	if err != nil {
+		return nil, false, err
+	}
+	vulnerabilities, err := parseGrypeReport(out)
+	return vulnerabilities, true, err
+}
+
+func runVulnerabilityScanner(name string, args ...string) ([]byte, error) {
+	log.Debugf("Running %s %s", name, strings.Join(args, " "))
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			PkgName         string `json:"PkgName"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			Id       string `json:"id"`
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+		Artifact struct {
+			Name string `json:"name"`
+		} `json:"artifact"`
+	} `json:"matches"`
+}
+
+func parseTrivyReport(data []byte) ([]ImageVulnerability, error) {
+	var report trivyReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse the trivy report: %w", err)
+	}
+	var vulnerabilities []ImageVulnerability
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			vulnerabilities = append(
+				vulnerabilities,
+				ImageVulnerability{Id: v.VulnerabilityID, Package: v.PkgName, Severity: v.Severity},
+			)
+		}
+	}
+	return vulnerabilities, nil
+}
+
+func parseGrypeReport(data []byte) ([]ImageVulnerability, error) {
+	var report grypeReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse the grype report: %w", err)
+	}
+	vulnerabilities := make([]ImageVulnerability, 0, len(report.Matches))
+	for _, match := range report.Matches {
+		vulnerabilities = append(
+			vulnerabilities,
+			ImageVulnerability{
+				Id:       match.Vulnerability.Id,
+				Package:  match.Artifact.Name,
+				Severity: match.Vulnerability.Severity,
+			},
+		)
+	}
+	return vulnerabilities, nil
+}
+
+// parseVulnAttestations reads the DSSE envelopes printed by cosign download attestation, one per line,
+// the scanner report is in the predicate of the in-toto statement. found is false if there is no vulnerability attestation.
+func parseVulnAttestations(data []byte) ([]ImageVulnerability, bool, error) {
+	var vulnerabilities []ImageVulnerability
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var envelope struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.Unmarshal(line, &envelope); err != nil {
+			return nil, false, fmt.Errorf("failed to parse the attestation: %w", err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode the attestation: %w", err)
+		}
+		var statement struct {
+			PredicateType string `json:"predicateType"`
+			Predicate     struct {
+				Scanner struct {
+					Uri    string          `json:"uri"`
+					Result json.RawMessage `json:"result"`
+				} `json:"scanner"`
+			} `json:"predicate"`
+		}
+		if err := json.Unmarshal(payload, &statement); err != nil {
+			return nil, false, fmt.Errorf("failed to parse the attestation statement: %w", err)
+		}
+		if statement.PredicateType != vulnAttestationType {
+			continue
+		}
+		var attested []ImageVulnerability
+		result := statement.Predicate.Scanner.Result
+		if strings.Contains(statement.Predicate.Scanner.Uri, "grype") {
+			attested, err = parseGrypeReport(result)
+		} else {
+			attested, err = parseTrivyReport(result)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		vulnerabilities = append(vulnerabilities, attested...)
+		found = true
+	}
+	return vulnerabilities, found, scanner.Err()
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const trivyImageReport = `{
+  "ArtifactName": "jetbrains/qodana-jvm:2026.2",
+  "Results": [
+    {
+      "Target": "jetbrains/qodana-jvm:2026.2 (debian 12.5)",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2024-0001", "PkgName": "openssl", "Severity": "CRITICAL"},
+        {"VulnerabilityID": "CVE-2024-0002", "PkgName": "libc6", "Severity": "LOW"}
+      ]
+    },
+    {"Target": "Java", "Vulnerabilities": [{"VulnerabilityID": "CVE-2024-0003", "PkgName": "log4j", "Severity": "HIGH"}]}
+  ]
+}`
+
+const grypeImageReport = `{
+  "matches": [
+    {"vulnerability": {"id": "CVE-2024-0004", "severity": "Critical"}, "artifact": {"name": "zlib"}},
+    {"vulnerability": {"id": "CVE-2024-0005", "severity": "Medium"}, "artifact": {"name": "curl"}}
+  ]
+}`
+
+func TestParseImageVulnerabilityReports(t *testing.T) {
+	trivy, err := parseTrivyReport([]byte(trivyImageReport))
+	assert.NoError(t, err)
+	assert.Equal(
+		t, []ImageVulnerability{
+			{Id: "CVE-2024-0001", Package: "openssl", Severity: "CRITICAL"},
+			{Id: "CVE-2024-0002", Package: "libc6", Severity: "LOW"},
+			{Id: "CVE-2024-0003", Package: "log4j", Severity: "HIGH"},
+		}, trivy,
+	)
+
+	grype, err := parseGrypeReport([]byte(grypeImageReport))
+	assert.NoError(t, err)
+	assert.Equal(
+		t, []ImageVulnerability{
+			{Id: "CVE-2024-0004", Package: "zlib", Severity: "Critical"},
+			{Id: "CVE-2024-0005", Package: "curl", Severity: "Medium"},
+		}, grype,
+	)
+
+	_, err = parseTrivyReport([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestParseVulnAttestations(t *testing.T) {
+	statement := func(predicateType, uri, result string) string {
+		payload := fmt.Sprintf(
+			`{"_type": "https://in-toto.io/Statement/v0.1", "predicateType": %q, "predicate": {"scanner": {"uri": %q, "result": %s}}}`,
+			predicateType,
+			uri,
+			result,
+		)
+		return fmt.Sprintf(
+			`{"payloadType": "application/vnd.in-toto+json", "payload": %q, "signatures": []}`,
+			base64.StdEncoding.EncodeToString([]byte(payload)),
+		)
+	}
+	data := statement("https://slsa.dev/provenance/v0.2", "", "{}") + "\n" +
+		statement(vulnAttestationType, "pkg:github/anchore/grype@0.74.0", grypeImageReport) + "\n"
+
+	vulnerabilities, found, err := parseVulnAttestations([]byte(data))
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Len(t, vulnerabilities, 2)
+
+	_, found, err = parseVulnAttestations([]byte(statement("https://slsa.dev/provenance/v0.2", "", "{}")))
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestFilterImageVulnerabilities(t *testing.T) {
+	vulnerabilities := []ImageVulnerability{
+		{Id: "CVE-1", Package: "a", Severity: "high"},
+		{Id: "CVE-2", Package: "b", Severity: "CRITICAL"},
+		{Id: "CVE-1", Package: "a", Severity: "HIGH"},
+		{Id: "CVE-3", Package: "c", Severity: "MEDIUM"},
+	}
+	assert.Equal(
+		t, []ImageVulnerability{
+			{Id: "CVE-2", Package: "b", Severity: "CRITICAL"},
+			{Id: "CVE-1", Package: "a", Severity: "HIGH"},
+		}, filterImageVulnerabilities(vulnerabilities, severityRank("high")),
+	)
+	assert.Empty(t, filterImageVulnerabilities(vulnerabilities[3:], severityRank("critical")))
+}
+
+func TestCheckImageVulnerabilities(t *testing.T) {
+	original := imageVulnerabilitiesSources
+	defer func() { imageVulnerabilitiesSources = original }()
+	unavailable := imageVulnerabilitiesSource{
+		"unavailable", func(string) ([]ImageVulnerability, bool, error) { return nil, false, nil },
+	}
+	scanner := imageVulnerabilitiesSource{
+		"scanner", func(string) ([]ImageVulnerability, bool, error) {
+			return []ImageVulnerability{{Id: "CVE-1", Package: "openssl", Severity: "HIGH"}}, true, nil
+		},
+	}
+
+	imageVulnerabilitiesSources = []imageVulnerabilitiesSource{unavailable, scanner}
+	assert.NoError(t, CheckImageVulnerabilities("image", ImageVulnerabilitiesOff, "critical"))
+	assert.NoError(t, CheckImageVulnerabilities("image", ImageVulnerabilitiesFail, "critical"))
+	assert.NoError(t, CheckImageVulnerabilities("image", ImageVulnerabilitiesWarn, "high"))
+	assert.Error(t, CheckImageVulnerabilities("image", ImageVulnerabilitiesFail, "high"))
+	assert.Error(t, CheckImageVulnerabilities("image", "block", "high"))
+	assert.Error(t, CheckImageVulnerabilities("image", ImageVulnerabilitiesFail, "severe"))
+
+	imageVulnerabilitiesSources = []imageVulnerabilitiesSource{unavailable}
+	assert.NoError(t, CheckImageVulnerabilities("image", ImageVulnerabilitiesWarn, "critical"))
+	assert.Error(t, CheckImageVulnerabilities("image", ImageVulnerabilitiesFail, "critical"))
+}
@@ -19,6 +19,7 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -31,6 +32,7 @@ import (
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/internal/platform/effectiveconfig"
 	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	log "github.com/sirupsen/logrus"
@@ -40,7 +42,7 @@ var defaultRunner = &defaultAnalysisRunner{}
 
 // AnalysisRunner defines the interface for logic on running analysis on commits
 type AnalysisRunner interface {
-	RunFunc(hash string, ctx context.Context, c corescan.Context) (bool, int)
+	RunFunc(hash string, ctx context.Context, c corescan.Context) (bool, int, error)
 }
 
 // DefaultAnalysisRunner is the production implementation of RunFunc
@@ -48,7 +50,7 @@ type defaultAnalysisRunner struct{}
 
 // SequenceRunner defines the interface for different sequence analysis strategies
 type SequenceRunner interface {
-	RunSequence(scopeFile string, runner AnalysisRunner) int
+	RunSequence(scopeFile string, runner AnalysisRunner) (int, error)
 	GetParams() (corescan.Context, string, string)
 	ComputeEndHash() string
 }
@@ -122,35 +124,35 @@ func NewReverseScopedAnalyzer(
 	}
 }
 
-func (sa *ScopedAnalyzer) RunAnalysis() int {
+func (sa *ScopedAnalyzer) RunAnalysis() (int, error) {
 	c, startRef, endRef := sa.sequenceRunner.GetParams()
 	var err error
 	if startRef == "" || endRef == "" {
-		log.Fatal("No commits given. Consider passing --commit or --diff-start and --diff-end (optional) with the range of commits to analyze.")
+		return 1, errors.New("no commits given. Consider passing --commit or --diff-start and --diff-end (optional) with the range of commits to analyze")
 	}
 
 	startSha, err := git.RevParse(c.RepositoryRoot(), startRef, c.LogDir())
 	if err != nil {
-		log.Fatalf("Failed to calculate analysis scope: %q is not a valid commit ref.", startRef)
+		return 1, fmt.Errorf("failed to calculate analysis scope: %q is not a valid commit ref", startRef)
 	}
 
 	endSha, err := git.RevParse(c.RepositoryRoot(), endRef, c.LogDir())
 	if err != nil {
-		log.Fatalf("Failed to calculate analysis scope: %q is not a valid commit ref.", endRef)
+		return 1, fmt.Errorf("failed to calculate analysis scope: %q is not a valid commit ref", endRef)
 	}
 
 	changedFiles, err := git.ComputeChangedFiles(c.RepositoryRoot(), startSha, endSha, c.LogDir())
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
 	if len(changedFiles.Files) == 0 {
 		log.Warnf("Nothing to compare between %s and %s", startRef, endRef)
-		return exitcodes.QodanaEmptyChangesetExitCodePlaceholder
+		return exitcodes.QodanaEmptyChangesetExitCodePlaceholder, nil
 	}
 
 	scopeFile, err := writeChangesFile(c, changedFiles)
 	if err != nil {
-		log.Fatal("Failed to prepare diff run ", err)
+		return 1, fmt.Errorf("failed to prepare diff run: %w", err)
 	}
 	defer func() {
 		_ = os.Remove(scopeFile)
@@ -159,10 +161,32 @@ func (sa *ScopedAnalyzer) RunAnalysis() int {
 	return sa.sequenceRunner.RunSequence(scopeFile, sa.runner)
 }
 
-func (r *defaultAnalysisRunner) RunFunc(hash string, ctx context.Context, c corescan.Context) (bool, int) {
+// runStagedChanges analyses the current working tree in the scope of the changes staged for the next commit.
+func runStagedChanges(ctx context.Context, c corescan.Context) (int, error) {
+	changedFiles, err := git.ComputeStagedChangedFiles(c.RepositoryRoot(), c.LogDir())
+	if err != nil {
+		return 1, err
+	}
+	if len(changedFiles.Files) == 0 {
+		log.Warnf("No staged changes in %s", c.RepositoryRoot())
+		return exitcodes.QodanaEmptyChangesetExitCodePlaceholder, nil
+	}
+
+	scopeFile, err := writeChangesFile(c, changedFiles)
+	if err != nil {
+		return 1, fmt.Errorf("failed to prepare staged changes run: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(scopeFile)
+	}()
+
+	return runQodana(ctx, c.StagedChangesRun(scopeFile))
+}
+
+func (r *defaultAnalysisRunner) RunFunc(hash string, ctx context.Context, c corescan.Context) (bool, int, error) {
 	e := git.CheckoutAndUpdateSubmodule(c.RepositoryRoot(), hash, true, c.LogDir())
 	if e != nil {
-		log.Fatalf("Cannot checkout commit %s: %v", hash, e)
+		return true, 1, fmt.Errorf("cannot checkout commit %s: %w", hash, e)
 	}
 
 	log.Infof("Analysing %s", hash)
@@ -175,7 +199,7 @@ func (r *defaultAnalysisRunner) RunFunc(hash string, ctx context.Context, c core
 	)
 	effectiveConfigDir, cleanup, err := fs.CreateTempDir("qd-effective-config-")
 	if err != nil {
-		log.Fatalf("Failed to create Qodana effective config directory: %v", err)
+		return true, 1, fmt.Errorf("failed to create Qodana effective config directory: %w", err)
 	}
 	defer cleanup()
 
@@ -186,98 +210,113 @@ func (r *defaultAnalysisRunner) RunFunc(hash string, ctx context.Context, c core
 		c.GlobalConfigurationId(),
 		effectiveConfigDir,
 		c.LogDir(),
+		product.QodanaYamlNames(c.Analyser())...,
 	)
 	if err != nil {
-		log.Fatalf("Failed to load Qodana configuration during analysis of commit %s: %v", hash, err)
+		return true, 1, fmt.Errorf("failed to load Qodana configuration during analysis of commit %s: %w", hash, err)
+	}
+	if err = excludeIgnoredProblems(effectiveConfigFiles); err != nil {
+		return true, 1, err
 	}
 
 	// if local qodana yaml doesn't exist on revision, for bootstrap fallback to the one constructed at the start
-	var bootstrap string
+	var bootstrapSteps []qdyaml.PrepareStep
 	if c.LocalQodanaYamlExists() {
-		yaml := qdyaml.LoadQodanaYamlByFullPath(effectiveConfigFiles.EffectiveQodanaYamlPath)
-		bootstrap = yaml.Bootstrap
+		yaml := qdyaml.LoadQodanaYamlForLinter(
+			effectiveConfigFiles.EffectiveQodanaYamlPath,
+			product.QodanaYamlNames(c.Analyser())...,
+		)
+		bootstrapSteps = qdyaml.BootstrapSteps(yaml.Prepare, yaml.Bootstrap, yaml.BootstrapRetry)
 	} else {
-		bootstrap = c.QodanaYamlConfig().Bootstrap
+		bootstrapSteps = c.QodanaYamlConfig().BootstrapSteps()
 	}
 	// TODO: mention that bootstrap should be relative to the project path
-	utils.Bootstrap(bootstrap, c.ProjectDir())
+	utils.Bootstrap(bootstrapSteps, c.ProjectDir(), c.LogDir())
 
 	contextForAnalysis := c.WithEffectiveConfigurationDirOnRevision(effectiveConfigFiles.ConfigDir)
-	exitCode := runQodana(ctx, contextForAnalysis)
+	exitCode, err := runQodana(ctx, contextForAnalysis)
+	if err != nil {
+		return true, 1, err
+	}
 	if exitCode != 0 && exitCode != 255 {
 		log.Errorf("Qodana analysis on %s exited with code %d. Aborting", hash, exitCode)
-		return true, exitCode
+		return true, exitCode, nil
 	}
-	return false, exitCode
+	return false, exitCode, nil
 }
 
 func (r *ScopeSequenceRunner) RunSequence(
 	scopeFile string,
 	runner AnalysisRunner,
-) int {
+) (int, error) {
 	ctx, c, startHash, endHash := computeSequenceParams(&r.SequenceRunnerBase)
 	startRunContext := c.FirstStageOfScopedScript(scopeFile)
-	stop, code := runner.RunFunc(startHash, ctx, startRunContext)
+	stop, code, err := runner.RunFunc(startHash, ctx, startRunContext)
 	if stop {
-		return code
+		return code, err
 	}
 
 	startSarif := platform.GetSarifPath(startRunContext.ResultsDir())
 
 	endRunContext := c.SecondStageOfScopedScript(scopeFile, startSarif)
-	stop, code = runner.RunFunc(endHash, ctx, endRunContext)
+	stop, code, err = runner.RunFunc(endHash, ctx, endRunContext)
 	if stop {
-		return code
+		return code, err
 	}
 
-	copyAndSaveReport(endRunContext, c)
-	return code
+	return code, copyAndSaveReport(endRunContext, c)
 }
 
 func (r *ReverseScopeSequenceRunner) RunSequence(
 	scopeFile string,
 	runner AnalysisRunner,
-) int {
+) (int, error) {
 	var code int
 	var stop bool
+	var err error
 
 	ctx, c, startHash, endHash := computeSequenceParams(&r.SequenceRunnerBase)
 	newCodeContext := c.FirstStageOfReverseScopedScript(scopeFile)
-	if stop, code = runner.RunFunc(endHash, ctx, newCodeContext); stop {
-		return code
+	if stop, code, err = runner.RunFunc(endHash, ctx, newCodeContext); stop {
+		return code, err
 	}
 
 	currentContext := newCodeContext
-	if shouldProceedToNextStage(currentContext) {
-		copyAndSaveReport(currentContext, c)
-		return code
+	if proceed, err := shouldProceedToNextStage(currentContext); err != nil {
+		return 1, err
+	} else if proceed {
+		return code, copyAndSaveReport(currentContext, c)
 	}
 
 	scopeFile, coverageArtifactsPath, newCodeSarif := prepareArtifactPaths(newCodeContext, scopeFile)
 
 	startRunContext := c.SecondStageOfReverseScopedScript(scopeFile, newCodeSarif)
-	copyCoverageFromNewStage(coverageArtifactsPath, startRunContext.ResultsDir())
-	if stop, code = runner.RunFunc(startHash, ctx, startRunContext); stop {
-		return code
+	if err = copyCoverageFromNewStage(coverageArtifactsPath, startRunContext.ResultsDir()); err != nil {
+		return 1, err
+	}
+	if stop, code, err = runner.RunFunc(startHash, ctx, startRunContext); stop {
+		return code, err
 	}
 
 	currentContext = startRunContext
-	if shouldProceedToNextStage(currentContext) {
-		copyAndSaveReport(currentContext, c)
-		return code
+	if proceed, err := shouldProceedToNextStage(currentContext); err != nil {
+		return 1, err
+	} else if proceed {
+		return code, copyAndSaveReport(currentContext, c)
 	}
 
 	if shouldApplyFixes := c.ApplyFixes() || c.Cleanup(); shouldApplyFixes {
 		fixesContext := c.ThirdStageOfReverseScopedScript(scopeFile, newCodeSarif)
-		copyCoverageFromNewStage(coverageArtifactsPath, fixesContext.ResultsDir())
-		if stop, code = runner.RunFunc(endHash, ctx, fixesContext); stop {
-			return code
+		if err = copyCoverageFromNewStage(coverageArtifactsPath, fixesContext.ResultsDir()); err != nil {
+			return 1, err
+		}
+		if stop, code, err = runner.RunFunc(endHash, ctx, fixesContext); stop {
+			return code, err
 		}
 		currentContext = fixesContext
 	}
 
-	copyAndSaveReport(currentContext, c)
-	return code
+	return code, copyAndSaveReport(currentContext, c)
 }
 
 func computeSequenceParams(r *SequenceRunnerBase) (context.Context, corescan.Context, string, string) {
@@ -316,26 +355,31 @@ func prepareArtifactPaths(
 	return scopeFile, coverageArtifactsPath, newCodeSarif
 }
 
-func shouldProceedToNextStage(ctx corescan.Context) bool {
-	value := getInvocationProperties(ctx.ResultsDir()).AdditionalProperties["qodana.result.skipped"]
+func shouldProceedToNextStage(ctx corescan.Context) (bool, error) {
+	properties, err := getInvocationProperties(ctx.ResultsDir())
+	if err != nil {
+		return false, err
+	}
+	value := properties.AdditionalProperties["qodana.result.skipped"]
 	if strValue, ok := value.(string); ok {
-		return strValue == "false"
+		return strValue == "false", nil
 	}
 	if boolValue, ok := value.(bool); ok {
-		return !boolValue
+		return !boolValue, nil
 	}
-	return false
+	return false, nil
 }
 
-func copyAndSaveReport(lastContext corescan.Context, c corescan.Context) {
+func copyAndSaveReport(lastContext corescan.Context, c corescan.Context) error {
 	err := fs.CopyDir(lastContext.ResultsDir(), c.ResultsDir())
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	if c.SaveReport() || c.ShowReport() {
 		commoncontext.SaveReport(c.ResultsDir(), c.ReportDir(), c.CacheDir())
 	}
+	return nil
 }
 
 // writeChangesFile creates a temp file containing the changes between diffStart and diffEnd
@@ -368,12 +412,13 @@ func writeChangesFile(c corescan.Context, changedFiles git.ChangedFiles) (string
 	return file.Name(), nil
 }
 
-func copyCoverageFromNewStage(coverageDataPath string, resultsDir string) {
+func copyCoverageFromNewStage(coverageDataPath string, resultsDir string) error {
 	if info, err := os.Stat(coverageDataPath); err == nil && info.IsDir() {
 		startup.MakeDirAll(resultsDir)
 		targetCoveragePath := filepath.Join(resultsDir, "coverage")
 		if err := fs.CopyDir(coverageDataPath, targetCoveragePath); err != nil {
-			log.Fatalf("Failed to copy coverage data from %s to %s: %v", coverageDataPath, targetCoveragePath, err)
+			return fmt.Errorf("failed to copy coverage data from %s to %s: %w", coverageDataPath, targetCoveragePath, err)
 		}
 	}
+	return nil
 }
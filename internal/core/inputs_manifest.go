@@ -0,0 +1,282 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// InputsManifestFileName is the file in the results directory listing the files in scope of the scan.
+const InputsManifestFileName = "inputs-manifest.json"
+
+const inputsManifestVersion = 1
+
+// InputsManifest lists the files considered in scope of the scan, relative to the project directory,
+// so build systems like Bazel or Buck can declare them as the inputs of the scan action.
+type InputsManifest struct {
+	Version       int      `json:"version"`
+	OnlyDirectory string   `json:"onlyDirectory,omitempty"`
+	Files         []string `json:"files"`
+}
+
+// inputsSkippedDirs are never the scan inputs: VCS metadata and the Qodana own files.
+var inputsSkippedDirs = []string{".git", ".qodana"}
+
+// processInputsManifest writes the manifest of the files in scope to the results directory.
+// If --inputs-manifest is given, the scan fails when the files in scope differ from the declared ones.
+func processInputsManifest(c corescan.Context) error {
+	files, err := inputFiles(c)
+	if err != nil {
+		if c.InputsManifest() != "" {
+			return fmt.Errorf("cannot verify the scan inputs against --inputs-manifest: %w", err)
+		}
+		msg.WarningMessage("Failed to compute the scan inputs: %s", err)
+		return nil
+	}
+	manifest := InputsManifest{
+		Version:       inputsManifestVersion,
+		OnlyDirectory: filepath.ToSlash(c.OnlyDirectory()),
+		Files:         files,
+	}
+	if err := writeInputsManifest(filepath.Join(c.ResultsDir(), InputsManifestFileName), manifest); err != nil {
+		msg.WarningMessage("Failed to write %s: %s", InputsManifestFileName, err)
+	}
+	if c.InputsManifest() == "" {
+		return nil
+	}
+
+	declared, err := readInputsManifest(c.InputsManifest())
+	if err != nil {
+		return fmt.Errorf("failed to read the inputs manifest %s: %w", c.InputsManifest(), err)
+	}
+	undeclared, missing := diffInputs(declared, files)
+	if len(undeclared) == 0 && len(missing) == 0 {
+		log.Debugf("The scan inputs match %s: %d files", c.InputsManifest(), len(files))
+		return nil
+	}
+	var problems []string
+	if len(undeclared) > 0 {
+		problems = append(problems, "not declared:\n  "+strings.Join(undeclared, "\n  "))
+	}
+	if len(missing) > 0 {
+		problems = append(problems, "declared but not in scope:\n  "+strings.Join(missing, "\n  "))
+	}
+	return fmt.Errorf(
+		"the files in scope of the scan differ from %s, %s",
+		c.InputsManifest(),
+		strings.Join(problems, "\n"),
+	)
+}
+
+// inputFiles returns the sorted slash-separated paths of the files in scope, relative to the project directory.
+// In a git repository these are the tracked and not ignored untracked files, otherwise all files of the project.
+func inputFiles(c corescan.Context) ([]string, error) {
+	var files []string
+	var err error
+	if isGitRepository(c.RepositoryRoot()) {
+		files, err = git.LsFiles(c.ProjectDir(), c.LogDir())
+	} else {
+		files, err = walkFiles(c.ProjectDir())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := slices.Clone(inputsSkippedDirs)
+	for _, dir := range []string{c.ResultsDir(), c.CacheDir()} {
+		if rel, err := filepath.Rel(c.ProjectDir(), dir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			skipped = append(skipped, filepath.ToSlash(rel))
+		}
+	}
+
+	existing := make([]string, 0, len(files))
+	for _, file := range files {
+		// ls-files lists the tracked files deleted in the working tree as well
+		if info, err := os.Stat(filepath.Join(c.ProjectDir(), file)); err == nil && !info.IsDir() {
+			existing = append(existing, filepath.ToSlash(file))
+		}
+	}
+	return filterInputs(existing, c.OnlyDirectory(), c.QodanaYamlConfig().Excludes, skipped), nil
+}
+
+func isGitRepository(repositoryRoot string) bool {
+	if !utils.IsInstalled("git") {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(repositoryRoot, ".git"))
+	return err == nil
+}
+
+func walkFiles(root string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.WalkDir(
+		root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != root && slices.Contains(inputsSkippedDirs, d.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+			return nil
+		},
+	)
+	return files, err
+}
+
+// filterInputs keeps the files inside onlyDirectory that are not excluded from all inspections in qodana.yaml,
+// and are not inside one of the skipped directories.
+func filterInputs(files []string, onlyDirectory string, excludes []qdyaml.Clude, skipped []string) []string {
+	var excludedPaths []string
+	for _, exclude := range excludes {
+		if exclude.Name == "All" {
+			excludedPaths = append(excludedPaths, exclude.Paths...)
+		}
+	}
+	onlyDirectory = strings.Trim(filepath.ToSlash(onlyDirectory), "/")
+	if onlyDirectory == "." {
+		onlyDirectory = ""
+	}
+
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		if onlyDirectory != "" && !isUnder(file, onlyDirectory) {
+			continue
+		}
+		if slices.ContainsFunc(skipped, func(dir string) bool { return isUnder(file, dir) }) {
+			continue
+		}
+		if slices.ContainsFunc(excludedPaths, func(p string) bool { return matchesExcludedPath(file, p) }) {
+			continue
+		}
+		result = append(result, file)
+	}
+	slices.Sort(result)
+	return result
+}
+
+// matchesExcludedPath returns true if the file is the excluded path, is inside it, or matches it as a glob pattern.
+func matchesExcludedPath(file string, excluded string) bool {
+	excluded = strings.Trim(filepath.ToSlash(excluded), "/")
+	if excluded == "" {
+		return false
+	}
+	if isUnder(file, excluded) {
+		return true
+	}
+	// like in .gitignore, a pattern without a slash matches the file name in any directory
+	name := file
+	if !strings.Contains(excluded, "/") {
+		name = path.Base(file)
+	}
+	matched, err := path.Match(excluded, name)
+	return err == nil && matched
+}
+
+func isUnder(file string, dir string) bool {
+	return file == dir || strings.HasPrefix(file, dir+"/")
+}
+
+func writeInputsManifest(manifestPath string, manifest InputsManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(manifestPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath, data, 0o644)
+}
+
+// readInputsManifest reads the declared inputs either in the format written by the scan,
+// or as a plain list of paths, one per line, as build systems usually produce.
+func readInputsManifest(manifestPath string) ([]string, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		var manifest InputsManifest
+		if err = json.Unmarshal(trimmed, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", manifestPath, err)
+		}
+		files = manifest.Files
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			files = append(files, scanner.Text())
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		file = strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(file)), "./")
+		if file != "" {
+			result = append(result, file)
+		}
+	}
+	return result, nil
+}
+
+// diffInputs returns the files in scope that are not declared, and the declared files that are not in scope.
+func diffInputs(declared []string, actual []string) (undeclared []string, missing []string) {
+	declaredSet := make(map[string]bool, len(declared))
+	for _, file := range declared {
+		declaredSet[file] = true
+	}
+	actualSet := make(map[string]bool, len(actual))
+	for _, file := range actual {
+		actualSet[file] = true
+		if !declaredSet[file] {
+			undeclared = append(undeclared, file)
+		}
+	}
+	for file := range declaredSet {
+		if !actualSet[file] {
+			missing = append(missing, file)
+		}
+	}
+	slices.Sort(missing)
+	return undeclared, missing
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterInputs(t *testing.T) {
+	files := []string{
+		"src/main/App.java",
+		"src/main/gen/Parser.java",
+		"src/test/AppTest.java",
+		"docs/readme.md",
+		"build/out.log",
+		"qodana.yaml",
+		"src/main/Util.java",
+	}
+	excludes := []qdyaml.Clude{
+		{Name: "All", Paths: []string{"src/main/gen/", "*.md"}},
+		{Name: "JavaDoc", Paths: []string{"src/main/Util.java"}},
+	}
+
+	assert.Equal(
+		t,
+		[]string{"qodana.yaml", "src/main/App.java", "src/main/Util.java", "src/test/AppTest.java"},
+		filterInputs(files, "", excludes, []string{"build"}),
+	)
+	assert.Equal(
+		t,
+		[]string{"src/main/App.java", "src/main/Util.java"},
+		filterInputs(files, "src/main/", excludes, nil),
+	)
+}
+
+func TestReadInputsManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonManifest := filepath.Join(dir, InputsManifestFileName)
+	err := writeInputsManifest(jsonManifest, InputsManifest{Version: 1, Files: []string{"a.go", "pkg/b.go"}})
+	assert.NoError(t, err)
+	files, err := readInputsManifest(jsonManifest)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "pkg/b.go"}, files)
+
+	listManifest := filepath.Join(dir, "inputs.txt")
+	err = os.WriteFile(listManifest, []byte("./a.go\n\npkg/b.go\r\n"), 0o644)
+	assert.NoError(t, err)
+	files, err = readInputsManifest(listManifest)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.go", "pkg/b.go"}, files)
+}
+
+func TestDiffInputs(t *testing.T) {
+	undeclared, missing := diffInputs([]string{"a.go", "c.go", "b.go"}, []string{"a.go", "b.go", "d.go"})
+	assert.Equal(t, []string{"d.go"}, undeclared)
+	assert.Equal(t, []string{"c.go"}, missing)
+
+	undeclared, missing = diffInputs([]string{"a.go"}, []string{"a.go"})
+	assert.Empty(t, undeclared)
+	assert.Empty(t, missing)
+}
+
+func TestProcessInputsManifestMismatch(t *testing.T) {
+	projectDir := t.TempDir()
+	resultsDir := filepath.Join(projectDir, ".qodana", "results")
+	assert.NoError(t, os.MkdirAll(resultsDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "a.go"), []byte("package a"), 0o644))
+	declared := filepath.Join(t.TempDir(), "inputs.txt")
+	assert.NoError(t, os.WriteFile(declared, []byte("b.go\n"), 0o644))
+	c := corescan.ContextBuilder{
+		ProjectDir:     projectDir,
+		RepositoryRoot: projectDir,
+		ResultsDir:     resultsDir,
+		LogDir:         t.TempDir(),
+		InputsManifest: declared,
+	}.Build()
+
+	err := processInputsManifest(c)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not declared:\n  a.go")
+		assert.Contains(t, err.Error(), "declared but not in scope:\n  b.go")
+	}
+	assert.FileExists(t, filepath.Join(resultsDir, InputsManifestFileName))
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/docker/docker/api/types/container"
+	log "github.com/sirupsen/logrus"
+)
+
+// imageIdeDir is where the linter images have the IDE installed.
+const imageIdeDir = "/opt/idea"
+
+// ImageInspections returns the inspections of the IDE in the linter image. The image is pulled if needed, and the
+// IDE directory is copied from a container created (not started) from it.
+func ImageInspections(ctx context.Context, linter product.Linter, image string) (catalog inspections.Catalog, err error) {
+	docker, err := qdcontainer.NewContainerClient(ctx)
+	if err != nil {
+		return inspections.Catalog{}, fmt.Errorf("couldn't connect to the container engine: %w", err)
+	}
+	if _, err = docker.ImageInspect(ctx, image); err != nil {
+		if err = PullImage(ctx, docker, image); err != nil {
+			return inspections.Catalog{}, err
+		}
+	}
+
+	created, err := docker.ContainerCreate(
+		ctx,
+		&container.Config{Image: image, Entrypoint: []string{"true"}},
+		nil,
+		nil,
+		nil,
+		"",
+	)
+	if err != nil {
+		return inspections.Catalog{}, fmt.Errorf("couldn't create a container from %s: %w", image, err)
+	}
+	defer func() {
+		removeErr := docker.ContainerRemove(context.Background(), created.ID, container.RemoveOptions{Force: true})
+		if removeErr != nil {
+			log.Warnf("Failed to remove the container %s: %s", created.ID, removeErr)
+		}
+	}()
+
+	content, _, err := docker.CopyFromContainer(ctx, created.ID, imageIdeDir)
+	if err != nil {
+		return inspections.Catalog{}, fmt.Errorf("couldn't copy %s from %s: %w", imageIdeDir, image, err)
+	}
+	defer func() { err = errors.Join(err, content.Close()) }()
+	return inspections.CatalogFromIdeTar(linter, content, image)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/ideintegration"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/pterm/pterm"
+)
+
+// Indexes of getScanStages entries reached while following the analyzer output.
+const (
+	stageOpening     = 2
+	stageConfiguring = 3
+	stageAnalyzing   = 4
+	stageReporting   = 5
+)
+
+// stageMarkers are the phase markers printed by the analyzer when the corresponding stage starts.
+var stageMarkers = []struct {
+	marker string
+	stage  int
+}{
+	{"Starting up", stageOpening},
+	{"The Project opening stage completed in", stageConfiguring},
+	{"The Project configuration stage completed in", stageAnalyzing},
+	{"Detailed summary", stageReporting},
+}
+
+// scanProgress is the analysis progress parsed from the analyzer output, Stage is an index of getScanStages.
+// Percent is the share of the stages passed before Stage, the analyzer doesn't report the progress within a stage.
+type scanProgress struct {
+	Stage     int    `json:"stage"`
+	StageName string `json:"stageName"`
+	Percent   int    `json:"percent"`
+}
+
+// progressTracker turns analyzer output lines into progress updates of the spinner, JSON log, IDE and scan state.
+type progressTracker struct {
+	progress   *pterm.SpinnerPrinter
+	scanStages []string
+	state      *ScanState
+	current    scanProgress
+	// printJson prints the progress records with --log-format json, nil for the text output.
+	printJson func(kind string, value any)
+}
+
+func newProgressTracker(progress *pterm.SpinnerPrinter, scanStages []string, state *ScanState) *progressTracker {
+	t := &progressTracker{
+		progress:   progress,
+		scanStages: scanStages,
+		state:      state,
+		current:    scanProgress{Stage: 1},
+	}
+	if msg.IsJsonLog() {
+		t.printJson = msg.PrintJsonLog
+	}
+	if state != nil && state.Stage > 0 {
+		t.current.Stage = state.Stage
+	}
+	return t
+}
+
+// handleLine updates the progress if the line contains a phase marker.
+func (t *progressTracker) handleLine(line string) {
+	next, ok := parseProgressLine(line, t.current)
+	if !ok {
+		return
+	}
+	t.current = next
+	t.report()
+	updateScanStage(t.state, next.Stage)
+}
+
+func (t *progressTracker) report() {
+	stage := min(max(t.current.Stage, 0), len(t.scanStages)-1)
+	p := stageProgress(stage)
+	ideintegration.Progress(p)
+	if t.printJson != nil {
+		t.printJson("progress", p)
+		return
+	}
+	msg.UpdateText(t.progress, t.scanStages[stage])
+}
+
+// stageProgress returns the progress at the start of the stage.
+func stageProgress(stage int) scanProgress {
+	names := scanStageNames()
+	return scanProgress{Stage: stage, StageName: names[stage], Percent: stage * 100 / len(names)}
+}
+
+// parseProgressLine returns the progress after the given line, ok is false if the line carries no new stage.
+func parseProgressLine(line string, current scanProgress) (scanProgress, bool) {
+	for _, m := range stageMarkers {
+		if strings.Contains(line, m.marker) {
+			if m.stage <= current.Stage {
+				return current, false
+			}
+			return scanProgress{Stage: m.stage}, true
+		}
+	}
+	return current, false
+}
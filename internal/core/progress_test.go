@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	start := scanProgress{Stage: 1}
+	for _, tc := range []struct {
+		name     string
+		line     string
+		current  scanProgress
+		expected scanProgress
+		ok       bool
+	}{
+		{
+			name:     "regular log line",
+			line:     "2024-01-01 12:00:00,000 [INFO] Loading plugins",
+			current:  start,
+			expected: start,
+		},
+		{
+			name:     "phase marker",
+			line:     "The Project opening stage completed in 1 sec",
+			current:  start,
+			expected: scanProgress{Stage: stageConfiguring},
+			ok:       true,
+		},
+		{
+			name:     "phase marker of an already reached stage",
+			line:     "Starting up IntelliJ IDEA",
+			current:  scanProgress{Stage: stageAnalyzing},
+			expected: scanProgress{Stage: stageAnalyzing},
+		},
+		{
+			name:     "report stage",
+			line:     "Detailed summary",
+			current:  scanProgress{Stage: stageAnalyzing},
+			expected: scanProgress{Stage: stageReporting},
+			ok:       true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := parseProgressLine(tc.line, tc.current)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestProgressTrackerPersistsStage(t *testing.T) {
+	resultsDir := t.TempDir()
+	state := ScanState{ContainerId: "abc123", ResultsDir: resultsDir, Stage: 1}
+	if err := WriteScanState(resultsDir, state); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := newProgressTracker(nil, getScanStages(), &state)
+	tracker.handleLine("The Project configuration stage completed in 3 sec")
+
+	read, err := ReadScanState(resultsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, stageAnalyzing, read.Stage)
+}
+
+func TestProgressTrackerPrintsJsonRecords(t *testing.T) {
+	type record struct {
+		kind  string
+		value any
+	}
+	var printed []record
+	tracker := newProgressTracker(nil, getScanStages(), nil)
+	tracker.printJson = func(kind string, value any) {
+		printed = append(printed, record{kind, value})
+	}
+
+	tracker.handleLine("2024-01-01 12:00:00,000 [INFO] Loading plugins")
+	tracker.handleLine("The Project configuration stage completed in 3 sec")
+	tracker.handleLine("Detailed summary")
+
+	assert.Equal(t, []record{
+		{"progress", scanProgress{Stage: stageAnalyzing, StageName: "Analyzing the project", Percent: 66}},
+		{"progress", scanProgress{Stage: stageReporting, StageName: "Preparing the report", Percent: 83}},
+	}, printed)
+}
+
+func TestStageProgressPercent(t *testing.T) {
+	assert.Equal(t, 0, stageProgress(0).Percent)
+	assert.Equal(t, 50, stageProgress(stageConfiguring).Percent)
+	assert.Equal(t, "Configuring the project", stageProgress(stageConfiguring).StageName)
+}
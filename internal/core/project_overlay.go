@@ -0,0 +1,233 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/pmezard/go-difflib/difflib"
+	log "github.com/sirupsen/logrus"
+)
+
+// projectOverlayDirName is the directory of the cache with the writable layers of the read-only project.
+const projectOverlayDirName = "project-overlay"
+
+// overlaySkippedDirs are the directories of the project written by the linter itself, not by the quick-fixes.
+var overlaySkippedDirs = []string{".git", ".idea", ".qodana"}
+
+// projectOverlayDir returns the directory with the upper and the work directories of the project overlay.
+func projectOverlayDir(c corescan.Context) string {
+	return filepath.Join(c.CacheDir(), projectOverlayDirName)
+}
+
+// projectOverlayMount returns the mount of the repository as the lower layer of an overlay volume created by the
+// local driver of the daemon: the container sees the project as usual, but its writes go to the upper directory of
+// overlayDir and the repository is never changed.
+func projectOverlayMount(repositoryRoot string, overlayDir string) (mount.Mount, error) {
+	upper, work := filepath.Join(overlayDir, "upper"), filepath.Join(overlayDir, "work")
+	for _, dir := range []string{repositoryRoot, upper, work} {
+		if strings.ContainsAny(dir, ",:") {
+			return mount.Mount{}, fmt.Errorf("the path %s can't be used in an overlay, it contains ',' or ':'", dir)
+		}
+	}
+	return mount.Mount{
+		Type:   mount.TypeVolume,
+		Target: qdcontainer.MountDir,
+		VolumeOptions: &mount.VolumeOptions{
+			DriverConfig: &mount.Driver{
+				Name: "local",
+				Options: map[string]string{
+					"type":   "overlay",
+					"device": "overlay",
+					"o":      fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", repositoryRoot, upper, work),
+				},
+			},
+		},
+	}, nil
+}
+
+// startReadOnlyProject prepares the overlay of the read-only project and returns the function to call after the
+// analysis: it saves the changes made by the quick-fixes in the overlay as a diff, the project itself is left
+// unchanged.
+func startReadOnlyProject(c corescan.Context) (func() error, error) {
+	if !c.Analyser().IsContainer() {
+		msg.WarningMessage("--read-only-project is supported only for container runs, the project is analyzed in place")
+		return nil, nil
+	}
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("--read-only-project is supported only with the Docker daemon running on the same Linux host")
+	}
+	if c.FixesBranch() != "" {
+		return nil, errors.New("--fixes-branch can't be used with --read-only-project, the quick-fixes are saved to a patch")
+	}
+	overlayDir := projectOverlayDir(c)
+	if err := os.RemoveAll(overlayDir); err != nil {
+		log.Warnf("Failed to clean up the overlay of the previous run %s: %s", overlayDir, err)
+	}
+	for _, dir := range []string{"upper", "work"} {
+		if err := os.MkdirAll(filepath.Join(overlayDir, dir), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create the overlay of the read-only project: %w", err)
+		}
+	}
+	return func() error {
+		defer func() {
+			if err := os.RemoveAll(overlayDir); err != nil {
+				log.Debugf("Failed to remove the overlay of the read-only project %s: %s", overlayDir, err)
+			}
+		}()
+		if c.FixesDiff() == "" && !fixesRequested(c) {
+			return nil
+		}
+		diff, err := overlayDiff(c.RepositoryRoot(), filepath.Join(overlayDir, "upper"), c.ProjectDir())
+		if err != nil {
+			return fmt.Errorf("failed to collect the changes made by the quick-fixes: %w", err)
+		}
+		target := c.FixesDiff()
+		if target == "" {
+			target = filepath.Join(c.ResultsDir(), FixesPatchName)
+		}
+		return writeFixesDiff(target, diff)
+	}, nil
+}
+
+// overlayDiff returns the unified diff of the files of projectDir changed in the upper directory of the overlay,
+// relative to projectDir. The deleted files are the whiteouts of the overlay: character devices.
+func overlayDiff(repositoryRoot string, upper string, projectDir string) (string, error) {
+	projectPath, err := filepath.Rel(repositoryRoot, projectDir)
+	if err != nil {
+		return "", err
+	}
+	var diff strings.Builder
+	err = filepath.WalkDir(
+		filepath.Join(upper, projectPath), func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			name, err := filepath.Rel(filepath.Join(upper, projectPath), path)
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if slices.Contains(overlaySkippedDirs, name) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && info.Mode()&fs.ModeCharDevice == 0 {
+				return nil
+			}
+			before, existed, err := readOverlayLayer(filepath.Join(projectDir, name))
+			if err != nil {
+				return err
+			}
+			deleted := !info.Mode().IsRegular()
+			after := ""
+			if !deleted {
+				if after, _, err = readOverlayLayer(path); err != nil {
+					return err
+				}
+			}
+			if deleted && !existed {
+				return nil
+			}
+			if isBinaryContent(before) || isBinaryContent(after) {
+				log.Warnf("Skipping the binary file %s changed by the fixes", name)
+				return nil
+			}
+			fileDiff, err := unifiedFileDiff(filepath.ToSlash(name), before, after, !existed, deleted)
+			if err != nil {
+				return err
+			}
+			diff.WriteString(fileDiff)
+			return nil
+		},
+	)
+	return diff.String(), err
+}
+
+// readOverlayLayer returns the content of the file and whether it exists.
+func readOverlayLayer(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	return string(data), err == nil, err
+}
+
+// unifiedFileDiff returns the diff of the file in the format of git apply: the created and the deleted files are
+// compared with /dev/null.
+func unifiedFileDiff(name string, before string, after string, created bool, deleted bool) (string, error) {
+	if before == after && !created && !deleted {
+		return "", nil
+	}
+	fromFile, toFile := "a/"+name, "b/"+name
+	if created {
+		fromFile = "/dev/null"
+	}
+	if deleted {
+		toFile = "/dev/null"
+	}
+	var a, b []string
+	if before != "" {
+		a = splitLines(before)
+	}
+	if after != "" {
+		b = splitLines(after)
+	}
+	return difflib.GetUnifiedDiffString(
+		difflib.UnifiedDiff{A: a, B: b, FromFile: fromFile, ToFile: toFile, Context: 3},
+	)
+}
+
+// noNewlineMarker follows the last line of the file without a line break in the unified diff.
+const noNewlineMarker = "\\ No newline at end of file\n"
+
+// splitLines splits the text to the lines keeping their line breaks, unlike difflib.SplitLines it doesn't add an empty
+// line after the last line break. The last line without a line break gets the marker, so it differs from the same line
+// with a line break.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n" + noNewlineMarker
+	return lines
+}
+
+// isBinaryContent reports whether the content is binary the way git detects it: a NUL byte in the first 8000 bytes.
+func isBinaryContent(content string) bool {
+	return strings.IndexByte(content[:min(len(content), 8000)], 0) >= 0
+}
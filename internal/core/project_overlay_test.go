@@ -0,0 +1,143 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestOverlayDiff(t *testing.T) {
+	repository, upper := t.TempDir(), t.TempDir()
+	writeTestFiles(
+		t, repository, map[string]string{
+			"project/Main.java":      "class Main {\n    int a;;\n}\n",
+			"project/Unchanged.java": "class Unchanged {}\n",
+			"other/Other.java":       "class Other {}\n",
+		},
+	)
+	writeTestFiles(
+		t, upper, map[string]string{
+			"project/Main.java":           "class Main {\n    int a;\n}\n",
+			"project/Unchanged.java":      "class Unchanged {}\n",
+			"project/New.java":            "class New {}",
+			"project/.idea/workspace.xml": "<project/>\n",
+			"other/Other.java":            "class Changed {}\n",
+		},
+	)
+
+	diff, err := overlayDiff(repository, upper, filepath.Join(repository, "project"))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`--- a/Main.java
++++ b/Main.java
+@@ -1,3 +1,3 @@
+ class Main {
+-    int a;;
++    int a;
+ }
+--- /dev/null
++++ b/New.java
+@@ -0,0 +1 @@
++class New {}
+\ No newline at end of file
+`,
+		diff,
+	)
+
+	diff, err = overlayDiff(repository, filepath.Join(upper, "missing"), filepath.Join(repository, "project"))
+	require.NoError(t, err)
+	assert.Equal(t, "", diff)
+}
+
+func TestOverlayDiffAppliesWithGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repository, upper := t.TempDir(), t.TempDir()
+	project := filepath.Join(repository, "project")
+	writeTestFiles(
+		t, project, map[string]string{
+			"NoNewline.java":    "class NoNewline {\n    int a;;\n}",
+			"AddsNewline.java":  "class AddsNewline {}",
+			"DropsNewline.java": "class DropsNewline {}\n",
+			"Unchanged.java":    "class Unchanged {}",
+			"image.png":         "\x89PNG\x00\x01",
+			"src/Nested.java":   "class Nested {\n}\n",
+		},
+	)
+	fixed := map[string]string{
+		"NoNewline.java":    "class NoNewline {\n    int a;\n}",
+		"AddsNewline.java":  "class AddsNewline {}\n",
+		"DropsNewline.java": "class DropsNewline {}",
+		"Unchanged.java":    "class Unchanged {}",
+		"New.java":          "class New {}",
+		"src/Nested.java":   "class Nested {\n    int b;\n}\n",
+	}
+	writeTestFiles(t, filepath.Join(upper, "project"), fixed)
+	writeTestFiles(t, filepath.Join(upper, "project"), map[string]string{"image.png": "\x89PNG\x00\x02"})
+
+	diff, err := overlayDiff(repository, upper, project)
+	require.NoError(t, err)
+	assert.NotContains(t, diff, "image.png")
+
+	patch := filepath.Join(t.TempDir(), "fixes.patch")
+	require.NoError(t, os.WriteFile(patch, []byte(diff), 0o644))
+	out, err := exec.Command("git", "-C", project, "apply", patch).CombinedOutput()
+	require.NoError(t, err, string(out))
+	for name, content := range fixed {
+		data, err := os.ReadFile(filepath.Join(project, name))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data), name)
+	}
+}
+
+func TestProjectOverlayMount(t *testing.T) {
+	m, err := projectOverlayMount("/home/user/project", "/home/user/.cache/project-overlay")
+	require.NoError(t, err)
+	assert.Equal(t, mount.TypeVolume, m.Type)
+	assert.Equal(t, qdcontainer.MountDir, m.Target)
+	assert.Equal(
+		t,
+		"lowerdir=/home/user/project,upperdir=/home/user/.cache/project-overlay/upper,workdir=/home/user/.cache/project-overlay/work",
+		m.VolumeOptions.DriverConfig.Options["o"],
+	)
+	assert.Equal(
+		t,
+		`type=volume,dst=/data/project,volume-driver=local,volume-opt=device=overlay,"volume-opt=o=lowerdir=/home/user/project,upperdir=/home/user/.cache/project-overlay/upper,workdir=/home/user/.cache/project-overlay/work",volume-opt=type=overlay`,
+		volumeDriverMount(m),
+	)
+
+	_, err = projectOverlayMount("/home/user/my,project", "/tmp/overlay")
+	assert.Error(t, err)
+}
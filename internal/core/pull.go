@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+)
+
+// PullTargets are the linters and the other images qodana pull prefetches.
+type PullTargets struct {
+	Linters []product.Linter
+	// Images are the images given explicitly, e.g. with an exact version tag or from a mirror.
+	Images []string
+}
+
+// ResolvePullTargets returns the linters and images to pull: the arguments are linter names (qodana-jvm) or images
+// (jetbrains/qodana-jvm:2024.3), allFree adds all free linters, projectDir adds the linters recommended for the
+// languages of the project when it's not empty.
+func ResolvePullTargets(args []string, allFree bool, projectDir string) (PullTargets, error) {
+	var targets PullTargets
+	addLinter := func(linter product.Linter) {
+		if !slices.Contains(targets.Linters, linter) {
+			targets.Linters = append(targets.Linters, linter)
+		}
+	}
+	for _, arg := range args {
+		if linter := product.FindLinterByName(arg); linter != product.UnknownLinter {
+			addLinter(linter)
+			continue
+		}
+		if !strings.ContainsAny(arg, "/:") {
+			return PullTargets{}, fmt.Errorf("unknown linter %s, expected one of %s or an image", arg, strings.Join(product.AllNames, ", "))
+		}
+		if !slices.Contains(targets.Images, arg) {
+			targets.Images = append(targets.Images, arg)
+		}
+	}
+	if allFree {
+		for _, linter := range product.AllSupportedFreeLinters {
+			addLinter(linter)
+		}
+	}
+	if projectDir != "" {
+		languages, err := commoncontext.DetectProjectLanguages(projectDir)
+		if err != nil {
+			return PullTargets{}, fmt.Errorf("failed to detect the languages of %s: %w", projectDir, err)
+		}
+		for _, recommended := range languages.Linters {
+			addLinter(product.FindLinterByName(recommended.Name))
+		}
+	}
+	return targets, nil
+}
+
+// AllImages returns the images of the linters and the explicit images.
+func (t PullTargets) AllImages() []string {
+	images := make([]string, 0, len(t.Linters)+len(t.Images))
+	for _, linter := range t.Linters {
+		images = append(images, linter.Image())
+	}
+	return append(images, t.Images...)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePullTargets(t *testing.T) {
+	targets, err := ResolvePullTargets(
+		[]string{product.JvmLinter.Name, "jetbrains/qodana-go:2024.3", product.JvmLinter.Name},
+		false,
+		"",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []product.Linter{product.JvmLinter}, targets.Linters)
+	assert.Equal(t, []string{product.JvmLinter.Image(), "jetbrains/qodana-go:2024.3"}, targets.AllImages())
+
+	_, err = ResolvePullTargets([]string{"qodana-cobol"}, false, "")
+	assert.ErrorContains(t, err, "unknown linter qodana-cobol")
+
+	targets, err = ResolvePullTargets(nil, true, "")
+	assert.NoError(t, err)
+	assert.Equal(t, product.AllSupportedFreeLinters, targets.Linters)
+}
+
+func TestResolvePullTargets_ForProject(t *testing.T) {
+	projectDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(projectDir, ".idea"), 0o755))
+	iml := `<module type="PYTHON_MODULE" version="4" />`
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, ".idea", "project.iml"), []byte(iml), 0o600))
+
+	targets, err := ResolvePullTargets(nil, false, projectDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []product.Linter{product.PythonLinter, product.PythonCommunityLinter}, targets.Linters)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/core/startup"
+	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/effectiveconfig"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+)
+
+// PrepareScan prepares the host for the analysis and loads qodana.yaml, the returned cleanup removes the effective
+// configuration once the analysis is finished. A native run applies the effective configuration here, a container
+// run applies qodana.yaml in the container and only the options deciding the exit code are loaded.
+func PrepareScan(cliOptions platformcmd.CliOptions, commonCtx commoncontext.Context) (corescan.Context, func(), error) {
+	cleanup := func() {}
+	preparedHost, err := startup.PrepareHost(commonCtx)
+	if err != nil {
+		return corescan.Context{}, cleanup, err
+	}
+
+	effectiveConfigFiles := effectiveconfig.Files{}
+	qodanaYamlConfig := corescan.QodanaYamlConfig{}
+	if !commonCtx.Analyzer.IsContainer() {
+		localQodanaYamlFullPath := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(
+			commonCtx.ProjectDir,
+			cliOptions.ConfigName,
+		)
+
+		effectiveConfigDir, removeEffectiveConfigDir, err := fs.CreateTempDir("qd-effective-config")
+		if err != nil {
+			return corescan.Context{}, cleanup, fmt.Errorf("failed to create effective config directory: %w", err)
+		}
+		cleanup = removeEffectiveConfigDir
+
+		effectiveConfigFiles, err = effectiveconfig.CreateEffectiveConfigFiles(
+			commonCtx.CacheDir,
+			localQodanaYamlFullPath,
+			cliOptions.GlobalConfigurationsDir,
+			cliOptions.GlobalConfigurationId,
+			effectiveConfigDir,
+			commonCtx.LogDir(),
+			product.QodanaYamlNames(commonCtx.Analyzer)...,
+		)
+		if err != nil {
+			return corescan.Context{}, cleanup, fmt.Errorf("failed to load Qodana configuration %w", err)
+		}
+		if err = excludeIgnoredProblems(effectiveConfigFiles); err != nil {
+			return corescan.Context{}, cleanup, err
+		}
+		if effectiveConfigFiles.EffectiveQodanaYamlPath != "" {
+			yaml := qdyaml.LoadQodanaYamlForLinter(
+				effectiveConfigFiles.EffectiveQodanaYamlPath,
+				product.QodanaYamlNames(commonCtx.Analyzer)...,
+			)
+			qodanaYamlConfig = corescan.YamlConfig(yaml)
+		}
+	} else {
+		// qodana.yaml is applied in the container, the exit code of the quality gates is decided here
+		yaml := qdyaml.LoadQodanaYamlForLinter(
+			qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(commonCtx.ProjectDir, cliOptions.ConfigName),
+			product.QodanaYamlNames(commonCtx.Analyzer)...,
+		)
+		qodanaYamlConfig.EnforceAfter = yaml.EnforceAfter
+		qodanaYamlConfig.SbomExclude = yaml.DependencySbomExclude
+		qodanaYamlConfig.Mounts = yaml.Mounts
+		qodanaYamlConfig.RemoteCache = yaml.RemoteCache
+		qodanaYamlConfig.FailThreshold = yaml.FailThreshold
+		qodanaYamlConfig.FailureConditions = yaml.FailureConditions
+	}
+	scanContext := corescan.CreateContext(
+		cliOptions,
+		commonCtx,
+		preparedHost,
+		qodanaYamlConfig,
+		effectiveConfigFiles.ConfigDir,
+	)
+	return scanContext, cleanup, nil
+}
+
+// excludeIgnoredProblems passes the ignore rules of qodana.yaml to the linter as exclude entries of the effective
+// configuration, so the ignored problems are neither printed nor uploaded to Qodana Cloud.
+func excludeIgnoredProblems(files effectiveconfig.Files) error {
+	if files.EffectiveQodanaYamlPath == "" {
+		return nil
+	}
+	if err := qdyaml.ExcludeIgnoredInFile(files.EffectiveQodanaYamlPath); err != nil {
+		return fmt.Errorf("failed to apply the ignore rules to %s: %w", files.EffectiveQodanaYamlPath, err)
+	}
+	return nil
+}
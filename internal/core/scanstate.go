@@ -0,0 +1,143 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+)
+
+// scanStateFileName is the name of the file in the results directory that keeps track of a running container analysis.
+const scanStateFileName = "scan-state.json"
+
+// scanStateMu guards the scan state files, the stage written by the log follower must not bring back the state removed
+// once the analysis is finished.
+var scanStateMu sync.Mutex
+
+// ScanState is the persisted state of a container analysis, used by `qodana attach` to pick up a scan
+// after the CLI process that started it has died.
+type ScanState struct {
+	ContainerId               string    `json:"containerId"`
+	ContainerName             string    `json:"containerName"`
+	Image                     string    `json:"image"`
+	Stage                     int       `json:"stage"`
+	StartTime                 time.Time `json:"startTime"`
+	AnalysisId                string    `json:"analysisId"`
+	ResultsDir                string    `json:"resultsDir"`
+	ReportDir                 string    `json:"reportDir"`
+	CacheDir                  string    `json:"cacheDir"`
+	PrintProblems             bool      `json:"printProblems"`
+	GenerateCodeClimateReport bool      `json:"generateCodeClimateReport"`
+	SendBitBucketInsights     bool      `json:"sendBitBucketInsights"`
+	Ci                        string    `json:"ci,omitempty"`
+	Publish                   []string  `json:"publish,omitempty"`
+	Webhooks                  []string  `json:"webhooks,omitempty"`
+	QodanaSystemDir           string    `json:"qodanaSystemDir,omitempty"`
+	// Notifications are the notifications of qodana.yaml, sent by qodana attach when the analysis finishes.
+	Notifications *qdyaml.Notifications `json:"notifications,omitempty"`
+}
+
+// newScanState creates the state of a freshly started container analysis.
+func newScanState(c corescan.Context, containerId string, containerName string, image string) ScanState {
+	return ScanState{
+		ContainerId:               containerId,
+		ContainerName:             containerName,
+		Image:                     image,
+		StartTime:                 time.Now(),
+		AnalysisId:                c.AnalysisId(),
+		ResultsDir:                c.ResultsDir(),
+		ReportDir:                 c.ReportDir(),
+		CacheDir:                  c.CacheDir(),
+		PrintProblems:             c.PrintProblems(),
+		GenerateCodeClimateReport: c.GenerateCodeClimateReport(),
+		SendBitBucketInsights:     c.SendBitBucketInsights(),
+		Ci:                        c.Ci(),
+		Publish:                   c.Publish(),
+		Webhooks:                  c.Webhooks(),
+		QodanaSystemDir:           c.QodanaSystemDir(),
+		Notifications:             c.QodanaYamlConfig().Notifications,
+	}
+}
+
+// scanStatePath returns the path to the scan state file in the given results directory.
+func scanStatePath(resultsDir string) string {
+	return filepath.Join(resultsDir, scanStateFileName)
+}
+
+// WriteScanState persists the scan state to the results directory, the file is replaced atomically for the readers.
+func WriteScanState(resultsDir string, state ScanState) error {
+	scanStateMu.Lock()
+	defer scanStateMu.Unlock()
+	return writeScanState(resultsDir, state)
+}
+
+func writeScanState(resultsDir string, state ScanState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan state: %w", err)
+	}
+	return fs.WriteFileAtomic(scanStatePath(resultsDir), data, 0o644)
+}
+
+// ReadScanState reads the scan state from the results directory, returns os.ErrNotExist if no scan is recorded.
+func ReadScanState(resultsDir string) (ScanState, error) {
+	var state ScanState
+	data, err := os.ReadFile(scanStatePath(resultsDir))
+	if err != nil {
+		return state, err
+	}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse scan state %s: %w", scanStatePath(resultsDir), err)
+	}
+	return state, nil
+}
+
+// RemoveScanState removes the scan state once the analysis is finished and post-processed.
+func RemoveScanState(resultsDir string) {
+	scanStateMu.Lock()
+	defer scanStateMu.Unlock()
+	if err := os.Remove(scanStatePath(resultsDir)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("Could not remove scan state from %s: %s", resultsDir, err)
+	}
+}
+
+// updateScanStage persists the current stage of the analysis unless the state is already removed, failures are not
+// fatal for the analysis.
+func updateScanStage(state *ScanState, stage int) {
+	if state == nil || state.Stage == stage {
+		return
+	}
+	state.Stage = stage
+	scanStateMu.Lock()
+	defer scanStateMu.Unlock()
+	if _, err := os.Stat(scanStatePath(state.ResultsDir)); err != nil {
+		return // the analysis is already finished
+	}
+	if err := writeScanState(state.ResultsDir, *state); err != nil {
+		log.Warnf("Could not persist scan state: %s", err)
+	}
+}
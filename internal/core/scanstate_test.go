@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanStateRoundTrip(t *testing.T) {
+	resultsDir := t.TempDir()
+	state := ScanState{
+		ContainerId:   "abc123",
+		ContainerName: "qodana-cli-test",
+		Image:         "jetbrains/qodana-jvm:latest",
+		Stage:         1,
+		StartTime:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		AnalysisId:    "analysis",
+		ResultsDir:    resultsDir,
+		PrintProblems: true,
+	}
+	require.NoError(t, WriteScanState(resultsDir, state))
+
+	read, err := ReadScanState(resultsDir)
+	require.NoError(t, err)
+	assert.Equal(t, state, read)
+
+	RemoveScanState(resultsDir)
+	_, err = ReadScanState(resultsDir)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestUpdateScanStage(t *testing.T) {
+	resultsDir := t.TempDir()
+	state := ScanState{ContainerId: "abc123", ResultsDir: resultsDir, Stage: 1}
+	require.NoError(t, WriteScanState(resultsDir, state))
+
+	updateScanStage(&state, 3)
+	read, err := ReadScanState(resultsDir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, read.Stage)
+
+	// the state must not be recreated after the analysis is finished
+	RemoveScanState(resultsDir)
+	updateScanStage(&state, 4)
+	_, err = ReadScanState(resultsDir)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	updateScanStage(nil, 5)
+}
+
+func TestUpdateScanStageConcurrentRemove(t *testing.T) {
+	resultsDir := t.TempDir()
+	state := ScanState{ContainerId: "abc123", ResultsDir: resultsDir, Stage: 1}
+	require.NoError(t, WriteScanState(resultsDir, state))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for stage := 2; stage < 200; stage++ {
+			updateScanStage(&state, stage)
+		}
+	}()
+	RemoveScanState(resultsDir)
+	<-done
+
+	_, err := ReadScanState(resultsDir)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+	entries, err := os.ReadDir(resultsDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no temporary state files must be left")
+}
+
+func TestAttachLogsOptions(t *testing.T) {
+	options := attachLogsOptions()
+	assert.Equal(t, "0", options.Tail)
+	assert.True(t, options.Follow)
+	assert.Empty(t, containerLogsOptions.Tail)
+}
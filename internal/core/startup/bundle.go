@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package startup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	log "github.com/sirupsen/logrus"
+)
+
+// bundledIdesFileName lists the IDE distributions installed by qodana bundle load in the Qodana system directory,
+// the offline native runs use them instead of downloading the IDE.
+const bundledIdesFileName = "bundled-ides.json"
+
+// RegisterBundledIdes records the IDE directories of the linters, relative to the system directory.
+func RegisterBundledIdes(systemDir string, ides map[string]string) error {
+	registered := readBundledIdes(systemDir)
+	for linter, ideDir := range ides {
+		registered[linter] = filepath.FromSlash(ideDir)
+	}
+	data, err := json.MarshalIndent(registered, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(systemDir, bundledIdesFileName), data, 0o644)
+}
+
+// findBundledIde returns the IDE directory of the linter installed from a bundle, empty if there is none.
+func findBundledIde(systemDir string, linter product.Linter) string {
+	ideDir, ok := readBundledIdes(systemDir)[linter.Name]
+	if !ok {
+		return ""
+	}
+	ideDir = filepath.Join(systemDir, ideDir)
+	if _, err := os.Stat(ideDir); err != nil {
+		log.Debugf("The bundled IDE of %s is not available: %s", linter.Name, err)
+		return ""
+	}
+	return ideDir
+}
+
+func readBundledIdes(systemDir string) map[string]string {
+	ides := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(systemDir, bundledIdesFileName))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Debugf("Failed to read the bundled IDEs: %s", err)
+		}
+		return ides
+	}
+	if err = json.Unmarshal(data, &ides); err != nil {
+		log.Debugf("Failed to read the bundled IDEs: %s", err)
+	}
+	return ides
+}
+
+// DownloadCustomPlugins downloads the custom plugins of the IDE on macOS, where they are kept outside the IDE
+// distribution, and returns their directory. It's empty on other systems, the plugins come with the distribution.
+func DownloadCustomPlugins(analyzer product.Analyzer, ideDir string) (string, error) {
+	if runtime.GOOS != "darwin" {
+		return "", nil
+	}
+	info := getIde(analyzer)
+	if info == nil {
+		return "", fmt.Errorf("no IDE distribution of %s found", analyzer.GetLinter().Name)
+	}
+	pluginsDir := filepath.Dir(product.GuessProduct(ideDir, analyzer).CustomPluginsPath())
+	if err := downloadCustomPlugins(info.Link, pluginsDir, nil); err != nil {
+		return "", err
+	}
+	return pluginsDir, nil
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package startup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterBundledIdes(t *testing.T) {
+	systemDir := t.TempDir()
+	assert.Empty(t, findBundledIde(systemDir, product.JvmLinter))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(systemDir, "ideaIC-2024.3"), 0o755))
+	require.NoError(t, RegisterBundledIdes(systemDir, map[string]string{product.JvmLinter.Name: "ideaIC-2024.3"}))
+	require.NoError(t, RegisterBundledIdes(systemDir, map[string]string{product.PythonLinter.Name: "pycharm-2024.3"}))
+	assert.Equal(t, filepath.Join(systemDir, "ideaIC-2024.3"), findBundledIde(systemDir, product.JvmLinter))
+	// the registered IDE that was removed is not used
+	assert.Empty(t, findBundledIde(systemDir, product.PythonLinter))
+}
+
+func TestPrepareHostOfflineWithoutBundledIde(t *testing.T) {
+	t.Setenv(qdenv.QodanaOffline, "true")
+	commonCtx := commoncontext.Context{
+		Analyzer:        &product.NativeAnalyzer{Linter: product.JvmLinter},
+		ProjectDir:      t.TempDir(),
+		CacheDir:        t.TempDir(),
+		ResultsDir:      t.TempDir(),
+		ReportDir:       t.TempDir(),
+		QodanaSystemDir: t.TempDir(),
+	}
+
+	_, err := PrepareHost(commonCtx)
+	assert.ErrorContains(t, err, "can't be downloaded in the offline mode")
+}
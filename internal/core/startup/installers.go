@@ -17,10 +17,7 @@
 package startup
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"io"
 	"math/rand"
 	"os"
 	"os/exec"
@@ -86,9 +83,12 @@ func downloadAndInstallIDE(
 		}
 	}(downloadedIdePath)
 
-	if checkSumUrl != "" {
-		checksumFilePath := filepath.Join(baseDir, strings.TrimSuffix(fileName, fileExt)+".sha256")
-		verifySha256(checksumFilePath, checkSumUrl, downloadedIdePath)
+	verification := utils.Verification{ChecksumUrl: checkSumUrl, ChecksumRequired: true, Auth: getInternalAuth()}
+	if checkSumUrl == "" {
+		verification = utils.Verification{ChecksumUrl: ideUrl + ".sha256", Auth: getInternalAuth()}
+	}
+	if err = utils.VerifyDownload(downloadedIdePath, verification); err != nil {
+		log.Fatalf("Error while verifying the downloaded IDE: %v", err)
 	}
 
 	switch fileExt {
@@ -165,43 +165,14 @@ func getIde(analyzer product.Analyzer) *ReleaseDownloadInfo {
 		return nil
 	}
 
-	var downloadType string
-	switch runtime.GOOS {
-	case "darwin":
-		downloadType = "macSit"
-		_, ok := (*release.Downloads)[downloadType]
-		if !ok {
-			downloadType = "mac"
-		}
-		if runtime.GOARCH == "arm64" {
-			downloadType = "macSitM1"
-			_, ok := (*release.Downloads)[downloadType]
-			if !ok {
-				downloadType = "macM1"
-			}
-		}
-	case "windows":
-		downloadType = "windowsZip"
-		_, ok := (*release.Downloads)[downloadType]
-		if !ok {
-			downloadType = "windows"
-		}
-		if runtime.GOARCH == "arm64" {
-			downloadType = "windowsZipARM64"
-			_, ok := (*release.Downloads)[downloadType]
-			if !ok {
-				downloadType = "windowsARM64"
-			}
-		}
-	default:
-		downloadType = "linux"
-		if runtime.GOARCH == "arm64" {
-			downloadType = "linuxARM64"
-		}
-	}
-
+	arch := hostArch()
+	downloadType := selectDownloadType(runtime.GOOS, arch, *release.Downloads)
 	res, ok := (*release.Downloads)[downloadType]
 	if !ok {
+		if arch == "arm64" {
+			reportNoArm64Build(analyzer.GetLinter(), linterProperties.PresentableName, *release.Version, dist)
+			return nil
+		}
 		msg.ErrorMessage(
 			"%s %s (%s) is not available or not supported for the current platform",
 			qodanaLinterName,
@@ -215,6 +186,64 @@ func getIde(analyzer product.Analyzer) *ReleaseDownloadInfo {
 	return &res
 }
 
+// selectDownloadType returns the feed download type of the IDE distribution for the platform, archives are preferred
+// over installers. There is no fallback from ARM64 to x86 distributions, the caller reports it as unsupported.
+func selectDownloadType(goos string, arch string, downloads map[string]ReleaseDownloadInfo) string {
+	preferred := func(archive string, installer string) string {
+		if _, ok := downloads[archive]; ok {
+			return archive
+		}
+		return installer
+	}
+	switch goos {
+	case "darwin":
+		if arch == "arm64" {
+			return preferred("macSitM1", "macM1")
+		}
+		return preferred("macSit", "mac")
+	case "windows":
+		if arch == "arm64" {
+			return preferred("windowsZipARM64", "windowsARM64")
+		}
+		return preferred("windowsZip", "windows")
+	default:
+		if arch == "arm64" {
+			return "linuxARM64"
+		}
+		return "linux"
+	}
+}
+
+// reportNoArm64Build explains that the product is not published for arm64 yet, downloading the x86_64 build instead
+// would fail to start, so the analysis can only run in a container.
+func reportNoArm64Build(linter product.Linter, presentableName string, version string, dist string) {
+	msg.ErrorMessage(
+		"%s %s (%s) has no native %s/arm64 build, the x86_64 one can't run on this machine",
+		presentableName,
+		version,
+		dist,
+		runtime.GOOS,
+	)
+	if linter.DockerImage != "" {
+		msg.ErrorMessage("Run the analysis in a container instead: qodana scan --image %s", linter.Image())
+	}
+}
+
+// hostArch returns the architecture of the machine. An amd64 CLI running under Rosetta 2 on Apple Silicon reports
+// arm64, so the native aarch64 IDE is downloaded instead of the x86 one running under translation.
+//
+//goland:noinspection GoBoolExpressions
+func hostArch() string {
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "amd64" {
+		out, err := exec.Command("sysctl", "-n", "sysctl.proc_translated").Output()
+		if err == nil && strings.TrimSpace(string(out)) == "1" {
+			log.Debug("Running under Rosetta 2, using the arm64 IDE distribution")
+			return "arm64"
+		}
+	}
+	return runtime.GOARCH
+}
+
 // installIdeWindowsExe is used as a fallback, since it needs installation privileges and alters the registry
 func installIdeWindowsExe(archivePath string, targetDir string) error {
 	stdout, stderr, _, err := fexec.ExecRedirectOutput(
@@ -324,53 +353,6 @@ func installIdeMacOS(archivePath string, targetDir string) error {
 	return nil
 }
 
-func verifySha256(checksumFile string, checkSumUrl string, filePath string) {
-	err := utils.DownloadFile(checksumFile, checkSumUrl, getInternalAuth(), nil)
-	if err != nil {
-		log.Fatalf("Error while downloading checksum for IDE: %v", err)
-	}
-
-	defer func(filePath string) {
-		err = os.Remove(filePath)
-		if err != nil {
-			log.Warning("Error while removing temporary file: " + err.Error())
-		}
-	}(checksumFile)
-
-	checksum, err := os.ReadFile(checksumFile)
-	if err != nil {
-		log.Fatalf("Error occurred during reading checksum file: %v", err)
-	}
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.Fatalf("Error while opening IDE archive: %v", err)
-	}
-
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Fatalf("Error while closing IDE archive: %v", err)
-		}
-	}(file)
-
-	h := sha256.New()
-	if _, err := io.Copy(h, file); err != nil {
-		log.Fatalf("Error while computing checksum of IDE archive: %v", err)
-	}
-
-	actual := hex.EncodeToString(h.Sum(nil))
-	expected := strings.SplitN(string(checksum), " ", 2)[0]
-	if actual != expected {
-		err = os.Remove(filePath)
-		if err != nil {
-			log.Warning("Error while removing temporary file: " + err.Error())
-		}
-		log.Fatalf("Checksums doesn't match. Expected: %s, Actual: %s", expected, actual)
-	}
-	log.Info("Checksum of downloaded IDE was verified")
-}
-
 func downloadCustomPlugins(ideUrl string, targetDir string, spinner *pterm.SpinnerPrinter) error {
 	pluginsUrl := getPluginsURL(ideUrl)
 	log.Debugf("Downloading custom plugins from %s to %s", pluginsUrl, targetDir)
@@ -384,6 +366,10 @@ func downloadCustomPlugins(ideUrl string, targetDir string, spinner *pterm.Spinn
 	if err != nil {
 		return fmt.Errorf("error while downloading plugins: %v", err)
 	}
+	err = utils.VerifyDownload(archivePath, utils.Verification{ChecksumUrl: pluginsUrl + ".sha256", Auth: getInternalAuth()})
+	if err != nil {
+		return fmt.Errorf("error while verifying plugins: %v", err)
+	}
 
 	_, err = fexec.Exec(".", "tar", "-xf", archivePath, "-C", targetDir)
 	if err != nil {
@@ -59,6 +59,29 @@ func TestGetIde(t *testing.T) {
 	}
 }
 
+func TestSelectDownloadType(t *testing.T) {
+	macDownloads := map[string]ReleaseDownloadInfo{
+		"mac":      {Link: "https://download.jetbrains.com/go/goland-2025.1.dmg"},
+		"macSit":   {Link: "https://download.jetbrains.com/go/goland-2025.1.sit"},
+		"macM1":    {Link: "https://download.jetbrains.com/go/goland-2025.1-aarch64.dmg"},
+		"macSitM1": {Link: "https://download.jetbrains.com/go/goland-2025.1-aarch64.sit"},
+	}
+	assert.Equal(t, "macSitM1", selectDownloadType("darwin", "arm64", macDownloads))
+	assert.Equal(t, "macSit", selectDownloadType("darwin", "amd64", macDownloads))
+
+	delete(macDownloads, "macSitM1")
+	assert.Equal(t, "macM1", selectDownloadType("darwin", "arm64", macDownloads))
+
+	windowsDownloads := map[string]ReleaseDownloadInfo{"windows": {}, "windowsZipARM64": {}}
+	assert.Equal(t, "windows", selectDownloadType("windows", "amd64", windowsDownloads))
+	assert.Equal(t, "windowsZipARM64", selectDownloadType("windows", "arm64", windowsDownloads))
+	assert.Equal(t, "linuxARM64", selectDownloadType("linux", "arm64", nil))
+	// the x86_64 archive fails to start on aarch64 runners, it is never selected instead
+	linuxDownloads := map[string]ReleaseDownloadInfo{"linux": {Link: "https://download.jetbrains.com/go/goland-2025.1.tar.gz"}}
+	assert.Equal(t, "linuxARM64", selectDownloadType("linux", "arm64", linuxDownloads))
+	assert.Equal(t, "linux", selectDownloadType("linux", "amd64", linuxDownloads))
+}
+
 func TestDownloadAndInstallIDE(t *testing.T) {
 	product.RequireNightlyAuth(t)
 	linters := []product.Linter{product.GoLinter}
@@ -66,6 +66,15 @@ func SetupLicenseAndProjectHash(prod product.Product, endpoints *cloud.QdApiEndp
 	}
 
 	// usual builds should have token and LicenseData for execution
+	if qdenv.IsOffline() {
+		log.Fatalf(
+			"The %s linter needs a license obtained from Qodana Cloud, which is not available in the offline mode. "+
+				"Set %s to run it offline or use one of the community linters: %s",
+			prod.GetProductNameFromCode(),
+			qdenv.QodanaLicense,
+			allCommunityNames(),
+		)
+	}
 	if token == "" {
 		log.Fatalf(cloud.EmptyTokenMessage, endpoints.RootEndpoint.Url)
 	}
@@ -52,7 +52,7 @@ type PreparedHost struct {
 }
 
 // PrepareHost gets the current user, creates the necessary folders for the analysis.
-func PrepareHost(commonCtx commoncontext.Context) PreparedHost {
+func PrepareHost(commonCtx commoncontext.Context) (PreparedHost, error) {
 	prod := product.Product{}
 	cloudUploadToken := commonCtx.QodanaToken
 	ideDir := ""
@@ -79,17 +79,18 @@ func PrepareHost(commonCtx commoncontext.Context) PreparedHost {
 
 	if commonCtx.Analyzer.DownloadDist() {
 		linter := commonCtx.Analyzer.GetLinter()
-		msg.PrintProcess(
-			func(spinner *pterm.SpinnerPrinter) {
-				if spinner != nil {
-					spinner.ShowTimer = false // We will update interactive spinner
-				}
-				ideDir = downloadAndInstallIDE(commonCtx.Analyzer, commonCtx.QodanaSystemDir, spinner)
-				fixWindowsPlugins(ideDir)
-			},
-			fmt.Sprintf("Downloading %s", linter.Name),
-			fmt.Sprintf("downloading IDE distribution to %s", commonCtx.QodanaSystemDir),
-		)
+		if qdenv.IsOffline() {
+			if ideDir = findBundledIde(commonCtx.QodanaSystemDir, linter); ideDir == "" {
+				return PreparedHost{}, fmt.Errorf(
+					"%s can't be downloaded in the offline mode, pass the path to a downloaded IDE with --ide, "+
+						"load a bundle with the IDE with 'qodana bundle load' "+
+						"or run the analysis in a container with the linter image available locally",
+					linter.Name,
+				)
+			}
+		} else {
+			ideDir = DownloadIde(commonCtx.Analyzer, commonCtx.QodanaSystemDir)
+		}
 	}
 
 	if commonCtx.Analyzer.IsContainer() {
@@ -110,7 +111,25 @@ func PrepareHost(commonCtx commoncontext.Context) PreparedHost {
 		QodanaUploadToken: cloudUploadToken,
 		Prod:              prod,
 	}
-	return result
+	return result, nil
+}
+
+// DownloadIde downloads and installs the IDE distribution of the analyzer to the Qodana system directory, unless it's
+// already there, and returns the IDE directory.
+func DownloadIde(analyzer product.Analyzer, systemDir string) string {
+	ideDir := ""
+	msg.PrintProcess(
+		func(spinner *pterm.SpinnerPrinter) {
+			if spinner != nil {
+				spinner.ShowTimer = false // We will update interactive spinner
+			}
+			ideDir = downloadAndInstallIDE(analyzer, systemDir, spinner)
+			fixWindowsPlugins(ideDir)
+		},
+		fmt.Sprintf("Downloading %s", analyzer.GetLinter().Name),
+		fmt.Sprintf("downloading IDE distribution to %s", systemDir),
+	)
+	return ideDir
 }
 
 func prepareLocalIdeSettingsAndGetQodanaCloudUploadToken(
@@ -147,6 +166,13 @@ func SyncCache(commonCtx commoncontext.Context, prod product.Product) {
 }
 
 func prepareQodanaTokenForNative(token string) {
+	if qdenv.IsOffline() {
+		// the IDE reads the token from the environment and would upload the report
+		if err := os.Unsetenv(qdenv.QodanaToken); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	_, isSet := os.LookupEnv(qdenv.QodanaToken)
 	if !isSet {
 		err := os.Setenv(qdenv.QodanaToken, token)
@@ -157,7 +183,7 @@ func prepareQodanaTokenForNative(token string) {
 }
 
 func prepareCustomPlugins(prod product.Product) {
-	if runtime.GOOS == "darwin" && !prod.Analyzer.IsContainer() {
+	if runtime.GOOS == "darwin" && !prod.Analyzer.IsContainer() && !qdenv.IsOffline() {
 		if info := getIde(prod.Analyzer); info != nil {
 			err := downloadCustomPlugins(info.Link, filepath.Dir(prod.CustomPluginsPath()), nil)
 			if err != nil {
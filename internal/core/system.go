@@ -20,22 +20,31 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/JetBrains/qodana-cli/internal/cloud"
 	"github.com/JetBrains/qodana-cli/internal/core/corescan"
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
 	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
 	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/fingerprint"
 	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/metrics"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/nuget"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	cienvironment "github.com/cucumber/ci-environment/go"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
@@ -53,7 +62,8 @@ func CheckForUpdates(currentVersion string) {
 	if currentVersion == "dev" || strings.HasSuffix(
 		currentVersion,
 		"nightly",
-	) || qdenv.IsContainer() || cienvironment.DetectCIEnvironment() != nil || DisableCheckUpdates {
+	) || qdenv.IsContainer() || cienvironment.DetectCIEnvironment() != nil || DisableCheckUpdates ||
+		qdenv.IsOffline() {
 		return
 	}
 	latestVersion := getLatestVersion()
@@ -120,22 +130,48 @@ func IsHomeDirectory(path string) bool {
 	return fs.SameFile(path, home)
 }
 
-// RunAnalysis runs the linter with the given options.
-func RunAnalysis(ctx context.Context, c corescan.Context) int {
+// RunAnalysis runs the linter with the given options, it returns the exit code of the linter or the error that
+// prevented the analysis from finishing.
+func RunAnalysis(ctx context.Context, c corescan.Context) (exitCode int, err error) {
 	log.Debug("Running analysis with options")
 	platform.LogContext(&c)
 
-	if !utils.IsInstalled("git") && (c.FullHistory() || c.Commit() != "" || c.DiffStart() != "" || c.DiffEnd() != "") {
-		log.Fatal("Cannot use git related functionality without a git executable")
+	if !utils.IsInstalled("git") && (c.FullHistory() || c.Staged() || c.Commit() != "" || c.DiffStart() != "" || c.DiffEnd() != "") {
+		return 1, errors.New("cannot use git related functionality without a git executable")
+	}
+
+	// finishFixes collects the changes made by the quick-fixes after the analysis
+	var finishFixes func() error
+	if c.ReadOnlyProject() {
+		finishFixes, err = startReadOnlyProject(c)
+	} else if c.FixesDiff() != "" {
+		finishFixes, err = startFixesDiff(c)
+	} else if fixesRequested(c) {
+		finishFixes, err = startFixesPatch(c)
+	} else if c.FixesBranch() != "" {
+		msg.WarningMessage("--fixes-branch is set, but no quick-fixes are applied: use --apply-fixes or --cleanup")
+	}
+	if err != nil {
+		return 1, err
+	}
+	if finishFixes != nil {
+		defer func() {
+			if fixesErr := finishFixes(); fixesErr != nil {
+				err = errors.Join(err, fixesErr)
+				exitCode = max(exitCode, 1)
+			}
+		}()
 	}
 
 	startHash, err := c.StartHash()
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
 
 	scenario := c.DetermineRunScenario(startHash != "")
-	if scenario != corescan.RunScenarioDefault && !git.RevisionExists(c.RepositoryRoot(), startHash, c.LogDir()) {
+	// the staged changes are compared with HEAD, there is no commit to check
+	if scenario != corescan.RunScenarioDefault && scenario != corescan.RunScenarioStaged &&
+		!git.RevisionExists(c.RepositoryRoot(), startHash, c.LogDir()) {
 		msg.WarningMessageCI(
 			"Cannot run analysis for commit %s because it doesn't exist in the repository. Check that you retrieve the full git history before running Qodana.",
 			startHash,
@@ -145,13 +181,31 @@ func RunAnalysis(ctx context.Context, c corescan.Context) int {
 		// backoff to regular analysis
 		c = c.BackoffToDefaultAnalysisBecauseOfMissingCommit()
 	}
+	if c.ReadOnlyProject() && scenario != corescan.RunScenarioDefault {
+		return 1, errors.New("--read-only-project is supported only for the analysis of the whole project")
+	}
+
+	// before bootstrap, the files it generates are not the inputs of the scan
+	if err := processInputsManifest(c); err != nil {
+		return 1, err
+	}
+
+	// every revision of the full history is compared with the baseline as it is
+	if c.Baseline() != "" && scenario != corescan.RunScenarioFullHistory {
+		c = c.WithBaselineRemappedForRenames(
+			platform.RemapBaselineRenames(c.Baseline(), c.ProjectDir(), c.CacheDir(), c.LogDir()),
+		)
+	}
 
 	if err := installPlugins(c); err != nil {
-		log.Fatalf("Failed to install plugins: %v", err)
+		return 1, fmt.Errorf("failed to install plugins: %w", err)
 	}
 	// this way of running needs to do bootstrap twice on different commits and will do it internally
 	if !corescan.IsScopedScenario(scenario) && !c.Analyser().IsContainer() {
-		utils.Bootstrap(c.QodanaYamlConfig().Bootstrap, c.ProjectDir())
+		utils.Bootstrap(c.QodanaYamlConfig().BootstrapSteps(), c.ProjectDir(), c.LogDir())
+	}
+	if len(c.ChainProfiles()) > 0 && scenario != corescan.RunScenarioDefault {
+		msg.WarningMessage("--chain-profile is supported only for the analysis of the whole project, the chained profiles are skipped")
 	}
 	switch scenario {
 	case corescan.RunScenarioFullHistory:
@@ -164,20 +218,20 @@ func RunAnalysis(ctx context.Context, c corescan.Context) int {
 	case corescan.RunScenarioReversedScoped:
 		analyzer := NewReverseScopedAnalyzer(ctx, c, startHash, c.DiffEnd(), defaultRunner)
 		return analyzer.RunAnalysis()
+	case corescan.RunScenarioStaged:
+		return runStagedChanges(ctx, c)
 	case corescan.RunScenarioDefault:
-		return runQodana(ctx, c)
+		return runQodanaWithChainedProfiles(ctx, c)
 	default:
-		log.Fatalf("Unknown run scenario %s", scenario)
-		panic("Unreachable")
+		return 1, fmt.Errorf("unknown run scenario %s", scenario)
 	}
 }
 
-func runLocalChanges(ctx context.Context, c corescan.Context, startHash string) int {
-	var exitCode int
+func runLocalChanges(ctx context.Context, c corescan.Context, startHash string) (int, error) {
 	gitReset := false
 	r, err := git.CurrentRevision(c.RepositoryRoot(), c.LogDir())
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
 	if c.DiffEnd() != "" && c.DiffEnd() != r {
 		msg.WarningMessage("Cannot run local-changes because --diff-end is %s and HEAD is %s", c.DiffEnd(), r)
@@ -191,30 +245,30 @@ func runLocalChanges(ctx context.Context, c corescan.Context, startHash string)
 		}
 	}
 
-	exitCode = runQodana(ctx, c)
+	exitCode, err := runQodana(ctx, c)
 
 	if gitReset {
 		_ = git.ResetBack(c.RepositoryRoot(), c.LogDir())
 	}
-	return exitCode
+	return exitCode, err
 }
 
-func runWithFullHistory(ctx context.Context, c corescan.Context, startHash string) int {
+func runWithFullHistory(ctx context.Context, c corescan.Context, startHash string) (int, error) {
 	remoteUrl, err := git.RemoteUrl(c.RepositoryRoot(), c.LogDir())
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
 	branch, err := git.Branch(c.RepositoryRoot(), c.LogDir())
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
 	if remoteUrl == "" && branch == "" {
-		log.Fatal("Please check that project is located within the Git repo. If you specified --repository-root option, check that it points to the right directory.")
+		return 1, errors.New("please check that project is located within the Git repo. If you specified --repository-root option, check that it points to the right directory")
 	}
 
 	err = git.Clean(c.RepositoryRoot(), c.LogDir())
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
 	revisions := git.Revisions(c.RepositoryRoot())
 	allCommits := len(revisions)
@@ -237,49 +291,95 @@ func runWithFullHistory(ctx context.Context, c corescan.Context, startHash strin
 		msg.WarningMessage("[%d/%d] Running analysis for revision %s", counter+1, allCommits, revision)
 		err = git.CheckoutAndUpdateSubmodule(c.RepositoryRoot(), revision, true, c.LogDir())
 		if err != nil {
-			log.Fatal(err)
+			break
 		}
 		msg.EmptyMessage()
 
 		contextForAnalysis := c.WithVcsEnvForFullHistoryAnalysisIteration(remoteUrl, branch, revision)
-		exitCode = runQodana(ctx, contextForAnalysis)
+		if exitCode, err = runQodana(ctx, contextForAnalysis); err != nil {
+			break
+		}
+	}
+	// the branch is checked out back even if the analysis of a revision failed
+	if checkoutErr := git.CheckoutAndUpdateSubmodule(c.RepositoryRoot(), branch, true, c.LogDir()); checkoutErr != nil {
+		err = errors.Join(err, checkoutErr)
 	}
-	err = git.CheckoutAndUpdateSubmodule(c.RepositoryRoot(), branch, true, c.LogDir())
 	if err != nil {
-		log.Fatal(err)
+		return 1, err
 	}
-	return exitCode
+	return exitCode, nil
 }
 
-func runQodana(ctx context.Context, c corescan.Context) int {
-	var exitCode int
-	var err error
+func runQodana(ctx context.Context, c corescan.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		// e.g. the next revision or the next profile after the interrupted one
+		return exitcodes.QodanaInterruptedExitCode, err
+	}
+	defer timings.Start(timings.Analysis)()
+	recordSystemCache(c.CacheDir())
 	if c.Analyser().IsContainer() {
-		exitCode = runQodanaContainer(ctx, c)
+		return runQodanaContainer(ctx, c)
+	}
+	nuget.UnsetNugetVariables() // TODO: get rid of it from 241 release
+	recordEnvironment(c, fingerprint.Host())
+	return runQodanaLocal(ctx, c)
+}
+
+// FinishInterruptedAnalysis keeps the partial results of the analysis interrupted by the cancellation of its context:
+// they are uploaded to Qodana Cloud with the logs if the token is set and the linter hasn't uploaded them itself.
+func FinishInterruptedAnalysis(c corescan.Context) {
+	timings.Finish(c.ResultsDir())
+	sarifPath := platform.GetSarifPath(c.ResultsDir())
+	if _, err := os.Stat(sarifPath); err != nil {
+		msg.WarningMessage("The analysis is interrupted before saving any results, the logs are in %s", c.LogDir())
+		return
+	}
+	msg.WarningMessage("The analysis is interrupted, the partial results are saved to %s", c.ResultsDir())
+	if c.QodanaUploadToken() == "" || cloud.GetReportUrl(c.ResultsDir()) != "" || qdenv.IsContainer() {
+		return
+	}
+	publisher := platform.Publisher{ResultsDir: c.ResultsDir(), LogDir: c.LogDir(), AnalysisId: c.AnalysisId()}
+	if res, err := platform.UploadReport(c.CacheDir(), publisher, c.QodanaUploadToken()); res != 0 || err != nil {
+		msg.ErrorMessage(
+			"Failed to upload the partial results to Qodana Cloud, upload them later with: qodana upload --results-dir %s",
+			c.ResultsDir(),
+		)
+	}
+}
+
+// recordSystemCache records whether the IDE system directory with the indexes is left by a previous run, the same
+// directory is used by the native runs and mounted to the container.
+func recordSystemCache(cacheDir string) {
+	entries, err := os.ReadDir(filepath.Join(cacheDir, "idea"))
+	if err == nil && len(entries) > 0 {
+		metrics.CacheHit(metrics.SystemCache)
 	} else {
-		nuget.UnsetNugetVariables() // TODO: get rid of it from 241 release
-		exitCode, err = runQodanaLocal(c)
-		if err != nil {
-			log.Fatal(err)
-		}
+		metrics.CacheMiss(metrics.SystemCache)
 	}
-	return exitCode
 }
 
-// followLinter follows the linter logs and prints the progress.
-func followLinter(client client.APIClient, containerName string, progress *pterm.SpinnerPrinter, scanStages []string) {
-	reader, err := client.ContainerLogs(context.Background(), containerName, containerLogsOptions)
+// followLinter follows the linter logs and prints the progress parsed from them.
+// Reached stages are persisted to state, so an attached CLI can restore the progress.
+func followLinter(
+	client client.APIClient,
+	containerName string,
+	logsOptions container.LogsOptions,
+	progress *pterm.SpinnerPrinter,
+	scanStages []string,
+	state *ScanState,
+) (err error) {
+	reader, err := client.ContainerLogs(context.Background(), containerName, logsOptions)
 	if err != nil {
-		log.Fatal(err.Error())
+		return fmt.Errorf("failed to follow the linter logs: %w", err)
 	}
 	defer func(reader io.ReadCloser) {
-		err := reader.Close()
-		if err != nil {
-			log.Fatal(err.Error())
+		if closeErr := reader.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
 		}
 	}(reader)
 	scanner := bufio.NewScanner(reader)
 	interactive := msg.IsInteractive()
+	tracker := newProgressTracker(progress, scanStages, state)
 	for scanner.Scan() {
 		line := scanner.Text()
 		if !interactive && len(line) >= dockerSpecialCharsLength {
@@ -288,20 +388,9 @@ func followLinter(client client.APIClient, containerName string, progress *pterm
 
 		line = strings.TrimSuffix(line, "\n")
 		if err == nil || len(line) > 0 {
-			if strings.Contains(line, "Starting up") {
-				msg.UpdateText(progress, scanStages[2])
-			}
-			if strings.Contains(line, "The Project opening stage completed in") {
-				msg.UpdateText(progress, scanStages[3])
-			}
-			if strings.Contains(line, "The Project configuration stage completed in") {
-				msg.UpdateText(progress, scanStages[4])
-			}
-			if strings.Contains(line, "Detailed summary") {
-				msg.UpdateText(progress, scanStages[5])
-				if !msg.IsInteractive() {
-					msg.EmptyMessage()
-				}
+			tracker.handleLine(line)
+			if strings.Contains(line, "Detailed summary") && !msg.IsInteractive() && !msg.IsJsonLog() {
+				msg.EmptyMessage()
 			}
 			msg.PrintLinterLog(line)
 		}
@@ -309,13 +398,14 @@ func followLinter(client client.APIClient, containerName string, progress *pterm
 			if err != io.EOF {
 				log.Errorf("Error scanning docker log stream: %s", err)
 			}
-			return
+			return nil
 		}
 	}
+	return nil
 }
 
-func getScanStages() []string {
-	scanStages := []string{
+func scanStageNames() []string {
+	return []string{
 		"Preparing Qodana Docker images",
 		"Starting the analysis engine",
 		"Opening the project",
@@ -323,6 +413,10 @@ func getScanStages() []string {
 		"Analyzing the project",
 		"Preparing the report",
 	}
+}
+
+func getScanStages() []string {
+	scanStages := scanStageNames()
 	for i, stage := range scanStages {
 		scanStages[i] = msg.PrimaryBold("[%d/%d] ", i+1, len(scanStages)+1) + msg.Primary(stage)
 	}
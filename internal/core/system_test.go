@@ -39,8 +39,9 @@ func NewMockAnalysisRunner(mockFunc func(hash string, c corescan.Context) (bool,
 	return &MockAnalysisRunner{MockFunc: mockFunc}
 }
 
-func (r *MockAnalysisRunner) RunFunc(hash string, _ context.Context, c corescan.Context) (bool, int) {
-	return r.MockFunc(hash, c)
+func (r *MockAnalysisRunner) RunFunc(hash string, _ context.Context, c corescan.Context) (bool, int, error) {
+	stop, code := r.MockFunc(hash, c)
+	return stop, code, nil
 }
 
 func TestScopedScript(t *testing.T) {
@@ -130,7 +131,8 @@ func TestScopedScript(t *testing.T) {
 						endHash:   "endHash",
 					},
 				}
-				exitCode := sequenceRunner.RunSequence("scope", runner)
+				exitCode, err := sequenceRunner.RunSequence("scope", runner)
+				assert.NoError(t, err)
 
 				expectedParams := tc.expectedParamsFunc(resultsDir)
 				assert.Equal(t, tc.expectedCalls, calls, "Expected %d calls", tc.expectedCalls)
@@ -414,7 +416,8 @@ func TestReverseScopedScript(t *testing.T) {
 						endHash:   "endHash",
 					},
 				}
-				exitCode := sequenceRunner.RunSequence("scope", runner)
+				exitCode, err := sequenceRunner.RunSequence("scope", runner)
+				assert.NoError(t, err)
 
 				expectedParams := tc.expectedParamsFunc(firstDir)
 				assert.Equal(t, tc.expectedCalls, calls, "Expected %d calls", tc.expectedCalls)
@@ -259,7 +259,10 @@ func CreateTarGz(sourceDir, archivePath, topDir string) (err error) {
 		err = errors.Join(err, f.Close())
 	}()
 
-	gw := gzip.NewWriter(f)
+	gw, err := NewParallelGzipWriter(f, gzip.DefaultCompression)
+	if err != nil {
+		return err
+	}
 	defer func() {
 		err = errors.Join(err, gw.Close())
 	}()
@@ -0,0 +1,126 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// parallelGzipBlockSize is the size of the input compressed by one worker. Larger blocks lose less of the compression
+// ratio on the dictionary reset between them.
+const parallelGzipBlockSize = 1 << 20
+
+// ParallelGzipWriter compresses the blocks of the input on all CPUs and writes them in order as the members of one
+// multi-member gzip stream, which is read by gzip.Reader, gunzip and tar like a single-member one.
+type ParallelGzipWriter struct {
+	w         io.Writer
+	level     int
+	blockSize int
+	block     []byte
+	written   bool
+	results   chan chan gzipBlock
+	done      chan error
+	closed    bool
+
+	mu  sync.Mutex
+	err error
+}
+
+type gzipBlock struct {
+	data []byte
+	err  error
+}
+
+// NewParallelGzipWriter returns a writer compressing to w at the level of compress/gzip.
+func NewParallelGzipWriter(w io.Writer, level int) (*ParallelGzipWriter, error) {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+	p := &ParallelGzipWriter{
+		w:         w,
+		level:     level,
+		blockSize: parallelGzipBlockSize,
+		results:   make(chan chan gzipBlock, runtime.GOMAXPROCS(0)),
+		done:      make(chan error, 1),
+	}
+	p.block = make([]byte, 0, p.blockSize)
+	go p.writeBlocks()
+	return p, nil
+}
+
+// writeBlocks writes the compressed blocks in the order of the input, the first error stops the writing.
+func (p *ParallelGzipWriter) writeBlocks() {
+	var err error
+	for result := range p.results {
+		block := <-result
+		if err == nil {
+			err = block.err
+		}
+		if err == nil {
+			_, err = p.w.Write(block.data)
+		}
+		if err != nil {
+			p.mu.Lock()
+			p.err = err
+			p.mu.Unlock()
+		}
+	}
+	p.done <- err
+}
+
+func (p *ParallelGzipWriter) failed() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *ParallelGzipWriter) Write(b []byte) (int, error) {
+	if err := p.failed(); err != nil {
+		return 0, err
+	}
+	n := len(b)
+	for len(b) > 0 {
+		chunk := min(len(b), p.blockSize-len(p.block))
+		p.block = append(p.block, b[:chunk]...)
+		b = b[chunk:]
+		if len(p.block) == p.blockSize {
+			p.flushBlock()
+		}
+	}
+	return n, nil
+}
+
+// flushBlock hands the buffered input to a worker, it waits while all workers are busy.
+func (p *ParallelGzipWriter) flushBlock() {
+	data := p.block
+	p.block = make([]byte, 0, p.blockSize)
+	p.written = true
+	result := make(chan gzipBlock, 1)
+	p.results <- result
+	go func() {
+		var compressed bytes.Buffer
+		writer, _ := gzip.NewWriterLevel(&compressed, p.level)
+		_, err := writer.Write(data)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+		result <- gzipBlock{data: compressed.Bytes(), err: err}
+	}()
+}
+
+// Close compresses the rest of the input and waits for all blocks to be written, it doesn't close the underlying
+// writer.
+func (p *ParallelGzipWriter) Close() error {
+	if p.closed {
+		return p.failed()
+	}
+	p.closed = true
+	if len(p.block) > 0 || !p.written {
+		// an empty input is written as an empty member, the output is always a valid gzip stream
+		p.flushBlock()
+	}
+	close(p.results)
+	return <-p.done
+}
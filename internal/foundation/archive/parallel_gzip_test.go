@@ -0,0 +1,62 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/archive"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelGzipWriter(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	large := make([]byte, 5<<20+123)
+	for i := range large {
+		// compressible, but not trivially
+		large[i] = byte('a' + random.Intn(8))
+	}
+
+	for name, input := range map[string][]byte{"empty": {}, "small": []byte("qodana"), "large": large} {
+		t.Run(
+			name, func(t *testing.T) {
+				var compressed bytes.Buffer
+				writer, err := archive.NewParallelGzipWriter(&compressed, gzip.BestSpeed)
+				require.NoError(t, err)
+				// uneven writes cross the block boundaries
+				for rest := input; len(rest) > 0; {
+					n := min(len(rest), 100_003)
+					_, err = writer.Write(rest[:n])
+					require.NoError(t, err)
+					rest = rest[n:]
+				}
+				require.NoError(t, writer.Close())
+
+				reader, err := gzip.NewReader(&compressed)
+				require.NoError(t, err)
+				decompressed, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				assert.Equal(t, input, decompressed)
+			},
+		)
+	}
+
+	_, err := archive.NewParallelGzipWriter(io.Discard, 42)
+	assert.Error(t, err)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestParallelGzipWriterError(t *testing.T) {
+	writer, err := archive.NewParallelGzipWriter(failingWriter{}, gzip.DefaultCompression)
+	require.NoError(t, err)
+	_, err = writer.Write(make([]byte, 3<<20))
+	require.NoError(t, err)
+	assert.EqualError(t, writer.Close(), "disk full")
+}
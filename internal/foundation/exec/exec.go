@@ -2,6 +2,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -38,10 +39,26 @@ func ExecWithTimeout(
 	arg0 string,
 	argv ...string,
 ) (int, error) {
-	return execWithEnv(cwd, nil, stdout, stderr, timeout, timeoutExitCode, arg0, argv...)
+	return execWithEnv(context.Background(), cwd, nil, stdout, stderr, timeout, timeoutExitCode, arg0, argv...)
+}
+
+// ExecWithContext is ExecWithTimeout also terminating the subprocess when ctx is cancelled, it returns the exit code
+// of the terminated subprocess.
+func ExecWithContext(
+	ctx context.Context,
+	cwd string,
+	stdout io.Writer,
+	stderr io.Writer,
+	timeout time.Duration,
+	timeoutExitCode int,
+	arg0 string,
+	argv ...string,
+) (int, error) {
+	return execWithEnv(ctx, cwd, nil, stdout, stderr, timeout, timeoutExitCode, arg0, argv...)
 }
 
 func execWithEnv(
+	ctx context.Context,
 	cwd string,
 	env []string,
 	stdout io.Writer,
@@ -72,7 +89,7 @@ func execWithEnv(
 		close(waitCh)
 	}()
 
-	return handleSignals(cmd, waitCh, timeout, timeoutExitCode)
+	return handleSignals(ctx, cmd, waitCh, timeout, timeoutExitCode)
 }
 
 // ExecRedirectOutput executes subprocess with forwarding of signals, returns stdout, stderr and exit code.
@@ -86,7 +103,7 @@ func ExecRedirectOutput(cwd string, arg0 string, argv ...string) (string, string
 // subprocess environment. Pass os.Environ() plus any extra variables.
 func ExecRedirectOutputWithEnv(cwd string, env []string, arg0 string, argv ...string) (string, string, int, error) {
 	var stdout, stderr bytes.Buffer
-	res, err := execWithEnv(cwd, env, &stdout, &stderr, time.Duration(math.MaxInt64), 1, arg0, argv...)
+	res, err := execWithEnv(context.Background(), cwd, env, &stdout, &stderr, time.Duration(math.MaxInt64), 1, arg0, argv...)
 	return stdout.String(), stderr.String(), res, err
 }
 
@@ -96,6 +113,20 @@ func RunShell(cwd string, command string) (int, error) {
 	return Exec(cwd, argv[0], argv[1:]...)
 }
 
+// RunShellWithTimeout executes a shell command with the output written to stdout and stderr, the command is terminated
+// after timeout and timeoutExitCode is returned.
+func RunShellWithTimeout(
+	cwd string,
+	stdout io.Writer,
+	stderr io.Writer,
+	timeout time.Duration,
+	timeoutExitCode int,
+	command string,
+) (int, error) {
+	argv := getSystemShellArgv(command)
+	return ExecWithTimeout(cwd, stdout, stderr, timeout, timeoutExitCode, argv[0], argv[1:]...)
+}
+
 // RunShellRedirectOutput executes a shell command and captures stdout/stderr.
 func RunShellRedirectOutput(cwd string, command string) (string, string, int, error) {
 	argv := getSystemShellArgv(command)
@@ -111,7 +142,13 @@ func getSystemShellArgv(command string) []string {
 }
 
 // handleSignals handles the signals from the subprocess
-func handleSignals(cmd *exec.Cmd, waitCh <-chan error, timeout time.Duration, timeoutExitCode int) (int, error) {
+func handleSignals(
+	ctx context.Context,
+	cmd *exec.Cmd,
+	waitCh <-chan error,
+	timeout time.Duration,
+	timeoutExitCode int,
+) (int, error) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer func() {
@@ -120,9 +157,16 @@ func handleSignals(cmd *exec.Cmd, waitCh <-chan error, timeout time.Duration, ti
 	}()
 
 	var timeoutCh = time.After(timeout)
+	done := ctx.Done()
 
 	for {
 		select {
+		case <-done:
+			// the subprocess is asked to stop once, its exit code is returned when it exits
+			done = nil
+			if err := RequestTermination(cmd.Process); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				log.Error("Error terminating process: ", err)
+			}
 		case <-sigChan:
 			if err := RequestTermination(cmd.Process); err != nil && !errors.Is(
 				err,
@@ -1,6 +1,8 @@
 package exec
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"os"
 	"testing"
@@ -42,6 +44,32 @@ func TestExecWithTimeout(t *testing.T) {
 	})
 }
 
+func TestExecWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	exitCode, err := ExecWithContext(ctx, ".", os.Stdout, os.Stderr, 5*time.Second, 99, "sleep", "5")
+	assert.NoError(t, err)
+	assert.NotEqual(t, 0, exitCode)
+	assert.NotEqual(t, 99, exitCode)
+	assert.Less(t, time.Since(start), 4*time.Second)
+}
+
+func TestRunShellWithTimeout(t *testing.T) {
+	t.Run("command finishes before timeout", func(t *testing.T) {
+		var stdout bytes.Buffer
+		exitCode, err := RunShellWithTimeout(".", &stdout, os.Stderr, 5*time.Second, 99, "echo test")
+		assert.NoError(t, err)
+		assert.Equal(t, 0, exitCode)
+		assert.Contains(t, stdout.String(), "test")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		exitCode, _ := RunShellWithTimeout(".", os.Stdout, os.Stderr, 100*time.Millisecond, 99, "sleep 5")
+		assert.Equal(t, 99, exitCode)
+	})
+}
+
 func TestExecRedirectOutput(t *testing.T) {
 	t.Run("capture stdout", func(t *testing.T) {
 		stdout, stderr, exitCode, err := ExecRedirectOutput(".", "echo", "test")
@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 )
 
 // CopyFile copies a file from src to dst using streaming (not loading entire file into memory).
@@ -30,8 +32,44 @@ func CopyFile(src, dst string) (err error) {
 	return err
 }
 
-// CopyDir copies a directory from src to dst.
+// CopyDir copies a directory from src to dst. The directories are created first, then the files are copied
+// by runtime.NumCPU() workers: the results of a large monorepo are thousands of files.
 func CopyDir(src string, dst string) error {
+	var files [][2]string
+	if err := copyDirTree(src, dst, &files); err != nil {
+		return err
+	}
+
+	jobs := make(chan [2]string)
+	errs := make(chan error, len(files))
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), max(len(files), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				if err := CopyFile(file[0], file[1]); err != nil {
+					errs <- fmt.Errorf("failed to copy %s: %w", file[0], err)
+				}
+			}
+		}()
+	}
+	for _, file := range files {
+		jobs <- file
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	var err error
+	for copyErr := range errs {
+		err = errors.Join(err, copyErr)
+	}
+	return err
+}
+
+// copyDirTree creates the directories of src in dst and collects the files to copy.
+func copyDirTree(src string, dst string, files *[][2]string) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return err
@@ -48,15 +86,11 @@ func CopyDir(src string, dst string) error {
 		srcPath := filepath.Join(src, item.Name())
 		dstPath := filepath.Join(dst, item.Name())
 		if item.IsDir() {
-			err = CopyDir(srcPath, dstPath)
-			if err != nil {
+			if err = copyDirTree(srcPath, dstPath, files); err != nil {
 				return err
 			}
 		} else {
-			err = CopyFile(srcPath, dstPath)
-			if err != nil {
-				return err
-			}
+			*files = append(*files, [2]string{srcPath, dstPath})
 		}
 	}
 	return nil
@@ -1,6 +1,7 @@
 package fs
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -49,6 +50,25 @@ func TestCopyDir(t *testing.T) {
 	assert.Equal(t, "content2", string(data))
 }
 
+func TestCopyDir_ManyFiles(t *testing.T) {
+	srcDir := t.TempDir()
+	for i := range 100 {
+		dir := filepath.Join(srcDir, fmt.Sprintf("dir%d", i%7))
+		_ = os.MkdirAll(dir, 0o755)
+		_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte(fmt.Sprint(i)), 0o644)
+	}
+	_ = os.Mkdir(filepath.Join(srcDir, "empty"), 0o755)
+
+	dstDir := filepath.Join(t.TempDir(), "copied")
+	assert.NoError(t, CopyDir(srcDir, dstDir))
+	for i := range 100 {
+		data, err := os.ReadFile(filepath.Join(dstDir, fmt.Sprintf("dir%d", i%7), fmt.Sprintf("file%d.txt", i)))
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(i), string(data))
+	}
+	assert.DirExists(t, filepath.Join(dstDir, "empty"))
+}
+
 func TestCopyDir_ReadDirError(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("permission-based test not reliable on Windows")
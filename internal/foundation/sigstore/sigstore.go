@@ -0,0 +1,63 @@
+// Package sigstore verifies the message signatures of the Sigstore bundles made with
+// `cosign sign-blob --key ... --bundle ...`. Only the signature made with a known key is checked, like
+// `cosign verify-blob --key ... --insecure-ignore-tlog` does: the keyless certificates and the transparency log
+// entries of the bundle are not verified.
+package sigstore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Sha256 is the message digest algorithm of the bundles.
+const Sha256 = "SHA2_256"
+
+// bundle is the part of a Sigstore bundle (https://github.com/sigstore/protobuf-specs) with a message signature.
+type bundle struct {
+	MediaType        string `json:"mediaType"`
+	MessageSignature *struct {
+		MessageDigest struct {
+			Algorithm string `json:"algorithm"`
+			Digest    []byte `json:"digest"`
+		} `json:"messageDigest"`
+		Signature []byte `json:"signature"`
+	} `json:"messageSignature"`
+}
+
+// VerifyMessageSignature checks that the bundle contains a signature of the content with the SHA-256 digest made
+// with the PEM ECDSA public key. The digest is passed instead of the content, so large files are not read into memory.
+func VerifyMessageSignature(digest [32]byte, bundleData []byte, publicKey []byte) error {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return errors.New("failed to decode the public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse the public key: %w", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	var b bundle
+	if err = json.Unmarshal(bundleData, &b); err != nil {
+		return fmt.Errorf("failed to parse the Sigstore bundle: %w", err)
+	}
+	if b.MessageSignature == nil {
+		return errors.New("the Sigstore bundle contains no message signature")
+	}
+	messageDigest := b.MessageSignature.MessageDigest
+	if messageDigest.Algorithm != Sha256 || !bytes.Equal(messageDigest.Digest, digest[:]) {
+		return errors.New("the Sigstore bundle is made for different content")
+	}
+	if !ecdsa.VerifyASN1(ecdsaKey, digest[:], b.MessageSignature.Signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
@@ -18,14 +18,24 @@ package platform
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
 	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
 	"github.com/JetBrains/qodana-cli/internal/tooling"
+	log "github.com/sirupsen/logrus"
 )
 
+// remappedBaselineDir is the directory in the cache the baseline with the renames applied is written to.
+const remappedBaselineDir = "baseline"
+
 // computeBaselinePrintResults runs SARIF analysis (compares with baseline and prints the result)=
 func computeBaselinePrintResults(c thirdpartyscan.Context, thresholds map[string]string) (int, error) {
+	defer timings.Start(timings.Baseline)()
 	sarifPath := GetSarifPath(c.ResultsDir())
 	args := []string{
 		tooling.GetQodanaJBRPath(c.CacheDir()),
@@ -40,7 +50,7 @@ func computeBaselinePrintResults(c thirdpartyscan.Context, thresholds map[string
 	severities := thresholdsToArgs(thresholds)
 	args = append(args, severities...)
 	if c.Baseline() != "" {
-		args = append(args, "-b", c.Baseline())
+		args = append(args, "-b", RemapBaselineRenames(c.Baseline(), c.ProjectDir(), c.CacheDir(), c.LogDir()))
 	}
 	if c.BaselineIncludeAbsent() {
 		args = append(args, "-i")
@@ -57,3 +67,98 @@ func computeBaselinePrintResults(c thirdpartyscan.Context, thresholds map[string
 	}
 	return ret, nil
 }
+
+// RemapBaselineRenames returns the baseline to compare the results with. When files have been moved or renamed since
+// the baseline revision, their results in the baseline are relocated to the current paths, and a copy written to the
+// cache directory is returned, otherwise such results would be reported as new and absent ones.
+// The baseline is returned as is when it has nothing to remap or git can't tell the renames.
+func RemapBaselineRenames(baseline string, projectDir string, cacheDir string, logDir string) string {
+	path := baseline
+	if _, err := os.Stat(path); err != nil && !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, baseline)
+	}
+	report, err := ReadReport(path)
+	if err != nil {
+		log.Debugf("Skipping the renames in baseline %s: %s", baseline, err)
+		return baseline
+	}
+	revision := baselineRevision(report)
+	if revision == "" || !utils.IsInstalled("git") || !git.RevisionExists(projectDir, revision, logDir) {
+		log.Debugf("Skipping the renames in baseline %s: its revision %q is not available", baseline, revision)
+		return baseline
+	}
+	renames, err := git.Renames(projectDir, revision, logDir)
+	if err != nil {
+		log.Warnf("Failed to detect the files renamed since the baseline revision %s: %s", revision, err)
+		return baseline
+	}
+	remapped := remapResultLocations(report, renames)
+	if remapped == 0 {
+		return baseline
+	}
+
+	dir := filepath.Join(cacheDir, remappedBaselineDir)
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		log.Warnf("Failed to create %s: %s", dir, err)
+		return baseline
+	}
+	remappedPath := filepath.Join(dir, filepath.Base(path))
+	if err = WriteReport(remappedPath, report); err != nil {
+		log.Warnf("Failed to write the remapped baseline: %s", err)
+		return baseline
+	}
+	log.Infof("Relocated %d baseline results in the files renamed since %s", remapped, revision)
+	return remappedPath
+}
+
+// baselineRevision returns the revision the baseline report was made for.
+func baselineRevision(report *sarif.Report) string {
+	for _, run := range report.Runs {
+		for _, vcs := range run.VersionControlProvenance {
+			if vcs.RevisionId != "" {
+				return vcs.RevisionId
+			}
+		}
+	}
+	return ""
+}
+
+// remapResultLocations moves the locations of the results in the renamed files to the new paths, it returns
+// the number of the relocated results.
+func remapResultLocations(report *sarif.Report, renames map[string]string) int {
+	if len(renames) == 0 {
+		return 0
+	}
+	remap := func(location *sarif.ArtifactLocation) bool {
+		if location == nil {
+			return false
+		}
+		newPath, ok := renames[location.Uri]
+		if ok {
+			location.Uri = newPath
+		}
+		return ok
+	}
+	remapped := 0
+	for i := range report.Runs {
+		run := &report.Runs[i]
+		for j := range run.Artifacts {
+			remap(run.Artifacts[j].Location)
+		}
+		for j := range run.Results {
+			result := &run.Results[j]
+			relocated := false
+			for _, locations := range [][]sarif.Location{result.Locations, result.RelatedLocations} {
+				for k := range locations {
+					if locations[k].PhysicalLocation != nil && remap(locations[k].PhysicalLocation.ArtifactLocation) {
+						relocated = true
+					}
+				}
+			}
+			if relocated {
+				remapped++
+			}
+		}
+	}
+	return remapped
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	"github.com/stretchr/testify/assert"
+)
+
+func baselineResult(ruleId string, uris ...string) sarif.Result {
+	result := sarif.Result{RuleId: ruleId}
+	for _, uri := range uris {
+		result.Locations = append(
+			result.Locations,
+			sarif.Location{PhysicalLocation: &sarif.PhysicalLocation{ArtifactLocation: &sarif.ArtifactLocation{Uri: uri}}},
+		)
+	}
+	return result
+}
+
+func TestRemapResultLocations(t *testing.T) {
+	report := &sarif.Report{
+		Runs: []sarif.Run{
+			{
+				Results: []sarif.Result{
+					baselineResult("Unused", "src/Main.java"),
+					baselineResult("Duplicates", "src/Util.java", "src/Main.java"),
+					baselineResult("Typo", "README.md"),
+					baselineResult("Sanity"),
+				},
+			},
+		},
+	}
+
+	remapped := remapResultLocations(report, map[string]string{"src/Main.java": "app/Main.java"})
+	assert.Equal(t, 2, remapped)
+	results := report.Runs[0].Results
+	assert.Equal(t, "app/Main.java", results[0].Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+	assert.Equal(t, "src/Util.java", results[1].Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+	assert.Equal(t, "app/Main.java", results[1].Locations[1].PhysicalLocation.ArtifactLocation.Uri)
+	assert.Equal(t, "README.md", results[2].Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+}
+
+func TestRemapBaselineRenames(t *testing.T) {
+	repo := git.NewGitRepo(t)
+	assert.NoError(t, os.MkdirAll(filepath.Join(repo.Dir(), "src"), 0755))
+	repo.WriteFile("src/Main.java", "class Main {\n    void main() {\n    }\n}\n")
+	revision := repo.CommitAll("initial")
+	repo.Run("mv", "src/Main.java", "Main.java")
+	repo.CommitAll("move")
+
+	baseline := &sarif.Report{
+		Runs: []sarif.Run{
+			{
+				VersionControlProvenance: []sarif.VersionControlDetails{{RevisionId: revision}},
+				Results:                  []sarif.Result{baselineResult("Unused", "src/Main.java")},
+			},
+		},
+	}
+	baselinePath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	assert.NoError(t, WriteReport(baselinePath, baseline))
+	cacheDir, logDir := t.TempDir(), t.TempDir()
+
+	remappedPath := RemapBaselineRenames(baselinePath, repo.Dir(), cacheDir, logDir)
+	assert.Equal(t, filepath.Join(cacheDir, remappedBaselineDir, "qodana.sarif.json"), remappedPath)
+	remapped, err := ReadReport(remappedPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "Main.java", remapped.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation.Uri)
+
+	// nothing is renamed since the revision
+	baseline.Runs[0].VersionControlProvenance[0].RevisionId = repo.RevParse("HEAD")
+	assert.NoError(t, WriteReport(baselinePath, baseline))
+	assert.Equal(t, baselinePath, RemapBaselineRenames(baselinePath, repo.Dir(), cacheDir, logDir))
+
+	// the revision is unknown
+	baseline.Runs[0].VersionControlProvenance = nil
+	assert.NoError(t, WriteReport(baselinePath, baseline))
+	assert.Equal(t, baselinePath, RemapBaselineRenames(baselinePath, repo.Dir(), cacheDir, logDir))
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ci
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The CI systems detected by Detect.
+const (
+	GitHubActions  = "github-actions"
+	GitLab         = "gitlab"
+	Jenkins        = "jenkins"
+	CircleCI       = "circleci"
+	TeamCity       = "teamcity"
+	AzurePipelines = "azure-pipelines"
+)
+
+// The reporters accepted by --ci: the formats the new problems are reported in to be shown by the CI system.
+const (
+	ReporterNone     = "none"
+	ReporterGitHub   = "github"
+	ReporterAzure    = "azure"
+	ReporterTeamCity = "teamcity"
+	ReporterJenkins  = "jenkins"
+)
+
+// Reporters are the values accepted by --ci.
+var Reporters = []string{ReporterGitHub, ReporterAzure, ReporterTeamCity, ReporterJenkins, ReporterNone}
+
+// Environment is the CI system of the run, the revision it builds and the build.
+type Environment struct {
+	// System is one of the detected CI systems, empty outside of them
+	System string
+	// Branch is the branch being built, the source branch for pull and merge requests
+	Branch string
+	// Revision is the commit being built
+	Revision string
+	// BuildUrl is the page of the build in the CI system
+	BuildUrl string
+}
+
+// Detect returns the CI system of the run from the environment variables it sets.
+func Detect() Environment {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return Environment{
+			System:   GitHubActions,
+			Branch:   firstNonEmpty(os.Getenv("GITHUB_HEAD_REF"), os.Getenv("GITHUB_REF_NAME")),
+			Revision: os.Getenv("GITHUB_SHA"),
+			BuildUrl: gitHubRunUrl(),
+		}
+	case os.Getenv("GITLAB_CI") == "true":
+		return Environment{
+			System: GitLab,
+			Branch: firstNonEmpty(
+				os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"),
+				os.Getenv("CI_COMMIT_BRANCH"),
+				os.Getenv("CI_COMMIT_REF_NAME"),
+			),
+			Revision: os.Getenv("CI_COMMIT_SHA"),
+			BuildUrl: os.Getenv("CI_JOB_URL"),
+		}
+	case os.Getenv("TF_BUILD") != "":
+		return Environment{
+			System: AzurePipelines,
+			Branch: strings.TrimPrefix(
+				firstNonEmpty(os.Getenv("SYSTEM_PULLREQUEST_SOURCEBRANCH"), os.Getenv("BUILD_SOURCEBRANCH")),
+				"refs/heads/",
+			),
+			Revision: os.Getenv("BUILD_SOURCEVERSION"),
+		}
+	case os.Getenv("CIRCLECI") == "true":
+		return Environment{
+			System:   CircleCI,
+			Branch:   os.Getenv("CIRCLE_BRANCH"),
+			Revision: os.Getenv("CIRCLE_SHA1"),
+			BuildUrl: os.Getenv("CIRCLE_BUILD_URL"),
+		}
+	case os.Getenv("TEAMCITY_VERSION") != "":
+		// the branch is a build parameter, not passed to the environment by default
+		return Environment{System: TeamCity, Revision: os.Getenv("BUILD_VCS_NUMBER")}
+	case os.Getenv("JENKINS_URL") != "":
+		return Environment{
+			System: Jenkins,
+			Branch: strings.TrimPrefix(
+				firstNonEmpty(os.Getenv("CHANGE_BRANCH"), os.Getenv("BRANCH_NAME"), os.Getenv("GIT_BRANCH")),
+				"origin/",
+			),
+			Revision: os.Getenv("GIT_COMMIT"),
+			BuildUrl: os.Getenv("BUILD_URL"),
+		}
+	}
+	return Environment{}
+}
+
+// Reporter returns the reporter showing the problems inline in the CI system, ReporterNone if it has no such mechanism.
+// GitLab shows the Code Climate report instead, enabled by --code-climate.
+func (e Environment) Reporter() string {
+	switch e.System {
+	case GitHubActions:
+		return ReporterGitHub
+	case AzurePipelines:
+		return ReporterAzure
+	case TeamCity:
+		return ReporterTeamCity
+	case Jenkins:
+		return ReporterJenkins
+	}
+	return ReporterNone
+}
+
+func gitHubRunUrl() string {
+	server, repository, runId := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID")
+	if server == "" || repository == "" || runId == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/actions/runs/%s", server, repository, runId)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
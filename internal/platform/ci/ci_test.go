@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ciVariables are unset before every case, the tests may run on any of the CI systems.
+var ciVariables = []string{
+	"GITHUB_ACTIONS", "GITHUB_HEAD_REF", "GITHUB_REF_NAME", "GITHUB_SHA",
+	"GITHUB_SERVER_URL", "GITHUB_REPOSITORY", "GITHUB_RUN_ID",
+	"GITLAB_CI", "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_BRANCH", "CI_COMMIT_REF_NAME", "CI_COMMIT_SHA",
+	"CI_JOB_URL",
+	"TF_BUILD", "SYSTEM_PULLREQUEST_SOURCEBRANCH", "BUILD_SOURCEBRANCH", "BUILD_SOURCEVERSION",
+	"CIRCLECI", "CIRCLE_BRANCH", "CIRCLE_SHA1", "CIRCLE_BUILD_URL",
+	"TEAMCITY_VERSION", "BUILD_VCS_NUMBER",
+	"JENKINS_URL", "CHANGE_BRANCH", "BRANCH_NAME", "GIT_BRANCH", "GIT_COMMIT", "BUILD_URL",
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected Environment
+		reporter string
+	}{
+		{
+			name:     "no CI",
+			reporter: ReporterNone,
+		},
+		{
+			name: "GitHub Actions pull request",
+			env: map[string]string{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_HEAD_REF":   "feature",
+				"GITHUB_REF_NAME":   "42/merge",
+				"GITHUB_SHA":        "abc",
+				"GITHUB_SERVER_URL": "https://github.com",
+				"GITHUB_REPOSITORY": "owner/repo",
+				"GITHUB_RUN_ID":     "7",
+			},
+			expected: Environment{
+				System:   GitHubActions,
+				Branch:   "feature",
+				Revision: "abc",
+				BuildUrl: "https://github.com/owner/repo/actions/runs/7",
+			},
+			reporter: ReporterGitHub,
+		},
+		{
+			name:     "GitLab CI",
+			env:      map[string]string{"GITLAB_CI": "true", "CI_COMMIT_REF_NAME": "main", "CI_COMMIT_SHA": "abc"},
+			expected: Environment{System: GitLab, Branch: "main", Revision: "abc"},
+			reporter: ReporterNone,
+		},
+		{
+			name: "Azure Pipelines",
+			env: map[string]string{
+				"TF_BUILD":            "True",
+				"BUILD_SOURCEBRANCH":  "refs/heads/feature/name",
+				"BUILD_SOURCEVERSION": "abc",
+			},
+			expected: Environment{System: AzurePipelines, Branch: "feature/name", Revision: "abc"},
+			reporter: ReporterAzure,
+		},
+		{
+			name:     "CircleCI",
+			env:      map[string]string{"CIRCLECI": "true", "CIRCLE_BRANCH": "main", "CIRCLE_SHA1": "abc"},
+			expected: Environment{System: CircleCI, Branch: "main", Revision: "abc"},
+			reporter: ReporterNone,
+		},
+		{
+			name:     "TeamCity",
+			env:      map[string]string{"TEAMCITY_VERSION": "2025.07", "BUILD_VCS_NUMBER": "abc"},
+			expected: Environment{System: TeamCity, Revision: "abc"},
+			reporter: ReporterTeamCity,
+		},
+		{
+			name: "Jenkins",
+			env: map[string]string{
+				"JENKINS_URL": "https://jenkins.example.com",
+				"GIT_BRANCH":  "origin/main",
+				"GIT_COMMIT":  "abc",
+				"BUILD_URL":   "https://jenkins.example.com/job/qodana/7/",
+			},
+			expected: Environment{
+				System:   Jenkins,
+				Branch:   "main",
+				Revision: "abc",
+				BuildUrl: "https://jenkins.example.com/job/qodana/7/",
+			},
+			reporter: ReporterJenkins,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range ciVariables {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			environment := Detect()
+			assert.Equal(t, tt.expected, environment)
+			assert.Equal(t, tt.reporter, environment.Reporter())
+		})
+	}
+}
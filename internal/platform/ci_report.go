@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/ci"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+// ciProblemsLimit is the maximum number of problems reported by one run, not to flood the CI build log or the editor.
+const ciProblemsLimit = 1000
+
+// ciProblem is a new problem of the report as the CI systems show it.
+type ciProblem struct {
+	// error is set for the critical and high problems, the rest are warnings
+	error bool
+	// severity is the threshold severity: critical, high, moderate, low or info
+	severity string
+	ruleId   string
+	message  string
+	path     string
+	line     int
+	column   int
+}
+
+// ReportToCi reports the new problems of the final SARIF report with the reporter of the CI system, one of
+// ci.Reporters.
+func ReportToCi(sarifPath string, reporter string) {
+	var format func(ciProblem) string
+	switch reporter {
+	case "", ci.ReporterNone:
+		return
+	case ci.ReporterGitHub:
+		format = formatGitHubAnnotation
+	case ci.ReporterAzure:
+		format = formatAzureIssue
+	case ci.ReporterTeamCity:
+		format = newTeamCityInspectionFormatter()
+	case ci.ReporterJenkins:
+		// the plugin reads the problems from a file, not from the build log
+		if err := writeWarningsNgReport(sarifPath); err != nil {
+			log.Warnf("Problems writing the Jenkins warnings-ng report: %v", err)
+		}
+		return
+	default:
+		log.Warnf("Unknown CI reporter %s, supported values: %s", reporter, strings.Join(ci.Reporters, ", "))
+		return
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Warnf("Problems reading the report for the %s reporter: %v", reporter, err)
+		return
+	}
+	writeCiProblems(os.Stdout, report, format)
+}
+
+// writeCiProblems writes the new problems of the report in the format of the CI system or the output.
+func writeCiProblems(w io.Writer, report *sarif.Report, format func(ciProblem) string) {
+	problems := 0
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if !isNewProblem(r) {
+				continue
+			}
+			problems++
+			if problems > ciProblemsLimit {
+				continue
+			}
+			p := ciProblem{ruleId: r.RuleId, severity: thresholdSeverityOf(getSeverity(r))}
+			switch p.severity {
+			case strings.ToLower(qodanaCritical), strings.ToLower(qodanaHigh):
+				p.error = true
+			}
+			if r.Message != nil {
+				p.message = r.Message.Text
+			}
+			p.path, p.line, p.column = liveProblemLocation(r)
+			_, _ = io.WriteString(w, format(p))
+		}
+	}
+	if problems > ciProblemsLimit {
+		log.Warnf("Only the first %d of %d new problems are reported", ciProblemsLimit, problems)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/JetBrains/qodana-cli/internal/platform/ci"
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/google/uuid"
@@ -30,6 +31,7 @@ import (
 
 type CliOptions struct {
 	ResultsDir                string
+	KeepRuns                  int
 	CacheDir                  string
 	ProjectDir                string
 	RepositoryRoot            string
@@ -37,12 +39,16 @@ type CliOptions struct {
 	CoverageDir               string
 	Linter                    string
 	Image                     string
+	FallbackLinter            string
 	WithinDocker              string
 	Ide                       string
 	OnlyDirectory             string
+	InputsManifest            string
 	DisableSanity             bool
 	ProfileName               string
 	ProfilePath               string
+	ChainProfiles             []string
+	Preset                    string
 	RunPromo                  string
 	Baseline                  string
 	BaselineIncludeAbsent     bool
@@ -53,26 +59,53 @@ type CliOptions struct {
 	Property                  []string
 	Script                    string
 	FailThreshold             string
+	FailOn                    string
+	FailOnLicense             string
+	ExitCodePolicy            string
+	Observe                   bool
 	Commit                    string
 	DiffStart                 string
 	DiffEnd                   string
 	ForceLocalChangesScript   bool
 	ReversePrAnalysis         bool
+	Staged                    bool
 	AnalysisId                string
 	Env_                      []string
 	Volumes                   []string
+	Tmpfs                     []string
+	CacheVolume               string
 	User                      string
 	PrintProblems             bool
 	GenerateCodeClimateReport bool
 	SendBitBucketInsights     bool
+	Ci                        string
+	Output                    string
+	Publish                   []string
+	Webhooks                  []string
+	MetricsFormat             string
+	SbomFormat                string
+	IdeIntegration            string
 	SkipPull                  bool
+	Registry                  string
+	RegistryUsername          string
+	RegistryPasswordStdin     bool
+	ImageVulnCheck            string
+	ImageVulnLevel            string
+	LiveProblems              bool
+	StopTimeout               int
+	ReadOnlyProject           bool
 	ClearCache                bool
+	ConcurrentRun             string
 	ConfigName                string
 	FullHistory               bool
 	ApplyFixes                bool
 	Cleanup                   bool
 	FixesStrategy             string // note: deprecated option
+	FixesDiff                 string // set by qodana fix
+	FixesBranch               string
 	NoStatistics              bool
+	Offline                   bool
+	NoVerify                  bool
 	CdnetSolution             string // cdnet specific options
 	CdnetProject              string
 	CdnetConfiguration        string
@@ -123,6 +156,12 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 			"Defines an image to be used for analysis execution. \nSets --within-docker=true. Sets --linter to the one preinstalled within the image. \nAvailable images are: "+
 				product.JvmLinter.Image()+", "+product.DotNetLinter.Image()+", etc. Full list of images is available at https://hub.docker.com/u/jetbrains?search=qodana .",
 		)
+		flags.StringVar(
+			&options.FallbackLinter,
+			"fallback-linter",
+			"",
+			"Defines the linter to rerun the analysis with if the selected linter fails to build the project model, e.g. qodana-dotnet for qodana-cdnet. Overrides fallbackLinter from qodana.yaml",
+		)
 	}
 	flags.StringVar(
 		&options.Ide,
@@ -148,6 +187,12 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"",
 		"Override directory to save Qodana inspection results to (default <userCacheDir>/JetBrains/<linter>/results)",
 	)
+	flags.IntVar(
+		&options.KeepRuns,
+		"keep-runs",
+		0,
+		"Save the results of each run to <results-dir>/<time> with the 'latest' symlink to the last one and keep the given number of runs, see 'qodana history'",
+	)
 	flags.StringVar(
 		&options.CacheDir,
 		"cache-dir",
@@ -178,9 +223,63 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		&options.SendBitBucketInsights,
 		"bitbucket-insights",
 		qdenv.IsBitBucket(),
-		"Send the results BitBucket Code Insights, no additional configuration required if ran in BitBucket Pipelines (default true if Qodana is executed on BitBucket Pipelines)",
+		"Send the results BitBucket Code Insights, no additional configuration required if ran in BitBucket Pipelines. For BitBucket Data Center/Server set QD_BITBUCKET_URL to its REST API (https://<host>/rest/api/1.0), BITBUCKET_REPO_FULL_NAME (PROJECT/repo), BITBUCKET_COMMIT and QD_BITBUCKET_TOKEN (default true if Qodana is executed on BitBucket Pipelines)",
+	)
+	flags.StringVar(
+		&options.Ci,
+		"ci",
+		ci.Detect().Reporter(),
+		"Report the new problems in the format of the CI system to show them in the build: github (workflow annotations), azure (Azure DevOps logging commands), teamcity (inspections service messages), jenkins (qodana-warnings-ng.json in the results directory for the warnings-ng plugin) or none (default depends on the CI system Qodana is executed on)",
+	)
+	flags.StringVar(
+		&options.Output,
+		"output",
+		"text",
+		"Output format: text, or vscode to print the new problems as '[qodana] <severity> <file>:<line>:<column> <inspection>: <message>' lines for the problem matcher of a VS Code task",
+	)
+	flags.StringSliceVar(
+		&options.Publish,
+		"publish",
+		nil,
+		"Publish the final SARIF report to the given targets: github-code-scanning (uploads to GitHub code scanning using GITHUB_TOKEN with the security-events: write permission), gitlab-mr-discussions (starts a merge request thread on the line of every new problem using QD_GITLAB_TOKEN or CI_JOB_TOKEN, the problems posted by the previous runs are skipped), azure-code-analysis (attaches the report to the Scans tab of the Azure DevOps pipeline run)",
+	)
+	flags.StringArrayVar(
+		&options.Webhooks,
+		"webhook",
+		nil,
+		"Send the analysis result and the quality gate status to the URL (can be repeated), undelivered notifications are retried on the next run or with \"qodana notify --flush\"",
+	)
+	flags.StringArrayVar(
+		&options.Webhooks,
+		"webhook-url",
+		nil,
+		"POST the analysis summary (project, branch, commit, new problems by severity, report URL, exit code) as JSON to the URL when the scan finishes, same as --webhook; set QODANA_WEBHOOK_SECRET to sign the payloads with HMAC-SHA256 in the X-Qodana-Signature-256 header",
+	)
+	flags.StringVar(
+		&options.MetricsFormat,
+		"metrics-format",
+		"",
+		"Write the scan metrics (the time of each phase, the problems by severity, the cache hit ratio) to metrics.prom or metrics.json in the results directory: prom or json",
+	)
+	flags.StringVar(
+		&options.SbomFormat,
+		"sbom-format",
+		"",
+		"Write the SBOM of the dependencies found by the license audit to qodana.sbom.cdx.json or qodana.sbom.spdx.json in the results directory: cyclonedx or spdx",
+	)
+	flags.StringVar(
+		&options.IdeIntegration,
+		"ide-integration",
+		"",
+		"Serve the progress and the problems of the scan to the IDE over JSON-RPC on the address: localhost:<port> (0 for a free port) or unix:<socket path>",
 	)
 	flags.BoolVar(&options.ClearCache, "clear-cache", false, "Clear the local Qodana cache before running the analysis")
+	flags.StringVar(
+		&options.ConcurrentRun,
+		"concurrent-run",
+		"wait",
+		"What to do when another Qodana run uses the results or the cache directory: wait for it, unique (save the results to a new directory) or fail",
+	)
 	flags.BoolVarP(&options.ShowReport, "show-report", "w", false, "Serve HTML report on port")
 	flags.IntVar(
 		&options.Port,
@@ -208,7 +307,7 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"baseline",
 		"b",
 		"",
-		"Provide the path to an existing SARIF report to be used in the baseline state calculation",
+		"Provide the path to an existing SARIF report to be used in the baseline state calculation (default: .qodana/baseline.sarif.json if it exists)",
 	)
 	flags.BoolVar(
 		&options.BaselineIncludeAbsent,
@@ -234,6 +333,30 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"",
 		"Set the number of problems that will serve as a quality gate. If this number is reached, the inspection run is terminated with a non-zero exit code",
 	)
+	flags.StringVar(
+		&options.FailOn,
+		"fail-on",
+		"",
+		"Set the quality gate per problem severity, e.g. critical=0,high=5: the run fails if there are more new problems of a severity. Severities: any, critical, high, moderate, low, info. Overrides failureConditions.severityThresholds from qodana.yaml",
+	)
+	flags.StringVar(
+		&options.FailOnLicense,
+		"fail-on-license",
+		"",
+		"Fail the run if the license audit finds dependencies available only under the licenses prohibited in the YAML file (prohibited: [GPL-3.0-only, AGPL-*]) or the prohibited license problems, they are printed as a table",
+	)
+	flags.StringVar(
+		&options.ExitCodePolicy,
+		"exit-code-policy",
+		"",
+		"Set the exit codes of the run outcomes, e.g. problems=1,threshold=2,failure=3: problems if new problems are found within the fail threshold, threshold if the fail threshold is exceeded, failure if the analysis fails to run. The outcomes not set keep the default exit codes",
+	)
+	flags.BoolVar(
+		&options.Observe,
+		"observe",
+		false,
+		"Evaluate and report the quality gates without failing: the exit code is zero even if a gate is not passed. Also enabled until the enforceAfter date from qodana.yaml",
+	)
 	flags.BoolVar(
 		&options.DisableSanity,
 		"disable-sanity",
@@ -253,8 +376,26 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"",
 		"Directory inside the project-dir directory must be inspected. If not specified, the whole project is inspected",
 	)
+	flags.StringVar(
+		&options.InputsManifest,
+		"inputs-manifest",
+		"",
+		"Path to the manifest of files declared as the scan inputs, the scan fails if the files in scope differ from it",
+	)
 	flags.StringVarP(&options.ProfileName, "profile-name", "n", "", "Profile name defined in the project")
 	flags.StringVarP(&options.ProfilePath, "profile-path", "p", "", "Path to the profile file")
+	flags.StringArrayVar(
+		&options.ChainProfiles,
+		"chain-profile",
+		nil,
+		"Profile name or path to run after the main profile in the same invocation, reusing the warmed caches; the results are saved to <results-dir>/profiles/<profile>. Can be repeated",
+	)
+	flags.StringVar(
+		&options.Preset,
+		"preset",
+		"",
+		"Scan preset bundling the profile, the scope, the timeout and the linter properties: quick, full or security. Overrides \"preset:\" in qodana.yaml, the options given on the command line override the preset",
+	)
 	flags.StringVar(
 		&options.RunPromo,
 		"run-promo",
@@ -266,6 +407,12 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 
 	flags.BoolVar(&options.ApplyFixes, "apply-fixes", false, "Apply all available quick-fixes, including cleanup")
 	flags.BoolVar(&options.Cleanup, "cleanup", false, "Run project cleanup")
+	flags.StringVar(
+		&options.FixesBranch,
+		"fixes-branch",
+		"",
+		"Commit the changes made by the quick-fixes (also saved to fixes.patch in the results directory) to a new branch with the given name. The project must have no uncommitted changes",
+	)
 	flags.StringVar(
 		&options.FixesStrategy,
 		"fixes-strategy",
@@ -313,6 +460,12 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		false,
 		"Override the default run-scenario for diff runs to always use the reverse-scoped script",
 	)
+	flags.BoolVar(
+		&options.Staged,
+		"staged",
+		false,
+		"Analyse only the files with the changes staged in git, used by qodana precommit. Not supported for container runs",
+	)
 
 	flags.IntVar(&options.JvmDebugPort, "jvm-debug-port", -1, "Enable JVM remote debug under given port")
 
@@ -322,6 +475,19 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		false,
 		"[qodana-clang/qodana-dotnet] Disable sending anonymous statistics",
 	)
+	flags.BoolVar(
+		&options.Offline,
+		"offline",
+		false,
+		"Run without network access: no update checks, Qodana Cloud token validation, statistics and image pulls. "+
+			"Container runs need the linter image available locally, native runs need --ide with the path to a downloaded IDE or one loaded with qodana bundle load",
+	)
+	flags.BoolVar(
+		&options.NoVerify,
+		"no-verify",
+		false,
+		"Don't verify the checksums and the signatures of the downloaded IDEs and tools, e.g. for a mirror without them",
+	)
 	flags.StringVar(
 		&options.ClangCompileCommands,
 		"compile-commands",
@@ -357,7 +523,19 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 			"volume",
 			"v",
 			[]string{},
-			"Only for container runs. Define additional volumes for the Qodana container (you can use the flag multiple times)",
+			"Only for container runs. Define additional volumes for the Qodana container (you can use the flag multiple times) in the format source:target[:options], the source is a host path or a volume name. Options: ro, z or Z, consistent, cached or delegated, the bind propagation (e.g. rslave), nocopy",
+		)
+		flags.StringArrayVar(
+			&options.Tmpfs,
+			"tmpfs",
+			[]string{},
+			"Only for container runs. Mount a tmpfs to the Qodana container (you can use the flag multiple times) in the format target[:options], e.g. /data/cache/idea:size=2g. Options: size, mode",
+		)
+		flags.StringVar(
+			&options.CacheVolume,
+			"cache-volume",
+			"",
+			"Only for container runs. Keep the cache of the Qodana container in the Docker volume with the given name instead of the cache directory, much faster on Docker Desktop. The volume is created if it doesn't exist and removed by 'qodana cache prune --volumes' with the cache directory",
 		)
 		flags.StringVarP(
 			&options.User,
@@ -366,7 +544,7 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 			"auto",
 			"Only for container runs. Override user inside the Qodana container. "+
 				"Format: uid[:gid] (e.g. '0:0' for root, '$(id -u):$(id -g)' for current user). "+
-				"Default: current system user, or root in privileged images",
+				"Default: current system user, or root in privileged images and with rootless Docker",
 		)
 		flags.BoolVar(
 			&options.SkipPull,
@@ -374,11 +552,68 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 			false,
 			"Only for container runs. Skip pulling the latest Qodana container",
 		)
+		flags.StringVar(
+			&options.Registry,
+			"registry",
+			"",
+			"Only for container runs. Registry host the credentials of --registry-username are sent to, QODANA_REGISTRY or the registry of the linter image by default",
+		)
+		flags.StringVar(
+			&options.RegistryUsername,
+			"registry-username",
+			"",
+			"Only for container runs. Username of the registry the linter image is pulled from, for the CI systems without a Docker config. "+
+				"QODANA_REGISTRY_USERNAME by default, the credential helpers of ECR, GCR and ACR are used without it",
+		)
+		flags.BoolVar(
+			&options.RegistryPasswordStdin,
+			"registry-password-stdin",
+			false,
+			"Only for container runs. Read the password or the token of --registry-username from stdin, QODANA_REGISTRY_PASSWORD is used otherwise",
+		)
+		flags.StringVar(
+			&options.ImageVulnCheck,
+			"image-vuln-check",
+			"off",
+			"Only for container runs. Check the linter image for known vulnerabilities before running it: off, warn or fail. "+
+				"The image is scanned with trivy or grype, otherwise the unverified vulnerability attestation of the image is used if cosign is installed",
+		)
+		flags.StringVar(
+			&options.ImageVulnLevel,
+			"image-vuln-level",
+			"critical",
+			"Only for container runs. The lowest severity of the image vulnerabilities reported by --image-vuln-check: low, medium, high or critical",
+		)
+		flags.BoolVar(
+			&options.LiveProblems,
+			"live-problems",
+			false,
+			"Only for container runs. Print the problems as soon as the linter writes them to qodana.sarif.json in the results directory, before the analysis finishes",
+		)
+		flags.IntVar(
+			&options.StopTimeout,
+			"stop-timeout",
+			30,
+			"Only for container runs. Seconds the interrupted linter has to save the partial results before the Qodana container is killed, the second Ctrl+C kills it immediately",
+		)
+		flags.BoolVar(
+			&options.ReadOnlyProject,
+			"read-only-project",
+			false,
+			"Only for container runs with Docker on Linux. Mount the project read-only with an overlay for the writes of the linter: the changes made by the quick-fixes are saved to fixes.patch in the results directory instead of being applied to the project",
+		)
 		cmd.MarkFlagsMutuallyExclusive("linter", "ide")
 		cmd.MarkFlagsMutuallyExclusive("skip-pull", "ide")
+		cmd.MarkFlagsMutuallyExclusive("registry-username", "ide")
+		cmd.MarkFlagsMutuallyExclusive("registry-password-stdin", "ide")
 		cmd.MarkFlagsMutuallyExclusive("volume", "ide")
+		cmd.MarkFlagsMutuallyExclusive("tmpfs", "ide")
+		cmd.MarkFlagsMutuallyExclusive("cache-volume", "ide")
 		cmd.MarkFlagsMutuallyExclusive("user", "ide")
 		cmd.MarkFlagsMutuallyExclusive("env", "ide")
+		cmd.MarkFlagsMutuallyExclusive("live-problems", "ide")
+		cmd.MarkFlagsMutuallyExclusive("read-only-project", "ide")
+		cmd.MarkFlagsMutuallyExclusive("read-only-project", "fixes-branch")
 	}
 
 	globalConfigDirOptionName := "global-config-dir"
@@ -388,7 +623,7 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		globalConfigDirOptionName,
 		"",
 		fmt.Sprintf(
-			"Path to the global configurations directory with `qodana-global-configurations.yaml` file in the root, must be specified with '--%s'",
+			"Path to the global configurations directory with `qodana-global-configurations.yaml` file in the root, or a git URL (repository[#ref]) or a .zip/.tar.gz URL to fetch it from, must be specified with '--%s'",
 			globalConfigIdOptionName,
 		),
 	)
@@ -413,6 +648,7 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 
 	cmd.MarkFlagsMutuallyExclusive("script", "force-local-changes-script", "full-history")
 	cmd.MarkFlagsMutuallyExclusive("commit", "script", "diff-start")
+	cmd.MarkFlagsMutuallyExclusive("staged", "commit", "diff-start", "diff-end", "full-history", "script")
 	cmd.MarkFlagsMutuallyExclusive("profile-name", "profile-path")
 	cmd.MarkFlagsMutuallyExclusive("apply-fixes", "cleanup")
 	cmd.MarkFlagsMutuallyExclusive("source-directory", "only-directory")
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// BundleManifestName is the last entry of a bundle archive, it describes the bundled images and directories.
+const BundleManifestName = ".qodana-bundle-manifest.json"
+
+// bundleArchiveVersion is the version of the bundle archive format, the archives of other versions are not loaded.
+const bundleArchiveVersion = 1
+
+// The top-level directories of a bundle archive.
+const (
+	// BundleImagesArchive is the docker save archive of the bundled images.
+	BundleImagesArchive = "images/images.tar"
+	// BundleSystemDir is installed to the Qodana system directory, <userCacheDir>/JetBrains/Qodana.
+	BundleSystemDir = "system"
+	// BundleConfigDir is installed to the Qodana configuration directory, <userConfigDir>/JetBrains/Qodana.
+	BundleConfigDir = "config"
+)
+
+// BundleManifest describes an air-gapped bundle written by ExportBundle.
+type BundleManifest struct {
+	Version    int    `json:"version"`
+	CliVersion string `json:"cliVersion"`
+	Os         string `json:"os"`
+	Arch       string `json:"arch"`
+	CreatedAt  string `json:"createdAt"`
+	// Images are the images saved to BundleImagesArchive.
+	Images []string `json:"images,omitempty"`
+	// Ides are the IDE directories of the linters for the native mode, relative to BundleSystemDir.
+	Ides map[string]string `json:"ides,omitempty"`
+	// Dirs are the bundled directories replacing the installed ones on load, e.g. system/ideaIC-2024.3.
+	Dirs   []string `json:"dirs,omitempty"`
+	Files  int      `json:"files"`
+	Size   int64    `json:"size"`
+	Sha256 string   `json:"sha256"`
+}
+
+// BundleSource is a directory or a file added to a bundle archive under Name.
+type BundleSource struct {
+	Name string
+	Path string
+}
+
+// ExportBundle writes the sources to the bundle archive compressed by its extension, with the manifest as the last
+// entry. The archive is written next to archivePath first, an interrupted export doesn't leave a broken archive.
+func ExportBundle(archivePath string, sources []BundleSource, manifest BundleManifest) (BundleManifest, error) {
+	compression, err := cacheArchiveCompression(archivePath)
+	if err != nil {
+		return manifest, err
+	}
+	tmpPath := archivePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return manifest, err
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	manifest, err = writeBundleArchive(file, compression, sources, manifest)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, os.Rename(tmpPath, archivePath)
+}
+
+func writeBundleArchive(w io.Writer, compression string, sources []BundleSource, manifest BundleManifest) (BundleManifest, error) {
+	compressor, err := newArchiveCompressor(w, compression)
+	if err != nil {
+		return manifest, err
+	}
+	tw := tar.NewWriter(compressor)
+	contentHash := newCacheContentHash()
+	for _, source := range sources {
+		if err = addArchiveDir(tw, source.Path, path.Clean(source.Name), contentHash); err != nil {
+			return manifest, fmt.Errorf("failed to add %s: %w", source.Path, err)
+		}
+	}
+	manifest.Version = bundleArchiveVersion
+	manifest.Files, manifest.Size, manifest.Sha256 = contentHash.files, contentHash.size, contentHash.sum()
+	if err = writeArchiveManifest(tw, BundleManifestName, manifest); err != nil {
+		return manifest, err
+	}
+	if err = tw.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, compressor.Close()
+}
+
+// ExtractBundle extracts the bundle archive written by ExportBundle to destDir and checks its content against the
+// manifest, installing the extracted images and directories is up to the caller.
+func ExtractBundle(archivePath string, destDir string) (BundleManifest, error) {
+	var manifest BundleManifest
+	compression, err := cacheArchiveCompression(archivePath)
+	if err != nil {
+		return manifest, err
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return manifest, err
+	}
+	defer func() { _ = file.Close() }()
+
+	contentHash, err := readArchive(file, compression, destDir, BundleManifestName, &manifest)
+	if err == nil && manifest.Version != bundleArchiveVersion {
+		err = fmt.Errorf("unsupported version %d of the bundle format", manifest.Version)
+	}
+	if err == nil {
+		err = checkArchiveContent(contentHash, manifest.Files, manifest.Size, manifest.Sha256)
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("invalid bundle %s: %w", archivePath, err)
+	}
+	return manifest, nil
+}
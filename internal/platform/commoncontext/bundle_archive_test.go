@@ -0,0 +1,64 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportExtractBundle(t *testing.T) {
+	ideDir, tmpDir := filepath.Join(t.TempDir(), "ideaIC-2024.3"), t.TempDir()
+	writeCacheFiles(t, ideDir)
+	imagesPath := filepath.Join(tmpDir, "images.tar")
+	require.NoError(t, os.WriteFile(imagesPath, []byte("layers"), 0o644))
+	archivePath := filepath.Join(t.TempDir(), "bundle.tar.zst")
+
+	manifest, err := ExportBundle(
+		archivePath,
+		[]BundleSource{
+			{Name: BundleImagesArchive, Path: imagesPath},
+			{Name: "system/ideaIC-2024.3", Path: ideDir},
+		},
+		BundleManifest{CliVersion: "2024.3.0", Images: []string{"jetbrains/qodana-jvm:2024.3"}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 4, manifest.Files)
+	assert.Equal(t, int64(26), manifest.Size)
+	assert.NoFileExists(t, archivePath+".tmp")
+
+	destDir := filepath.Join(t.TempDir(), "bundle")
+	extracted, err := ExtractBundle(archivePath, destDir)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, extracted)
+	assert.FileExists(t, filepath.Join(destDir, "images", "images.tar"))
+	content, err := os.ReadFile(filepath.Join(destDir, "system", "ideaIC-2024.3", "idea", "index", "stamps"))
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(content))
+	assert.DirExists(t, filepath.Join(destDir, "system", "ideaIC-2024.3", "empty"))
+
+	// a cache archive is not a bundle
+	cachePath := filepath.Join(t.TempDir(), "cache.tar")
+	_, err = ExportCache(ideDir, cachePath, CacheManifest{Analyzer: "qodana-jvm"})
+	require.NoError(t, err)
+	_, err = ExtractBundle(cachePath, filepath.Join(t.TempDir(), "bundle"))
+	assert.ErrorContains(t, err, "no manifest")
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LinterDirInfoFileName is the file in a linter directory telling which project and linter it belongs to.
+const LinterDirInfoFileName = "linter-dir.json"
+
+// linterDirIdPattern matches the names of the linter directories, see computeId.
+var linterDirIdPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{8}$`)
+
+// LinterDir is the directory with the caches and results of a linter run on a project, inside the Qodana system directory.
+type LinterDir struct {
+	Id         string    `json:"id"`
+	Path       string    `json:"path"`
+	ProjectDir string    `json:"projectDir,omitempty"`
+	Analyzer   string    `json:"analyzer,omitempty"`
+	Size       int64     `json:"size"`
+	LastUsed   time.Time `json:"lastUsed"`
+}
+
+type linterDirInfo struct {
+	ProjectDir string `json:"projectDir"`
+	Analyzer   string `json:"analyzer"`
+}
+
+// writeLinterDirInfo records the project and the analyzer of the linter directory, it also marks the directory as used.
+func writeLinterDirInfo(c Context) {
+	data, err := json.Marshal(linterDirInfo{ProjectDir: c.ProjectDir, Analyzer: c.Analyzer.Name()})
+	if err != nil {
+		log.Debugf("Failed to marshal %s: %s", LinterDirInfoFileName, err)
+		return
+	}
+	linterDir := c.GetLinterDir()
+	if err = os.MkdirAll(linterDir, os.ModePerm); err != nil {
+		log.Debugf("Failed to create %s: %s", linterDir, err)
+		return
+	}
+	if err = os.WriteFile(filepath.Join(linterDir, LinterDirInfoFileName), data, 0o644); err != nil {
+		log.Debugf("Failed to write %s: %s", LinterDirInfoFileName, err)
+	}
+}
+
+// ListLinterDirs returns the linter directories in the Qodana system directory, the most recently used first.
+// The other directories there, like the IDE distributions, are not included.
+func ListLinterDirs(systemDir string) ([]LinterDir, error) {
+	entries, err := os.ReadDir(systemDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]LinterDir, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || !linterDirIdPattern.MatchString(entry.Name()) {
+			continue
+		}
+		dir := LinterDir{Id: entry.Name(), Path: filepath.Join(systemDir, entry.Name())}
+		dir.Size, dir.LastUsed, err = DirUsage(dir.Path)
+		if err != nil {
+			return nil, err
+		}
+		if data, err := os.ReadFile(filepath.Join(dir.Path, LinterDirInfoFileName)); err == nil {
+			var info linterDirInfo
+			if err = json.Unmarshal(data, &info); err == nil {
+				dir.ProjectDir = info.ProjectDir
+				dir.Analyzer = info.Analyzer
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	slices.SortFunc(
+		dirs, func(a, b LinterDir) int {
+			return b.LastUsed.Compare(a.LastUsed)
+		},
+	)
+	return dirs, nil
+}
+
+// PruneLinterDirs removes the linter directories not used since the given time and returns the removed ones.
+func PruneLinterDirs(dirs []LinterDir, usedBefore time.Time, dryRun bool) ([]LinterDir, error) {
+	removed := make([]LinterDir, 0)
+	for _, dir := range dirs {
+		if !dir.LastUsed.Before(usedBefore) {
+			continue
+		}
+		if !dryRun {
+			if err := os.RemoveAll(dir.Path); err != nil {
+				return removed, err
+			}
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// DirUsage returns the total size of the files in the directory and the latest modification time in it.
+func DirUsage(dir string) (int64, time.Time, error) {
+	var size int64
+	var lastModified time.Time
+	err := filepath.WalkDir(
+		dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// the files of a running analysis can disappear during the walk
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if info.ModTime().After(lastModified) {
+				lastModified = info.ModTime()
+			}
+			if info.Mode().IsRegular() {
+				size += info.Size()
+			}
+			return nil
+		},
+	)
+	return size, lastModified, err
+}
@@ -0,0 +1,463 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/archive"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CacheManifestName is the last entry of a cache archive, it describes the archived cache to validate it on import.
+const CacheManifestName = ".qodana-cache-manifest.json"
+
+// cacheArchiveVersion is the version of the cache archive format, the archives of other versions are not imported.
+const cacheArchiveVersion = 1
+
+// CacheManifest describes a cache archive written by ExportCache.
+type CacheManifest struct {
+	Version    int    `json:"version"`
+	CliVersion string `json:"cliVersion"`
+	Analyzer   string `json:"analyzer"`
+	ProjectId  string `json:"projectId"`
+	CreatedAt  string `json:"createdAt"`
+	Files      int    `json:"files"`
+	Size       int64  `json:"size"`
+	Sha256     string `json:"sha256"`
+}
+
+// cacheArchiveCompressions are the supported archive extensions and their compressions.
+var cacheArchiveCompressions = []struct {
+	extension   string
+	compression string
+}{
+	{".tar.zst", "zstd"},
+	{".tzst", "zstd"},
+	{".tar.gz", "gzip"},
+	{".tgz", "gzip"},
+	{".tar", ""},
+}
+
+// cacheArchiveCompression returns the compression of the archive from its extension.
+func cacheArchiveCompression(archivePath string) (string, error) {
+	name := strings.ToLower(archivePath)
+	for _, c := range cacheArchiveCompressions {
+		if strings.HasSuffix(name, c.extension) {
+			return c.compression, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported archive %s, use .tar.zst, .tar.gz or .tar", archivePath)
+}
+
+// cacheContentHash is the SHA-256 of the names and the contents of the archived files, in the archive order.
+type cacheContentHash struct {
+	hash.Hash
+	files int
+	size  int64
+}
+
+func newCacheContentHash() *cacheContentHash {
+	return &cacheContentHash{Hash: sha256.New()}
+}
+
+func (h *cacheContentHash) addFile(name string, content io.Reader) (int64, error) {
+	_, _ = h.Write([]byte(name + "\x00"))
+	n, err := io.Copy(h, content)
+	h.files++
+	h.size += n
+	return n, err
+}
+
+func (h *cacheContentHash) sum() string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ExportCache archives the cache directory to archivePath, compressed by its extension, with the manifest as the last
+// entry. The archive is written next to archivePath first, an interrupted export doesn't leave a broken archive.
+func ExportCache(cacheDir string, archivePath string, manifest CacheManifest) (CacheManifest, error) {
+	compression, err := cacheArchiveCompression(archivePath)
+	if err != nil {
+		return manifest, err
+	}
+	if _, err = os.Stat(cacheDir); err != nil {
+		return manifest, fmt.Errorf("no cache to export: %w", err)
+	}
+	tmpPath := archivePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return manifest, err
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	manifest, err = writeCacheArchive(file, compression, cacheDir, manifest)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, os.Rename(tmpPath, archivePath)
+}
+
+func writeCacheArchive(w io.Writer, compression string, cacheDir string, manifest CacheManifest) (CacheManifest, error) {
+	compressor, err := newArchiveCompressor(w, compression)
+	if err != nil {
+		return manifest, err
+	}
+	tw := tar.NewWriter(compressor)
+	contentHash := newCacheContentHash()
+	if err = addArchiveDir(tw, cacheDir, "", contentHash); err != nil {
+		return manifest, err
+	}
+	manifest.Version = cacheArchiveVersion
+	manifest.Files, manifest.Size, manifest.Sha256 = contentHash.files, contentHash.size, contentHash.sum()
+	if err = writeArchiveManifest(tw, CacheManifestName, manifest); err != nil {
+		return manifest, err
+	}
+	if err = tw.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, compressor.Close()
+}
+
+// newArchiveCompressor returns the writer compressing the archive, closing it doesn't close w.
+func newArchiveCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "zstd":
+		return zstd.NewWriter(w)
+	case "gzip":
+		return archive.NewParallelGzipWriter(w, gzip.DefaultCompression)
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+// addArchiveDir adds the directory or the file to the archive under prefix, the root itself if prefix is empty.
+func addArchiveDir(tw *tar.Writer, root string, prefix string, contentHash *cacheContentHash) error {
+	return filepath.WalkDir(
+		root, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name, err := filepath.Rel(root, filePath)
+			if err != nil {
+				return err
+			}
+			name = path.Join(prefix, filepath.ToSlash(name))
+			if name == "." {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			link := ""
+			if info.Mode()&fs.ModeSymlink != 0 {
+				if link, err = os.Readlink(filePath); err != nil {
+					return err
+				}
+			} else if !info.IsDir() && !info.Mode().IsRegular() {
+				return nil
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+			if err = tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(filePath)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			_, err = contentHash.addFile(name, io.TeeReader(f, tw))
+			return err
+		},
+	)
+}
+
+// writeArchiveManifest writes the manifest as the last entry of the archive.
+func writeArchiveManifest(tw *tar.Writer, name string, manifest any) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+// ImportCache restores the cache directory from the archive written by ExportCache. The archive is extracted next to
+// the cache directory and replaces it only if its content matches the manifest and validate accepts the manifest.
+func ImportCache(archivePath string, cacheDir string, validate func(CacheManifest) error) (CacheManifest, error) {
+	var manifest CacheManifest
+	compression, err := cacheArchiveCompression(archivePath)
+	if err != nil {
+		return manifest, err
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return manifest, err
+	}
+	defer func() { _ = file.Close() }()
+
+	tmpDir := filepath.Clean(cacheDir) + ".import"
+	if err = os.RemoveAll(tmpDir); err != nil {
+		return manifest, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	if manifest, err = readCacheArchive(file, compression, tmpDir); err != nil {
+		return manifest, fmt.Errorf("invalid cache archive %s: %w", archivePath, err)
+	}
+	if err = validate(manifest); err != nil {
+		return manifest, err
+	}
+	if err = os.RemoveAll(cacheDir); err != nil {
+		return manifest, err
+	}
+	if err = os.MkdirAll(filepath.Dir(cacheDir), os.ModePerm); err != nil {
+		return manifest, err
+	}
+	return manifest, os.Rename(tmpDir, cacheDir)
+}
+
+func readCacheArchive(r io.Reader, compression string, destDir string) (CacheManifest, error) {
+	var manifest CacheManifest
+	contentHash, err := readArchive(r, compression, destDir, CacheManifestName, &manifest)
+	if err != nil {
+		return manifest, err
+	}
+	if manifest.Version != cacheArchiveVersion {
+		return manifest, fmt.Errorf("unsupported version %d of the archive format", manifest.Version)
+	}
+	return manifest, checkArchiveContent(contentHash, manifest.Files, manifest.Size, manifest.Sha256)
+}
+
+// readArchive extracts the archive to destDir and decodes its last entry to manifest, it returns the hash of the
+// extracted content to check it against the manifest.
+func readArchive(r io.Reader, compression string, destDir string, manifestName string, manifest any) (*cacheContentHash, error) {
+	switch compression {
+	case "zstd":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		r = decoder
+	case "gzip":
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = reader.Close() }()
+		r = reader
+	}
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(r)
+	contentHash := newCacheContentHash()
+	hasManifest := false
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hasManifest {
+			return nil, fmt.Errorf("unexpected entry %s after the manifest", header.Name)
+		}
+		if header.Name == manifestName {
+			if err = json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("failed to read the manifest: %w", err)
+			}
+			hasManifest = true
+			continue
+		}
+		if err = extractCacheEntry(tr, header, destDir, contentHash); err != nil {
+			return nil, err
+		}
+	}
+	if !hasManifest {
+		return nil, errors.New("no manifest, it's not a Qodana archive")
+	}
+	return contentHash, nil
+}
+
+// checkArchiveContent checks the extracted content against the summary in the manifest.
+func checkArchiveContent(contentHash *cacheContentHash, files int, size int64, sha256 string) error {
+	switch {
+	case files != contentHash.files || size != contentHash.size:
+		return fmt.Errorf(
+			"%d files of %d bytes are expected, %d files of %d bytes are found",
+			files,
+			size,
+			contentHash.files,
+			contentHash.size,
+		)
+	case sha256 != contentHash.sum():
+		return errors.New("the checksum of the content doesn't match the manifest")
+	}
+	return nil
+}
+
+func extractCacheEntry(tr *tar.Reader, header *tar.Header, destDir string, contentHash *cacheContentHash) error {
+	name := path.Clean(header.Name)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("%s: illegal file path", header.Name)
+	}
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if _, err := resolveInsideDir(destDir, target); err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+		return os.MkdirAll(target, 0o755)
+	case tar.TypeSymlink:
+		if path.IsAbs(header.Linkname) {
+			return fmt.Errorf("%s: illegal link to %s", header.Name, header.Linkname)
+		}
+		// the parent can be a link extracted before, the link is resolved against where it really is
+		parent, err := resolveInsideDir(destDir, filepath.Dir(target))
+		if err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+		if !isLinkInsideDir(destDir, parent, header.Linkname) {
+			return fmt.Errorf("%s: illegal link to %s", header.Name, header.Linkname)
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return os.Symlink(header.Linkname, target)
+	case tar.TypeReg:
+		if _, err := resolveInsideDir(destDir, filepath.Dir(target)); err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+		if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s: illegal write through a link", header.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm()|0o200)
+		if err != nil {
+			return err
+		}
+		_, err = contentHash.addFile(name, io.TeeReader(tr, f))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		// the IDE compares the modification times of the files with its indexes
+		return os.Chtimes(target, header.ModTime, header.ModTime)
+	default:
+		return fmt.Errorf("%s: unsupported entry type %c", header.Name, header.Typeflag)
+	}
+}
+
+// resolveInsideDir resolves the links of the existing part of target and checks that it stays inside destDir, the
+// links extracted before can point anywhere inside the archive. It returns the resolved target.
+func resolveInsideDir(destDir string, target string) (string, error) {
+	existing, rest := target, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil || existing == destDir {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = filepath.Dir(existing)
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	resolved = filepath.Join(resolved, rest)
+	if !isInsideDir(destDir, resolved) {
+		return "", errors.New("illegal path outside of the archive through a link")
+	}
+	return resolved, nil
+}
+
+// isLinkInsideDir tells if the link in parent points inside destDir, the link is followed element by element, so
+// .. after a link extracted before goes to the parent of where that link points.
+func isLinkInsideDir(destDir string, parent string, linkname string) bool {
+	current := parent
+	for _, element := range strings.Split(linkname, "/") {
+		switch element {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+		default:
+			current = filepath.Join(current, element)
+			if info, err := os.Lstat(current); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				resolved, err := filepath.EvalSymlinks(current)
+				if err != nil {
+					return false
+				}
+				current = resolved
+			}
+		}
+		if !isInsideDir(destDir, current) {
+			return false
+		}
+	}
+	return true
+}
+
+// isInsideDir tells if the path is dir or under it, dir is compared with its links resolved.
+func isInsideDir(dir string, p string) bool {
+	if resolvedDir, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolvedDir
+	}
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCacheFiles(t *testing.T, cacheDir string) time.Time {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for name, content := range map[string]string{
+		"idea/index/stamps": "0123456789",
+		"idea/log/idea.log": "started",
+		"m2/repository/lib": "jar",
+	} {
+		path := filepath.Join(cacheDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "empty"), 0o755))
+	return modTime
+}
+
+func acceptAnyCache(CacheManifest) error { return nil }
+
+func TestExportImportCache(t *testing.T) {
+	for _, archiveName := range []string{"cache.tar.zst", "cache.tar.gz", "cache.tar"} {
+		t.Run(
+			archiveName, func(t *testing.T) {
+				cacheDir, archivePath := filepath.Join(t.TempDir(), "cache"), filepath.Join(t.TempDir(), archiveName)
+				modTime := writeCacheFiles(t, cacheDir)
+
+				manifest, err := ExportCache(cacheDir, archivePath, CacheManifest{Analyzer: "qodana-jvm", ProjectId: "0123abcd-89abcdef"})
+				require.NoError(t, err)
+				assert.Equal(t, 3, manifest.Files)
+				assert.Equal(t, int64(20), manifest.Size)
+				assert.NoFileExists(t, archivePath+".tmp")
+
+				importDir := filepath.Join(t.TempDir(), "linter", "cache")
+				require.NoError(t, os.MkdirAll(importDir, 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(importDir, "stale"), []byte("stale"), 0o644))
+				imported, err := ImportCache(archivePath, importDir, acceptAnyCache)
+				require.NoError(t, err)
+				assert.Equal(t, manifest, imported)
+				assert.NoFileExists(t, filepath.Join(importDir, "stale"))
+				assert.DirExists(t, filepath.Join(importDir, "empty"))
+				assert.NoDirExists(t, importDir+".import")
+				content, err := os.ReadFile(filepath.Join(importDir, "idea", "index", "stamps"))
+				require.NoError(t, err)
+				assert.Equal(t, "0123456789", string(content))
+				info, err := os.Stat(filepath.Join(importDir, "idea", "index", "stamps"))
+				require.NoError(t, err)
+				assert.True(t, modTime.Equal(info.ModTime()))
+			},
+		)
+	}
+}
+
+func TestImportCacheValidation(t *testing.T) {
+	cacheDir, archivePath := filepath.Join(t.TempDir(), "cache"), filepath.Join(t.TempDir(), "cache.tar")
+	writeCacheFiles(t, cacheDir)
+	_, err := ExportCache(cacheDir, archivePath, CacheManifest{Analyzer: "qodana-jvm"})
+	require.NoError(t, err)
+
+	importDir := filepath.Join(t.TempDir(), "cache")
+	require.NoError(t, os.MkdirAll(importDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(importDir, "kept"), []byte("kept"), 0o644))
+	_, err = ImportCache(archivePath, importDir, func(CacheManifest) error { return errors.New("another linter") })
+	assert.EqualError(t, err, "another linter")
+	assert.FileExists(t, filepath.Join(importDir, "kept"))
+
+	_, err = ImportCache(filepath.Join(t.TempDir(), "cache.zip"), importDir, acceptAnyCache)
+	assert.Error(t, err)
+
+	// an archive without the manifest, or with the content not matching it, is not imported
+	for name, entries := range map[string][]tar.Header{
+		"no-manifest.tar": {{Name: "index"}},
+		"changed.tar":     {{Name: "index"}, {Name: CacheManifestName}},
+		"traversal.tar":   {{Name: "../index"}},
+	} {
+		path := filepath.Join(t.TempDir(), name)
+		file, err := os.Create(path)
+		require.NoError(t, err)
+		tw := tar.NewWriter(file)
+		for _, header := range entries {
+			content := "index"
+			if header.Name == CacheManifestName {
+				content = `{"version":1,"files":1,"size":5,"sha256":"broken"}`
+			}
+			header.Mode, header.Size = 0o644, int64(len(content))
+			require.NoError(t, tw.WriteHeader(&header))
+			_, err = tw.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		require.NoError(t, file.Close())
+
+		_, err = ImportCache(path, importDir, acceptAnyCache)
+		assert.Error(t, err, name)
+		assert.FileExists(t, filepath.Join(importDir, "kept"), name)
+	}
+}
+
+func TestExtractChainedLinks(t *testing.T) {
+	for name, entries := range map[string][]tar.Header{
+		"link-through-link.tar": {
+			{Name: "l1", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "l1/l2", Typeflag: tar.TypeSymlink, Linkname: "../x"},
+			{Name: "l2/escaped", Typeflag: tar.TypeReg},
+		},
+		"parent-of-link.tar": {
+			{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a/../x"},
+			{Name: "b/escaped", Typeflag: tar.TypeReg},
+		},
+		"write-through-link.tar": {
+			{Name: "dir", Typeflag: tar.TypeDir},
+			{Name: "dir/up", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "dir/up/up", Typeflag: tar.TypeSymlink, Linkname: "../x"},
+			{Name: "dir/up/up/escaped", Typeflag: tar.TypeReg},
+		},
+	} {
+		t.Run(
+			name, func(t *testing.T) {
+				root := t.TempDir()
+				destDir := filepath.Join(root, "bundle")
+				path := filepath.Join(t.TempDir(), name)
+				file, err := os.Create(path)
+				require.NoError(t, err)
+				tw := tar.NewWriter(file)
+				for _, header := range entries {
+					header.Mode = 0o755
+					require.NoError(t, tw.WriteHeader(&header))
+				}
+				require.NoError(t, tw.Close())
+				require.NoError(t, file.Close())
+
+				_, err = ExtractBundle(path, destDir)
+				assert.ErrorContains(t, err, "illegal")
+				assert.NoFileExists(t, filepath.Join(root, "x", "escaped"))
+			},
+		)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createLinterDir(t *testing.T, systemDir string, id string, info string, lastUsed time.Time) {
+	dir := filepath.Join(systemDir, id)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "cache"), os.ModePerm))
+	files := map[string]string{filepath.Join(dir, "cache", "index"): "0123456789"}
+	if info != "" {
+		files[filepath.Join(dir, LinterDirInfoFileName)] = info
+	}
+	for path, content := range files {
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	assert.NoError(
+		t, filepath.Walk(
+			dir, func(path string, _ os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				return os.Chtimes(path, lastUsed, lastUsed)
+			},
+		),
+	)
+}
+
+func TestListAndPruneLinterDirs(t *testing.T) {
+	systemDir := t.TempDir()
+	now := time.Now()
+	createLinterDir(t, systemDir, "0123abcd-89abcdef", `{"projectDir":"/work/old","analyzer":"qodana-jvm"}`, now.AddDate(0, 0, -40))
+	createLinterDir(t, systemDir, "00000000-11111111", `{"projectDir":"/work/new","analyzer":"qodana-go"}`, now.Add(-time.Hour))
+	createLinterDir(t, systemDir, "ffffffff-eeeeeeee", "", now.AddDate(0, 0, -10))
+	// IDE distributions and other files are not linter directories
+	assert.NoError(t, os.MkdirAll(filepath.Join(systemDir, "QDJVM-2025.1", "bin"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(systemDir, "qodana.log"), []byte("log"), 0o644))
+
+	dirs, err := ListLinterDirs(systemDir)
+	assert.NoError(t, err)
+	if assert.Len(t, dirs, 3) {
+		assert.Equal(t, "00000000-11111111", dirs[0].Id)
+		assert.Equal(t, "/work/new", dirs[0].ProjectDir)
+		assert.Equal(t, "qodana-go", dirs[0].Analyzer)
+		assert.Equal(t, "ffffffff-eeeeeeee", dirs[1].Id)
+		assert.Empty(t, dirs[1].ProjectDir)
+		assert.Equal(t, int64(10), dirs[1].Size)
+		assert.Equal(t, "0123abcd-89abcdef", dirs[2].Id)
+	}
+
+	removed, err := PruneLinterDirs(dirs, now.AddDate(0, 0, -30), true)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 1)
+	assert.DirExists(t, dirs[2].Path)
+
+	removed, err = PruneLinterDirs(dirs, now.AddDate(0, 0, -5), false)
+	assert.NoError(t, err)
+	assert.Len(t, removed, 2)
+	assert.NoDirExists(t, dirs[1].Path)
+	assert.NoDirExists(t, dirs[2].Path)
+	assert.DirExists(t, dirs[0].Path)
+	assert.DirExists(t, filepath.Join(systemDir, "QDJVM-2025.1"))
+}
+
+func TestListLinterDirsMissingSystemDir(t *testing.T) {
+	dirs, err := ListLinterDirs(filepath.Join(t.TempDir(), "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, dirs)
+}
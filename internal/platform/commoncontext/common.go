@@ -34,6 +34,7 @@ import (
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	"github.com/JetBrains/qodana-cli/internal/tooling"
 	"github.com/codeclysm/extract/v4"
@@ -186,9 +187,10 @@ func BuildPathNativeAnalyzer(dist string) (product.Analyzer, error) {
 // SelectAnalyzerForPath gets linter for the given path
 func SelectAnalyzerForPath(path string, token string) product.Analyzer {
 	var linters []product.Linter
+	var languages []string
 	msg.PrintProcess(
 		func(_ *pterm.SpinnerPrinter) {
-			languages := readIdeaDir(path)
+			languages = readIdeaDir(path)
 			if len(languages) == 0 {
 				languages, _ = recognizeDirLanguages(path)
 			}
@@ -196,34 +198,13 @@ func SelectAnalyzerForPath(path string, token string) product.Analyzer {
 				msg.WarningMessage("No technologies detected (no source code files?)\n")
 			} else {
 				msg.WarningMessage("Detected technologies: " + strings.Join(languages, ", ") + "\n")
-				for _, language := range languages {
-					if i, ok := product.LangsToLinters[language]; ok {
-						linters = append(linters, i...)
-					}
-				}
-				if len(linters) == 0 {
-					linters = product.AllLinters
-				}
-			}
-			// breaking change will not be backported to 241
-			if (slices.Contains(linters, product.AndroidCommunityLinter) ||
-				slices.Contains(linters, product.AndroidLinter)) &&
-				isAndroidProject(path) {
-
-				filteredLinters := make([]product.Linter, 0, len(linters))
-				for _, l := range linters {
-					if l != product.AndroidLinter && l != product.AndroidCommunityLinter {
-						filteredLinters = append(filteredLinters, l)
-					}
-				}
-				linters = append(
-					[]product.Linter{product.AndroidLinter, product.AndroidCommunityLinter},
-					filteredLinters...,
-				)
 			}
+			linters = recommendedLinters(path, languages)
 		}, "Scanning project", "",
 	)
-	linters = algorithm.Unique(linters)
+	if msg.IsInteractive() {
+		printLanguagesPreview(languagesPreview(languages))
+	}
 
 	selector := func(choices []string) string {
 		choice, err := msg.QodanaInteractiveSelect.WithOptions(choices).Show()
@@ -240,14 +221,73 @@ func SelectAnalyzerForPath(path string, token string) product.Analyzer {
 	if analyzer == nil {
 		msg.ErrorMessage("Could not configure project as it is not supported by Qodana")
 		msg.WarningMessage("See https://www.jetbrains.com/help/qodana/supported-technologies.html for more details")
-		os.Exit(1)
+		log.StandardLogger().Exit(1)
 	}
 	msg.SuccessMessage("Selected '%s'", analyzer.GetLinter().PresentableName)
 	return analyzer
 }
 
+// languagesPreview returns the rows of the table of the detected languages with the linters supporting them.
+func languagesPreview(languages []string) [][]string {
+	rows := make([][]string, 0, len(languages))
+	for _, language := range languages {
+		var names []string
+		for _, linter := range product.LangsToLinters[language] {
+			names = append(names, linter.PresentableName)
+		}
+		if len(names) == 0 {
+			names = append(names, "-")
+		}
+		rows = append(rows, []string{language, strings.Join(names, ", ")})
+	}
+	return rows
+}
+
+func printLanguagesPreview(rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	data := append([][]string{{"Language", "Linters"}}, rows...)
+	if err := pterm.DefaultTable.WithHasHeader().WithData(data).Render(); err != nil {
+		log.Debugf("Failed to print the detected languages: %s", err)
+	}
+}
+
+// recommendedLinters returns the linters supporting the given languages of the project, all linters if none of them is supported.
+func recommendedLinters(path string, languages []string) []product.Linter {
+	if len(languages) == 0 {
+		return nil
+	}
+	var linters []product.Linter
+	for _, language := range languages {
+		if i, ok := product.LangsToLinters[language]; ok {
+			linters = append(linters, i...)
+		}
+	}
+	if len(linters) == 0 {
+		linters = product.AllLinters
+	}
+	// breaking change will not be backported to 241
+	if (slices.Contains(linters, product.AndroidCommunityLinter) ||
+		slices.Contains(linters, product.AndroidLinter)) &&
+		isAndroidProject(path) {
+
+		filteredLinters := make([]product.Linter, 0, len(linters))
+		for _, l := range linters {
+			if l != product.AndroidLinter && l != product.AndroidCommunityLinter {
+				filteredLinters = append(filteredLinters, l)
+			}
+		}
+		linters = append(
+			[]product.Linter{product.AndroidLinter, product.AndroidCommunityLinter},
+			filteredLinters...,
+		)
+	}
+	return algorithm.Unique(linters)
+}
+
 func filterByLicensePlan(linters []product.Linter, token string) []product.Linter {
-	if token == "" {
+	if token == "" || qdenv.IsOffline() {
 		return linters
 	}
 	cloud.SetupLicenseToken(token)
@@ -336,6 +376,7 @@ func analyzerToSelect(linters []product.Linter, path string) (map[string]product
 
 // SaveReport converts analysis output into the HTML report.
 func SaveReport(resultDir string, reportDir string, cacheDir string) {
+	defer timings.Start(timings.Conversion)()
 	log.Println("Generating HTML report ...")
 	if res, err := exec.Exec(
 		".",
@@ -347,7 +388,7 @@ func SaveReport(resultDir string, reportDir string, cacheDir string) {
 		"-o",
 		filepath.Join(reportDir, "results"),
 	); res > 0 || err != nil {
-		os.Exit(res)
+		log.StandardLogger().Exit(res)
 	}
 	unpackWebUI(cacheDir, reportDir)
 }
@@ -507,3 +507,11 @@ func TestComputeId_EmptyProjectDir(t *testing.T) {
 	assert.Equal(t, idDot, idEmpty,
 		"empty projectDir should resolve identically to '.'")
 }
+
+func TestLanguagesPreview(t *testing.T) {
+	rows := languagesPreview([]string{"Go", "Markdown"})
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "Go", rows[0][0])
+	assert.Contains(t, rows[0][1], product.GoLinter.PresentableName)
+	assert.Equal(t, []string{"Markdown", "-"}, rows[1])
+}
@@ -115,7 +115,7 @@ func computeCommon(
 	}
 
 	qodanaId := computeId(analyzer, projectDir)
-	systemDir := computeQodanaSystemDir(cacheDirFromCliOptions)
+	systemDir := ComputeQodanaSystemDir(cacheDirFromCliOptions)
 	linterDir := filepath.Join(systemDir, qodanaId)
 	resultsDir := computeResultsDir(resultsDirFromCliOptions, linterDir)
 	cacheDir := computeCacheDir(cacheDirFromCliOptions, linterDir)
@@ -165,6 +165,9 @@ func computeCommon(
 
 	commonCtx.ProjectDir = normalizedProjectDir
 	commonCtx.RepositoryRoot = normalizedRepoRoot
+	if !qdenv.IsContainer() {
+		writeLinterDirInfo(commonCtx)
+	}
 	return commonCtx
 }
 
@@ -276,7 +279,8 @@ func getHash(s string) string {
 	return hex.EncodeToString(sha256sum[:])
 }
 
-func computeQodanaSystemDir(cacheDirFromCliOptions string) string {
+// ComputeQodanaSystemDir returns the directory with the caches and distributions of all linters.
+func ComputeQodanaSystemDir(cacheDirFromCliOptions string) string {
 	if cacheDirFromCliOptions != "" {
 		return filepath.Dir(filepath.Dir(cacheDirFromCliOptions))
 	}
@@ -289,6 +293,23 @@ func computeQodanaSystemDir(cacheDirFromCliOptions string) string {
 	)
 }
 
+// ComputeProjectsResultsDir returns the top-level results directory of the scan of the projects from qodana.yaml,
+// the results of each project are kept in its subdirectory.
+func ComputeProjectsResultsDir(resultsDirFromCliOptions string, cacheDirFromCliOptions string, projectDir string) string {
+	if resultsDirFromCliOptions != "" {
+		return resultsDirFromCliOptions
+	}
+	projectAbs, err := fs.Canonical(projectDir)
+	if err != nil {
+		projectAbs = projectDir
+	}
+	return filepath.Join(
+		ComputeQodanaSystemDir(cacheDirFromCliOptions),
+		"projects-"+getHash(projectAbs)[0:8],
+		"results",
+	)
+}
+
 func computeResultsDir(resultsDirFromCliOptions string, linterDir string) string {
 	if resultsDirFromCliOptions != "" {
 		return resultsDirFromCliOptions
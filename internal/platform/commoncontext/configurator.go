@@ -55,8 +55,24 @@ func isInIgnoredDirectory(path string) bool {
 
 // recognizeDirLanguages returns the languages detected in the given directory.
 func recognizeDirLanguages(projectPath string) ([]string, error) {
+	stats, _, err := walkDirLanguages(projectPath, false)
+	if err != nil {
+		return nil, err
+	}
+	languages := make([]string, 0, len(stats))
+	for _, stat := range stats {
+		languages = append(languages, stat.Language)
+	}
+	slices.Sort(languages)
+	return languages, nil
+}
+
+// walkDirLanguages returns the files of every programming language detected in the given directory, sorted by
+// the number of files, and the frameworks detected by frameworkMarkers. The lines are counted if countLines is set.
+func walkDirLanguages(projectPath string, countLines bool) ([]LanguageStats, []string, error) {
 	const limitKb = 64
-	out := make(map[string]int)
+	out := make(map[string]*LanguageStats)
+	var frameworks []string
 	err := filepath.Walk(
 		projectPath, func(path string, f os.FileInfo, err error) error {
 			if err != nil {
@@ -80,8 +96,19 @@ func recognizeDirLanguages(projectPath string) ([]string, error) {
 			if f.IsDir() {
 				relpath += "/"
 			}
-			if isInIgnoredDirectory(path) || enry.IsVendor(relpath) || enry.IsDotFile(relpath) ||
-				enry.IsDocumentation(relpath) || enry.IsConfiguration(relpath) ||
+			if isInIgnoredDirectory(path) || enry.IsVendor(relpath) {
+				if f.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !f.IsDir() {
+				// the build files are configuration, they are checked before being skipped
+				if framework := frameworkByFile(f.Name()); framework != "" {
+					frameworks = algorithm.AppendUnique(frameworks, framework)
+				}
+			}
+			if enry.IsDotFile(relpath) || enry.IsDocumentation(relpath) || enry.IsConfiguration(relpath) ||
 				enry.IsGenerated(relpath, nil) {
 				if f.IsDir() {
 					return filepath.SkipDir
@@ -111,33 +138,39 @@ func recognizeDirLanguages(projectPath string) ([]string, error) {
 				return nil
 			}
 
-			out[language] += 1
+			stat, ok := out[language]
+			if !ok {
+				stat = &LanguageStats{Language: language}
+				out[language] = stat
+			}
+			stat.Files++
+			if countLines {
+				lines, err := countFileLines(path)
+				if err != nil {
+					log.Debugf("Failed to count lines of %s: %s", path, err)
+				}
+				stat.Lines += lines
+			}
 			return nil
 		},
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	type languageCount struct {
-		Language string
-		Count    int
-	}
-	langCounts := make([]languageCount, 0, len(out))
-	for language, count := range out {
-		langCounts = append(langCounts, languageCount{Language: language, Count: count})
+	stats := make([]LanguageStats, 0, len(out))
+	for _, stat := range out {
+		stats = append(stats, *stat)
 	}
 	sort.Slice(
-		langCounts, func(i, j int) bool {
-			return langCounts[i].Count > langCounts[j].Count
+		stats, func(i, j int) bool {
+			if stats[i].Files != stats[j].Files {
+				return stats[i].Files > stats[j].Files
+			}
+			return stats[i].Language < stats[j].Language
 		},
 	)
-	languages := make([]string, 0, len(langCounts))
-	for _, langCount := range langCounts {
-		languages = append(languages, langCount.Language)
-	}
-
-	slices.Sort(languages)
-	return languages, nil
+	slices.Sort(frameworks)
+	return stats, frameworks, nil
 }
 
 // readFile reads the file at the given path and returns its content.
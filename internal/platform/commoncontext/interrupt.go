@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+var interruption = struct {
+	sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	graceful int
+}{}
+
+// InterruptContext is the context of the command, cancelled by the first interrupt while the command stops
+// gracefully (see StopGracefullyOnInterrupt).
+func InterruptContext() context.Context {
+	interruption.Lock()
+	defer interruption.Unlock()
+	if interruption.ctx == nil {
+		interruption.ctx, interruption.cancel = context.WithCancel(context.Background())
+	}
+	return interruption.ctx
+}
+
+// StopGracefullyOnInterrupt makes the first interrupt cancel InterruptContext instead of exiting the process, until
+// the returned function is called. The code run meanwhile stops when the context is cancelled: e.g. the analysis
+// stops the linter and keeps the partial results. The second interrupt exits the process.
+func StopGracefullyOnInterrupt() func() {
+	InterruptContext()
+	interruption.Lock()
+	defer interruption.Unlock()
+	interruption.graceful++
+	var once sync.Once
+	return func() {
+		once.Do(
+			func() {
+				interruption.Lock()
+				defer interruption.Unlock()
+				interruption.graceful--
+			},
+		)
+	}
+}
+
+// CancelOnInterrupt cancels InterruptContext if the command stops gracefully, it tells the interrupt handler whether
+// the process should be left running.
+func CancelOnInterrupt() bool {
+	interruption.Lock()
+	defer interruption.Unlock()
+	if interruption.graceful == 0 || interruption.cancel == nil || interruption.ctx.Err() != nil {
+		return false
+	}
+	interruption.cancel()
+	return true
+}
+
+// Interrupt interrupts the command like Ctrl+C, e.g. when the scan is cancelled by the IDE.
+func Interrupt() {
+	select {
+	case InterruptChannel <- os.Interrupt:
+	default:
+	}
+}
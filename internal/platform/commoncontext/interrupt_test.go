@@ -0,0 +1,40 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancelOnInterrupt(t *testing.T) {
+	ctx := InterruptContext()
+	assert.False(t, CancelOnInterrupt(), "the process exits outside of the graceful stop")
+	assert.NoError(t, ctx.Err())
+
+	stopGracefully := StopGracefullyOnInterrupt()
+	stopGracefully()
+	stopGracefully()
+	assert.False(t, CancelOnInterrupt(), "the graceful stop is over")
+
+	stopGracefully = StopGracefullyOnInterrupt()
+	defer stopGracefully()
+	assert.True(t, CancelOnInterrupt())
+	assert.Error(t, ctx.Err())
+	assert.False(t, CancelOnInterrupt(), "the second interrupt exits the process")
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LanguageStats is the size of the code in a programming language detected in the project.
+type LanguageStats struct {
+	Language string `json:"language"`
+	Files    int    `json:"files"`
+	Lines    int    `json:"lines"`
+}
+
+// RecommendedLinter is a linter supporting the languages of the project.
+type RecommendedLinter struct {
+	Name            string `json:"name"`
+	PresentableName string `json:"presentableName"`
+	Image           string `json:"image"`
+}
+
+// ProjectLanguages is what qodana init detects in the project to select the linter.
+type ProjectLanguages struct {
+	Languages  []LanguageStats     `json:"languages"`
+	Frameworks []string            `json:"frameworks"`
+	Linters    []RecommendedLinter `json:"linters"`
+}
+
+// frameworkMarkers are the files telling the build system or framework of the project.
+var frameworkMarkers = map[string]string{
+	"AndroidManifest.xml": "Android",
+	"pom.xml":             "Maven",
+	"build.gradle":        "Gradle",
+	"build.gradle.kts":    "Gradle",
+	"settings.gradle":     "Gradle",
+	"settings.gradle.kts": "Gradle",
+	"build.sbt":           "sbt",
+	"package.json":        "Node.js",
+	"go.mod":              "Go modules",
+	"composer.json":       "Composer",
+	"Gemfile":             "Bundler",
+	"Cargo.toml":          "Cargo",
+	"pyproject.toml":      "Python packaging",
+	"requirements.txt":    "pip",
+	"CMakeLists.txt":      "CMake",
+}
+
+// frameworkByFile returns the framework the file belongs to, empty if the file isn't a marker.
+func frameworkByFile(name string) string {
+	if framework, ok := frameworkMarkers[name]; ok {
+		return framework
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".sln", ".csproj", ".vbproj", ".fsproj":
+		return ".NET"
+	}
+	return ""
+}
+
+// DetectProjectLanguages returns the languages of the project with their files and lines, the detected frameworks
+// and the linters qodana init offers for the project.
+func DetectProjectLanguages(projectDir string) (ProjectLanguages, error) {
+	stats, frameworks, err := walkDirLanguages(projectDir, true)
+	if err != nil {
+		return ProjectLanguages{}, err
+	}
+	languages := readIdeaDir(projectDir)
+	if len(languages) == 0 {
+		for _, stat := range stats {
+			languages = append(languages, stat.Language)
+		}
+	}
+	result := ProjectLanguages{
+		Languages:  stats,
+		Frameworks: frameworks,
+		Linters:    make([]RecommendedLinter, 0),
+	}
+	if result.Frameworks == nil {
+		result.Frameworks = make([]string, 0)
+	}
+	for _, linter := range recommendedLinters(projectDir, languages) {
+		result.Linters = append(
+			result.Linters,
+			RecommendedLinter{Name: linter.Name, PresentableName: linter.PresentableName, Image: linter.Image()},
+		)
+	}
+	return result, nil
+}
+
+// countFileLines returns the number of lines in the file, the last line is counted without the line break too.
+func countFileLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	lines := 0
+	last := byte('\n')
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
@@ -17,8 +17,12 @@
 package commoncontext
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
 )
 
 func TestDirLanguagesExcluded(t *testing.T) {
@@ -32,3 +36,40 @@ func TestDirLanguagesExcluded(t *testing.T) {
 		t.Fatalf("expected \"%s\" got \"%s\"", expected, actual)
 	}
 }
+
+func TestDetectProjectLanguages(t *testing.T) {
+	projectDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                      "module example.com/test\n",
+		"main.go":                     "package main\n\nfunc main() {}\n",
+		"util/util.go":                "package util\n\nfunc Util() {}",
+		"web/package.json":            "{}\n",
+		"node_modules/x/index.js":     "module.exports = {}\n",
+		"node_modules/x/package.json": "{}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(projectDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual, err := DetectProjectLanguages(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLanguages := []LanguageStats{{Language: "Go", Files: 2, Lines: 6}}
+	if !reflect.DeepEqual(expectedLanguages, actual.Languages) {
+		t.Errorf("expected languages %v got %v", expectedLanguages, actual.Languages)
+	}
+	expectedFrameworks := []string{"Go modules", "Node.js"}
+	if !reflect.DeepEqual(expectedFrameworks, actual.Frameworks) {
+		t.Errorf("expected frameworks %v got %v", expectedFrameworks, actual.Frameworks)
+	}
+	if len(actual.Linters) == 0 || actual.Linters[0].Name != product.GoLinter.Name {
+		t.Errorf("expected %s to be recommended, got %v", product.GoLinter.Name, actual.Linters)
+	}
+}
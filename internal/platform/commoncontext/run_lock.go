@@ -0,0 +1,351 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// The modes of --concurrent-run: what a run does when another run uses its results or cache directory.
+const (
+	ConcurrentRunWait   = "wait"
+	ConcurrentRunUnique = "unique"
+	ConcurrentRunFail   = "fail"
+)
+
+const (
+	// runLockHeartbeat is how often the holder of a lock touches the lock file.
+	runLockHeartbeat = time.Minute
+	// runLockStaleAfter is the age of a lock file not touched by its holder, after which the lock is taken over.
+	runLockStaleAfter = 10 * time.Minute
+	// runLockMaxUniqueDirs limits the results directories tried by --concurrent-run unique.
+	runLockMaxUniqueDirs = 100
+)
+
+// runLockPollInterval is how often a waiting run checks the lock.
+var runLockPollInterval = time.Second
+
+// RunLockOwner is the content of a lock file: the run holding the directory.
+type RunLockOwner struct {
+	Pid       int    `json:"pid"`
+	Host      string `json:"host"`
+	Token     string `json:"token"`
+	StartedAt string `json:"startedAt"`
+}
+
+func (o RunLockOwner) String() string {
+	return fmt.Sprintf("pid %d on %s, started at %s", o.Pid, o.Host, o.StartedAt)
+}
+
+// DirLockedError is returned when the directory is used by another run and --concurrent-run is fail.
+type DirLockedError struct {
+	Dir      string
+	LockPath string
+	Owner    RunLockOwner
+}
+
+func (e *DirLockedError) Error() string {
+	return fmt.Sprintf(
+		"%s is used by another Qodana run (%s). Wait for it to finish, run with --concurrent-run wait or unique, "+
+			"or remove %s if that run is gone",
+		e.Dir,
+		e.Owner,
+		e.LockPath,
+	)
+}
+
+type runLock struct {
+	path string
+	stop chan struct{}
+	done chan struct{}
+}
+
+var heldRunLocks struct {
+	sync.Mutex
+	locks            []*runLock
+	exitHandlerIsSet bool
+	tokenIsAssigned  bool
+	token            string
+}
+
+// runLockPath is the lock file of the directory. It's kept next to the directory: the cache directory is removed
+// with --clear-cache and on the cache import.
+func runLockPath(dir string) string {
+	return filepath.Clean(dir) + ".lock"
+}
+
+// LockRunDirs takes the locks of the results and the cache directories of the run, released by ReleaseRunLocks.
+// With --concurrent-run wait (the default) the run waits for the other run using a directory, with unique it takes
+// a new results directory next to the used one (and waits for the cache), with fail it returns DirLockedError.
+// The locks of a dead run, or of a run that stopped touching them, are taken over. The runs started by this run
+// (e.g. with the fallback linter) share its locks, a container doesn't lock its directories.
+func LockRunDirs(c Context, mode string) (Context, error) {
+	if mode == "" {
+		mode = ConcurrentRunWait
+	}
+	if mode != ConcurrentRunWait && mode != ConcurrentRunUnique && mode != ConcurrentRunFail {
+		return c, fmt.Errorf(
+			"unknown --concurrent-run %q, use %s, %s or %s",
+			mode,
+			ConcurrentRunWait,
+			ConcurrentRunUnique,
+			ConcurrentRunFail,
+		)
+	}
+	if qdenv.IsContainer() {
+		// the directories of the container are its own, the CLI locks the mounted ones on the host
+		return c, nil
+	}
+
+	resultsDir := c.ResultsDir
+	if mode == ConcurrentRunUnique {
+		for i := 1; ; i++ {
+			if i > runLockMaxUniqueDirs {
+				return c, fmt.Errorf("all results directories %s-* are used by other Qodana runs", c.ResultsDir)
+			}
+			if i > 1 {
+				resultsDir = fmt.Sprintf("%s-%d", c.ResultsDir, i)
+			}
+			err := lockRunDir(resultsDir, ConcurrentRunFail)
+			var lockedErr *DirLockedError
+			if errors.As(err, &lockedErr) {
+				continue
+			}
+			if err != nil {
+				return c, err
+			}
+			break
+		}
+		if resultsDir != c.ResultsDir {
+			msg.WarningMessage("%s is used by another Qodana run, the results are saved to %s", c.ResultsDir, resultsDir)
+			if c.ReportDir == filepath.Join(c.ResultsDir, "report") {
+				c.ReportDir = filepath.Join(resultsDir, "report")
+			}
+			c.ResultsDir = resultsDir
+		}
+	} else if err := lockRunDir(resultsDir, mode); err != nil {
+		return c, err
+	}
+
+	if filepath.Clean(c.CacheDir) != filepath.Clean(c.ResultsDir) {
+		cacheMode := mode
+		if mode == ConcurrentRunUnique {
+			cacheMode = ConcurrentRunWait
+		}
+		if err := lockRunDir(c.CacheDir, cacheMode); err != nil {
+			ReleaseRunLocks()
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// LockRunDirsOrFatal takes the locks of LockRunDirs before the run writes to the directories.
+func LockRunDirsOrFatal(c Context, mode string) Context {
+	c, err := LockRunDirs(c, mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
+// ReleaseRunLocks removes the lock files taken by this process.
+func ReleaseRunLocks() {
+	heldRunLocks.Lock()
+	locks := heldRunLocks.locks
+	heldRunLocks.locks = nil
+	heldRunLocks.Unlock()
+	for _, lock := range locks {
+		close(lock.stop)
+		<-lock.done
+		if owner, err := readRunLockOwner(lock.path); err == nil && owner.Token == runLockToken() {
+			if err = os.Remove(lock.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Warnf("Failed to remove the lock %s: %s", lock.path, err)
+			}
+		}
+	}
+}
+
+// runLockToken identifies the locks of this process and of the processes started by it, which inherit it.
+func runLockToken() string {
+	heldRunLocks.Lock()
+	defer heldRunLocks.Unlock()
+	if !heldRunLocks.tokenIsAssigned {
+		heldRunLocks.tokenIsAssigned = true
+		heldRunLocks.token = os.Getenv(qdenv.QodanaRunLockToken)
+		if heldRunLocks.token == "" {
+			b := make([]byte, 16)
+			_, _ = rand.Read(b)
+			heldRunLocks.token = hex.EncodeToString(b)
+			qdenv.SetEnv(qdenv.QodanaRunLockToken, heldRunLocks.token)
+		}
+	}
+	return heldRunLocks.token
+}
+
+// lockRunDir creates the lock file of the directory, in the wait mode it waits for the lock to be released.
+func lockRunDir(dir string, mode string) error {
+	path := runLockPath(dir)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create the lock %s: %w", path, err)
+	}
+	token := runLockToken()
+	hostname, _ := os.Hostname()
+	owner := RunLockOwner{
+		Pid:       os.Getpid(),
+		Host:      hostname,
+		Token:     token,
+		StartedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	content, err := json.MarshalIndent(owner, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	waiting := false
+	for {
+		created, err := createRunLock(path, content)
+		if err != nil {
+			return err
+		}
+		if created {
+			if waiting {
+				msg.SuccessMessage("%s is released, the run continues", dir)
+			}
+			holdRunLock(path)
+			return nil
+		}
+
+		current, err := readRunLockOwner(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			// a lock file being written or damaged, it's taken over once stale
+			log.Debugf("Failed to read the lock %s: %s", path, err)
+		}
+		switch {
+		case err == nil && current.Token == token:
+			log.Debugf("%s is locked by the parent run", dir)
+			return nil
+		case isStaleRunLock(path, current, err == nil):
+			msg.WarningMessage("Taking over the lock %s of the run that is gone (%s)", path, current)
+			removeStaleRunLock(path, current)
+			continue
+		case mode == ConcurrentRunFail:
+			return &DirLockedError{Dir: dir, LockPath: path, Owner: current}
+		}
+		if !waiting {
+			waiting = true
+			msg.WarningMessage("Waiting for another Qodana run (%s) to release %s", current, dir)
+		}
+		time.Sleep(runLockPollInterval)
+	}
+}
+
+// createRunLock creates the lock file, false if it exists.
+func createRunLock(path string, content []byte) (bool, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create the lock %s: %w", path, err)
+	}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return false, fmt.Errorf("failed to write the lock %s: %w", path, err)
+	}
+	return true, nil
+}
+
+func readRunLockOwner(path string) (RunLockOwner, error) {
+	var owner RunLockOwner
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return owner, err
+	}
+	err = json.Unmarshal(content, &owner)
+	return owner, err
+}
+
+// isStaleRunLock tells if the run holding the lock is gone: its process on this host isn't running, or it hasn't
+// touched the lock file for runLockStaleAfter, e.g. a run on another host sharing the directory.
+func isStaleRunLock(path string, owner RunLockOwner, ownerIsKnown bool) bool {
+	if ownerIsKnown {
+		if hostname, _ := os.Hostname(); owner.Host == hostname && owner.Pid > 0 && !isProcessRunning(owner.Pid) {
+			return true
+		}
+	}
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > runLockStaleAfter
+}
+
+// removeStaleRunLock removes the lock file unless it has been taken over by another run in the meantime.
+func removeStaleRunLock(path string, stale RunLockOwner) {
+	if current, err := readRunLockOwner(path); err == nil && current != stale {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("Failed to remove the lock %s: %s", path, err)
+	}
+}
+
+// holdRunLock keeps touching the lock file until it's released, so the other runs see this run is alive.
+func holdRunLock(path string) {
+	lock := &runLock{path: path, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(lock.done)
+		ticker := time.NewTicker(runLockHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lock.stop:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				if err := os.Chtimes(path, now, now); err != nil {
+					log.Debugf("Failed to touch the lock %s: %s", path, err)
+				}
+			}
+		}
+	}()
+
+	heldRunLocks.Lock()
+	defer heldRunLocks.Unlock()
+	heldRunLocks.locks = append(heldRunLocks.locks, lock)
+	if !heldRunLocks.exitHandlerIsSet {
+		heldRunLocks.exitHandlerIsSet = true
+		log.RegisterExitHandler(ReleaseRunLocks)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commoncontext
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runLockTestContext(t *testing.T) Context {
+	dir := t.TempDir()
+	return Context{
+		ResultsDir: filepath.Join(dir, "results"),
+		ReportDir:  filepath.Join(dir, "results", "report"),
+		CacheDir:   filepath.Join(dir, "cache"),
+	}
+}
+
+func writeOtherRunLock(t *testing.T, dir string, owner RunLockOwner) {
+	content, err := json.Marshal(owner)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(runLockPath(dir), content, 0o644))
+}
+
+func aliveOtherRun() RunLockOwner {
+	hostname, _ := os.Hostname()
+	return RunLockOwner{Pid: os.Getpid(), Host: hostname, Token: "other", StartedAt: "2024-01-01T00:00:00Z"}
+}
+
+func TestLockRunDirs(t *testing.T) {
+	c := runLockTestContext(t)
+
+	locked, err := LockRunDirs(c, ConcurrentRunFail)
+	assert.NoError(t, err)
+	assert.Equal(t, c, locked)
+	assert.FileExists(t, runLockPath(c.ResultsDir))
+	assert.FileExists(t, runLockPath(c.CacheDir))
+	owner, err := readRunLockOwner(runLockPath(c.ResultsDir))
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getpid(), owner.Pid)
+
+	// the runs started by this run share its locks
+	_, err = LockRunDirs(c, ConcurrentRunFail)
+	assert.NoError(t, err)
+
+	ReleaseRunLocks()
+	assert.NoFileExists(t, runLockPath(c.ResultsDir))
+	assert.NoFileExists(t, runLockPath(c.CacheDir))
+}
+
+func TestLockRunDirsFail(t *testing.T) {
+	c := runLockTestContext(t)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(c.ResultsDir), os.ModePerm))
+	writeOtherRunLock(t, c.CacheDir, aliveOtherRun())
+
+	_, err := LockRunDirs(c, ConcurrentRunFail)
+	var lockedErr *DirLockedError
+	assert.True(t, errors.As(err, &lockedErr))
+	assert.Equal(t, c.CacheDir, lockedErr.Dir)
+	assert.ErrorContains(t, err, "is used by another Qodana run")
+	// the lock of the results directory taken before is released
+	assert.NoFileExists(t, runLockPath(c.ResultsDir))
+	assert.FileExists(t, runLockPath(c.CacheDir))
+}
+
+func TestLockRunDirsUnique(t *testing.T) {
+	c := runLockTestContext(t)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(c.ResultsDir), os.ModePerm))
+	writeOtherRunLock(t, c.ResultsDir, aliveOtherRun())
+	writeOtherRunLock(t, c.ResultsDir+"-2", aliveOtherRun())
+	defer ReleaseRunLocks()
+
+	locked, err := LockRunDirs(c, ConcurrentRunUnique)
+	assert.NoError(t, err)
+	assert.Equal(t, c.ResultsDir+"-3", locked.ResultsDir)
+	assert.Equal(t, filepath.Join(c.ResultsDir+"-3", "report"), locked.ReportDir)
+	assert.Equal(t, c.CacheDir, locked.CacheDir)
+	assert.FileExists(t, runLockPath(locked.ResultsDir))
+}
+
+func TestLockRunDirsWait(t *testing.T) {
+	pollInterval := runLockPollInterval
+	runLockPollInterval = 10 * time.Millisecond
+	defer func() { runLockPollInterval = pollInterval }()
+
+	c := runLockTestContext(t)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(c.ResultsDir), os.ModePerm))
+	writeOtherRunLock(t, c.ResultsDir, aliveOtherRun())
+	released := time.Now().Add(50 * time.Millisecond)
+	go func() {
+		time.Sleep(time.Until(released))
+		_ = os.Remove(runLockPath(c.ResultsDir))
+	}()
+	defer ReleaseRunLocks()
+
+	_, err := LockRunDirs(c, ConcurrentRunWait)
+	assert.NoError(t, err)
+	assert.False(t, time.Now().Before(released))
+	owner, err := readRunLockOwner(runLockPath(c.ResultsDir))
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getpid(), owner.Pid)
+}
+
+func TestLockRunDirsStale(t *testing.T) {
+	finished := exec.Command(os.Args[0], "-test.run=^$")
+	assert.NoError(t, finished.Run())
+
+	c := runLockTestContext(t)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(c.ResultsDir), os.ModePerm))
+	deadRun := aliveOtherRun()
+	deadRun.Pid = finished.Process.Pid
+	writeOtherRunLock(t, c.ResultsDir, deadRun)
+	silentRun := aliveOtherRun()
+	silentRun.Host = "another-host"
+	writeOtherRunLock(t, c.CacheDir, silentRun)
+	old := time.Now().Add(-2 * runLockStaleAfter)
+	assert.NoError(t, os.Chtimes(runLockPath(c.CacheDir), old, old))
+	defer ReleaseRunLocks()
+
+	_, err := LockRunDirs(c, ConcurrentRunFail)
+	assert.NoError(t, err)
+	for _, dir := range []string{c.ResultsDir, c.CacheDir} {
+		owner, err := readRunLockOwner(runLockPath(dir))
+		assert.NoError(t, err)
+		assert.Equal(t, os.Getpid(), owner.Pid)
+	}
+}
+
+func TestLockRunDirsUnknownMode(t *testing.T) {
+	_, err := LockRunDirs(runLockTestContext(t), "queue")
+	assert.ErrorContains(t, err, "unknown --concurrent-run")
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	"github.com/JetBrains/qodana-cli/internal/tooling"
 	log "github.com/sirupsen/logrus"
@@ -40,6 +41,9 @@ type Files struct {
 	QodanaConfigJsonPath    string
 }
 
+// CreateEffectiveConfigFiles runs config-loader-cli to resolve the configuration of the project into
+// effectiveConfigDir. Of a multi-document qodana.yaml, the document specific to the linter with one of the names is
+// resolved, see qdyaml.ParseQodanaYaml.
 func CreateEffectiveConfigFiles(
 	cacheDir string,
 	localQodanaYamlFullPath string,
@@ -47,7 +51,9 @@ func CreateEffectiveConfigFiles(
 	globalConfigId string,
 	effectiveConfigDir string,
 	logDir string,
+	names ...string,
 ) (Files, error) {
+	defer timings.Start(timings.ConfigResolution)()
 	if globalConfigId != "" && globalConfigurationsDir == "" {
 		return Files{}, fmt.Errorf(
 			"global configuration id %s is defined without global cofigurations directory",
@@ -62,7 +68,6 @@ func CreateEffectiveConfigFiles(
 	}
 	globalConfigurationsFile := ""
 	if globalConfigurationsDir != "" {
-		globalConfigurationsYamlFilename := "qodana-global-configurations.yaml"
 		globalConfigurationsFile = filepath.Join(globalConfigurationsDir, globalConfigurationsYamlFilename)
 		if !isFileExists(globalConfigurationsFile) {
 			msg.ErrorMessage(
@@ -74,9 +79,15 @@ func CreateEffectiveConfigFiles(
 		}
 	}
 
+	loadedQodanaYamlPath, removeLoadedQodanaYaml, err := selectLinterDocument(localQodanaYamlFullPath, names)
+	if err != nil {
+		return Files{}, fmt.Errorf("failed to select the configuration of the linter in %s: %w", localQodanaYamlFullPath, err)
+	}
+	defer removeLoadedQodanaYaml()
+
 	args, err := configurationLoaderCliArgs(
 		cacheDir,
-		localQodanaYamlFullPath,
+		loadedQodanaYamlPath,
 		globalConfigurationsFile,
 		globalConfigId,
 		effectiveConfigDir,
@@ -100,6 +111,24 @@ func CreateEffectiveConfigFiles(
 	if err != nil {
 		return Files{}, err
 	}
+	base, err := qdyaml.LoadBaseQodanaYamlNode(localQodanaYamlFullPath, names...)
+	if err != nil {
+		msg.ErrorMessage("Failed to load the configuration %s extends.", localQodanaYamlFullPath)
+		return Files{}, err
+	}
+	for _, path := range []string{effectiveQodanaYamlData.EffectiveQodanaYamlPath, effectiveQodanaYamlData.LocalQodanaYamlPath} {
+		if path == "" {
+			continue
+		}
+		if base != nil {
+			if err = qdyaml.ExtendQodanaYamlFile(path, base); err != nil {
+				return Files{}, fmt.Errorf("failed to merge the extended configuration into %s: %w", path, err)
+			}
+		}
+		if err = qdyaml.ExpandEnvInFile(path); err != nil {
+			return Files{}, fmt.Errorf("failed to expand environment variables in %s: %w", path, err)
+		}
+	}
 
 	err = verifyEffectiveQodanaYamlIdeAndLinterMatchLocal(effectiveQodanaYamlData, localQodanaYamlFullPath)
 	if err != nil {
@@ -109,6 +138,44 @@ func CreateEffectiveConfigFiles(
 	return effectiveQodanaYamlData, nil
 }
 
+// selectLinterDocument writes the document of a multi-document qodana.yaml specific to the linter to a temporary
+// file next to it, config-loader-cli loads a single document and resolves the relative paths from the same
+// directory. It returns the qodana.yaml to load and the function removing the temporary file.
+func selectLinterDocument(localQodanaYamlFullPath string, names []string) (string, func(), error) {
+	if localQodanaYamlFullPath == "" {
+		return "", func() {}, nil
+	}
+	data, err := os.ReadFile(localQodanaYamlFullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return localQodanaYamlFullPath, func() {}, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	selected, isMultiDocument, err := qdyaml.SelectQodanaYamlDocument(data, names...)
+	if err != nil || !isMultiDocument {
+		return localQodanaYamlFullPath, func() {}, err
+	}
+	file, err := os.CreateTemp(filepath.Dir(localQodanaYamlFullPath), ".qodana-linter-*.yaml")
+	if err != nil {
+		return "", nil, err
+	}
+	remove := func() {
+		if err := os.Remove(file.Name()); err != nil {
+			log.Debugf("Failed to remove %s: %s", file.Name(), err)
+		}
+	}
+	_, err = file.Write(selected)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return "", nil, err
+	}
+	return file.Name(), remove, nil
+}
+
 func configurationLoaderCliArgs(
 	cacheDir string,
 	localQodanaYamlPath string,
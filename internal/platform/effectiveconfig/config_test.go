@@ -17,7 +17,9 @@
 package effectiveconfig
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,6 +27,7 @@ import (
 
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSuccess(t *testing.T) {
@@ -108,6 +111,46 @@ func TestSuccess(t *testing.T) {
 	}
 }
 
+func TestSelectLinterDocument(t *testing.T) {
+	dir := t.TempDir()
+	localQodanaYaml := filepath.Join(dir, "qodana.yaml")
+	content := `version: "1.0"
+profile:
+  name: qodana.recommended
+---
+linter: jetbrains/qodana-jvm:latest
+exclude: &excluded
+  - name: All
+    paths: [build]
+---
+linter: jetbrains/qodana-python:latest
+failThreshold: 1
+`
+	assert.NoError(t, os.WriteFile(localQodanaYaml, []byte(content), 0o644))
+
+	path, remove, err := selectLinterDocument(localQodanaYaml, []string{"jetbrains/qodana-jvm:latest"})
+	assert.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var document map[string]any
+	assert.NoError(t, decoder.Decode(&document))
+	assert.ErrorIs(t, decoder.Decode(&map[string]any{}), io.EOF, "the effective qodana.yaml has one document")
+	assert.Equal(t, "jetbrains/qodana-jvm:latest", document["linter"])
+	assert.Equal(t, "qodana.recommended", document["profile"].(map[string]any)["name"])
+	assert.NotContains(t, document, "failThreshold")
+	remove()
+	assert.NoFileExists(t, path)
+
+	assert.NoError(t, os.WriteFile(localQodanaYaml, []byte("linter: jetbrains/qodana-jvm:latest\n"), 0o644))
+	path, remove, err = selectLinterDocument(localQodanaYaml, []string{"jetbrains/qodana-jvm:latest"})
+	assert.NoError(t, err)
+	assert.Equal(t, localQodanaYaml, path)
+	remove()
+	assert.FileExists(t, localQodanaYaml)
+}
+
 func TestError(t *testing.T) {
 	assert.Equal(t, true, utils.IsInstalled("java"))
 	workingDir, err := os.Getwd()
@@ -0,0 +1,391 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package effectiveconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"gopkg.in/yaml.v3"
+)
+
+const globalConfigurationsYamlFilename = "qodana-global-configurations.yaml"
+
+// Override is a value of the effective configuration set by a command line option or an environment variable.
+type Override struct {
+	// Path is the location of the value in qodana.yaml, e.g. profile, name
+	Path []string
+	// Value replaces the value at Path, mappings are replaced as a whole
+	Value any
+	// Source is the option or the variable setting the value, e.g. --profile-name
+	Source string
+}
+
+// Annotated is the effective configuration with the source of every value: the file and the line, the option or
+// the variable.
+type Annotated struct {
+	root    *yaml.Node
+	sources map[*yaml.Node]string
+}
+
+// Resolve computes the effective configuration without config-loader-cli: the qodana.yaml of the global
+// configuration, the files from `imports` and the local qodana.yaml are merged in this order, the later ones win. The
+// configuration a file extends is merged under its imports.
+// Mappings are merged key by key and lists are concatenated like config-loader-cli does, then the overrides are set
+// on top. The document of a multi-document file is selected by the names, see qdyaml.ParseQodanaYaml.
+func Resolve(
+	projectDir string,
+	localQodanaYamlFullPath string,
+	globalConfigurationsDir string,
+	globalConfigId string,
+	names []string,
+	overrides []Override,
+) (*Annotated, error) {
+	r := &provenanceResolver{
+		names:   names,
+		sources: make(map[*yaml.Node]string),
+		loading: make(map[string]bool),
+	}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if globalConfigurationsDir != "" {
+		globalQodanaYaml, err := findGlobalQodanaYaml(globalConfigurationsDir, globalConfigId)
+		if err != nil {
+			return nil, err
+		}
+		global, err := r.load(
+			globalQodanaYaml,
+			fmt.Sprintf("global configuration %s: ", globalConfigId),
+			globalConfigurationsDir,
+		)
+		if err != nil {
+			return nil, err
+		}
+		root = qdyaml.MergeLayers(root, global)
+	}
+	if localQodanaYamlFullPath != "" {
+		local, err := r.load(localQodanaYamlFullPath, "", projectDir)
+		if err != nil {
+			return nil, err
+		}
+		root = qdyaml.MergeLayers(root, local)
+	}
+	for _, override := range overrides {
+		if err := r.override(root, override); err != nil {
+			return nil, err
+		}
+	}
+	return &Annotated{root: root, sources: r.sources}, nil
+}
+
+type provenanceResolver struct {
+	names   []string
+	sources map[*yaml.Node]string
+	// loading are the files being loaded, to detect import cycles
+	loading map[string]bool
+}
+
+// load returns the configuration of the file merged on top of the configuration it extends and its imports, the
+// sources are the paths relative to baseDir (or the URLs) prefixed with the label.
+func (r *provenanceResolver) load(path string, label string, baseDir string) (*yaml.Node, error) {
+	absPath := path
+	if !qdyaml.IsRemoteLocation(path) {
+		var err error
+		if absPath, err = filepath.Abs(path); err != nil {
+			return nil, err
+		}
+	}
+	if r.loading[absPath] {
+		return nil, fmt.Errorf("%s imports itself", path)
+	}
+	r.loading[absPath] = true
+	defer delete(r.loading, absPath)
+
+	data, err := qdyaml.ReadLocation(path)
+	if err != nil {
+		return nil, err
+	}
+	node, err := qdyaml.ParseQodanaYamlNode(data, r.names...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	r.recordSources(node, label+displayPath(baseDir, path))
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	extends, err := qdyaml.ExtendsLocation(node, path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if extends != "" {
+		base, err := r.load(extends, label, baseDir)
+		if err != nil {
+			return nil, err
+		}
+		merged = base
+	}
+	own := &yaml.Node{Kind: node.Kind, Tag: node.Tag}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value == qdyaml.ExtendsKey {
+			continue
+		}
+		if key.Value != "imports" {
+			own.Content = append(own.Content, key, value)
+			continue
+		}
+		var imports []string
+		if err = value.Decode(&imports); err != nil {
+			return nil, fmt.Errorf("%s:%d: imports must be a list of paths", path, value.Line)
+		}
+		for _, imported := range imports {
+			if !filepath.IsAbs(imported) {
+				imported = filepath.Join(filepath.Dir(path), imported)
+			}
+			importedNode, err := r.load(imported, label, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			merged = qdyaml.MergeLayers(merged, importedNode)
+		}
+	}
+	return qdyaml.MergeLayers(merged, own), nil
+}
+
+// recordSources sets the source of the scalar values of the node, their lines are in the file.
+func (r *provenanceResolver) recordSources(node *yaml.Node, file string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if _, ok := r.sources[node]; !ok {
+			r.sources[node] = fmt.Sprintf("%s:%d", file, node.Line)
+		}
+	case yaml.MappingNode, yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			r.recordSources(child, file)
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			r.recordSources(node.Alias, file)
+		}
+	}
+}
+
+func displayPath(baseDir string, path string) string {
+	if rel, err := filepath.Rel(baseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func (r *provenanceResolver) override(root *yaml.Node, override Override) error {
+	value := &yaml.Node{}
+	if err := value.Encode(override.Value); err != nil {
+		return fmt.Errorf("failed to apply %s: %w", override.Source, err)
+	}
+	var setSource func(node *yaml.Node)
+	setSource = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode {
+			r.sources[node] = override.Source
+		}
+		for _, child := range node.Content {
+			setSource(child)
+		}
+	}
+	setSource(value)
+
+	mapping := root
+	for i, key := range override.Path {
+		j := slices.IndexFunc(mappingKeys(mapping), func(k *yaml.Node) bool { return k.Value == key })
+		if i == len(override.Path)-1 {
+			if j < 0 {
+				mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+			} else {
+				mapping.Content[2*j+1] = value
+			}
+			break
+		}
+		if j < 0 || resolveAlias(mapping.Content[2*j+1]).Kind != yaml.MappingNode {
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if j < 0 {
+				mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+			} else {
+				mapping.Content[2*j+1] = child
+			}
+			mapping = child
+			continue
+		}
+		// the merged mapping can be shared with the source files, it is copied before the change
+		child := *resolveAlias(mapping.Content[2*j+1])
+		child.Content = slices.Clone(child.Content)
+		mapping.Content[2*j+1] = &child
+		mapping = &child
+	}
+	return nil
+}
+
+// Yaml returns the effective configuration with the source of every value in its line comment.
+func (a *Annotated) Yaml() ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(a.materialize(a.root, "", nil)); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Json returns the effective configuration and the sources of the values by their paths, e.g. include[0].name.
+func (a *Annotated) Json() ([]byte, error) {
+	sources := make(map[string]string)
+	var config any
+	if err := a.materialize(a.root, "", sources).Decode(&config); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = map[string]any{}
+	}
+	return json.MarshalIndent(map[string]any{"config": config, "sources": sources}, "", "  ")
+}
+
+// materialize returns a copy of the node with the aliases and the merge keys resolved, the comments of the source
+// files are replaced with the sources of the values. The sources by the paths are saved to paths, if not nil.
+func (a *Annotated) materialize(node *yaml.Node, path string, paths map[string]string) *yaml.Node {
+	node = resolveAlias(node)
+	result := &yaml.Node{Kind: node.Kind, Tag: node.Tag, Value: node.Value, Style: node.Style &^ yaml.FlowStyle}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if source, ok := a.sources[node]; ok {
+			result.LineComment = "from " + source
+			if paths != nil {
+				paths[path] = source
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			result.Content = append(result.Content, a.materialize(item, fmt.Sprintf("%s[%d]", path, i), paths))
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			// the keys starting with x- only hold the anchors reused in the file
+			if key.Value == "<<" || (path == "" && strings.HasPrefix(key.Value, "x-")) {
+				continue
+			}
+			keyPath := key.Value
+			if path != "" {
+				keyPath = path + "." + key.Value
+			}
+			result.Content = append(
+				result.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: key.Tag, Value: key.Value},
+				a.materialize(value, keyPath, paths),
+			)
+		}
+		for _, merged := range mergeKeyMappings(node) {
+			for i := 0; i+1 < len(merged.Content); i += 2 {
+				key := merged.Content[i]
+				if slices.ContainsFunc(mappingKeys(result), func(k *yaml.Node) bool { return k.Value == key.Value }) {
+					continue
+				}
+				keyPath := key.Value
+				if path != "" {
+					keyPath = path + "." + key.Value
+				}
+				result.Content = append(
+					result.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: key.Tag, Value: key.Value},
+					a.materialize(merged.Content[i+1], keyPath, paths),
+				)
+			}
+		}
+	}
+	return result
+}
+
+// mergeKeyMappings returns the mappings merged into the node with `<<`, in the order of precedence.
+func mergeKeyMappings(node *yaml.Node) []*yaml.Node {
+	var mappings []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "<<" {
+			continue
+		}
+		value := resolveAlias(node.Content[i+1])
+		if value.Kind == yaml.MappingNode {
+			mappings = append(mappings, value)
+			continue
+		}
+		for _, item := range value.Content {
+			if item = resolveAlias(item); item.Kind == yaml.MappingNode {
+				mappings = append(mappings, item)
+			}
+		}
+	}
+	return mappings
+}
+
+func mappingKeys(mapping *yaml.Node) []*yaml.Node {
+	keys := make([]*yaml.Node, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keys = append(keys, mapping.Content[i])
+	}
+	return keys
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// findGlobalQodanaYaml returns the qodana.yaml of the global configuration with the id.
+func findGlobalQodanaYaml(globalConfigurationsDir string, globalConfigId string) (string, error) {
+	path := filepath.Join(globalConfigurationsDir, globalConfigurationsYamlFilename)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var globalConfigurations struct {
+		Configurations []struct {
+			Id         string `yaml:"id"`
+			QodanaYaml string `yaml:"qodanaYaml"`
+		} `yaml:"configurations"`
+	}
+	if err = yaml.Unmarshal(data, &globalConfigurations); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, configuration := range globalConfigurations.Configurations {
+		if configuration.Id != globalConfigId {
+			continue
+		}
+		if filepath.IsAbs(configuration.QodanaYaml) {
+			return configuration.QodanaYaml, nil
+		}
+		return filepath.Join(globalConfigurationsDir, configuration.QodanaYaml), nil
+	}
+	return "", fmt.Errorf("global configuration %s is not defined in %s", globalConfigId, path)
+}
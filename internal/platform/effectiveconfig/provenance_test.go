@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package effectiveconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	configurationDir := filepath.Join("testdata", "local and global input qodana yaml", "configuration")
+	projectDir := filepath.Join(configurationDir, "local")
+	annotated, err := Resolve(
+		projectDir,
+		filepath.Join(projectDir, "qodana.yaml"),
+		filepath.Join(configurationDir, "global"),
+		"main",
+		nil,
+		[]Override{
+			{Path: []string{"profile"}, Value: map[string]string{"name": "qodana.starter"}, Source: "--profile-name"},
+			{Path: []string{"properties", "idea.max.intellisense.filesize"}, Value: "5000", Source: "--property"},
+		},
+	)
+	assert.NoError(t, err)
+
+	data, err := annotated.Yaml()
+	assert.NoError(t, err)
+	assert.Equal(
+		t, `version: 1.0 # from qodana.yaml:1
+profile:
+  name: qodana.starter # from --profile-name
+failureConditions:
+  severityThresholds:
+    critical: 2 # from qodana.yaml:16
+    moderate: 3 # from global configuration main: files/qodana.yaml:9
+    any: 1 # from qodana.yaml:15
+include:
+  - name: InspectionC # from global configuration main: files/qodana.yaml:13
+  - name: InspectionD # from global configuration main: files/qodana.yaml:14
+  - name: InspectionA # from qodana.yaml:8
+  - name: InspectionB # from qodana.yaml:9
+properties:
+  idea.max.intellisense.filesize: "5000" # from --property
+`, string(data),
+	)
+
+	data, err = annotated.Json()
+	assert.NoError(t, err)
+	var effective struct {
+		Sources map[string]string `json:"sources"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &effective))
+	assert.Equal(t, "qodana.yaml:8", effective.Sources["include[2].name"])
+	assert.Equal(t, "--profile-name", effective.Sources["profile.name"])
+}
+
+func TestResolveImports(t *testing.T) {
+	projectDir := t.TempDir()
+	files := map[string]string{
+		"qodana.yaml": `version: "1.0"
+imports:
+  - shared/base.yaml
+x-paths: &generated
+  - build
+exclude:
+  - name: All
+    paths: *generated
+---
+linter: qodana-jvm
+---
+linter: qodana-js
+`,
+		"shared/base.yaml": "profile:\n  name: qodana.recommended\nexclude:\n  - name: JavaDoc\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(projectDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	annotated, err := Resolve(projectDir, filepath.Join(projectDir, "qodana.yaml"), "", "", []string{"qodana-js"}, nil)
+	assert.NoError(t, err)
+	data, err := annotated.Yaml()
+	assert.NoError(t, err)
+	assert.Equal(
+		t, `profile:
+  name: qodana.recommended # from shared/base.yaml:2
+exclude:
+  - name: JavaDoc # from shared/base.yaml:4
+  - name: All # from qodana.yaml:7
+    paths:
+      - build # from qodana.yaml:5
+version: "1.0" # from qodana.yaml:1
+linter: qodana-js # from qodana.yaml:12
+`, string(data),
+	)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "shared/base.yaml"), []byte("imports:\n  - ../qodana.yaml\n"), 0o644))
+	_, err = Resolve(projectDir, filepath.Join(projectDir, "qodana.yaml"), "", "", nil, nil)
+	assert.ErrorContains(t, err, "imports itself")
+}
+
+func TestResolveExtends(t *testing.T) {
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "project")
+	files := map[string]string{
+		"project/qodana.yaml": `version: "1.0"
+extends: ../org/base.yaml
+imports:
+  - shared.yaml
+exclude:
+  - name: All
+`,
+		"project/shared.yaml": "exclude:\n  - name: JavaDoc\n",
+		"org/base.yaml":       "linter: jetbrains/qodana-jvm\nexclude:\n  - name: Deprecated\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	annotated, err := Resolve(projectDir, filepath.Join(projectDir, "qodana.yaml"), "", "", nil, nil)
+	assert.NoError(t, err)
+	data, err := annotated.Yaml()
+	assert.NoError(t, err)
+	assert.Equal(
+		t, `linter: jetbrains/qodana-jvm # from `+filepath.Join(dir, "org", "base.yaml")+`:1
+exclude:
+  - name: Deprecated # from `+filepath.Join(dir, "org", "base.yaml")+`:3
+  - name: JavaDoc # from shared.yaml:2
+  - name: All # from qodana.yaml:6
+version: "1.0" # from qodana.yaml:1
+`, string(data),
+	)
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package effectiveconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/archive"
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// QodanaGlobalConfigTtl overrides how long a fetched remote global configurations directory is used before it's
+// fetched again, a Go duration like 30m.
+const QodanaGlobalConfigTtl = "QODANA_GLOBAL_CONFIG_TTL"
+
+const (
+	defaultRemoteGlobalConfigurationsTtl = time.Hour
+	remoteGlobalConfigurationsDirName    = "global-configurations"
+	remoteGlobalConfigurationsMarker     = ".qodana-fetched"
+	remoteArchiveDownloadTimeout         = 5 * time.Minute
+)
+
+// IsRemoteGlobalConfigurations reports whether the global configurations directory is given by a git URL or by
+// the URL of a .zip or .tar.gz archive instead of a local path.
+func IsRemoteGlobalConfigurations(location string) bool {
+	return isGitLocation(location) || isArchiveLocation(location)
+}
+
+// isGitLocation reports whether the location is a git repository: git@host:path, ssh://, git://, git+https:// or
+// an HTTP(S) URL ending with .git, optionally followed by #ref.
+func isGitLocation(location string) bool {
+	for _, prefix := range []string{"git@", "ssh://", "git://", "git+https://", "git+http://"} {
+		if strings.HasPrefix(location, prefix) {
+			return true
+		}
+	}
+	repository, _ := splitGitRef(location)
+	return isHttpLocation(repository) && strings.HasSuffix(repository, ".git")
+}
+
+// isArchiveLocation reports whether the location is the HTTP(S) URL of a .zip, .tar.gz or .tgz archive.
+func isArchiveLocation(location string) bool {
+	if !isHttpLocation(location) {
+		return false
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	return archiveExtension(u.Path) != ""
+}
+
+func isHttpLocation(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
+func archiveExtension(path string) string {
+	for _, extension := range []string{".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(path, extension) {
+			return extension
+		}
+	}
+	return ""
+}
+
+// splitGitRef splits repository#ref to the repository and the branch or the tag to check out.
+func splitGitRef(location string) (string, string) {
+	repository, ref, _ := strings.Cut(location, "#")
+	return strings.TrimPrefix(repository, "git+"), ref
+}
+
+// remoteGlobalConfigurationsTtl returns how long a fetched global configurations directory is fresh.
+func remoteGlobalConfigurationsTtl() time.Duration {
+	value := os.Getenv(QodanaGlobalConfigTtl)
+	if value == "" {
+		return defaultRemoteGlobalConfigurationsTtl
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		log.Warnf("Ignoring %s=%q, it's not a duration", QodanaGlobalConfigTtl, value)
+		return defaultRemoteGlobalConfigurationsTtl
+	}
+	return ttl
+}
+
+// FetchGlobalConfigurations returns the local directory with the global configurations from the location: local
+// paths are returned as is, git repositories are cloned and archives are downloaded and extracted to
+// <systemDir>/global-configurations. The fetched copy is reused until it's older than QODANA_GLOBAL_CONFIG_TTL
+// (an hour by default). If it can't be refreshed, or the run is offline, the cached copy is used.
+func FetchGlobalConfigurations(location string, systemDir string, logDir string) (string, error) {
+	if !IsRemoteGlobalConfigurations(location) {
+		return location, nil
+	}
+	sum := sha256.Sum256([]byte(location))
+	cacheDir := filepath.Join(systemDir, remoteGlobalConfigurationsDirName, hex.EncodeToString(sum[:])[0:16])
+	marker := filepath.Join(cacheDir, remoteGlobalConfigurationsMarker)
+
+	fetchedAt := time.Time{}
+	if info, err := os.Stat(marker); err == nil {
+		fetchedAt = info.ModTime()
+	}
+	cached := !fetchedAt.IsZero()
+	if cached && (qdenv.IsOffline() || time.Since(fetchedAt) < remoteGlobalConfigurationsTtl()) {
+		log.Debugf("Using global configurations from %s fetched at %s", location, fetchedAt.Format(time.RFC3339))
+		return globalConfigurationsRoot(filepath.Join(cacheDir, "content")), nil
+	}
+	if qdenv.IsOffline() {
+		return "", fmt.Errorf("global configurations %s are not cached and can't be fetched in the offline mode", location)
+	}
+
+	err := fetchRemoteGlobalConfigurations(location, cacheDir, logDir)
+	if err != nil {
+		if cached {
+			log.Warnf("Failed to refresh global configurations from %s, using the copy fetched at %s: %s",
+				location, fetchedAt.Format(time.RFC3339), err)
+			return globalConfigurationsRoot(filepath.Join(cacheDir, "content")), nil
+		}
+		return "", fmt.Errorf("failed to fetch global configurations from %s: %w", location, err)
+	}
+	return globalConfigurationsRoot(filepath.Join(cacheDir, "content")), nil
+}
+
+// fetchRemoteGlobalConfigurations fetches the location to a new directory and replaces <cacheDir>/content with it,
+// so an interrupted fetch never leaves a partial copy.
+func fetchRemoteGlobalConfigurations(location string, cacheDir string, logDir string) error {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return err
+	}
+	fetchDir, err := os.MkdirTemp(cacheDir, "fetch-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(fetchDir) }()
+
+	content := filepath.Join(fetchDir, "content")
+	if isGitLocation(location) {
+		repository, ref := splitGitRef(location)
+		log.Infof("Cloning global configurations from %s", repository)
+		err = git.CloneShallow(repository, ref, content, logDir)
+	} else {
+		log.Infof("Downloading global configurations from %s", location)
+		err = downloadGlobalConfigurationsArchive(location, fetchDir, content)
+	}
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(cacheDir, "content")
+	if err = os.RemoveAll(target); err != nil {
+		return err
+	}
+	if err = os.Rename(content, target); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(cacheDir, remoteGlobalConfigurationsMarker), []byte(location+"\n"), 0o644)
+}
+
+func downloadGlobalConfigurationsArchive(location string, fetchDir string, destDir string) error {
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	archivePath := filepath.Join(fetchDir, "archive"+archiveExtension(u.Path))
+
+	client := &http.Client{Timeout: remoteArchiveDownloadTimeout}
+	resp, err := client.Get(location)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", location, resp.Status)
+	}
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, resp.Body)
+	if err = errors.Join(err, out.Close()); err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(archivePath, ".zip") {
+		return extractZip(archivePath, destDir)
+	}
+	return archive.ExtractTarGz(archivePath, destDir, false)
+}
+
+func extractZip(archivePath string, destDir string) error {
+	var extractErr error
+	err := archive.WalkZipArchive(
+		archivePath, func(path string, info os.FileInfo, contents io.Reader) {
+			if extractErr != nil {
+				return
+			}
+			target := filepath.Join(destDir, path)
+			if info.IsDir() {
+				extractErr = os.MkdirAll(target, 0o755)
+				return
+			}
+			if extractErr = os.MkdirAll(filepath.Dir(target), 0o755); extractErr != nil {
+				return
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm()|0o600)
+			if err != nil {
+				extractErr = err
+				return
+			}
+			_, err = io.Copy(out, contents)
+			extractErr = errors.Join(err, out.Close())
+		},
+	)
+	return errors.Join(err, extractErr)
+}
+
+// globalConfigurationsRoot returns the directory with qodana-global-configurations.yaml: archives of repositories
+// (like the ones GitHub serves) put everything in a single top-level directory.
+func globalConfigurationsRoot(dir string) string {
+	if isFileExists(filepath.Join(dir, globalConfigurationsYamlFilename)) {
+		return dir
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package effectiveconfig
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRemoteGlobalConfigurations(t *testing.T) {
+	for location, expected := range map[string]bool{
+		"global":                      false,
+		"/opt/qodana/global":          false,
+		"https://example.com/qodana/": false,
+		"https://git.example.com/qodana-config.git":    true,
+		"https://git.example.com/qodana-config.git#v1": true,
+		"git@git.example.com:qodana-config.git":        true,
+		"ssh://git@git.example.com/qodana-config":      true,
+		"git+https://git.example.com/qodana-config":    true,
+		"https://example.com/qodana-config.zip":        true,
+		"https://example.com/qodana-config.tar.gz?x=1": true,
+		"https://example.com/qodana-config.tgz":        true,
+	} {
+		assert.Equal(t, expected, IsRemoteGlobalConfigurations(location), location)
+	}
+}
+
+func TestSplitGitRef(t *testing.T) {
+	repository, ref := splitGitRef("git+https://git.example.com/qodana-config#release")
+	assert.Equal(t, "https://git.example.com/qodana-config", repository)
+	assert.Equal(t, "release", ref)
+}
+
+func globalConfigurationsZip(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	file, err := writer.Create("qodana-config-main/" + globalConfigurationsYamlFilename)
+	require.NoError(t, err)
+	_, err = file.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestFetchGlobalConfigurationsArchive(t *testing.T) {
+	var requests atomic.Int32
+	content := "version: \"1.0\"\n"
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				_, _ = w.Write(globalConfigurationsZip(t, content))
+			},
+		),
+	)
+	defer server.Close()
+	systemDir := t.TempDir()
+	location := server.URL + "/qodana-config.zip"
+
+	dir, err := FetchGlobalConfigurations(location, systemDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, "qodana-config-main", filepath.Base(dir))
+	data, err := os.ReadFile(filepath.Join(dir, globalConfigurationsYamlFilename))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	_, err = FetchGlobalConfigurations(location, systemDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load(), "the fresh copy is reused")
+
+	t.Setenv(QodanaGlobalConfigTtl, "0s")
+	content = "version: \"1.0\"\nconfigurations: []\n"
+	dir, err = FetchGlobalConfigurations(location, systemDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load(), "the stale copy is refreshed")
+	data, err = os.ReadFile(filepath.Join(dir, globalConfigurationsYamlFilename))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestFetchGlobalConfigurationsFallsBackToCachedCopy(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if failing.Load() {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				_, _ = w.Write(globalConfigurationsZip(t, "version: \"1.0\"\n"))
+			},
+		),
+	)
+	defer server.Close()
+	systemDir := t.TempDir()
+	location := server.URL + "/qodana-config.zip"
+
+	fetched, err := FetchGlobalConfigurations(location, systemDir, "")
+	require.NoError(t, err)
+
+	failing.Store(true)
+	t.Setenv(QodanaGlobalConfigTtl, "0s")
+	dir, err := FetchGlobalConfigurations(location, systemDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, fetched, dir)
+
+	_, err = FetchGlobalConfigurations(server.URL+"/other.zip", systemDir, "")
+	assert.Error(t, err)
+}
+
+func TestFetchGlobalConfigurationsOffline(t *testing.T) {
+	t.Setenv(qdenv.QodanaOffline, "true")
+	_, err := FetchGlobalConfigurations("https://example.com/qodana-config.zip", t.TempDir(), "")
+	assert.ErrorContains(t, err, "offline")
+
+	dir, err := FetchGlobalConfigurations("global", t.TempDir(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "global", dir)
+}
+
+func TestRemoteGlobalConfigurationsTtl(t *testing.T) {
+	assert.Equal(t, defaultRemoteGlobalConfigurationsTtl, remoteGlobalConfigurationsTtl())
+	t.Setenv(QodanaGlobalConfigTtl, "15m")
+	assert.Equal(t, 15*time.Minute, remoteGlobalConfigurationsTtl())
+	t.Setenv(QodanaGlobalConfigTtl, "soon")
+	assert.Equal(t, defaultRemoteGlobalConfigurationsTtl, remoteGlobalConfigurationsTtl())
+}
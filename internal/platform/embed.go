@@ -19,62 +19,112 @@ package platform
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
 	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 
 	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/tooling"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 // Mount a third-party linter.
-func extractUtils(linter ThirdPartyLinter, cacheDir string) thirdpartyscan.MountInfo {
+func extractUtils(linter ThirdPartyLinter, cacheDir string) (thirdpartyscan.MountInfo, error) {
+	defer timings.Start(timings.ToolExtraction)()
 	mountPath := tooling.GetToolsMountPath(cacheDir)
 	customTools, err := linter.MountTools(mountPath)
 	if err != nil {
-		log.Fatal(err)
+		return thirdpartyscan.MountInfo{}, fmt.Errorf("failed to mount the linter tools: %w", err)
 	}
 	mountInfo := thirdpartyscan.MountInfo{
 		CustomTools: customTools,
 	}
-	return mountInfo
+	return mountInfo, nil
 }
 
-func ProcessAuxiliaryTool(toolName, moniker, mountPath string, bytes []byte) string {
+// ProcessAuxiliaryTool writes the embedded tool to the mount path unless it's already there, and returns its path.
+func ProcessAuxiliaryTool(toolName, moniker, mountPath string, bytes []byte) (string, error) {
 	toolPath := filepath.Join(mountPath, toolName)
 	if _, err := os.Stat(toolPath); err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			err := os.WriteFile(toolPath, bytes, 0644)
-			if err != nil { // change the second parameter depending on which tool you have to process
-				log.Fatalf("Failed to write %s : %s", moniker, err)
+			if err != nil {
+				_ = os.Remove(toolPath)
+				return "", fmt.Errorf("failed to write %s: %w", moniker, err)
 			}
 		}
 	}
-	return toolPath
+	return toolPath, nil
+}
+
+// Archive formats detected by Decompress from the first bytes of the file.
+const (
+	archiveZip = "zip"
+	archiveTar = "tar"
+	archiveGz  = "tar.gz"
+	archiveZst = "tar.zst"
+	archiveXz  = "tar.xz"
+)
+
+// archiveMagics are the signatures of the archives and the compressions at the start of the file.
+var archiveMagics = []struct {
+	format string
+	magic  []byte
+}{
+	{archiveZip, []byte("PK\x03\x04")},
+	{archiveZip, []byte("PK\x05\x06")}, // empty zip
+	{archiveGz, []byte{0x1f, 0x8b}},
+	{archiveZst, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{archiveXz, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
 }
 
+// tarMagicOffset is the offset of "ustar" in the header of a tar entry.
+const tarMagicOffset = 257
+
+// Decompress extracts the archive to destPath. The format is detected from the content, not from the extension:
+// zip, or tar uncompressed or compressed with gzip, zstd or xz.
 func Decompress(archivePath string, destPath string) error {
-	isZip := strings.HasSuffix(archivePath, ".zip")
-	if //goland:noinspection GoBoolExpressions
-	isZip || runtime.GOOS == "windows" {
-		err, done := unpackZip(archivePath, destPath)
-		if done {
-			return err
-		}
+	format, err := detectArchiveFormat(archivePath)
+	if err != nil {
+		return err
+	}
+	if format == archiveZip {
+		err, _ = unpackZip(archivePath, destPath)
 	} else {
-		err, done := extractTarGz(archivePath, destPath)
-		if done {
-			return err
-		}
+		err, _ = extractTar(archivePath, format, destPath)
 	}
+	return err
+}
 
-	return nil
+// detectArchiveFormat returns the format of the archive from its first bytes.
+func detectArchiveFormat(archivePath string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+	header := make([]byte, tarMagicOffset+5)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	header = header[:n]
+	for _, m := range archiveMagics {
+		if bytes.HasPrefix(header, m.magic) {
+			return m.format, nil
+		}
+	}
+	if len(header) == tarMagicOffset+5 && string(header[tarMagicOffset:]) == "ustar" {
+		return archiveTar, nil
+	}
+	return "", fmt.Errorf("%s: unsupported archive format, expected zip, tar, tar.gz, tar.zst or tar.xz", archivePath)
 }
 
 // unpackZip unpacks zip archive to the destination path
@@ -83,12 +133,7 @@ func unpackZip(archivePath string, destPath string) (error, bool) {
 	if err != nil {
 		return err, true
 	}
-	defer func(zipReader *zip.ReadCloser) {
-		err = zipReader.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(zipReader)
+	defer func() { _ = zipReader.Close() }()
 
 	for _, f := range zipReader.File {
 		fpath := filepath.Join(destPath, f.Name)
@@ -117,11 +162,14 @@ func unpackZip(archivePath string, destPath string) (error, bool) {
 
 		src, err := f.Open()
 		if err != nil {
+			_ = dst.Close()
 			return err, true
 		}
 
 		_, err = io.Copy(dst, src)
 		if err != nil {
+			_ = src.Close()
+			_ = dst.Close()
 			return err, true
 		}
 
@@ -137,31 +185,39 @@ func unpackZip(archivePath string, destPath string) (error, bool) {
 	return nil, false
 }
 
-// extractTarGz extracts tar.gz archive to the destination path
-func extractTarGz(archivePath string, destPath string) (error, bool) {
+// extractTar extracts tar archive compressed in the format to the destination path
+func extractTar(archivePath string, format string, destPath string) (error, bool) {
 	archiveFile, err := os.Open(archivePath)
 	if err != nil {
 		return err, true
 	}
-	defer func(archiveFile *os.File) {
-		err := archiveFile.Close()
+	defer func() { _ = archiveFile.Close() }()
+
+	var reader io.Reader = archiveFile
+	switch format {
+	case archiveGz:
+		gzipReader, err := gzip.NewReader(archiveFile)
 		if err != nil {
-			log.Fatal(err)
+			return err, true
 		}
-	}(archiveFile)
-
-	gzipReader, err := gzip.NewReader(archiveFile)
-	if err != nil {
-		return err, true
-	}
-	defer func(gzipReader *gzip.Reader) {
-		err := gzipReader.Close()
+		defer func() { _ = gzipReader.Close() }()
+		reader = gzipReader
+	case archiveZst:
+		zstdReader, err := zstd.NewReader(archiveFile)
+		if err != nil {
+			return err, true
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	case archiveXz:
+		xzReader, err := xz.NewReader(archiveFile)
 		if err != nil {
-			log.Fatal(err)
+			return err, true
 		}
-	}(gzipReader)
+		reader = xzReader
+	}
 
-	tarReader := tar.NewReader(gzipReader)
+	tarReader := tar.NewReader(reader)
 
 	for {
 		header, err := tarReader.Next()
@@ -189,10 +245,7 @@ func extractTarGz(archivePath string, destPath string) (error, bool) {
 				return err, true
 			}
 			if _, err := io.Copy(file, tarReader); err != nil {
-				err := file.Close()
-				if err != nil {
-					return err, true
-				}
+				_ = file.Close()
 				return err, true
 			}
 			err = file.Close()
@@ -17,13 +17,20 @@
 package platform
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/internal/tooling"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 func TestMount(t *testing.T) {
@@ -32,7 +39,10 @@ func TestMount(t *testing.T) {
 	mountPath := tooling.GetToolsMountPath(tempCacheDir)
 	_ = os.WriteFile(filepath.Join(mountPath, "tool.lib"), []byte("test"), 0644)
 
-	mountInfo := extractUtils(linter, tempCacheDir)
+	mountInfo, err := extractUtils(linter, tempCacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	for _, p := range mountInfo.CustomTools {
 		_, err := os.Stat(p)
@@ -73,7 +83,10 @@ func TestProcessAuxiliaryTool(t *testing.T) {
 	dir := t.TempDir()
 	testBytes := []byte("test content")
 
-	path := ProcessAuxiliaryTool("test.jar", "test", dir, testBytes)
+	path, err := ProcessAuxiliaryTool("test.jar", "test", dir, testBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if path == "" {
 		t.Error("ProcessAuxiliaryTool returned empty path")
@@ -88,7 +101,7 @@ func TestProcessAuxiliaryTool(t *testing.T) {
 		t.Error("File content mismatch")
 	}
 
-	path2 := ProcessAuxiliaryTool("test.jar", "test", dir, testBytes)
+	path2, _ := ProcessAuxiliaryTool("test.jar", "test", dir, testBytes)
 	if path != path2 {
 		t.Error("ProcessAuxiliaryTool should return same path on re-run")
 	}
@@ -118,3 +131,111 @@ func TestIsInDirectory(t *testing.T) {
 		)
 	}
 }
+
+// writeTestArchive writes an archive of the format with a directory and a file.
+func writeTestArchive(t *testing.T, path string, format string) {
+	var buffer bytes.Buffer
+	if format == archiveZip {
+		zw := zip.NewWriter(&buffer)
+		w, err := zw.Create("tool/bin/tool")
+		if err == nil {
+			_, err = w.Write([]byte("tool"))
+		}
+		if err == nil {
+			err = zw.Close()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		var compressor io.WriteCloser
+		var err error
+		switch format {
+		case archiveGz:
+			compressor = gzip.NewWriter(&buffer)
+		case archiveZst:
+			compressor, err = zstd.NewWriter(&buffer)
+		case archiveXz:
+			compressor, err = xz.NewWriter(&buffer)
+		default:
+			compressor = nopWriteCloser{&buffer}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw := tar.NewWriter(compressor)
+		for _, header := range []*tar.Header{
+			{Name: "tool/bin/", Typeflag: tar.TypeDir, Mode: 0o755},
+			{Name: "tool/bin/tool", Typeflag: tar.TypeReg, Mode: 0o755, Size: 4},
+		} {
+			if err = tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err = tw.Write([]byte("tool")); err != nil {
+			t.Fatal(err)
+		}
+		if err = tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err = compressor.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestDecompress(t *testing.T) {
+	for _, format := range []string{archiveZip, archiveTar, archiveGz, archiveZst, archiveXz} {
+		t.Run(
+			format, func(t *testing.T) {
+				// the extension doesn't tell the format, the content does
+				archivePath := filepath.Join(t.TempDir(), "tool.archive")
+				writeTestArchive(t, archivePath, format)
+				destPath := t.TempDir()
+				if err := Decompress(archivePath, destPath); err != nil {
+					t.Fatalf("Decompress failed: %v", err)
+				}
+				content, err := os.ReadFile(filepath.Join(destPath, "tool", "bin", "tool"))
+				if err != nil || string(content) != "tool" {
+					t.Errorf("unexpected extracted file %q: %v", content, err)
+				}
+			},
+		)
+	}
+
+	unknown := filepath.Join(t.TempDir(), "tool.tar.gz")
+	if err := os.WriteFile(unknown, []byte("not an archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Decompress(unknown, t.TempDir()); err == nil {
+		t.Error("expected an error for an unknown archive format")
+	}
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	for format, header := range map[string][]byte{
+		archiveZip: []byte("PK\x03\x04rest"),
+		archiveGz:  {0x1f, 0x8b, 0x08},
+		archiveZst: {0x28, 0xb5, 0x2f, 0xfd, 0x00},
+		archiveXz:  {0xfd, '7', 'z', 'X', 'Z', 0x00, 0x00},
+		archiveTar: append(make([]byte, tarMagicOffset), []byte("ustar\x0000")...),
+	} {
+		path := filepath.Join(t.TempDir(), "archive")
+		if err := os.WriteFile(path, header, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		detected, err := detectArchiveFormat(path)
+		if err != nil || detected != format {
+			t.Errorf("detectArchiveFormat() = %s, %v, want %s", detected, err, format)
+		}
+	}
+}
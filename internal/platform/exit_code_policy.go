@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExitCodePolicy maps the outcomes of the run to the exit codes set by --exit-code-policy,
+// the outcomes not set keep the default exit code.
+type ExitCodePolicy struct {
+	// Problems is the exit code of the run that found new problems without exceeding the fail threshold.
+	Problems *int
+	// Threshold is the exit code of the run exceeding the fail threshold.
+	Threshold *int
+	// Failure is the exit code of the analysis that failed to run.
+	Failure *int
+}
+
+// ParseExitCodePolicy reads the comma-separated outcome=code pairs, e.g. problems=1,threshold=2,failure=3.
+func ParseExitCodePolicy(value string) (ExitCodePolicy, error) {
+	policy := ExitCodePolicy{}
+	fields := map[string]**int{
+		"problems":  &policy.Problems,
+		"threshold": &policy.Threshold,
+		"failure":   &policy.Failure,
+	}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		outcome, code, ok := strings.Cut(pair, "=")
+		field, known := fields[strings.ToLower(strings.TrimSpace(outcome))]
+		if !ok || !known {
+			return ExitCodePolicy{}, fmt.Errorf(
+				"invalid exit code %q, expected outcome=code with one of the outcomes: problems, threshold, failure",
+				pair,
+			)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(code))
+		if err != nil || n < 0 || n > 255 {
+			return ExitCodePolicy{}, fmt.Errorf("invalid exit code %q, the code must be a number from 0 to 255", pair)
+		}
+		*field = &n
+	}
+	return policy, nil
+}
+
+// ParseExitCodePolicyOrFatal validates --exit-code-policy before the analysis is started.
+func ParseExitCodePolicyOrFatal(value string) ExitCodePolicy {
+	policy, err := ParseExitCodePolicy(value)
+	if err != nil {
+		log.Fatalf("Invalid --exit-code-policy value: %s", err)
+	}
+	return policy
+}
+
+// ExitCode returns the exit code of the run by the policy. sarifPath is the report to find the new problems
+// in a successful run, it's not read if the policy doesn't set the problems exit code.
+func (p ExitCodePolicy) ExitCode(exitCode int, sarifPath string) int {
+	switch exitCode {
+	case exitcodes.QodanaSuccessExitCode:
+		if p.Problems != nil && sarifPath != "" && hasNewProblems(sarifPath) {
+			return *p.Problems
+		}
+	case exitcodes.QodanaFailThresholdExitCode:
+		if p.Threshold != nil {
+			return *p.Threshold
+		}
+	default:
+		if p.Failure != nil {
+			return *p.Failure
+		}
+	}
+	return exitCode
+}
+
+func hasNewProblems(sarifPath string) bool {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Warnf("Failed to read %s to apply --exit-code-policy: %s", sarifPath, err)
+		return false
+	}
+	return countProblems(report.Runs) > 0
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExitCodePolicy(t *testing.T) {
+	one, two := 1, 2
+	policy, err := ParseExitCodePolicy("problems=1, Failure=2")
+	assert.NoError(t, err)
+	assert.Equal(t, ExitCodePolicy{Problems: &one, Failure: &two}, policy)
+
+	policy, err = ParseExitCodePolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, ExitCodePolicy{}, policy)
+
+	for _, value := range []string{"problems", "issues=1", "failure=-1", "failure=256", "threshold=x"} {
+		_, err = ParseExitCodePolicy(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestExitCodePolicy(t *testing.T) {
+	dir := t.TempDir()
+	withProblems := filepath.Join(dir, "problems.sarif.json")
+	assert.NoError(t, os.WriteFile(withProblems, []byte(mergeSarifFirst), 0o644))
+
+	policy, err := ParseExitCodePolicy("problems=1,threshold=2,failure=3")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, policy.ExitCode(0, withProblems))
+	assert.Equal(t, 0, policy.ExitCode(0, ""))
+	assert.Equal(t, 0, policy.ExitCode(0, filepath.Join(dir, "missing.sarif.json")))
+	assert.Equal(t, 2, policy.ExitCode(255, withProblems))
+	assert.Equal(t, 3, policy.ExitCode(1, ""))
+	assert.Equal(t, 3, policy.ExitCode(137, ""))
+
+	defaults := ExitCodePolicy{}
+	assert.Equal(t, 0, defaults.ExitCode(0, withProblems))
+	assert.Equal(t, 255, defaults.ExitCode(255, ""))
+	assert.Equal(t, 7, defaults.ExitCode(7, ""))
+}
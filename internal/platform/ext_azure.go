@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+)
+
+// https://learn.microsoft.com/en-us/azure/devops/pipelines/scripts/logging-commands
+const (
+	// PublishAzureCodeAnalysis is the --publish target attaching the SARIF report to the Scans tab of the pipeline run
+	PublishAzureCodeAnalysis = "azure-code-analysis"
+
+	// azureCodeAnalysisArtifact is the artifact the SARIF SAST Scans Tab extension shows the reports from
+	azureCodeAnalysisArtifact = "CodeAnalysisLogs"
+	azureCodeAnalysisSarif    = "qodana.sarif"
+)
+
+// formatAzureIssue returns the task.logissue command of the problem.
+func formatAzureIssue(p ciProblem) string {
+	issueType := "warning"
+	if p.error {
+		issueType = "error"
+	}
+	properties := []string{"type=" + issueType}
+	if p.path != "" {
+		properties = append(properties, "sourcepath="+azurePropertyEscape(p.path))
+	}
+	if p.line > 0 {
+		properties = append(properties, fmt.Sprintf("linenumber=%d", p.line))
+	}
+	if p.column > 0 {
+		properties = append(properties, fmt.Sprintf("columnnumber=%d", p.column))
+	}
+	properties = append(properties, "code="+azurePropertyEscape(p.ruleId))
+	return fmt.Sprintf(
+		"##vso[task.logissue %s;]%s\n",
+		strings.Join(properties, ";"),
+		azureMessageEscape(fmt.Sprintf("%s: %s", p.ruleId, p.message)),
+	)
+}
+
+// publishAzureCodeAnalysis uploads the report as the CodeAnalysisLogs artifact of the pipeline run, the report is
+// copied with the .sarif extension the Scans tab expects.
+func publishAzureCodeAnalysis(sarifPath string) error {
+	dir := filepath.Join(filepath.Dir(sarifPath), azureCodeAnalysisArtifact)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, azureCodeAnalysisSarif)
+	if err := fs.CopyFile(sarifPath, target); err != nil {
+		return err
+	}
+	fmt.Printf(
+		"##vso[artifact.upload containerfolder=%s;artifactname=%s]%s\n",
+		azureCodeAnalysisArtifact,
+		azureCodeAnalysisArtifact,
+		azureMessageEscape(target),
+	)
+	msg.SuccessMessage("Report is attached to the Scans tab of the Azure DevOps pipeline run")
+	return nil
+}
+
+// azureMessageEscape keeps the message of a logging command on one line.
+func azureMessageEscape(text string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A").Replace(text)
+}
+
+// azurePropertyEscape prevents the property value from ending the property or the command.
+func azurePropertyEscape(text string) string {
+	return strings.NewReplacer("%", "%AZP25", "\r", "%0D", "\n", "%0A", "]", "%5D", ";", "%3B").Replace(text)
+}
@@ -17,7 +17,9 @@
 package platform
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -69,6 +71,10 @@ var (
 
 // sendBitBucketReport sends annotations to BitBucket Code Insights
 func sendBitBucketReport(annotations []bbapi.ReportAnnotation, toolName, cloudUrl, reportId string) error {
+	// BitBucket Pipelines run only for BitBucket Cloud
+	if serverUrl, ok := getBitBucketServerUrl(getBitBucketApiUrl()); ok && !qdenv.IsBitBucket() {
+		return sendBitBucketServerReport(serverUrl, annotations, toolName, cloudUrl, reportId)
+	}
 	client, ctx := getBitBucketClient(), getBitBucketContext()
 	repoOwner, repoName, sha := qdenv.GetBitBucketRepoOwner(), qdenv.GetBitBucketRepoName(), qdenv.GetBitBucketCommit()
 	_, resp, err := client.
@@ -175,24 +181,8 @@ func getBitBucketClient() *bbapi.APIClient {
 		Timeout: httpTimeout,
 	}
 
-	apiURL := os.Getenv("QD_BITBUCKET_URL")
-	if apiURL == "" {
-		if gitOrigin := os.Getenv("BITBUCKET_GIT_HTTP_ORIGIN"); gitOrigin != "" {
-			if parsedURL, err := url.Parse(gitOrigin); err == nil {
-				if !strings.Contains(parsedURL.Host, "bitbucket.org") {
-					// Construct API URL for self-hosted BitBucket Data Center/Server
-					// Reference: https://developer.atlassian.com/server/bitbucket/rest/v1000/intro/
-					apiURL = fmt.Sprintf("%s://%s/rest/api/1.0", parsedURL.Scheme, parsedURL.Host)
-				}
-			}
-		}
-		if apiURL == "" {
-			apiURL = "https://api.bitbucket.org/2.0"
-		}
-	}
-
 	server := bbapi.ServerConfiguration{
-		URL:         apiURL,
+		URL:         getBitBucketApiUrl(),
 		Description: `HTTPS API endpoint`,
 	}
 	if qdenv.IsBitBucket() {
@@ -214,6 +204,27 @@ func getBitBucketClient() *bbapi.APIClient {
 	return bbapi.NewAPIClient(config)
 }
 
+// getBitBucketApiUrl returns QD_BITBUCKET_URL, the API of the self-hosted BitBucket the repository is cloned from,
+// or the BitBucket Cloud API
+func getBitBucketApiUrl() string {
+	apiURL := os.Getenv("QD_BITBUCKET_URL")
+	if apiURL == "" {
+		if gitOrigin := os.Getenv("BITBUCKET_GIT_HTTP_ORIGIN"); gitOrigin != "" {
+			if parsedURL, err := url.Parse(gitOrigin); err == nil {
+				if !strings.Contains(parsedURL.Host, "bitbucket.org") {
+					// Construct API URL for self-hosted BitBucket Data Center/Server
+					// Reference: https://developer.atlassian.com/server/bitbucket/rest/v1000/intro/
+					apiURL = fmt.Sprintf("%s://%s/rest/api/1.0", parsedURL.Scheme, parsedURL.Host)
+				}
+			}
+		}
+		if apiURL == "" {
+			apiURL = "https://api.bitbucket.org/2.0"
+		}
+	}
+	return apiURL
+}
+
 // checkBitBucketApiError checks if the API call was successful
 func checkBitBucketApiError(err error, resp *http.Response, expectedCode int) error {
 	selfHostedHint := "Note: If you are using BitBucket Data Center/Server (self-hosted), " +
@@ -234,3 +245,148 @@ func checkBitBucketApiError(err error, resp *http.Response, expectedCode int) er
 	}
 	return nil
 }
+
+// https://developer.atlassian.com/server/bitbucket/rest/v1000/api-group-code-insights/
+const (
+	bitBucketServerApiPath      = "/rest/api/1.0"
+	bitBucketServerReportsPath  = "%s/rest/insights/1.0/projects/%s/repos/%s/commits/%s/reports/%s"
+	bitBucketServerReportPassed = "PASS"
+	bitBucketServerReportFailed = "FAIL"
+	// bitBucketServerMessageLimit is the maximum length of the annotation message accepted by the API
+	bitBucketServerMessageLimit = 2000
+)
+
+type bitBucketServerReport struct {
+	Title    string `json:"title"`
+	Details  string `json:"details,omitempty"`
+	Result   string `json:"result"`
+	Reporter string `json:"reporter"`
+	Link     string `json:"link,omitempty"`
+	LogoUrl  string `json:"logoUrl,omitempty"`
+}
+
+type bitBucketServerAnnotation struct {
+	ExternalId string `json:"externalId,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Line       int32  `json:"line,omitempty"`
+	Message    string `json:"message"`
+	Severity   string `json:"severity"`
+	Type       string `json:"type"`
+	Link       string `json:"link,omitempty"`
+}
+
+type bitBucketServerAnnotations struct {
+	Annotations []bitBucketServerAnnotation `json:"annotations"`
+}
+
+// getBitBucketServerUrl returns the base URL of BitBucket Data Center/Server if the API URL is its REST API
+func getBitBucketServerUrl(apiUrl string) (string, bool) {
+	apiUrl = strings.TrimSuffix(apiUrl, "/")
+	if !strings.HasSuffix(apiUrl, bitBucketServerApiPath) {
+		return "", false
+	}
+	return strings.TrimSuffix(apiUrl, bitBucketServerApiPath), true
+}
+
+// sendBitBucketServerReport sends the report and the annotations to BitBucket Data Center/Server Code Insights,
+// the repository is BITBUCKET_REPO_FULL_NAME in the form "PROJECT/repo"
+func sendBitBucketServerReport(
+	serverUrl string,
+	annotations []bbapi.ReportAnnotation,
+	toolName, cloudUrl, reportId string,
+) error {
+	project, repo, sha := qdenv.GetBitBucketRepoOwner(), qdenv.GetBitBucketRepoName(), qdenv.GetBitBucketCommit()
+	if project == "" || repo == "" || sha == "" {
+		return fmt.Errorf("BITBUCKET_REPO_FULL_NAME (PROJECT/repo) and BITBUCKET_COMMIT must be set for BitBucket Data Center/Server")
+	}
+	endpoint := fmt.Sprintf(
+		bitBucketServerReportsPath,
+		serverUrl,
+		url.PathEscape(project),
+		url.PathEscape(repo),
+		url.PathEscape(sha),
+		url.PathEscape(reportId),
+	)
+	result := bitBucketServerReportPassed
+	if len(annotations) > 0 {
+		result = bitBucketServerReportFailed
+	}
+	report := bitBucketServerReport{
+		Title:    toolName,
+		Details:  msg.GetProblemsFoundMessage(len(annotations)),
+		Result:   result,
+		Reporter: bitBucketReporter,
+		Link:     cloudUrl,
+		LogoUrl:  bitBucketAvatar,
+	}
+	if err := sendBitBucketServerRequest(http.MethodPut, endpoint, report); err != nil {
+		return fmt.Errorf("failed to create code insights report: %w", err)
+	}
+	if len(annotations) > bitBucketAnnotationLimit {
+		log.Debugf("Warning: Only first 1000 of %d annotations will be sent", len(annotations))
+		annotations = annotations[:bitBucketAnnotationLimit]
+	}
+	for i := 0; i < len(annotations); i += 100 {
+		j := min(i+100, len(annotations))
+		body := bitBucketServerAnnotations{}
+		for _, annotation := range annotations[i:j] {
+			body.Annotations = append(body.Annotations, toBitBucketServerAnnotation(annotation))
+		}
+		if err := sendBitBucketServerRequest(http.MethodPost, endpoint+"/annotations", body); err != nil {
+			return fmt.Errorf("failed to create code insights annotations: %w", err)
+		}
+	}
+	return nil
+}
+
+func toBitBucketServerAnnotation(annotation bbapi.ReportAnnotation) bitBucketServerAnnotation {
+	message := annotation.GetSummary()
+	if len(message) > bitBucketServerMessageLimit {
+		message = message[:bitBucketServerMessageLimit-3] + "..."
+	}
+	return bitBucketServerAnnotation{
+		ExternalId: annotation.GetExternalId(),
+		Path:       annotation.GetPath(),
+		Line:       annotation.GetLine(),
+		Message:    message,
+		Severity:   annotation.GetSeverity(),
+		Type:       annotation.GetAnnotationType(),
+		Link:       annotation.GetLink(),
+	}
+}
+
+// sendBitBucketServerRequest sends the request authenticated with QD_BITBUCKET_TOKEN (an HTTP access token) or
+// QD_BITBUCKET_USER and QD_BITBUCKET_PASSWORD
+func sendBitBucketServerRequest(method string, endpoint string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := os.Getenv("QD_BITBUCKET_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user, password := os.Getenv("QD_BITBUCKET_USER"), os.Getenv("QD_BITBUCKET_PASSWORD"); user != "" && password != "" {
+		req.SetBasicAuth(user, password)
+	}
+	client := &http.Client{Timeout: httpTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("BitBucket API error: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf(
+			"BitBucket API returned unexpected status code %d: %s",
+			resp.StatusCode,
+			strings.TrimSpace(string(respBody)),
+		)
+	}
+	return nil
+}
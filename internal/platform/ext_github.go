@@ -0,0 +1,237 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+// https://docs.github.com/en/rest/code-scanning/code-scanning#upload-an-analysis-as-sarif-data
+const (
+	// PublishGitHubCodeScanning is the --publish target uploading the SARIF report to GitHub code scanning
+	PublishGitHubCodeScanning = "github-code-scanning"
+
+	gitHubDefaultApiUrl = "https://api.github.com"
+	// gitHubSarifSizeLimit is the maximum size of the gzip-compressed SARIF file accepted by the API
+	gitHubSarifSizeLimit = 10 * 1024 * 1024
+	// gitHubResultsLimit is the maximum number of results in a run accepted by the API
+	gitHubResultsLimit    = 25000
+	gitHubCategoryPrefix  = "qodana-"
+	gitHubUploadTimeout   = time.Minute
+	gitHubUploadsEndpoint = "%s/repos/%s/code-scanning/sarifs"
+)
+
+// PublishTargets are the values accepted by --publish.
+var PublishTargets = []string{
+	PublishGitHubCodeScanning,
+	PublishGitLabMergeRequestDiscussions,
+	PublishAzureCodeAnalysis,
+}
+
+type gitHubSarifUpload struct {
+	CommitSha string `json:"commit_sha"`
+	Ref       string `json:"ref"`
+	Sarif     string `json:"sarif"`
+	ToolName  string `json:"tool_name,omitempty"`
+}
+
+type gitHubSarifUploadResponse struct {
+	Id  string `json:"id"`
+	Url string `json:"url"`
+}
+
+// PublishSarif publishes the final SARIF report to the given targets, the failures are reported as warnings.
+func PublishSarif(sarifPath string, targets []string) {
+	for _, target := range targets {
+		switch target {
+		case PublishGitHubCodeScanning:
+			if err := sendGitHubCodeScanningReport(sarifPath); err != nil {
+				log.Warnf("Problems sending the report to GitHub code scanning: %v", err)
+			}
+		case PublishGitLabMergeRequestDiscussions:
+			if err := sendGitLabMergeRequestDiscussions(sarifPath); err != nil {
+				log.Warnf("Problems posting the new problems to the GitLab merge request: %v", err)
+			}
+		case PublishAzureCodeAnalysis:
+			if err := publishAzureCodeAnalysis(sarifPath); err != nil {
+				log.Warnf("Problems attaching the report to the Azure DevOps pipeline run: %v", err)
+			}
+		default:
+			log.Warnf("Unknown publish target %s, supported targets: %s", target, strings.Join(PublishTargets, ", "))
+		}
+	}
+}
+
+// sendGitHubCodeScanningReport uploads the report to GitHub code scanning, with the same parameters as the upload-sarif action:
+// GITHUB_TOKEN (with the security-events: write permission), GITHUB_REPOSITORY, GITHUB_SHA and GITHUB_REF.
+func sendGitHubCodeScanningReport(sarifPath string) error {
+	token, repository := os.Getenv("GITHUB_TOKEN"), os.Getenv("GITHUB_REPOSITORY")
+	upload := gitHubSarifUpload{CommitSha: os.Getenv("GITHUB_SHA"), Ref: os.Getenv("GITHUB_REF")}
+	if token == "" || repository == "" || upload.CommitSha == "" || upload.Ref == "" {
+		return fmt.Errorf("GITHUB_TOKEN, GITHUB_REPOSITORY, GITHUB_SHA and GITHUB_REF must be set")
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return err
+	}
+	if len(report.Runs) > 0 && report.Runs[0].Tool != nil && report.Runs[0].Tool.Driver != nil {
+		upload.ToolName = report.Runs[0].Tool.Driver.FullName
+	}
+	prepareGitHubReport(report)
+	if upload.Sarif, err = encodeGitHubSarif(report); err != nil {
+		return err
+	}
+	apiUrl := os.Getenv("GITHUB_API_URL")
+	if apiUrl == "" {
+		apiUrl = gitHubDefaultApiUrl
+	}
+	response, err := postGitHubSarif(fmt.Sprintf(gitHubUploadsEndpoint, apiUrl, repository), token, upload)
+	if err != nil {
+		return err
+	}
+	log.Debugf("GitHub code scanning upload %s: %s", response.Id, response.Url)
+	msg.SuccessMessage("Report is successfully sent to GitHub code scanning")
+	return nil
+}
+
+// prepareGitHubReport makes the report fit GitHub code scanning: the analyses are grouped by a category per linter,
+// the results above the limit are dropped.
+func prepareGitHubReport(report *sarif.Report) {
+	for i := range report.Runs {
+		run := &report.Runs[i]
+		category := "qodana"
+		if run.Tool != nil && run.Tool.Driver != nil && run.Tool.Driver.Name != "" {
+			category = gitHubCategoryPrefix + strings.ToLower(run.Tool.Driver.Name)
+		}
+		if run.AutomationDetails == nil {
+			run.AutomationDetails = &sarif.RunAutomationDetails{}
+		}
+		// the part before the last slash is the category, the report id contains the date and can't be used as is
+		run.AutomationDetails.Id = category + "/"
+		if len(run.Results) > gitHubResultsLimit {
+			log.Warnf(
+				"Only the first %d of %d problems are sent to GitHub code scanning",
+				gitHubResultsLimit,
+				len(run.Results),
+			)
+			run.Results = run.Results[:gitHubResultsLimit]
+		}
+	}
+}
+
+// encodeGitHubSarif returns the gzip-compressed and base64-encoded report, as required by the API.
+func encodeGitHubSarif(report *sarif.Report) (string, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling report: %w", err)
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err = writer.Write(data); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+	if compressed.Len() > gitHubSarifSizeLimit {
+		return "", fmt.Errorf(
+			"the compressed report is %d bytes, GitHub code scanning accepts up to %d bytes: use a baseline or exclude paths to reduce the number of problems",
+			compressed.Len(),
+			gitHubSarifSizeLimit,
+		)
+	}
+	return base64.StdEncoding.EncodeToString(compressed.Bytes()), nil
+}
+
+func postGitHubSarif(endpoint string, token string, upload gitHubSarifUpload) (gitHubSarifUploadResponse, error) {
+	var response gitHubSarifUploadResponse
+	body, err := json.Marshal(upload)
+	if err != nil {
+		return response, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return response, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	client := &http.Client{Timeout: gitHubUploadTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return response, err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return response, fmt.Errorf("unexpected response status %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return response, fmt.Errorf("failed to parse the response: %w", err)
+	}
+	return response, nil
+}
+
+// formatGitHubAnnotation returns the workflow command annotating the line of the problem,
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-a-warning-message
+func formatGitHubAnnotation(p ciProblem) string {
+	command := "warning"
+	if p.error {
+		command = "error"
+	}
+	properties := []string{"title=" + gitHubPropertyEscape(p.ruleId)}
+	if p.path != "" {
+		properties = append(properties, "file="+gitHubPropertyEscape(p.path))
+	}
+	if p.line > 0 {
+		properties = append(properties, fmt.Sprintf("line=%d", p.line))
+	}
+	if p.column > 0 {
+		properties = append(properties, fmt.Sprintf("col=%d", p.column))
+	}
+	return fmt.Sprintf("::%s %s::%s\n", command, strings.Join(properties, ","), gitHubMessageEscape(p.message))
+}
+
+// gitHubMessageEscape keeps the message of a workflow command on one line.
+func gitHubMessageEscape(text string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(text)
+}
+
+// gitHubPropertyEscape prevents the property value from ending the property or the command.
+func gitHubPropertyEscape(text string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(text)
+}
@@ -17,11 +17,21 @@
 package platform
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/sarif"
 	log "github.com/sirupsen/logrus"
 )
@@ -135,3 +145,259 @@ func writeGlCodeQualityReport(issues []CCIssue, sarifPath string) error {
 	}
 	return nil
 }
+
+// https://docs.gitlab.com/ee/api/discussions.html#create-a-new-thread-in-the-merge-request-diff
+const (
+	// PublishGitLabMergeRequestDiscussions is the --publish target posting the new problems as merge request threads
+	PublishGitLabMergeRequestDiscussions = "gitlab-mr-discussions"
+
+	gitLabDefaultApiUrl = "https://gitlab.com/api/v4"
+	// gitLabDiscussionsLimit is the maximum number of threads started by one run, not to flood the merge request
+	gitLabDiscussionsLimit = 50
+	gitLabRequestTimeout   = 30 * time.Second
+	gitLabPageSize         = 100
+	gitLabMarkerFormat     = "<!-- qodana-problem: %s -->"
+)
+
+// gitLabMarker finds the problem key in the threads started by the previous runs.
+var gitLabMarker = regexp.MustCompile(`<!-- qodana-problem: ([0-9a-f]+) -->`)
+
+type gitLabDiffRefs struct {
+	BaseSha  string `json:"base_sha"`
+	HeadSha  string `json:"head_sha"`
+	StartSha string `json:"start_sha"`
+}
+
+type gitLabMergeRequest struct {
+	DiffRefs *gitLabDiffRefs `json:"diff_refs"`
+}
+
+type gitLabDiscussion struct {
+	Notes []struct {
+		Body string `json:"body"`
+	} `json:"notes"`
+}
+
+type gitLabPosition struct {
+	PositionType string `json:"position_type"`
+	BaseSha      string `json:"base_sha"`
+	HeadSha      string `json:"head_sha"`
+	StartSha     string `json:"start_sha"`
+	OldPath      string `json:"old_path"`
+	NewPath      string `json:"new_path"`
+	NewLine      int    `json:"new_line"`
+}
+
+type gitLabNewDiscussion struct {
+	Body     string         `json:"body"`
+	Position gitLabPosition `json:"position"`
+}
+
+// gitLabClient calls the API of the merge request of the pipeline.
+type gitLabClient struct {
+	endpoint    string
+	tokenHeader string
+	token       string
+	client      *http.Client
+}
+
+// newGitLabClient returns the client for the merge request of the pipeline: CI_API_V4_URL, CI_PROJECT_ID and
+// CI_MERGE_REQUEST_IID, authenticated with QD_GITLAB_TOKEN if it's set or with CI_JOB_TOKEN otherwise.
+func newGitLabClient() (*gitLabClient, error) {
+	project, mergeRequest := os.Getenv("CI_PROJECT_ID"), os.Getenv("CI_MERGE_REQUEST_IID")
+	if project == "" || mergeRequest == "" {
+		return nil, fmt.Errorf("CI_PROJECT_ID and CI_MERGE_REQUEST_IID must be set, run Qodana in a merge request pipeline")
+	}
+	c := &gitLabClient{client: &http.Client{Timeout: gitLabRequestTimeout}}
+	if token := os.Getenv("QD_GITLAB_TOKEN"); token != "" {
+		c.tokenHeader, c.token = "PRIVATE-TOKEN", token
+	} else if token = os.Getenv("CI_JOB_TOKEN"); token != "" {
+		c.tokenHeader, c.token = "JOB-TOKEN", token
+	} else {
+		return nil, fmt.Errorf("QD_GITLAB_TOKEN or CI_JOB_TOKEN must be set")
+	}
+	apiUrl := os.Getenv("CI_API_V4_URL")
+	if apiUrl == "" {
+		apiUrl = gitLabDefaultApiUrl
+	}
+	c.endpoint = fmt.Sprintf(
+		"%s/projects/%s/merge_requests/%s",
+		strings.TrimSuffix(apiUrl, "/"),
+		url.PathEscape(project),
+		url.PathEscape(mergeRequest),
+	)
+	return c, nil
+}
+
+// sendGitLabMergeRequestDiscussions starts a merge request thread on the line of every new problem, the problems
+// already posted by the previous runs are skipped.
+func sendGitLabMergeRequestDiscussions(sarifPath string) error {
+	client, err := newGitLabClient()
+	if err != nil {
+		return err
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return err
+	}
+	var mergeRequest gitLabMergeRequest
+	if _, err = client.do(http.MethodGet, "", nil, &mergeRequest); err != nil {
+		return err
+	}
+	if mergeRequest.DiffRefs == nil {
+		return fmt.Errorf("the merge request has no changes yet")
+	}
+	posted, err := client.postedProblems()
+	if err != nil {
+		return err
+	}
+
+	discussions := gitLabDiscussions(report, *mergeRequest.DiffRefs, posted)
+	if len(discussions) > gitLabDiscussionsLimit {
+		log.Warnf(
+			"Only the first %d of %d new problems are posted to the GitLab merge request",
+			gitLabDiscussionsLimit,
+			len(discussions),
+		)
+		discussions = discussions[:gitLabDiscussionsLimit]
+	}
+	created := 0
+	for _, discussion := range discussions {
+		status, err := client.do(http.MethodPost, "/discussions", discussion, nil)
+		if status == http.StatusBadRequest {
+			// the line isn't a part of the merge request diff
+			log.Debugf("Skipped the problem at %s:%d: %v", discussion.Position.NewPath, discussion.Position.NewLine, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		created++
+	}
+	msg.SuccessMessage("Posted %d new problems to the GitLab merge request", created)
+	return nil
+}
+
+// gitLabDiscussions returns the threads of the new problems on a line of a file, except the posted ones.
+func gitLabDiscussions(report *sarif.Report, refs gitLabDiffRefs, posted map[string]bool) []gitLabNewDiscussion {
+	var discussions []gitLabNewDiscussion
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if !isNewProblem(r) {
+				continue
+			}
+			path, line := resultLocation(r)
+			if path == "" || line == 0 {
+				continue
+			}
+			key := gitLabProblemKey(r)
+			if posted[key] {
+				continue
+			}
+			posted[key] = true
+			message := ""
+			if r.Message != nil {
+				message = r.Message.Text
+			}
+			body := fmt.Sprintf(
+				"**Qodana** %s `%s`: %s\n\n"+gitLabMarkerFormat,
+				thresholdSeverityOf(getSeverity(r)),
+				r.RuleId,
+				markdownEscape(message),
+				key,
+			)
+			discussions = append(
+				discussions, gitLabNewDiscussion{
+					Body: body,
+					Position: gitLabPosition{
+						PositionType: "text",
+						BaseSha:      refs.BaseSha,
+						HeadSha:      refs.HeadSha,
+						StartSha:     refs.StartSha,
+						OldPath:      path,
+						NewPath:      path,
+						NewLine:      line,
+					},
+				},
+			)
+		}
+	}
+	return discussions
+}
+
+// gitLabProblemKey identifies the problem across the runs.
+func gitLabProblemKey(r *sarif.Result) string {
+	sum := sha256.Sum256([]byte(liveProblemKey(r)))
+	return hex.EncodeToString(sum[:])
+}
+
+// postedProblems returns the keys of the problems in the threads of the merge request.
+func (c *gitLabClient) postedProblems() (map[string]bool, error) {
+	posted := make(map[string]bool)
+	for page := 1; ; page++ {
+		var discussions []gitLabDiscussion
+		if _, err := c.do(
+			http.MethodGet,
+			fmt.Sprintf("/discussions?per_page=%d&page=%d", gitLabPageSize, page),
+			nil,
+			&discussions,
+		); err != nil {
+			return nil, err
+		}
+		for _, discussion := range discussions {
+			for _, note := range discussion.Notes {
+				for _, match := range gitLabMarker.FindAllStringSubmatch(note.Body, -1) {
+					posted[match[1]] = true
+				}
+			}
+		}
+		if len(discussions) < gitLabPageSize {
+			return posted, nil
+		}
+	}
+}
+
+// do sends the request to the merge request endpoint and decodes the response to result if it's not nil.
+func (c *gitLabClient) do(method string, path string, body any, result any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.endpoint+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set(c.tokenHeader, c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf(
+			"unexpected response status %s: %s",
+			resp.Status,
+			strings.TrimSpace(string(respBody)),
+		)
+	}
+	if result != nil {
+		if err = json.Unmarshal(respBody, result); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to parse the response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+// https://github.com/jenkinsci/warnings-ng-plugin/blob/main/doc/Documentation.md#export-your-issues-into-a-supported-format
+const (
+	// warningsNgReport is the name of the report in the native format of the Jenkins warnings-ng plugin
+	warningsNgReport = "qodana-warnings-ng.json"
+	warningsNgOrigin = "qodana"
+
+	warningsNgError  = "ERROR"
+	warningsNgHigh   = "HIGH"
+	warningsNgNormal = "NORMAL"
+	warningsNgLow    = "LOW"
+)
+
+// toWarningsNgSeverity maps the threshold severities to the warnings-ng severities.
+var toWarningsNgSeverity = map[string]string{
+	"critical": warningsNgError,
+	"high":     warningsNgHigh,
+	"moderate": warningsNgNormal,
+	"low":      warningsNgLow,
+	"info":     warningsNgLow,
+}
+
+type warningsNgIssue struct {
+	FileName    string `json:"fileName,omitempty"`
+	LineStart   int    `json:"lineStart,omitempty"`
+	ColumnStart int    `json:"columnStart,omitempty"`
+	Type        string `json:"type"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Fingerprint string `json:"fingerprint"`
+	Origin      string `json:"origin"`
+	OriginName  string `json:"originName"`
+}
+
+type warningsNgIssues struct {
+	Issues []warningsNgIssue `json:"issues"`
+}
+
+// writeWarningsNgReport saves the new problems next to the SARIF report in the format the warnings-ng plugin reads
+// with recordIssues(tool: issues(pattern: '...')). The paths are relative to the project directory, the plugin takes
+// the blame data from the workspace checkout.
+func writeWarningsNgReport(sarifPath string) error {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(newWarningsNgIssues(report), "", "  ")
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(filepath.Dir(sarifPath), warningsNgReport)
+	if err = os.WriteFile(target, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write the warnings-ng report: %w", err)
+	}
+	msg.SuccessMessage(
+		"Saved the new problems for the Jenkins warnings-ng plugin to %s, record them with %s",
+		target,
+		msg.PrimaryBold(fmt.Sprintf("recordIssues(tool: issues(pattern: '**/%s'))", warningsNgReport)),
+	)
+	return nil
+}
+
+func newWarningsNgIssues(report *sarif.Report) warningsNgIssues {
+	issues := warningsNgIssues{Issues: make([]warningsNgIssue, 0)}
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if !isNewProblem(r) {
+				continue
+			}
+			severity, ok := toWarningsNgSeverity[thresholdSeverityOf(getSeverity(r))]
+			if !ok {
+				severity = warningsNgLow
+			}
+			issue := warningsNgIssue{
+				Type:        r.RuleId,
+				Severity:    severity,
+				Fingerprint: liveProblemKey(r),
+				Origin:      warningsNgOrigin,
+				OriginName:  "Qodana",
+			}
+			if r.Message != nil {
+				issue.Message = r.Message.Text
+			}
+			issue.FileName, issue.LineStart, issue.ColumnStart = liveProblemLocation(r)
+			issues.Issues = append(issues.Issues, issue)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://www.jetbrains.com/help/teamcity/service-messages.html#Reporting+Inspections
+const teamCityInspectionCategory = "Qodana"
+
+// newTeamCityInspectionFormatter returns the formatter of the inspection service messages shown in the Inspections tab
+// of the build, the inspection type is reported before the first problem of a rule.
+func newTeamCityInspectionFormatter() func(ciProblem) string {
+	reported := make(map[string]bool)
+	return func(p ciProblem) string {
+		var sb strings.Builder
+		if !reported[p.ruleId] {
+			reported[p.ruleId] = true
+			_, _ = fmt.Fprintf(
+				&sb,
+				"##teamcity[inspectionType id='%s' name='%s' category='%s' description='%s']\n",
+				teamCityEscape(p.ruleId),
+				teamCityEscape(p.ruleId),
+				teamCityInspectionCategory,
+				teamCityEscape(p.ruleId),
+			)
+		}
+		severity := "WARNING"
+		if p.error {
+			severity = "ERROR"
+		}
+		_, _ = fmt.Fprintf(
+			&sb,
+			"##teamcity[inspection typeId='%s' message='%s' file='%s' line='%d' SEVERITY='%s']\n",
+			teamCityEscape(p.ruleId),
+			teamCityEscape(p.message),
+			teamCityEscape(p.path),
+			p.line,
+			severity,
+		)
+		return sb.String()
+	}
+}
+
+// teamCityEscape escapes the value of a service message attribute.
+func teamCityEscape(text string) string {
+	return strings.NewReplacer(
+		"|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]",
+	).Replace(text)
+}
@@ -17,13 +17,18 @@
 package platform
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -403,3 +408,240 @@ func TestWriteGlCodeQualityReport(t *testing.T) {
 		assert.Contains(t, string(content), "Test description")
 	})
 }
+
+func TestSendGitHubCodeScanningReport(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	assert.NoError(t, os.WriteFile(sarifPath, []byte(sarifFileData), 0o644))
+
+	var upload gitHubSarifUpload
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/repos/owner/repo/code-scanning/sarifs", r.URL.Path)
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&upload))
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(`{"id":"47177e22","url":"https://api.github.com/repos/owner/repo/code-scanning/sarifs/47177e22"}`))
+			},
+		),
+	)
+	defer server.Close()
+
+	t.Setenv("GITHUB_TOKEN", "")
+	assert.Error(t, sendGitHubCodeScanningReport(sarifPath))
+
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	t.Setenv("GITHUB_SHA", "4b6472266afd7b471e86085a6659e8c7f2b119da")
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	t.Setenv("GITHUB_API_URL", server.URL)
+	assert.NoError(t, sendGitHubCodeScanningReport(sarifPath))
+	assert.Equal(t, "4b6472266afd7b471e86085a6659e8c7f2b119da", upload.CommitSha)
+	assert.Equal(t, "refs/heads/main", upload.Ref)
+
+	compressed, err := base64.StdEncoding.DecodeString(upload.Sarif)
+	assert.NoError(t, err)
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	assert.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	report, err := ReadReportFromString(string(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "qodana-mocktool/", report.Runs[0].AutomationDetails.Id)
+	assert.Len(t, report.Runs[0].Results, 5)
+}
+
+func TestPrepareGitHubReportResultsLimit(t *testing.T) {
+	report, err := ReadReportFromString(sarifFileData)
+	assert.NoError(t, err)
+	results := report.Runs[0].Results
+	for len(report.Runs[0].Results) <= gitHubResultsLimit {
+		report.Runs[0].Results = append(report.Runs[0].Results, results...)
+	}
+
+	prepareGitHubReport(report)
+	assert.Len(t, report.Runs[0].Results, gitHubResultsLimit)
+}
+
+func TestSendGitLabMergeRequestDiscussions(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	assert.NoError(t, os.WriteFile(sarifPath, []byte(sarifFileData), 0o644))
+	report, err := ReadReportFromString(sarifFileData)
+	assert.NoError(t, err)
+	postedKey := gitLabProblemKey(&report.Runs[0].Results[0])
+
+	var created []gitLabNewDiscussion
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "job-token", r.Header.Get("JOB-TOKEN"))
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/projects/42/merge_requests/7":
+					_, _ = w.Write([]byte(`{"diff_refs":{"base_sha":"base","head_sha":"head","start_sha":"start"}}`))
+				case r.Method == http.MethodGet && r.URL.Path == "/projects/42/merge_requests/7/discussions":
+					_, _ = w.Write([]byte(`[{"notes":[{"body":"<!-- qodana-problem: ` + postedKey + ` -->"}]}]`))
+				case r.Method == http.MethodPost && r.URL.Path == "/projects/42/merge_requests/7/discussions":
+					var discussion gitLabNewDiscussion
+					assert.NoError(t, json.NewDecoder(r.Body).Decode(&discussion))
+					created = append(created, discussion)
+					if discussion.Position.NewLine == 2 {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"message":"400 Bad request - Note {:line_code=>[\"can't be blank\"]}"}`))
+						return
+					}
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	t.Setenv("CI_PROJECT_ID", "")
+	assert.Error(t, sendGitLabMergeRequestDiscussions(sarifPath))
+
+	t.Setenv("CI_PROJECT_ID", "42")
+	t.Setenv("CI_MERGE_REQUEST_IID", "7")
+	t.Setenv("QD_GITLAB_TOKEN", "")
+	t.Setenv("CI_JOB_TOKEN", "job-token")
+	t.Setenv("CI_API_V4_URL", server.URL)
+	assert.NoError(t, sendGitLabMergeRequestDiscussions(sarifPath))
+
+	// the first problem is already posted, the problems without a line are skipped
+	assert.Len(t, created, 2)
+	assert.Contains(t, created[0].Body, "`VulnerableLibrariesLocal`")
+	assert.Equal(t, "src/main/java/AppStarter.java", created[0].Position.NewPath)
+	assert.Equal(t, 9, created[0].Position.NewLine)
+	assert.Equal(t, "start", created[0].Position.StartSha)
+	assert.Contains(t, created[1].Body, "`ExampleNoteLevel`")
+}
+
+func TestGetBitBucketServerUrl(t *testing.T) {
+	serverUrl, ok := getBitBucketServerUrl("https://bitbucket.example.com/rest/api/1.0/")
+	assert.True(t, ok)
+	assert.Equal(t, "https://bitbucket.example.com", serverUrl)
+
+	_, ok = getBitBucketServerUrl("https://api.bitbucket.org/2.0")
+	assert.False(t, ok)
+}
+
+func TestSendBitBucketServerReport(t *testing.T) {
+	var report bitBucketServerReport
+	var annotations bitBucketServerAnnotations
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				reportPath := "/rest/insights/1.0/projects/PROJ/repos/repo/commits/abc/reports/qodana-id"
+				switch {
+				case r.Method == http.MethodPut && r.URL.Path == reportPath:
+					assert.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+					w.WriteHeader(http.StatusOK)
+				case r.Method == http.MethodPost && r.URL.Path == reportPath+"/annotations":
+					assert.NoError(t, json.NewDecoder(r.Body).Decode(&annotations))
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	sarifReport, err := ReadReportFromString(sarifFileData)
+	assert.NoError(t, err)
+	annotation := buildAnnotation(&sarifReport.Runs[0].Results[0], "description", "https://qodana.cloud/report")
+
+	t.Setenv("BITBUCKET_PIPELINE_UUID", "")
+	t.Setenv("BITBUCKET_REPO_FULL_NAME", "PROJ/repo")
+	t.Setenv("BITBUCKET_COMMIT", "abc")
+	t.Setenv("QD_BITBUCKET_TOKEN", "token")
+	t.Setenv("QD_BITBUCKET_URL", server.URL+"/rest/api/1.0")
+	err = sendBitBucketReport([]bbapi.ReportAnnotation{annotation}, "Qodana for Go", "https://qodana.cloud/report", "qodana-id")
+	assert.NoError(t, err)
+
+	assert.Equal(t, bitBucketServerReportFailed, report.Result)
+	assert.Equal(t, "Qodana for Go", report.Title)
+	assert.Len(t, annotations.Annotations, 1)
+	assert.Equal(t, "src/main/java/AppStarter.java", annotations.Annotations[0].Path)
+	assert.Equal(t, int32(12), annotations.Annotations[0].Line)
+	assert.Equal(t, bitBucketAnnotationType, annotations.Annotations[0].Type)
+}
+
+func TestWriteCiProblems(t *testing.T) {
+	report, err := ReadReportFromString(sarifFileData)
+	assert.NoError(t, err)
+	report.Runs[0].Results[1].BaselineState = "unchanged"
+	report.Runs[0].Results[2].Message.Text = "100% wrong;\nsecond line"
+
+	t.Run("azure", func(t *testing.T) {
+		var out bytes.Buffer
+		writeCiProblems(&out, report, formatAzureIssue)
+		issues := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, issues, 4)
+		assert.True(t, strings.HasPrefix(issues[0], "##vso[task.logissue type="))
+		assert.Contains(t, issues[0], ";sourcepath=src/main/java/AppStarter.java;linenumber=12;")
+		assert.Contains(t, issues[0], ";code=GoUnusedExportedFunction;]GoUnusedExportedFunction: ")
+		assert.Contains(t, issues[1], "]ExampleNoteLevel: 100%AZP25 wrong;%0Asecond line")
+	})
+
+	t.Run("github", func(t *testing.T) {
+		var out bytes.Buffer
+		writeCiProblems(&out, report, formatGitHubAnnotation)
+		annotations := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, annotations, 4)
+		assert.Contains(t, annotations[0], " title=GoUnusedExportedFunction,file=src/main/java/AppStarter.java,line=12")
+		assert.True(t, strings.HasSuffix(annotations[1], "::100%25 wrong;%0Asecond line"))
+	})
+
+	t.Run("teamcity", func(t *testing.T) {
+		var out bytes.Buffer
+		writeCiProblems(&out, report, newTeamCityInspectionFormatter())
+		messages := strings.Split(strings.TrimSpace(out.String()), "\n")
+		// every rule is reported once as an inspection type
+		assert.Len(t, messages, 8)
+		assert.Equal(
+			t,
+			"##teamcity[inspectionType id='GoUnusedExportedFunction' name='GoUnusedExportedFunction' category='Qodana' description='GoUnusedExportedFunction']",
+			messages[0],
+		)
+		assert.Contains(t, messages[1], "##teamcity[inspection typeId='GoUnusedExportedFunction' message='Unused function |'SaveReportFile|''")
+		assert.Contains(t, messages[3], "message='100% wrong;|nsecond line' file='src/main/java/AppStarter.java' line='2'")
+	})
+
+	t.Run("vscode", func(t *testing.T) {
+		var out bytes.Buffer
+		projectDir := t.TempDir()
+		writeCiProblems(&out, report, newVsCodeProblemFormatter(projectDir))
+		problems := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, problems, 4)
+		pattern := regexp.MustCompile(VsCodeProblemPattern)
+		for _, problem := range problems {
+			assert.Regexp(t, pattern, problem)
+		}
+		match := pattern.FindStringSubmatch(problems[0])
+		assert.Equal(t, filepath.Join(projectDir, "src", "main", "java", "AppStarter.java"), match[2])
+		assert.Equal(t, "12", match[3])
+		assert.Equal(t, "GoUnusedExportedFunction", match[5])
+		assert.True(t, strings.HasSuffix(problems[1], ": 100% wrong; second line"))
+	})
+}
+
+func TestNewWarningsNgIssues(t *testing.T) {
+	report, err := ReadReportFromString(sarifFileData)
+	assert.NoError(t, err)
+	report.Runs[0].Results[1].BaselineState = "unchanged"
+
+	issues := newWarningsNgIssues(report)
+	assert.Len(t, issues.Issues, 4)
+	issue := issues.Issues[0]
+	assert.Equal(t, "src/main/java/AppStarter.java", issue.FileName)
+	assert.Equal(t, 12, issue.LineStart)
+	assert.Equal(t, "GoUnusedExportedFunction", issue.Type)
+	assert.Equal(t, "2faa123efwsfsdqwer144d723b5999101424efba41c6caf11e6da4c2d7622ae01", issue.Fingerprint)
+	assert.Equal(t, warningsNgOrigin, issue.Origin)
+	assert.Equal(t, warningsNgLow, issues.Issues[1].Severity)
+}
@@ -0,0 +1,376 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/template"
+
+	fexec "github.com/JetBrains/qodana-cli/internal/foundation/exec"
+	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	externalLinterBinary      = "binary"
+	externalLinterProductCode = "QDEXT"
+	externalLinterManifestExt = ".yaml"
+)
+
+// ExternalLinterManifest describes a third-party linter shipped as a standalone binary.
+//
+// Url, ChecksumUrl, Signature, Binary, Args and Sarif.Output are Go templates, see externalLinterTemplateData for
+// the available fields. The download is verified with Sha256 or with the checksum file at ChecksumUrl, one of them is
+// required with Url, and with the Sigstore bundle at Signature made with the PEM PublicKey.
+type ExternalLinterManifest struct {
+	Name            string              `yaml:"name"`
+	PresentableName string              `yaml:"presentableName"`
+	ProductCode     string              `yaml:"productCode"`
+	Version         string              `yaml:"version"`
+	Url             string              `yaml:"url"`
+	Sha256          string              `yaml:"sha256"`
+	ChecksumUrl     string              `yaml:"checksumUrl"`
+	Signature       string              `yaml:"signature"`
+	PublicKey       string              `yaml:"publicKey"`
+	Binary          string              `yaml:"binary"`
+	Args            []string            `yaml:"args"`
+	ExitCodes       []int               `yaml:"exitCodes"`
+	Sarif           ExternalLinterSarif `yaml:"sarif"`
+}
+
+// ExternalLinterSarif describes where the linter writes its SARIF report and how it is post-processed.
+type ExternalLinterSarif struct {
+	Output       string `yaml:"output"`
+	ToolName     string `yaml:"toolName"`
+	RuleIdPrefix string `yaml:"ruleIdPrefix"`
+}
+
+// externalLinterTemplateData is available in manifest templates.
+type externalLinterTemplateData struct {
+	OS         string
+	Arch       string
+	Version    string
+	ToolsDir   string
+	Binary     string
+	ProjectDir string
+	ResultsDir string
+	CacheDir   string
+	LogDir     string
+	SarifDir   string
+	SarifPath  string
+}
+
+// ExternalLinter runs a linter described by ExternalLinterManifest.
+type ExternalLinter struct {
+	Manifest ExternalLinterManifest
+}
+
+// ExternalLintersDir returns the directory where manifests of external linters are looked up by name.
+func ExternalLintersDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "JetBrains", "Qodana", "linters")
+}
+
+// FindExternalLinterManifest resolves either a path to a manifest or a name of a manifest registered in ExternalLintersDir.
+func FindExternalLinterManifest(nameOrPath string) (ExternalLinterManifest, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return LoadExternalLinterManifest(nameOrPath)
+	}
+	if dir := ExternalLintersDir(); dir != "" && !strings.ContainsAny(nameOrPath, `/\`) {
+		path := filepath.Join(dir, nameOrPath+externalLinterManifestExt)
+		if _, err := os.Stat(path); err == nil {
+			return LoadExternalLinterManifest(path)
+		}
+	}
+	return ExternalLinterManifest{}, fmt.Errorf("external linter manifest %s not found", nameOrPath)
+}
+
+// LoadExternalLinterManifest reads and validates the manifest.
+func LoadExternalLinterManifest(path string) (ExternalLinterManifest, error) {
+	var manifest ExternalLinterManifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if err = yaml.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse external linter manifest %s: %w", path, err)
+	}
+	if manifest.Name == "" {
+		return manifest, fmt.Errorf("external linter manifest %s: name is required", path)
+	}
+	if manifest.Binary == "" {
+		return manifest, fmt.Errorf("external linter manifest %s: binary is required", path)
+	}
+	if manifest.Url != "" && manifest.Sha256 == "" && manifest.ChecksumUrl == "" {
+		return manifest, fmt.Errorf("external linter manifest %s: sha256 or checksumUrl is required with url", path)
+	}
+	return manifest, nil
+}
+
+// LinterInfo returns the information about the linter used in the third-party scan pipeline.
+func (l ExternalLinter) LinterInfo() thirdpartyscan.LinterInfo {
+	m := l.Manifest
+	info := thirdpartyscan.LinterInfo{
+		ProductCode:           m.ProductCode,
+		LinterName:            m.Name,
+		LinterPresentableName: m.PresentableName,
+		LinterVersion:         m.Version,
+	}
+	if info.ProductCode == "" {
+		info.ProductCode = externalLinterProductCode
+	}
+	if info.LinterPresentableName == "" {
+		info.LinterPresentableName = m.Name
+	}
+	return info
+}
+
+// MountTools downloads the linter binary to the tools directory, or looks it up in PATH if no url is given.
+func (l ExternalLinter) MountTools(path string) (map[string]string, error) {
+	m := l.Manifest
+	data := externalLinterTemplateData{
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Version:  m.Version,
+		ToolsDir: path,
+	}
+	binary, err := renderExternalLinterTemplate(m.Binary, data)
+	if err != nil {
+		return nil, err
+	}
+	if m.Url == "" {
+		binaryPath, err := exec.LookPath(binary)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not installed: %w", binary, err)
+		}
+		return map[string]string{externalLinterBinary: binaryPath}, nil
+	}
+
+	url, err := renderExternalLinterTemplate(m.Url, data)
+	if err != nil {
+		return nil, err
+	}
+	toolDir := filepath.Join(path, m.Name, m.Version)
+	binaryPath := filepath.Join(toolDir, binary)
+	if _, err := os.Stat(binaryPath); err == nil {
+		return map[string]string{externalLinterBinary: binaryPath}, nil
+	}
+	if err = os.MkdirAll(toolDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	fileName, err := downloadFileName(url)
+	if err != nil {
+		return nil, err
+	}
+	downloadPath := filepath.Join(toolDir, fileName)
+	log.Debugf("Downloading %s from %s", m.Name, url)
+	if err = utils.DownloadFile(downloadPath, url, "", nil); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", m.Name, err)
+	}
+	verification, err := m.verification(url, data)
+	if err != nil {
+		return nil, err
+	}
+	if err = utils.VerifyDownload(downloadPath, verification); err != nil {
+		_ = os.Remove(downloadPath)
+		return nil, err
+	}
+	if isArchive(downloadPath) {
+		if err = Decompress(downloadPath, toolDir); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", downloadPath, err)
+		}
+		_ = os.Remove(downloadPath)
+	} else if downloadPath != binaryPath {
+		if err = os.Rename(downloadPath, binaryPath); err != nil {
+			return nil, err
+		}
+	}
+	if err = os.Chmod(binaryPath, 0o755); err != nil {
+		return nil, err
+	}
+	return map[string]string{externalLinterBinary: binaryPath}, nil
+}
+
+// RunAnalysis runs the linter binary and post-processes its SARIF report.
+func (l ExternalLinter) RunAnalysis(c thirdpartyscan.Context) error {
+	m := l.Manifest
+	utils.Bootstrap(c.QodanaYamlConfig().BootstrapSteps(), c.ProjectDir(), c.LogDir())
+
+	sarifDir := GetTmpResultsDir(c.ResultsDir())
+	if err := os.MkdirAll(sarifDir, os.ModePerm); err != nil {
+		return err
+	}
+	data := externalLinterTemplateData{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Version:    m.Version,
+		Binary:     c.MountInfo().CustomTools[externalLinterBinary],
+		ProjectDir: c.ProjectDir(),
+		ResultsDir: c.ResultsDir(),
+		CacheDir:   c.CacheDir(),
+		LogDir:     c.LogDir(),
+		SarifDir:   sarifDir,
+		SarifPath:  filepath.Join(sarifDir, m.Name+extension),
+	}
+	args, err := externalLinterArgs(m, data)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Running %s", strings.Join(args, " "))
+	ret, err := fexec.Exec(c.ProjectDir(), args[0], args[1:]...)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(externalLinterExitCodes(m), ret) {
+		return fmt.Errorf("analysis exited with code: %d", ret)
+	}
+
+	output := data.SarifPath
+	if m.Sarif.Output != "" {
+		if output, err = renderExternalLinterTemplate(m.Sarif.Output, data); err != nil {
+			return err
+		}
+	}
+	if err = postProcessExternalSarif(output, data.SarifPath, m); err != nil {
+		return err
+	}
+	_, err = MergeSarifReports(c, GetDeviceIdSalt()[0])
+	return err
+}
+
+// externalLinterArgs renders the command line of the linter, the binary is the first element.
+func externalLinterArgs(m ExternalLinterManifest, data externalLinterTemplateData) ([]string, error) {
+	args := []string{data.Binary}
+	for _, arg := range m.Args {
+		rendered, err := renderExternalLinterTemplate(arg, data)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, rendered)
+	}
+	return args, nil
+}
+
+func externalLinterExitCodes(m ExternalLinterManifest) []int {
+	if len(m.ExitCodes) == 0 {
+		return []int{0}
+	}
+	return m.ExitCodes
+}
+
+// postProcessExternalSarif applies the manifest SARIF settings and puts the report where MergeSarifReports finds it.
+func postProcessExternalSarif(output string, target string, m ExternalLinterManifest) error {
+	report, err := ReadReport(output)
+	if err != nil {
+		return fmt.Errorf("failed to read SARIF report of %s: %w", m.Name, err)
+	}
+	for _, run := range report.Runs {
+		if run.Tool != nil && run.Tool.Driver != nil {
+			if m.Sarif.ToolName != "" {
+				run.Tool.Driver.Name = m.Sarif.ToolName
+			}
+			if m.Version != "" && run.Tool.Driver.Version == "" {
+				run.Tool.Driver.Version = m.Version
+			}
+			if m.Sarif.RuleIdPrefix != "" {
+				rules := run.Tool.Driver.Rules
+				for i := range rules {
+					rules[i].Id = m.Sarif.RuleIdPrefix + rules[i].Id
+				}
+			}
+		}
+		if m.Sarif.RuleIdPrefix != "" {
+			for i := range run.Results {
+				run.Results[i].RuleId = m.Sarif.RuleIdPrefix + run.Results[i].RuleId
+			}
+		}
+	}
+	if output != target {
+		defer func() {
+			if err := os.Remove(output); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Warnf("Failed to remove %s: %s", output, err)
+			}
+		}()
+	}
+	return WriteReport(target, report)
+}
+
+func renderExternalLinterTemplate(text string, data externalLinterTemplateData) (string, error) {
+	tmpl, err := template.New("manifest").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+// downloadFileName returns the file name of the download URL path, without the query and the fragment.
+func downloadFileName(rawUrl string) (string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", rawUrl, err)
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || name == ".." {
+		return "", fmt.Errorf("no file name in the url %q", rawUrl)
+	}
+	return name, nil
+}
+
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, ".zip") || strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// verification returns how the download from url is verified.
+func (m ExternalLinterManifest) verification(url string, data externalLinterTemplateData) (utils.Verification, error) {
+	verification := utils.Verification{Sha256: m.Sha256, ChecksumRequired: true, PublicKey: m.PublicKey}
+	var err error
+	if m.Sha256 == "" && m.ChecksumUrl == "" {
+		return verification, fmt.Errorf("no sha256 or checksumUrl of %s to verify %s", m.Name, url)
+	}
+	if m.ChecksumUrl != "" {
+		if verification.ChecksumUrl, err = renderExternalLinterTemplate(m.ChecksumUrl, data); err != nil {
+			return verification, err
+		}
+	}
+	if m.Signature != "" {
+		if verification.SignatureUrl, err = renderExternalLinterTemplate(m.Signature, data); err != nil {
+			return verification, err
+		}
+	}
+	return verification, nil
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	"github.com/stretchr/testify/assert"
+)
+
+const testExternalLinterManifest = `
+name: golangci-lint
+presentableName: golangci-lint
+version: 1.61.0
+url: https://github.com/golangci/golangci-lint/releases/download/v{{.Version}}/golangci-lint-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz
+checksumUrl: https://github.com/golangci/golangci-lint/releases/download/v{{.Version}}/golangci-lint-{{.Version}}-checksums.txt
+binary: golangci-lint-{{.Version}}-{{.OS}}-{{.Arch}}/golangci-lint
+args:
+  - run
+  - --out-format=sarif:{{.SarifPath}}
+exitCodes: [0, 1]
+sarif:
+  toolName: golangci-lint
+  ruleIdPrefix: "go/"
+`
+
+func TestLoadExternalLinterManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golangci-lint.yaml")
+	if err := os.WriteFile(path, []byte(testExternalLinterManifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadExternalLinterManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "golangci-lint", m.Name)
+	assert.Equal(t, []int{0, 1}, m.ExitCodes)
+	assert.Equal(t, "go/", m.Sarif.RuleIdPrefix)
+
+	info := ExternalLinter{Manifest: m}.LinterInfo()
+	assert.Equal(t, externalLinterProductCode, info.ProductCode)
+	assert.Equal(t, "1.61.0", info.LinterVersion)
+
+	args, err := externalLinterArgs(
+		m, externalLinterTemplateData{Binary: "/tools/golangci-lint", SarifPath: "/results/tmp/golangci-lint.sarif.json"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(
+		t,
+		[]string{"/tools/golangci-lint", "run", "--out-format=sarif:/results/tmp/golangci-lint.sarif.json"},
+		args,
+	)
+}
+
+func TestLoadExternalLinterManifestInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.yaml")
+	if err := os.WriteFile(path, []byte("name: broken\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadExternalLinterManifest(path)
+	assert.Error(t, err)
+
+	_, err = FindExternalLinterManifest(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+
+	unverified := filepath.Join(t.TempDir(), "unverified.yaml")
+	manifest := "name: tool\nurl: https://example.com/tool.tar.gz\nbinary: tool\n"
+	if err = os.WriteFile(unverified, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadExternalLinterManifest(unverified)
+	assert.ErrorContains(t, err, "sha256 or checksumUrl is required")
+}
+
+func TestExternalLinterVerification(t *testing.T) {
+	m := ExternalLinterManifest{Name: "tool", ChecksumUrl: "https://example.com/{{.Version}}/checksums.txt"}
+	v, err := m.verification("https://example.com/1.0/tool.tar.gz", externalLinterTemplateData{Version: "1.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/1.0/checksums.txt", v.ChecksumUrl)
+	assert.True(t, v.ChecksumRequired)
+
+	m = ExternalLinterManifest{Name: "tool"}
+	_, err = m.verification("https://example.com/tool.tar.gz", externalLinterTemplateData{})
+	assert.Error(t, err, "the download must not be verified with a guessed checksum url")
+}
+
+func TestDownloadFileName(t *testing.T) {
+	name, err := downloadFileName("https://example.com/releases/tool-1.0.tar.gz?token=secret#latest")
+	assert.NoError(t, err)
+	assert.Equal(t, "tool-1.0.tar.gz", name)
+
+	_, err = downloadFileName("https://example.com/")
+	assert.Error(t, err)
+}
+
+func TestPostProcessExternalSarif(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.json")
+	target := filepath.Join(dir, "tmp", "linter.sarif.json")
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	report := &sarif.Report{
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{
+					Driver: &sarif.ToolComponent{
+						Name:  "linter",
+						Rules: []sarif.ReportingDescriptor{{Id: "unused"}},
+					},
+				},
+				Results: []sarif.Result{{RuleId: "unused"}},
+			},
+		},
+	}
+	if err := WriteReport(output, report); err != nil {
+		t.Fatal(err)
+	}
+
+	m := ExternalLinterManifest{
+		Name:    "linter",
+		Version: "1.0",
+		Sarif:   ExternalLinterSarif{ToolName: "Linter", RuleIdPrefix: "ext/"},
+	}
+	if err := postProcessExternalSarif(output, target, m); err != nil {
+		t.Fatal(err)
+	}
+
+	processed, err := ReadReport(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver := processed.Runs[0].Tool.Driver
+	assert.Equal(t, "Linter", driver.Name)
+	assert.Equal(t, "1.0", driver.Version)
+	assert.Equal(t, "ext/unused", driver.Rules[0].Id)
+	assert.Equal(t, "ext/unused", processed.Runs[0].Results[0].RuleId)
+	assert.NoFileExists(t, output)
+}
@@ -0,0 +1,263 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fingerprint describes the environment a scan runs in, to explain the scan time or result changes
+// caused by a different machine, container engine or image rather than by the code.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// FileName is the file in the results directory the fingerprint is written to, it's uploaded with the report.
+	FileName = "environment.json"
+
+	// NativeEngine is the engine of the scans run without a container.
+	NativeEngine = "native"
+
+	stateDirName = "environment"
+
+	// memoryDriftRatio is the relative memory change considered material, the memory available to the container
+	// engine fluctuates a little between the runs on the same machine.
+	memoryDriftRatio = 0.25
+)
+
+// Fingerprint is the normalized environment of a scan.
+type Fingerprint struct {
+	Engine        string `json:"engine"`
+	EngineVersion string `json:"engineVersion,omitempty"`
+	Os            string `json:"os"`
+	Arch          string `json:"arch"`
+	Kernel        string `json:"kernel,omitempty"`
+	Cpus          int    `json:"cpus"`
+	MemoryGb      int    `json:"memoryGb"`
+	ImageDigest   string `json:"imageDigest,omitempty"`
+}
+
+// Environment is the environment of a scan as reported by the host or the container engine.
+type Environment struct {
+	Engine        string
+	EngineVersion string
+	Os            string
+	Arch          string
+	Kernel        string
+	Cpus          int
+	MemoryBytes   int64
+	ImageDigest   string
+}
+
+// Fingerprint returns the normalized fingerprint of the environment: the kernel is reduced to its major and minor
+// versions, the patch updates don't change the analysis, and the memory is rounded to gigabytes.
+func (e Environment) Fingerprint() Fingerprint {
+	return Fingerprint{
+		Engine:        e.Engine,
+		EngineVersion: e.EngineVersion,
+		Os:            strings.ToLower(e.Os),
+		Arch:          normalizeArch(e.Arch),
+		Kernel:        normalizeKernel(e.Kernel),
+		Cpus:          e.Cpus,
+		MemoryGb:      int(math.Round(float64(e.MemoryBytes) / (1 << 30))),
+		ImageDigest:   e.ImageDigest,
+	}
+}
+
+// Host returns the environment of the machine the CLI runs on, for the native scans.
+func Host() Environment {
+	kernel, err := host.KernelVersion()
+	if err != nil {
+		log.Debugf("Failed to get the kernel version: %s", err)
+	}
+	var memory int64
+	if stat, err := mem.VirtualMemory(); err != nil {
+		log.Debugf("Failed to get the memory size: %s", err)
+	} else {
+		memory = int64(stat.Total)
+	}
+	return Environment{
+		Engine:      NativeEngine,
+		Os:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Kernel:      kernel,
+		Cpus:        runtime.NumCPU(),
+		MemoryBytes: memory,
+	}
+}
+
+// normalizeArch converts the architecture names of the container engines to the Go ones.
+func normalizeArch(arch string) string {
+	switch arch = strings.ToLower(arch); arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	default:
+		return arch
+	}
+}
+
+func normalizeKernel(kernel string) string {
+	kernel = strings.TrimSpace(kernel)
+	end := len(kernel)
+	dots := 0
+	for i, c := range kernel {
+		if c == '.' {
+			dots++
+		}
+		if dots == 2 || (c != '.' && (c < '0' || c > '9')) {
+			end = i
+			break
+		}
+	}
+	return kernel[:end]
+}
+
+// Drift returns the material differences of the current fingerprint from the previous one, as "name: old → new".
+func Drift(previous Fingerprint, current Fingerprint) []string {
+	var changes []string
+	changed := func(name string, from string, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", name, unknownIfEmpty(from), unknownIfEmpty(to)))
+		}
+	}
+	changed("engine", previous.Engine, current.Engine)
+	if previous.Engine == current.Engine {
+		changed("engine version", previous.EngineVersion, current.EngineVersion)
+	}
+	changed("platform", previous.Os+"/"+previous.Arch, current.Os+"/"+current.Arch)
+	changed("kernel", previous.Kernel, current.Kernel)
+	if previous.Cpus != current.Cpus {
+		changed("CPUs", fmt.Sprint(previous.Cpus), fmt.Sprint(current.Cpus))
+	}
+	if memoryDrifted(previous.MemoryGb, current.MemoryGb) {
+		changed("memory", fmt.Sprintf("%d GB", previous.MemoryGb), fmt.Sprintf("%d GB", current.MemoryGb))
+	}
+	changed("image", previous.ImageDigest, current.ImageDigest)
+	return changes
+}
+
+func memoryDrifted(previous int, current int) bool {
+	if previous == 0 || current == 0 {
+		return previous != current
+	}
+	return math.Abs(float64(current-previous))/float64(previous) > memoryDriftRatio
+}
+
+func unknownIfEmpty(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// Record writes the fingerprint of the environment to FileName in resultsDir, to be uploaded with the report,
+// and warns if it differs materially from the previous scan of the branch, the fingerprint of every scan is kept in
+// cacheDir to be compared with the next one.
+func Record(environment Environment, resultsDir string, cacheDir string, branch string) {
+	current := environment.Fingerprint()
+	if err := writeJson(filepath.Join(resultsDir, FileName), current); err != nil {
+		log.Warnf("Failed to write %s: %s", FileName, err)
+	}
+	if branch == "" {
+		return
+	}
+	path := filepath.Join(cacheDir, stateDirName, url.PathEscape(branch)+".json")
+	previous, err := read(path)
+	if err != nil {
+		log.Debugf("Failed to read the previous scan environment: %s", err)
+	} else if previous != nil {
+		if changes := Drift(*previous, current); len(changes) > 0 {
+			msg.WarningMessageCI(
+				"The scan environment changed since the previous scan on branch %s (%s), the scan time and the results can differ because of it",
+				branch,
+				strings.Join(changes, ", "),
+			)
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		err = writeJson(path, current)
+	}
+	if err != nil {
+		log.Debugf("Failed to save the scan environment: %s", err)
+	}
+}
+
+// Branch returns the branch reported to Qodana Cloud, or the current branch of the repository outside CI,
+// empty if it's unknown.
+func Branch(repositoryRoot string, logDir string) string {
+	if branch := qdenv.GetQodanaGlobalEnv(qdenv.QodanaBranch); branch != "" {
+		return branch
+	}
+	if !utils.IsInstalled("git") {
+		return ""
+	}
+	branch, err := git.Branch(repositoryRoot, logDir)
+	if err != nil || branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
+
+// Load returns the fingerprint written by Record to resultsDir, nil if there is none.
+func Load(resultsDir string) (*Fingerprint, error) {
+	return read(filepath.Join(resultsDir, FileName))
+}
+
+// Hash returns the SHA-256 of the fingerprint, the same for the scans run in the same environment.
+func (f Fingerprint) Hash() string {
+	data, _ := json.Marshal(f)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func read(path string) (*Fingerprint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fingerprint Fingerprint
+	if err = json.Unmarshal(data, &fingerprint); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &fingerprint, nil
+}
+
+func writeJson(path string, fingerprint Fingerprint) error {
+	data, err := json.MarshalIndent(fingerprint, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fingerprint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dockerEnvironment() Environment {
+	return Environment{
+		Engine:        "docker",
+		EngineVersion: "27.1.1",
+		Os:            "linux",
+		Arch:          "x86_64",
+		Kernel:        "6.8.0-45-generic",
+		Cpus:          8,
+		MemoryBytes:   16*1024*1024*1024 - 300*1024*1024,
+		ImageDigest:   "jetbrains/qodana-jvm@sha256:aaa",
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(
+		t,
+		Fingerprint{
+			Engine:        "docker",
+			EngineVersion: "27.1.1",
+			Os:            "linux",
+			Arch:          "amd64",
+			Kernel:        "6.8",
+			Cpus:          8,
+			MemoryGb:      16,
+			ImageDigest:   "jetbrains/qodana-jvm@sha256:aaa",
+		},
+		dockerEnvironment().Fingerprint(),
+	)
+	assert.Equal(t, "23.4", normalizeKernel("23.4.0"))
+	assert.Equal(t, "5", normalizeKernel("5-custom"))
+	assert.Equal(t, "", normalizeKernel(""))
+}
+
+func TestDrift(t *testing.T) {
+	previous := dockerEnvironment().Fingerprint()
+	assert.Empty(t, Drift(previous, previous))
+
+	patched := dockerEnvironment()
+	patched.Kernel = "6.8.0-47-generic"
+	patched.MemoryBytes = 15 * 1024 * 1024 * 1024
+	assert.Empty(t, Drift(previous, patched.Fingerprint()), "patch updates and small memory changes are not material")
+
+	changed := dockerEnvironment()
+	changed.Cpus = 4
+	changed.MemoryBytes = 8 * 1024 * 1024 * 1024
+	changed.ImageDigest = "jetbrains/qodana-jvm@sha256:bbb"
+	assert.Equal(
+		t,
+		[]string{
+			"CPUs: 8 → 4",
+			"memory: 16 GB → 8 GB",
+			"image: jetbrains/qodana-jvm@sha256:aaa → jetbrains/qodana-jvm@sha256:bbb",
+		},
+		Drift(previous, changed.Fingerprint()),
+	)
+
+	native := Environment{Engine: NativeEngine, Os: "linux", Arch: "amd64", Kernel: "6.8.0", Cpus: 8, MemoryBytes: 16 << 30}
+	assert.Equal(
+		t,
+		[]string{"engine: docker → native", "image: jetbrains/qodana-jvm@sha256:aaa → unknown"},
+		Drift(previous, native.Fingerprint()),
+	)
+}
+
+func TestRecord(t *testing.T) {
+	resultsDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	Record(dockerEnvironment(), resultsDir, cacheDir, "feature/drift")
+
+	data, err := os.ReadFile(filepath.Join(resultsDir, FileName))
+	assert.NoError(t, err)
+	var written Fingerprint
+	assert.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, dockerEnvironment().Fingerprint(), written)
+
+	saved, err := read(filepath.Join(cacheDir, stateDirName, "feature%2Fdrift.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, &written, saved)
+
+	changed := dockerEnvironment()
+	changed.Cpus = 2
+	Record(changed, resultsDir, cacheDir, "feature/drift")
+	saved, err = read(filepath.Join(cacheDir, stateDirName, "feature%2Fdrift.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, saved.Cpus)
+}
@@ -86,6 +86,12 @@ func RevParse(cwd string, ref string, logdir string) (string, error) {
 	return strings.TrimSpace(stdout), err
 }
 
+// MergeBase returns the best common ancestor of the two commits, e.g. the commit a pull request branch started from.
+func MergeBase(cwd string, ref1 string, ref2 string, logdir string) (string, error) {
+	stdout, _, err := gitRun(cwd, []string{"merge-base", "--end-of-options", ref1, ref2}, logdir)
+	return strings.TrimSpace(stdout), err
+}
+
 // Reset resets the git repository to the given commit.
 func Reset(cwd string, sha string, logdir string) error {
 	_, _, err := gitRun(cwd, []string{"reset", "--soft", sha}, logdir)
@@ -266,6 +272,69 @@ func Clean(cwd string, logdir string) error {
 	return err
 }
 
+// HasChanges returns true when the tracked files under cwd have uncommitted changes, staged or not.
+func HasChanges(cwd string, logdir string) (bool, error) {
+	stdout, _, err := gitRun(cwd, []string{"status", "--porcelain", "--untracked-files=no", "--", "."}, logdir)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(stdout) != "", nil
+}
+
+// Snapshot returns the commit with the current state of the tracked files, HEAD when they have no uncommitted changes.
+// The working tree and the stash are left as they are.
+func Snapshot(cwd string, logdir string) (string, error) {
+	stdout, _, err := gitRun(cwd, []string{"stash", "create"}, logdir)
+	if err != nil {
+		return "", err
+	}
+	if snapshot := strings.TrimSpace(stdout); snapshot != "" {
+		return snapshot, nil
+	}
+	return CurrentRevision(cwd, logdir)
+}
+
+// Diff returns the changes of the tracked files under cwd since the revision as a unified diff with the paths
+// relative to cwd.
+func Diff(cwd string, revision string, logdir string) (string, error) {
+	stdout, _, err := gitRun(cwd, []string{"diff", "--no-color", "--no-ext-diff", "--relative", revision, "--", "."}, logdir)
+	return stdout, err
+}
+
+// CommitToNewBranch commits the changes of the tracked files under cwd to a new branch created from HEAD. When no
+// committer identity is configured, the commit is made as Qodana.
+func CommitToNewBranch(cwd string, branch string, message string, logdir string) error {
+	if _, _, err := gitRun(cwd, []string{"checkout", "-b", branch}, logdir); err != nil {
+		return err
+	}
+	if _, _, err := gitRun(cwd, []string{"add", "--update", "--", "."}, logdir); err != nil {
+		return err
+	}
+	command := []string{"commit", "--no-verify", "-m", message}
+	if email, _, _ := gitRun(cwd, []string{"config", "user.email"}, logdir); strings.TrimSpace(email) == "" {
+		command = append([]string{"-c", "user.name=Qodana", "-c", "user.email=qodana-support@jetbrains.com"}, command...)
+	}
+	_, _, err := gitRun(cwd, command, logdir)
+	return err
+}
+
+// Restore discards the uncommitted changes of the tracked files under cwd.
+func Restore(cwd string, logdir string) error {
+	_, _, err := gitRun(cwd, []string{"checkout", "HEAD", "--", "."}, logdir)
+	return err
+}
+
+// CloneShallow clones the last commit of the repository to dir, of the branch or the tag ref if it's not empty.
+func CloneShallow(repository string, ref string, dir string, logdir string) error {
+	command := []string{"clone", "--depth", "1", "--quiet"}
+	if ref != "" {
+		command = append(command, "--branch", ref)
+	}
+	command = append(command, "--", repository, dir)
+	_, _, err := gitRun(filepath.Dir(dir), command, logdir)
+	return err
+}
+
 // Revisions returns the list of commits of the git repository in chronological order.
 func Revisions(cwd string) []string {
 	return str.Reverse(Log(cwd, "%H", 0))
@@ -280,6 +349,54 @@ func Root(cwd string, logdir string) (string, error) {
 	return strings.TrimSpace(stdout), nil
 }
 
+// LsFiles returns the tracked and untracked not ignored files under cwd, relative to cwd.
+func LsFiles(cwd string, logdir string) ([]string, error) {
+	stdout, _, err := gitRun(cwd, []string{"ls-files", "--cached", "--others", "--exclude-standard", "-z"}, logdir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0)
+	for _, file := range strings.Split(stdout, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Renames returns the files under cwd moved or renamed since the given revision, mapping their paths at the revision
+// to their current paths in the working tree, both relative to cwd.
+func Renames(cwd string, revision string, logdir string) (map[string]string, error) {
+	stdout, _, err := gitRun(
+		cwd,
+		[]string{"diff", "--name-status", "--find-renames", "--relative", "-z", revision, "--"},
+		logdir,
+	)
+	if err != nil {
+		return nil, err
+	}
+	renames := make(map[string]string)
+	fields := strings.Split(stdout, "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
+			continue
+		}
+		if strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+			if i+2 >= len(fields) {
+				break
+			}
+			if strings.HasPrefix(status, "R") {
+				renames[fields[i+1]] = fields[i+2]
+			}
+			i += 2
+		} else {
+			i++
+		}
+	}
+	return renames, nil
+}
+
 // RemoteUrl returns the remote url of the git repository.
 func RemoteUrl(cwd string, logdir string) (string, error) {
 	stdout, _, err := gitRun(cwd, []string{"remote", "get-url", "origin"}, logdir)
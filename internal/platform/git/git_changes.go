@@ -53,40 +53,47 @@ type ChangedFiles struct {
 }
 
 func ComputeChangedFiles(cwd string, diffStart string, diffEnd string, logdir string) (ChangedFiles, error) {
-	absCwd, err := fs.Canonical(cwd)
+	// Rev-parsing references in advance helps with clearer error messages and in case references could be confused
+	// with `git diff` options.
+	diffStartSha, err := RevParse(cwd, diffStart, logdir)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
-	repoRoot, err := Root(cwd, logdir)
+	log.Debugf("Resolved git ref %q as %s", diffStart, diffStartSha)
+
+	diffEndSha, err := RevParse(cwd, diffEnd, logdir)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
-	absRepoRoot, err := fs.Canonical(repoRoot)
+	log.Debugf("Resolved git ref %q as %s", diffEnd, diffEndSha)
+
+	return computeChangedFiles(cwd, []string{diffStartSha, diffEndSha}, logdir)
+}
+
+// ComputeStagedChangedFiles returns the changes staged for the next commit, the way ComputeChangedFiles returns
+// the changes between two commits.
+func ComputeStagedChangedFiles(cwd string, logdir string) (ChangedFiles, error) {
+	return computeChangedFiles(cwd, []string{"--cached"}, logdir)
+}
+
+func computeChangedFiles(cwd string, diffArgs []string, logdir string) (ChangedFiles, error) {
+	absCwd, err := fs.Canonical(cwd)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
-
-	filePath, _ := fs.WeaklyCanonical(filepath.Join(logdir, "git-diff.log"))
-
-	// Rev-parsing references in advance helps with clearer error messages and in case references could be confused
-	// with `git diff` options.
-	diffStartSha, err := RevParse(cwd, diffStart, logdir)
+	repoRoot, err := Root(cwd, logdir)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
-	log.Debugf("Resolved git ref %q as %s", diffStart, diffStartSha)
-
-	diffEndSha, err := RevParse(cwd, diffEnd, logdir)
+	absRepoRoot, err := fs.Canonical(repoRoot)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
-	log.Debugf("Resolved git ref %q as %s", diffEnd, diffEndSha)
 
-	stdout, _, err := gitRun(
-		cwd,
-		[]string{"diff", diffStartSha, diffEndSha, "--unified=0", "--no-renames"},
-		logdir,
-	)
+	filePath, _ := fs.WeaklyCanonical(filepath.Join(logdir, "git-diff.log"))
+
+	command := append([]string{"diff"}, diffArgs...)
+	stdout, _, err := gitRun(cwd, append(command, "--unified=0", "--no-renames"), logdir)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
@@ -354,3 +354,21 @@ func createRepo(t *testing.T, tc TestConfig) string {
 	repo.CommitAll(tc.action + " file")
 	return repo.Dir()
 }
+
+func TestComputeStagedChangedFiles(t *testing.T) {
+	repo := NewGitRepo(t)
+	repo.WriteFile("staged.txt", "initial")
+	repo.WriteFile("unstaged.txt", "initial")
+	repo.CommitAll("initial")
+
+	repo.WriteFile("staged.txt", "initial\nmodified")
+	repo.Run("add", "staged.txt")
+	repo.WriteFile("unstaged.txt", "modified")
+
+	changes, err := ComputeStagedChangedFiles(repo.Dir(), t.TempDir())
+	assert.NoError(t, err)
+	if assert.Len(t, changes.Files, 1) {
+		assert.Equal(t, "staged.txt", filepath.Base(changes.Files[0].Path))
+		assert.Equal(t, []*ChangedRegion{{FirstLine: 1, Count: 2}}, changes.Files[0].Added)
+	}
+}
@@ -217,6 +217,62 @@ func TestClean(t *testing.T) {
 	assert.True(t, errors.Is(err, os.ErrNotExist))
 }
 
+func TestDiffAndRestore(t *testing.T) {
+	repo := NewGitRepo(t)
+	sub := filepath.Join(repo.Dir(), "sub")
+	assert.NoError(t, os.MkdirAll(sub, 0o755))
+	repo.WriteFile("sub/file.txt", "line\n")
+	repo.CommitAll("commit")
+	logdir := t.TempDir()
+
+	hasChanges, err := HasChanges(sub, logdir)
+	assert.NoError(t, err)
+	assert.False(t, hasChanges)
+
+	repo.WriteFile("sub/file.txt", "fixed line\n")
+	repo.WriteFile("sub/untracked.txt", "test")
+	hasChanges, err = HasChanges(sub, logdir)
+	assert.NoError(t, err)
+	assert.True(t, hasChanges)
+
+	diff, err := Diff(sub, "HEAD", logdir)
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "--- a/file.txt")
+	assert.Contains(t, diff, "+fixed line")
+
+	assert.NoError(t, Restore(sub, logdir))
+	assert.Equal(t, "line\n", repo.ReadFile("sub/file.txt"))
+	assert.FileExists(t, filepath.Join(sub, "untracked.txt"))
+}
+
+func TestSnapshotAndCommitToNewBranch(t *testing.T) {
+	repo := NewGitRepo(t)
+	repo.WriteFile("file.txt", "line\n")
+	repo.CommitAll("commit")
+	logdir := t.TempDir()
+
+	repo.WriteFile("file.txt", "changed line\n")
+	snapshot, err := Snapshot(repo.Dir(), logdir)
+	assert.NoError(t, err)
+	head, err := CurrentRevision(repo.Dir(), logdir)
+	assert.NoError(t, err)
+	assert.NotEqual(t, head, snapshot)
+
+	repo.WriteFile("file.txt", "fixed line\n")
+	diff, err := Diff(repo.Dir(), snapshot, logdir)
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "-changed line")
+	assert.Contains(t, diff, "+fixed line")
+
+	assert.NoError(t, CommitToNewBranch(repo.Dir(), "qodana-fixes", "fixes", logdir))
+	branch, err := Branch(repo.Dir(), logdir)
+	assert.NoError(t, err)
+	assert.Equal(t, "qodana-fixes", branch)
+	hasChanges, err := HasChanges(repo.Dir(), logdir)
+	assert.NoError(t, err)
+	assert.False(t, hasChanges)
+}
+
 func TestRevisions(t *testing.T) {
 	repo := NewGitRepo(t)
 	repo.CommitAll("commit")
@@ -239,6 +295,42 @@ func TestRemoteUrl(t *testing.T) {
 	assert.Equal(t, "https://github.com/test/repo.git", url)
 }
 
+func TestMergeBase(t *testing.T) {
+	logdir := t.TempDir()
+	repo := NewGitRepo(t)
+	base := repo.CommitAll("base")
+	repo.Run("checkout", "-b", "feature")
+	repo.CommitAll("feature")
+	repo.Checkout("main")
+	repo.CommitAll("main")
+
+	mergeBase, err := MergeBase(repo.Dir(), "main", "feature", logdir)
+	assert.NoError(t, err)
+	assert.Equal(t, base, mergeBase)
+}
+
+func TestRenames(t *testing.T) {
+	logdir := t.TempDir()
+	repo := NewGitRepo(t)
+	for _, dir := range []string{"app/src", "app/pkg", "docs"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(repo.Dir(), dir), 0755))
+	}
+	repo.WriteFile("app/src/main.go", "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n")
+	repo.WriteFile("app/util.go", "package main\n")
+	repo.WriteFile("docs/README.md", "# Sample project\n\nThe sample project.\n")
+	revision := repo.CommitAll("initial")
+
+	repo.Run("mv", "app/src/main.go", "app/pkg/main.go")
+	repo.Run("mv", "docs/README.md", "app/README.md")
+	repo.CommitAll("move")
+	repo.Run("mv", "app/util.go", "app/pkg/util.go") // not committed yet
+
+	renames, err := Renames(filepath.Join(repo.Dir(), "app"), revision, logdir)
+	assert.NoError(t, err)
+	// README.md is moved from outside the directory, so it is a new file there
+	assert.Equal(t, map[string]string{"src/main.go": "pkg/main.go", "util.go": "pkg/util.go"}, renames)
+}
+
 func TestBranch(t *testing.T) {
 	logdir := t.TempDir()
 	repo := NewGitRepo(t)
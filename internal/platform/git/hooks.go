@@ -0,0 +1,62 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HookMarker is the line of the hooks written by InstallHook, such hooks are overwritten without --force.
+const HookMarker = "# Installed by qodana hooks install"
+
+// HooksDir returns the absolute path of the hooks directory of the repository, core.hooksPath is respected.
+func HooksDir(cwd string, logdir string) (string, error) {
+	stdout, _, err := gitRun(cwd, []string{"rev-parse", "--git-path", "hooks"}, logdir)
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(stdout)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+	return dir, nil
+}
+
+// InstallHook writes the git hook with the given name and script body to the hooks directory of the repository and
+// returns its path. A hook not written by InstallHook is overwritten only with force.
+func InstallHook(cwd string, name string, script string, force bool, logdir string) (string, error) {
+	dir, err := HooksDir(cwd, logdir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if existing, err := os.ReadFile(path); err == nil && !force && !strings.Contains(string(existing), HookMarker) {
+		return "", fmt.Errorf("the %s hook already exists at %s, use --force to overwrite it", name, path)
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	content := "#!/bin/sh\n" + HookMarker + "\n" + script
+	if err = os.WriteFile(path, []byte(content), 0o755); err != nil {
+		return "", err
+	}
+	// WriteFile keeps the mode of an existing file
+	return path, os.Chmod(path, 0o755)
+}
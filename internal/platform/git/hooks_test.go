@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallHook(t *testing.T) {
+	repo := NewGitRepo(t)
+
+	path, err := InstallHook(repo.Dir(), "pre-commit", "exec qodana precommit\n", false, "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo.Dir(), ".git", "hooks", "pre-commit"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\n"+HookMarker+"\nexec qodana precommit\n", string(content))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	}
+
+	// the hook written before is updated
+	_, err = InstallHook(repo.Dir(), "pre-commit", "exec qodana precommit --timeout 1000\n", false, "")
+	assert.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nmake lint\n"), 0o755))
+	_, err = InstallHook(repo.Dir(), "pre-commit", "exec qodana precommit\n", false, "")
+	assert.Error(t, err)
+	_, err = InstallHook(repo.Dir(), "pre-commit", "exec qodana precommit\n", true, "")
+	assert.NoError(t, err)
+}
+
+func TestInstallHookRespectsHooksPath(t *testing.T) {
+	repo := NewGitRepo(t)
+	repo.Run("config", "core.hooksPath", ".githooks")
+
+	path, err := InstallHook(repo.Dir(), "pre-push", "exit 0\n", false, "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo.Dir(), ".githooks", "pre-push"), path)
+	assert.FileExists(t, path)
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The groupings of the problem counts of qodana history trend.
+const (
+	TrendBySeverity   = "severity"
+	TrendByInspection = "inspection"
+)
+
+const (
+	// trendOtherInspections is the series of the inspections not in the top ones.
+	trendOtherInspections = "other"
+	trendBarWidth         = 40
+)
+
+var trendSparks = []rune("▁▂▃▄▅▆▇█")
+
+// TrendSeries is the number of the problems of a severity or an inspection in each run.
+type TrendSeries struct {
+	Name   string `json:"name"`
+	Counts []int  `json:"counts"`
+}
+
+// Trend is the number of the problems across the kept runs, the runs without a report are skipped.
+type Trend struct {
+	By     string        `json:"by"`
+	Runs   []string      `json:"runs"`
+	Totals []int         `json:"totals"`
+	Series []TrendSeries `json:"series"`
+}
+
+// BuildTrend groups the problems of the runs by severity or by inspection, the top inspections with the most problems
+// across the runs are kept and the rest are summed up as "other".
+func BuildTrend(runs []HistoryRun, by string, top int) (Trend, error) {
+	if by != TrendBySeverity && by != TrendByInspection {
+		return Trend{}, fmt.Errorf("unknown trend grouping %q, use %s or %s", by, TrendBySeverity, TrendByInspection)
+	}
+	trend := Trend{By: by}
+	var complete []HistoryRun
+	for _, run := range runs {
+		if run.Complete {
+			complete = append(complete, run)
+			trend.Runs = append(trend.Runs, run.Name)
+			trend.Totals = append(trend.Totals, run.Total())
+		}
+	}
+
+	counts := func(run HistoryRun) map[string]int { return run.Problems }
+	names := HistorySeverities
+	if by == TrendByInspection {
+		counts = func(run HistoryRun) map[string]int { return run.Inspections }
+		names = topInspections(complete, top)
+	}
+	kept := map[string]bool{}
+	for _, name := range names {
+		kept[name] = true
+		series := TrendSeries{Name: name, Counts: make([]int, len(complete))}
+		for i, run := range complete {
+			series.Counts[i] = counts(run)[name]
+		}
+		trend.Series = append(trend.Series, series)
+	}
+	if by == TrendByInspection {
+		other := TrendSeries{Name: trendOtherInspections, Counts: make([]int, len(complete))}
+		hasOther := false
+		for i, run := range complete {
+			for name, count := range run.Inspections {
+				if !kept[name] {
+					other.Counts[i] += count
+					hasOther = true
+				}
+			}
+		}
+		if hasOther {
+			trend.Series = append(trend.Series, other)
+		}
+	}
+	return trend, nil
+}
+
+// topInspections returns the inspections with the most problems across the runs, the most frequent first.
+func topInspections(runs []HistoryRun, top int) []string {
+	sums := map[string]int{}
+	for _, run := range runs {
+		for name, count := range run.Inspections {
+			sums[name] += count
+		}
+	}
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Slice(
+		names, func(i, j int) bool {
+			if sums[names[i]] != sums[names[j]] {
+				return sums[names[i]] > sums[names[j]]
+			}
+			return names[i] < names[j]
+		},
+	)
+	if top > 0 && len(names) > top {
+		names = names[:top]
+	}
+	return names
+}
+
+// Text renders the trend for the console: a sparkline of each series from the first run to the last one and a bar of
+// the total of each run.
+func (t Trend) Text() string {
+	var b strings.Builder
+	if len(t.Runs) == 0 {
+		return "No runs with a report\n"
+	}
+	_, _ = fmt.Fprintf(&b, "Problems by %s in %d runs, %s to %s\n\n", t.By, len(t.Runs), t.Runs[0], t.Runs[len(t.Runs)-1])
+	nameWidth := len("total")
+	for _, series := range t.Series {
+		nameWidth = max(nameWidth, len(series.Name))
+	}
+	for _, series := range append(t.Series, TrendSeries{Name: "total", Counts: t.Totals}) {
+		first, last := series.Counts[0], series.Counts[len(series.Counts)-1]
+		_, _ = fmt.Fprintf(
+			&b,
+			"%-*s  %s  %d → %d (%s)\n",
+			nameWidth,
+			series.Name,
+			sparkline(series.Counts),
+			first,
+			last,
+			formatDelta(last-first),
+		)
+	}
+	b.WriteString("\n")
+
+	maxTotal := 0
+	for _, total := range t.Totals {
+		maxTotal = max(maxTotal, total)
+	}
+	for i, run := range t.Runs {
+		width := 0
+		if maxTotal > 0 {
+			width = t.Totals[i] * trendBarWidth / maxTotal
+		}
+		if width == 0 && t.Totals[i] > 0 {
+			width = 1
+		}
+		_, _ = fmt.Fprintf(&b, "%s  %-*s %d\n", run, trendBarWidth, strings.Repeat("█", width), t.Totals[i])
+	}
+	return b.String()
+}
+
+func sparkline(counts []int) string {
+	maxCount := 0
+	for _, count := range counts {
+		maxCount = max(maxCount, count)
+	}
+	sparks := make([]rune, len(counts))
+	for i, count := range counts {
+		level := 0
+		if maxCount > 0 {
+			level = count * (len(trendSparks) - 1) / maxCount
+		}
+		sparks[i] = trendSparks[level]
+	}
+	return string(sparks)
+}
+
+func formatDelta(delta int) string {
+	if delta > 0 {
+		return fmt.Sprintf("+%d", delta)
+	}
+	return fmt.Sprintf("%d", delta)
+}
+
+// trendColors are the colors of the series of the HTML chart, the severities are colored from critical to info.
+var trendColors = []string{
+	"#d32f2f", "#f57c00", "#fbc02d", "#388e3c", "#1976d2", "#7b1fa2", "#0097a7", "#5d4037", "#c2185b", "#455a64",
+	"#9e9e9e",
+}
+
+const (
+	trendChartWidth   = 960
+	trendChartHeight  = 320
+	trendChartPadding = 40
+)
+
+type trendHtmlLine struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+type trendHtmlData struct {
+	Trend  Trend
+	Lines  []trendHtmlLine
+	Width  int
+	Height int
+	Max    int
+	Bottom int
+	Right  int
+	Pad    int
+}
+
+var trendHtmlTemplate = template.Must(
+	template.New("trend").Funcs(template.FuncMap{"dec": func(i int) int { return i - 1 }}).Parse(
+		`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Qodana problems by {{.Trend.By}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 2em; color: #212121; }
+table { border-collapse: collapse; margin-top: 2em; }
+th, td { border: 1px solid #e0e0e0; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.legend span { display: inline-block; margin-right: 1.5em; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>Qodana problems by {{.Trend.By}}</h1>
+<p>{{len .Trend.Runs}} runs{{if .Trend.Runs}}, {{index .Trend.Runs 0}} to {{index .Trend.Runs (len .Trend.Runs | dec)}}{{end}}</p>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" role="img">
+<line x1="{{.Pad}}" y1="{{.Bottom}}" x2="{{.Right}}" y2="{{.Bottom}}" stroke="#9e9e9e"/>
+<line x1="{{.Pad}}" y1="{{.Pad}}" x2="{{.Pad}}" y2="{{.Bottom}}" stroke="#9e9e9e"/>
+<text x="{{.Pad}}" y="{{.Pad}}" dx="-6" text-anchor="end" font-size="12">{{.Max}}</text>
+<text x="{{.Pad}}" y="{{.Bottom}}" dx="-6" text-anchor="end" font-size="12">0</text>
+{{range .Lines}}<polyline fill="none" stroke="{{.Color}}" stroke-width="2" points="{{.Points}}"><title>{{.Name}}</title></polyline>
+{{end}}</svg>
+<div class="legend">{{range .Lines}}<span><i style="background: {{.Color}}"></i>{{.Name}}</span>{{end}}</div>
+<table>
+<tr><th>Run</th>{{range .Trend.Series}}<th>{{.Name}}</th>{{end}}<th>Total</th></tr>
+{{range $i, $run := .Trend.Runs}}<tr><td>{{$run}}</td>{{range $.Trend.Series}}<td>{{index .Counts $i}}</td>{{end}}<td>{{index $.Trend.Totals $i}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`,
+	),
+)
+
+// WriteHtml renders the trend as an HTML page with an SVG line chart of the series and a table of the counts.
+func (t Trend) WriteHtml(w io.Writer) error {
+	data := trendHtmlData{
+		Trend:  t,
+		Width:  trendChartWidth,
+		Height: trendChartHeight,
+		Pad:    trendChartPadding,
+		Bottom: trendChartHeight - trendChartPadding,
+		Right:  trendChartWidth - trendChartPadding,
+	}
+	for _, series := range t.Series {
+		for _, count := range series.Counts {
+			data.Max = max(data.Max, count)
+		}
+	}
+	plotWidth := float64(data.Right - data.Pad)
+	plotHeight := float64(data.Bottom - data.Pad)
+	for i, series := range t.Series {
+		points := make([]string, len(series.Counts))
+		for j, count := range series.Counts {
+			x := float64(data.Pad)
+			if len(series.Counts) > 1 {
+				x += plotWidth * float64(j) / float64(len(series.Counts)-1)
+			}
+			y := float64(data.Bottom)
+			if data.Max > 0 {
+				y -= plotHeight * float64(count) / float64(data.Max)
+			}
+			points[j] = fmt.Sprintf("%.1f,%.1f", x, y)
+		}
+		color := trendColors[len(trendColors)-1]
+		if series.Name != trendOtherInspections && i < len(trendColors)-1 {
+			color = trendColors[i]
+		}
+		data.Lines = append(data.Lines, trendHtmlLine{Name: series.Name, Color: color, Points: strings.Join(points, " ")})
+	}
+	return trendHtmlTemplate.Execute(w, data)
+}
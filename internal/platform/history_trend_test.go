@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func trendTestRuns() []HistoryRun {
+	return []HistoryRun{
+		{
+			Name:        "20240101-100000",
+			Complete:    true,
+			Problems:    map[string]int{"critical": 2, "high": 1},
+			Inspections: map[string]int{"A": 2, "B": 1},
+		},
+		{Name: "20240102-100000"},
+		{
+			Name:        "20240103-100000",
+			Complete:    true,
+			Problems:    map[string]int{"high": 3, "low": 1},
+			Inspections: map[string]int{"A": 1, "B": 2, "C": 1},
+		},
+	}
+}
+
+func TestBuildTrendBySeverity(t *testing.T) {
+	trend, err := BuildTrend(trendTestRuns(), TrendBySeverity, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"20240101-100000", "20240103-100000"}, trend.Runs)
+	assert.Equal(t, []int{3, 4}, trend.Totals)
+	assert.Equal(
+		t,
+		[]TrendSeries{
+			{Name: "critical", Counts: []int{2, 0}},
+			{Name: "high", Counts: []int{1, 3}},
+			{Name: "moderate", Counts: []int{0, 0}},
+			{Name: "low", Counts: []int{0, 1}},
+			{Name: "info", Counts: []int{0, 0}},
+		},
+		trend.Series,
+	)
+
+	text := trend.Text()
+	assert.Contains(t, text, "Problems by severity in 2 runs, 20240101-100000 to 20240103-100000")
+	assert.Contains(t, text, "critical  █▁  2 → 0 (-2)")
+	assert.Contains(t, text, "total     ▆█  3 → 4 (+1)")
+	assert.Contains(t, text, "20240103-100000  "+strings.Repeat("█", trendBarWidth)+" 4")
+}
+
+func TestBuildTrendByInspection(t *testing.T) {
+	trend, err := BuildTrend(trendTestRuns(), TrendByInspection, 2)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]TrendSeries{
+			{Name: "A", Counts: []int{2, 1}},
+			{Name: "B", Counts: []int{1, 2}},
+			{Name: "other", Counts: []int{0, 1}},
+		},
+		trend.Series,
+	)
+
+	_, err = BuildTrend(trendTestRuns(), "rule", 0)
+	assert.Error(t, err)
+}
+
+func TestTrendWriteHtml(t *testing.T) {
+	runs := trendTestRuns()
+	runs[0].Inspections["<script>"] = 1
+	trend, err := BuildTrend(runs, TrendByInspection, 0)
+	assert.NoError(t, err)
+
+	var html strings.Builder
+	assert.NoError(t, trend.WriteHtml(&html))
+	assert.Contains(t, html.String(), "2 runs, 20240101-100000 to 20240103-100000")
+	assert.Contains(t, html.String(), `<polyline fill="none" stroke="#d32f2f" stroke-width="2" points="40.0,40.0 920.0,160.0">`)
+	assert.Contains(t, html.String(), "&lt;script&gt;")
+	assert.NotContains(t, html.String(), "<script>")
+}
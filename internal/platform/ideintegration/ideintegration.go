@@ -0,0 +1,437 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ideintegration serves a running scan to the IDE Qodana plugin and other GUIs, enabled by
+// qodana scan --ide-integration: the stage progress and the problems found so far are sent to the connected clients,
+// and a client can cancel the scan like Ctrl+C.
+//
+// The protocol is JSON-RPC 2.0, one message per line, over a local TCP or unix socket. The address and the token of
+// the socket are printed on start as ##qodana[ide-integration address='...' token='...' protocolVersion='1'] (a JSON
+// line with --log-format json), the token can be set by the client with QODANA_IDE_INTEGRATION_TOKEN. A client sends
+// initialize with the token first, then it gets the current progress and the problems found so far, and the
+// progress, problem and finished notifications until the scan exits. The requests are initialize, status and cancel.
+package ideintegration
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProtocolVersion is the version of the protocol, it changes when the messages change incompatibly.
+const ProtocolVersion = 1
+
+const (
+	// writeTimeout limits a write to a client, a stuck client is disconnected instead of blocking the scan.
+	writeTimeout = 5 * time.Second
+	// maxMessageSize is the size of the largest request.
+	maxMessageSize   = 1024 * 1024
+	jsonRpcVersion   = "2.0"
+	unixSocketPrefix = "unix:"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	parseError     = -32700
+	invalidRequest = -32600
+	methodNotFound = -32601
+	invalidToken   = -32001
+	notInitialized = -32002
+)
+
+// Problem is a problem written by the linter to the SARIF report, sent with the problem notification.
+type Problem struct {
+	RuleId   string `json:"ruleId"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type notification struct {
+	JsonRpc string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type initializeParams struct {
+	Token string `json:"token"`
+	// Client is the name of the client, it's logged.
+	Client string `json:"client"`
+}
+
+type initializeResult struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	CliVersion      string `json:"cliVersion"`
+}
+
+type statusResult struct {
+	Progress any  `json:"progress"`
+	Problems int  `json:"problems"`
+	Finished bool `json:"finished"`
+}
+
+type cancelResult struct {
+	Cancelling bool `json:"cancelling"`
+}
+
+type finishedParams struct {
+	ExitCode int `json:"exitCode"`
+}
+
+var (
+	currentMu sync.Mutex
+	current   *server
+)
+
+// Start listens on the address, localhost:<port> (0 for a free port) or unix:<socket path>, and prints the handshake
+// line with the address and the token. The cancel request of a client calls cancel. It does nothing if the address is
+// empty.
+func Start(address string, cancel func()) error {
+	if address == "" {
+		return nil
+	}
+	listener, err := Listen(address)
+	if err != nil {
+		return fmt.Errorf("failed to listen for the IDE integration on %s: %w", address, err)
+	}
+	token := os.Getenv(qdenv.QodanaIdeIntegrationToken)
+	if token == "" {
+		if token, err = newToken(); err != nil {
+			_ = listener.Close()
+			return err
+		}
+	}
+	s := newServer(listener, token, cancel)
+	currentMu.Lock()
+	current = s
+	currentMu.Unlock()
+	go s.serve()
+	printHandshake(s.address(), token)
+	return nil
+}
+
+// Listen listens on the local address: localhost:<port>, :<port> for localhost too, or unix:<socket path>.
+func Listen(address string) (net.Listener, error) {
+	network, listenAddress := "tcp", address
+	if path, ok := strings.CutPrefix(address, unixSocketPrefix); ok {
+		network, listenAddress = "unix", path
+	} else if strings.HasPrefix(address, ":") {
+		// only the local clients are served
+		listenAddress = "127.0.0.1" + address
+	}
+	return net.Listen(network, listenAddress)
+}
+
+// Address returns the address of the listener in the form accepted by Listen.
+func Address(listener net.Listener) string {
+	addr := listener.Addr()
+	if addr.Network() == "unix" {
+		return unixSocketPrefix + addr.String()
+	}
+	return addr.String()
+}
+
+// IsEnabled tells whether the scan is served to the IDE.
+func IsEnabled() bool {
+	return getCurrent() != nil
+}
+
+// Progress sends the progress of the scan to the clients, the last one is sent to the clients connected later.
+func Progress(progress any) {
+	if s := getCurrent(); s != nil {
+		s.setProgress(progress)
+	}
+}
+
+// ReportProblem sends the problem found by the running linter to the clients.
+func ReportProblem(problem Problem) {
+	if s := getCurrent(); s != nil {
+		s.addProblem(problem)
+	}
+}
+
+// Finish sends the exit code of the scan to the clients and stops serving them, only the first call has an effect.
+func Finish(exitCode int) {
+	currentMu.Lock()
+	s := current
+	current = nil
+	currentMu.Unlock()
+	if s != nil {
+		s.finish(exitCode)
+	}
+}
+
+func getCurrent() *server {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	return current
+}
+
+func newToken() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("failed to generate the IDE integration token: %w", err)
+	}
+	return hex.EncodeToString(data), nil
+}
+
+func printHandshake(address string, token string) {
+	if msg.IsJsonLog() {
+		msg.PrintJsonLog(
+			"ideIntegration",
+			map[string]any{"address": address, "token": token, "protocolVersion": ProtocolVersion},
+		)
+		return
+	}
+	fmt.Printf("##qodana[ide-integration address='%s' token='%s' protocolVersion='%d']\n", address, token, ProtocolVersion)
+}
+
+// server keeps the state of the scan sent to the clients, the mutex orders the notifications.
+type server struct {
+	mu       sync.Mutex
+	listener net.Listener
+	token    string
+	cancel   func()
+	clients  map[*client]bool
+	progress any
+	problems []Problem
+	finished bool
+}
+
+type client struct {
+	conn        net.Conn
+	initialized bool
+}
+
+func newServer(listener net.Listener, token string, cancel func()) *server {
+	return &server{listener: listener, token: token, cancel: cancel, clients: make(map[*client]bool)}
+}
+
+func (s *server) address() string {
+	return Address(s.listener)
+}
+
+func (s *server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{conn: conn}
+		s.mu.Lock()
+		if s.finished {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.clients[c] = true
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *server) handle(c *client) {
+	defer s.disconnect(c)
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			s.respond(c, nil, nil, &responseError{Code: parseError, Message: err.Error()})
+			continue
+		}
+		if !s.handleRequest(c, req) {
+			return
+		}
+	}
+}
+
+// handleRequest answers the request, it returns false if the client must be disconnected.
+func (s *server) handleRequest(c *client, req request) bool {
+	if req.JsonRpc != jsonRpcVersion || req.Method == "" {
+		s.respond(c, req.Id, nil, &responseError{Code: invalidRequest, Message: "not a JSON-RPC 2.0 request"})
+		return true
+	}
+	s.mu.Lock()
+	initialized := c.initialized
+	s.mu.Unlock()
+	if req.Method == "initialize" {
+		return s.initialize(c, req)
+	}
+	if !initialized {
+		s.respond(c, req.Id, nil, &responseError{Code: notInitialized, Message: "initialize wasn't sent"})
+		return true
+	}
+	switch req.Method {
+	case "status":
+		s.mu.Lock()
+		result := statusResult{Progress: s.progress, Problems: len(s.problems), Finished: s.finished}
+		s.mu.Unlock()
+		s.respond(c, req.Id, result, nil)
+	case "cancel":
+		log.Debugf("The scan is cancelled by the IDE")
+		if s.cancel != nil {
+			s.cancel()
+		}
+		s.respond(c, req.Id, cancelResult{Cancelling: true}, nil)
+	default:
+		s.respond(c, req.Id, nil, &responseError{Code: methodNotFound, Message: "unknown method " + req.Method})
+	}
+	return true
+}
+
+// initialize checks the token, then it sends the current progress and the problems found so far.
+func (s *server) initialize(c *client, req request) bool {
+	var params initializeParams
+	if len(req.Params) > 0 {
+		_ = json.Unmarshal(req.Params, &params)
+	}
+	if subtle.ConstantTimeCompare([]byte(params.Token), []byte(s.token)) != 1 {
+		s.respond(c, req.Id, nil, &responseError{Code: invalidToken, Message: "invalid token"})
+		return false
+	}
+	log.Debugf("The IDE integration client %s is connected", params.Client)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(
+		c,
+		response{
+			JsonRpc: jsonRpcVersion,
+			Id:      req.Id,
+			Result:  initializeResult{ProtocolVersion: ProtocolVersion, CliVersion: version.Version},
+		},
+	)
+	if !c.initialized {
+		c.initialized = true
+		if s.progress != nil {
+			s.write(c, notification{JsonRpc: jsonRpcVersion, Method: "progress", Params: s.progress})
+		}
+		for _, problem := range s.problems {
+			s.write(c, notification{JsonRpc: jsonRpcVersion, Method: "problem", Params: problem})
+		}
+	}
+	return true
+}
+
+func (s *server) setProgress(progress any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = progress
+	s.broadcast("progress", progress)
+}
+
+func (s *server) addProblem(problem Problem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.problems = append(s.problems, problem)
+	s.broadcast("problem", problem)
+}
+
+func (s *server) finish(exitCode int) {
+	s.mu.Lock()
+	s.finished = true
+	s.broadcast("finished", finishedParams{ExitCode: exitCode})
+	clients := s.clients
+	s.clients = make(map[*client]bool)
+	s.mu.Unlock()
+	_ = s.listener.Close()
+	for c := range clients {
+		_ = c.conn.Close()
+	}
+}
+
+// broadcast sends the notification to the initialized clients, it's called with the mutex locked.
+func (s *server) broadcast(method string, params any) {
+	for c := range s.clients {
+		if c.initialized {
+			s.write(c, notification{JsonRpc: jsonRpcVersion, Method: method, Params: params})
+		}
+	}
+}
+
+// respond answers the request, the notifications from the client (without the id) aren't answered.
+func (s *server) respond(c *client, id json.RawMessage, result any, err *responseError) {
+	if len(id) == 0 && err == nil {
+		return
+	}
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.write(c, response{JsonRpc: jsonRpcVersion, Id: id, Result: result, Error: err})
+}
+
+// write sends the message to the client, it's called with the mutex locked. A client failing to read it in time is
+// disconnected.
+func (s *server) write(c *client, message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Errorf("Failed to marshal the IDE integration message: %s", err)
+		return
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err = c.conn.Write(append(data, '\n')); err != nil {
+		log.Debugf("Disconnecting the IDE integration client: %s", err)
+		delete(s.clients, c)
+		_ = c.conn.Close()
+	}
+}
+
+func (s *server) disconnect(c *client) {
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	_ = c.conn.Close()
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ideintegration
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClient struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+}
+
+func connect(t *testing.T, s *server) *testClient {
+	conn, err := net.Dial("tcp", s.address())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testClient{conn: conn, scanner: bufio.NewScanner(conn)}
+}
+
+func (c *testClient) send(t *testing.T, message string) {
+	_, err := c.conn.Write([]byte(message + "\n"))
+	require.NoError(t, err)
+}
+
+func (c *testClient) receive(t *testing.T) map[string]any {
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	require.True(t, c.scanner.Scan(), "no message: %v", c.scanner.Err())
+	var message map[string]any
+	require.NoError(t, json.Unmarshal(c.scanner.Bytes(), &message))
+	return message
+}
+
+func startTestServer(t *testing.T, cancel func()) *server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := newServer(listener, "secret", cancel)
+	go s.serve()
+	t.Cleanup(func() { s.finish(0) })
+	return s
+}
+
+func TestServer(t *testing.T) {
+	cancelled := false
+	s := startTestServer(t, func() { cancelled = true })
+	s.setProgress(map[string]any{"stage": 4, "percent": 10})
+	s.addProblem(Problem{RuleId: "UnusedDeclaration", Severity: "High", Message: "unused", Path: "a.kt", Line: 3})
+
+	c := connect(t, s)
+	c.send(t, `{"jsonrpc":"2.0","id":1,"method":"status"}`)
+	assert.EqualValues(t, notInitialized, c.receive(t)["error"].(map[string]any)["code"])
+
+	c.send(t, `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"token":"secret","client":"test"}}`)
+	initialized := c.receive(t)
+	assert.EqualValues(t, 2, initialized["id"])
+	assert.EqualValues(t, ProtocolVersion, initialized["result"].(map[string]any)["protocolVersion"])
+	progress := c.receive(t)
+	assert.Equal(t, "progress", progress["method"])
+	assert.EqualValues(t, 10, progress["params"].(map[string]any)["percent"])
+	problem := c.receive(t)
+	assert.Equal(t, "problem", problem["method"])
+	assert.Equal(t, "UnusedDeclaration", problem["params"].(map[string]any)["ruleId"])
+
+	s.addProblem(Problem{RuleId: "NullableProblems", Severity: "Moderate", Path: "b.kt", Line: 7})
+	assert.Equal(t, "NullableProblems", c.receive(t)["params"].(map[string]any)["ruleId"])
+
+	c.send(t, `{"jsonrpc":"2.0","id":3,"method":"status"}`)
+	assert.EqualValues(t, 2, c.receive(t)["result"].(map[string]any)["problems"])
+
+	c.send(t, `{"jsonrpc":"2.0","id":4,"method":"cancel"}`)
+	assert.Equal(t, true, c.receive(t)["result"].(map[string]any)["cancelling"])
+	assert.True(t, cancelled)
+
+	c.send(t, `{"jsonrpc":"2.0","id":5,"method":"rerun"}`)
+	assert.EqualValues(t, methodNotFound, c.receive(t)["error"].(map[string]any)["code"])
+	c.send(t, `not json`)
+	assert.EqualValues(t, parseError, c.receive(t)["error"].(map[string]any)["code"])
+
+	s.finish(255)
+	finished := c.receive(t)
+	assert.Equal(t, "finished", finished["method"])
+	assert.EqualValues(t, 255, finished["params"].(map[string]any)["exitCode"])
+	assert.False(t, c.scanner.Scan())
+}
+
+func TestServerRejectsInvalidToken(t *testing.T) {
+	s := startTestServer(t, nil)
+	c := connect(t, s)
+	c.send(t, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"token":"guess"}}`)
+	assert.EqualValues(t, invalidToken, c.receive(t)["error"].(map[string]any)["code"])
+	assert.False(t, c.scanner.Scan())
+}
+
+func TestStartWithoutAddress(t *testing.T) {
+	assert.NoError(t, Start("", nil))
+	assert.False(t, IsEnabled())
+	Progress(map[string]any{"stage": 1})
+	ReportProblem(Problem{RuleId: "rule"})
+	Finish(0)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ignoreSuppressionKind is the SARIF kind of the suppressions made by the ignore rules, they're not in the source.
+	ignoreSuppressionKind = "external"
+
+	ignoreSuppressionStatus = "accepted"
+	defaultIgnoreReason     = "Suppressed by an ignore rule of qodana.yaml"
+)
+
+// applyIgnoreRules marks the problems of the SARIF report matching the ignore rules of qodana.yaml as suppressed,
+// before the results are printed and uploaded. It returns the number of the suppressed problems.
+func applyIgnoreRules(sarifPath string, rules []qdyaml.IgnoreRule) int {
+	if len(rules) == 0 {
+		return 0
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Warnf("Failed to read %s to apply the ignore rules: %s", sarifPath, err)
+		return 0
+	}
+	suppressed := suppressIgnoredResults(report, rules)
+	if suppressed == 0 {
+		return 0
+	}
+	if err = WriteReport(sarifPath, report); err != nil {
+		log.Warnf("Failed to write %s with the ignore rules applied: %s", sarifPath, err)
+		return 0
+	}
+	msg.SuccessMessage("%d problems are suppressed by the ignore rules of qodana.yaml", suppressed)
+	return suppressed
+}
+
+// exitCodeWithoutIgnored returns the success exit code if the run failed on the fail thresholds, but they are not
+// exceeded without the suppressed problems.
+func exitCodeWithoutIgnored(sarifPath string, thresholds map[string]string, exitCode int) int {
+	if exitCode != exitcodes.QodanaFailThresholdExitCode || len(thresholds) == 0 {
+		return exitCode
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Warnf("Failed to read %s to check the fail thresholds: %s", sarifPath, err)
+		return exitCode
+	}
+	if len(exceededThresholds(report, thresholds)) == 0 {
+		log.Debugf("The fail thresholds are not exceeded without the suppressed problems")
+		return exitcodes.QodanaSuccessExitCode
+	}
+	return exitCode
+}
+
+// suppressIgnoredResults adds an external suppression to the results matching the rules, it returns the number of
+// the newly suppressed results.
+func suppressIgnoredResults(report *sarif.Report, rules []qdyaml.IgnoreRule) int {
+	suppressed := 0
+	for i := range report.Runs {
+		run := &report.Runs[i]
+		for j := range run.Results {
+			result := &run.Results[j]
+			if len(result.Suppressions) > 0 {
+				continue
+			}
+			rule, ok := matchingIgnoreRule(result, rules)
+			if !ok {
+				continue
+			}
+			reason := rule.Reason
+			if reason == "" {
+				reason = defaultIgnoreReason
+			}
+			result.Suppressions = append(
+				result.Suppressions,
+				sarif.Suppression{Kind: ignoreSuppressionKind, Status: ignoreSuppressionStatus, Justification: reason},
+			)
+			suppressed++
+		}
+	}
+	return suppressed
+}
+
+// matchingIgnoreRule returns the first rule matching the inspection and the file of the result.
+func matchingIgnoreRule(result *sarif.Result, rules []qdyaml.IgnoreRule) (qdyaml.IgnoreRule, bool) {
+	file := resultFile(result)
+	if file == "" {
+		return qdyaml.IgnoreRule{}, false
+	}
+	for _, rule := range rules {
+		if len(rule.Inspections) > 0 && !slices.Contains(rule.Inspections, result.RuleId) {
+			continue
+		}
+		if slices.ContainsFunc(rule.Paths, func(pattern string) bool { return matchesIgnorePath(file, pattern) }) {
+			return rule, true
+		}
+	}
+	return qdyaml.IgnoreRule{}, false
+}
+
+// resultFile returns the path of the file of the result relative to the project root.
+func resultFile(result *sarif.Result) string {
+	if len(result.Locations) == 0 {
+		return ""
+	}
+	location := result.Locations[0].PhysicalLocation
+	if location == nil || location.ArtifactLocation == nil {
+		return ""
+	}
+	file := location.ArtifactLocation.Uri
+	if unescaped, err := url.PathUnescape(file); err == nil {
+		file = unescaped
+	}
+	file = strings.TrimPrefix(file, "file://")
+	return strings.TrimPrefix(path.Clean("/"+file), "/")
+}
+
+// matchesIgnorePath returns true if the file is the path, is inside it, or matches it as a glob pattern where **
+// matches any number of directories. Like in .gitignore, a pattern without a slash matches the file name in any
+// directory.
+func matchesIgnorePath(file string, pattern string) bool {
+	pattern = strings.Trim(strings.TrimPrefix(pattern, "./"), "/")
+	if pattern == "" {
+		return false
+	}
+	if file == pattern || strings.HasPrefix(file, pattern+"/") {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	patternParts := strings.Split(pattern, "/")
+	fileParts := strings.Split(file, "/")
+	// a pattern matching a directory matches the files inside it
+	for end := len(fileParts); end > 0; end-- {
+		if matchGlobParts(patternParts, fileParts[:end]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlobParts(pattern []string, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchGlobParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern[0], parts[0])
+	return err == nil && matched && matchGlobParts(pattern[1:], parts[1:])
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+)
+
+func TestMatchesIgnorePath(t *testing.T) {
+	for _, testData := range []struct {
+		file     string
+		pattern  string
+		expected bool
+	}{
+		{"vendor/lib/a.go", "vendor", true},
+		{"vendor/lib/a.go", "./vendor/", true},
+		{"src/vendor/a.go", "vendor", true},
+		{"vendorized/a.go", "vendor", false},
+		{"src/gen/a.pb.go", "**/*.pb.go", true},
+		{"a.pb.go", "**/*.pb.go", true},
+		{"src/gen/a.pb.go", "*.pb.go", true},
+		{"src/gen/a.go", "src/*/a.go", true},
+		{"src/gen/deep/a.go", "src/*/a.go", false},
+		{"src/gen/deep/a.go", "src/**/a.go", true},
+		{"build/generated/sources/a.java", "**/generated", true},
+		{"src/a.go", "", false},
+	} {
+		if actual := matchesIgnorePath(testData.file, testData.pattern); actual != testData.expected {
+			t.Errorf("%s with %s: expected %v got %v", testData.file, testData.pattern, testData.expected, actual)
+		}
+	}
+}
+
+func TestApplyIgnoreRules(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	location := func(uri string) string {
+		return `[{"physicalLocation": {"artifactLocation": {"uri": "` + uri + `"}}}]`
+	}
+	report := `{
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {"driver": {"name": "QDGO"}},
+      "results": [
+        {"ruleId": "A", "message": {"text": "a"}, "locations": ` + location("vendor/a.go") + `},
+        {"ruleId": "B", "message": {"text": "b"}, "locations": ` + location("api/service.pb.go") + `},
+        {"ruleId": "A", "message": {"text": "a"}, "locations": ` + location("api/service.pb.go") + `},
+        {"ruleId": "C", "message": {"text": "c"}, "locations": ` + location("main.go") + `}
+      ]
+    }
+  ]
+}`
+	if err := os.WriteFile(sarifPath, []byte(report), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules := []qdyaml.IgnoreRule{
+		{Paths: []string{"vendor"}},
+		{Paths: []string{"**/*.pb.go"}, Inspections: []string{"B"}, Reason: "Generated code"},
+	}
+
+	if suppressed := applyIgnoreRules(sarifPath, rules); suppressed != 2 {
+		t.Errorf("expected 2 suppressed problems, got %d", suppressed)
+	}
+	exitCode := exitCodeWithoutIgnored(sarifPath, map[string]string{severityAny: "1"}, exitcodes.QodanaFailThresholdExitCode)
+	if exitCode != exitcodes.QodanaFailThresholdExitCode {
+		t.Errorf("expected the fail threshold exit code with 2 new problems, got %d", exitCode)
+	}
+	result, err := ReadReport(sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	justifications := make([]string, 0)
+	for _, r := range result.Runs[0].Results {
+		for _, suppression := range r.Suppressions {
+			justifications = append(justifications, suppression.Justification)
+		}
+	}
+	expected := []string{defaultIgnoreReason, "Generated code"}
+	if len(justifications) != len(expected) || justifications[0] != expected[0] || justifications[1] != expected[1] {
+		t.Errorf("expected suppressions %v got %v", expected, justifications)
+	}
+
+	rules = append(rules, qdyaml.IgnoreRule{Paths: []string{"main.go"}})
+	applyIgnoreRules(sarifPath, rules)
+	exitCode = exitCodeWithoutIgnored(sarifPath, map[string]string{severityAny: "1"}, exitcodes.QodanaFailThresholdExitCode)
+	if exitCode != exitcodes.QodanaSuccessExitCode {
+		t.Errorf("expected the success exit code with 1 new problem, got %d", exitCode)
+	}
+}
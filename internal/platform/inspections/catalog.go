@@ -0,0 +1,165 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inspections describes the inspections of the linters and the inspection profiles enabling them: the
+// catalog of the inspections available to a linter and the profile.yaml / XML profiles referenced in qodana.yaml.
+package inspections
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+//go:embed catalog.json
+var bundledCatalogJson []byte
+
+const (
+	// BundledSource is the source of the catalog bundled into the CLI, it lists the most common inspections only.
+	BundledSource = "bundled"
+)
+
+// Inspection describes an inspection available to a linter.
+type Inspection struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Severity    string `json:"severity,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Catalog is the list of the inspections available to a linter.
+type Catalog struct {
+	Linter string `json:"linter"`
+
+	// Source is where the inspections are taken from: "bundled" or the path of the report.
+	Source string `json:"source"`
+
+	// Complete is true when the catalog lists all inspections of the linter, the bundled one is not.
+	Complete bool `json:"complete"`
+
+	Inspections []Inspection `json:"inspections"`
+}
+
+type bundledInspection struct {
+	Inspection
+	Linters []string `json:"linters"`
+}
+
+// BundledCatalog returns the inspections of the linter from the catalog bundled into the CLI.
+func BundledCatalog(linter product.Linter) (Catalog, error) {
+	var bundled struct {
+		Inspections []bundledInspection `json:"inspections"`
+	}
+	if err := json.Unmarshal(bundledCatalogJson, &bundled); err != nil {
+		return Catalog{}, fmt.Errorf("failed to read the bundled inspections catalog: %w", err)
+	}
+	catalog := Catalog{Linter: linter.Name, Source: BundledSource}
+	for _, inspection := range bundled.Inspections {
+		if slices.Contains(inspection.Linters, linter.ProductCode) {
+			catalog.Inspections = append(catalog.Inspections, inspection.Inspection)
+		}
+	}
+	sortInspections(catalog.Inspections)
+	return catalog, nil
+}
+
+// CatalogFromSarif returns the inspections listed as the rules of a SARIF report produced by the linter. Qodana
+// reports list the rules of all inspections, not only the ones with problems.
+func CatalogFromSarif(linter product.Linter, path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Catalog{}, err
+	}
+	var report sarif.Report
+	if err = json.Unmarshal(data, &report); err != nil {
+		return Catalog{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	catalog := Catalog{Linter: linter.Name, Source: path, Complete: true}
+	seen := map[string]bool{}
+	for _, run := range report.Runs {
+		if run.Tool == nil {
+			continue
+		}
+		var components []sarif.ToolComponent
+		if run.Tool.Driver != nil {
+			components = append(components, *run.Tool.Driver)
+		}
+		components = append(components, run.Tool.Extensions...)
+		for _, component := range components {
+			for _, rule := range component.Rules {
+				if rule.Id == "" || seen[rule.Id] {
+					continue
+				}
+				seen[rule.Id] = true
+				catalog.Inspections = append(catalog.Inspections, inspectionFromRule(rule))
+			}
+		}
+	}
+	sortInspections(catalog.Inspections)
+	return catalog, nil
+}
+
+func inspectionFromRule(rule sarif.ReportingDescriptor) Inspection {
+	inspection := Inspection{Id: rule.Id, Name: rule.Name}
+	if rule.ShortDescription != nil && rule.ShortDescription.Text != "" {
+		inspection.Name = rule.ShortDescription.Text
+	}
+	if rule.FullDescription != nil {
+		inspection.Description = rule.FullDescription.Text
+	}
+	if rule.DefaultConfiguration != nil {
+		inspection.Severity = ruleSeverity(rule.DefaultConfiguration)
+	}
+	return inspection
+}
+
+// ruleSeverity returns the Qodana severity of the rule, or the SARIF level when the report has no Qodana severities.
+func ruleSeverity(configuration *sarif.ReportingConfiguration) string {
+	if configuration.Parameters != nil {
+		if severity, ok := configuration.Parameters.AdditionalProperties["qodanaSeverity"].(string); ok {
+			return severity
+		}
+	}
+	if level, ok := configuration.Level.(string); ok {
+		return level
+	}
+	return ""
+}
+
+func sortInspections(inspections []Inspection) {
+	slices.SortFunc(
+		inspections, func(a, b Inspection) int {
+			return strings.Compare(a.Id, b.Id)
+		},
+	)
+}
+
+// Find returns the inspection with the ID.
+func (c Catalog) Find(id string) (Inspection, bool) {
+	for _, inspection := range c.Inspections {
+		if inspection.Id == id {
+			return inspection, true
+		}
+	}
+	return Inspection{}, false
+}
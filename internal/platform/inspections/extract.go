@@ -0,0 +1,350 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inspections
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	inspectionDescriptionsDir = "inspectionDescriptions/"
+	messagesDir               = "messages/"
+)
+
+var (
+	htmlTagPattern      = regexp.MustCompile(`<[^>]*>`)
+	whitespacePattern   = regexp.MustCompile(`\s+`)
+	localeBundlePattern = regexp.MustCompile(`_[a-z]{2}(_[A-Z]{2})?\.properties$`)
+)
+
+// inspectionDeclaration is a <localInspection> or <globalInspection> of a plugin descriptor.
+type inspectionDeclaration struct {
+	ShortName           string `xml:"shortName,attr"`
+	DisplayName         string `xml:"displayName,attr"`
+	Key                 string `xml:"key,attr"`
+	Bundle              string `xml:"bundle,attr"`
+	GroupPath           string `xml:"groupPath,attr"`
+	GroupName           string `xml:"groupName,attr"`
+	GroupKey            string `xml:"groupKey,attr"`
+	GroupBundle         string `xml:"groupBundle,attr"`
+	Level               string `xml:"level,attr"`
+	ImplementationClass string `xml:"implementationClass,attr"`
+
+	// descriptorBundle is the <resource-bundle> of the descriptor, used when the inspection has no bundle.
+	descriptorBundle string
+}
+
+// catalogBuilder collects the inspections declared in the jars of an IDE distribution with their descriptions and
+// the message bundles their names are resolved from.
+type catalogBuilder struct {
+	declarations []inspectionDeclaration
+	descriptions map[string]string
+	bundles      map[string]map[string]string
+}
+
+func newCatalogBuilder() *catalogBuilder {
+	return &catalogBuilder{descriptions: map[string]string{}, bundles: map[string]map[string]string{}}
+}
+
+// CatalogFromIde returns the inspections of the IDE distribution in ideDir: the inspections declared in the plugin
+// descriptors of lib and plugins, with the descriptions from inspectionDescriptions.
+func CatalogFromIde(linter product.Linter, ideDir string) (Catalog, error) {
+	builder := newCatalogBuilder()
+	jars := 0
+	for _, dir := range []string{"lib", "plugins"} {
+		root := filepath.Join(ideDir, dir)
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		err := filepath.WalkDir(
+			root, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() || !strings.HasSuffix(path, ".jar") {
+					return err
+				}
+				jars++
+				return builder.addJarFile(path)
+			},
+		)
+		if err != nil {
+			return Catalog{}, err
+		}
+	}
+	if jars == 0 {
+		return Catalog{}, errors.New("no lib or plugins jars found in " + ideDir)
+	}
+	return builder.build(linter, ideDir), nil
+}
+
+// CatalogFromIdeTar returns the inspections of the IDE distribution streamed as a tar archive of its directory,
+// like the one a container engine returns when copying /opt/idea from a linter image.
+func CatalogFromIdeTar(linter product.Linter, r io.Reader, source string) (Catalog, error) {
+	builder := newCatalogBuilder()
+	tr := tar.NewReader(r)
+	jars := 0
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Catalog{}, err
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, ".jar") || !isIdeJar(header.Name) {
+			continue
+		}
+		jars++
+		if err = builder.addJarStream(tr); err != nil {
+			return Catalog{}, err
+		}
+	}
+	if jars == 0 {
+		return Catalog{}, errors.New("no lib or plugins jars found in " + source)
+	}
+	return builder.build(linter, source), nil
+}
+
+// isIdeJar reports whether the jar in the archive of the IDE directory is in its lib or plugins directory.
+func isIdeJar(name string) bool {
+	for _, part := range strings.Split(path.Dir(name), "/") {
+		if part == "lib" || part == "plugins" {
+			return true
+		}
+	}
+	return false
+}
+
+// addJarStream copies the jar to a temporary file, zip needs random access.
+func (b *catalogBuilder) addJarStream(r io.Reader) error {
+	tmp, err := os.CreateTemp("", "qodana-inspections-*.jar")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return err
+	}
+	reader, err := zip.NewReader(tmp, size)
+	if err != nil {
+		log.Debugf("Skipping a jar that can't be read: %s", err)
+		return nil
+	}
+	b.addJar(reader)
+	return nil
+}
+
+func (b *catalogBuilder) addJarFile(path string) error {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		log.Debugf("Skipping %s: %s", path, err)
+		return nil
+	}
+	defer func() { _ = reader.Close() }()
+	b.addJar(&reader.Reader)
+	return nil
+}
+
+func (b *catalogBuilder) addJar(reader *zip.Reader) {
+	for _, file := range reader.File {
+		name := file.Name
+		switch {
+		case strings.HasPrefix(name, inspectionDescriptionsDir) && strings.HasSuffix(name, ".html"):
+			id := strings.TrimSuffix(strings.TrimPrefix(name, inspectionDescriptionsDir), ".html")
+			if _, ok := b.descriptions[id]; !ok {
+				if data, err := readZipFile(file); err == nil {
+					b.descriptions[id] = htmlToText(data)
+				}
+			}
+		case strings.HasSuffix(name, ".xml") && (strings.HasPrefix(name, "META-INF/") || !strings.Contains(name, "/")):
+			if data, err := readZipFile(file); err == nil {
+				b.declarations = append(b.declarations, parseInspectionDeclarations(data)...)
+			}
+		case strings.Contains(name, messagesDir) && strings.HasSuffix(name, ".properties") && !localeBundlePattern.MatchString(name):
+			bundle := strings.ReplaceAll(strings.TrimSuffix(name, ".properties"), "/", ".")
+			if _, ok := b.bundles[bundle]; !ok {
+				if data, err := readZipFile(file); err == nil {
+					b.bundles[bundle] = parseProperties(data)
+				}
+			}
+		}
+	}
+}
+
+func (b *catalogBuilder) build(linter product.Linter, source string) Catalog {
+	catalog := Catalog{Linter: linter.Name, Source: source, Complete: true}
+	seen := map[string]bool{}
+	for _, declaration := range b.declarations {
+		id := declaration.ShortName
+		if id == "" {
+			id = shortNameOfClass(declaration.ImplementationClass)
+		}
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		name := b.message(declaration.DisplayName, declaration.Key, declaration.Bundle, declaration.descriptorBundle)
+		if name == "" {
+			name = id
+		}
+		category := b.message(
+			declaration.GroupName,
+			declaration.GroupKey,
+			declaration.GroupBundle,
+			declaration.Bundle,
+			declaration.descriptorBundle,
+		)
+		if declaration.GroupPath != "" {
+			category = strings.Join(append(strings.Split(declaration.GroupPath, ","), category), "/")
+		}
+		catalog.Inspections = append(
+			catalog.Inspections, Inspection{
+				Id:          id,
+				Name:        name,
+				Severity:    qodanaSeverity(declaration.Level),
+				Category:    strings.Trim(category, "/"),
+				Description: b.descriptions[id],
+			},
+		)
+	}
+	sortInspections(catalog.Inspections)
+	return catalog
+}
+
+// message returns the text, or the message with the key from the first of the bundles that has it.
+func (b *catalogBuilder) message(text string, key string, bundles ...string) string {
+	if text != "" || key == "" {
+		return text
+	}
+	for _, bundle := range bundles {
+		if value, ok := b.bundles[bundle][key]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseInspectionDeclarations returns the inspections declared in a plugin or a module descriptor.
+func parseInspectionDeclarations(data []byte) []inspectionDeclaration {
+	var declarations []inspectionDeclaration
+	descriptorBundle := ""
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch element.Name.Local {
+		case "resource-bundle":
+			var bundle string
+			if decoder.DecodeElement(&bundle, &element) == nil {
+				descriptorBundle = strings.TrimSpace(bundle)
+			}
+		case "localInspection", "globalInspection":
+			var declaration inspectionDeclaration
+			if decoder.DecodeElement(&declaration, &element) == nil {
+				declarations = append(declarations, declaration)
+			}
+		}
+	}
+	for i := range declarations {
+		declarations[i].descriptorBundle = descriptorBundle
+	}
+	return declarations
+}
+
+// shortNameOfClass returns the short name the IDE derives from the inspection class: the class name without
+// the package and the Inspection suffix.
+func shortNameOfClass(class string) string {
+	if class == "" {
+		return ""
+	}
+	name := class[strings.LastIndex(class, ".")+1:]
+	return strings.TrimSuffix(name, "Inspection")
+}
+
+// qodanaSeverity maps the level of an inspection in a plugin descriptor to the Qodana severity.
+func qodanaSeverity(level string) string {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return "Critical"
+	case "WARNING":
+		return "High"
+	case "WEAK WARNING":
+		return "Moderate"
+	case "TYPO", "INFO", "INFORMATION", "TEXT ATTRIBUTES":
+		return "Info"
+	case "":
+		return "High"
+	}
+	return "Low"
+}
+
+func parseProperties(data []byte) map[string]string {
+	properties := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		properties[strings.TrimSpace(key)] = strings.ReplaceAll(strings.TrimSpace(value), "''", "'")
+	}
+	return properties
+}
+
+func htmlToText(data []byte) string {
+	text := htmlTagPattern.ReplaceAllString(string(data), " ")
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(html.UnescapeString(text), " "))
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	return io.ReadAll(r)
+}
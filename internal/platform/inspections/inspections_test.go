@@ -0,0 +1,207 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inspections
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSarif = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {
+      "driver": {"name": "QDJVM", "rules": []},
+      "extensions": [{
+        "name": "org.jetbrains.java",
+        "rules": [
+          {"id": "ConstantValue", "shortDescription": {"text": "Constant values"}, "fullDescription": {"text": "Reports constant values."},
+           "defaultConfiguration": {"enabled": true, "level": "warning", "parameters": {"qodanaSeverity": "High"}}},
+          {"id": "JavadocReference", "shortDescription": {"text": "Javadoc references"}, "defaultConfiguration": {"enabled": true, "level": "error"}}
+        ]
+      }]
+    },
+    "results": []
+  }]
+}`
+
+func TestBundledCatalog(t *testing.T) {
+	catalog, err := BundledCatalog(product.PythonLinter)
+	require.NoError(t, err)
+	assert.Equal(t, BundledSource, catalog.Source)
+	assert.False(t, catalog.Complete)
+	inspection, ok := catalog.Find("PyUnresolvedReferences")
+	assert.True(t, ok)
+	assert.Equal(t, "High", inspection.Severity)
+	_, ok = catalog.Find("ConstantValue")
+	assert.False(t, ok)
+}
+
+func TestCatalogFromSarif(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	require.NoError(t, os.WriteFile(path, []byte(testSarif), 0o644))
+
+	catalog, err := CatalogFromSarif(product.JvmLinter, path)
+	require.NoError(t, err)
+	assert.True(t, catalog.Complete)
+	assert.Equal(
+		t, []Inspection{
+			{Id: "ConstantValue", Name: "Constant values", Severity: "High", Description: "Reports constant values."},
+			{Id: "JavadocReference", Name: "Javadoc references", Severity: "error"},
+		}, catalog.Inspections,
+	)
+}
+
+func TestNewProfile(t *testing.T) {
+	catalog := Catalog{
+		Linter:      "qodana-jvm",
+		Inspections: []Inspection{{Id: "ConstantValue", Name: "Constant values", Severity: "High"}},
+	}
+	dir := t.TempDir()
+
+	data, err := NewProfile(YamlFormat, "team", "qodana.starter", catalog)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "baseProfile: qodana.starter\n")
+	assert.Contains(t, string(data), "#- inspection: ConstantValue # Constant values\n")
+	yamlPath := filepath.Join(dir, "team.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, data, 0o644))
+	name, ids, err := ProfileInspections(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, "team", name)
+	assert.Empty(t, ids)
+
+	data, err = NewProfile(XmlFormat, "team", "", catalog)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<inspection_tool class="ConstantValue" enabled="true" level="WARNING" enabled_by_default="true">`)
+	xmlPath := filepath.Join(dir, "team.xml")
+	require.NoError(t, os.WriteFile(xmlPath, data, 0o644))
+	name, ids, err = ProfileInspections(xmlPath)
+	require.NoError(t, err)
+	assert.Equal(t, "team", name)
+	assert.Equal(t, []string{"ConstantValue"}, ids)
+
+	_, err = NewProfile("json", "team", "", catalog)
+	assert.Error(t, err)
+}
+
+func TestValidateProfile(t *testing.T) {
+	projectDir := t.TempDir()
+	catalog := Catalog{Linter: "qodana-jvm", Complete: true, Inspections: []Inspection{{Id: "ConstantValue"}}}
+	profilesDir := filepath.Join(projectDir, ".idea", "inspectionProfiles")
+	require.NoError(t, os.MkdirAll(profilesDir, 0o755))
+	require.NoError(
+		t, os.WriteFile(
+			filepath.Join(profilesDir, "Team.xml"),
+			[]byte(`<component name="InspectionProjectProfileManager"><profile version="1.0"><option name="myName" value="Team" /></profile></component>`),
+			0o644,
+		),
+	)
+	require.NoError(
+		t, os.WriteFile(
+			filepath.Join(projectDir, "profile.yaml"),
+			[]byte("baseProfile: qodana.starter\ninspections:\n  - inspection: ConstantValue\n  - inspection: Missing\n"),
+			0o644,
+		),
+	)
+
+	assert.Empty(t, ValidateProfile(projectDir, qdyaml.QodanaYaml{Profile: qdyaml.Profile{Name: "qodana.starter"}}, catalog))
+	assert.Empty(t, ValidateProfile(projectDir, qdyaml.QodanaYaml{Profile: qdyaml.Profile{Name: "Team"}}, catalog))
+
+	problems := ValidateProfile(projectDir, qdyaml.QodanaYaml{Profile: qdyaml.Profile{Name: "Other"}}, catalog)
+	require.Len(t, problems, 1)
+	assert.True(t, problems[0].Error)
+
+	problems = ValidateProfile(projectDir, qdyaml.QodanaYaml{Profile: qdyaml.Profile{Path: "missing.yaml"}}, catalog)
+	require.Len(t, problems, 1)
+	assert.True(t, problems[0].Error)
+
+	q := qdyaml.QodanaYaml{
+		Profile:  qdyaml.Profile{Path: "profile.yaml"},
+		Excludes: []qdyaml.Clude{{Name: "All", Paths: []string{"vendor"}}, {Name: "Unknown"}},
+	}
+	problems = ValidateProfile(projectDir, q, catalog)
+	assert.Equal(
+		t, []ProfileProblem{
+			{Message: "inspection Missing is not available in qodana-jvm"},
+			{Message: "inspection Unknown is not available in qodana-jvm"},
+		}, problems,
+	)
+	catalog.Complete = false
+	assert.Empty(t, ValidateProfile(projectDir, q, catalog))
+}
+
+func writeTestJar(t *testing.T, path string, files map[string]string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		entry, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = entry.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func TestCatalogFromIde(t *testing.T) {
+	ideDir := t.TempDir()
+	writeTestJar(
+		t, filepath.Join(ideDir, "plugins", "java", "lib", "java-impl.jar"), map[string]string{
+			"META-INF/plugin.xml": `<idea-plugin>
+  <resource-bundle>messages.JavaBundle</resource-bundle>
+  <extensions defaultExtensionNs="com.intellij">
+    <localInspection language="JAVA" shortName="ConstantValue" key="inspection.constant.value.display.name"
+                     groupPath="Java" groupKey="group.names.probable.bugs" level="WARNING"
+                     implementationClass="com.intellij.codeInspection.dataFlow.ConstantValueInspection"/>
+    <globalInspection displayName="Unused declaration" groupName="Declaration redundancy" level="ERROR"
+                      implementationClass="com.intellij.codeInspection.UnusedDeclarationInspection"/>
+  </extensions>
+</idea-plugin>`,
+			"messages/JavaBundle.properties":            "inspection.constant.value.display.name=Constant values\ngroup.names.probable.bugs=Probable bugs\n",
+			"messages/JavaBundle_zh.properties":         "inspection.constant.value.display.name=-\n",
+			"inspectionDescriptions/ConstantValue.html": "<html><body>Reports <b>constant</b> values &amp; conditions.</body></html>",
+		},
+	)
+	writeTestJar(t, filepath.Join(ideDir, "lib", "util.jar"), map[string]string{"util.txt": ""})
+
+	catalog, err := CatalogFromIde(product.JvmLinter, ideDir)
+	require.NoError(t, err)
+	assert.Equal(
+		t, []Inspection{
+			{
+				Id:          "ConstantValue",
+				Name:        "Constant values",
+				Severity:    "High",
+				Category:    "Java/Probable bugs",
+				Description: "Reports constant values & conditions.",
+			},
+			{Id: "UnusedDeclaration", Name: "Unused declaration", Severity: "Critical", Category: "Declaration redundancy"},
+		}, catalog.Inspections,
+	)
+
+	_, err = CatalogFromIde(product.JvmLinter, t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,327 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inspections
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"gopkg.in/yaml.v3"
+)
+
+// BuiltinProfiles are the profile names every linter understands.
+var BuiltinProfiles = []string{"qodana.starter", "qodana.recommended", "qodana.sanity", "empty"}
+
+const (
+	YamlFormat = "yaml"
+	XmlFormat  = "xml"
+)
+
+// DependencyInspections are the inspections of the dependencies: the vulnerability checks and the license audit.
+var DependencyInspections = []string{
+	"VulnerableLibrariesLocal",
+	"VulnerableLibrariesGlobal",
+	"CheckDependencyLicenses",
+	"CheckThirdPartySoftwareList",
+}
+
+// SlowInspections are the global inspections analyzing the whole project: the duplicates and the unused declarations
+// across the files. They take most of the analysis time of a large project.
+var SlowInspections = []string{
+	"DuplicatedCode",
+	"unused",
+	"WeakerAccess",
+	"SameParameterValue",
+	"JSUnusedGlobalSymbols",
+	"PhpUnused",
+	"RedundantSuppression",
+}
+
+// ideaProfilesDir is where the IDE keeps the inspection profiles of the project, they can be referenced by name.
+var ideaProfilesDir = filepath.Join(".idea", "inspectionProfiles")
+
+// YamlProfile is a profile.yaml: the inspections enabled or disabled on top of the base profile.
+type YamlProfile struct {
+	Name        string                  `yaml:"name,omitempty"`
+	BaseProfile string                  `yaml:"baseProfile,omitempty"`
+	Groups      []YamlProfileGroup      `yaml:"groups,omitempty"`
+	Inspections []YamlProfileInspection `yaml:"inspections,omitempty"`
+}
+
+// YamlProfileGroup is a named group of inspections referenced from the inspections of a profile.yaml.
+type YamlProfileGroup struct {
+	GroupId     string   `yaml:"groupId"`
+	Groups      []string `yaml:"groups,omitempty"`
+	Inspections []string `yaml:"inspections,omitempty"`
+}
+
+// YamlProfileInspection enables, disables or reconfigures an inspection or a group of inspections.
+type YamlProfileInspection struct {
+	Inspection string   `yaml:"inspection,omitempty"`
+	Group      string   `yaml:"group,omitempty"`
+	Enabled    *bool    `yaml:"enabled,omitempty"`
+	Severity   string   `yaml:"severity,omitempty"`
+	Ignore     []string `yaml:"ignore,omitempty"`
+}
+
+type xmlProfile struct {
+	Options []xmlOption `xml:"option"`
+	Tools   []xmlTool   `xml:"inspection_tool"`
+}
+
+type xmlOption struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type xmlTool struct {
+	Class            string `xml:"class,attr"`
+	Enabled          bool   `xml:"enabled,attr"`
+	Level            string `xml:"level,attr"`
+	EnabledByDefault bool   `xml:"enabled_by_default,attr"`
+}
+
+type xmlProfileFile struct {
+	XMLName xml.Name
+	xmlProfile
+	Component *xmlProfile `xml:"profile"`
+}
+
+// NewProfile returns a starter profile in the format: a profile.yaml extending the base profile with the
+// inspections of the catalog listed to be enabled or disabled, or an XML profile enabling them.
+func NewProfile(format string, name string, baseProfile string, catalog Catalog) ([]byte, error) {
+	switch format {
+	case YamlFormat:
+		return newYamlProfile(name, baseProfile, catalog)
+	case XmlFormat:
+		return newXmlProfile(name, catalog)
+	}
+	return nil, fmt.Errorf("unknown profile format %q, use %s or %s", format, YamlFormat, XmlFormat)
+}
+
+// DependenciesProfile returns a profile.yaml enabling only the DependencyInspections, for the analysis of the
+// dependencies without the inspection of the code.
+func DependenciesProfile() ([]byte, error) {
+	enabled := true
+	profile := YamlProfile{Name: "qodana.dependencies", BaseProfile: "empty"}
+	for _, id := range DependencyInspections {
+		profile.Inspections = append(profile.Inspections, YamlProfileInspection{Inspection: id, Enabled: &enabled})
+	}
+	return yaml.Marshal(profile)
+}
+
+// QuickProfile returns a profile.yaml extending qodana.starter without the SlowInspections, for the fast analysis of
+// a pull request.
+func QuickProfile() ([]byte, error) {
+	enabled := false
+	profile := YamlProfile{Name: "qodana.quick", BaseProfile: "qodana.starter"}
+	for _, id := range SlowInspections {
+		profile.Inspections = append(profile.Inspections, YamlProfileInspection{Inspection: id, Enabled: &enabled})
+	}
+	return yaml.Marshal(profile)
+}
+
+func newYamlProfile(name string, baseProfile string, catalog Catalog) ([]byte, error) {
+	data, err := yaml.Marshal(YamlProfile{Name: name, BaseProfile: baseProfile})
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	out.WriteString("# Qodana inspection profile, see https://www.jetbrains.com/help/qodana/custom-profiles.html\n")
+	out.WriteString("# Reference it from qodana.yaml with profile.path\n")
+	out.Write(data)
+	out.WriteString("inspections:\n")
+	out.WriteString("  # Disable the inspections of the base profile for the paths\n")
+	out.WriteString("  - group: ALL\n")
+	out.WriteString("    ignore:\n")
+	out.WriteString("      - \"vendor/**\"\n")
+	if len(catalog.Inspections) > 0 {
+		out.WriteString("  # The inspections of ")
+		out.WriteString(catalog.Linter)
+		out.WriteString(", uncomment to enable or disable them\n")
+	}
+	for _, inspection := range catalog.Inspections {
+		_, _ = fmt.Fprintf(&out, "  #- inspection: %s # %s\n  #  enabled: true\n", inspection.Id, inspection.Name)
+	}
+	return out.Bytes(), nil
+}
+
+func newXmlProfile(name string, catalog Catalog) ([]byte, error) {
+	profile := xmlProfile{Options: []xmlOption{{Name: "myName", Value: name}}}
+	for _, inspection := range catalog.Inspections {
+		profile.Tools = append(
+			profile.Tools,
+			xmlTool{Class: inspection.Id, Enabled: true, Level: ideaLevel(inspection.Severity), EnabledByDefault: true},
+		)
+	}
+	data, err := xml.MarshalIndent(
+		struct {
+			XMLName xml.Name   `xml:"component"`
+			Name    string     `xml:"name,attr"`
+			Profile xmlProfile `xml:"profile"`
+		}{Name: "InspectionProjectProfileManager", Profile: profile},
+		"",
+		"  ",
+	)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.Replace(data, []byte("<profile>"), []byte(`<profile version="1.0">`), 1)
+	return append(data, '\n'), nil
+}
+
+// ideaLevel maps a Qodana severity to the level of the inspection in an XML profile.
+func ideaLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "error":
+		return "ERROR"
+	case "low", "note":
+		return "WEAK WARNING"
+	case "info", "none":
+		return "INFORMATION"
+	}
+	return "WARNING"
+}
+
+// ProfileInspections returns the name of the profile file and the IDs of the inspections it references.
+func ProfileInspections(path string) (string, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var profile YamlProfile
+		if err = yaml.Unmarshal(data, &profile); err != nil {
+			return "", nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		var ids []string
+		for _, inspection := range profile.Inspections {
+			if inspection.Inspection != "" {
+				ids = append(ids, inspection.Inspection)
+			}
+		}
+		for _, group := range profile.Groups {
+			ids = append(ids, group.Inspections...)
+		}
+		return profile.Name, ids, nil
+	case ".xml":
+		var file xmlProfileFile
+		if err = xml.Unmarshal(data, &file); err != nil {
+			return "", nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		profile := file.xmlProfile
+		if file.XMLName.Local == "component" {
+			if file.Component == nil {
+				return "", nil, fmt.Errorf("%s has no profile element", path)
+			}
+			profile = *file.Component
+		}
+		var ids []string
+		for _, tool := range profile.Tools {
+			ids = append(ids, tool.Class)
+		}
+		name := ""
+		for _, option := range profile.Options {
+			if option.Name == "myName" {
+				name = option.Value
+			}
+		}
+		return name, ids, nil
+	}
+	return "", nil, fmt.Errorf("%s is not a .yaml or .xml profile", path)
+}
+
+// ProfileProblem is a problem found in the profile configuration of qodana.yaml.
+type ProfileProblem struct {
+	Error   bool   `json:"error"`
+	Message string `json:"message"`
+}
+
+// ValidateProfile checks the profile referenced in qodana.yaml: a profile name must be built-in or defined in
+// .idea/inspectionProfiles, a profile path must be a readable .yaml or .xml profile. The inspections referenced by
+// the profile and by include/exclude are reported when the catalog is complete and doesn't list them.
+func ValidateProfile(projectDir string, q qdyaml.QodanaYaml, catalog Catalog) []ProfileProblem {
+	var problems []ProfileProblem
+	addError := func(format string, args ...any) {
+		problems = append(problems, ProfileProblem{Error: true, Message: fmt.Sprintf(format, args...)})
+	}
+	addWarning := func(format string, args ...any) {
+		problems = append(problems, ProfileProblem{Message: fmt.Sprintf(format, args...)})
+	}
+
+	var referenced []string
+	if q.Profile.Name != "" && q.Profile.Path != "" {
+		addWarning("both profile.name and profile.path are set, profile.path %s is used", q.Profile.Path)
+	}
+	if q.Profile.Path != "" {
+		path := q.Profile.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+		_, ids, err := ProfileInspections(path)
+		if err != nil {
+			addError("profile.path: %s", err)
+		}
+		referenced = append(referenced, ids...)
+	} else if q.Profile.Name != "" && !slices.Contains(BuiltinProfiles, q.Profile.Name) {
+		if !hasIdeaProfile(projectDir, q.Profile.Name) {
+			addError(
+				"profile.name %s is neither a built-in profile (%s) nor a profile in %s",
+				q.Profile.Name,
+				strings.Join(BuiltinProfiles, ", "),
+				ideaProfilesDir,
+			)
+		}
+	}
+
+	for _, clude := range append(slices.Clone(q.Includes), q.Excludes...) {
+		if clude.Name != "All" {
+			referenced = append(referenced, clude.Name)
+		}
+	}
+	if catalog.Complete {
+		seen := map[string]bool{}
+		for _, id := range referenced {
+			if _, ok := catalog.Find(id); !ok && !seen[id] {
+				seen[id] = true
+				addWarning("inspection %s is not available in %s", id, catalog.Linter)
+			}
+		}
+	}
+	return problems
+}
+
+// hasIdeaProfile reports whether .idea/inspectionProfiles of the project has the profile with the name.
+func hasIdeaProfile(projectDir string, name string) bool {
+	files, err := filepath.Glob(filepath.Join(projectDir, ideaProfilesDir, "*.xml"))
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if profileName, _, err := ProfileInspections(file); err == nil && profileName == name {
+			return true
+		}
+	}
+	return false
+}
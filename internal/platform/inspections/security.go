@@ -0,0 +1,78 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inspections
+
+import (
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecurityClassification is the CWE weaknesses and the OWASP Top 10 2021 categories a security inspection detects.
+type SecurityClassification struct {
+	Cwe   []int
+	Owasp []string
+}
+
+// securityInspections are the security inspections of the linters by their IDs.
+var securityInspections = map[string]SecurityClassification{
+	// the vulnerable dependencies, all linters
+	"VulnerableLibrariesLocal":  {Cwe: []int{1395}, Owasp: []string{"A06:2021"}},
+	"VulnerableLibrariesGlobal": {Cwe: []int{1395}, Owasp: []string{"A06:2021"}},
+	"VulnerableCodeUsages":      {Cwe: []int{1395}, Owasp: []string{"A06:2021"}},
+	// the taint analysis of the JVM and PHP linters
+	"JvmTaintAnalysis":             {Cwe: []int{74}, Owasp: []string{"A03:2021"}},
+	"PhpVulnerablePathsInspection": {Cwe: []int{74}, Owasp: []string{"A03:2021"}},
+	// the Java security inspections
+	"JDBCExecuteWithNonConstantString":          {Cwe: []int{89}, Owasp: []string{"A03:2021"}},
+	"JDBCPrepareStatementWithNonConstantString": {Cwe: []int{89}, Owasp: []string{"A03:2021"}},
+	"RuntimeExecWithNonConstantString":          {Cwe: []int{78}, Owasp: []string{"A03:2021"}},
+	"LoadLibraryWithNonConstantString":          {Cwe: []int{114}, Owasp: []string{"A03:2021"}},
+	"UnsecureRandomNumberGeneration":            {Cwe: []int{330}, Owasp: []string{"A02:2021"}},
+	"DeserializableClassInSecureContext":        {Cwe: []int{502}, Owasp: []string{"A08:2021"}},
+	"SerializableClassInSecureContext":          {Cwe: []int{499}, Owasp: []string{"A08:2021"}},
+	"PublicStaticArrayField":                    {Cwe: []int{582}},
+	// the links with unencrypted protocols, all IDE linters
+	"HttpUrlsUsage": {Cwe: []int{319}, Owasp: []string{"A02:2021"}},
+}
+
+// SecurityClassificationOf returns the classification of the inspection, false if it's not a security inspection.
+func SecurityClassificationOf(id string) (SecurityClassification, bool) {
+	classification, ok := securityInspections[id]
+	return classification, ok
+}
+
+// SecurityInspections returns the IDs of the security inspections in the alphabetical order.
+func SecurityInspections() []string {
+	var ids []string
+	for id := range securityInspections {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// SecurityProfile returns a profile.yaml enabling only the security inspections, the linters skip the ones they
+// don't have.
+func SecurityProfile() ([]byte, error) {
+	enabled := true
+	profile := YamlProfile{Name: "qodana.security", BaseProfile: "empty"}
+	for _, id := range SecurityInspections() {
+		profile.Inspections = append(profile.Inspections, YamlProfileInspection{Inspection: id, Enabled: &enabled})
+	}
+	return yaml.Marshal(profile)
+}
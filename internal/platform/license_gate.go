@@ -0,0 +1,198 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/sbom"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// licenseAuditInspection reports the dependencies with the licenses prohibited by the licenseRules of qodana.yaml.
+const licenseAuditInspection = "CheckDependencyLicenses"
+
+// LicenseDenylist is the --fail-on-license file: the licenses the dependencies must not be used under.
+type LicenseDenylist struct {
+	// Prohibited are the SPDX IDs of the prohibited licenses, or patterns like GPL-*.
+	Prohibited []string `yaml:"prohibited"`
+
+	// AllowedDependencies are the names of the dependencies accepted whatever their licenses are.
+	AllowedDependencies []string `yaml:"allowedDependencies,omitempty"`
+}
+
+// licenseViolation is a dependency failing the license gate.
+type licenseViolation struct {
+	dependency string
+	licenses   string
+	reason     string
+}
+
+// LoadLicenseDenylist reads the --fail-on-license file.
+func LoadLicenseDenylist(file string) (LicenseDenylist, error) {
+	var denylist LicenseDenylist
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return denylist, err
+	}
+	if err = yaml.Unmarshal(data, &denylist); err != nil {
+		return denylist, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if len(denylist.Prohibited) == 0 {
+		return denylist, fmt.Errorf("no prohibited licenses in %s", file)
+	}
+	for _, pattern := range denylist.Prohibited {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return denylist, fmt.Errorf("invalid license pattern %q in %s: %w", pattern, file, err)
+		}
+	}
+	return denylist, nil
+}
+
+// LoadLicenseDenylistOrFatal validates --fail-on-license before the analysis is started.
+func LoadLicenseDenylistOrFatal(file string) {
+	if file == "" {
+		return
+	}
+	if _, err := LoadLicenseDenylist(file); err != nil {
+		log.Fatalf("Invalid --fail-on-license file: %s", err)
+	}
+}
+
+// ApplyLicenseGate checks the dependencies found by the license audit against the --fail-on-license denylist and
+// the prohibited license problems of the report. The offending dependencies are printed, and the fail threshold exit
+// code is returned for them if the run succeeded otherwise.
+func ApplyLicenseGate(resultsDir string, sarifPath string, denylistPath string, exitCode int) int {
+	if denylistPath == "" {
+		return exitCode
+	}
+	denylist, err := LoadLicenseDenylist(denylistPath)
+	if err != nil {
+		log.Fatalf("Invalid --fail-on-license file: %s", err)
+	}
+	violations := make([]licenseViolation, 0)
+	dependencies, err := sbom.ReadDependencies(resultsDir)
+	switch {
+	case errors.Is(err, sbom.ErrNoDependencies):
+		log.Warnf("The dependencies are not checked against %s: %s", denylistPath, err)
+	case err != nil:
+		log.Warnf("Failed to read the dependencies to check against %s: %s", denylistPath, err)
+	default:
+		violations = append(violations, deniedDependencies(dependencies, denylist, denylistPath)...)
+	}
+	if report, err := ReadReport(sarifPath); err != nil {
+		log.Warnf("Failed to read %s to check the license problems: %s", sarifPath, err)
+	} else {
+		violations = append(violations, licenseProblems(report)...)
+	}
+	if len(violations) == 0 {
+		return exitCode
+	}
+
+	msg.EmptyMessage()
+	msg.ErrorMessage("%d dependencies are used under prohibited licenses", len(violations))
+	tableData := pterm.TableData{[]string{"DEPENDENCY", "LICENSES", "REASON"}}
+	for _, v := range violations {
+		tableData = append(tableData, []string{v.dependency, v.licenses, v.reason})
+	}
+	if err = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render(); err != nil {
+		log.Debugf("Failed to print the license violations: %s", err)
+	}
+	if exitCode != exitcodes.QodanaSuccessExitCode {
+		return exitCode
+	}
+	return exitcodes.QodanaFailThresholdExitCode
+}
+
+// deniedDependencies returns the dependencies available only under the prohibited licenses: a dependency with
+// several licenses can be used under any of them.
+func deniedDependencies(
+	dependencies []sbom.Dependency,
+	denylist LicenseDenylist,
+	denylistPath string,
+) []licenseViolation {
+	violations := make([]licenseViolation, 0)
+	for _, d := range dependencies {
+		isAllowed := func(name string) bool { return strings.EqualFold(name, d.Name) }
+		if len(d.Licenses) == 0 || slices.ContainsFunc(denylist.AllowedDependencies, isAllowed) {
+			continue
+		}
+		keys := make([]string, 0, len(d.Licenses))
+		allowed := false
+		for _, l := range d.Licenses {
+			keys = append(keys, l.Key)
+			if !denylist.prohibits(l.Key) {
+				allowed = true
+			}
+		}
+		if allowed {
+			continue
+		}
+		name := d.Name
+		if d.Version != "" {
+			name += " " + d.Version
+		}
+		violations = append(
+			violations,
+			licenseViolation{dependency: name, licenses: strings.Join(keys, ", "), reason: "prohibited in " + denylistPath},
+		)
+	}
+	return violations
+}
+
+func (d LicenseDenylist) prohibits(license string) bool {
+	for _, pattern := range d.Prohibited {
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(license)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseProblems returns the new problems of the license audit, the dependencies with the licenses prohibited by
+// the licenseRules of qodana.yaml.
+func licenseProblems(report *sarif.Report) []licenseViolation {
+	violations := make([]licenseViolation, 0)
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if r.RuleId != licenseAuditInspection || !isNewProblem(r) {
+				continue
+			}
+			file := resultFile(r)
+			if file == "" {
+				file = "-"
+			}
+			text := ""
+			if r.Message != nil {
+				text = r.Message.Text
+			}
+			violations = append(violations, licenseViolation{dependency: file, licenses: "-", reason: text})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/sbom"
+)
+
+func TestDeniedDependencies(t *testing.T) {
+	denylist := LicenseDenylist{Prohibited: []string{"GPL-*", "AGPL-3.0-only"}, AllowedDependencies: []string{"accepted"}}
+	dependencies := []sbom.Dependency{
+		{Name: "gpl", Version: "1.0", Licenses: []sbom.License{{Key: "GPL-3.0-only"}}},
+		{Name: "dual", Version: "2.0", Licenses: []sbom.License{{Key: "gpl-2.0-only"}, {Key: "MIT"}}},
+		{Name: "agpl", Licenses: []sbom.License{{Key: "AGPL-3.0-only"}, {Key: "GPL-2.0-or-later"}}},
+		{Name: "accepted", Version: "1.0", Licenses: []sbom.License{{Key: "GPL-3.0-only"}}},
+		{Name: "unknown", Version: "1.0"},
+	}
+	expected := []licenseViolation{
+		{dependency: "gpl 1.0", licenses: "GPL-3.0-only", reason: "prohibited in denylist.yaml"},
+		{dependency: "agpl", licenses: "AGPL-3.0-only, GPL-2.0-or-later", reason: "prohibited in denylist.yaml"},
+	}
+	if actual := deniedDependencies(dependencies, denylist, "denylist.yaml"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v got %v", expected, actual)
+	}
+}
+
+func TestApplyLicenseGate(t *testing.T) {
+	dir := t.TempDir()
+	denylistPath := filepath.Join(dir, "denylist.yaml")
+	if err := os.WriteFile(denylistPath, []byte("prohibited:\n  - GPL-*\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sarifPath := filepath.Join(dir, "qodana.sarif.json")
+	report := `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}, "results": [
+  {"ruleId": "CheckDependencyLicenses", "message": {"text": "Dependency lib:1.0 is licensed under AGPL-3.0"}, "baselineState": "unchanged"}
+]}]}`
+	if err := os.WriteFile(sarifPath, []byte(report), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := ApplyLicenseGate(dir, sarifPath, denylistPath, exitcodes.QodanaSuccessExitCode); code != exitcodes.QodanaSuccessExitCode {
+		t.Errorf("expected success without new license problems, got %d", code)
+	}
+
+	report = `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}, "results": [
+  {"ruleId": "CheckDependencyLicenses", "message": {"text": "Dependency lib:1.0 is licensed under AGPL-3.0"}}
+]}]}`
+	if err := os.WriteFile(sarifPath, []byte(report), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := ApplyLicenseGate(dir, sarifPath, denylistPath, exitcodes.QodanaSuccessExitCode); code != exitcodes.QodanaFailThresholdExitCode {
+		t.Errorf("expected the fail threshold exit code, got %d", code)
+	}
+	if code := ApplyLicenseGate(dir, sarifPath, "", exitcodes.QodanaSuccessExitCode); code != exitcodes.QodanaSuccessExitCode {
+		t.Errorf("expected success without --fail-on-license, got %d", code)
+	}
+}
+
+func TestLoadLicenseDenylist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.yaml")
+	for content, valid := range map[string]bool{
+		"prohibited: [GPL-3.0-only]\nallowedDependencies: [lib]\n": true,
+		"prohibited: []\n":           false,
+		"prohibited: ['GPL-[']\n":    false,
+		"prohibited: GPL-3.0-only\n": false,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadLicenseDenylist(path); (err == nil) != valid {
+			t.Errorf("%q: expected valid=%v, got error %v", content, valid, err)
+		}
+	}
+}
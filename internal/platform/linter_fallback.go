@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+// linterFallbackProperty is the SARIF run property recording that the analysis was done by the fallback linter.
+const linterFallbackProperty = "qodana.linter.fallback"
+
+// LinterFallback is the substitution of the linter that failed to build the project model.
+type LinterFallback struct {
+	// Linter is the linter selected for the project.
+	Linter string `json:"linter"`
+	// ExitCode is the exit code of the analysis by Linter.
+	ExitCode int `json:"exitCode"`
+	// FallbackLinter is the linter that analyzed the project instead.
+	FallbackLinter string `json:"fallbackLinter"`
+}
+
+// ParseLinterFallback reads the substitution passed to the analysis by the fallback linter.
+func ParseLinterFallback(value string) (LinterFallback, error) {
+	var fallback LinterFallback
+	if err := json.Unmarshal([]byte(value), &fallback); err != nil {
+		return fallback, fmt.Errorf("failed to parse the linter fallback %q: %w", value, err)
+	}
+	return fallback, nil
+}
+
+// Encode returns the substitution in the form read by ParseLinterFallback.
+func (f LinterFallback) Encode() string {
+	data, _ := json.Marshal(f)
+	return string(data)
+}
+
+// IsProjectModelFailure tells if the analysis failed before producing the report, which happens when the linter can't
+// build the project model, e.g. qodana-cdnet on a project type it doesn't support.
+func IsProjectModelFailure(exitCode int, resultsDir string) bool {
+	switch exitCode {
+	case exitcodes.QodanaSuccessExitCode,
+		exitcodes.QodanaFailThresholdExitCode,
+		exitcodes.QodanaOutOfMemoryExitCode,
+		exitcodes.QodanaEapLicenseExpiredExitCode,
+		exitcodes.QodanaTimeoutExitCodePlaceholder,
+		exitcodes.QodanaEmptyChangesetExitCodePlaceholder:
+		return false
+	}
+	_, err := os.Stat(GetSarifPath(resultsDir))
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// RecordLinterFallback adds the substitution to the run properties of the reports in the results directory.
+func RecordLinterFallback(resultsDir string, fallback LinterFallback) error {
+	for _, path := range []string{GetSarifPath(resultsDir), GetShortSarifPath(resultsDir)} {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		report, err := ReadReport(path)
+		if err != nil {
+			return err
+		}
+		for i := range report.Runs {
+			run := &report.Runs[i]
+			if run.Properties == nil {
+				run.Properties = &sarif.PropertyBag{}
+			}
+			if run.Properties.AdditionalProperties == nil {
+				run.Properties.AdditionalProperties = map[string]any{}
+			}
+			run.Properties.AdditionalProperties[linterFallbackProperty] = fallback
+		}
+		if err = WriteReport(path, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
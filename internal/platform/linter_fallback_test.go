@@ -0,0 +1,57 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsProjectModelFailure(t *testing.T) {
+	resultsDir := t.TempDir()
+	assert.True(t, IsProjectModelFailure(1, resultsDir))
+	assert.False(t, IsProjectModelFailure(exitcodes.QodanaSuccessExitCode, resultsDir))
+	assert.False(t, IsProjectModelFailure(exitcodes.QodanaTimeoutExitCodePlaceholder, resultsDir))
+
+	assert.NoError(t, os.WriteFile(GetSarifPath(resultsDir), []byte(mergeSarifFirst), 0o644))
+	assert.False(t, IsProjectModelFailure(1, resultsDir))
+}
+
+func TestRecordLinterFallback(t *testing.T) {
+	fallback := LinterFallback{Linter: "qodana-cdnet", ExitCode: 1, FallbackLinter: "qodana-dotnet"}
+	parsed, err := ParseLinterFallback(fallback.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, fallback, parsed)
+	_, err = ParseLinterFallback("qodana-cdnet")
+	assert.Error(t, err)
+
+	resultsDir := t.TempDir()
+	assert.NoError(t, RecordLinterFallback(resultsDir, fallback))
+	assert.NoError(t, os.WriteFile(GetSarifPath(resultsDir), []byte(mergeSarifFirst), 0o644))
+	assert.NoError(t, RecordLinterFallback(resultsDir, fallback))
+
+	report, err := ReadReport(GetSarifPath(resultsDir))
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string]any{"linter": "qodana-cdnet", "exitCode": float64(1), "fallbackLinter": "qodana-dotnet"},
+		report.Runs[0].Properties.AdditionalProperties[linterFallbackProperty],
+	)
+}
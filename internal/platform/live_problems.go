@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/ideintegration"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+// liveProblemsInterval is how often the SARIF file is checked for the new problems.
+const liveProblemsInterval = 2 * time.Second
+
+// FollowSarifProblems sends the problems written to the SARIF file by the running linter to the IDE, and prints them if
+// printProblems is set, until the context is done. The file left by a previous run is ignored until it's rewritten.
+func FollowSarifProblems(ctx context.Context, sarifPath string, printProblems bool) {
+	tail := newSarifTail(sarifPath, time.Now())
+	ticker := time.NewTicker(liveProblemsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, r := range tail.poll() {
+				reportLiveProblem(&r, printProblems)
+			}
+		}
+	}
+}
+
+// sarifTail reads the SARIF file again after every change and returns the problems not returned before.
+type sarifTail struct {
+	path    string
+	since   time.Time
+	modTime time.Time
+	size    int64
+	seen    map[string]bool
+}
+
+func newSarifTail(path string, since time.Time) *sarifTail {
+	return &sarifTail{path: path, since: since, seen: make(map[string]bool)}
+}
+
+func (t *sarifTail) poll() []sarif.Result {
+	info, err := os.Stat(t.path)
+	if err != nil || info.ModTime().Before(t.since) {
+		return nil
+	}
+	if info.ModTime().Equal(t.modTime) && info.Size() == t.size {
+		return nil
+	}
+	report, err := ReadReport(t.path)
+	if err != nil {
+		// the linter is still writing the file, it's read again on the next poll
+		log.Debugf("Failed to read %s: %s", t.path, err)
+		return nil
+	}
+	t.modTime, t.size = info.ModTime(), info.Size()
+
+	var found []sarif.Result
+	for _, run := range report.Runs {
+		for _, r := range run.Results {
+			// only the new problems are printed, like in the report of the finished analysis
+			if !isNewProblem(&r) {
+				continue
+			}
+			key := liveProblemKey(&r)
+			if t.seen[key] {
+				continue
+			}
+			t.seen[key] = true
+			found = append(found, r)
+		}
+	}
+	return found
+}
+
+// liveProblemKey identifies the problem between the reads of the file, the fingerprints may not be computed yet.
+func liveProblemKey(r *sarif.Result) string {
+	if r.PartialFingerprints != nil {
+		for _, key := range []string{"equalIndicator/v2", "equalIndicator/v1"} {
+			if fingerprint, ok := r.PartialFingerprints[key]; ok {
+				return fingerprint
+			}
+		}
+	}
+	path, line, column := liveProblemLocation(r)
+	return fmt.Sprintf("%s:%s:%d:%d:%s", r.RuleId, path, line, column, r.Message.Text)
+}
+
+func liveProblemLocation(r *sarif.Result) (string, int, int) {
+	if len(r.Locations) == 0 || r.Locations[0].PhysicalLocation == nil {
+		return "", 0, 0
+	}
+	location := r.Locations[0].PhysicalLocation
+	path := ""
+	if location.ArtifactLocation != nil {
+		path = location.ArtifactLocation.Uri
+	}
+	if location.Region == nil {
+		return path, 0, 0
+	}
+	return path, int(location.Region.StartLine), int(location.Region.StartColumn)
+}
+
+func reportLiveProblem(r *sarif.Result, printProblem bool) {
+	path, line, column := liveProblemLocation(r)
+	ideintegration.ReportProblem(
+		ideintegration.Problem{
+			RuleId:   r.RuleId,
+			Severity: getSeverity(r),
+			Message:  r.Message.Text,
+			Path:     path,
+			Line:     line,
+			Column:   column,
+		},
+	)
+	if !printProblem {
+		return
+	}
+	if msg.IsJsonLog() {
+		msg.PrintJsonLog(
+			"problem", map[string]any{
+				"ruleId":   r.RuleId,
+				"severity": getSeverity(r),
+				"message":  r.Message.Text,
+				"path":     path,
+				"line":     line,
+			},
+		)
+		return
+	}
+	msg.PrintLiveProblem(getSeverity(r), r.RuleId, r.Message.Text, path, line)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+func writeLiveSarif(t *testing.T, path string, modTime time.Time, results ...string) {
+	content := `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}, "results": [` +
+		strings.Join(results, ",") + `]}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func ruleIds(results []sarif.Result) []string {
+	ids := make([]string, 0)
+	for _, r := range results {
+		ids = append(ids, r.RuleId)
+	}
+	return ids
+}
+
+func TestSarifTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	start := time.Now()
+	tail := newSarifTail(path, start)
+
+	if found := tail.poll(); len(found) != 0 {
+		t.Fatalf("expected no problems without the file, got %v", ruleIds(found))
+	}
+
+	a := `{"ruleId": "A", "message": {"text": "a"}}`
+	writeLiveSarif(t, path, start.Add(-time.Hour), a)
+	if found := tail.poll(); len(found) != 0 {
+		t.Fatalf("expected the file of the previous run to be ignored, got %v", ruleIds(found))
+	}
+
+	writeLiveSarif(t, path, start.Add(time.Second), a)
+	if found := ruleIds(tail.poll()); strings.Join(found, ",") != "A" {
+		t.Fatalf("expected [A] got %v", found)
+	}
+	if found := tail.poll(); len(found) != 0 {
+		t.Fatalf("expected no problems from the unchanged file, got %v", ruleIds(found))
+	}
+
+	b := `{"ruleId": "B", "message": {"text": "b"}, "locations": [{"physicalLocation": {"artifactLocation": {"uri": "Main.java"}, "region": {"startLine": 3, "startColumn": 1}}}]}`
+	c := `{"ruleId": "C", "message": {"text": "c"}, "baselineState": "unchanged"}`
+	writeLiveSarif(t, path, start.Add(2*time.Second), a, b, c)
+	if found := ruleIds(tail.poll()); strings.Join(found, ",") != "B" {
+		t.Fatalf("expected [B] got %v", found)
+	}
+
+	writeLiveSarif(t, path, start.Add(3*time.Second), a, `{"ruleId": "B", "message"`)
+	if found := tail.poll(); len(found) != 0 {
+		t.Fatalf("expected no problems from the partially written file, got %v", ruleIds(found))
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package logging configures the CLI logs: the console output of the requested level and format, and the log file of
+// the run with all the entries, written to the log directory of the results. The secrets are masked in both.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/redact"
+	log "github.com/sirupsen/logrus"
+)
+
+// Formats of the logs.
+const (
+	FormatText = "text"
+	FormatJson = "json"
+)
+
+const (
+	fileNamePrefix = "qodana-cli-"
+	fileNameSuffix = ".log"
+
+	// KeptFiles is the number of the CLI log files kept in the log directory, the older ones are removed.
+	KeptFiles = 10
+
+	// bufferedEntries is the number of the entries logged before the log file is started that are written to it.
+	bufferedEntries = 1000
+)
+
+// consoleHook prints the entries of the requested level to stderr, in the format of the logger.
+type consoleHook struct {
+	mu       sync.Mutex
+	levels   []log.Level
+	out      io.Writer
+	disabled bool
+}
+
+func (h *consoleHook) Levels() []log.Level {
+	return h.levels
+}
+
+func (h *consoleHook) Fire(entry *log.Entry) error {
+	line, err := formatRedacted(entry.Logger.Formatter, entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.disabled {
+		return nil
+	}
+	_, err = h.out.Write(line)
+	return err
+}
+
+// runLogHook writes all the entries to the log file of the run, the entries logged before the file is started are
+// kept in memory.
+type runLogHook struct {
+	mu        sync.Mutex
+	formatter log.Formatter
+	buffer    [][]byte
+	file      *os.File
+}
+
+func (h *runLogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *runLogHook) Fire(entry *log.Entry) error {
+	line, err := formatRedacted(h.formatter, entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.file != nil {
+		_, err = h.file.Write(line)
+		return err
+	}
+	if len(h.buffer) < bufferedEntries {
+		h.buffer = append(h.buffer, line)
+	}
+	return nil
+}
+
+// formatRedacted formats the entry with the secrets masked.
+func formatRedacted(formatter log.Formatter, entry *log.Entry) ([]byte, error) {
+	line, err := formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(redact.String(string(line))), nil
+}
+
+var (
+	console = &consoleHook{out: os.Stderr}
+	runLog  = &runLogHook{formatter: &log.TextFormatter{FullTimestamp: true, DisableColors: true}}
+)
+
+// Setup configures the logs of the command: the entries of the level are printed in the format, and all of them are
+// written to the log file of the run once it's started with StartRunLog.
+func Setup(level string, format string) error {
+	consoleLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatText:
+		runLog.formatter = &log.TextFormatter{FullTimestamp: true, DisableColors: true}
+	case FormatJson:
+		msg.EnableJsonLog()
+		runLog.formatter = &log.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown log format %s, supported formats: %s, %s", format, FormatText, FormatJson)
+	}
+	console.levels = slices.Clone(log.AllLevels[:consoleLevel+1])
+
+	// the entries are created for every level to be written to the log file, the console prints its level only
+	logger := log.StandardLogger()
+	logger.SetLevel(max(consoleLevel, log.DebugLevel))
+	logger.SetOutput(io.Discard)
+	logger.ReplaceHooks(log.LevelHooks{})
+	logger.AddHook(console)
+	logger.AddHook(runLog)
+	return nil
+}
+
+// DisableConsole stops printing the logs, e.g. when the CLI is interrupted. The log file is still written.
+func DisableConsole() {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	console.disabled = true
+}
+
+// StartRunLog writes the logs of the run to a new file in the log directory, starting with the entries logged so far,
+// and removes the oldest CLI log files.
+func StartRunLog(logDir string) {
+	if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
+		log.Warnf("Failed to create the log directory %s: %s", logDir, err)
+		return
+	}
+	name := fmt.Sprintf("%s%s-%d%s", fileNamePrefix, time.Now().Format("20060102-150405"), os.Getpid(), fileNameSuffix)
+	file, err := os.OpenFile(filepath.Join(logDir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Warnf("Failed to create the log file in %s: %s", logDir, err)
+		return
+	}
+
+	runLog.mu.Lock()
+	previous := runLog.file
+	runLog.file = file
+	for _, line := range runLog.buffer {
+		_, _ = file.Write(line)
+	}
+	runLog.buffer = nil
+	runLog.mu.Unlock()
+	if previous != nil {
+		_ = previous.Close()
+	}
+
+	if err = removeOldLogFiles(logDir, KeptFiles); err != nil {
+		log.Debugf("Failed to remove the old log files from %s: %s", logDir, err)
+	}
+	log.Debugf("Writing the CLI log to %s", file.Name())
+}
+
+// removeOldLogFiles keeps the given number of the newest CLI log files in the directory.
+func removeOldLogFiles(logDir string, keep int) error {
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, fileNamePrefix) && strings.HasSuffix(name, fileNameSuffix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	// the names start with the time of the run
+	slices.Sort(names)
+	for _, name := range names[:len(names)-keep] {
+		if err = os.Remove(filepath.Join(logDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/ideintegration"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DiagnosticsFramingLines writes one JSON-RPC message per line, for the editor jobs reading the output by lines.
+	DiagnosticsFramingLines = "lines"
+	// DiagnosticsFramingLsp writes the messages with the Content-Length headers of the Language Server Protocol.
+	DiagnosticsFramingLsp = "lsp"
+
+	publishDiagnosticsMethod = "textDocument/publishDiagnostics"
+	diagnosticsSource        = "qodana"
+	baselineStateAbsent      = "absent"
+	diagnosticsWriteTimeout  = 5 * time.Second
+)
+
+// LSP DiagnosticSeverity values.
+const (
+	lspSeverityError       = 1
+	lspSeverityWarning     = 2
+	lspSeverityInformation = 3
+	lspSeverityHint        = 4
+)
+
+// lspSeverities maps the threshold severities to the LSP severities.
+var lspSeverities = map[string]int{
+	"critical": lspSeverityError,
+	"high":     lspSeverityError,
+	"moderate": lspSeverityWarning,
+	"low":      lspSeverityInformation,
+	"info":     lspSeverityHint,
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	Uri         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+type publishDiagnosticsMessage struct {
+	JsonRpc string                   `json:"jsonrpc"`
+	Method  string                   `json:"method"`
+	Params  publishDiagnosticsParams `json:"params"`
+}
+
+// DiagnosticsOptions are the options of StreamDiagnostics.
+type DiagnosticsOptions struct {
+	SarifPath  string
+	ProjectDir string
+	// Follow publishes the changes of the report written by a running analysis until the context is done.
+	Follow bool
+	// Listen is the local address the diagnostics are served on (see ideintegration.Listen), stdout if empty.
+	Listen  string
+	Framing string
+}
+
+// ValidateDiagnosticsFraming checks the framing of the diagnostics messages.
+func ValidateDiagnosticsFraming(framing string) error {
+	if framing != DiagnosticsFramingLines && framing != DiagnosticsFramingLsp {
+		return fmt.Errorf(
+			"unsupported framing %q, the supported ones are %s and %s",
+			framing,
+			DiagnosticsFramingLines,
+			DiagnosticsFramingLsp,
+		)
+	}
+	return nil
+}
+
+// StreamDiagnostics publishes the problems of the SARIF report as LSP textDocument/publishDiagnostics notifications,
+// one per file, to stdout or to the clients connected to the listen address. With Follow or Listen, the changes of
+// the report are published until the context is done: the files without problems anymore get empty diagnostics.
+func StreamDiagnostics(ctx context.Context, out io.Writer, opts DiagnosticsOptions) error {
+	if err := ValidateDiagnosticsFraming(opts.Framing); err != nil {
+		return err
+	}
+	projectDir, err := filepath.Abs(opts.ProjectDir)
+	if err != nil {
+		return err
+	}
+	publisher := newDiagnosticsPublisher(opts.Framing)
+	if opts.Listen == "" {
+		publisher.addClient(out)
+	} else {
+		listener, err := ideintegration.Listen(opts.Listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen for the diagnostics clients on %s: %w", opts.Listen, err)
+		}
+		defer func() { _ = listener.Close() }()
+		_, _ = fmt.Fprintf(out, "Serving the Qodana diagnostics on %s\n", ideintegration.Address(listener))
+		go publisher.serve(listener)
+	}
+
+	tail := &diagnosticsTail{path: opts.SarifPath}
+	if err = tail.poll(publisher, projectDir); err != nil && !opts.Follow && opts.Listen == "" {
+		return err
+	}
+	if !opts.Follow && opts.Listen == "" {
+		return nil
+	}
+	ticker := time.NewTicker(liveProblemsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			publisher.close()
+			return nil
+		case <-ticker.C:
+			if err = tail.poll(publisher, projectDir); err != nil {
+				log.Debugf("Failed to read %s: %s", opts.SarifPath, err)
+			}
+		}
+	}
+}
+
+// diagnosticsTail reads the SARIF report again after every change.
+type diagnosticsTail struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+func (t *diagnosticsTail) poll(publisher *diagnosticsPublisher, projectDir string) error {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(t.modTime) && info.Size() == t.size {
+		return nil
+	}
+	report, err := ReadReport(t.path)
+	if err != nil {
+		// the linter is still writing the file, it's read again on the next poll
+		return err
+	}
+	t.modTime, t.size = info.ModTime(), info.Size()
+	publisher.publish(reportDiagnostics(report, projectDir))
+	return nil
+}
+
+// reportDiagnostics returns the diagnostics of the problems present in the project by the file URI: the fixed
+// problems of the baseline and the suppressed ones are left out.
+func reportDiagnostics(report *sarif.Report, projectDir string) map[string][]lspDiagnostic {
+	diagnostics := make(map[string][]lspDiagnostic)
+	for _, run := range report.Runs {
+		for _, r := range run.Results {
+			state, _ := r.BaselineState.(string)
+			if state == baselineStateAbsent || len(r.Suppressions) > 0 {
+				continue
+			}
+			path, diagnostic, ok := resultDiagnostic(&r)
+			if !ok {
+				continue
+			}
+			uri := fileUri(projectDir, path)
+			diagnostics[uri] = append(diagnostics[uri], diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+// resultDiagnostic converts the result to a diagnostic, the SARIF lines and columns are 1-based and the LSP ones are
+// 0-based. A result about the whole file is put on its first line.
+func resultDiagnostic(r *sarif.Result) (string, lspDiagnostic, bool) {
+	if len(r.Locations) == 0 || r.Locations[0].PhysicalLocation == nil {
+		return "", lspDiagnostic{}, false
+	}
+	location := r.Locations[0].PhysicalLocation
+	if location.ArtifactLocation == nil || location.ArtifactLocation.Uri == "" {
+		return "", lspDiagnostic{}, false
+	}
+	var start, end lspPosition
+	if region := location.Region; region != nil && region.StartLine > 0 {
+		start = lspPosition{Line: int(region.StartLine) - 1, Character: max(int(region.StartColumn)-1, 0)}
+		end = start
+		switch {
+		case region.EndLine > 0:
+			end = lspPosition{Line: int(region.EndLine) - 1, Character: max(int(region.EndColumn)-1, 0)}
+		case region.EndColumn > 0:
+			end.Character = int(region.EndColumn) - 1
+		case region.CharLength > 0:
+			end.Character = start.Character + int(region.CharLength)
+		}
+	}
+	severity, ok := lspSeverities[thresholdSeverityOf(getSeverity(r))]
+	if !ok {
+		severity = lspSeverityWarning
+	}
+	return location.ArtifactLocation.Uri, lspDiagnostic{
+		Range:    lspRange{Start: start, End: end},
+		Severity: severity,
+		Code:     r.RuleId,
+		Source:   diagnosticsSource,
+		Message:  r.Message.Text,
+	}, true
+}
+
+// fileUri returns the file URI of the path relative to the project directory.
+func fileUri(projectDir string, path string) string {
+	if u, err := url.Parse(path); err == nil && u.Scheme == "file" {
+		return path
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, filepath.FromSlash(path))
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// a Windows path, file:///C:/...
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// diagnosticsPublisher sends the changed diagnostics to the clients, a new client gets all the current ones.
+type diagnosticsPublisher struct {
+	mu        sync.Mutex
+	framing   string
+	clients   []io.Writer
+	published map[string][]lspDiagnostic
+}
+
+func newDiagnosticsPublisher(framing string) *diagnosticsPublisher {
+	return &diagnosticsPublisher{framing: framing, published: make(map[string][]lspDiagnostic)}
+}
+
+func (p *diagnosticsPublisher) addClient(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, uri := range sortedUris(p.published) {
+		if err := writeDiagnosticsMessage(w, p.framing, uri, p.published[uri]); err != nil {
+			log.Debugf("Failed to send the diagnostics: %s", err)
+			return
+		}
+	}
+	p.clients = append(p.clients, w)
+}
+
+func (p *diagnosticsPublisher) publish(diagnostics map[string][]lspDiagnostic) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var changed []string
+	for uri := range p.published {
+		if _, ok := diagnostics[uri]; !ok {
+			// the problems of the file are gone, the editor clears them with the empty diagnostics
+			changed = append(changed, uri)
+		}
+	}
+	for uri, fileDiagnostics := range diagnostics {
+		if !reflect.DeepEqual(p.published[uri], fileDiagnostics) {
+			changed = append(changed, uri)
+		}
+	}
+	sort.Strings(changed)
+	for _, uri := range changed {
+		fileDiagnostics := diagnostics[uri]
+		p.clients = sendToClients(
+			p.clients, func(w io.Writer) error {
+				return writeDiagnosticsMessage(w, p.framing, uri, fileDiagnostics)
+			},
+		)
+		if len(fileDiagnostics) == 0 {
+			delete(p.published, uri)
+		} else {
+			p.published[uri] = fileDiagnostics
+		}
+	}
+}
+
+func (p *diagnosticsPublisher) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		p.addClient(conn)
+	}
+}
+
+func (p *diagnosticsPublisher) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, w := range p.clients {
+		if conn, ok := w.(net.Conn); ok {
+			_ = conn.Close()
+		}
+	}
+	p.clients = nil
+}
+
+// sendToClients sends the message to the clients and drops the ones it failed for, e.g. the disconnected ones.
+func sendToClients(clients []io.Writer, send func(io.Writer) error) []io.Writer {
+	kept := clients[:0]
+	for _, w := range clients {
+		if err := send(w); err != nil {
+			log.Debugf("Dropping the diagnostics client: %s", err)
+			if conn, ok := w.(net.Conn); ok {
+				_ = conn.Close()
+			}
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return kept
+}
+
+func sortedUris(diagnostics map[string][]lspDiagnostic) []string {
+	uris := make([]string, 0, len(diagnostics))
+	for uri := range diagnostics {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// writeDiagnosticsMessage writes the publishDiagnostics notification of the file.
+func writeDiagnosticsMessage(w io.Writer, framing string, uri string, diagnostics []lspDiagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []lspDiagnostic{}
+	}
+	data, err := json.Marshal(
+		publishDiagnosticsMessage{
+			JsonRpc: "2.0",
+			Method:  publishDiagnosticsMethod,
+			Params:  publishDiagnosticsParams{Uri: uri, Diagnostics: diagnostics},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if conn, ok := w.(net.Conn); ok {
+		// a stuck client is dropped instead of blocking the others
+		_ = conn.SetWriteDeadline(time.Now().Add(diagnosticsWriteTimeout))
+	}
+	if framing == DiagnosticsFramingLsp {
+		_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
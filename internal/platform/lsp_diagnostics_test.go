@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readDiagnosticsMessages(t *testing.T, out string) []publishDiagnosticsMessage {
+	var messages []publishDiagnosticsMessage
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var message publishDiagnosticsMessage
+		require.NoError(t, json.Unmarshal([]byte(line), &message))
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestStreamDiagnostics(t *testing.T) {
+	projectDir := t.TempDir()
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	writeLiveSarif(
+		t, sarifPath, time.Now(),
+		`{"ruleId": "A", "message": {"text": "a"}, "properties": {"qodanaSeverity": "Critical"},
+		  "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/Main.kt"},
+		  "region": {"startLine": 3, "startColumn": 5, "charLength": 4}}}]}`,
+		`{"ruleId": "B", "message": {"text": "b"}, "properties": {"qodanaSeverity": "Low"},
+		  "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/Main.kt"},
+		  "region": {"startLine": 7, "startColumn": 1, "endLine": 8, "endColumn": 2}}}]}`,
+		`{"ruleId": "C", "message": {"text": "c"}, "baselineState": "absent",
+		  "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/Gone.kt"}}}]}`,
+		`{"ruleId": "D", "message": {"text": "d"}, "suppressions": [{"kind": "inSource"}],
+		  "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/Suppressed.kt"}}}]}`,
+	)
+
+	var out bytes.Buffer
+	err := StreamDiagnostics(
+		context.Background(),
+		&out,
+		DiagnosticsOptions{SarifPath: sarifPath, ProjectDir: projectDir, Framing: DiagnosticsFramingLines},
+	)
+	require.NoError(t, err)
+
+	messages := readDiagnosticsMessages(t, out.String())
+	require.Len(t, messages, 1)
+	assert.Equal(t, publishDiagnosticsMethod, messages[0].Method)
+	assert.Equal(t, fileUri(projectDir, "src/Main.kt"), messages[0].Params.Uri)
+	assert.True(t, strings.HasPrefix(messages[0].Params.Uri, "file:///"))
+	assert.Equal(
+		t,
+		[]lspDiagnostic{
+			{
+				Range:    lspRange{Start: lspPosition{Line: 2, Character: 4}, End: lspPosition{Line: 2, Character: 8}},
+				Severity: lspSeverityError,
+				Code:     "A",
+				Source:   diagnosticsSource,
+				Message:  "a",
+			},
+			{
+				Range:    lspRange{Start: lspPosition{Line: 6}, End: lspPosition{Line: 7, Character: 1}},
+				Severity: lspSeverityInformation,
+				Code:     "B",
+				Source:   diagnosticsSource,
+				Message:  "b",
+			},
+		},
+		messages[0].Params.Diagnostics,
+	)
+
+	err = StreamDiagnostics(
+		context.Background(),
+		&out,
+		DiagnosticsOptions{SarifPath: filepath.Join(t.TempDir(), "none.json"), Framing: DiagnosticsFramingLines},
+	)
+	assert.Error(t, err)
+	assert.Error(t, ValidateDiagnosticsFraming("xml"))
+}
+
+func TestDiagnosticsPublisher(t *testing.T) {
+	var out bytes.Buffer
+	publisher := newDiagnosticsPublisher(DiagnosticsFramingLsp)
+	publisher.addClient(&out)
+	a := lspDiagnostic{Severity: lspSeverityWarning, Code: "A", Source: diagnosticsSource, Message: "a"}
+
+	publisher.publish(map[string][]lspDiagnostic{"file:///a.kt": {a}})
+	assert.True(t, strings.HasPrefix(out.String(), "Content-Length: "))
+	out.Reset()
+
+	publisher.publish(map[string][]lspDiagnostic{"file:///a.kt": {a}})
+	assert.Empty(t, out.String(), "unchanged diagnostics are not published again")
+
+	publisher.publish(map[string][]lspDiagnostic{"file:///b.kt": {a}})
+	assert.Contains(t, out.String(), `"uri":"file:///a.kt","diagnostics":[]`)
+	assert.Contains(t, out.String(), `"uri":"file:///b.kt"`)
+
+	var late bytes.Buffer
+	publisher.addClient(&late)
+	assert.Contains(t, late.String(), `"uri":"file:///b.kt"`)
+	assert.NotContains(t, late.String(), `file:///a.kt`)
+}
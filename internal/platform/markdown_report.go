@@ -0,0 +1,312 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+// markdownSeverities are the severities of the Markdown report, from the most severe.
+var markdownSeverities = []string{severityCritical, severityHigh, severityModerate, severityLow, severityInfo}
+
+var markdownSeverityEmoji = map[string]string{
+	severityCritical: "🔴",
+	severityHigh:     "🟠",
+	severityModerate: "🟡",
+	severityLow:      "🔵",
+	severityInfo:     "⚪",
+}
+
+// ChangedLines are the lines changed in a pull request, by the slash-separated path relative to the project
+// directory, a nil ChangedLines doesn't filter the results.
+type ChangedLines map[string][]*git.ChangedRegion
+
+// ChangedLinesSince returns the lines added in projectDir since the merge base of ref and HEAD, the same changes
+// a pull request from HEAD to ref shows.
+func ChangedLinesSince(projectDir string, ref string, logDir string) (ChangedLines, error) {
+	base, err := git.MergeBase(projectDir, ref, "HEAD", logDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the merge base of %s and HEAD: %w", ref, err)
+	}
+	changes, err := git.ComputeChangedFiles(projectDir, base, "HEAD", logDir)
+	if err != nil {
+		return nil, err
+	}
+	root, err := fs.Canonical(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	return newChangedLines(changes, root), nil
+}
+
+func newChangedLines(changes git.ChangedFiles, projectDir string) ChangedLines {
+	lines := make(ChangedLines)
+	for _, file := range changes.Files {
+		rel, err := filepath.Rel(projectDir, file.Path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(file.Added) > 0 {
+			lines[filepath.ToSlash(rel)] = file.Added
+		}
+	}
+	return lines
+}
+
+func (c ChangedLines) contains(path string, line int) bool {
+	if c == nil {
+		return true
+	}
+	regions, ok := c[path]
+	if !ok {
+		return false
+	}
+	if line == 0 {
+		return true // the result is about the whole file
+	}
+	for _, region := range regions {
+		if line >= region.FirstLine && line < region.FirstLine+region.Count {
+			return true
+		}
+	}
+	return false
+}
+
+type markdownProblem struct {
+	severity string
+	ruleId   string
+	message  string
+	line     int
+}
+
+// MarkdownReport renders the new problems of the report grouped by file, to post them as a pull request comment.
+// Only the problems on the changed lines are rendered, if they are given.
+func MarkdownReport(report *sarif.Report, changed ChangedLines) string {
+	files := make(map[string][]markdownProblem)
+	counts := make(map[string]int)
+	total := 0
+	permalinks := newPermalinks(report)
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if !isNewProblem(r) {
+				continue
+			}
+			path, line := resultLocation(r)
+			if path == "" || !changed.contains(path, line) {
+				continue
+			}
+			severity := thresholdSeverityOf(getSeverity(r))
+			message := ""
+			if r.Message != nil {
+				message = r.Message.Text
+			}
+			files[path] = append(
+				files[path],
+				markdownProblem{severity: severity, ruleId: r.RuleId, message: message, line: line},
+			)
+			counts[severity]++
+			total++
+		}
+	}
+
+	var sb strings.Builder
+	if total == 0 {
+		sb.WriteString("## Qodana: no new problems ✅\n")
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("## Qodana: %s\n\n", problemsCount(total)))
+	summary := make([]string, 0, len(markdownSeverities))
+	for _, severity := range markdownSeverities {
+		if counts[severity] > 0 {
+			summary = append(summary, fmt.Sprintf("%s %d %s", markdownSeverityEmoji[severity], counts[severity], severity))
+		}
+	}
+	sb.WriteString(strings.Join(summary, " · ") + "\n")
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		problems := files[path]
+		sort.SliceStable(
+			problems, func(i, j int) bool {
+				if problems[i].line != problems[j].line {
+					return problems[i].line < problems[j].line
+				}
+				return severityRank(problems[i].severity) < severityRank(problems[j].severity)
+			},
+		)
+		sb.WriteString(fmt.Sprintf("\n### %s\n\n", markdownLink("`"+path+"`", permalinks.file(path, 0))))
+		for _, p := range problems {
+			location := "file"
+			if p.line > 0 {
+				location = fmt.Sprintf("L%d", p.line)
+			}
+			sb.WriteString(
+				fmt.Sprintf(
+					"- %s %s `%s` %s\n",
+					markdownSeverityEmoji[p.severity],
+					markdownLink(location, permalinks.file(path, p.line)),
+					p.ruleId,
+					markdownEscape(p.message),
+				),
+			)
+		}
+	}
+	return sb.String()
+}
+
+func problemsCount(n int) string {
+	if n == 1 {
+		return "1 new problem"
+	}
+	return fmt.Sprintf("%d new problems", n)
+}
+
+func severityRank(severity string) int {
+	for i, s := range markdownSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(markdownSeverities)
+}
+
+// resultLocation returns the path relative to the project directory and the line of the result, the line is 0
+// for the results about the whole file.
+func resultLocation(r *sarif.Result) (string, int) {
+	if len(r.Locations) == 0 || r.Locations[0].PhysicalLocation == nil {
+		return "", 0
+	}
+	location := r.Locations[0].PhysicalLocation
+	if location.ArtifactLocation == nil {
+		return "", 0
+	}
+	line := 0
+	if location.Region != nil {
+		line = int(location.Region.StartLine)
+	}
+	return location.ArtifactLocation.Uri, line
+}
+
+func markdownLink(text string, link string) string {
+	if link == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, link)
+}
+
+// markdownEscape keeps the problem on one line and prevents the message from being rendered as Markdown or HTML.
+func markdownEscape(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	replacer := strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", "&lt;", ">", "&gt;", "|", `\|`,
+	)
+	return replacer.Replace(text)
+}
+
+// permalinks builds the links to the analyzed revision of the files on GitHub, GitLab and Bitbucket.
+type permalinks struct {
+	base string
+}
+
+func newPermalinks(report *sarif.Report) permalinks {
+	for _, run := range report.Runs {
+		for _, vcs := range run.VersionControlProvenance {
+			if vcs.RepositoryUri == "" || vcs.RevisionId == "" {
+				continue
+			}
+			webUrl := repositoryWebUrl(vcs.RepositoryUri)
+			if webUrl == "" {
+				continue
+			}
+			switch {
+			case strings.Contains(webUrl, "bitbucket"):
+				return permalinks{base: webUrl + "/src/" + vcs.RevisionId}
+			case strings.Contains(webUrl, "gitlab"):
+				return permalinks{base: webUrl + "/-/blob/" + vcs.RevisionId}
+			default:
+				return permalinks{base: webUrl + "/blob/" + vcs.RevisionId}
+			}
+		}
+	}
+	return permalinks{}
+}
+
+func (p permalinks) file(path string, line int) string {
+	if p.base == "" {
+		return ""
+	}
+	link := p.base + "/" + (&url.URL{Path: path}).EscapedPath()
+	if line > 0 {
+		if strings.Contains(p.base, "/src/") {
+			return fmt.Sprintf("%s#lines-%d", link, line)
+		}
+		return fmt.Sprintf("%s#L%d", link, line)
+	}
+	return link
+}
+
+// repositoryWebUrl converts the clone URL of the repository to the URL of its web page, it is empty for the local
+// repositories.
+func repositoryWebUrl(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), "/")
+	remote = strings.TrimSuffix(remote, ".git")
+	if strings.HasPrefix(remote, "ssh://") && !strings.Contains(strings.TrimPrefix(remote, "ssh://"), "/") {
+		return ""
+	}
+	// scp-like syntax: git@github.com:org/repo, also written as ssh://git@github.com:org/repo
+	if user, rest, ok := strings.Cut(strings.TrimPrefix(remote, "ssh://"), "@"); ok && !strings.Contains(user, "/") {
+		if host, path, ok := strings.Cut(rest, ":"); ok && !strings.HasPrefix(path, "/") && !isPort(path) {
+			return "https://" + host + "/" + path
+		}
+	}
+	parsed, err := url.Parse(remote)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	switch parsed.Scheme {
+	case "http", "https", "ssh", "git":
+	default:
+		return ""
+	}
+	return "https://" + parsed.Hostname() + parsed.Path
+}
+
+func isPort(path string) bool {
+	port, _, _ := strings.Cut(path, "/")
+	if port == "" {
+		return false
+	}
+	for _, c := range port {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics writes the metrics of a scan to the results directory, in the Prometheus text format or as JSON,
+// to be collected by the CI observability tooling.
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
+)
+
+// Formats of the metrics file.
+const (
+	FormatProm = "prom"
+	FormatJson = "json"
+)
+
+// Caches looked up by the CLI, reported in the cache hit ratio.
+const (
+	// ImageCache is the linter image, a hit when the pull didn't change the local image.
+	ImageCache = "image"
+	// ToolsCache is the tools extracted to the cache directory.
+	ToolsCache = "tools"
+	// SystemCache is the IDE system directory with the indexes and the caches of the previous runs.
+	SystemCache = "system"
+)
+
+// CacheStats is the number of the cache lookups that hit and missed.
+type CacheStats struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// Metrics is the content of the metrics file.
+type Metrics struct {
+	Phases           []timings.Phase       `json:"phases"`
+	TotalSeconds     float64               `json:"totalSeconds"`
+	ImagePullSeconds float64               `json:"imagePullSeconds"`
+	Problems         map[string]int        `json:"problems"`
+	NewProblems      map[string]int        `json:"newProblems"`
+	Caches           map[string]CacheStats `json:"caches"`
+	CacheHitRatio    float64               `json:"cacheHitRatio"`
+}
+
+var (
+	cachesMu sync.Mutex
+	caches   = make(map[string]CacheStats)
+)
+
+// CacheHit records a lookup of the cache that found the entry.
+func CacheHit(cache string) {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	stats := caches[cache]
+	stats.Hits++
+	caches[cache] = stats
+}
+
+// CacheMiss records a lookup of the cache that didn't find the entry.
+func CacheMiss(cache string) {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	stats := caches[cache]
+	stats.Misses++
+	caches[cache] = stats
+}
+
+// Caches returns the cache lookups recorded so far.
+func Caches() map[string]CacheStats {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	return maps.Clone(caches)
+}
+
+// FileName returns the name of the metrics file of the format.
+func FileName(format string) string {
+	return "metrics." + format
+}
+
+// ValidateFormat checks the --metrics-format value, empty means no metrics file.
+func ValidateFormat(format string) error {
+	switch format {
+	case "", FormatProm, FormatJson:
+		return nil
+	default:
+		return fmt.Errorf("unknown metrics format %q, supported formats: %s, %s", format, FormatProm, FormatJson)
+	}
+}
+
+// New returns the metrics with the timings and the cache lookups collected so far and the given problem counts.
+func New(problems map[string]int, newProblems map[string]int) Metrics {
+	phases, total := timings.Phases()
+	m := Metrics{
+		Phases:       phases,
+		TotalSeconds: total.Seconds(),
+		Problems:     problems,
+		NewProblems:  newProblems,
+		Caches:       Caches(),
+	}
+	for _, phase := range phases {
+		if phase.Name == timings.ImagePull {
+			m.ImagePullSeconds = phase.Seconds
+		}
+	}
+	var hits, lookups int
+	for _, stats := range m.Caches {
+		hits += stats.Hits
+		lookups += stats.Hits + stats.Misses
+	}
+	if lookups > 0 {
+		m.CacheHitRatio = float64(hits) / float64(lookups)
+	}
+	return m
+}
+
+// Write writes the metrics file of the format to the results directory.
+func Write(resultsDir string, format string, m Metrics) error {
+	var data []byte
+	switch format {
+	case FormatProm:
+		data = []byte(m.Prometheus())
+	case FormatJson:
+		var err error
+		if data, err = json.MarshalIndent(m, "", "  "); err != nil {
+			return err
+		}
+	default:
+		return ValidateFormat(format)
+	}
+	if err := os.MkdirAll(resultsDir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(resultsDir, FileName(format)), data, 0o644)
+}
+
+// Prometheus returns the metrics in the Prometheus text exposition format.
+func (m Metrics) Prometheus() string {
+	var b strings.Builder
+	gauge := func(name string, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	sample := func(name string, label string, value string, v float64) {
+		if label == "" {
+			fmt.Fprintf(&b, "%s %s\n", name, formatValue(v))
+		} else {
+			fmt.Fprintf(&b, "%s{%s=%q} %s\n", name, label, value, formatValue(v))
+		}
+	}
+
+	gauge("qodana_scan_duration_seconds", "Time spent in the scan.")
+	sample("qodana_scan_duration_seconds", "", "", m.TotalSeconds)
+	gauge("qodana_scan_phase_duration_seconds", "Time spent in the phase of the scan, excluding the nested phases.")
+	for _, phase := range m.Phases {
+		sample("qodana_scan_phase_duration_seconds", "phase", phaseLabel(phase.Name), phase.Seconds)
+	}
+	gauge("qodana_image_pull_duration_seconds", "Time spent pulling the linter image.")
+	sample("qodana_image_pull_duration_seconds", "", "", m.ImagePullSeconds)
+
+	gauge("qodana_problems", "Number of problems found by severity.")
+	for _, severity := range slices.Sorted(maps.Keys(m.Problems)) {
+		sample("qodana_problems", "severity", severity, float64(m.Problems[severity]))
+	}
+	gauge("qodana_new_problems", "Number of problems not in the baseline by severity.")
+	for _, severity := range slices.Sorted(maps.Keys(m.NewProblems)) {
+		sample("qodana_new_problems", "severity", severity, float64(m.NewProblems[severity]))
+	}
+
+	gauge("qodana_cache_hits", "Number of the cache lookups that found the entry.")
+	for _, cache := range slices.Sorted(maps.Keys(m.Caches)) {
+		sample("qodana_cache_hits", "cache", cache, float64(m.Caches[cache].Hits))
+	}
+	gauge("qodana_cache_misses", "Number of the cache lookups that didn't find the entry.")
+	for _, cache := range slices.Sorted(maps.Keys(m.Caches)) {
+		sample("qodana_cache_misses", "cache", cache, float64(m.Caches[cache].Misses))
+	}
+	gauge("qodana_cache_hit_ratio", "Share of the cache lookups that found the entry.")
+	sample("qodana_cache_hit_ratio", "", "", m.CacheHitRatio)
+	return b.String()
+}
+
+// phaseLabel turns the phase name into a label value, e.g. "Image pull" into image_pull.
+func phaseLabel(phase string) string {
+	return strings.ReplaceAll(strings.ToLower(phase), " ", "_")
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
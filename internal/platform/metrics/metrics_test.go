@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Cleanup(func() { caches = make(map[string]CacheStats) })
+	CacheHit(ImageCache)
+	CacheHit(ToolsCache)
+	CacheHit(ToolsCache)
+	CacheMiss(SystemCache)
+
+	m := New(map[string]int{"high": 2}, map[string]int{"high": 1})
+	assert.Equal(t, map[string]CacheStats{ImageCache: {Hits: 1}, ToolsCache: {Hits: 2}, SystemCache: {Misses: 1}}, m.Caches)
+	assert.Equal(t, 0.75, m.CacheHitRatio)
+	assert.Equal(t, map[string]int{"high": 2}, m.Problems)
+}
+
+func TestPrometheus(t *testing.T) {
+	m := Metrics{
+		Phases:           []timings.Phase{{Name: timings.ImagePull, Seconds: 12.5}, {Name: timings.Analysis, Seconds: 80}},
+		TotalSeconds:     95,
+		ImagePullSeconds: 12.5,
+		Problems:         map[string]int{"moderate": 4, "critical": 1},
+		NewProblems:      map[string]int{"critical": 1},
+		Caches:           map[string]CacheStats{ImageCache: {Hits: 1}, SystemCache: {Misses: 1}},
+		CacheHitRatio:    0.5,
+	}
+	expected := `# HELP qodana_scan_duration_seconds Time spent in the scan.
+# TYPE qodana_scan_duration_seconds gauge
+qodana_scan_duration_seconds 95
+# HELP qodana_scan_phase_duration_seconds Time spent in the phase of the scan, excluding the nested phases.
+# TYPE qodana_scan_phase_duration_seconds gauge
+qodana_scan_phase_duration_seconds{phase="image_pull"} 12.5
+qodana_scan_phase_duration_seconds{phase="analysis"} 80
+# HELP qodana_image_pull_duration_seconds Time spent pulling the linter image.
+# TYPE qodana_image_pull_duration_seconds gauge
+qodana_image_pull_duration_seconds 12.5
+# HELP qodana_problems Number of problems found by severity.
+# TYPE qodana_problems gauge
+qodana_problems{severity="critical"} 1
+qodana_problems{severity="moderate"} 4
+# HELP qodana_new_problems Number of problems not in the baseline by severity.
+# TYPE qodana_new_problems gauge
+qodana_new_problems{severity="critical"} 1
+# HELP qodana_cache_hits Number of the cache lookups that found the entry.
+# TYPE qodana_cache_hits gauge
+qodana_cache_hits{cache="image"} 1
+qodana_cache_hits{cache="system"} 0
+# HELP qodana_cache_misses Number of the cache lookups that didn't find the entry.
+# TYPE qodana_cache_misses gauge
+qodana_cache_misses{cache="image"} 0
+qodana_cache_misses{cache="system"} 1
+# HELP qodana_cache_hit_ratio Share of the cache lookups that found the entry.
+# TYPE qodana_cache_hit_ratio gauge
+qodana_cache_hit_ratio 0.5
+`
+	assert.Equal(t, expected, m.Prometheus())
+}
+
+func TestWrite(t *testing.T) {
+	resultsDir := t.TempDir()
+	m := Metrics{TotalSeconds: 10, Problems: map[string]int{"high": 1}}
+
+	require.NoError(t, Write(resultsDir, FormatJson, m))
+	data, err := os.ReadFile(filepath.Join(resultsDir, "metrics.json"))
+	require.NoError(t, err)
+	var written Metrics
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, m.Problems, written.Problems)
+
+	require.NoError(t, Write(resultsDir, FormatProm, m))
+	assert.FileExists(t, filepath.Join(resultsDir, "metrics.prom"))
+
+	assert.Error(t, Write(resultsDir, "xml", m))
+	assert.Error(t, ValidateFormat("xml"))
+	assert.NoError(t, ValidateFormat(""))
+}
@@ -17,6 +17,8 @@
 package msg
 
 import (
+	"strings"
+
 	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
 )
@@ -45,3 +47,31 @@ func AskUserConfirm(what string) bool {
 	}
 	return answer
 }
+
+// AskUserSelect asks the user to select one of the options, the first one is selected by default.
+func AskUserSelect(what string, options []string) string {
+	if !IsInteractive() || len(options) == 0 {
+		return ""
+	}
+	answer, err := QodanaInteractiveSelect.WithOptions(options).WithDefaultText(what).Show()
+	if err != nil {
+		log.Fatalf("Error while waiting for user input: %s", err)
+	}
+	return answer
+}
+
+// AskUserInput asks the user to enter a value, defaultValue is returned when nothing is entered.
+func AskUserInput(what string, defaultValue string) string {
+	if !IsInteractive() {
+		return defaultValue
+	}
+	answer, err := pterm.DefaultInteractiveTextInput.WithTextStyle(PrimaryStyle).Show("?  " + what)
+	if err != nil {
+		log.Fatalf("Error while waiting for user input: %s", err)
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return defaultValue
+	}
+	return answer
+}
@@ -17,11 +17,15 @@
 package msg
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/redact"
 	cienvironment "github.com/cucumber/ci-environment/go"
 
 	"github.com/liamg/clinch/terminal"
@@ -60,6 +64,30 @@ func IsInteractive() bool {
 	return !qdenv.IsContainer() && os.Getenv("NONINTERACTIVE") == "" && (isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()))
 }
 
+// jsonLog is set when the CLI output is requested in the JSON format.
+var jsonLog = false
+
+// EnableJsonLog switches logs and the analysis progress output to JSON lines.
+func EnableJsonLog() {
+	jsonLog = true
+	log.SetFormatter(&log.JSONFormatter{})
+}
+
+// IsJsonLog returns true if the output is requested in the JSON format.
+func IsJsonLog() bool {
+	return jsonLog
+}
+
+// PrintJsonLog prints the value as a single JSON line of the given type.
+func PrintJsonLog(kind string, value any) {
+	line, err := json.Marshal(map[string]any{"type": kind, kind: value})
+	if err != nil {
+		log.Errorf("Failed to marshal %s: %s", kind, err)
+		return
+	}
+	fmt.Println(string(line))
+}
+
 // DisableColor disables colors in the output.
 func DisableColor() {
 	pterm.DisableColor()
@@ -128,8 +156,14 @@ func ErrorMessage(message string, a ...any) {
 	pterm.Println(icon, errorStyle.Sprint(message))
 }
 
-// PrintLinterLog prints the linter logs with color, when needed.
+// PrintLinterLog prints the linter logs with color, when needed, or as JSON records with --log-format json. The known
+// secrets are masked.
 func PrintLinterLog(line string) {
+	line = redact.String(line)
+	if jsonLog {
+		PrintJsonLog("linterLog", map[string]string{"line": line})
+		return
+	}
 	if strings.Contains(line, " / /") ||
 		strings.Contains(line, "_              _") ||
 		strings.Contains(line, "\\/__") ||
@@ -140,6 +174,37 @@ func PrintLinterLog(line string) {
 	}
 }
 
+// LinterLogWriter returns the writer of the linter output printed to w line by line with the known secrets masked,
+// with --log-format json the lines are printed as JSON records instead.
+func LinterLogWriter(w io.Writer) io.Writer {
+	if jsonLog {
+		return &linterLogWriter{print: PrintLinterLog}
+	}
+	return &linterLogWriter{
+		print: func(line string) {
+			_, _ = fmt.Fprintln(w, redact.String(line))
+		},
+	}
+}
+
+// linterLogWriter prints every complete line written to it, the incomplete one is kept until its end is written.
+type linterLogWriter struct {
+	print   func(line string)
+	pending []byte
+}
+
+func (w *linterLogWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.print(strings.TrimSuffix(string(w.pending[:i]), "\r"))
+		w.pending = w.pending[i+1:]
+	}
+}
+
 // PrintProcess prints the message for processing phase. TODO: Add ETA based on previous runs
 func PrintProcess(f func(spinner *pterm.SpinnerPrinter), start string, finished string) {
 	if err := spin(f, start); err != nil {
@@ -165,7 +230,7 @@ func spin(fun func(spinner *pterm.SpinnerPrinter), message string) error {
 
 // StartQodanaSpinner starts a new spinner with the given message.
 func StartQodanaSpinner(message string) (*pterm.SpinnerPrinter, error) {
-	if IsInteractive() {
+	if IsInteractive() && !jsonLog {
 		QodanaSpinner.Sequence = spinnerSequence
 		QodanaSpinner.MessageStyle = PrimaryStyle
 		return QodanaSpinner.WithStyle(pterm.NewStyle(pterm.FgGray)).WithRemoveWhenDone(true).Start(message + "...")
@@ -207,6 +272,15 @@ func PrintProblem(
 	fmt.Print(message + "\n")
 }
 
+// PrintLiveProblem prints the problem found by the running analysis in one line.
+func PrintLiveProblem(level string, ruleId string, message string, path string, line int) {
+	location := path
+	if path != "" && line > 0 {
+		location = fmt.Sprintf("%s:%d", path, line)
+	}
+	fmt.Printf("%s %s %s %s\n", PrimaryBold(strings.ToUpper(level)), Primary(ruleId), location, message)
+}
+
 // getTerminalWidth returns the width of the terminal.
 func getTerminalWidth() int {
 	width, _ := terminal.Size()
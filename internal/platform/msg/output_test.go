@@ -1,8 +1,10 @@
 package msg
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/JetBrains/qodana-cli/internal/platform/redact"
 	"github.com/pterm/pterm"
 	"github.com/stretchr/testify/assert"
 )
@@ -84,6 +86,23 @@ func TestPrintLinterLog(t *testing.T) {
 	PrintLinterLog("test log message")
 }
 
+func TestLinterLogWriter(t *testing.T) {
+	var lines []string
+	w := &linterLogWriter{print: func(line string) { lines = append(lines, line) }}
+	_, _ = w.Write([]byte("Starting up\r\nThe Project opening"))
+	_, _ = w.Write([]byte(" stage completed in 1 sec\n"))
+	assert.Equal(t, []string{"Starting up", "The Project opening stage completed in 1 sec"}, lines)
+}
+
+func TestLinterLogWriterMasksSecrets(t *testing.T) {
+	redact.Add("linter-log-secret")
+	var out bytes.Buffer
+	w := LinterLogWriter(&out)
+	_, _ = w.Write([]byte("token=linter-log-"))
+	_, _ = w.Write([]byte("secret\nDone\n"))
+	assert.Equal(t, "token="+redact.Mask+"\nDone\n", out.String())
+}
+
 func TestUpdateText(t *testing.T) {
 	UpdateText(nil, "new text")
 }
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+)
+
+// When the notifications of qodana.yaml are sent.
+const (
+	NotifyAlways      = "always"
+	NotifyOnFailure   = "failure"
+	NotifyNewProblems = "newProblems"
+)
+
+// notificationSummary is the message posted to Slack, Microsoft Teams and email.
+type notificationSummary struct {
+	Title     string
+	Text      string
+	ReportUrl string
+	Failed    bool
+}
+
+// SendNotifications posts the summary of the analysis to the destinations of the notifications section of qodana.yaml,
+// if the analysis matches its condition. The webhook, Slack and Teams messages are queued like the --webhook events when
+// they are not delivered, a failed email is only reported.
+func SendNotifications(
+	systemDir string,
+	notifications *qdyaml.Notifications,
+	sarifPath, analysisId, reportUrl string,
+	exitCode int,
+	mode ObserveMode,
+) {
+	if notifications == nil {
+		return
+	}
+	if notifications.ReportUrl != "" {
+		reportUrl = notifications.ReportUrl
+	}
+	result := NewAnalysisResult(sarifPath, analysisId, reportUrl, exitCode, mode)
+	newProblems := map[string]int{}
+	if report, err := ReadReport(sarifPath); err == nil {
+		_, newProblems = countProblemsBySeverity(report)
+	}
+	notify, err := shouldNotify(notifications, result, newProblems)
+	if err != nil {
+		msg.WarningMessage("The notifications from qodana.yaml are not sent: %s", err)
+		return
+	}
+	if !notify {
+		log.Debugf("The analysis doesn't match the notifications condition %q, nothing is sent", notifications.When)
+		return
+	}
+	summary := newNotificationSummary(result, newProblems)
+
+	var events []WebhookEvent
+	addEvent := func(url string, payload any) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			log.Warnf("Failed to marshal the notification for %s: %s", url, err)
+			return
+		}
+		events = append(events, newWebhookEvent(url, data, result.FinishedAt))
+	}
+	for _, url := range notifications.Webhooks {
+		addEvent(url, result)
+	}
+	if notifications.Slack != "" {
+		addEvent(notifications.Slack, slackMessage(summary))
+	}
+	if notifications.Teams != "" {
+		addEvent(notifications.Teams, teamsMessage(summary))
+	}
+	if len(events) > 0 {
+		deliverWebhookEvents(systemDir, events)
+	}
+	if notifications.Email != nil {
+		if err = sendEmailNotification(notifications.Email, summary); err != nil {
+			msg.WarningMessage("Failed to send the notification email: %s", err)
+		}
+	}
+}
+
+// shouldNotify tells whether the analysis matches the condition of the notifications.
+func shouldNotify(notifications *qdyaml.Notifications, result AnalysisResult, newProblems map[string]int) (bool, error) {
+	severity := notifications.Severity
+	if severity == "" {
+		severity = severityInfo
+	}
+	if !slices.Contains(markdownSeverities, severity) {
+		return false, fmt.Errorf("unknown severity %q, use one of %s", severity, strings.Join(markdownSeverities, ", "))
+	}
+	switch notifications.When {
+	case "", NotifyAlways:
+		return true, nil
+	case NotifyOnFailure:
+		return result.QualityGate == qualityGateFailed, nil
+	case NotifyNewProblems:
+		count := 0
+		for s, n := range newProblems {
+			if severityRank(s) <= severityRank(severity) {
+				count += n
+			}
+		}
+		return count > notifications.Threshold, nil
+	default:
+		return false, fmt.Errorf(
+			"unknown condition when: %q, use %s, %s or %s",
+			notifications.When,
+			NotifyAlways,
+			NotifyOnFailure,
+			NotifyNewProblems,
+		)
+	}
+}
+
+func newNotificationSummary(result AnalysisResult, newProblems map[string]int) notificationSummary {
+	summary := notificationSummary{
+		Title:     "Qodana: the quality gate passed",
+		ReportUrl: result.ReportUrl,
+		Failed:    result.QualityGate == qualityGateFailed,
+	}
+	if summary.Failed {
+		summary.Title = "Qodana: the quality gate failed"
+		if result.Observed {
+			summary.Title += " (observe mode)"
+		}
+	}
+	var sb strings.Builder
+	linter := result.Linter
+	if linter == "" {
+		linter = "The analysis"
+	}
+	sb.WriteString(linter)
+	if result.Problems == 1 {
+		sb.WriteString(" found 1 new problem")
+	} else {
+		_, _ = fmt.Fprintf(&sb, " found %d new problems", result.Problems)
+	}
+	var counts []string
+	for _, severity := range markdownSeverities {
+		if newProblems[severity] > 0 {
+			counts = append(counts, fmt.Sprintf("%s %d", severity, newProblems[severity]))
+		}
+	}
+	if len(counts) > 0 {
+		_, _ = fmt.Fprintf(&sb, " (%s)", strings.Join(counts, ", "))
+	}
+	if result.Repository != "" {
+		_, _ = fmt.Fprintf(&sb, " in %s", result.Repository)
+	}
+	if result.Branch != "" {
+		_, _ = fmt.Fprintf(&sb, " on %s", result.Branch)
+	}
+	if result.Revision != "" {
+		_, _ = fmt.Fprintf(&sb, " at %s", result.Revision[:min(len(result.Revision), 8)])
+	}
+	summary.Text = sb.String()
+	return summary
+}
+
+// slackMessage is the payload of a Slack incoming webhook.
+func slackMessage(summary notificationSummary) map[string]any {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+	text := fmt.Sprintf("*%s*\n%s", escape(summary.Title), escape(summary.Text))
+	if summary.ReportUrl != "" {
+		text += fmt.Sprintf("\n<%s|Open the report>", summary.ReportUrl)
+	}
+	return map[string]any{"text": text}
+}
+
+// teamsMessage is the payload of a Microsoft Teams incoming webhook, an Adaptive Card.
+func teamsMessage(summary notificationSummary) map[string]any {
+	titleColor := "Good"
+	if summary.Failed {
+		titleColor = "Attention"
+	}
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]any{
+			{"type": "TextBlock", "text": summary.Title, "weight": "Bolder", "size": "Medium", "color": titleColor},
+			{"type": "TextBlock", "text": summary.Text, "wrap": true},
+		},
+	}
+	if summary.ReportUrl != "" {
+		card["actions"] = []map[string]any{{"type": "Action.OpenUrl", "title": "Open the report", "url": summary.ReportUrl}}
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
+
+func sendEmailNotification(email *qdyaml.EmailNotification, summary notificationSummary) error {
+	if email.Smtp == "" || email.From == "" || len(email.To) == 0 {
+		return errors.New("email needs the smtp server, from and to")
+	}
+	host, _, err := net.SplitHostPort(email.Smtp)
+	if err != nil {
+		return fmt.Errorf("invalid smtp server %q, use host:port: %w", email.Smtp, err)
+	}
+	var auth smtp.Auth
+	if email.Username != "" {
+		password := os.Getenv(qdenv.QodanaSmtpPassword)
+		if password == "" {
+			return fmt.Errorf("%s is not set for the SMTP user %s", qdenv.QodanaSmtpPassword, email.Username)
+		}
+		auth = smtp.PlainAuth("", email.Username, password, host)
+	}
+	return smtp.SendMail(email.Smtp, auth, email.From, email.To, emailMessage(email, summary, time.Now()))
+}
+
+// emailMessage returns the plain text email with the summary.
+func emailMessage(email *qdyaml.EmailNotification, summary notificationSummary, date time.Time) []byte {
+	var sb strings.Builder
+	header := func(name string, value string) {
+		// the values of qodana.yaml can't add headers
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		_, _ = fmt.Fprintf(&sb, "%s: %s\r\n", name, value)
+	}
+	header("From", email.From)
+	header("To", strings.Join(email.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", summary.Title))
+	header("Date", date.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	sb.WriteString("\r\n")
+	sb.WriteString(summary.Text)
+	sb.WriteString("\r\n")
+	if summary.ReportUrl != "" {
+		_, _ = fmt.Fprintf(&sb, "\r\nReport: %s\r\n", summary.ReportUrl)
+	}
+	return []byte(sb.String())
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendNotifications(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	assert.NoError(t, os.WriteFile(sarifPath, []byte(sarifFileData), 0o644))
+
+	var mu sync.Mutex
+	received := map[string]map[string]any{}
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var payload map[string]any
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+				mu.Lock()
+				defer mu.Unlock()
+				received[r.URL.Path] = payload
+			},
+		),
+	)
+	defer server.Close()
+
+	notifications := &qdyaml.Notifications{
+		When:      NotifyOnFailure,
+		ReportUrl: "https://ci.example.com/qodana",
+		Webhooks:  []string{server.URL + "/webhook"},
+		Slack:     server.URL + "/slack",
+		Teams:     server.URL + "/teams",
+	}
+	SendNotifications(t.TempDir(), notifications, sarifPath, "1", "", exitcodes.QodanaSuccessExitCode, ObserveMode{})
+	assert.Empty(t, received)
+
+	systemDir := t.TempDir()
+	SendNotifications(systemDir, notifications, sarifPath, "2", "", exitcodes.QodanaFailThresholdExitCode, ObserveMode{})
+	assert.NoFileExists(t, filepath.Join(systemDir, WebhookQueueFileName))
+	if assert.Len(t, received, 3) {
+		assert.Equal(t, "2", received["/webhook"]["analysisId"])
+		assert.Equal(t, "https://ci.example.com/qodana", received["/webhook"]["reportUrl"])
+		slack := received["/slack"]["text"].(string)
+		assert.True(t, strings.HasPrefix(slack, "*Qodana: the quality gate failed*\n"))
+		assert.Contains(t, slack, "found 5 new problems")
+		assert.Contains(t, slack, "<https://ci.example.com/qodana|Open the report>")
+		assert.Equal(t, "message", received["/teams"]["type"])
+		teams, err := json.Marshal(received["/teams"])
+		assert.NoError(t, err)
+		assert.Contains(t, string(teams), "application/vnd.microsoft.card.adaptive")
+		assert.Contains(t, string(teams), `"url":"https://ci.example.com/qodana"`)
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	passed := AnalysisResult{QualityGate: qualityGatePassed}
+	failed := AnalysisResult{QualityGate: qualityGateFailed}
+	newProblems := map[string]int{severityHigh: 2, severityLow: 3}
+
+	for _, tc := range []struct {
+		name          string
+		notifications qdyaml.Notifications
+		result        AnalysisResult
+		expected      bool
+		err           string
+	}{
+		{name: "always by default", result: passed, expected: true},
+		{name: "failure, passed", notifications: qdyaml.Notifications{When: NotifyOnFailure}, result: passed},
+		{name: "failure, failed", notifications: qdyaml.Notifications{When: NotifyOnFailure}, result: failed, expected: true},
+		{name: "new problems", notifications: qdyaml.Notifications{When: NotifyNewProblems}, result: passed, expected: true},
+		{
+			name:          "new problems under the threshold",
+			notifications: qdyaml.Notifications{When: NotifyNewProblems, Threshold: 5},
+			result:        passed,
+		},
+		{
+			name:          "new problems of the severity",
+			notifications: qdyaml.Notifications{When: NotifyNewProblems, Severity: severityHigh, Threshold: 1},
+			result:        passed,
+			expected:      true,
+		},
+		{
+			name:          "no new problems of the severity",
+			notifications: qdyaml.Notifications{When: NotifyNewProblems, Severity: severityCritical},
+			result:        failed,
+		},
+		{name: "unknown condition", notifications: qdyaml.Notifications{When: "sometimes"}, err: "unknown condition"},
+		{name: "unknown severity", notifications: qdyaml.Notifications{Severity: "blocker"}, err: "unknown severity"},
+	} {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				notify, err := shouldNotify(&tc.notifications, tc.result, newProblems)
+				if tc.err != "" {
+					assert.ErrorContains(t, err, tc.err)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, notify)
+			},
+		)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	email := &qdyaml.EmailNotification{
+		Smtp: "smtp.example.com:587",
+		From: "qodana@example.com\r\nBcc: everyone@example.com",
+		To:   []string{"team@example.com", "lead@example.com"},
+	}
+	summary := notificationSummary{
+		Title:     "Qodana: the quality gate failed ✗",
+		Text:      "Qodana for JVM found 2 new problems (high 2)",
+		ReportUrl: "https://qodana.cloud/report",
+	}
+	message := string(emailMessage(email, summary, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)))
+	headers, body, found := strings.Cut(message, "\r\n\r\n")
+	assert.True(t, found)
+	assert.Contains(t, headers, "From: qodana@example.com  Bcc: everyone@example.com\r\n")
+	assert.NotContains(t, headers, "\r\nBcc:")
+	assert.Contains(t, headers, "To: team@example.com, lead@example.com\r\n")
+	assert.Contains(t, headers, "Subject: =?utf-8?q?")
+	assert.Contains(t, headers, "Date: Wed, 01 May 2024 12:00:00 +0000")
+	assert.Equal(t, "Qodana for JVM found 2 new problems (high 2)\r\n\r\nReport: https://qodana.cloud/report\r\n", body)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	log "github.com/sirupsen/logrus"
+)
+
+const enforceAfterLayout = "2006-01-02"
+
+// ObserveMode tells whether the quality gates only report the failures instead of failing the run.
+type ObserveMode struct {
+	Enabled bool
+	// EnforceAfter is the date the gates start failing the run, zero if the observe mode was requested with --observe.
+	EnforceAfter time.Time
+}
+
+// ComputeObserveMode enables the observe mode with --observe or until the enforceAfter date from qodana.yaml.
+func ComputeObserveMode(observe bool, enforceAfter string, now time.Time) (ObserveMode, error) {
+	if observe {
+		return ObserveMode{Enabled: true}, nil
+	}
+	if enforceAfter == "" {
+		return ObserveMode{}, nil
+	}
+	date, err := time.ParseInLocation(enforceAfterLayout, enforceAfter, time.Local)
+	if err != nil {
+		return ObserveMode{}, fmt.Errorf("invalid enforceAfter date %q in qodana.yaml, expected YYYY-MM-DD", enforceAfter)
+	}
+	if now.Before(date) {
+		return ObserveMode{Enabled: true, EnforceAfter: date}, nil
+	}
+	return ObserveMode{}, nil
+}
+
+// ComputeObserveModeOrFatal is ComputeObserveMode for the current time, it stops the run on an invalid enforceAfter date.
+func ComputeObserveModeOrFatal(observe bool, enforceAfter string) ObserveMode {
+	mode, err := ComputeObserveMode(observe, enforceAfter, time.Now())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if mode.Enabled {
+		log.Debugf("Observe mode is enabled, quality gates will not fail the run")
+	}
+	return mode
+}
+
+// ObservedExitCode returns the exit code of the run: in the observe mode the exceeded fail threshold is only reported
+// and the run succeeds.
+func ObservedExitCode(exitCode int, mode ObserveMode) int {
+	if exitCode != exitcodes.QodanaFailThresholdExitCode || !mode.Enabled {
+		return exitCode
+	}
+	msg.EmptyMessage()
+	if mode.EnforceAfter.IsZero() {
+		msg.WarningMessage("The number of problems exceeds the fail threshold, the run is not failed in the observe mode")
+	} else {
+		msg.WarningMessage(
+			"The number of problems exceeds the fail threshold, the run will fail starting from %s (enforceAfter in qodana.yaml)",
+			mode.EnforceAfter.Format(enforceAfterLayout),
+		)
+	}
+	return exitcodes.QodanaSuccessExitCode
+}
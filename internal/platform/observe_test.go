@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+)
+
+func TestComputeObserveMode(t *testing.T) {
+	now := time.Date(2025, 8, 15, 12, 0, 0, 0, time.Local)
+	for _, testData := range []struct {
+		name         string
+		observe      bool
+		enforceAfter string
+		expected     bool
+	}{
+		{name: "enforced by default", expected: false},
+		{name: "observe option", observe: true, expected: true},
+		{name: "before enforceAfter", enforceAfter: "2025-09-01", expected: true},
+		{name: "on enforceAfter", enforceAfter: "2025-08-15", expected: false},
+		{name: "after enforceAfter", enforceAfter: "2025-01-01", expected: false},
+		{name: "observe option after enforceAfter", observe: true, enforceAfter: "2025-01-01", expected: true},
+	} {
+		t.Run(
+			testData.name, func(t *testing.T) {
+				mode, err := ComputeObserveMode(testData.observe, testData.enforceAfter, now)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if mode.Enabled != testData.expected {
+					t.Errorf("expected observe mode %v, got %v", testData.expected, mode.Enabled)
+				}
+			},
+		)
+	}
+
+	if _, err := ComputeObserveMode(false, "01.09.2025", now); err == nil {
+		t.Error("expected an error for an invalid enforceAfter date")
+	}
+}
+
+func TestObservedExitCode(t *testing.T) {
+	observed := ObserveMode{Enabled: true, EnforceAfter: time.Date(2025, 9, 1, 0, 0, 0, 0, time.Local)}
+	if code := ObservedExitCode(exitcodes.QodanaFailThresholdExitCode, observed); code != exitcodes.QodanaSuccessExitCode {
+		t.Errorf("expected %d in the observe mode, got %d", exitcodes.QodanaSuccessExitCode, code)
+	}
+	if code := ObservedExitCode(exitcodes.QodanaFailThresholdExitCode, ObserveMode{}); code != exitcodes.QodanaFailThresholdExitCode {
+		t.Errorf("expected %d, got %d", exitcodes.QodanaFailThresholdExitCode, code)
+	}
+	// other failures are not quality gates and are never hidden
+	if code := ObservedExitCode(exitcodes.QodanaOutOfMemoryExitCode, observed); code != exitcodes.QodanaOutOfMemoryExitCode {
+		t.Errorf("expected %d, got %d", exitcodes.QodanaOutOfMemoryExitCode, code)
+	}
+}
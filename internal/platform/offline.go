@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// SetupOfflineMode enables the offline mode of --offline for the run and the processes it starts,
+// the options that can't work without the network are rejected.
+func SetupOfflineMode(cliOptions platformcmd.CliOptions) error {
+	if !cliOptions.Offline {
+		return nil
+	}
+	networkOptions := map[string]bool{
+		"--publish":          len(cliOptions.Publish) > 0,
+		"--webhook":          len(cliOptions.Webhooks) > 0,
+		"--image-vuln-check": cliOptions.ImageVulnCheck != "" && cliOptions.ImageVulnCheck != "off",
+	}
+	for _, option := range []string{"--publish", "--webhook", "--image-vuln-check"} {
+		if networkOptions[option] {
+			return fmt.Errorf("%s needs network access and can't be used with --offline", option)
+		}
+	}
+	qdenv.SetEnv(qdenv.QodanaOffline, "true")
+	log.Debug("Running in the offline mode")
+	return nil
+}
+
+// SetupOfflineModeOrFatal validates --offline before the analysis is started.
+func SetupOfflineModeOrFatal(cliOptions platformcmd.CliOptions) {
+	if err := SetupOfflineMode(cliOptions); err != nil {
+		log.Fatal(err)
+	}
+}
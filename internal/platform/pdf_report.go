@@ -0,0 +1,318 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+// The A4 page of the PDF report in points.
+const (
+	pdfPageWidth    = 595.0
+	pdfPageHeight   = 842.0
+	pdfMargin       = 50.0
+	pdfFontSize     = 10.0
+	pdfLeading      = 14.0
+	pdfFooterHeight = 30.0
+	pdfRegularFont  = "F1"
+	pdfBoldFont     = "F2"
+)
+
+// pdfHelveticaWidths are the widths of the printable ASCII characters of Helvetica in 1/1000 of the font size, the
+// other characters are counted as wide as a digit.
+var pdfHelveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// WritePdfReport renders the summary of the report and its most severe problems as a paginated PDF document, e.g. for
+// the compliance archives. The document only depends on the report, the same report is always rendered the same way.
+func WritePdfReport(w io.Writer, report *sarif.Report, problems []SarifProblem, top int) error {
+	doc := &pdfDocument{}
+	doc.newPage()
+	doc.writeLine(pdfBoldFont, 18, 0, "Qodana report")
+	doc.skip(pdfLeading / 2)
+
+	analyzed := time.Time{}
+	for _, r := range report.Runs {
+		if r.Tool != nil && r.Tool.Driver != nil && r.Tool.Driver.Name != "" {
+			doc.writeField("Linter", r.Tool.Driver.Name)
+		}
+		if len(r.VersionControlProvenance) > 0 {
+			vcs := r.VersionControlProvenance[0]
+			doc.writeField("Repository", vcs.RepositoryUri)
+			doc.writeField("Branch", vcs.Branch)
+			doc.writeField("Revision", vcs.RevisionId)
+		}
+		for _, invocation := range r.Invocations {
+			if analyzed.IsZero() && !invocation.EndTimeUtc.IsZero() {
+				analyzed = invocation.EndTimeUtc.UTC()
+			}
+		}
+	}
+	if !analyzed.IsZero() {
+		doc.writeField("Analyzed", analyzed.Format("2006-01-02 15:04 UTC"))
+	}
+
+	var current []SarifProblem
+	counts, newCounts := map[string]int{}, map[string]int{}
+	for _, p := range problems {
+		if p.State != baselineStateNew && p.State != baselineStateUnchanged {
+			continue
+		}
+		current = append(current, p)
+		counts[p.Severity]++
+		if p.State == baselineStateNew {
+			newCounts[p.Severity]++
+		}
+	}
+	doc.skip(pdfLeading)
+	doc.writeLine(pdfBoldFont, 14, 0, "Summary")
+	doc.writeColumns(pdfBoldFont, "Severity", "Problems", "New")
+	for _, severity := range markdownSeverities {
+		doc.writeColumns(pdfRegularFont, severity, fmt.Sprint(counts[severity]), fmt.Sprint(newCounts[severity]))
+	}
+	doc.writeColumns(pdfBoldFont, "total", fmt.Sprint(len(current)), fmt.Sprint(sumCounts(newCounts)))
+
+	doc.skip(pdfLeading)
+	if top > 0 && len(current) > top {
+		doc.writeLine(pdfBoldFont, 14, 0, fmt.Sprintf("Top %d of %d problems", top, len(current)))
+		current = current[:top]
+	} else {
+		doc.writeLine(pdfBoldFont, 14, 0, "Problems")
+	}
+	if len(current) == 0 {
+		doc.writeLine(pdfRegularFont, pdfFontSize, 0, "No problems found")
+	}
+	for _, p := range current {
+		location := p.Path
+		if p.Line > 0 {
+			location = fmt.Sprintf("%s:%d", p.Path, p.Line)
+		}
+		state := ""
+		if p.State == baselineStateNew {
+			state = " (new)"
+		}
+		// the heading of a problem isn't left alone at the bottom of a page
+		doc.ensureSpace(2 * pdfLeading)
+		doc.writeWrapped(pdfBoldFont, 0, fmt.Sprintf("%s %s%s", strings.ToUpper(p.Severity), p.RuleId, state))
+		doc.writeWrapped(pdfRegularFont, 12, location)
+		doc.writeWrapped(pdfRegularFont, 12, p.Message)
+		doc.skip(pdfLeading / 2)
+	}
+	return doc.write(w, analyzed)
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// pdfDocument lays out the lines of text on the pages of a PDF document.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	// y is the baseline of the next line on the current page
+	y float64
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+func (d *pdfDocument) ensureSpace(height float64) {
+	if d.y-height < pdfMargin+pdfFooterHeight {
+		d.newPage()
+	}
+}
+
+func (d *pdfDocument) skip(height float64) {
+	d.y -= height
+}
+
+func (d *pdfDocument) text(font string, size float64, x float64, y float64, s string) {
+	_, _ = fmt.Fprintf(d.pages[len(d.pages)-1], "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(s))
+}
+
+func (d *pdfDocument) writeLine(font string, size float64, indent float64, s string) {
+	leading := max(pdfLeading, size*1.4)
+	d.ensureSpace(leading)
+	d.y -= leading - pdfLeading
+	d.text(font, size, pdfMargin+indent, d.y, s)
+	d.y -= pdfLeading
+}
+
+func (d *pdfDocument) writeField(name string, value string) {
+	if value == "" {
+		return
+	}
+	d.ensureSpace(pdfLeading)
+	d.text(pdfBoldFont, pdfFontSize, pdfMargin, d.y, name)
+	d.text(pdfRegularFont, pdfFontSize, pdfMargin+80, d.y, value)
+	d.y -= pdfLeading
+}
+
+func (d *pdfDocument) writeColumns(font string, values ...string) {
+	d.ensureSpace(pdfLeading)
+	for i, value := range values {
+		d.text(font, pdfFontSize, pdfMargin+float64(i)*100, d.y, value)
+	}
+	d.y -= pdfLeading
+}
+
+func (d *pdfDocument) writeWrapped(font string, indent float64, s string) {
+	width := pdfPageWidth - 2*pdfMargin - indent
+	if font == pdfBoldFont {
+		// the widths are of the regular font, the bold one is up to 10% wider
+		width /= 1.1
+	}
+	for _, line := range wrapPdfText(s, width, pdfFontSize) {
+		d.writeLine(font, pdfFontSize, indent, line)
+	}
+}
+
+// write writes the document with the page numbers in the footers, the creation date is the analysis time if known.
+func (d *pdfDocument) write(w io.Writer, created time.Time) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		_, _ = fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	info := "<< /Title (Qodana report) /Producer (Qodana CLI)"
+	if !created.IsZero() {
+		info += created.Format(" /CreationDate (D:20060102150405Z)")
+	}
+	object(info + " >>")
+	for i, page := range d.pages {
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+		_, _ = fmt.Fprintf(
+			page,
+			"BT /%s 8.0 Tf %.2f %.2f Td (%s) Tj ET\n",
+			pdfRegularFont,
+			pdfPageWidth-pdfMargin-pdfTextWidth(footer, 8),
+			pdfMargin,
+			footer,
+		)
+		object(
+			fmt.Sprintf(
+				"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth,
+				pdfPageHeight,
+				pdfRegularFont,
+				pdfBoldFont,
+				7+2*i,
+			),
+		)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+	xref := out.Len()
+	_, _ = fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		_, _ = fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	_, _ = fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfString encodes the text in WinAnsiEncoding as the content of a PDF string, the characters out of Latin-1 are
+// replaced with '?'.
+func pdfString(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			sb.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			_, _ = fmt.Fprintf(&sb, "\\%03o", r)
+		case r == '\t':
+			sb.WriteByte(' ')
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
+
+func pdfTextWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		if r >= 0x20 && r < 0x7f {
+			width += pdfHelveticaWidths[r-0x20]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// wrapPdfText splits the text into the lines fitting the width, the words longer than a line are split.
+func wrapPdfText(s string, width float64, size float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if pdfTextWidth(candidate, size) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = ""
+		for _, r := range word {
+			if line != "" && pdfTextWidth(line+string(r), size) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
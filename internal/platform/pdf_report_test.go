@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePdfReport(t *testing.T) {
+	report, err := ReadReportFromString(sarifQueryData)
+	require.NoError(t, err)
+	problems := SpreadsheetProblems(report, nil)
+	for i := 0; i < 80; i++ {
+		problems = append(
+			problems,
+			SarifProblem{
+				Severity: "info",
+				State:    "new",
+				RuleId:   "SpellCheckingInspection",
+				Path:     "docs/guide.md",
+				Line:     i + 1,
+				Message:  strings.Repeat("Typo (in word) ", 20),
+			},
+		)
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, WritePdfReport(&out, report, problems, 0))
+	pdf := out.Bytes()
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+
+	// every object of the cross-reference table is at its offset
+	startXref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	require.NotNil(t, startXref)
+	xref, _ := strconv.Atoi(string(startXref[1]))
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	require.NotEmpty(t, offsets)
+	for i, offset := range offsets {
+		at, _ := strconv.Atoi(string(offset[1]))
+		assert.True(t, bytes.HasPrefix(pdf[at:], []byte(fmt.Sprintf("%d 0 obj", i+1))), "object %d", i+1)
+	}
+
+	pages := bytes.Count(pdf, []byte("/Type /Page "))
+	assert.Greater(t, pages, 1)
+	assert.Contains(t, string(pdf), fmt.Sprintf("(Page %d of %d)", pages, pages))
+	// the absent and the suppressed problems are not listed
+	assert.Contains(t, string(pdf), "(CRITICAL NullableProblems) Tj")
+	assert.NotContains(t, string(pdf), "Old.java")
+	assert.Contains(t, string(pdf), `Typo \(in word\)`)
+
+	var again bytes.Buffer
+	require.NoError(t, WritePdfReport(&again, report, problems, 0))
+	assert.Equal(t, out.Bytes(), again.Bytes())
+}
+
+func TestWritePdfReportTop(t *testing.T) {
+	report, err := ReadReportFromString(sarifQueryData)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WritePdfReport(&out, report, SpreadsheetProblems(report, nil), 2))
+	assert.Contains(t, out.String(), "(Top 2 of 3 problems)")
+	assert.Contains(t, out.String(), `KotlinRedundantSemicolon \(new\)`)
+	assert.NotContains(t, out.String(), "KotlinUnusedImport")
+}
+
+func TestWrapPdfText(t *testing.T) {
+	assert.Equal(t, []string{""}, wrapPdfText("", 100, 10))
+	assert.Equal(t, []string{"Unused import"}, wrapPdfText("Unused   import", 100, 10))
+	for _, line := range wrapPdfText(strings.Repeat("word ", 50)+strings.Repeat("x", 100), 100, 10) {
+		assert.LessOrEqual(t, pdfTextWidth(line, 10), 100.0)
+	}
+	assert.Equal(t, `caf\351 \(?\)`, pdfString("café (✓)"))
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/JetBrains/qodana-cli/internal/core"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/logging"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/version"
 )
@@ -23,10 +24,21 @@ func Init() {
 	signal.Notify(commoncontext.InterruptChannel, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-commoncontext.InterruptChannel
+		force := false
+		if commoncontext.CancelOnInterrupt() {
+			msg.WarningMessage("Interrupting Qodana, the partial results are saved. Press Ctrl+C again to exit immediately")
+			<-commoncontext.InterruptChannel
+			force = true
+		}
 		msg.WarningMessage("Interrupting Qodana...")
 		log.SetOutput(io.Discard)
+		logging.DisableConsole()
 		core.CheckForUpdates(version.Version)
-		core.ContainerCleanup()
+		if force {
+			core.ContainerKill()
+		} else {
+			core.ContainerCleanup()
+		}
 		_ = msg.QodanaSpinner.Stop()
 		// Sleep for a second to allow other functions monitoring signals elsewhere to do their thing.
 		// A future rewrite of the subprocess API should incorporate a more structured signal handling.
@@ -130,3 +130,9 @@ func (a *PathNativeAnalyzer) InitYaml(_ qdyaml.QodanaYaml) qdyaml.QodanaYaml {
 	log.Fatalf("Customised path can't be stored to Yaml")
 	return qdyaml.QodanaYaml{}
 }
+
+// QodanaYamlNames returns the values `linter`, `ide` or `image` of a qodana.yaml document specific to the analyzer can have.
+func QodanaYamlNames(a Analyzer) []string {
+	linter := a.GetLinter()
+	return []string{linter.Name, linter.ProductCode, a.Name()}
+}
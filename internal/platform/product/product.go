@@ -56,6 +56,9 @@ const (
 	QDCLC  = "QDCLC"
 	QDCPP  = "QDCPP"
 	QDPOLY = "QDPOLY"
+	QDPHPC = "QDPHPC"
+	QDJSC  = "QDJSC"
+	QDGOC  = "QDGOC"
 )
 
 var (
@@ -237,6 +240,42 @@ var (
 		EapOnly:         true,
 	}
 
+	// PhpCommunityLinter runs PHPStan or Psalm installed in the project, it has no Docker image and is not detected.
+	PhpCommunityLinter = Linter{
+		PresentableName: "Qodana Community for PHP",
+		Name:            "qodana-php-community",
+		ProductCode:     QDPHPC,
+		DockerImage:     "",
+		SupportNative:   false,
+		IsPaid:          false,
+		SupportFixes:    false,
+		EapOnly:         true,
+	}
+
+	// EslintLinter runs ESLint installed in the project, it has no Docker image and is not detected.
+	EslintLinter = Linter{
+		PresentableName: "Qodana Community for JS (ESLint)",
+		Name:            "qodana-eslint",
+		ProductCode:     QDJSC,
+		DockerImage:     "",
+		SupportNative:   false,
+		IsPaid:          false,
+		SupportFixes:    false,
+		EapOnly:         true,
+	}
+
+	// GoCommunityLinter runs golangci-lint installed on the machine, it has no Docker image and is not detected.
+	GoCommunityLinter = Linter{
+		PresentableName: "Qodana Community for Go",
+		Name:            "qodana-go-community",
+		ProductCode:     QDGOC,
+		DockerImage:     "",
+		SupportNative:   false,
+		IsPaid:          false,
+		SupportFixes:    false,
+		EapOnly:         true,
+	}
+
 	// AllLinters Order is important for detection
 	AllLinters = []Linter{
 		JvmCommunityLinter,
@@ -212,6 +212,12 @@ func GetProductNameFromCode(code string) string {
 		return "Qodana for C/C++"
 	case QDPOLY:
 		return "Qodana Poly"
+	case QDPHPC:
+		return "Qodana Community for PHP"
+	case QDJSC:
+		return "Qodana Community for JS (ESLint)"
+	case QDGOC:
+		return "Qodana Community for Go"
 	default:
 		return "Qodana"
 	}
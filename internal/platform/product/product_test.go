@@ -428,6 +428,9 @@ func TestGetProductNameFromCode(t *testing.T) {
 		{QDRST, "Qodana for Rust"},
 		{QDRUBY, "Qodana for Ruby"},
 		{QDPOLY, "Qodana Poly"},
+		{QDPHPC, "Qodana Community for PHP"},
+		{QDJSC, "Qodana Community for JS (ESLint)"},
+		{QDGOC, "Qodana Community for Go"},
 		{"UNKNOWN", "Qodana"},
 	}
 
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProjectsSummaryFileName is the summary of the scan of the projects from qodana.yaml, in the top-level results directory.
+const ProjectsSummaryFileName = "projects-summary.json"
+
+// ProjectResult is the result of the scan of one project from qodana.yaml.
+type ProjectResult struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Linter     string `json:"linter,omitempty"`
+	ResultsDir string `json:"resultsDir"`
+	ExitCode   int    `json:"exitCode"`
+	Problems   int    `json:"problems"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ProjectsSummary is the top-level result of the scan of the projects.
+type ProjectsSummary struct {
+	Projects []ProjectResult `json:"projects"`
+	Problems int             `json:"problems"`
+	ExitCode int             `json:"exitCode"`
+	// Sarif is the report merged from the reports of all projects, empty if no project produced one.
+	Sarif string `json:"sarif,omitempty"`
+}
+
+// SummarizeProjects counts the problems of every project, merges the project reports into the top-level
+// results directory and writes the summary there.
+func SummarizeProjects(resultsDir string, results []ProjectResult) (ProjectsSummary, error) {
+	summary := ProjectsSummary{Projects: results, ExitCode: ProjectsExitCode(results)}
+	sarifFiles := make([]string, 0, len(results))
+	for i := range summary.Projects {
+		result := &summary.Projects[i]
+		sarifPath := filepath.Join(result.ResultsDir, commoncontext.QodanaSarifName)
+		report, err := ReadReport(sarifPath)
+		if err != nil {
+			log.Debugf("No report for the project %s: %s", result.Name, err)
+			continue
+		}
+		result.Problems = countProblems(report.Runs)
+		summary.Problems += result.Problems
+		sarifFiles = append(sarifFiles, sarifPath)
+	}
+
+	if err := os.MkdirAll(resultsDir, os.ModePerm); err != nil {
+		return summary, err
+	}
+	if len(sarifFiles) > 0 {
+		merged, _, err := MergeSarifFiles(sarifFiles)
+		if err != nil {
+			return summary, fmt.Errorf("failed to merge the project reports: %w", err)
+		}
+		summary.Sarif = filepath.Join(resultsDir, commoncontext.QodanaSarifName)
+		if err = WriteReport(summary.Sarif, merged); err != nil {
+			return summary, err
+		}
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return summary, err
+	}
+	return summary, os.WriteFile(filepath.Join(resultsDir, ProjectsSummaryFileName), data, 0o644)
+}
+
+// ProjectsExitCode returns the exit code of the scan of the projects: the first failure of a project,
+// the exceeded fail threshold is reported only if no project failed otherwise.
+func ProjectsExitCode(results []ProjectResult) int {
+	exitCode := exitcodes.QodanaSuccessExitCode
+	for _, result := range results {
+		switch result.ExitCode {
+		case exitcodes.QodanaSuccessExitCode:
+		case exitcodes.QodanaFailThresholdExitCode:
+			exitCode = exitcodes.QodanaFailThresholdExitCode
+		default:
+			return result.ExitCode
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeProjects(t *testing.T) {
+	resultsDir := t.TempDir()
+	backend := filepath.Join(resultsDir, "backend")
+	frontend := filepath.Join(resultsDir, "frontend")
+	for dir, report := range map[string]string{backend: mergeSarifFirst, frontend: mergeSarifSecond} {
+		assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, commoncontext.QodanaSarifName), []byte(report), 0o644))
+	}
+	results := []ProjectResult{
+		{Name: "backend", Path: "backend", Linter: "qodana-jvm", ResultsDir: backend, ExitCode: 255},
+		{Name: "frontend", Path: "frontend", Linter: "qodana-js", ResultsDir: frontend},
+		{Name: "docs", Path: "docs", ResultsDir: filepath.Join(resultsDir, "docs"), ExitCode: 1},
+	}
+
+	summary, err := SummarizeProjects(resultsDir, results)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.ExitCode)
+	assert.Equal(t, filepath.Join(resultsDir, commoncontext.QodanaSarifName), summary.Sarif)
+	assert.Equal(t, 0, summary.Projects[2].Problems)
+	assert.Equal(t, summary.Projects[0].Problems+summary.Projects[1].Problems, summary.Problems)
+	assert.Positive(t, summary.Projects[0].Problems)
+
+	data, err := os.ReadFile(filepath.Join(resultsDir, ProjectsSummaryFileName))
+	assert.NoError(t, err)
+	var saved ProjectsSummary
+	assert.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, summary, saved)
+
+	merged, err := ReadReport(summary.Sarif)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, merged.Runs)
+}
+
+func TestProjectsExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		exitCodes []int
+		expected  int
+	}{
+		{"no projects", nil, 0},
+		{"all passed", []int{0, 0}, 0},
+		{"fail threshold", []int{0, 255}, 255},
+		{"failure over fail threshold", []int{255, 3, 1}, 3},
+	} {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				results := make([]ProjectResult, 0, len(tc.exitCodes))
+				for _, exitCode := range tc.exitCodes {
+					results = append(results, ProjectResult{ExitCode: exitCode})
+				}
+				assert.Equal(t, tc.expected, ProjectsExitCode(results))
+			},
+		)
+	}
+}
@@ -0,0 +1,265 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package projectstate describes the .qodana directory in the project: the Qodana state kept next to the code,
+// part of it shared through the repository (the baseline, the triage state, the suppressions), part of it local to
+// the machine (the local overrides, the coverage data, the pointer to the last scan).
+package projectstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DirName is the directory in the project with the Qodana state.
+	DirName = ".qodana"
+
+	// Version is the layout version of the directory written and understood by this CLI.
+	Version = 1
+
+	// StateFileName is the file with the layout version, the directory is initialized when it exists.
+	StateFileName = "state.json"
+
+	// BaselineFileName is the baseline used when --baseline isn't given.
+	BaselineFileName = "baseline.sarif.json"
+
+	// TriageDirName is the directory with the triage state of the problems shared by the team.
+	TriageDirName = "triage"
+
+	// SuppressionsDirName is the directory with the suppressions shared by the team.
+	SuppressionsDirName = "suppressions"
+
+	// LocalDirName is the directory with the overrides local to the machine.
+	LocalDirName = "local"
+
+	// CoverageDirName is the directory with the coverage data used when --coverage-dir isn't given.
+	CoverageDirName = "code-coverage"
+
+	// LastScanFileName is the pointer to the results of the last scan of the project on the machine.
+	LastScanFileName = "last-scan.json"
+
+	gitignoreFileName = ".gitignore"
+)
+
+// sharedDirs are the directories committed to the repository.
+var sharedDirs = []string{TriageDirName, SuppressionsDirName}
+
+// localEntries are the entries local to the machine, they are git-ignored and removed by Clean.
+var localEntries = []string{LocalDirName, CoverageDirName, LastScanFileName}
+
+type state struct {
+	Version int `json:"version"`
+}
+
+// LastScan points to the results of the last scan of the project.
+type LastScan struct {
+	Linter     string    `json:"linter"`
+	ResultsDir string    `json:"resultsDir"`
+	ExitCode   int       `json:"exitCode"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Entry is a file or a directory of the state directory.
+type Entry struct {
+	Name   string `json:"name"`
+	Local  bool   `json:"local"`
+	Exists bool   `json:"exists"`
+}
+
+// Dir returns the state directory of the project.
+func Dir(projectDir string) string {
+	return filepath.Join(projectDir, DirName)
+}
+
+// ReadVersion returns the layout version of the state directory, 0 if it isn't initialized.
+func ReadVersion(projectDir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(Dir(projectDir), StateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var s state
+	if err = json.Unmarshal(data, &s); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", StateFileName, err)
+	}
+	if s.Version <= 0 {
+		return 0, fmt.Errorf("%s has no valid version", StateFileName)
+	}
+	return s.Version, nil
+}
+
+// Init creates the state directory with its layout and the .gitignore of the local entries, the existing state is
+// kept. It fails for a directory written by a newer CLI.
+func Init(projectDir string) error {
+	version, err := ReadVersion(projectDir)
+	if err != nil {
+		return err
+	}
+	if version > Version {
+		return unsupportedVersionError(version)
+	}
+	dir := Dir(projectDir)
+	for _, name := range append(sharedDirs, LocalDirName) {
+		if err = os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			return err
+		}
+	}
+	if err = os.WriteFile(filepath.Join(dir, gitignoreFileName), []byte(Gitignore()), 0o644); err != nil {
+		return err
+	}
+	return writeJson(filepath.Join(dir, StateFileName), state{Version: Version})
+}
+
+// Gitignore returns the .gitignore of the state directory, it keeps the local entries out of the repository.
+func Gitignore() string {
+	var b strings.Builder
+	b.WriteString("# Generated by qodana state init, the local state of the machine isn't committed\n")
+	for _, name := range localEntries {
+		b.WriteString("/" + name + "\n")
+	}
+	return b.String()
+}
+
+// IsGitignoreUpToDate tells whether the .gitignore of the state directory ignores all the local entries.
+func IsGitignoreUpToDate(projectDir string) bool {
+	data, err := os.ReadFile(filepath.Join(Dir(projectDir), gitignoreFileName))
+	if err != nil {
+		return false
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for _, name := range localEntries {
+		found := false
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line == "/"+name || line == name || line == "/"+name+"/" || line == name+"/" {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Entries returns the known entries of the state directory.
+func Entries(projectDir string) []Entry {
+	dir := Dir(projectDir)
+	entries := make([]Entry, 0)
+	for _, name := range append([]string{BaselineFileName}, sharedDirs...) {
+		entries = append(entries, Entry{Name: name, Exists: exists(filepath.Join(dir, name))})
+	}
+	for _, name := range localEntries {
+		entries = append(entries, Entry{Name: name, Local: true, Exists: exists(filepath.Join(dir, name))})
+	}
+	return entries
+}
+
+// Clean removes the local entries of the state directory and returns the removed ones, the shared state is kept.
+func Clean(projectDir string, dryRun bool) ([]string, error) {
+	dir := Dir(projectDir)
+	removed := make([]string, 0)
+	for _, name := range localEntries {
+		path := filepath.Join(dir, name)
+		if !exists(path) {
+			continue
+		}
+		if !dryRun {
+			if err := os.RemoveAll(path); err != nil {
+				return removed, err
+			}
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// Baseline returns the baseline of the state directory relative to the project directory, the way --baseline is
+// given, or empty if there is none.
+func Baseline(projectDir string) string {
+	if !exists(filepath.Join(Dir(projectDir), BaselineFileName)) {
+		return ""
+	}
+	return filepath.Join(DirName, BaselineFileName)
+}
+
+// CoverageDir returns the coverage directory of the project.
+func CoverageDir(projectDir string) string {
+	return filepath.Join(Dir(projectDir), CoverageDirName)
+}
+
+// RecordLastScan updates the pointer to the last scan, only in the state directory initialized with Init.
+func RecordLastScan(projectDir string, scan LastScan) {
+	version, err := ReadVersion(projectDir)
+	if err != nil {
+		log.Debugf("Skipping the last scan pointer: %s", err)
+		return
+	}
+	if version == 0 || version > Version {
+		return
+	}
+	if err = writeJson(filepath.Join(Dir(projectDir), LastScanFileName), scan); err != nil {
+		log.Warnf("Failed to write %s: %s", LastScanFileName, err)
+	}
+}
+
+// ReadLastScan returns the pointer to the last scan, nil if there is none.
+func ReadLastScan(projectDir string) (*LastScan, error) {
+	data, err := os.ReadFile(filepath.Join(Dir(projectDir), LastScanFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var scan LastScan
+	if err = json.Unmarshal(data, &scan); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LastScanFileName, err)
+	}
+	return &scan, nil
+}
+
+func unsupportedVersionError(version int) error {
+	return fmt.Errorf(
+		"%s has the layout version %d, this CLI supports up to version %d, update the CLI",
+		DirName,
+		version,
+		Version,
+	)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func writeJson(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
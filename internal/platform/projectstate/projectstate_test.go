@@ -0,0 +1,117 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package projectstate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	projectDir := t.TempDir()
+	version, err := ReadVersion(projectDir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.False(t, IsGitignoreUpToDate(projectDir))
+
+	require.NoError(t, Init(projectDir))
+	version, err = ReadVersion(projectDir)
+	require.NoError(t, err)
+	assert.Equal(t, Version, version)
+	assert.True(t, IsGitignoreUpToDate(projectDir))
+	for _, name := range []string{TriageDirName, SuppressionsDirName, LocalDirName} {
+		assert.DirExists(t, filepath.Join(Dir(projectDir), name))
+	}
+
+	// the existing state is kept
+	baseline := filepath.Join(Dir(projectDir), BaselineFileName)
+	require.NoError(t, os.WriteFile(baseline, []byte("{}"), 0o644))
+	require.NoError(t, Init(projectDir))
+	assert.FileExists(t, baseline)
+
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(projectDir), StateFileName), []byte(`{"version": 2}`), 0o644))
+	assert.Error(t, Init(projectDir))
+}
+
+func TestIsGitignoreUpToDate(t *testing.T) {
+	projectDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(Dir(projectDir), 0o755))
+	gitignore := filepath.Join(Dir(projectDir), ".gitignore")
+
+	require.NoError(t, os.WriteFile(gitignore, []byte("local/\r\ncode-coverage\r\n/last-scan.json\r\n"), 0o644))
+	assert.True(t, IsGitignoreUpToDate(projectDir))
+
+	require.NoError(t, os.WriteFile(gitignore, []byte("/local\n"), 0o644))
+	assert.False(t, IsGitignoreUpToDate(projectDir))
+}
+
+func TestBaseline(t *testing.T) {
+	projectDir := t.TempDir()
+	assert.Equal(t, "", Baseline(projectDir))
+
+	require.NoError(t, os.MkdirAll(Dir(projectDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(projectDir), BaselineFileName), []byte("{}"), 0o644))
+	assert.Equal(t, filepath.Join(".qodana", "baseline.sarif.json"), Baseline(projectDir))
+}
+
+func TestLastScan(t *testing.T) {
+	projectDir := t.TempDir()
+	scan := LastScan{
+		Linter:     "qodana-jvm",
+		ResultsDir: "/tmp/results",
+		ExitCode:   255,
+		FinishedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	// the pointer isn't written to a project without the state directory
+	RecordLastScan(projectDir, scan)
+	lastScan, err := ReadLastScan(projectDir)
+	require.NoError(t, err)
+	assert.Nil(t, lastScan)
+
+	require.NoError(t, Init(projectDir))
+	RecordLastScan(projectDir, scan)
+	lastScan, err = ReadLastScan(projectDir)
+	require.NoError(t, err)
+	require.NotNil(t, lastScan)
+	assert.Equal(t, scan, *lastScan)
+}
+
+func TestClean(t *testing.T) {
+	projectDir := t.TempDir()
+	require.NoError(t, Init(projectDir))
+	require.NoError(t, os.WriteFile(filepath.Join(Dir(projectDir), BaselineFileName), []byte("{}"), 0o644))
+	require.NoError(t, os.MkdirAll(CoverageDir(projectDir), 0o755))
+	RecordLastScan(projectDir, LastScan{Linter: "qodana-jvm"})
+
+	removed, err := Clean(projectDir, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{LocalDirName, CoverageDirName, LastScanFileName}, removed)
+	assert.DirExists(t, CoverageDir(projectDir))
+
+	removed, err = Clean(projectDir, false)
+	require.NoError(t, err)
+	assert.Len(t, removed, 3)
+	for _, entry := range Entries(projectDir) {
+		assert.Equal(t, !entry.Local, entry.Exists, entry.Name)
+	}
+}
@@ -23,12 +23,18 @@
 package platform
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	"github.com/JetBrains/qodana-cli/internal/tooling"
+	log "github.com/sirupsen/logrus"
 )
 
 type Publisher struct {
@@ -37,18 +43,68 @@ type Publisher struct {
 	AnalysisId string
 }
 
+// uploadReport is replaced in tests not to run the publisher.
+var uploadReport = UploadReport
+
+// sleepBeforeUploadRetry is replaced in tests not to wait for the backoff.
+var sleepBeforeUploadRetry = time.Sleep
+
 // SendReport sends report to Qodana Cloud.
 func SendReport(cacheDir string, publisher Publisher, token string) {
+	if res, err := UploadReport(cacheDir, publisher, token); res > 0 || err != nil {
+		msg.ErrorMessage(
+			"Failed to upload the report to Qodana Cloud, upload it later with: qodana upload --results-dir %s",
+			publisher.ResultsDir,
+		)
+		log.StandardLogger().Exit(res)
+	}
+}
+
+// UploadReport sends the report to Qodana Cloud, it returns the exit code of the publisher.
+// With QODANA_CLOUD_RESUMABLE_UPLOAD=true, the report files are uploaded by the CLI itself, and the upload
+// interrupted by a network failure is resumed by the next upload of the same analysis.
+func UploadReport(cacheDir string, publisher Publisher, token string) (int, error) {
+	defer timings.Start(timings.Upload)()
 
+	if strings.EqualFold(os.Getenv(qdenv.QodanaCloudResumableUpload), "true") {
+		client := cloud.GetCloudApiEndpoints().NewCloudApiClient(token)
+		reportUrl, err := client.UploadReport(publisher.ResultsDir, publisher.AnalysisId)
+		if err != nil {
+			return 1, err
+		}
+		msg.SuccessMessage("Report is uploaded to %s", reportUrl)
+		return 0, nil
+	}
 	publisherCommand := getPublisherArgs(
 		cacheDir,
 		publisher,
 		token,
 		cloud.GetCloudRootEndpoint().Url,
 	)
-	if _, _, res, err := utils.LaunchAndLog(publisher.LogDir, "publisher", publisherCommand); res > 0 || err != nil {
-		os.Exit(res)
+	_, _, res, err := utils.LaunchAndLog(publisher.LogDir, "publisher", publisherCommand)
+	return res, err
+}
+
+// UploadReportWithRetries sends the report to Qodana Cloud, the failed upload is retried up to retries times,
+// the backoff before a retry is doubled after each attempt. It returns the exit code of the last attempt.
+func UploadReportWithRetries(
+	cacheDir string,
+	publisher Publisher,
+	token string,
+	retries int,
+	backoff time.Duration,
+) (int, error) {
+	res, err := uploadReport(cacheDir, publisher, token)
+	for retry := 1; retry <= retries && (res != 0 || err != nil); retry++ {
+		if err == nil {
+			err = fmt.Errorf("publisher exited with code %d", res)
+		}
+		msg.WarningMessageCI("Failed to upload the report: %s, retrying in %s (retry %d of %d)", err, backoff, retry, retries)
+		sleepBeforeUploadRetry(backoff)
+		backoff *= 2
+		res, err = uploadReport(cacheDir, publisher, token)
 	}
+	return res, err
 }
 
 // getPublisherArgs returns args for the publisher.
@@ -17,10 +17,12 @@
 package platform
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/internal/tooling"
@@ -59,3 +61,57 @@ func TestGetPublisherArgs(t *testing.T) {
 		t.Errorf("getPublisherArgs returned incorrect arguments: got %v, expected %v", publisherArgs, expectedArgs)
 	}
 }
+
+func TestUploadReportWithRetries(t *testing.T) {
+	var backoffs []time.Duration
+	sleepBeforeUploadRetry = func(d time.Duration) { backoffs = append(backoffs, d) }
+	defer func() {
+		sleepBeforeUploadRetry = time.Sleep
+		uploadReport = UploadReport
+	}()
+
+	for _, tc := range []struct {
+		name             string
+		results          []int
+		retries          int
+		expectedRes      int
+		expectedBackoffs []time.Duration
+	}{
+		{"uploaded", []int{0}, 3, 0, nil},
+		{"uploaded after retries", []int{1, 1, 0}, 3, 0, []time.Duration{time.Second, 2 * time.Second}},
+		{"retries run out", []int{1, 2, 3}, 2, 3, []time.Duration{time.Second, 2 * time.Second}},
+		{"no retries", []int{1}, 0, 1, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			backoffs = nil
+			attempts := 0
+			uploadReport = func(string, Publisher, string) (int, error) {
+				attempts++
+				return tc.results[attempts-1], nil
+			}
+
+			res, err := UploadReportWithRetries(t.TempDir(), Publisher{}, "token", tc.retries, time.Second)
+			if err != nil && tc.expectedRes == 0 {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if res != tc.expectedRes {
+				t.Errorf("got exit code %d, expected %d", res, tc.expectedRes)
+			}
+			if attempts != len(tc.results) {
+				t.Errorf("got %d attempts, expected %d", attempts, len(tc.results))
+			}
+			if !reflect.DeepEqual(backoffs, tc.expectedBackoffs) {
+				t.Errorf("got backoffs %v, expected %v", backoffs, tc.expectedBackoffs)
+			}
+		})
+	}
+
+	t.Run("publisher failed to start", func(t *testing.T) {
+		uploadReport = func(string, Publisher, string) (int, error) {
+			return 1, errors.New("no java")
+		}
+		if _, err := UploadReportWithRetries(t.TempDir(), Publisher{}, "token", 1, time.Second); err == nil {
+			t.Error("expected the error of the last attempt")
+		}
+	})
+}
@@ -18,11 +18,15 @@ package qdcontainer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"runtime"
+	"strings"
 
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/docker/cli/cli/command"
 	dockerCliConfig "github.com/docker/cli/cli/config"
 	"github.com/docker/cli/cli/flags"
@@ -44,59 +48,109 @@ const (
 func PrepareContainerEnvSettings() {
 	ctx := context.Background()
 	_, err := NewContainerClient(ctx)
+	if err != nil && runtime.GOOS == "darwin" &&
+		msg.AskUserConfirm("No container engine is running. Provision a Lima VM with colima for the Qodana containers?") {
+		if err = SetupLimaVm(ctx, DefaultLimaVmOptions()); err == nil {
+			msg.SuccessMessage("The Lima VM is ready, run 'qodana engine setup' to change its resources")
+		}
+	}
 	if err != nil {
 		msg.ErrorMessage(
 			"An error occured while connecting to Docker: %s\n"+
-				"Make sure that Docker or Podman is installed and a socket is available. If Docker is already "+
-				"running, consider setting DOCKER_HOST variable explicitly.",
+				"Make sure that Docker or Podman is installed and a socket is available, or containerd with nerdctl. "+
+				"If Docker is already running, consider setting DOCKER_HOST variable explicitly. "+
+				"On macOS, 'qodana engine setup' provisions a Lima VM for the containers.",
 			err,
 		)
-		os.Exit(1)
+		log.StandardLogger().Exit(1)
 	}
 
 	checkEngineMemory()
 }
 
-// checkEngineMemory applicable only for Docker Desktop,
-// (has the default limit of 2GB which can be not enough when Gradle runs inside a container).
+// RecommendedEngineMemory is the memory limit of the container daemon below which the analysis can run out of memory.
+const RecommendedEngineMemory = 4 * 1024 * 1024 * 1024
+
+// EngineMemoryHelpUrl returns the Docker Desktop page describing how to change its memory limit, empty on Linux
+// where the daemon can use all the host memory.
+func EngineMemoryHelpUrl(goos string) string {
+	switch goos {
+	case "windows":
+		return "https://docs.docker.com/desktop/settings/windows/#advanced"
+	case "darwin":
+		return "https://docs.docker.com/desktop/settings/mac/#advanced-1"
+	default:
+		return ""
+	}
+}
+
+// checkEngineMemory applicable only for Docker Desktop and Lima VMs,
+// (have the default limit of 2GB which can be not enough when Gradle runs inside a container).
 func checkEngineMemory() {
 	docker, err := NewContainerClient(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	goos := runtime.GOOS
-	if //goland:noinspection GoBoolExpressions
-	goos != "windows" && goos != "darwin" {
-		return
-	}
 	info, err := docker.Info(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
-	var helpUrl string
-	//goland:noinspection GoDfaConstantCondition
-	switch goos {
-	case "windows":
-		helpUrl = "https://docs.docker.com/desktop/settings/windows/#advanced"
-	case "darwin":
-		helpUrl = "https://docs.docker.com/desktop/settings/mac/#advanced-1"
+	help := EngineMemoryHelp(info, runtime.GOOS)
+	if help == "" {
+		return
 	}
 	log.Debug("Docker memory limit is set to ", info.MemTotal/1024/1024, " MB")
 
-	if info.MemTotal < 4*1024*1024*1024 {
+	if info.MemTotal < RecommendedEngineMemory {
 		msg.WarningMessage(
 			`The container daemon is running with less than 4GB of RAM.
    If you experience issues, consider increasing the container runtime memory limit.
-   Refer to %s for more information.
+   %s.
 `,
-			helpUrl,
+			help,
 		)
 	}
 }
 
-// NewContainerClient getContainerClient returns a docker client.
+// NewContainerClient returns the client of the container engine: the Docker API of Docker, Podman or the colima VM,
+// or containerd through nerdctl when there's no Docker daemon. QODANA_CONTAINER_ENGINE=containerd selects containerd
+// explicitly, QODANA_CONTAINER_ENGINE=docker turns the fallback to nerdctl off.
 func NewContainerClient(ctx context.Context) (client.APIClient, error) {
+	engine := os.Getenv(qdenv.QodanaContainerEngine)
+	switch engine {
+	case "", "docker":
+	case ContainerdEngine:
+		return newNerdctlClient(ctx)
+	default:
+		return nil, fmt.Errorf("unknown container engine %s=%s, expected docker or %s", qdenv.QodanaContainerEngine, engine, ContainerdEngine)
+	}
+	apiClient, err := newDockerClient(ctx)
+	if dockerHost := colimaDockerHost(DefaultLimaProfile); err != nil && os.Getenv("DOCKER_HOST") == "" && dockerHost != "" {
+		log.Debugf("Docker API is not available, connecting to the colima VM at %s: %s", dockerHost, err)
+		if err = os.Setenv("DOCKER_HOST", dockerHost); err != nil {
+			return nil, err
+		}
+		if apiClient, err = newDockerClient(ctx); err != nil {
+			_ = os.Unsetenv("DOCKER_HOST")
+		}
+	}
+	if err == nil || engine != "" {
+		return apiClient, err
+	}
+	if _, lookErr := exec.LookPath("nerdctl"); lookErr != nil {
+		return nil, err
+	}
+	log.Debugf("Docker API is not available, falling back to containerd with nerdctl: %s", err)
+	containerd, nerdctlErr := newNerdctlClient(ctx)
+	if nerdctlErr != nil {
+		return nil, errors.Join(err, nerdctlErr)
+	}
+	return containerd, nil
+}
+
+// newDockerClient returns the client of the Docker API.
+func newDockerClient(ctx context.Context) (client.APIClient, error) {
 	logWarnWriter := log.StandardLogger().WriterLevel(log.WarnLevel)
 	configFile := dockerCliConfig.LoadDefaultConfigFile(logWarnWriter)
 	err := logWarnWriter.Close()
@@ -122,6 +176,23 @@ func NewContainerClient(ctx context.Context) (client.APIClient, error) {
 	return apiClient, nil
 }
 
+// ContainerEngineName returns the name of the container engine the client is connected to: docker, podman or containerd.
+func ContainerEngineName(ctx context.Context, apiClient client.APIClient) (string, error) {
+	if _, ok := apiClient.(*nerdctlClient); ok {
+		return ContainerdEngine, nil
+	}
+	serverVersion, err := apiClient.ServerVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the container engine version: %w", err)
+	}
+	for _, component := range serverVersion.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			return "podman", nil
+		}
+	}
+	return "docker", nil
+}
+
 func logClientInfo(info system.Info) {
 	if log.GetLevel() < log.DebugLevel {
 		return
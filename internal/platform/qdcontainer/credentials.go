@@ -0,0 +1,185 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// credentialHelperTimeout limits a credential helper, it may exchange a cloud token over the network.
+const credentialHelperTimeout = 30 * time.Second
+
+// acrTokenUsername is the username of the ACR access tokens exchanged for the Azure AD token.
+const acrTokenUsername = "00000000-0000-0000-0000-000000000000"
+
+// explicitCredentials are the registry credentials given with --registry-username, they are only sent to the registry
+// of their ServerAddress.
+var explicitCredentials registry.AuthConfig
+
+// SetRegistryCredentials sets the credentials of the registry host, for the CI systems without a Docker config file.
+func SetRegistryCredentials(host string, username string, password string) {
+	explicitCredentials = registry.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: NormalizeRegistryHost(host),
+	}
+}
+
+// NormalizeRegistryHost returns the registry host the way RegistryHost returns it for the images: without the scheme
+// and the path, and docker.io for Docker Hub.
+func NormalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	default:
+		return host
+	}
+}
+
+var (
+	ecrHostPattern = regexp.MustCompile(`^\d+\.dkr\.ecr(-fips)?\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+	gcrHostPattern = regexp.MustCompile(`^([a-z0-9-]+\.)?gcr\.io$|^[a-z0-9-]+-docker\.pkg\.dev$`)
+	acrHostPattern = regexp.MustCompile(`^[a-z0-9-]+\.azurecr\.(io|cn|us)$`)
+)
+
+// cloudCredentialHelpers returns the Docker credential helpers of the cloud registry, in the order they are tried.
+func cloudCredentialHelpers(host string) []string {
+	switch {
+	case ecrHostPattern.MatchString(host):
+		return []string{"ecr-login"}
+	case gcrHostPattern.MatchString(host):
+		return []string{"gcloud", "gcr"}
+	case acrHostPattern.MatchString(host):
+		return []string{"acr-env"}
+	default:
+		return nil
+	}
+}
+
+// RegistryAuth returns the credentials for the registry host given explicitly for it, or exchanged for the cloud token
+// by the credential helper of ECR, GCR and Artifact Registry or ACR found on PATH, without a Docker config file. It
+// returns false if there are none, the credentials from the Docker config are used if the registry requires them.
+func RegistryAuth(ctx context.Context, host string) (registry.AuthConfig, bool) {
+	if explicitCredentials.Username != "" && explicitCredentials.ServerAddress == host {
+		return explicitCredentials, true
+	}
+	ctx, cancel := context.WithTimeout(ctx, credentialHelperTimeout)
+	defer cancel()
+	for _, helper := range cloudCredentialHelpers(host) {
+		binary, err := exec.LookPath("docker-credential-" + helper)
+		if err != nil {
+			continue
+		}
+		auth, err := runCredentialHelper(ctx, binary, host)
+		if err != nil {
+			log.Warnf("The credential helper %s failed for %s: %s", helper, host, err)
+			continue
+		}
+		log.Debugf("Using the credentials of %s from the credential helper %s", host, helper)
+		return auth, true
+	}
+	if acrHostPattern.MatchString(host) {
+		auth, err := exchangeAcrToken(ctx, host)
+		if err == nil {
+			log.Debugf("Using the ACR access token of %s from the Azure CLI", host)
+			return auth, true
+		}
+		log.Debugf("No ACR access token of %s from the Azure CLI: %s", host, err)
+	}
+	return registry.AuthConfig{}, false
+}
+
+// credentialHelperOutput is the output of the get command of the Docker credential helper protocol.
+type credentialHelperOutput struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// runCredentialHelper gets the credentials of the registry from the Docker credential helper binary.
+func runCredentialHelper(ctx context.Context, binary string, host string) (registry.AuthConfig, error) {
+	out, err := runCredentialCommand(ctx, strings.NewReader(host), binary, "get")
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	return parseCredentialHelperOutput(out, host)
+}
+
+func parseCredentialHelperOutput(out []byte, host string) (registry.AuthConfig, error) {
+	var credentials credentialHelperOutput
+	if err := json.Unmarshal(out, &credentials); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("unexpected output: %w", err)
+	}
+	if credentials.Secret == "" {
+		return registry.AuthConfig{}, errors.New("no credentials")
+	}
+	auth := registry.AuthConfig{ServerAddress: host}
+	// the helpers return an identity token, e.g. an OAuth refresh token, with the <token> username
+	if credentials.Username == "<token>" {
+		auth.IdentityToken = credentials.Secret
+	} else {
+		auth.Username, auth.Password = credentials.Username, credentials.Secret
+	}
+	return auth, nil
+}
+
+// exchangeAcrToken exchanges the Azure AD token of the Azure CLI login for an ACR access token.
+func exchangeAcrToken(ctx context.Context, host string) (registry.AuthConfig, error) {
+	az, err := exec.LookPath("az")
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	name, _, _ := strings.Cut(host, ".")
+	out, err := runCredentialCommand(ctx, nil, az, "acr", "login", "--name", name, "--expose-token", "--output", "json")
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	var token struct {
+		AccessToken string `json:"accessToken"`
+	}
+	if err = json.Unmarshal(out, &token); err != nil || token.AccessToken == "" {
+		return registry.AuthConfig{}, fmt.Errorf("unexpected output of az acr login: %w", err)
+	}
+	return registry.AuthConfig{Username: acrTokenUsername, Password: token.AccessToken, ServerAddress: host}, nil
+}
+
+func runCredentialCommand(ctx context.Context, stdin *strings.Reader, binary string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudCredentialHelpers(t *testing.T) {
+	for host, helpers := range map[string][]string{
+		"123456789012.dkr.ecr.eu-west-1.amazonaws.com":      {"ecr-login"},
+		"123456789012.dkr.ecr-fips.us-east-1.amazonaws.com": {"ecr-login"},
+		"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn":  {"ecr-login"},
+		"gcr.io":                     {"gcloud", "gcr"},
+		"eu.gcr.io":                  {"gcloud", "gcr"},
+		"europe-docker.pkg.dev":      {"gcloud", "gcr"},
+		"qodana.azurecr.io":          {"acr-env"},
+		"docker.io":                  nil,
+		"registry.jetbrains.team":    nil,
+		"amazonaws.com.evil.example": nil,
+	} {
+		assert.Equal(t, helpers, cloudCredentialHelpers(host), host)
+	}
+}
+
+func TestParseCredentialHelperOutput(t *testing.T) {
+	auth, err := parseCredentialHelperOutput(
+		[]byte(`{"ServerURL":"gcr.io","Username":"oauth2accesstoken","Secret":"ya29.token"}`),
+		"gcr.io",
+	)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		registry.AuthConfig{Username: "oauth2accesstoken", Password: "ya29.token", ServerAddress: "gcr.io"},
+		auth,
+	)
+
+	auth, err = parseCredentialHelperOutput(
+		[]byte(`{"ServerURL":"qodana.azurecr.io","Username":"<token>","Secret":"refresh"}`),
+		"qodana.azurecr.io",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, registry.AuthConfig{IdentityToken: "refresh", ServerAddress: "qodana.azurecr.io"}, auth)
+
+	_, err = parseCredentialHelperOutput([]byte(`{"Username":"user"}`), "gcr.io")
+	assert.Error(t, err)
+	_, err = parseCredentialHelperOutput([]byte("credentials not found"), "gcr.io")
+	assert.Error(t, err)
+}
+
+func TestRegistryAuthExplicitCredentials(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	SetRegistryCredentials("https://registry.example.com/", "ci", "secret")
+	defer SetRegistryCredentials("", "", "")
+
+	auth, ok := RegistryAuth(context.Background(), "registry.example.com")
+	assert.True(t, ok)
+	assert.Equal(
+		t,
+		registry.AuthConfig{Username: "ci", Password: "secret", ServerAddress: "registry.example.com"},
+		auth,
+	)
+
+	_, ok = RegistryAuth(context.Background(), "docker.io")
+	assert.False(t, ok, "the credentials must not be sent to the other registries")
+}
+
+func TestNormalizeRegistryHost(t *testing.T) {
+	assert.Equal(t, "registry.example.com:5000", NormalizeRegistryHost("registry.example.com:5000"))
+	assert.Equal(t, "registry.example.com", NormalizeRegistryHost("https://registry.example.com/v2/"))
+	assert.Equal(t, "docker.io", NormalizeRegistryHost("index.docker.io"))
+	assert.Equal(t, RegistryHost("jetbrains/qodana-jvm"), NormalizeRegistryHost("registry-1.docker.io"))
+}
+
+func TestRegistryAuthWithoutCredentials(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, ok := RegistryAuth(context.Background(), "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+	assert.False(t, ok)
+}
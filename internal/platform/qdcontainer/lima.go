@@ -0,0 +1,205 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/docker/docker/api/types/system"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLimaProfile is the colima profile of the VM provisioned by qodana engine setup.
+	DefaultLimaProfile = "default"
+	gigabyte           = 1024 * 1024 * 1024
+)
+
+// LimaVmOptions are the resources of the Lima VM provisioned with colima.
+type LimaVmOptions struct {
+	Profile string
+	Cpus    int
+	// MemoryGb is the memory of the VM, the memory available to the containers is a bit lower.
+	MemoryGb int
+	DiskGb   int
+	// VmType is the colima VM type: vz for Apple Virtualization on macOS 13 and later, or qemu. Colima's default if empty.
+	VmType string
+}
+
+// DefaultLimaVmOptions returns the resources of the VM enough for the analysis of a large project.
+func DefaultLimaVmOptions() LimaVmOptions {
+	return LimaVmOptions{Profile: DefaultLimaProfile, Cpus: 4, MemoryGb: 6, DiskGb: 60}
+}
+
+// colimaStatus is the output of colima status --json.
+type colimaStatus struct {
+	DockerSocket string `json:"docker_socket"`
+	Cpu          int    `json:"cpu"`
+	Memory       int64  `json:"memory"`
+}
+
+// IsLimaVm tells whether the Docker daemon runs in a Lima VM, provisioned by colima or limactl.
+func IsLimaVm(info system.Info) bool {
+	return info.Name == "colima" || strings.HasPrefix(info.Name, "colima-") || strings.HasPrefix(info.Name, "lima-")
+}
+
+// EngineMemoryHelp returns how to increase the memory of the container engine: the colima command for a Lima VM, the
+// Docker Desktop page on macOS and Windows. It's empty on Linux where the daemon can use all the host memory.
+func EngineMemoryHelp(info system.Info, goos string) string {
+	if IsLimaVm(info) {
+		return fmt.Sprintf(
+			"Run 'qodana engine setup --memory %d' to restart the VM with more memory",
+			DefaultLimaVmOptions().MemoryGb,
+		)
+	}
+	if helpUrl := EngineMemoryHelpUrl(goos); helpUrl != "" {
+		return "See " + helpUrl
+	}
+	return ""
+}
+
+// colimaHome returns the directory of the colima VMs, COLIMA_HOME or ~/.colima.
+func colimaHome() string {
+	if home := os.Getenv("COLIMA_HOME"); home != "" {
+		return home
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(userHome, ".colima")
+}
+
+// colimaDockerHost returns the Docker host of the colima VM of the profile, empty if the VM was never started.
+func colimaDockerHost(profile string) string {
+	home := colimaHome()
+	if home == "" {
+		return ""
+	}
+	socket := filepath.Join(home, profile, "docker.sock")
+	if _, err := os.Stat(socket); err != nil {
+		return ""
+	}
+	return "unix://" + socket
+}
+
+// colimaStartArgs returns the arguments of colima start for the VM with the Docker runtime.
+func colimaStartArgs(opts LimaVmOptions) []string {
+	args := []string{
+		"start",
+		"--profile", opts.Profile,
+		"--runtime", "docker",
+		"--cpu", strconv.Itoa(opts.Cpus),
+		"--memory", strconv.Itoa(opts.MemoryGb),
+		"--disk", strconv.Itoa(opts.DiskGb),
+	}
+	if opts.VmType != "" {
+		args = append(args, "--vm-type", opts.VmType)
+	}
+	return args
+}
+
+// SetupLimaVm provisions the Lima VM with colima for the Qodana containers on macOS without Docker Desktop: colima is
+// installed with Homebrew after the confirmation, the VM is started or restarted with the given resources and Qodana
+// connects to its Docker socket.
+func SetupLimaVm(ctx context.Context, opts LimaVmOptions) error {
+	colima, err := findColima()
+	if err != nil {
+		return err
+	}
+	status, running := readColimaStatus(ctx, colima, opts.Profile)
+	if running && status.Memory >= int64(opts.MemoryGb)*gigabyte && status.Cpu >= opts.Cpus {
+		msg.SuccessMessage("The Lima VM %s is already running with %d CPUs and %d GB of memory", opts.Profile, status.Cpu, status.Memory/gigabyte)
+	} else {
+		if running {
+			msg.WarningMessage("Restarting the Lima VM %s with %d CPUs and %d GB of memory", opts.Profile, opts.Cpus, opts.MemoryGb)
+			if err = runColima(ctx, colima, "stop", "--profile", opts.Profile); err != nil {
+				return err
+			}
+		} else {
+			msg.SuccessMessage("Starting the Lima VM %s with %d CPUs and %d GB of memory", opts.Profile, opts.Cpus, opts.MemoryGb)
+		}
+		if err = runColima(ctx, colima, colimaStartArgs(opts)...); err != nil {
+			return err
+		}
+		if status, running = readColimaStatus(ctx, colima, opts.Profile); !running {
+			return fmt.Errorf("the Lima VM %s didn't start, see 'colima status --profile %s'", opts.Profile, opts.Profile)
+		}
+	}
+	if status.DockerSocket != "" {
+		if err = os.Setenv("DOCKER_HOST", "unix://"+status.DockerSocket); err != nil {
+			return err
+		}
+	}
+	if _, err = newDockerClient(ctx); err != nil {
+		return fmt.Errorf("the Lima VM %s is running, but its Docker socket is not available: %w", opts.Profile, err)
+	}
+	return nil
+}
+
+// findColima returns the colima binary, it's installed with Homebrew if the user agrees.
+func findColima() (string, error) {
+	if colima, err := exec.LookPath("colima"); err == nil {
+		return colima, nil
+	}
+	brew, err := exec.LookPath("brew")
+	if err != nil || !msg.AskUserConfirm("colima is not installed. Install it with Homebrew?") {
+		return "", errors.New("colima is required to run the containers in a Lima VM, install it with 'brew install colima'")
+	}
+	cmd := exec.Command(brew, "install", "colima")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to install colima: %w", err)
+	}
+	return exec.LookPath("colima")
+}
+
+// readColimaStatus returns the status of the VM of the profile, colima status fails if the VM is not running.
+func readColimaStatus(ctx context.Context, colima string, profile string) (colimaStatus, bool) {
+	var status colimaStatus
+	out, err := exec.CommandContext(ctx, colima, "status", "--profile", profile, "--json").Output()
+	if err != nil {
+		log.Debugf("The Lima VM %s is not running: %s", profile, err)
+		return status, false
+	}
+	if err = json.Unmarshal(out, &status); err != nil {
+		log.Debugf("Unexpected output of colima status: %s", err)
+	}
+	return status, true
+}
+
+func runColima(ctx context.Context, colima string, args ...string) error {
+	log.Debugf("Running %s %s", colima, strings.Join(args, " "))
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, colima, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("colima %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/system"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineMemoryHelp(t *testing.T) {
+	assert.Contains(t, EngineMemoryHelp(system.Info{Name: "colima"}, "darwin"), "qodana engine setup --memory 6")
+	assert.Contains(t, EngineMemoryHelp(system.Info{Name: "lima-docker"}, "linux"), "qodana engine setup")
+	assert.Equal(
+		t,
+		"See https://docs.docker.com/desktop/settings/mac/#advanced-1",
+		EngineMemoryHelp(system.Info{Name: "docker-desktop"}, "darwin"),
+	)
+	assert.Empty(t, EngineMemoryHelp(system.Info{Name: "build-agent"}, "linux"))
+}
+
+func TestColimaStartArgs(t *testing.T) {
+	opts := DefaultLimaVmOptions()
+	opts.VmType = "vz"
+	assert.Equal(
+		t,
+		"start --profile default --runtime docker --cpu 4 --memory 6 --disk 60 --vm-type vz",
+		strings.Join(colimaStartArgs(opts), " "),
+	)
+}
+
+func TestColimaDockerHost(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("COLIMA_HOME", home)
+	assert.Empty(t, colimaDockerHost(DefaultLimaProfile))
+
+	socket := filepath.Join(home, DefaultLimaProfile, "docker.sock")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(socket), 0o755))
+	assert.NoError(t, os.WriteFile(socket, nil, 0o600))
+	assert.Equal(t, "unix://"+socket, colimaDockerHost(DefaultLimaProfile))
+}
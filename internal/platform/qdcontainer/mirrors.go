@@ -0,0 +1,193 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// RegistryMirrorsFileName is the file in the Qodana user configuration directory with the registry mirrors the
+// images are pulled through, e.g. a pull-through cache of Docker Hub in the corporate registry:
+//
+//	mirrors:
+//	  - from: docker.io/jetbrains/*
+//	    to: registry.example.com/dockerhub/jetbrains
+const RegistryMirrorsFileName = "registries.yaml"
+
+// RegistryMirror rewrites the images of the repositories under From to the same paths under To.
+type RegistryMirror struct {
+	// From is a registry or a repository prefix, docker.io/jetbrains/* or docker.io/jetbrains. The images without a
+	// registry are from docker.io, like in Docker.
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+type registryMirrors struct {
+	Mirrors []RegistryMirror `yaml:"mirrors"`
+}
+
+var (
+	loadMirrorsOnce sync.Once
+	mirrors         []RegistryMirror
+)
+
+// RegistryMirrorsPath returns the path of the registry mirrors file, empty if there is no user configuration
+// directory.
+func RegistryMirrorsPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "JetBrains", "Qodana", RegistryMirrorsFileName)
+}
+
+// LoadRegistryMirrors reads the registry mirrors file, there are no mirrors if it doesn't exist.
+func LoadRegistryMirrors(path string) ([]RegistryMirror, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config registryMirrors
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i, mirror := range config.Mirrors {
+		from, to := mirrorRepository(mirror.From), mirrorRepository(mirror.To)
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("mirror %d in %s needs both from and to", i+1, path)
+		}
+		config.Mirrors[i] = RegistryMirror{From: normalizeMirrorPrefix(from), To: to}
+	}
+	return config.Mirrors, nil
+}
+
+// mirrorRepository trims the wildcard and the slashes of the repository prefix of a mirror.
+func mirrorRepository(prefix string) string {
+	return strings.Trim(strings.TrimSuffix(strings.TrimSpace(prefix), "*"), "/")
+}
+
+// loadedRegistryMirrors returns the mirrors from the user configuration, they are read once.
+func loadedRegistryMirrors() []RegistryMirror {
+	loadMirrorsOnce.Do(
+		func() {
+			path := RegistryMirrorsPath()
+			if path == "" {
+				return
+			}
+			var err error
+			if mirrors, err = LoadRegistryMirrors(path); err != nil {
+				log.Warnf("The registry mirrors are not used: %s", err)
+			}
+		},
+	)
+	return mirrors
+}
+
+// ResolveImage returns the image to pull instead of the given one, through the first matching registry mirror from
+// the user configuration. The image is returned as is if no mirror matches.
+func ResolveImage(image string) string {
+	return resolveImage(loadedRegistryMirrors(), image)
+}
+
+// UnmirrorImage returns the original image of the image of a registry mirror, e.g. to check it's an official one.
+func UnmirrorImage(image string) string {
+	return unmirrorImage(loadedRegistryMirrors(), image)
+}
+
+func resolveImage(mirrors []RegistryMirror, image string) string {
+	normalized := normalizeImage(image)
+	for _, mirror := range mirrors {
+		if rest, ok := cutRepositoryPrefix(normalized, mirror.From); ok {
+			log.Debugf("Pulling %s through the mirror %s", image, mirror.To)
+			return mirror.To + rest
+		}
+	}
+	return image
+}
+
+func unmirrorImage(mirrors []RegistryMirror, image string) string {
+	for _, mirror := range mirrors {
+		if rest, ok := cutRepositoryPrefix(image, mirror.To); ok {
+			return strings.TrimPrefix(mirror.From+rest, "docker.io/")
+		}
+	}
+	return image
+}
+
+// cutRepositoryPrefix returns the rest of the image after the repository prefix, it matches whole path components.
+func cutRepositoryPrefix(image string, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(image, prefix)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, ":") && !strings.HasPrefix(rest, "@")) {
+		return "", false
+	}
+	return rest, true
+}
+
+// RegistryHost returns the registry of the image, docker.io for the images without one.
+func RegistryHost(image string) string {
+	host, _, _ := strings.Cut(normalizeImage(image), "/")
+	return host
+}
+
+// normalizeImage adds the registry to the image the way Docker does: docker.io for the images without one and
+// docker.io/library for the official images, e.g. jetbrains/qodana-jvm is docker.io/jetbrains/qodana-jvm.
+func normalizeImage(image string) string {
+	first, rest, hasRest := strings.Cut(image, "/")
+	switch {
+	case !hasRest:
+		return "docker.io/library/" + image
+	case first == "docker.io" || first == "index.docker.io":
+		if !strings.Contains(rest, "/") {
+			return "docker.io/library/" + rest
+		}
+		return "docker.io/" + rest
+	case isRegistry(first):
+		return image
+	default:
+		return "docker.io/" + image
+	}
+}
+
+// normalizeMirrorPrefix adds docker.io to the prefix of a mirror unless it starts with a registry, jetbrains is the
+// namespace docker.io/jetbrains.
+func normalizeMirrorPrefix(prefix string) string {
+	first, rest, _ := strings.Cut(prefix, "/")
+	switch {
+	case first == "index.docker.io":
+		return strings.TrimSuffix("docker.io/"+rest, "/")
+	case isRegistry(first):
+		return prefix
+	default:
+		return "docker.io/" + prefix
+	}
+}
+
+// isRegistry tells whether the first component of an image is a registry host rather than a Docker Hub namespace.
+func isRegistry(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
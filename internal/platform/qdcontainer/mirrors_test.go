@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRegistryMirrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), RegistryMirrorsFileName)
+	mirrors, err := LoadRegistryMirrors(path)
+	assert.NoError(t, err)
+	assert.Empty(t, mirrors)
+
+	require.NoError(
+		t,
+		os.WriteFile(
+			path, []byte(`mirrors:
+  - from: docker.io/jetbrains/*
+    to: registry.example.com/dockerhub/jetbrains/
+  - from: ghcr.io
+    to: registry.example.com/ghcr
+  - from: library
+    to: registry.example.com/dockerhub/library
+`), 0o644,
+		),
+	)
+	mirrors, err = LoadRegistryMirrors(path)
+	require.NoError(t, err)
+	assert.Equal(
+		t, []RegistryMirror{
+			{From: "docker.io/jetbrains", To: "registry.example.com/dockerhub/jetbrains"},
+			{From: "ghcr.io", To: "registry.example.com/ghcr"},
+			{From: "docker.io/library", To: "registry.example.com/dockerhub/library"},
+		}, mirrors,
+	)
+
+	require.NoError(t, os.WriteFile(path, []byte("mirrors:\n  - from: docker.io\n"), 0o644))
+	_, err = LoadRegistryMirrors(path)
+	assert.ErrorContains(t, err, "needs both from and to")
+}
+
+func TestResolveImage(t *testing.T) {
+	mirrors := []RegistryMirror{
+		{From: "docker.io/jetbrains", To: "registry.example.com/dockerhub/jetbrains"},
+		{From: "ghcr.io", To: "registry.example.com/ghcr"},
+		{From: "docker.io/library", To: "registry.example.com/dockerhub/library"},
+	}
+	for image, expected := range map[string]string{
+		"jetbrains/qodana-jvm:2024.3":             "registry.example.com/dockerhub/jetbrains/qodana-jvm:2024.3",
+		"docker.io/jetbrains/qodana-go:2024.3":    "registry.example.com/dockerhub/jetbrains/qodana-go:2024.3",
+		"index.docker.io/jetbrains/qodana-go":     "registry.example.com/dockerhub/jetbrains/qodana-go",
+		"ghcr.io/example/linter@sha256:0123":      "registry.example.com/ghcr/example/linter@sha256:0123",
+		"ubuntu:24.04":                            "registry.example.com/dockerhub/library/ubuntu:24.04",
+		"jetbrainsx/qodana-jvm:2024.3":            "jetbrainsx/qodana-jvm:2024.3",
+		"registry.example.com/qodana-jvm:2024.3":  "registry.example.com/qodana-jvm:2024.3",
+		"localhost:5000/jetbrains/qodana-jvm:1.0": "localhost:5000/jetbrains/qodana-jvm:1.0",
+	} {
+		assert.Equal(t, expected, resolveImage(mirrors, image), image)
+	}
+	assert.Equal(t, "jetbrains/qodana-jvm:2024.3", resolveImage(nil, "jetbrains/qodana-jvm:2024.3"))
+
+	assert.Equal(
+		t,
+		"jetbrains/qodana-jvm:2024.3",
+		unmirrorImage(mirrors, "registry.example.com/dockerhub/jetbrains/qodana-jvm:2024.3"),
+	)
+	assert.Equal(t, "ghcr.io/example/linter", unmirrorImage(mirrors, "registry.example.com/ghcr/example/linter"))
+	assert.Equal(t, "jetbrains/qodana-jvm:2024.3", unmirrorImage(mirrors, "jetbrains/qodana-jvm:2024.3"))
+}
@@ -0,0 +1,516 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	log "github.com/sirupsen/logrus"
+)
+
+// ContainerdEngine is the name of the containerd engine, run with nerdctl when there's no Docker daemon.
+const ContainerdEngine = "containerd"
+
+// nerdctlClient runs the containers on containerd with nerdctl, the Docker-compatible CLI of containerd, for the hosts
+// without a Docker daemon (e.g. k3s nodes). It implements the calls of the Docker API the CLI makes to run a linter,
+// the other ones fail. The containerd socket and namespace are set for nerdctl with CONTAINERD_ADDRESS and
+// CONTAINERD_NAMESPACE.
+type nerdctlClient struct {
+	client.APIClient
+	binary string
+	// ttys are the names and IDs of the created containers with a TTY, their logs aren't multiplexed.
+	ttys sync.Map
+}
+
+// unsupportedTransport fails the calls of the Docker API the nerdctl client doesn't implement.
+type unsupportedTransport struct{}
+
+func (unsupportedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("%s %s is not supported by the %s engine", request.Method, request.URL.Path, ContainerdEngine)
+}
+
+func newNerdctlClient(ctx context.Context) (*nerdctlClient, error) {
+	binary, err := exec.LookPath("nerdctl")
+	if err != nil {
+		return nil, fmt.Errorf("nerdctl is required to run the containers on containerd: %w", err)
+	}
+	unsupported, err := client.NewClientWithOpts(client.WithHTTPClient(&http.Client{Transport: unsupportedTransport{}}))
+	if err != nil {
+		return nil, err
+	}
+	c := &nerdctlClient{APIClient: unsupported, binary: binary}
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to containerd: %w", err)
+	}
+	logClientInfo(info)
+	return c, nil
+}
+
+func (c *nerdctlClient) command(ctx context.Context, env []string, args ...string) *exec.Cmd {
+	log.Debugf("Running %s %s", c.binary, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+func (c *nerdctlClient) run(ctx context.Context, env []string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := c.command(ctx, env, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("nerdctl %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// runJson runs nerdctl and decodes its JSON output, the fields of another type than in the Docker API are skipped.
+func (c *nerdctlClient) runJson(ctx context.Context, v any, args ...string) error {
+	out, err := c.run(ctx, nil, args...)
+	if err != nil {
+		return err
+	}
+	var typeErr *json.UnmarshalTypeError
+	if err = json.Unmarshal(out, v); errors.As(err, &typeErr) {
+		log.Debugf("Skipped the field %s of nerdctl %s: %s", typeErr.Field, args[0], err)
+		return nil
+	}
+	return err
+}
+
+func (c *nerdctlClient) Info(ctx context.Context) (system.Info, error) {
+	var info system.Info
+	err := c.runJson(ctx, &info, "info", "--format", "{{json .}}")
+	return info, err
+}
+
+func (c *nerdctlClient) ServerVersion(ctx context.Context) (types.Version, error) {
+	var version struct {
+		Server types.Version
+	}
+	if err := c.runJson(ctx, &version, "version", "--format", "{{json .}}"); err != nil {
+		return types.Version{}, err
+	}
+	version.Server.Platform.Name = ContainerdEngine
+	for _, component := range version.Server.Components {
+		if component.Name == ContainerdEngine {
+			version.Server.Version = component.Version
+		}
+	}
+	return version.Server, nil
+}
+
+func (c *nerdctlClient) Close() error {
+	return nil
+}
+
+func (c *nerdctlClient) ImageInspect(ctx context.Context, ref string, _ ...client.ImageInspectOption) (
+	image.InspectResponse,
+	error,
+) {
+	var inspect []image.InspectResponse
+	if err := c.runJson(ctx, &inspect, "image", "inspect", "--mode=dockercompat", ref); err != nil {
+		return image.InspectResponse{}, err
+	}
+	if len(inspect) == 0 {
+		return image.InspectResponse{}, fmt.Errorf("no such image: %s", ref)
+	}
+	return inspect[0], nil
+}
+
+// ImagePull pulls the image with the registry credentials of the Docker config, nerdctl reads them itself.
+func (c *nerdctlClient) ImagePull(ctx context.Context, ref string, _ image.PullOptions) (io.ReadCloser, error) {
+	if _, err := c.run(ctx, nil, "pull", "--quiet", ref); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (c *nerdctlClient) ImageTag(ctx context.Context, source string, target string) error {
+	_, err := c.run(ctx, nil, "tag", source, target)
+	return err
+}
+
+func (c *nerdctlClient) ImageSave(ctx context.Context, refs []string, _ ...client.ImageSaveOption) (io.ReadCloser, error) {
+	var stderr bytes.Buffer
+	cmd := c.command(ctx, nil, append([]string{"save"}, refs...)...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &stderr
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("nerdctl save: %w", err)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := io.Copy(writer, out)
+		if waitErr := cmd.Wait(); waitErr != nil {
+			err = fmt.Errorf("nerdctl save: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+		}
+		_ = writer.CloseWithError(err)
+	}()
+	return &commandReader{PipeReader: reader, cmd: cmd}, nil
+}
+
+func (c *nerdctlClient) ImageLoad(ctx context.Context, input io.Reader, _ ...client.ImageLoadOption) (image.LoadResponse, error) {
+	var stderr bytes.Buffer
+	cmd := c.command(ctx, nil, "load")
+	cmd.Stdin = input
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return image.LoadResponse{}, fmt.Errorf("nerdctl load: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return image.LoadResponse{Body: io.NopCloser(bytes.NewReader(out))}, nil
+}
+
+func (c *nerdctlClient) ContainerCreate(
+	ctx context.Context,
+	config *container.Config,
+	hostConfig *container.HostConfig,
+	_ *network.NetworkingConfig,
+	_ *ocispec.Platform,
+	name string,
+) (container.CreateResponse, error) {
+	args, env, err := nerdctlCreateArgs(config, hostConfig, name)
+	if err != nil {
+		return container.CreateResponse{}, err
+	}
+	out, err := c.run(ctx, env, args...)
+	if err != nil {
+		return container.CreateResponse{}, err
+	}
+	id := strings.TrimSpace(string(out))
+	if config.Tty {
+		c.ttys.Store(id, true)
+		if name != "" {
+			c.ttys.Store(name, true)
+		}
+	}
+	return container.CreateResponse{ID: id}, nil
+}
+
+// nerdctlCreateArgs returns the arguments of nerdctl create and the environment of nerdctl: the values of the
+// container environment are passed through it to be hidden from the process list.
+func nerdctlCreateArgs(config *container.Config, hostConfig *container.HostConfig, name string) ([]string, []string, error) {
+	args := []string{"create"}
+	if name != "" {
+		args = append(args, "--name", name)
+	}
+	if config.Tty {
+		args = append(args, "--tty")
+	}
+	if config.User != "" {
+		args = append(args, "--user", config.User)
+	}
+	if config.StopTimeout != nil {
+		args = append(args, "--stop-timeout", strconv.Itoa(*config.StopTimeout))
+	}
+	var env []string
+	for _, variable := range config.Env {
+		key, _, _ := strings.Cut(variable, "=")
+		args = append(args, "--env", key)
+		env = append(env, variable)
+	}
+	if hostConfig != nil {
+		hostArgs, err := nerdctlHostArgs(hostConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, hostArgs...)
+	}
+	cmd := config.Cmd
+	if len(config.Entrypoint) > 0 {
+		args = append(args, "--entrypoint", config.Entrypoint[0])
+		cmd = append(append([]string{}, config.Entrypoint[1:]...), cmd...)
+	}
+	args = append(args, config.Image)
+	return append(args, cmd...), env, nil
+}
+
+func nerdctlHostArgs(hostConfig *container.HostConfig) ([]string, error) {
+	var args []string
+	if hostConfig.AutoRemove {
+		args = append(args, "--rm")
+	}
+	if hostConfig.UsernsMode != "" && !hostConfig.UsernsMode.IsHost() {
+		return nil, fmt.Errorf("the user namespace %s is not supported by the %s engine", hostConfig.UsernsMode, ContainerdEngine)
+	}
+	if hostConfig.NetworkMode != "" && !hostConfig.NetworkMode.IsDefault() {
+		args = append(args, "--network", string(hostConfig.NetworkMode))
+	}
+	for port, bindings := range hostConfig.PortBindings {
+		for _, binding := range bindings {
+			args = append(args, "--publish", fmt.Sprintf("%s:%s:%s", binding.HostIP, binding.HostPort, port))
+		}
+	}
+	for _, capability := range hostConfig.CapAdd {
+		args = append(args, "--cap-add", capability)
+	}
+	for _, option := range hostConfig.SecurityOpt {
+		args = append(args, "--security-opt", option)
+	}
+	for _, bind := range hostConfig.Binds {
+		args = append(args, "--volume", bind)
+	}
+	for _, m := range hostConfig.Mounts {
+		mountArgs, err := nerdctlMountArgs(m)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, mountArgs...)
+	}
+	return args, nil
+}
+
+func nerdctlMountArgs(m mount.Mount) ([]string, error) {
+	if m.Type == mount.TypeTmpfs {
+		var options []string
+		if m.TmpfsOptions != nil && m.TmpfsOptions.SizeBytes > 0 {
+			options = append(options, fmt.Sprintf("size=%d", m.TmpfsOptions.SizeBytes))
+		}
+		if m.TmpfsOptions != nil && m.TmpfsOptions.Mode != 0 {
+			options = append(options, fmt.Sprintf("mode=%o", m.TmpfsOptions.Mode))
+		}
+		if len(options) == 0 {
+			return []string{"--tmpfs", m.Target}, nil
+		}
+		return []string{"--tmpfs", m.Target + ":" + strings.Join(options, ",")}, nil
+	}
+	if m.Type != mount.TypeBind && m.Type != mount.TypeVolume {
+		return nil, fmt.Errorf("the %s mount of %s is not supported by the %s engine", m.Type, m.Target, ContainerdEngine)
+	}
+	if m.VolumeOptions != nil && m.VolumeOptions.DriverConfig != nil {
+		return nil, fmt.Errorf("the volume driver of %s is not supported by the %s engine", m.Target, ContainerdEngine)
+	}
+	fields := []string{"type=" + string(m.Type), "source=" + m.Source, "target=" + m.Target}
+	if m.ReadOnly {
+		fields = append(fields, "readonly")
+	}
+	if m.BindOptions != nil && m.BindOptions.Propagation != "" {
+		fields = append(fields, "bind-propagation="+string(m.BindOptions.Propagation))
+	}
+	return []string{"--mount", strings.Join(fields, ",")}, nil
+}
+
+func (c *nerdctlClient) ContainerStart(ctx context.Context, id string, _ container.StartOptions) error {
+	_, err := c.run(ctx, nil, "start", id)
+	return err
+}
+
+func (c *nerdctlClient) ContainerWait(
+	ctx context.Context,
+	id string,
+	_ container.WaitCondition,
+) (<-chan container.WaitResponse, <-chan error) {
+	statusCh := make(chan container.WaitResponse, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		out, err := c.run(ctx, nil, "wait", id)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		exitCode, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		if err != nil {
+			errCh <- fmt.Errorf("unexpected exit code of the container %s: %w", id, err)
+			return
+		}
+		statusCh <- container.WaitResponse{StatusCode: exitCode}
+	}()
+	return statusCh, errCh
+}
+
+// ContainerLogs returns the logs of the container like Docker: the lines of the container without a TTY are
+// multiplexed, with the header of the stdout stream.
+func (c *nerdctlClient) ContainerLogs(ctx context.Context, id string, options container.LogsOptions) (io.ReadCloser, error) {
+	args := []string{"logs"}
+	if options.Follow {
+		args = append(args, "--follow")
+	}
+	if options.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	cmd := c.command(ctx, nil, append(args, id)...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("nerdctl logs: %w", err)
+	}
+	reader, writer := io.Pipe()
+	_, tty := c.ttys.Load(id)
+	go func() {
+		_ = writer.CloseWithError(errors.Join(copyLogLines(writer, out, tty), cmd.Wait()))
+	}()
+	return &commandReader{PipeReader: reader, cmd: cmd}, nil
+}
+
+// copyLogLines copies the log lines, each one is framed like in the multiplexed logs of Docker unless there's a TTY.
+func copyLogLines(w io.Writer, r io.Reader, tty bool) error {
+	if !tty {
+		w = stdcopy.NewStdWriter(w, stdcopy.Stdout)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if _, err := w.Write(append(scanner.Bytes(), '\n')); err != nil {
+			// the logs are closed by the reader
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// commandReader reads the output of a command, closing it stops the command.
+type commandReader struct {
+	*io.PipeReader
+	cmd *exec.Cmd
+}
+
+func (r *commandReader) Close() error {
+	_ = r.cmd.Process.Kill()
+	return r.PipeReader.Close()
+}
+
+func (c *nerdctlClient) ContainerStop(ctx context.Context, id string, options container.StopOptions) error {
+	args := []string{"stop"}
+	if options.Timeout != nil {
+		args = append(args, "--time", strconv.Itoa(*options.Timeout))
+	}
+	_, err := c.run(ctx, nil, append(args, id)...)
+	return err
+}
+
+func (c *nerdctlClient) ContainerKill(ctx context.Context, id string, signal string) error {
+	args := []string{"kill"}
+	if signal != "" {
+		args = append(args, "--signal", signal)
+	}
+	_, err := c.run(ctx, nil, append(args, id)...)
+	return err
+}
+
+func (c *nerdctlClient) ContainerRemove(ctx context.Context, id string, options container.RemoveOptions) error {
+	args := []string{"rm"}
+	if options.Force {
+		args = append(args, "--force")
+	}
+	if options.RemoveVolumes {
+		args = append(args, "--volumes")
+	}
+	_, err := c.run(ctx, nil, append(args, id)...)
+	return err
+}
+
+func (c *nerdctlClient) ContainerInspect(ctx context.Context, id string) (container.InspectResponse, error) {
+	var inspect []container.InspectResponse
+	if err := c.runJson(ctx, &inspect, "inspect", "--mode=dockercompat", id); err != nil {
+		return container.InspectResponse{}, err
+	}
+	if len(inspect) == 0 {
+		return container.InspectResponse{}, fmt.Errorf("no such container: %s", id)
+	}
+	return inspect[0], nil
+}
+
+// ContainerList lists the containers, the filters of the options are not supported.
+func (c *nerdctlClient) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	args := []string{"ps", "--no-trunc", "--format", "{{json .}}"}
+	if options.All {
+		args = append(args, "--all")
+	}
+	out, err := c.run(ctx, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseNerdctlContainers(out)
+}
+
+func parseNerdctlContainers(out []byte) ([]container.Summary, error) {
+	var containers []container.Summary
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var ps struct {
+			ID     string
+			Names  string
+			Image  string
+			Status string
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &ps); err != nil {
+			return nil, fmt.Errorf("unexpected output of nerdctl ps: %w", err)
+		}
+		containers = append(
+			containers, container.Summary{
+				ID:     ps.ID,
+				Names:  []string{"/" + ps.Names},
+				Image:  ps.Image,
+				Status: ps.Status,
+				State:  nerdctlContainerState(ps.Status),
+			},
+		)
+	}
+	return containers, scanner.Err()
+}
+
+// nerdctlContainerState returns the state of the container from its status in nerdctl ps, e.g. "Exited (0) 1 minute ago".
+func nerdctlContainerState(status string) container.ContainerState {
+	switch {
+	case strings.HasPrefix(status, "Up"):
+		return container.StateRunning
+	case strings.HasPrefix(status, "Created"):
+		return container.StateCreated
+	case strings.HasPrefix(status, "Exited"):
+		return container.StateExited
+	case strings.HasPrefix(status, "Paused"):
+		return container.StatePaused
+	case strings.HasPrefix(status, "Restarting"):
+		return container.StateRestarting
+	default:
+		return container.ContainerState(strings.ToLower(status))
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNerdctlCreateArgs(t *testing.T) {
+	stopTimeout := 30
+	args, env, err := nerdctlCreateArgs(
+		&container.Config{
+			Image:       "jetbrains/qodana-jvm:latest",
+			Cmd:         []string{"--save-report"},
+			Tty:         true,
+			User:        "1000:1000",
+			Env:         []string{"QODANA_TOKEN=secret"},
+			StopTimeout: &stopTimeout,
+		},
+		&container.HostConfig{
+			AutoRemove:  true,
+			NetworkMode: "host",
+			PortBindings: nat.PortMap{
+				"5005": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "5006"}},
+			},
+			CapAdd: []string{"SYS_ADMIN"},
+			Mounts: []mount.Mount{
+				{Type: mount.TypeBind, Source: "/project", Target: "/data/project", ReadOnly: true},
+				{Type: mount.TypeTmpfs, Target: "/data/cache/idea", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 1024}},
+			},
+		},
+		"qodana-cli-run",
+	)
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		"create --name qodana-cli-run --tty --user 1000:1000 --stop-timeout 30 --env QODANA_TOKEN --rm "+
+			"--network host --publish 0.0.0.0:5006:5005 --cap-add SYS_ADMIN "+
+			"--mount type=bind,source=/project,target=/data/project,readonly --tmpfs /data/cache/idea:size=1024 "+
+			"jetbrains/qodana-jvm:latest --save-report",
+		strings.Join(args, " "),
+	)
+	assert.Equal(t, []string{"QODANA_TOKEN=secret"}, env)
+	assert.NotContains(t, args, "secret")
+}
+
+func TestNerdctlCreateArgs_Entrypoint(t *testing.T) {
+	args, _, err := nerdctlCreateArgs(&container.Config{Image: "image", Entrypoint: []string{"sh", "-c"}, Cmd: []string{"true"}}, nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"create", "--entrypoint", "sh", "image", "-c", "true"}, args)
+}
+
+func TestNerdctlCreateArgs_Unsupported(t *testing.T) {
+	_, _, err := nerdctlCreateArgs(
+		&container.Config{Image: "image"},
+		&container.HostConfig{
+			Mounts: []mount.Mount{
+				{
+					Type:          mount.TypeVolume,
+					Target:        "/data/project",
+					VolumeOptions: &mount.VolumeOptions{DriverConfig: &mount.Driver{Name: "local"}},
+				},
+			},
+		},
+		"",
+	)
+	assert.ErrorContains(t, err, "not supported by the containerd engine")
+}
+
+func TestParseNerdctlContainers(t *testing.T) {
+	containers, err := parseNerdctlContainers(
+		[]byte(`{"ID":"1","Names":"qodana-cli-run","Image":"image","Status":"Up"}
+{"ID":"2","Names":"created","Image":"image","Status":"Created"}
+{"ID":"3","Names":"exited","Image":"image","Status":"Exited (0) 2 minutes ago"}
+`),
+	)
+	assert.NoError(t, err)
+	assert.Len(t, containers, 3)
+	assert.Equal(t, []string{"/qodana-cli-run"}, containers[0].Names)
+	assert.Equal(t, container.StateRunning, containers[0].State)
+	assert.Equal(t, container.StateCreated, containers[1].State)
+	assert.Equal(t, container.StateExited, containers[2].State)
+}
+
+func TestCopyLogLines(t *testing.T) {
+	var framed bytes.Buffer
+	assert.NoError(t, copyLogLines(&framed, strings.NewReader("first\nsecond\n"), false))
+	lines := strings.Split(strings.TrimSuffix(framed.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	for i, line := range []string{"first", "second"} {
+		// the header of a multiplexed Docker log line is stripped by the readers of the logs
+		assert.Equal(t, line, lines[i][8:])
+	}
+
+	var raw bytes.Buffer
+	assert.NoError(t, copyLogLines(&raw, strings.NewReader("first\nsecond"), true))
+	assert.Equal(t, "first\nsecond\n", raw.String())
+}
@@ -23,6 +23,7 @@ import (
 	"strings"
 
 	"github.com/JetBrains/qodana-cli/internal/foundation/str"
+	"github.com/JetBrains/qodana-cli/internal/platform/ci"
 	"github.com/JetBrains/qodana-cli/internal/platform/version"
 	cienvironment "github.com/cucumber/ci-environment/go"
 	log "github.com/sirupsen/logrus"
@@ -42,6 +43,7 @@ const (
 	QodanaRevision                = "QODANA_REVISION"
 	QodanaCliContainerName        = "QODANA_CLI_CONTAINER_NAME"
 	QodanaCliContainerKeep        = "QODANA_CLI_CONTAINER_KEEP"
+	QodanaContainerEngine         = "QODANA_CONTAINER_ENGINE"
 	QodanaDistEnv                 = "QODANA_DIST"
 	QodanaCorettoSdk              = "QODANA_CORETTO_SDK"
 	AndroidSdkRoot                = "ANDROID_SDK_ROOT"
@@ -58,7 +60,31 @@ const (
 	QodanaCloudRequestCooldownEnv = "QODANA_CLOUD_REQUEST_COOLDOWN"
 	QodanaCloudRequestTimeoutEnv  = "QODANA_CLOUD_REQUEST_TIMEOUT"
 	QodanaCloudRequestRetriesEnv  = "QODANA_CLOUD_REQUEST_RETRIES"
+	QodanaCloudResumableUpload    = "QODANA_CLOUD_RESUMABLE_UPLOAD"
+	QodanaCloudUploadTimeoutEnv   = "QODANA_CLOUD_UPLOAD_TIMEOUT"
+	QodanaCloudUploadConcurrency  = "QODANA_CLOUD_UPLOAD_CONCURRENCY"
 	QodanaSkipSubmoduleUpdate     = "QODANA_SKIP_SUBMODULE_UPDATE"
+	QodanaToolsUpdate             = "QODANA_TOOLS_UPDATE"
+	QodanaToolsUpdateUrl          = "QODANA_TOOLS_UPDATE_URL"
+	QodanaToolsUpdateKey          = "QODANA_TOOLS_UPDATE_KEY"
+	QodanaProjectsScan            = "QODANA_PROJECTS_SCAN"
+	QodanaLinterFallback          = "QODANA_LINTER_FALLBACK"
+	QodanaRemoteCacheOnHost       = "QODANA_REMOTE_CACHE_ON_HOST"
+	QodanaRunLockToken            = "QODANA_RUN_LOCK_TOKEN"
+	QodanaOffline                 = "QODANA_OFFLINE"
+	QodanaNoVerify                = "QODANA_NO_VERIFY"
+	QodanaRegistry                = "QODANA_REGISTRY"
+	QodanaRegistryUsername        = "QODANA_REGISTRY_USERNAME"
+	QodanaRegistryPassword        = "QODANA_REGISTRY_PASSWORD"
+	QodanaIdeIntegrationToken     = "QODANA_IDE_INTEGRATION_TOKEN"
+	QodanaOidcToken               = "QODANA_OIDC_TOKEN"
+	QodanaOidcAudience            = "QODANA_OIDC_AUDIENCE"
+	QodanaEndpointProfile         = "QODANA_ENDPOINT_PROFILE"
+	QodanaSecretPatterns          = "QODANA_SECRET_PATTERNS"
+	QodanaSmtpPassword            = "QODANA_SMTP_PASSWORD"
+	QodanaWebhookSecret           = "QODANA_WEBHOOK_SECRET"
+	GitHubOidcRequestUrl          = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	GitHubOidcRequestToken        = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
 
 	// QodanaEndpointEnv QodanaToken properties accessed only by GetQodanaGlobalEnv
 	QodanaEndpointEnv = "QODANA_ENDPOINT"
@@ -138,6 +164,11 @@ func IsContainer() bool {
 	return os.Getenv(QodanaDockerEnv) != ""
 }
 
+// IsOffline tells if the run is in the offline mode (--offline), when nothing is requested over the network.
+func IsOffline() bool {
+	return strings.EqualFold(os.Getenv(QodanaOffline), "true")
+}
+
 // ExtractQodanaEnvironment extracts Qodana environment variables from the current environment.
 func ExtractQodanaEnvironment(setEnvironmentFunc func(string, string)) {
 	if license := os.Getenv(QodanaLicense); license != "" {
@@ -155,6 +186,9 @@ func ExtractQodanaEnvironment(setEnvironmentFunc func(string, string)) {
 	if revision := os.Getenv(QodanaRevision); revision != "" {
 		setEnvironmentFunc(QodanaRevision, revision)
 	}
+	if IsOffline() {
+		setEnvironmentFunc(QodanaOffline, "true")
+	}
 	ci := cienvironment.DetectCIEnvironment()
 	qEnv := "cli"
 	if ci != nil {
@@ -163,7 +197,7 @@ func ExtractQodanaEnvironment(setEnvironmentFunc func(string, string)) {
 		if ci.Git != nil {
 			setEnvironmentFunc(QodanaRemoteUrl, validateRemoteUrl(ci.Git.Remote, qEnv))
 			setEnvironmentFunc(QodanaBranch, validateBranch(ci.Git.Branch, qEnv))
-			setEnvironmentFunc(QodanaRevision, ci.Git.Revision)
+			setEnvironmentFunc(QodanaRevision, validateRevision(ci.Git.Revision))
 		}
 		setEnvironmentFunc(QodanaNugetUrl, os.Getenv(QodanaNugetUrl))
 		setEnvironmentFunc(QodanaNugetUser, os.Getenv(QodanaNugetUser))
@@ -211,6 +245,8 @@ func validateBranch(branch string, env string) string {
 			branch = os.Getenv("BUILD_SOURCEBRANCHNAME")
 		case "jenkins":
 			branch = os.Getenv("GIT_BRANCH")
+		default:
+			branch = ci.Detect().Branch
 		}
 	}
 	if branch == "" {
@@ -220,10 +256,20 @@ func validateBranch(branch string, env string) string {
 	return branch
 }
 
+func validateRevision(revision string) string {
+	if revision == "" {
+		return ci.Detect().Revision
+	}
+	return revision
+}
+
 func validateJobUrl(ciUrl string, qEnv string) string {
 	if strings.HasPrefix(qEnv, "azure") { // temporary workaround for Azure Pipelines
 		return getAzureJobUrl()
 	}
+	if ciUrl == "" {
+		ciUrl = ci.Detect().BuildUrl
+	}
 	_, err := url.ParseRequestURI(ciUrl)
 	if err != nil {
 		return ""
@@ -81,6 +81,20 @@ func TestIsContainer(t *testing.T) {
 	assert.True(t, IsContainer())
 }
 
+func TestIsOffline(t *testing.T) {
+	t.Setenv(QodanaOffline, "")
+	assert.False(t, IsOffline())
+
+	t.Setenv(QodanaOffline, "false")
+	assert.False(t, IsOffline())
+
+	t.Setenv(QodanaOffline, "true")
+	assert.True(t, IsOffline())
+
+	t.Setenv(QodanaOffline, "TRUE")
+	assert.True(t, IsOffline())
+}
+
 func TestInitializeAndGetQodanaGlobalEnv(t *testing.T) {
 	provider := mockEnvProvider{envVars: []string{QodanaEndpointEnv + "=https://test.endpoint"}}
 	InitializeQodanaGlobalEnv(provider)
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyComments are written above the keys of qodana.yaml by WriteConfigWithComments.
+var keyComments = map[string]string{
+	"version":       "The version of the qodana.yaml format",
+	"linter":        "The linter to run, see https://www.jetbrains.com/help/qodana/linters.html",
+	"ide":           "The IDE to run the analysis with natively, instead of the linter image",
+	"profile":       "The inspection profile: qodana.starter, qodana.recommended or a path to your own profile",
+	"include":       "The inspections to run in addition to the profile",
+	"exclude":       "The inspections or the paths to skip",
+	"ignore":        "The problems to suppress in the report on the paths, e.g. vendored or generated code",
+	"failThreshold": "The number of problems the run fails on",
+	"mounts":        "The tmpfs mounts and the named volumes of the Qodana container",
+	"remoteCache":   "The bucket the cache is restored from and saved to in CI",
+	"bootstrap":     "The shell command to prepare the project before the analysis",
+	"projectJDK":    "The JDK to build the project with",
+	"dotnet":        "The .NET solution or project to analyze",
+	"php":           "The PHP version of the project",
+}
+
+// commentedOutKeys are appended commented out to qodana.yaml by WriteConfigWithComments for the keys it doesn't set,
+// so the file shows what can be configured next.
+var commentedOutKeys = []struct {
+	key     string
+	example string
+}{
+	{"include", "include:\n  - name: <SomeEnabledInspectionId>"},
+	{"exclude", "exclude:\n  - name: <SomeDisabledInspectionId>\n    paths:\n      - <path/where/not/run/inspection>"},
+	{"failThreshold", "failThreshold: 0"},
+	{"bootstrap", "bootstrap: sh ./prepare-qodana.sh"},
+}
+
+// WriteConfigWithComments writes QodanaYaml to the given path like WriteConfigWithWarning, with a comment above
+// every known key and commented-out examples of the common keys that are not set.
+func (q *QodanaYaml) WriteConfigWithComments(path string) error {
+	var node yaml.Node
+	if err := node.Encode(q); err != nil {
+		return err
+	}
+	present := map[string]bool{}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			present[key.Value] = true
+			if comment, ok := keyComments[key.Value]; ok {
+				key.HeadComment = comment
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	yamlEncoder := yaml.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(&node); err != nil {
+		return err
+	}
+	if err := yamlEncoder.Close(); err != nil {
+		return err
+	}
+	for _, commented := range commentedOutKeys {
+		if present[commented.key] {
+			continue
+		}
+		b.WriteString("\n# " + keyComments[commented.key] + "\n")
+		for _, line := range strings.Split(commented.example, "\n") {
+			b.WriteString("#" + line + "\n")
+		}
+	}
+	out := append([]byte(warningComment), b.Bytes()...)
+	return os.WriteFile(path, out, 0o600)
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteConfigWithComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qodana.yaml")
+	threshold := 10
+	q := QodanaYaml{
+		Version:       "1.0",
+		Linter:        "jetbrains/qodana-jvm:latest",
+		Profile:       Profile{Name: "qodana.starter"},
+		FailThreshold: &threshold,
+	}
+	require.NoError(t, q.WriteConfigWithComments(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	content := string(data)
+	assert.Contains(t, content, "WARNING: Do not store sensitive information")
+	assert.Contains(t, content, "# The linter to run, see https://www.jetbrains.com/help/qodana/linters.html\nlinter: jetbrains/qodana-jvm:latest\n")
+	assert.Contains(t, content, "# The number of problems the run fails on\nfailThreshold: 10\n")
+	assert.Contains(t, content, "#include:\n#  - name: <SomeEnabledInspectionId>\n")
+	assert.NotContains(t, content, "#failThreshold")
+
+	loaded := LoadQodanaYamlByFullPath(path)
+	assert.Equal(t, q.Linter, loaded.Linter)
+	assert.Equal(t, q.Profile, loaded.Profile)
+	assert.Equal(t, 10, *loaded.FailThreshold)
+	assert.Empty(t, loaded.Includes)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// envReference matches `${VAR}` and `${VAR:-default}`.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?}`)
+
+// envExpansionSkippedKeys are the top-level keys with shell commands, they are expanded by the shell that runs them.
+var envExpansionSkippedKeys = []string{"bootstrap", "prepare"}
+
+// unsetEnvWarnings keeps the unset variables already reported, qodana.yaml is loaded several times during a run.
+var unsetEnvWarnings sync.Map
+
+// ExpandEnv replaces `${VAR}` and `${VAR:-default}` in s with the value of the environment variable VAR, the
+// default is used if VAR is unset or empty. References to unset variables without a default are kept as is.
+func ExpandEnv(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return envReference.ReplaceAllStringFunc(
+		s, func(reference string) string {
+			match := envReference.FindStringSubmatch(reference)
+			name, hasDefault := match[1], strings.Contains(reference, ":-")
+			value, ok := os.LookupEnv(name)
+			if ok && (value != "" || !hasDefault) {
+				return value
+			}
+			if hasDefault {
+				return match[2]
+			}
+			if _, warned := unsetEnvWarnings.LoadOrStore(name, true); !warned {
+				log.Warnf("qodana.yaml references the environment variable %s which is not set", name)
+			}
+			return reference
+		},
+	)
+}
+
+// expandYamlEnv expands the environment variables in the scalar values of a qodana.yaml document, keys and the
+// shell commands of bootstrap and prepare steps are left as is.
+func expandYamlEnv(document *yaml.Node) {
+	visited := map[*yaml.Node]bool{}
+	var expand func(node *yaml.Node)
+	expand = func(node *yaml.Node) {
+		node = resolveYamlAlias(node)
+		if visited[node] {
+			return
+		}
+		visited[node] = true
+		switch node.Kind {
+		case yaml.ScalarNode:
+			expanded := ExpandEnv(node.Value)
+			if expanded == node.Value {
+				return
+			}
+			node.Value = expanded
+			if node.Style == 0 {
+				// a plain scalar is resolved after the expansion, so `failThreshold: ${THRESHOLD}` is a number
+				node.Tag = ""
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				expand(node.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for _, child := range node.Content {
+				expand(child)
+			}
+		}
+	}
+
+	document = resolveYamlAlias(document)
+	if document.Kind != yaml.MappingNode {
+		expand(document)
+		return
+	}
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		if slices.Contains(envExpansionSkippedKeys, document.Content[i].Value) {
+			visited[resolveYamlAlias(document.Content[i+1])] = true
+		}
+	}
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		if !slices.Contains(envExpansionSkippedKeys, document.Content[i].Value) {
+			expand(document.Content[i+1])
+		}
+	}
+}
+
+// ExpandEnvInFile rewrites the qodana.yaml file at path with the environment variables expanded, so the analyzer
+// reading it gets the same values as the CLI. The file is not touched if it has no references.
+func ExpandEnvInFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(data, []byte("${")) {
+		return nil
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		err = decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(document.Content) > 0 {
+			expandYamlEnv(document.Content[0])
+		}
+		if err = encoder.Encode(&document); err != nil {
+			return err
+		}
+	}
+	if err = encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0o600)
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("QD_TEST_LINTER", "jetbrains/qodana-jvm")
+	t.Setenv("QD_TEST_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"no references", "qodana.recommended", "qodana.recommended"},
+		{"set", "${QD_TEST_LINTER}:latest", "jetbrains/qodana-jvm:latest"},
+		{"default of set", "${QD_TEST_LINTER:-jetbrains/qodana-go}", "jetbrains/qodana-jvm"},
+		{"default of unset", "${QD_TEST_UNSET:-Release}", "Release"},
+		{"default of empty", "${QD_TEST_EMPTY:-Release}", "Release"},
+		{"empty without default", "a${QD_TEST_EMPTY}b", "ab"},
+		{"unset without default", "${QD_TEST_UNSET}", "${QD_TEST_UNSET}"},
+		{"not a variable", "${user.home}/.m2", "${user.home}/.m2"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.expected, ExpandEnv(tt.value))
+			},
+		)
+	}
+}
+
+func TestParseQodanaYamlEnv(t *testing.T) {
+	t.Setenv("QD_TEST_LINTER", "jetbrains/qodana-dotnet")
+	t.Setenv("QD_TEST_CONFIGURATION", "Debug")
+	t.Setenv("QD_TEST_THRESHOLD", "5")
+
+	q, err := ParseQodanaYaml(
+		[]byte(`version: "1.0"
+linter: ${QD_TEST_LINTER}
+failThreshold: ${QD_TEST_THRESHOLD}
+properties:
+  build.configuration: ${QD_TEST_CONFIGURATION:-Release}
+  build.platform: "${QD_TEST_PLATFORM:-x64}"
+dotnet:
+  solution: ${QD_TEST_SOLUTION:-App.sln}
+bootstrap: echo ${QD_TEST_CONFIGURATION}
+prepare:
+  - run: dotnet restore -p:Configuration=${QD_TEST_CONFIGURATION}
+`),
+		"jetbrains/qodana-dotnet",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "jetbrains/qodana-dotnet", q.Linter)
+	assert.Equal(t, 5, *q.FailThreshold)
+	assert.Equal(t, map[string]string{"build.configuration": "Debug", "build.platform": "x64"}, q.Properties)
+	assert.Equal(t, "App.sln", q.DotNet.Solution)
+	assert.Equal(t, "echo ${QD_TEST_CONFIGURATION}", q.Bootstrap)
+	assert.Equal(t, "dotnet restore -p:Configuration=${QD_TEST_CONFIGURATION}", q.Prepare[0].Run)
+}
+
+func TestParseQodanaYamlEnvSelectsLinterDocument(t *testing.T) {
+	t.Setenv("QD_TEST_LINTER", "jetbrains/qodana-go")
+
+	q, err := ParseQodanaYaml(
+		[]byte(`linter: jetbrains/qodana-jvm
+profile:
+  name: qodana.recommended
+---
+linter: ${QD_TEST_LINTER}
+profile:
+  name: qodana.starter
+`),
+		"jetbrains/qodana-go",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "qodana.starter", q.Profile.Name)
+}
+
+func TestExpandEnvInFile(t *testing.T) {
+	t.Setenv("QD_TEST_PROFILE", "qodana.starter")
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "qodana.yaml")
+	err := os.WriteFile(
+		path, []byte(`version: "1.0"
+# the profile of the environment
+profile:
+  name: ${QD_TEST_PROFILE:-qodana.recommended}
+bootstrap: echo ${HOME}
+`), 0o600,
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, ExpandEnvInFile(path))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(
+		t, `version: "1.0"
+# the profile of the environment
+profile:
+  name: qodana.starter
+bootstrap: echo ${HOME}
+`, string(data),
+	)
+
+	unchanged := filepath.Join(dir, "unchanged.yaml")
+	content := "version: \"1.0\"\nlinter:   jetbrains/qodana-jvm\n"
+	assert.NoError(t, os.WriteFile(unchanged, []byte(content), 0o600))
+	assert.NoError(t, ExpandEnvInFile(unchanged))
+	data, err = os.ReadFile(unchanged)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
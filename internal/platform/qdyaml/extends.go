@@ -0,0 +1,263 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExtendsKey is the qodana.yaml key with the path or the URL of the base configuration.
+const ExtendsKey = "extends"
+
+const extendsDownloadTimeout = 30 * time.Second
+
+// downloadedConfigurations keeps the base configurations downloaded by the process, qodana.yaml is loaded several
+// times during a run.
+var downloadedConfigurations sync.Map
+
+// extendsClient downloads the remote base configurations.
+var extendsClient = &http.Client{Timeout: extendsDownloadTimeout}
+
+// IsRemoteLocation reports whether the location of a configuration is a URL, only HTTPS ones are read, see
+// ReadLocation.
+func IsRemoteLocation(location string) bool {
+	return strings.HasPrefix(location, "https://") || isInsecureLocation(location)
+}
+
+// isInsecureLocation reports whether the location is an HTTP URL, the configuration could be changed on the way.
+func isInsecureLocation(location string) bool {
+	return strings.HasPrefix(location, "http://")
+}
+
+// ResolveLocation returns the location of the configuration ref referenced from the configuration at from: URLs and
+// absolute paths are kept, relative ones are resolved against the directory (or the URL) of from.
+func ResolveLocation(from string, ref string) (string, error) {
+	if IsRemoteLocation(ref) || (filepath.IsAbs(ref) && !IsRemoteLocation(from)) {
+		return ref, nil
+	}
+	if IsRemoteLocation(from) {
+		base, err := url.Parse(from)
+		if err != nil {
+			return "", err
+		}
+		relative, err := url.Parse(filepath.ToSlash(ref))
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(relative).String(), nil
+	}
+	return filepath.Join(filepath.Dir(from), ref), nil
+}
+
+// ReadLocation returns the content of the configuration at the path or the HTTPS URL, HTTP URLs are rejected.
+func ReadLocation(location string) ([]byte, error) {
+	if !IsRemoteLocation(location) {
+		return os.ReadFile(location)
+	}
+	if isInsecureLocation(location) {
+		return nil, fmt.Errorf("%s is not an HTTPS URL, the configurations are only downloaded over HTTPS", location)
+	}
+	if data, ok := downloadedConfigurations.Load(location); ok {
+		return data.([]byte), nil
+	}
+	resp, err := extendsClient.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", location, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", location, err)
+	}
+	downloadedConfigurations.Store(location, data)
+	return data, nil
+}
+
+// ExtendsLocation returns the location of the base configuration of the mapping, "" if it doesn't extend any.
+func ExtendsLocation(mapping *yaml.Node, from string) (string, error) {
+	i := yamlMappingKeyIndex(mapping, ExtendsKey)
+	if i < 0 {
+		return "", nil
+	}
+	value := resolveYamlAlias(mapping.Content[i+1])
+	if value.Kind != yaml.ScalarNode || value.Value == "" {
+		return "", fmt.Errorf("line %d: %s must be a path or a URL", value.Line, ExtendsKey)
+	}
+	return ResolveLocation(from, value.Value)
+}
+
+// LoadQodanaYamlNode returns the configuration at the location merged on top of the configuration it extends, see
+// MergeLayers. The base configurations can extend other ones, the document specific to the linter is selected by
+// the names in every file, see ParseQodanaYaml. The result is nil if the file has no documents.
+func LoadQodanaYamlNode(location string, names ...string) (*yaml.Node, error) {
+	return loadQodanaYamlNode(location, names, nil)
+}
+
+func loadQodanaYamlNode(location string, names []string, loading []string) (*yaml.Node, error) {
+	if slices.Contains(loading, location) {
+		return nil, fmt.Errorf("%s extends itself: %s", location, strings.Join(append(loading, location), " -> "))
+	}
+	data, err := ReadLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	node, err := ParseQodanaYamlNode(data, names...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", location, err)
+	}
+	if node == nil {
+		return nil, nil
+	}
+	base, err := loadBaseQodanaYamlNode(node, location, names, append(loading, location))
+	if err != nil || base == nil {
+		return node, err
+	}
+	return withoutExtends(MergeLayers(base, node)), nil
+}
+
+// LoadBaseQodanaYamlNode returns the configuration the qodana.yaml at fullPath extends, nil if it doesn't extend any.
+func LoadBaseQodanaYamlNode(fullPath string, names ...string) (*yaml.Node, error) {
+	if fullPath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	node, err := ParseQodanaYamlNode(data, names...)
+	if err != nil || node == nil {
+		return nil, err
+	}
+	return loadBaseQodanaYamlNode(node, fullPath, names, []string{fullPath})
+}
+
+func loadBaseQodanaYamlNode(node *yaml.Node, location string, names []string, loading []string) (*yaml.Node, error) {
+	baseLocation, err := ExtendsLocation(node, location)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, err)
+	}
+	if baseLocation == "" {
+		return nil, nil
+	}
+	base, err := loadQodanaYamlNode(baseLocation, names, loading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the configuration %s extends: %w", location, err)
+	}
+	return base, nil
+}
+
+// ExtendQodanaYamlFile rewrites the qodana.yaml file at path with the base configuration merged under every document,
+// so the analyzer reading it gets the same values as the CLI whichever document it selects. The extends keys are
+// removed.
+func ExtendQodanaYamlFile(path string, base *yaml.Node) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	merged := false
+	for {
+		var document yaml.Node
+		err = decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(document.Content) > 0 && resolveYamlAlias(document.Content[0]).Kind == yaml.MappingNode {
+			root := withoutExtends(resolveYamlAlias(document.Content[0]))
+			if base != nil {
+				root = MergeLayers(base, root)
+				merged = true
+			}
+			document.Content[0] = root
+		}
+		if err = encoder.Encode(&document); err != nil {
+			return err
+		}
+	}
+	if base != nil && !merged {
+		if err = encoder.Encode(base); err != nil {
+			return err
+		}
+	}
+	if err = encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0o600)
+}
+
+// MergeLayers returns dst with src set on top: mappings are merged key by key, lists are concatenated and the other
+// values are replaced, like config-loader-cli merges `imports`. The nodes are not modified.
+func MergeLayers(dst *yaml.Node, src *yaml.Node) *yaml.Node {
+	dst, src = resolveYamlAlias(dst), resolveYamlAlias(src)
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		merged := *dst
+		merged.Content = slices.Clone(dst.Content)
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			j := yamlMappingKeyIndex(&merged, key.Value)
+			if j < 0 || key.Value == "<<" {
+				merged.Content = append(merged.Content, key, value)
+				continue
+			}
+			merged.Content[j+1] = MergeLayers(merged.Content[j+1], value)
+		}
+		return &merged
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		merged := *src
+		merged.Content = slices.Concat(dst.Content, src.Content)
+		return &merged
+	}
+	return src
+}
+
+// withoutExtends returns a copy of the mapping without the extends key.
+func withoutExtends(mapping *yaml.Node) *yaml.Node {
+	i := yamlMappingKeyIndex(mapping, ExtendsKey)
+	if i < 0 {
+		return mapping
+	}
+	result := *mapping
+	result.Content = slices.Delete(slices.Clone(mapping.Content), i, i+2)
+	return &result
+}
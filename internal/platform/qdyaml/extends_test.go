@@ -0,0 +1,217 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestLoadQodanaYamlExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(
+		t, dir, map[string]string{
+			"project/qodana.yaml": `version: "1.0"
+extends: ../org/qodana-base.yaml
+exclude:
+  - name: JavaDoc
+properties:
+  idea.max.intellisense.filesize: "5000"
+`,
+			"org/qodana-base.yaml": `extends: defaults.yaml
+linter: jetbrains/qodana-jvm
+exclude:
+  - name: All
+    paths:
+      - build
+properties:
+  idea.max.intellisense.filesize: "2500"
+  build.configuration: Release
+`,
+			"org/defaults.yaml": "profile:\n  name: qodana.recommended\nlinter: jetbrains/qodana-jvm-community\n",
+		},
+	)
+
+	q := LoadQodanaYamlForLinter(filepath.Join(dir, "project", "qodana.yaml"))
+	assert.Equal(t, "1.0", q.Version)
+	assert.Equal(t, "jetbrains/qodana-jvm", q.Linter)
+	assert.Equal(t, "qodana.recommended", q.Profile.Name)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"build"}}, {Name: "JavaDoc"}}, q.Excludes)
+	assert.Equal(
+		t,
+		map[string]string{"idea.max.intellisense.filesize": "5000", "build.configuration": "Release"},
+		q.Properties,
+	)
+}
+
+func TestLoadQodanaYamlExtendsUrl(t *testing.T) {
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/configs/jvm.yaml":
+					_, _ = w.Write([]byte("extends: common.yaml\nlinter: jetbrains/qodana-jvm\n"))
+				case "/configs/common.yaml":
+					_, _ = w.Write([]byte("profile:\n  name: qodana.starter\n"))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	defer server.Close()
+	defaultClient := extendsClient
+	extendsClient = server.Client()
+	defer func() { extendsClient = defaultClient }()
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"qodana.yaml": "extends: " + server.URL + "/configs/jvm.yaml\n"})
+	node, err := LoadQodanaYamlNode(filepath.Join(dir, "qodana.yaml"))
+	assert.NoError(t, err)
+	q := QodanaYaml{}
+	assert.NoError(t, node.Decode(&q))
+	assert.Equal(t, "jetbrains/qodana-jvm", q.Linter)
+	assert.Equal(t, "qodana.starter", q.Profile.Name)
+
+	writeTestFiles(t, dir, map[string]string{"qodana.yaml": "extends: " + server.URL + "/configs/missing.yaml\n"})
+	_, err = LoadQodanaYamlNode(filepath.Join(dir, "qodana.yaml"))
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	writeTestFiles(t, dir, map[string]string{"qodana.yaml": "extends: http://example.com/configs/jvm.yaml\n"})
+	_, err = LoadQodanaYamlNode(filepath.Join(dir, "qodana.yaml"))
+	assert.ErrorContains(t, err, "not an HTTPS URL")
+}
+
+func TestLoadQodanaYamlExtendsCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(
+		t, dir, map[string]string{
+			"qodana.yaml": "extends: base.yaml\n",
+			"base.yaml":   "extends: qodana.yaml\n",
+		},
+	)
+	_, err := LoadQodanaYamlNode(filepath.Join(dir, "qodana.yaml"))
+	assert.ErrorContains(t, err, "extends itself")
+
+	writeTestFiles(t, dir, map[string]string{"qodana.yaml": "extends:\n  - base.yaml\n"})
+	_, err = LoadQodanaYamlNode(filepath.Join(dir, "qodana.yaml"))
+	assert.ErrorContains(t, err, "extends must be a path or a URL")
+}
+
+func TestResolveLocation(t *testing.T) {
+	tests := []struct {
+		from     string
+		ref      string
+		expected string
+	}{
+		{"/project/qodana.yaml", "base.yaml", "/project/base.yaml"},
+		{"/project/qodana.yaml", "/org/base.yaml", "/org/base.yaml"},
+		{"/project/qodana.yaml", "https://example.com/base.yaml", "https://example.com/base.yaml"},
+		{"https://example.com/configs/jvm.yaml", "common.yaml", "https://example.com/configs/common.yaml"},
+		{"https://example.com/configs/jvm.yaml", "/common.yaml", "https://example.com/common.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.ref, func(t *testing.T) {
+				location, err := ResolveLocation(tt.from, tt.ref)
+				assert.NoError(t, err)
+				assert.Equal(t, filepath.FromSlash(tt.expected), filepath.FromSlash(location))
+			},
+		)
+	}
+}
+
+func TestExtendQodanaYamlFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(
+		t, dir, map[string]string{
+			"base.yaml": "profile:\n  name: qodana.starter\nexclude:\n  - name: All\n",
+			"qodana.yaml": `version: "1.0"
+extends: base.yaml
+exclude:
+  - name: JavaDoc
+`,
+		},
+	)
+	base, err := LoadBaseQodanaYamlNode(filepath.Join(dir, "qodana.yaml"))
+	assert.NoError(t, err)
+
+	effective := filepath.Join(dir, "effective.qodana.yaml")
+	writeTestFiles(t, dir, map[string]string{"effective.qodana.yaml": "version: \"1.0\"\nexclude:\n  - name: JavaDoc\n"})
+	assert.NoError(t, ExtendQodanaYamlFile(effective, base))
+	assert.NoError(t, ExtendQodanaYamlFile(filepath.Join(dir, "qodana.yaml"), base))
+
+	for _, path := range []string{effective, filepath.Join(dir, "qodana.yaml")} {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(
+			t, `profile:
+  name: qodana.starter
+exclude:
+  - name: All
+  - name: JavaDoc
+version: "1.0"
+`, string(data),
+		)
+	}
+}
+
+func TestExtendQodanaYamlFileDocuments(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(
+		t, dir, map[string]string{
+			"base.yaml": "profile:\n  name: qodana.starter\n",
+			"qodana.yaml": `extends: base.yaml
+linter: jetbrains/qodana-jvm
+---
+linter: jetbrains/qodana-js
+profile:
+  name: qodana.recommended
+`,
+		},
+	)
+	path := filepath.Join(dir, "qodana.yaml")
+	base, err := LoadBaseQodanaYamlNode(path)
+	assert.NoError(t, err)
+	assert.NoError(t, ExtendQodanaYamlFile(path, base))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(
+		t, `profile:
+  name: qodana.starter
+linter: jetbrains/qodana-jvm
+---
+profile:
+  name: qodana.recommended
+linter: jetbrains/qodana-js
+`, string(data),
+	)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// allInspections is the exclude name matching every inspection.
+const allInspections = "All"
+
+// IgnoreRulesToExcludes returns the exclude entries making the linter skip the problems matched by the rules.
+// Like in .gitignore, a pattern without a slash matches the file name in any directory.
+func IgnoreRulesToExcludes(rules []IgnoreRule) []Clude {
+	excludes := make([]Clude, 0)
+	for _, rule := range rules {
+		paths := make([]string, 0, len(rule.Paths))
+		for _, pattern := range rule.Paths {
+			pattern = strings.Trim(strings.TrimPrefix(pattern, "./"), "/")
+			if pattern == "" {
+				continue
+			}
+			if !strings.Contains(pattern, "/") {
+				pattern = "**/" + pattern
+			}
+			paths = append(paths, pattern)
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		names := rule.Inspections
+		if len(names) == 0 {
+			names = []string{allInspections}
+		}
+		for _, name := range names {
+			excludes = append(excludes, Clude{Name: name, Paths: paths})
+		}
+	}
+	return excludes
+}
+
+// ExcludeIgnoredInFile rewrites the qodana.yaml file at path with the ignore rules of every document added to its
+// exclude entries, so the linter doesn't report the ignored problems, print them or upload them to Qodana Cloud.
+// The file is not touched if it has no ignore rules.
+func ExcludeIgnoredInFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	changed := false
+	for {
+		var document yaml.Node
+		err = decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(document.Content) > 0 && resolveYamlAlias(document.Content[0]).Kind == yaml.MappingNode {
+			root := resolveYamlAlias(document.Content[0])
+			excludes, err := ignoredExcludesNode(root)
+			if err != nil {
+				return err
+			}
+			if excludes != nil {
+				document.Content[0] = MergeLayers(root, excludes)
+				changed = true
+			}
+		}
+		if err = encoder.Encode(&document); err != nil {
+			return err
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err = encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0o600)
+}
+
+// ignoredExcludesNode returns the mapping with the exclude entries of the ignore rules of root, nil if it has none.
+func ignoredExcludesNode(root *yaml.Node) (*yaml.Node, error) {
+	i := yamlMappingKeyIndex(root, "ignore")
+	if i < 0 {
+		return nil, nil
+	}
+	var rules []IgnoreRule
+	if err := root.Content[i+1].Decode(&rules); err != nil {
+		return nil, err
+	}
+	excludes := IgnoreRulesToExcludes(rules)
+	if len(excludes) == 0 {
+		return nil, nil
+	}
+	var node yaml.Node
+	if err := node.Encode(excludes); err != nil {
+		return nil, err
+	}
+	return &yaml.Node{
+		Kind:    yaml.MappingNode,
+		Tag:     "!!map",
+		Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "exclude"}, &node},
+	}, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreRulesToExcludes(t *testing.T) {
+	excludes := IgnoreRulesToExcludes(
+		[]IgnoreRule{
+			{Paths: []string{"vendor", "./gen/**/*.java", "**/*.pb.go"}},
+			{Paths: []string{"src/legacy/"}, Inspections: []string{"UnusedDeclaration", "JavaDoc"}},
+			{Paths: []string{"/"}},
+		},
+	)
+	assert.Equal(
+		t,
+		[]Clude{
+			{Name: "All", Paths: []string{"**/vendor", "gen/**/*.java", "**/*.pb.go"}},
+			{Name: "UnusedDeclaration", Paths: []string{"src/legacy"}},
+			{Name: "JavaDoc", Paths: []string{"src/legacy"}},
+		},
+		excludes,
+	)
+}
+
+func TestExcludeIgnoredInFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qodana.yaml")
+	assert.NoError(
+		t, os.WriteFile(
+			path, []byte(`version: "1.0"
+exclude:
+  - name: JavaDoc
+ignore:
+  - paths: [src/legacy]
+    inspections: [UnusedDeclaration]
+`), 0o600,
+		),
+	)
+	assert.NoError(t, ExcludeIgnoredInFile(path))
+	yaml := LoadQodanaYamlByFullPath(path)
+	assert.Equal(
+		t,
+		[]Clude{{Name: "JavaDoc"}, {Name: "UnusedDeclaration", Paths: []string{"src/legacy"}}},
+		yaml.Excludes,
+	)
+	assert.Len(t, yaml.Ignore, 1)
+
+	unchanged := []byte("version: \"1.0\"\nprofile: {name: qodana.recommended}\n")
+	assert.NoError(t, os.WriteFile(path, unchanged, 0o600))
+	assert.NoError(t, ExcludeIgnoredInFile(path))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, unchanged, data)
+}
@@ -0,0 +1,345 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersions are the qodana.yaml versions with an embedded schema, the last one is used for the files without
+// a version.
+var SchemaVersions = []string{"1.0"}
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+const (
+	SchemaError   = "error"
+	SchemaWarning = "warning"
+)
+
+// SchemaProblem is a problem of qodana.yaml found by ValidateQodanaYaml.
+type SchemaProblem struct {
+	// Severity is SchemaError for unknown keys and wrong values, SchemaWarning for deprecated keys
+	Severity string `json:"severity"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	// Path is the location of the value in the document, e.g. include[0].name
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (p SchemaProblem) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", p.Line, p.Column, p.Message)
+}
+
+// jsonSchema is the subset of JSON Schema draft-07 the qodana.yaml schemas use.
+type jsonSchema struct {
+	Ref                string                 `json:"$ref"`
+	Type               string                 `json:"type"`
+	Properties         map[string]*jsonSchema `json:"properties"`
+	PatternProperties  map[string]*jsonSchema `json:"patternProperties"`
+	Required           []string               `json:"required"`
+	Items              *jsonSchema            `json:"items"`
+	Enum               []string               `json:"enum"`
+	Deprecated         bool                   `json:"deprecated"`
+	DeprecationMessage string                 `json:"deprecationMessage"`
+	Definitions        map[string]*jsonSchema `json:"definitions"`
+
+	// AdditionalProperties is the schema of the keys not listed in Properties, any value is accepted if nil
+	AdditionalProperties *jsonSchema `json:"-"`
+	// Closed is set by "additionalProperties": false, the keys not listed in Properties are not allowed
+	Closed bool `json:"-"`
+}
+
+func (s *jsonSchema) UnmarshalJSON(data []byte) error {
+	type plain jsonSchema
+	var schema struct {
+		plain
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return err
+	}
+	*s = jsonSchema(schema.plain)
+	switch strings.TrimSpace(string(schema.AdditionalProperties)) {
+	case "", "true":
+	case "false":
+		s.Closed = true
+	default:
+		s.AdditionalProperties = &jsonSchema{}
+		return json.Unmarshal(schema.AdditionalProperties, s.AdditionalProperties)
+	}
+	return nil
+}
+
+// loadSchema returns the embedded schema of the qodana.yaml version.
+func loadSchema(version string) (*jsonSchema, error) {
+	data, err := schemas.ReadFile(fmt.Sprintf("schemas/qodana-yaml-%s.json", version))
+	if err != nil {
+		return nil, err
+	}
+	schema := &jsonSchema{}
+	if err = json.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("invalid schema of qodana.yaml %s: %w", version, err)
+	}
+	return schema, nil
+}
+
+// ValidateQodanaYaml validates every document of qodana.yaml against the embedded schema of its version: unknown keys
+// and values of a wrong type are reported as errors, deprecated keys as warnings. The error is returned if the content
+// is not a valid YAML.
+func ValidateQodanaYaml(data []byte) ([]SchemaProblem, error) {
+	documents, err := decodeYamlDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+	var problems []SchemaProblem
+	for _, document := range documents {
+		version := SchemaVersions[len(SchemaVersions)-1]
+		if i := yamlMappingKeyIndex(document, "version"); i >= 0 {
+			node := resolveYamlAlias(document.Content[i+1])
+			if slices.Contains(SchemaVersions, node.Value) {
+				version = node.Value
+			} else {
+				problems = append(problems, SchemaProblem{
+					Severity: SchemaError,
+					Line:     node.Line,
+					Column:   node.Column,
+					Path:     "version",
+					Message: fmt.Sprintf(
+						"unsupported version %q, supported versions: %s",
+						node.Value,
+						strings.Join(SchemaVersions, ", "),
+					),
+				})
+			}
+		}
+		schema, err := loadSchema(version)
+		if err != nil {
+			return nil, err
+		}
+		v := &schemaValidator{root: schema}
+		v.validate(document, schema, "")
+		problems = append(problems, v.problems...)
+	}
+	return problems, nil
+}
+
+type schemaValidator struct {
+	root     *jsonSchema
+	problems []SchemaProblem
+}
+
+func (v *schemaValidator) report(severity string, node *yaml.Node, path string, message string, a ...any) {
+	v.problems = append(v.problems, SchemaProblem{
+		Severity: severity,
+		Line:     node.Line,
+		Column:   node.Column,
+		Path:     path,
+		Message:  fmt.Sprintf(message, a...),
+	})
+}
+
+// resolve follows the $ref of the schema to #/definitions.
+func (v *schemaValidator) resolve(schema *jsonSchema) *jsonSchema {
+	for schema.Ref != "" {
+		definition, ok := v.root.Definitions[strings.TrimPrefix(schema.Ref, "#/definitions/")]
+		if !ok {
+			return &jsonSchema{}
+		}
+		schema = definition
+	}
+	return schema
+}
+
+func (v *schemaValidator) validate(node *yaml.Node, schema *jsonSchema, path string) {
+	node = resolveYamlAlias(node)
+	schema = v.resolve(schema)
+	// an empty value is the same as an absent one
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if schema.Type != "" && !matchesSchemaType(node, schema.Type) {
+		v.report(SchemaError, node, path, "%s must be %s, got %s", displayPath(path), schemaTypeName(schema.Type), yamlKindName(node))
+		return
+	}
+	if len(schema.Enum) > 0 && node.Kind == yaml.ScalarNode && !slices.Contains(schema.Enum, node.Value) {
+		v.report(
+			SchemaError, node, path, "%s must be one of %s, got %q",
+			displayPath(path), strings.Join(schema.Enum, ", "), node.Value,
+		)
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		v.validateMapping(node, schema, path)
+	case yaml.SequenceNode:
+		if schema.Items == nil {
+			return
+		}
+		for i, item := range node.Content {
+			v.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (v *schemaValidator) validateMapping(node *yaml.Node, schema *jsonSchema, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		// the merged keys come from an anchor defined elsewhere, they are not validated here
+		if key.Value == "<<" {
+			continue
+		}
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+		property, ok := schema.Properties[key.Value]
+		if !ok {
+			property, ok = matchPatternProperty(key.Value, schema.PatternProperties)
+		}
+		switch {
+		case ok:
+			property = v.resolve(property)
+			if property.Deprecated {
+				message := fmt.Sprintf("%s is deprecated", keyPath)
+				if property.DeprecationMessage != "" {
+					message += ", " + property.DeprecationMessage
+				}
+				v.report(SchemaWarning, key, keyPath, "%s", message)
+			}
+			v.validate(value, property, keyPath)
+		case schema.AdditionalProperties != nil:
+			v.validate(value, schema.AdditionalProperties, keyPath)
+		case schema.Closed:
+			message := fmt.Sprintf("unknown key %s", keyPath)
+			if suggestion := suggestKey(key.Value, schema.Properties); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %s?", suggestion)
+			}
+			v.report(SchemaError, key, keyPath, "%s", message)
+		}
+	}
+	for _, required := range schema.Required {
+		if yamlMappingKeyIndex(node, required) < 0 {
+			v.report(SchemaError, node, path, "%s is missing the required key %s", displayPath(path), required)
+		}
+	}
+}
+
+func matchPatternProperty(key string, patternProperties map[string]*jsonSchema) (*jsonSchema, bool) {
+	for pattern, property := range patternProperties {
+		if matched, _ := regexp.MatchString(pattern, key); matched {
+			return property, true
+		}
+	}
+	return nil, false
+}
+
+func matchesSchemaType(node *yaml.Node, schemaType string) bool {
+	switch schemaType {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		// qodana.yaml is decoded to strings, `version: 1.0` or `linter: 2025` are fine
+		return node.Kind == yaml.ScalarNode
+	case "integer":
+		var value int
+		return node.Kind == yaml.ScalarNode && node.Decode(&value) == nil
+	case "number":
+		var value float64
+		return node.Kind == yaml.ScalarNode && node.Decode(&value) == nil
+	case "boolean":
+		var value bool
+		return node.Kind == yaml.ScalarNode && node.Decode(&value) == nil
+	}
+	return true
+}
+
+func schemaTypeName(schemaType string) string {
+	switch schemaType {
+	case "object":
+		return "a mapping"
+	case "array":
+		return "a list"
+	case "integer":
+		return "an integer"
+	}
+	return "a " + schemaType
+}
+
+func yamlKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "the document"
+	}
+	return path
+}
+
+// suggestKey returns the known key closest to the unknown one, if it is probably a typo.
+func suggestKey(key string, properties map[string]*jsonSchema) string {
+	best, bestDistance := "", 3
+	for name := range properties {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+		distance := editDistance(strings.ToLower(key), strings.ToLower(name))
+		if distance < bestDistance || (distance == bestDistance && best != "" && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateQodanaYaml(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []SchemaProblem
+	}{
+		{
+			name: "valid",
+			content: `version: "1.0"
+x-paths: &generated
+  - src/gen
+linter: jetbrains/qodana-jvm:latest
+preset: quick
+withinDocker: false
+failThreshold: 10
+exclude:
+  - name: All
+    paths: *generated
+properties:
+  idea.max.intellisense.filesize: 5000
+failureConditions:
+  severityThresholds:
+    critical: 0
+report:
+  title: ACME code quality
+  logo: .qodana/logo.svg
+  severityColors:
+    critical: "#b71c1c"
+  footerLinks:
+    - text: Coding guidelines
+      url: https://example.com/guidelines
+notifications:
+  when: newProblems
+  severity: high
+  slack: https://hooks.slack.com/services/T000/B000/XXXX
+  email:
+    smtp: smtp.example.com:587
+    from: qodana@example.com
+    to: [team@example.com]
+`,
+		},
+		{
+			name:    "unknown keys",
+			content: "version: \"1.0\"\nexlude:\n  - name: All\nprofile:\n  Name: qodana.starter\n",
+			expected: []SchemaProblem{
+				{
+					Severity: SchemaError,
+					Line:     2,
+					Column:   1,
+					Path:     "exlude",
+					Message:  "unknown key exlude, did you mean exclude?",
+				},
+				{
+					Severity: SchemaError,
+					Line:     5,
+					Column:   3,
+					Path:     "profile.Name",
+					Message:  "unknown key profile.Name, did you mean name?",
+				},
+			},
+		},
+		{
+			name:    "wrong types",
+			content: "failThreshold: many\ninclude:\n  - paths: [src]\nplugins: id\n",
+			expected: []SchemaProblem{
+				{
+					Severity: SchemaError,
+					Line:     1,
+					Column:   16,
+					Path:     "failThreshold",
+					Message:  `failThreshold must be an integer, got "many"`,
+				},
+				{
+					Severity: SchemaError,
+					Line:     3,
+					Column:   5,
+					Path:     "include[0]",
+					Message:  "include[0] is missing the required key name",
+				},
+				{
+					Severity: SchemaError,
+					Line:     4,
+					Column:   10,
+					Path:     "plugins",
+					Message:  `plugins must be a list, got "id"`,
+				},
+			},
+		},
+		{
+			name:    "deprecated keys in a linter document",
+			content: "version: \"1.0\"\n---\nide: QDJVMC\nfixesStrategy: apply\n",
+			expected: []SchemaProblem{
+				{
+					Severity: SchemaWarning,
+					Line:     3,
+					Column:   1,
+					Path:     "ide",
+					Message:  "ide is deprecated, use linter with withinDocker: false instead",
+				},
+				{
+					Severity: SchemaWarning,
+					Line:     4,
+					Column:   1,
+					Path:     "fixesStrategy",
+					Message:  "fixesStrategy is deprecated, use the --apply-fixes or --cleanup options instead",
+				},
+			},
+		},
+		{
+			name:    "unsupported version",
+			content: "version: \"2.0\"\n",
+			expected: []SchemaProblem{
+				{
+					Severity: SchemaError,
+					Line:     1,
+					Column:   10,
+					Path:     "version",
+					Message:  `unsupported version "2.0", supported versions: 1.0`,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := ValidateQodanaYaml([]byte(tt.content))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, problems)
+		})
+	}
+}
+
+func TestValidateQodanaYamlSyntaxError(t *testing.T) {
+	_, err := ValidateQodanaYaml([]byte("linter: [qodana-jvm\n"))
+	assert.Error(t, err)
+}
@@ -19,9 +19,13 @@ package qdyaml
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -49,21 +53,45 @@ type QodanaYaml struct {
 	// Include property to enable the wanted checks.
 	Includes []Clude `yaml:"include,omitempty"`
 
+	// Ignore contains the rules suppressing the problems of the inspections on the paths in the report.
+	Ignore []IgnoreRule `yaml:"ignore,omitempty"`
+
 	// Linter to run.
 	Linter string `yaml:"linter,omitempty"`
 
+	// FallbackLinter to run if Linter fails to build the project model.
+	FallbackLinter string `yaml:"fallbackLinter,omitempty"`
+
+	// Preset is the scan preset (quick, full or security) applied when --preset is not given.
+	Preset string `yaml:"preset,omitempty"`
+
 	// Image to use.
 	Image string `yaml:"image,omitempty"`
 
 	//WithinDocker defines if analysis should be performed in a container.
 	WithinDocker string `yaml:"withinDocker,omitempty"`
 
+	// Mounts are the tmpfs mounts and the named volumes added to the Qodana container.
+	Mounts []Mount `yaml:"mounts,omitempty"`
+
+	// RemoteCache is the bucket the cache is restored from before the analysis and saved to after it.
+	RemoteCache *RemoteCache `yaml:"remoteCache,omitempty"`
+
 	// IDE to run.
 	Ide string `yaml:"ide,omitempty"`
 
+	// Projects are the sub-projects of a monorepo, each analyzed by its linter and reported together.
+	Projects []Project `yaml:"projects,omitempty"`
+
+	// Prepare contains the steps restoring or installing the project dependencies, run before bootstrap.
+	Prepare []PrepareStep `yaml:"prepare,omitempty"`
+
 	// Bootstrap contains a command to run in the container before the analysis starts.
 	Bootstrap string `yaml:"bootstrap,omitempty"`
 
+	// BootstrapRetry configures the retries of the bootstrap command.
+	BootstrapRetry Retry `yaml:"bootstrapRetry,omitempty"`
+
 	// Properties property to override IDE properties.
 	Properties map[string]string `yaml:"properties,omitempty"`
 
@@ -115,6 +143,9 @@ type QodanaYaml struct {
 	// FailureConditions configures individual failure conditions. Absent properties will not be checked
 	FailureConditions FailureConditions `yaml:"failureConditions,omitempty"`
 
+	// EnforceAfter is the date (YYYY-MM-DD) from which the failure conditions fail the run, before it they are only reported.
+	EnforceAfter string `yaml:"enforceAfter,omitempty"`
+
 	// DependencySbomExclude property to define which dependencies to exclude from the generated SBOM report
 	DependencySbomExclude []DependencyIgnore `yaml:"dependencySbomExclude,omitempty"`
 
@@ -129,6 +160,12 @@ type QodanaYaml struct {
 
 	// RaiseLicenseProblems property to show license problems like other inspections.
 	RaiseLicenseProblems bool `yaml:"raiseLicenseProblems,omitempty"`
+
+	// Report customizes the HTML report assembled by the CLI: the title, the logo, the severity colors and the footer.
+	Report *Report `yaml:"report,omitempty"`
+
+	// Notifications are the summary messages posted by the CLI when the analysis finishes.
+	Notifications *Notifications `yaml:"notifications,omitempty"`
 }
 
 // WriteConfig writes QodanaYaml to the given path.
@@ -192,6 +229,179 @@ type Clude struct {
 	Paths []string `yaml:"paths,omitempty"`
 }
 
+// IgnoreRule suppresses the problems found on the paths, by the inspections if any are listed. The IDE linters get
+// the rules as exclude entries, the problems of the third-party linters are kept in the report as suppressed.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type IgnoreRule struct {
+	// Paths are the glob patterns relative to the project root, ** matches any number of directories.
+	Paths []string `yaml:"paths"`
+
+	// Inspections are the IDs of the inspections whose problems are suppressed, all inspections if empty.
+	Inspections []string `yaml:"inspections,omitempty"`
+
+	// Reason is the justification of the suppression written to the report.
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// Mount is a tmpfs mount or a named volume of the Qodana container, e.g. for the IDE system directory to keep it off
+// a slow network filesystem and out of the host.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type Mount struct {
+	// Type is tmpfs or volume.
+	Type string `yaml:"type"`
+
+	// Source is the name of the volume, created by Docker if it doesn't exist. Not used for tmpfs.
+	Source string `yaml:"source,omitempty"`
+
+	// Target is the absolute path in the container.
+	Target string `yaml:"target"`
+
+	// Size is the size limit of tmpfs, e.g. 512m or 2g, unlimited if empty.
+	Size string `yaml:"size,omitempty"`
+
+	// ReadOnly mounts the volume read-only.
+	ReadOnly bool `yaml:"readOnly,omitempty"`
+}
+
+// RemoteCache is the storage keeping the caches of the CI runs by the linter, the project and the branch, so every
+// pipeline doesn't need its own cache upload scripts.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type RemoteCache struct {
+	// Url is s3://<bucket>/<prefix>, gs://<bucket>/<prefix> or https://<account>.blob.core.windows.net/<container>/<prefix>.
+	Url string `yaml:"url"`
+
+	// Region of the S3 bucket, AWS_REGION by default.
+	Region string `yaml:"region,omitempty"`
+
+	// Endpoint of an S3-compatible storage, e.g. MinIO.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// ReadOnly restores the cache without saving it, e.g. in the pull request pipelines.
+	ReadOnly bool `yaml:"readOnly,omitempty"`
+
+	// FallbackBranches are restored from when there is no cache of the branch, main and master if empty.
+	FallbackBranches []string `yaml:"fallbackBranches,omitempty"`
+}
+
+// Report is the branding of the HTML report, applied when the CLI saves the report to the report directory.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type Report struct {
+	// Title replaces the title of the report page.
+	Title string `yaml:"title,omitempty"`
+
+	// Logo is the image shown in the header of the report, relative to the project directory.
+	Logo string `yaml:"logo,omitempty"`
+
+	// SeverityColors are the CSS colors of the severities: critical, high, moderate, low and info.
+	SeverityColors map[string]string `yaml:"severityColors,omitempty"`
+
+	// FooterLinks are the links shown in the footer of the report, e.g. the coding guidelines of the team.
+	FooterLinks []ReportLink `yaml:"footerLinks,omitempty"`
+}
+
+// ReportLink is a link in the footer of the HTML report.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type ReportLink struct {
+	Text string `yaml:"text"`
+	Url  string `yaml:"url"`
+}
+
+// Notifications configures the summary of the analysis posted to the webhooks, Slack, Microsoft Teams and email.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type Notifications struct {
+	// When the summary is posted: always (default), failure when the quality gate failed, or newProblems when the new
+	// problems of Severity or higher exceed Threshold.
+	When string `yaml:"when,omitempty"`
+
+	// Severity is the lowest severity of the new problems counted for the threshold: critical, high, moderate, low or info.
+	Severity string `yaml:"severity,omitempty"`
+
+	// Threshold is the number of the new problems not notified about with when: newProblems.
+	Threshold int `yaml:"threshold,omitempty"`
+
+	// ReportUrl is the link to the report published by the pipeline, the Qodana Cloud report is linked if it's empty.
+	ReportUrl string `yaml:"reportUrl,omitempty"`
+
+	// Webhooks receive the analysis result as JSON, like the --webhook URLs.
+	Webhooks []string `yaml:"webhooks,omitempty"`
+
+	// Slack is the incoming webhook URL of a Slack channel.
+	Slack string `yaml:"slack,omitempty"`
+
+	// Teams is the incoming webhook (Workflows) URL of a Microsoft Teams channel.
+	Teams string `yaml:"teams,omitempty"`
+
+	// Email sends the summary to the recipients with the SMTP server.
+	Email *EmailNotification `yaml:"email,omitempty"`
+}
+
+// EmailNotification is the SMTP server and the recipients of the summary email.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type EmailNotification struct {
+	// Smtp is the host:port of the SMTP server, STARTTLS is used if the server supports it.
+	Smtp string   `yaml:"smtp"`
+	From string   `yaml:"from"`
+	To   []string `yaml:"to"`
+
+	// Username to authenticate with, the password is read from QODANA_SMTP_PASSWORD.
+	Username string `yaml:"username,omitempty"`
+}
+
+// Project is a sub-project of a monorepo analyzed with `qodana scan` run in the root directory.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type Project struct {
+	// Path is the project directory relative to the root directory.
+	Path string `yaml:"path"`
+
+	// Linter to run for the project, the linter from the project qodana.yaml is used if empty.
+	Linter string `yaml:"linter,omitempty"`
+}
+
+// PrepareStep is a command run before the analysis, e.g. dotnet restore or npm ci.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type PrepareStep struct {
+	// Name of the step in the output and the log file name, prepare-<position> if empty.
+	Name string `yaml:"name,omitempty"`
+
+	// Run is the shell command of the step, run in the project directory.
+	Run string `yaml:"run"`
+
+	Retry `yaml:",inline"`
+}
+
+// Retry configures how a prepare step is retried, transient network failures of package registries
+// shouldn't fail the whole analysis.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type Retry struct {
+	// Attempts is the maximum number of runs of the step, 1 if not set.
+	Attempts int `yaml:"attempts,omitempty"`
+
+	// Backoff is the delay before the second attempt (e.g. 10s), doubled before each next one, 10s if not set.
+	Backoff string `yaml:"backoff,omitempty"`
+
+	// Timeout limits the duration of a single attempt (e.g. 10m), not limited if not set.
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// BootstrapSteps returns the steps run before the analysis: the prepare steps followed by the bootstrap command.
+func BootstrapSteps(prepare []PrepareStep, bootstrap string, bootstrapRetry Retry) []PrepareStep {
+	steps := slices.Clone(prepare)
+	if bootstrap != "" {
+		steps = append(steps, PrepareStep{Name: "bootstrap", Run: bootstrap, Retry: bootstrapRetry})
+	}
+	return steps
+}
+
 // Plugin to be installed during the Qodana run.
 //
 //goland:noinspection GoUnnecessarilyExportedIdentifiers
@@ -319,6 +529,40 @@ type SeverityThresholds struct {
 	Moderate *int `yaml:"moderate,omitempty"`
 }
 
+// ParseSeverityThresholds parses the thresholds given on the command line as comma-separated severity=count pairs,
+// e.g. critical=0,high=5. The severities are the same as in failureConditions.severityThresholds.
+func ParseSeverityThresholds(value string) (SeverityThresholds, error) {
+	thresholds := SeverityThresholds{}
+	fields := map[string]**int{
+		"any":      &thresholds.Any,
+		"critical": &thresholds.Critical,
+		"high":     &thresholds.High,
+		"moderate": &thresholds.Moderate,
+		"low":      &thresholds.Low,
+		"info":     &thresholds.Info,
+	}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		severity, count, ok := strings.Cut(pair, "=")
+		field, known := fields[strings.ToLower(strings.TrimSpace(severity))]
+		if !ok || !known {
+			return SeverityThresholds{}, fmt.Errorf(
+				"invalid threshold %q, expected severity=count with one of the severities: any, critical, high, moderate, low, info",
+				pair,
+			)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 0 {
+			return SeverityThresholds{}, fmt.Errorf("invalid threshold %q, the count must be a non-negative number", pair)
+		}
+		*field = &n
+	}
+	return thresholds, nil
+}
+
 // CoverageThresholds Configures minimum thresholds for test coverage metrics. Absent properties are not checked
 //
 //goland:noinspection GoUnnecessarilyExportedIdentifiers
@@ -380,22 +624,182 @@ func TestOnlyLoadLocalNotEffectiveQodanaYaml(project string, yamlPathInProject s
 }
 
 func LoadQodanaYamlByFullPath(fullPath string) QodanaYaml {
+	return LoadQodanaYamlForLinter(fullPath)
+}
+
+// LoadQodanaYamlForLinter loads qodana.yaml, selecting the document of a multi-document file specific to the linter
+// with one of the given names (linter name, product code or image), merged on top of the configuration it extends.
+// See ParseQodanaYaml and LoadQodanaYamlNode.
+func LoadQodanaYamlForLinter(fullPath string, names ...string) QodanaYaml {
 	if fullPath == "" {
 		return QodanaYaml{}
 	}
-	q := &QodanaYaml{}
 	if _, err := os.Stat(fullPath); errors.Is(err, os.ErrNotExist) {
-		return *q
+		return QodanaYaml{}
+	}
+	q := QodanaYaml{}
+	node, err := LoadQodanaYamlNode(fullPath, names...)
+	if err == nil && node != nil {
+		err = node.Decode(&q)
 	}
-	yamlFile, err := os.ReadFile(fullPath)
 	if err != nil {
-		log.Printf("yamlFile.Get err   #%v ", err)
+		log.Fatalf("Failed to parse %s: %v", fullPath, err)
+	}
+	return q
+}
+
+// ParseQodanaYaml parses the content of qodana.yaml, which can consist of several documents separated by `---`.
+// The documents without `linter`, `ide` or `image` are shared and applied in order, the document specific to
+// the linter with one of the given names is applied on top of them. Without names, or if there is only one
+// linter-specific document, the first linter-specific document is used. Mappings are merged key by key,
+// other values are replaced. Anchors and aliases are resolved within a document. `${VAR}` and `${VAR:-default}`
+// in the values are replaced with the environment variables, see ExpandEnv.
+func ParseQodanaYaml(data []byte, names ...string) (QodanaYaml, error) {
+	q := QodanaYaml{}
+	merged, err := ParseQodanaYamlNode(data, names...)
+	if err != nil || merged == nil {
+		return q, err
+	}
+	err = merged.Decode(&q)
+	return q, err
+}
+
+// ParseQodanaYamlNode returns the mapping ParseQodanaYaml decodes: the shared documents merged with the document
+// specific to the linter, nil if there are no documents. The values are the nodes of the documents, with their lines.
+func ParseQodanaYamlNode(data []byte, names ...string) (*yaml.Node, error) {
+	documents, err := decodeYamlDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []*yaml.Node
+	var specific []*yaml.Node
+	var selected *yaml.Node
+	for _, document := range documents {
+		var target struct {
+			Linter string `yaml:"linter"`
+			Ide    string `yaml:"ide"`
+			Image  string `yaml:"image"`
+		}
+		if err = document.Decode(&target); err != nil {
+			return nil, err
+		}
+		if target.Linter == "" && target.Ide == "" && target.Image == "" {
+			shared = append(shared, document)
+			continue
+		}
+		specific = append(specific, document)
+		if selected == nil && (slices.Contains(names, target.Linter) ||
+			slices.Contains(names, target.Ide) ||
+			slices.Contains(names, target.Image)) {
+			selected = document
+		}
+	}
+	if selected == nil && (len(names) == 0 || len(specific) == 1) && len(specific) > 0 {
+		selected = specific[0]
+	}
+	if selected != nil {
+		shared = append(shared, selected)
+	}
+	if len(shared) == 0 {
+		return nil, nil
+	}
+
+	merged := shared[0]
+	for _, document := range shared[1:] {
+		merged = mergeYamlMappings(merged, document)
 	}
-	err = yaml.Unmarshal(yamlFile, q)
+	return merged, nil
+}
+
+// SelectQodanaYamlDocument returns the content of a multi-document qodana.yaml as a single document: the shared
+// documents merged with the document specific to the linter with one of the given names, see ParseQodanaYamlNode.
+// It returns false if the content has at most one document, then it can be used as it is.
+func SelectQodanaYamlDocument(data []byte, names ...string) ([]byte, bool, error) {
+	documents, err := decodeYamlDocuments(data)
+	if err != nil || len(documents) <= 1 {
+		return nil, false, err
+	}
+	merged, err := ParseQodanaYamlNode(data, names...)
+	if err != nil || merged == nil {
+		return nil, false, err
+	}
+	// decoded to resolve the aliases, their anchors can be replaced by the merge
+	var values map[string]any
+	if err = merged.Decode(&values); err != nil {
+		return nil, false, err
+	}
+	selected, err := yaml.Marshal(values)
 	if err != nil {
-		log.Fatalf("Unmarshal: %v", err)
+		return nil, false, err
+	}
+	return selected, true, nil
+}
+
+// decodeYamlDocuments returns the top-level mappings of all non-empty documents, with the environment variables
+// expanded.
+func decodeYamlDocuments(data []byte) ([]*yaml.Node, error) {
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(document.Content) == 0 {
+			continue
+		}
+		root := resolveYamlAlias(document.Content[0])
+		if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+			continue
+		}
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: a qodana.yaml document must be a mapping", root.Line)
+		}
+		expandYamlEnv(root)
+		documents = append(documents, root)
+	}
+}
+
+// mergeYamlMappings returns a copy of dst with the keys of src set on top, nested mappings are merged recursively.
+func mergeYamlMappings(dst *yaml.Node, src *yaml.Node) *yaml.Node {
+	merged := *dst
+	merged.Content = slices.Clone(dst.Content)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		j := yamlMappingKeyIndex(&merged, key.Value)
+		if j < 0 {
+			merged.Content = append(merged.Content, key, value)
+			continue
+		}
+		existing, replacement := resolveYamlAlias(merged.Content[j+1]), resolveYamlAlias(value)
+		if key.Value != "<<" && existing.Kind == yaml.MappingNode && replacement.Kind == yaml.MappingNode {
+			merged.Content[j+1] = mergeYamlMappings(existing, replacement)
+		} else {
+			merged.Content[j+1] = value
+		}
+	}
+	return &merged
+}
+
+func yamlMappingKeyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func resolveYamlAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
 	}
-	return *q
+	return node
 }
 
 // Sort makes QodanaYaml prettier.
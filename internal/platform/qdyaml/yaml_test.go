@@ -304,3 +304,123 @@ func TestSetQodanaDotNet(t *testing.T) {
 	assert.Equal(t, "test.sln", loaded.DotNet.Solution)
 	assert.Equal(t, "test.csproj", loaded.DotNet.Project)
 }
+
+func TestParseQodanaYamlAnchors(t *testing.T) {
+	q, err := ParseQodanaYaml(
+		[]byte(`version: "1.0"
+x-paths: &generated
+  - src/gen
+  - build
+exclude:
+  - name: All
+    paths: *generated
+  - name: JavaDoc
+    paths: *generated
+properties: &props
+  idea.max.intellisense.filesize: "5000"
+`),
+	)
+	assert.NoError(t, err)
+	assert.Equal(
+		t, []Clude{
+			{Name: "All", Paths: []string{"src/gen", "build"}},
+			{Name: "JavaDoc", Paths: []string{"src/gen", "build"}},
+		}, q.Excludes,
+	)
+	assert.Equal(t, map[string]string{"idea.max.intellisense.filesize": "5000"}, q.Properties)
+}
+
+func TestParseQodanaYamlPrepare(t *testing.T) {
+	q, err := ParseQodanaYaml(
+		[]byte(`version: "1.0"
+prepare:
+  - name: restore
+    run: dotnet restore
+    attempts: 3
+    backoff: 30s
+    timeout: 10m
+  - run: npm ci
+bootstrap: ./generate.sh
+bootstrapRetry:
+  attempts: 2
+`),
+	)
+	assert.NoError(t, err)
+	assert.Equal(
+		t, []PrepareStep{
+			{Name: "restore", Run: "dotnet restore", Retry: Retry{Attempts: 3, Backoff: "30s", Timeout: "10m"}},
+			{Run: "npm ci"},
+			{Name: "bootstrap", Run: "./generate.sh", Retry: Retry{Attempts: 2}},
+		}, BootstrapSteps(q.Prepare, q.Bootstrap, q.BootstrapRetry),
+	)
+	assert.Empty(t, BootstrapSteps(nil, "", Retry{Attempts: 2}))
+}
+
+func TestParseQodanaYamlMultiDocument(t *testing.T) {
+	content := []byte(`version: "1.0"
+profile:
+  name: qodana.recommended
+properties:
+  shared: "true"
+---
+linter: qodana-jvm
+bootstrap: ./gradlew assemble
+properties:
+  jvm: "true"
+---
+linter: qodana-js
+profile:
+  name: qodana.starter
+`)
+
+	jvm, err := ParseQodanaYaml(content, "qodana-jvm", "QDJVM")
+	assert.NoError(t, err)
+	assert.Equal(t, "qodana-jvm", jvm.Linter)
+	assert.Equal(t, "./gradlew assemble", jvm.Bootstrap)
+	assert.Equal(t, "qodana.recommended", jvm.Profile.Name)
+	assert.Equal(t, map[string]string{"shared": "true", "jvm": "true"}, jvm.Properties)
+
+	js, err := ParseQodanaYaml(content, "qodana-js")
+	assert.NoError(t, err)
+	assert.Equal(t, "qodana-js", js.Linter)
+	assert.Equal(t, "qodana.starter", js.Profile.Name)
+	assert.Equal(t, "1.0", js.Version)
+	assert.Empty(t, js.Bootstrap)
+
+	first, err := ParseQodanaYaml(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "qodana-jvm", first.Linter)
+
+	other, err := ParseQodanaYaml(content, "qodana-go")
+	assert.NoError(t, err)
+	assert.Empty(t, other.Linter)
+	assert.Equal(t, "qodana.recommended", other.Profile.Name)
+}
+
+func TestParseQodanaYamlErrors(t *testing.T) {
+	_, err := ParseQodanaYaml([]byte("version: \"1.0\"\n---\nlinter: qodana-jvm\nfailThreshold: many\n"))
+	assert.ErrorContains(t, err, "line 4")
+
+	_, err = ParseQodanaYaml([]byte("version: \"1.0\"\n---\n- linter: qodana-jvm\n"))
+	assert.ErrorContains(t, err, "line 3")
+
+	q, err := ParseQodanaYaml([]byte("---\n# only a comment\n---\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, QodanaYaml{}, q)
+}
+
+func TestParseSeverityThresholds(t *testing.T) {
+	zero, five := 0, 5
+	thresholds, err := ParseSeverityThresholds("critical=0, High=5")
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityThresholds{Critical: &zero, High: &five}, thresholds)
+
+	thresholds, err = ParseSeverityThresholds("")
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityThresholds{}, thresholds)
+
+	for _, value := range []string{"critical", "severe=1", "high=-1", "high=many"} {
+		_, err = ParseSeverityThresholds(value)
+		assert.Error(t, err, value)
+	}
+}
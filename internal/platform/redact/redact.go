@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package redact masks the secrets in the logs and the debug output of the CLI: the values of the environment
+// variables whose names look like secrets, the values registered with Add, and the passwords in URLs.
+package redact
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+)
+
+// Mask replaces the secrets.
+const Mask = "***"
+
+// minSecretLength is the length of the shortest value masked, the shorter ones are too likely to be found in any text.
+const minSecretLength = 4
+
+// DefaultPatterns are the name patterns of the environment variables holding secrets, QODANA_SECRET_PATTERNS adds
+// more of them separated by commas. The names are matched case-insensitively.
+var DefaultPatterns = []string{
+	"*TOKEN*",
+	"*SECRET*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*_PWD",
+	"*API_KEY*",
+	"*ACCESS_KEY*",
+	"*PRIVATE_KEY*",
+	"*CREDENTIAL*",
+	qdenv.QodanaLicense,
+}
+
+// urlPasswordPattern matches the password of the credentials in a URL, e.g. in HTTPS_PROXY.
+var urlPasswordPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:@/\s]+:)([^@/\s]+)@`)
+
+var (
+	mu     sync.RWMutex
+	values []string
+)
+
+// Add registers the secret values to mask in addition to the ones of the environment, e.g. a token from the keychain.
+func Add(secrets ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength && !slices.Contains(values, secret) {
+			values = append(values, secret)
+		}
+	}
+}
+
+// AddEnv registers the values of the variables with the secret names from the NAME=value pairs, e.g. passed with -e.
+func AddEnv(env []string) {
+	for _, variable := range env {
+		if name, value, ok := strings.Cut(variable, "="); ok && IsSecretName(name) {
+			Add(value)
+		}
+	}
+}
+
+// IsSecretName reports whether the environment variable holds a secret by its name.
+func IsSecretName(name string) bool {
+	return isSecretName(name, patterns())
+}
+
+func isSecretName(name string, patterns []string) bool {
+	name = strings.ToUpper(name)
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Env masks the value of the NAME=value pair if the name is a secret one, and the URL passwords otherwise.
+func Env(variable string) string {
+	name, value, ok := strings.Cut(variable, "=")
+	if !ok {
+		return String(variable)
+	}
+	if IsSecretName(name) && value != "" {
+		return name + "=" + Mask
+	}
+	return name + "=" + String(value)
+}
+
+// String masks the secrets in the text.
+func String(text string) string {
+	for _, secret := range secrets() {
+		text = strings.ReplaceAll(text, secret, Mask)
+	}
+	return urlPasswordPattern.ReplaceAllString(text, "${1}"+Mask+"@")
+}
+
+func patterns() []string {
+	ret := slices.Clone(DefaultPatterns)
+	for _, pattern := range strings.Split(os.Getenv(qdenv.QodanaSecretPatterns), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			ret = append(ret, strings.ToUpper(pattern))
+		}
+	}
+	return ret
+}
+
+// secrets returns the registered values and the values of the secret environment variables, the longest first so that
+// a secret containing another one is masked whole.
+func secrets() []string {
+	mu.RLock()
+	ret := slices.Clone(values)
+	mu.RUnlock()
+	secretPatterns := patterns()
+	for _, variable := range os.Environ() {
+		name, value, ok := strings.Cut(variable, "=")
+		if !ok || name == qdenv.QodanaSecretPatterns || len(value) < minSecretLength {
+			continue
+		}
+		if isSecretName(name, secretPatterns) && !slices.Contains(ret, value) {
+			ret = append(ret, value)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return len(ret[i]) > len(ret[j]) })
+	return ret
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// SetupRegistryCredentials sets the credentials of the registry the linter image is pulled from: the username of
+// --registry-username or QODANA_REGISTRY_USERNAME with the password read from stdin with --registry-password-stdin or
+// QODANA_REGISTRY_PASSWORD. Without them, the credential helpers of the cloud registries and the Docker config are used.
+// The credentials are only sent to the registry of --registry or QODANA_REGISTRY, the registry of the image (or of its
+// mirror) by default.
+func SetupRegistryCredentials(registry string, image string, username string, passwordStdin bool, stdin io.Reader) error {
+	if username == "" {
+		username = os.Getenv(qdenv.QodanaRegistryUsername)
+	}
+	password := os.Getenv(qdenv.QodanaRegistryPassword)
+	if passwordStdin {
+		if username == "" {
+			return errors.New("--registry-password-stdin needs --registry-username")
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read the registry password from stdin: %w", err)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	}
+	switch {
+	case username == "" && password == "":
+		return nil
+	case username == "":
+		return fmt.Errorf("%s is set without the registry username, set --registry-username", qdenv.QodanaRegistryPassword)
+	case password == "":
+		return fmt.Errorf(
+			"no password of the registry user %s, pass it with --registry-password-stdin or %s",
+			username,
+			qdenv.QodanaRegistryPassword,
+		)
+	}
+	if registry == "" {
+		registry = os.Getenv(qdenv.QodanaRegistry)
+	}
+	if registry == "" && image != "" {
+		registry = qdcontainer.RegistryHost(qdcontainer.ResolveImage(image))
+	}
+	if registry == "" {
+		return fmt.Errorf("no registry of the user %s, set --registry or %s", username, qdenv.QodanaRegistry)
+	}
+	qdcontainer.SetRegistryCredentials(registry, username, password)
+	return nil
+}
+
+// SetupRegistryCredentialsOrFatal sets the registry credentials before the linter image is pulled.
+func SetupRegistryCredentialsOrFatal(registry string, image string, username string, passwordStdin bool) {
+	if err := SetupRegistryCredentials(registry, image, username, passwordStdin, os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupRegistryCredentials(t *testing.T) {
+	t.Setenv(qdenv.QodanaRegistry, "")
+	t.Setenv(qdenv.QodanaRegistryUsername, "")
+	t.Setenv(qdenv.QodanaRegistryPassword, "")
+	defer qdcontainer.SetRegistryCredentials("", "", "")
+	const image = "registry.example.com/qodana-jvm:2024.3"
+
+	assert.NoError(t, SetupRegistryCredentials("", "", "", false, strings.NewReader("")))
+	assert.NoError(t, SetupRegistryCredentials("", image, "ci", true, strings.NewReader("secret\n")))
+	assert.ErrorContains(
+		t,
+		SetupRegistryCredentials("", image, "", true, strings.NewReader("secret")),
+		"--registry-password-stdin needs --registry-username",
+	)
+	assert.ErrorContains(t, SetupRegistryCredentials("", image, "ci", false, strings.NewReader("")), "no password")
+	assert.ErrorContains(
+		t,
+		SetupRegistryCredentials("", "", "ci", true, strings.NewReader("secret")),
+		"set --registry",
+	)
+
+	t.Setenv(qdenv.QodanaRegistryPassword, "secret")
+	assert.ErrorContains(t, SetupRegistryCredentials("", image, "", false, strings.NewReader("")), "without the registry username")
+	t.Setenv(qdenv.QodanaRegistryUsername, "ci")
+	assert.NoError(t, SetupRegistryCredentials("", image, "", false, strings.NewReader("")))
+}
+
+func TestSetupRegistryCredentialsHost(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv(qdenv.QodanaRegistry, "")
+	t.Setenv(qdenv.QodanaRegistryUsername, "ci")
+	t.Setenv(qdenv.QodanaRegistryPassword, "secret")
+	defer qdcontainer.SetRegistryCredentials("", "", "")
+
+	for _, tc := range []struct {
+		name     string
+		registry string
+		env      string
+		image    string
+		host     string
+	}{
+		{name: "registry of the image", image: "registry.example.com/qodana-jvm:2024.3", host: "registry.example.com"},
+		{name: "--registry", registry: "mirror.example.com", image: "jetbrains/qodana-jvm", host: "mirror.example.com"},
+		{name: "QODANA_REGISTRY", env: "https://env.example.com", image: "jetbrains/qodana-jvm", host: "env.example.com"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(qdenv.QodanaRegistry, tc.env)
+			require.NoError(t, SetupRegistryCredentials(tc.registry, tc.image, "", false, strings.NewReader("")))
+
+			auth, ok := qdcontainer.RegistryAuth(context.Background(), tc.host)
+			assert.True(t, ok)
+			assert.Equal(t, "ci", auth.Username)
+			_, ok = qdcontainer.RegistryAuth(context.Background(), "docker.io")
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/remotecache"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	log "github.com/sirupsen/logrus"
+)
+
+// remoteCacheFallbackBranches are restored from when there is no cache of the branch and qodana.yaml doesn't list
+// the fallback branches.
+var remoteCacheFallbackBranches = []string{"main", "master"}
+
+// RemoteCacheProject identifies the cache in the remote storage: the caches are kept by the linter, the project
+// directory relative to the repository root and the branch, so they are shared by the runners checking out
+// the repository to different paths.
+type RemoteCacheProject struct {
+	Linter string
+	// Path is the project directory relative to the repository root, with '/' separators.
+	Path   string
+	Branch string
+}
+
+// Id is the project part of the key, the same for all branches.
+func (p RemoteCacheProject) Id() string {
+	segments := strings.Split(p.Path, "/")
+	for i, segment := range segments {
+		segments[i] = remoteCacheKeySegment(segment)
+	}
+	return path.Join(remoteCacheKeySegment(p.Linter), path.Join(segments...))
+}
+
+// key is the object key of the cache of the branch.
+func (p RemoteCacheProject) key(branch string) string {
+	return p.Id() + "/" + remoteCacheKeySegment(branch) + ".tar.zst"
+}
+
+// remoteCacheKeySegment replaces the characters other than letters, digits, '.', '_' and '-', e.g. '/' of the branch.
+func remoteCacheKeySegment(s string) string {
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return strings.Map(
+		func(r rune) rune {
+			if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("._-", r) {
+				return r
+			}
+			return '_'
+		}, s,
+	)
+}
+
+// remoteCacheBranches are the branches whose caches are tried in order on restore.
+func remoteCacheBranches(branch string, fallbackBranches []string) []string {
+	if len(fallbackBranches) == 0 {
+		fallbackBranches = remoteCacheFallbackBranches
+	}
+	branches := make([]string, 0, len(fallbackBranches)+1)
+	seen := map[string]bool{}
+	for _, b := range append([]string{branch}, fallbackBranches...) {
+		if b != "" && !seen[b] {
+			seen[b] = true
+			branches = append(branches, b)
+		}
+	}
+	return branches
+}
+
+// remoteCacheBackend returns the backend of qodana.yaml remoteCache, nil if the remote cache isn't used by this run:
+// a container started by the CLI leaves it to the CLI on the host.
+func remoteCacheBackend(config *qdyaml.RemoteCache) remotecache.Backend {
+	if config == nil || config.Url == "" {
+		return nil
+	}
+	if qdenv.IsContainer() && os.Getenv(qdenv.QodanaRemoteCacheOnHost) != "" {
+		log.Debug("The remote cache is restored and saved by the CLI on the host")
+		return nil
+	}
+	backend, err := remotecache.New(config.Url, remotecache.Options{Region: config.Region, Endpoint: config.Endpoint})
+	if err != nil {
+		msg.WarningMessage("Remote cache is not used: %s", err)
+		return nil
+	}
+	return backend
+}
+
+// RestoreRemoteCache downloads the cache of the branch, or of the first fallback branch having one, to the empty
+// cache directory. The analysis runs without the cache if the download fails.
+func RestoreRemoteCache(ctx context.Context, config *qdyaml.RemoteCache, cacheDir string, project RemoteCacheProject) {
+	backend := remoteCacheBackend(config)
+	if backend == nil {
+		return
+	}
+	if entries, err := os.ReadDir(cacheDir); err == nil && len(entries) > 0 {
+		log.Debugf("The cache %s is not empty, it's not restored from %s", cacheDir, backend)
+		return
+	}
+	archivePath, cleanup, err := remoteCacheArchivePath()
+	if err != nil {
+		msg.WarningMessage("Failed to restore the remote cache: %s", err)
+		return
+	}
+	defer cleanup()
+
+	for _, branch := range remoteCacheBranches(project.Branch, config.FallbackBranches) {
+		key := project.key(branch)
+		err = downloadRemoteCache(ctx, backend, key, archivePath)
+		if errors.Is(err, remotecache.ErrNotFound) {
+			log.Debugf("No cache %s in %s", key, backend)
+			continue
+		}
+		if err != nil {
+			msg.WarningMessage("Failed to download the cache %s from %s: %s", key, backend, err)
+			return
+		}
+		manifest, err := commoncontext.ImportCache(
+			archivePath,
+			cacheDir,
+			func(manifest commoncontext.CacheManifest) error {
+				if manifest.Analyzer != project.Linter {
+					return fmt.Errorf("the cache is of %s, not of %s", manifest.Analyzer, project.Linter)
+				}
+				return nil
+			},
+		)
+		if err != nil {
+			msg.WarningMessage("Failed to restore the cache %s from %s: %s", key, backend, err)
+			return
+		}
+		msg.SuccessMessage("Restored the cache of %s (%d files) from %s", branch, manifest.Files, backend)
+		return
+	}
+	log.Infof("No cache of the project in %s, the analysis runs without it", backend)
+}
+
+// SaveRemoteCache uploads the cache directory as the cache of the branch, unless the remote cache is read-only.
+func SaveRemoteCache(ctx context.Context, config *qdyaml.RemoteCache, cacheDir string, project RemoteCacheProject) {
+	backend := remoteCacheBackend(config)
+	if backend == nil || config.ReadOnly {
+		return
+	}
+	if project.Branch == "" {
+		msg.WarningMessage("The cache is not saved to %s: the branch is unknown, set %s", backend, qdenv.QodanaBranch)
+		return
+	}
+	archivePath, cleanup, err := remoteCacheArchivePath()
+	if err != nil {
+		msg.WarningMessage("Failed to save the remote cache: %s", err)
+		return
+	}
+	defer cleanup()
+
+	if _, err = commoncontext.ExportCache(
+		cacheDir,
+		archivePath,
+		commoncontext.CacheManifest{
+			CliVersion: version.Version,
+			Analyzer:   project.Linter,
+			ProjectId:  project.Id(),
+			CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		},
+	); err != nil {
+		msg.WarningMessage("Failed to save the remote cache: %s", err)
+		return
+	}
+	key := project.key(project.Branch)
+	if err = uploadRemoteCache(ctx, backend, key, archivePath); err != nil {
+		msg.WarningMessage("Failed to upload the cache %s to %s: %s", key, backend, err)
+		return
+	}
+	msg.SuccessMessage("Saved the cache of %s to %s", project.Branch, backend)
+}
+
+// remoteCacheArchivePath returns the path of a temporary archive, its extension selects the compression.
+func remoteCacheArchivePath() (string, func(), error) {
+	dir, err := os.MkdirTemp("", "qodana-remote-cache")
+	if err != nil {
+		return "", nil, err
+	}
+	return filepath.Join(dir, "cache.tar.zst"), func() { _ = os.RemoveAll(dir) }, nil
+}
+
+func downloadRemoteCache(ctx context.Context, backend remotecache.Backend, key string, archivePath string) error {
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	err = backend.Download(ctx, key, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func uploadRemoteCache(ctx context.Context, backend remotecache.Backend, key string, archivePath string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return backend.Upload(ctx, key, file, info.Size())
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteCacheProjectKey(t *testing.T) {
+	project := RemoteCacheProject{Linter: "qodana-jvm", Path: "services/api", Branch: "feature/remote cache"}
+	assert.Equal(t, "qodana-jvm/services/api", project.Id())
+	assert.Equal(t, "qodana-jvm/services/api/feature_remote_cache.tar.zst", project.key(project.Branch))
+
+	project.Path = "."
+	assert.Equal(t, "qodana-jvm/_/main.tar.zst", project.key("main"))
+}
+
+func TestRemoteCacheBranches(t *testing.T) {
+	assert.Equal(t, []string{"feature", "main", "master"}, remoteCacheBranches("feature", nil))
+	assert.Equal(t, []string{"main", "master"}, remoteCacheBranches("main", nil))
+	assert.Equal(t, []string{"develop"}, remoteCacheBranches("", []string{"develop"}))
+}
+
+func TestSaveAndRestoreRemoteCache(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodPut {
+					objects[r.URL.Path], _ = io.ReadAll(r.Body)
+					return
+				}
+				object, ok := objects[r.URL.Path]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write(object)
+			},
+		),
+	)
+	defer server.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+	config := &qdyaml.RemoteCache{Url: "gs://caches/ci"}
+	ctx := context.Background()
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	assert.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "idea"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(cacheDir, "idea", "index"), []byte("index"), 0o644))
+	SaveRemoteCache(ctx, config, cacheDir, RemoteCacheProject{Linter: "qodana-jvm", Path: ".", Branch: "main"})
+	assert.Contains(t, objects, "/caches/ci/qodana-jvm/_/main.tar.zst")
+
+	// the cache of a new branch is restored from main
+	restoredDir := filepath.Join(t.TempDir(), "cache")
+	RestoreRemoteCache(ctx, config, restoredDir, RemoteCacheProject{Linter: "qodana-jvm", Path: ".", Branch: "feature"})
+	content, err := os.ReadFile(filepath.Join(restoredDir, "idea", "index"))
+	assert.NoError(t, err)
+	assert.Equal(t, "index", string(content))
+
+	// the cache of another linter isn't restored
+	otherDir := filepath.Join(t.TempDir(), "cache")
+	RestoreRemoteCache(ctx, config, otherDir, RemoteCacheProject{Linter: "qodana-python", Path: ".", Branch: "main"})
+	assert.NoDirExists(t, otherDir)
+
+	// the read-only cache isn't saved
+	SaveRemoteCache(
+		ctx,
+		&qdyaml.RemoteCache{Url: "gs://caches/ci", ReadOnly: true},
+		cacheDir,
+		RemoteCacheProject{Linter: "qodana-jvm", Path: ".", Branch: "feature"},
+	)
+	assert.NotContains(t, objects, "/caches/ci/qodana-jvm/_/feature.tar.zst")
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package remotecache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// azureBlobVersion is the version of the Blob service API, since 2019-12-12 a block blob of up to 5000 MiB is
+// uploaded with one request.
+const azureBlobVersion = "2020-04-08"
+
+// azureBackend uses the Blob service API with the SAS token of the container from AZURE_STORAGE_SAS_TOKEN.
+type azureBackend struct {
+	client    *http.Client
+	baseUrl   string
+	container string
+	prefix    string
+	sasToken  string
+}
+
+func newAzure(accountUrl string, container string, prefix string) (*azureBackend, error) {
+	return &azureBackend{
+		client:    &http.Client{},
+		baseUrl:   accountUrl + "/" + escapeSegment(container),
+		container: container,
+		prefix:    prefix,
+		sasToken:  strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"),
+	}, nil
+}
+
+func (b *azureBackend) String() string {
+	return strings.TrimSuffix(b.baseUrl+"/"+b.prefix, "/")
+}
+
+func (b *azureBackend) request(ctx context.Context, method string, key string) (*http.Request, error) {
+	blobUrl := b.baseUrl + "/" + objectPath(b.prefix, key)
+	if b.sasToken != "" {
+		blobUrl += "?" + b.sasToken
+	}
+	req, err := http.NewRequestWithContext(ctx, method, blobUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Ms-Version", azureBlobVersion)
+	return req, nil
+}
+
+func (b *azureBackend) Download(ctx context.Context, key string, w io.Writer) error {
+	req, err := b.request(ctx, http.MethodGet, key)
+	if err != nil {
+		return err
+	}
+	return download(b.client, req, w)
+}
+
+func (b *azureBackend) Upload(ctx context.Context, key string, r io.Reader, size int64) error {
+	req, err := b.request(ctx, http.MethodPut, key)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	return upload(b.client, req, r, size)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package remotecache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const gcsDefaultEndpoint = "https://storage.googleapis.com"
+
+// gcsBackend uses the XML API of Google Cloud Storage with the OAuth access token, e.g. printed by
+// `gcloud auth print-access-token` or issued to the workload identity of the CI job.
+type gcsBackend struct {
+	client  *http.Client
+	baseUrl string
+	bucket  string
+	prefix  string
+	token   string
+}
+
+func newGcs(bucket string, prefix string) (*gcsBackend, error) {
+	endpoint := gcsDefaultEndpoint
+	if emulator := os.Getenv("STORAGE_EMULATOR_HOST"); emulator != "" {
+		endpoint = emulator
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+	}
+	return &gcsBackend{
+		client:  &http.Client{},
+		baseUrl: strings.TrimSuffix(endpoint, "/") + "/" + escapeSegment(bucket),
+		bucket:  bucket,
+		prefix:  prefix,
+		token:   firstNonEmpty(os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"), os.Getenv("CLOUDSDK_AUTH_ACCESS_TOKEN")),
+	}, nil
+}
+
+func (b *gcsBackend) String() string {
+	return "gs://" + b.bucket + "/" + b.prefix
+}
+
+func (b *gcsBackend) request(ctx context.Context, method string, key string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.baseUrl+"/"+objectPath(b.prefix, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	return req, nil
+}
+
+func (b *gcsBackend) Download(ctx context.Context, key string, w io.Writer) error {
+	req, err := b.request(ctx, http.MethodGet, key)
+	if err != nil {
+		return err
+	}
+	return download(b.client, req, w)
+}
+
+func (b *gcsBackend) Upload(ctx context.Context, key string, r io.Reader, size int64) error {
+	req, err := b.request(ctx, http.MethodPut, key)
+	if err != nil {
+		return err
+	}
+	return upload(b.client, req, r, size)
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package remotecache keeps the cache archives of the CI runs in S3, Google Cloud Storage or Azure Blob Storage.
+// The storages are accessed with their REST APIs and the credentials from the standard environment variables.
+package remotecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned by Backend.Download when there is no object with the key.
+var ErrNotFound = errors.New("not found in the remote cache")
+
+// Backend is a storage of the cache archives.
+type Backend interface {
+	// Download writes the object with the key to w, ErrNotFound if there is none.
+	Download(ctx context.Context, key string, w io.Writer) error
+	// Upload stores size bytes from r as the object with the key, replacing the existing one.
+	Upload(ctx context.Context, key string, r io.Reader, size int64) error
+	// String is the location of the storage shown in the messages.
+	String() string
+}
+
+// Options are the settings of a storage, besides its URL.
+type Options struct {
+	// Region of the S3 bucket, AWS_REGION by default.
+	Region string
+	// Endpoint of an S3-compatible storage, e.g. MinIO, the objects are addressed by the path on it.
+	Endpoint string
+}
+
+// New returns the backend of the storage URL: s3://bucket/prefix, gs://bucket/prefix or
+// https://<account>.blob.core.windows.net/<container>/prefix.
+func New(storageUrl string, options Options) (Backend, error) {
+	u, err := url.Parse(storageUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote cache URL %s: %w", storageUrl, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch {
+	case u.Scheme == "s3" && u.Host != "":
+		return newS3(u.Host, prefix, options)
+	case u.Scheme == "gs" && u.Host != "":
+		return newGcs(u.Host, prefix)
+	case u.Scheme == "https" && strings.HasSuffix(u.Host, ".blob.core.windows.net"):
+		container, prefix, _ := strings.Cut(prefix, "/")
+		if container == "" {
+			break
+		}
+		return newAzure("https://"+u.Host, container, prefix)
+	}
+	return nil, fmt.Errorf(
+		"unsupported remote cache URL %s, use s3://<bucket>/<prefix>, gs://<bucket>/<prefix> or https://<account>.blob.core.windows.net/<container>/<prefix>",
+		storageUrl,
+	)
+}
+
+// objectPath joins the prefix and the key and escapes them for the URL path.
+func objectPath(prefix string, key string) string {
+	segments := strings.Split(path.Join(prefix, key), "/")
+	for i, segment := range segments {
+		segments[i] = escapeSegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escapeSegment percent-encodes everything but the unreserved characters, as the request signatures expect.
+func escapeSegment(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			_, _ = fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// do sends the request and returns the response of a 2xx status, ErrNotFound for 404.
+func do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	// the query is dropped, it keeps the SAS token of Azure
+	location := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path}
+	return nil, fmt.Errorf("%s %s: %s %s", req.Method, location.String(), resp.Status, strings.TrimSpace(string(body)))
+}
+
+// download copies the body of the GET request to w.
+func download(client *http.Client, req *http.Request, w io.Writer) error {
+	resp, err := do(client, req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// upload sends the PUT request with the body of the given size.
+func upload(client *http.Client, req *http.Request, r io.Reader, size int64) error {
+	req.Body = io.NopCloser(r)
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	resp, err := do(client, req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package remotecache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3DefaultRegion   = "us-east-1"
+)
+
+// awsCredentials are read from the environment variables of the AWS CLI, the requests are anonymous without them.
+type awsCredentials struct {
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+type s3Backend struct {
+	client      *http.Client
+	baseUrl     string
+	bucket      string
+	prefix      string
+	region      string
+	credentials awsCredentials
+	now         func() time.Time
+}
+
+func newS3(bucket string, prefix string, options Options) (*s3Backend, error) {
+	region := firstNonEmpty(options.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), s3DefaultRegion)
+	endpoint := strings.TrimSuffix(firstNonEmpty(options.Endpoint, os.Getenv("AWS_ENDPOINT_URL_S3")), "/")
+	var baseUrl string
+	switch {
+	case endpoint != "":
+		baseUrl = endpoint + "/" + escapeSegment(bucket)
+	case strings.Contains(bucket, "."):
+		// the virtual-hosted style doesn't match the certificate of *.s3.amazonaws.com
+		baseUrl = fmt.Sprintf("https://s3.%s.amazonaws.com/%s", region, escapeSegment(bucket))
+	default:
+		baseUrl = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	}
+	return &s3Backend{
+		client:  &http.Client{},
+		baseUrl: baseUrl,
+		bucket:  bucket,
+		prefix:  prefix,
+		region:  region,
+		credentials: awsCredentials{
+			accessKeyId:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		now: time.Now,
+	}, nil
+}
+
+func (b *s3Backend) String() string {
+	return "s3://" + b.bucket + "/" + b.prefix
+}
+
+func (b *s3Backend) request(ctx context.Context, method string, key string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.baseUrl+"/"+objectPath(b.prefix, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	if b.credentials.accessKeyId != "" {
+		signV4(req, b.credentials, b.region, "s3", b.now())
+	}
+	return req, nil
+}
+
+func (b *s3Backend) Download(ctx context.Context, key string, w io.Writer) error {
+	req, err := b.request(ctx, http.MethodGet, key)
+	if err != nil {
+		return err
+	}
+	return download(b.client, req, w)
+}
+
+func (b *s3Backend) Upload(ctx context.Context, key string, r io.Reader, size int64) error {
+	req, err := b.request(ctx, http.MethodPut, key)
+	if err != nil {
+		return err
+	}
+	return upload(b.client, req, r, size)
+}
+
+// signV4 adds the AWS Signature Version 4 of the request to its headers. The host and all the headers set so far are
+// signed, the payload hash is taken from X-Amz-Content-Sha256.
+func signV4(req *http.Request, credentials awsCredentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join(
+		[]string{
+			req.Method,
+			req.URL.EscapedPath(),
+			req.URL.RawQuery,
+			canonicalHeaders.String(),
+			signedHeaders,
+			req.Header.Get("X-Amz-Content-Sha256"),
+		},
+		"\n",
+	)
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+credentials.secretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSha256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			credentials.accessKeyId,
+			scope,
+			signedHeaders,
+			signature,
+		),
+	)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
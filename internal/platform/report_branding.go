@@ -0,0 +1,194 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+)
+
+const (
+	brandingDir         = "branding"
+	brandingStyleSheet  = "branding.css"
+	brandingHeadMarker  = "qodana-branding-head"
+	brandingBodyMarker  = "qodana-branding-body"
+	brandingFooterClass = "qodana-branding-footer"
+)
+
+var (
+	brandingColorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+)$`)
+	brandingTitlePattern = regexp.MustCompile(`(?is)<title>.*?</title>`)
+	brandingLogoFormats  = []string{".svg", ".png", ".jpg", ".jpeg", ".gif", ".webp"}
+)
+
+// ApplyReportBranding customizes the HTML report saved to reportDir with the report section of qodana.yaml. The report
+// is kept as it is if the branding is invalid, the analysis doesn't fail because of it.
+func ApplyReportBranding(reportDir string, projectDir string, branding *qdyaml.Report) {
+	if branding == nil {
+		return
+	}
+	if err := brandReport(reportDir, projectDir, branding); err != nil {
+		msg.WarningMessage("The report branding from qodana.yaml is not applied: %s", err)
+	}
+}
+
+func brandReport(reportDir string, projectDir string, branding *qdyaml.Report) error {
+	if err := validateReportBranding(branding); err != nil {
+		return err
+	}
+	indexPath := filepath.Join(reportDir, "index.html")
+	index, err := os.ReadFile(indexPath)
+	if err != nil {
+		return fmt.Errorf("no HTML report in %s: %w", reportDir, err)
+	}
+	if err = os.MkdirAll(filepath.Join(reportDir, brandingDir), 0o755); err != nil {
+		return err
+	}
+	logo := ""
+	if branding.Logo != "" {
+		source := branding.Logo
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(projectDir, source)
+		}
+		logo = brandingDir + "/logo" + strings.ToLower(filepath.Ext(source))
+		if err = fs.CopyFile(source, filepath.Join(reportDir, filepath.FromSlash(logo))); err != nil {
+			return fmt.Errorf("cannot copy the logo %s: %w", branding.Logo, err)
+		}
+	}
+	styleSheet := filepath.Join(reportDir, brandingDir, brandingStyleSheet)
+	if err = os.WriteFile(styleSheet, []byte(brandingCss(branding)), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(indexPath, []byte(brandIndexHtml(string(index), branding, logo)), 0o644)
+}
+
+func validateReportBranding(branding *qdyaml.Report) error {
+	for severity, color := range branding.SeverityColors {
+		if !slices.Contains(markdownSeverities, severity) {
+			return fmt.Errorf(
+				"unknown severity %q in severityColors, the severities are %s",
+				severity,
+				strings.Join(markdownSeverities, ", "),
+			)
+		}
+		if !brandingColorPattern.MatchString(color) {
+			return fmt.Errorf("invalid color %q of %s, use a hex color like #d32f2f or a CSS color name", color, severity)
+		}
+	}
+	for _, link := range branding.FooterLinks {
+		u, err := url.Parse(link.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || link.Text == "" {
+			return fmt.Errorf("invalid footer link %q to %q, a link needs a text and an http(s) URL", link.Text, link.Url)
+		}
+	}
+	if branding.Logo != "" && !slices.Contains(brandingLogoFormats, strings.ToLower(filepath.Ext(branding.Logo))) {
+		return fmt.Errorf("unsupported logo format %s, use one of %s", branding.Logo, strings.Join(brandingLogoFormats, ", "))
+	}
+	return nil
+}
+
+// brandingCss returns the style sheet of the branding: the severity colors are the --qodana-severity-<severity>
+// variables of the report.
+func brandingCss(branding *qdyaml.Report) string {
+	var sb strings.Builder
+	severities := make([]string, 0, len(branding.SeverityColors))
+	for severity := range branding.SeverityColors {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool { return severityRank(severities[i]) < severityRank(severities[j]) })
+	sb.WriteString(":root {\n")
+	for _, severity := range severities {
+		_, _ = fmt.Fprintf(&sb, "  --qodana-severity-%s: %s;\n", severity, branding.SeverityColors[severity])
+	}
+	sb.WriteString("}\n")
+	sb.WriteString(".qodana-branding-header { display: flex; align-items: center; gap: 12px; padding: 8px 16px; }\n")
+	sb.WriteString(".qodana-branding-header img { max-height: 32px; }\n")
+	_, _ = fmt.Fprintf(&sb, ".%s { display: flex; flex-wrap: wrap; gap: 16px; padding: 8px 16px; }\n", brandingFooterClass)
+	return sb.String()
+}
+
+// brandIndexHtml returns the index.html of the report with the branding, the branding of a previous run is replaced.
+func brandIndexHtml(index string, branding *qdyaml.Report, logo string) string {
+	index = removeBrandingBlock(index, brandingHeadMarker)
+	index = removeBrandingBlock(index, brandingBodyMarker)
+	if branding.Title != "" {
+		title := "<title>" + html.EscapeString(branding.Title) + "</title>"
+		if brandingTitlePattern.MatchString(index) {
+			index = brandingTitlePattern.ReplaceAllLiteralString(index, title)
+		} else {
+			index = insertBefore(index, "</head>", title)
+		}
+	}
+	head := fmt.Sprintf(`<link rel="stylesheet" href="%s/%s">`, brandingDir, brandingStyleSheet)
+	index = insertBefore(index, "</head>", brandingBlock(brandingHeadMarker, head))
+
+	var body strings.Builder
+	if logo != "" || branding.Title != "" {
+		body.WriteString(`<header class="qodana-branding-header">`)
+		if logo != "" {
+			_, _ = fmt.Fprintf(&body, `<img src="%s" alt="">`, logo)
+		}
+		if branding.Title != "" {
+			_, _ = fmt.Fprintf(&body, `<span>%s</span>`, html.EscapeString(branding.Title))
+		}
+		body.WriteString(`</header>`)
+	}
+	if len(branding.FooterLinks) > 0 {
+		_, _ = fmt.Fprintf(&body, `<footer class="%s">`, brandingFooterClass)
+		for _, link := range branding.FooterLinks {
+			_, _ = fmt.Fprintf(&body, `<a href="%s">%s</a>`, html.EscapeString(link.Url), html.EscapeString(link.Text))
+		}
+		body.WriteString(`</footer>`)
+	}
+	if body.Len() > 0 {
+		index = insertBefore(index, "</body>", brandingBlock(brandingBodyMarker, body.String()))
+	}
+	return index
+}
+
+func brandingBlock(marker string, content string) string {
+	return fmt.Sprintf("<!-- %s -->%s<!-- /%s -->", marker, content, marker)
+}
+
+func removeBrandingBlock(index string, marker string) string {
+	start := strings.Index(index, "<!-- "+marker+" -->")
+	end := strings.Index(index, "<!-- /"+marker+" -->")
+	if start < 0 || end < start {
+		return index
+	}
+	return index[:start] + index[end+len("<!-- /"+marker+" -->"):]
+}
+
+// insertBefore inserts the content before the last tag, or appends it if there is no such tag.
+func insertBefore(index string, tag string, content string) string {
+	i := strings.LastIndex(strings.ToLower(index), tag)
+	if i < 0 {
+		return index + content
+	}
+	return index[:i] + content + index[i:]
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const brandingIndexHtml = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Qodana</title></head>
+<body><div id="app"></div><script src="app.js"></script></body></html>
+`
+
+func TestBrandReport(t *testing.T) {
+	projectDir := t.TempDir()
+	reportDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "logo.SVG"), []byte("<svg/>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(reportDir, "index.html"), []byte(brandingIndexHtml), 0o644))
+	branding := &qdyaml.Report{
+		Title:          "ACME <code> quality",
+		Logo:           "logo.SVG",
+		SeverityColors: map[string]string{"low": "green", "critical": "#b71c1c"},
+		FooterLinks:    []qdyaml.ReportLink{{Text: "Guidelines", Url: "https://example.com/guidelines?a=1&b=2"}},
+	}
+
+	require.NoError(t, brandReport(reportDir, projectDir, branding))
+	// applied twice, like by the CLI in the container and on the host
+	require.NoError(t, brandReport(reportDir, projectDir, branding))
+
+	index, err := os.ReadFile(filepath.Join(reportDir, "index.html"))
+	require.NoError(t, err)
+	html := string(index)
+	assert.Contains(t, html, "<title>ACME &lt;code&gt; quality</title>")
+	assert.NotContains(t, html, "<title>Qodana</title>")
+	assert.Equal(t, 1, strings.Count(html, `<link rel="stylesheet" href="branding/branding.css">`))
+	assert.Equal(t, 1, strings.Count(html, `<img src="branding/logo.svg" alt="">`))
+	assert.Contains(t, html, `<a href="https://example.com/guidelines?a=1&amp;b=2">Guidelines</a></footer>`)
+	assert.True(t, strings.HasSuffix(html, "</footer><!-- /qodana-branding-body --></body></html>\n"))
+	assert.FileExists(t, filepath.Join(reportDir, "branding", "logo.svg"))
+
+	css, err := os.ReadFile(filepath.Join(reportDir, "branding", "branding.css"))
+	require.NoError(t, err)
+	assert.Contains(t, string(css), ":root {\n  --qodana-severity-critical: #b71c1c;\n  --qodana-severity-low: green;\n}\n")
+}
+
+func TestBrandReportInvalid(t *testing.T) {
+	reportDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(reportDir, "index.html"), []byte(brandingIndexHtml), 0o644))
+
+	for name, branding := range map[string]*qdyaml.Report{
+		"unknown severity": {SeverityColors: map[string]string{"urgent": "red"}},
+		"css injection":    {SeverityColors: map[string]string{"high": "red; } body { display: none"}},
+		"script link":      {FooterLinks: []qdyaml.ReportLink{{Text: "x", Url: "javascript:alert(1)"}}},
+		"logo format":      {Logo: "logo.exe"},
+		"missing logo":     {Logo: "missing.png"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, brandReport(reportDir, t.TempDir(), branding))
+		})
+	}
+	assert.Error(t, brandReport(t.TempDir(), t.TempDir(), &qdyaml.Report{Title: "ACME"}))
+
+	index, err := os.ReadFile(filepath.Join(reportDir, "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, brandingIndexHtml, string(index))
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+// ReportIndexRun is a run listed by qodana report index.
+type ReportIndexRun struct {
+	HistoryRun
+	Branch   string
+	Revision string
+	// Report is the path to the HTML report of the run relative to the index, empty if the run has no HTML report.
+	Report string
+}
+
+// NewTotal is the number of the new problems of the run.
+func (r ReportIndexRun) NewTotal() int {
+	total := 0
+	for _, count := range r.NewProblems {
+		total += count
+	}
+	return total
+}
+
+// LoadReportIndexRuns returns the runs in the subdirectories of dir, the newest first. A run is a results directory,
+// e.g. kept with --keep-runs, or a report directory saved with --save-report; the directories without a SARIF report
+// are skipped. The paths to the HTML reports are relative to indexDir.
+func LoadReportIndexRuns(dir string, indexDir string) ([]ReportIndexRun, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the runs in %s: %w", dir, err)
+	}
+	var runs []ReportIndexRun
+	for _, entry := range entries {
+		// the latest symlink of --keep-runs is not a directory entry
+		if !entry.IsDir() {
+			continue
+		}
+		runDir := filepath.Join(dir, entry.Name())
+		sarifPath := findRunSarif(runDir)
+		if sarifPath == "" {
+			continue
+		}
+		run := ReportIndexRun{HistoryRun: HistoryRun{Name: entry.Name(), Dir: runDir}}
+		report := loadHistoryRunSarif(&run.HistoryRun, sarifPath)
+		if report == nil {
+			continue
+		}
+		run.Time = runTime(entry, report.Runs)
+		for _, r := range report.Runs {
+			if len(r.VersionControlProvenance) > 0 && run.Revision == "" {
+				run.Branch = r.VersionControlProvenance[0].Branch
+				run.Revision = r.VersionControlProvenance[0].RevisionId
+			}
+		}
+		for _, index := range []string{filepath.Join(runDir, "report", "index.html"), filepath.Join(runDir, "index.html")} {
+			if _, err = os.Stat(index); err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(indexDir, index); err == nil {
+				run.Report = filepath.ToSlash(rel)
+			}
+			break
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Time.After(runs[j].Time) })
+	return runs, nil
+}
+
+// findRunSarif returns the SARIF report of the results directory or of the report directory, empty if there is none.
+func findRunSarif(runDir string) string {
+	for _, path := range []string{
+		filepath.Join(runDir, commoncontext.QodanaSarifName),
+		filepath.Join(ReportResultsPath(filepath.Join(runDir, "report")), commoncontext.QodanaSarifName),
+		filepath.Join(ReportResultsPath(runDir), commoncontext.QodanaSarifName),
+	} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return ""
+		}
+	}
+	return ""
+}
+
+// runTime returns the start time of the run from the name of a --keep-runs directory, the end of the analysis from
+// the report otherwise, or the modification time of the directory.
+func runTime(entry os.DirEntry, runs []sarif.Run) time.Time {
+	if len(entry.Name()) >= len(runDirLayout) {
+		if started, err := time.ParseInLocation(runDirLayout, entry.Name()[:len(runDirLayout)], time.Local); err == nil {
+			return started
+		}
+	}
+	for _, r := range runs {
+		for _, invocation := range r.Invocations {
+			if !invocation.EndTimeUtc.IsZero() {
+				return invocation.EndTimeUtc.Local()
+			}
+		}
+	}
+	if info, err := entry.Info(); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+type reportIndexData struct {
+	Title      string
+	Generated  string
+	Severities []string
+	Runs       []ReportIndexRun
+}
+
+// WriteReportIndex renders the runs as a static HTML page linking their reports, to publish the reports together,
+// e.g. on GitHub Pages or in an S3 bucket.
+func WriteReportIndex(w io.Writer, title string, runs []ReportIndexRun) error {
+	return reportIndexTemplate.Execute(
+		w,
+		reportIndexData{
+			Title:      title,
+			Generated:  time.Now().Format("2006-01-02 15:04"),
+			Severities: HistorySeverities,
+			Runs:       runs,
+		},
+	)
+}
+
+var reportIndexTemplate = template.Must(
+	template.New("index").Funcs(
+		template.FuncMap{
+			"count":     func(counts map[string]int, severity string) int { return counts[severity] },
+			"shortHash": func(revision string) string { return revision[:min(len(revision), 8)] },
+		},
+	).Parse(
+		`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 2em; color: #212121; }
+table { border-collapse: collapse; margin-top: 1em; }
+th, td { border: 1px solid #e0e0e0; padding: 4px 8px; text-align: right; }
+th:nth-child(-n+4), td:nth-child(-n+4) { text-align: left; }
+code { font-size: 0.9em; }
+.new { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Runs}} runs, generated at {{.Generated}}</p>
+<table>
+<tr><th>Run</th><th>Time</th><th>Linter</th><th>Revision</th><th>Total</th>{{range .Severities}}<th>{{.}}</th>{{end}}<th>New</th></tr>
+{{range .Runs}}{{$run := .}}<tr><td>{{if .Report}}<a href="{{.Report}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td><td>{{.Time.Format "2006-01-02 15:04"}}</td><td>{{.Linter}}</td><td>{{if .Branch}}{{.Branch}} {{end}}{{if .Revision}}<code>{{shortHash .Revision}}</code>{{end}}</td><td>{{.Total}}</td>{{range $.Severities}}<td>{{count $run.Problems .}}</td>{{end}}<td class="new">{{.NewTotal}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`,
+	),
+)
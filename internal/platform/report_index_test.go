@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reportIndexSarif = `{
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {"driver": {"name": "QDGO"}},
+      "invocations": [{"executionSuccessful": true, "endTimeUtc": "2024-03-01T12:00:00Z"}],
+      "versionControlProvenance": [{"repositoryUri": "https://example.com/repo.git", "revisionId": "0123456789abcdef", "branch": "main"}],
+      "results": [
+        {"ruleId": "A", "message": {"text": "a"}, "properties": {"qodanaSeverity": "Moderate"}, "baselineState": "new"}
+      ]
+    }
+  ]
+}`
+
+func TestLoadReportIndexRuns(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(path string, content string) {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	// a results directory kept with --keep-runs
+	writeFile("20240102-100000/"+commoncontext.QodanaSarifName, historyRunSarif)
+	writeFile("20240102-100000/report/index.html", "<html></html>")
+	// a report directory saved with --save-report
+	writeFile("pr-42/results/"+commoncontext.QodanaSarifName, reportIndexSarif)
+	writeFile("pr-42/index.html", "<html></html>")
+	writeFile("log/idea.log", "")
+	require.NoError(t, os.Symlink("20240102-100000", filepath.Join(dir, LatestRunLink)))
+
+	runs, err := LoadReportIndexRuns(dir, dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	assert.Equal(t, "pr-42", runs[0].Name)
+	assert.True(t, runs[0].Time.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "QDGO", runs[0].Linter)
+	assert.Equal(t, "main", runs[0].Branch)
+	assert.Equal(t, "0123456789abcdef", runs[0].Revision)
+	assert.Equal(t, "pr-42/index.html", runs[0].Report)
+	assert.Equal(t, 1, runs[0].NewTotal())
+
+	assert.Equal(t, "20240102-100000", runs[1].Name)
+	assert.Equal(t, "20240102-100000/report/index.html", runs[1].Report)
+	assert.Equal(t, 2, runs[1].Total())
+
+	var out bytes.Buffer
+	require.NoError(t, WriteReportIndex(&out, "ACME <reports>", runs))
+	page := out.String()
+	assert.Contains(t, page, "<title>ACME &lt;reports&gt;</title>")
+	assert.Contains(t, page, `<a href="pr-42/index.html">pr-42</a>`)
+	assert.Contains(t, page, "main <code>01234567</code>")
+	assert.Less(t, strings.Index(page, "pr-42"), strings.Index(page, "20240102-100000"))
+}
+
+func TestLoadReportIndexRunsMissingDir(t *testing.T) {
+	_, err := LoadReportIndexRuns(filepath.Join(t.TempDir(), "missing"), ".")
+	assert.Error(t, err)
+}
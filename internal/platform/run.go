@@ -22,17 +22,24 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
 	"github.com/JetBrains/qodana-cli/internal/foundation/fs"
 	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/internal/platform/effectiveconfig"
+	"github.com/JetBrains/qodana-cli/internal/platform/fingerprint"
+	"github.com/JetBrains/qodana-cli/internal/platform/logging"
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/platform/tokenloader"
+	"github.com/JetBrains/qodana-cli/internal/platform/tracing"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -42,6 +49,14 @@ func RunThirdPartyLinterAnalysis(
 	linterInfo thirdpartyscan.LinterInfo,
 ) (int, error) {
 	qdenv.InitializeQodanaGlobalEnv(cliOptions)
+	ParseFailOnOrFatal(cliOptions.FailOn)
+	LoadLicenseDenylistOrFatal(cliOptions.FailOnLicense)
+	exitCodePolicy := ParseExitCodePolicyOrFatal(cliOptions.ExitCodePolicy)
+	SetupOfflineModeOrFatal(cliOptions)
+	SetupDownloadVerification(cliOptions)
+	SetupMetricsOrFatal(cliOptions.MetricsFormat)
+	ValidateSbomFormatOrFatal(cliOptions.SbomFormat)
+	ValidateOutputOrFatal(cliOptions.Output)
 
 	var err error
 
@@ -56,20 +71,37 @@ func RunThirdPartyLinterAnalysis(
 		cliOptions.ProjectDir,
 		cliOptions.RepositoryRoot,
 	)
+	commonCtx, err = commoncontext.LockRunDirs(commonCtx, cliOptions.ConcurrentRun)
+	if err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
+	defer commoncontext.ReleaseRunLocks()
+	commonCtx, err = StartHistoryRun(commonCtx, cliOptions.KeepRuns)
+	if err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
 	commonCtx, err = correctInitArgsForThirdParty(commonCtx)
 	if err != nil {
 		msg.ErrorMessage(err.Error())
 		return 1, err
 	}
+	logging.StartRunLog(commonCtx.LogDir())
 	resultDir := commonCtx.ResultsDir
 	defer changeResultDirPermissionsInContainer(resultDir)
+	defer timings.Finish(resultDir)
+	defer tracing.Finish()
 
 	thirdPartyCloudData := checkLinterLicense(commonCtx)
 
 	printLinterLicense(thirdPartyCloudData.LicensePlan, linterInfo)
 	printQodanaLogo(commonCtx.LogDir(), commonCtx.CacheDir, linterInfo)
 
-	mountInfo := extractUtils(linter, commonCtx.CacheDir)
+	mountInfo, err := extractUtils(linter, commonCtx.CacheDir)
+	if err != nil {
+		return 1, err
+	}
 
 	localQodanaYamlFullPath := qdyaml.GetLocalNotEffectiveQodanaYamlFullPath(
 		commonCtx.ProjectDir,
@@ -89,15 +121,22 @@ func RunThirdPartyLinterAnalysis(
 		cliOptions.GlobalConfigurationId,
 		effectiveDir,
 		commonCtx.LogDir(),
+		linterInfo.LinterName,
+		linterInfo.ProductCode,
 	)
 	if err != nil {
 		log.Fatalf("Failed to load Qodana configuration %s", err)
 	}
 	qodanaYamlConfig := thirdpartyscan.QodanaYamlConfig{}
 	if qodanaConfigEffectiveFiles.EffectiveQodanaYamlPath != "" {
-		yaml := qdyaml.LoadQodanaYamlByFullPath(qodanaConfigEffectiveFiles.EffectiveQodanaYamlPath)
+		yaml := qdyaml.LoadQodanaYamlForLinter(
+			qodanaConfigEffectiveFiles.EffectiveQodanaYamlPath,
+			linterInfo.LinterName,
+			linterInfo.ProductCode,
+		)
 		qodanaYamlConfig = thirdpartyscan.YamlConfig(yaml)
 	}
+	observeMode := ComputeObserveModeOrFatal(cliOptions.Observe, qodanaYamlConfig.EnforceAfter)
 
 	context := thirdpartyscan.ComputeContext(
 		cliOptions,
@@ -121,17 +160,54 @@ func RunThirdPartyLinterAnalysis(
 	logOs(eventsCh, linterInfo, projectIdHash)
 	logProjectOpen(eventsCh, linterInfo, projectIdHash)
 
-	if err = linter.RunAnalysis(context); err != nil {
+	fingerprint.Record(
+		fingerprint.Host(),
+		context.ResultsDir(),
+		context.CacheDir(),
+		fingerprint.Branch(commonCtx.RepositoryRoot, context.LogDir()),
+	)
+	stopAnalysis := timings.Start(timings.Analysis)
+	err = linter.RunAnalysis(context)
+	stopAnalysis()
+	if err != nil {
 		msg.ErrorMessage(err.Error())
 		return 1, err
 	}
 
 	thresholds := getFailureThresholds(context)
+	ignored := applyIgnoreRules(
+		filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
+		context.QodanaYamlConfig().Ignore,
+	)
 	var analysisResult int
 	if analysisResult, err = computeBaselinePrintResults(context, thresholds); err != nil {
 		msg.ErrorMessage(err.Error())
 		return 1, err
 	}
+	if ignored > 0 {
+		analysisResult = exitCodeWithoutIgnored(
+			filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
+			thresholds,
+			analysisResult,
+		)
+	}
+	analysisResult = ApplyLicenseGate(
+		context.ResultsDir(),
+		filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
+		cliOptions.FailOnLicense,
+		analysisResult,
+	)
+	if !qdenv.IsContainer() {
+		projectstate.RecordLastScan(
+			context.ProjectDir(),
+			projectstate.LastScan{
+				Linter:     linterInfo.LinterName,
+				ResultsDir: context.ResultsDir(),
+				ExitCode:   analysisResult,
+				FinishedAt: time.Now(),
+			},
+		)
+	}
 	if qodanaConfigEffectiveFiles.EffectiveQodanaYamlPath != "" {
 		err = copyQodanaYamlToLogDir(qodanaConfigEffectiveFiles.EffectiveQodanaYamlPath, context.LogDir())
 		if err != nil {
@@ -141,8 +217,21 @@ func RunThirdPartyLinterAnalysis(
 	}
 	if context.SaveReport() || context.ShowReport() {
 		commoncontext.SaveReport(context.ResultsDir(), context.ReportDir(), context.CacheDir())
+		ApplyReportBranding(context.ReportDir(), context.ProjectDir(), context.QodanaYamlConfig().Report)
 	}
+	// written before the upload to be sent to Qodana Cloud with the report
+	WriteScanSbom(
+		context.ResultsDir(),
+		context.ProjectDir(),
+		cliOptions.SbomFormat,
+		context.QodanaYamlConfig().SbomExclude,
+	)
 	sendReportToQodanaServer(context)
+	WriteScanMetrics(
+		context.ResultsDir(),
+		filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
+		cliOptions.MetricsFormat,
+	)
 	newReportUrl := cloud.GetReportUrl(context.ResultsDir())
 	ProcessSarif(
 		filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
@@ -152,6 +241,9 @@ func RunThirdPartyLinterAnalysis(
 		context.GenerateCodeClimateReport(),
 		context.SendBitBucketInsights(),
 	)
+	PublishSarif(filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName), context.Publish())
+	ReportToCi(filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName), context.Ci())
+	ReportToOutput(filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName), context.ProjectDir(), cliOptions.Output)
 	err = writeShortSarifReport(context)
 	if err != nil {
 		log.Warnf("Problems writing short SARIF report: %v", err)
@@ -164,7 +256,37 @@ func RunThirdPartyLinterAnalysis(
 		context.ReportDir(),
 		context.ShowReportPort(),
 	)
-	return analysisResult, nil
+	NotifyWebhooks(
+		commonCtx.QodanaSystemDir,
+		context.Webhooks(),
+		filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
+		context.AnalysisId(),
+		newReportUrl,
+		analysisResult,
+		observeMode,
+	)
+	SendNotifications(
+		commonCtx.QodanaSystemDir,
+		context.QodanaYamlConfig().Notifications,
+		filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
+		context.AnalysisId(),
+		newReportUrl,
+		analysisResult,
+		observeMode,
+	)
+	exitCode := ObservedExitCode(analysisResult, observeMode)
+	if exitCode == exitcodes.QodanaFailThresholdExitCode {
+		msg.EmptyMessage()
+		msg.ErrorMessage("The number of problems exceeds the fail threshold")
+	}
+	exitCode = exitCodePolicy.ExitCode(exitCode, filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName))
+	WriteRunMetadata(
+		context.ResultsDir(),
+		filepath.Join(context.ResultsDir(), commoncontext.QodanaSarifName),
+		context.QodanaYamlConfig(),
+		RunMetadata{AnalysisId: context.AnalysisId(), ExitCode: exitCode},
+	)
+	return exitCode, nil
 }
 
 func correctInitArgsForThirdParty(commonCtx commoncontext.Context) (commoncontext.Context, error) {
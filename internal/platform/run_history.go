@@ -0,0 +1,199 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LatestRunLink is the symlink to the directory of the last run in the results directory kept with --keep-runs.
+	LatestRunLink = "latest"
+	// runDirLayout is the name of the run directory, the time the run started.
+	runDirLayout = "20060102-150405"
+)
+
+// HistorySeverities are the severities of the problem counts of the runs, the most severe first.
+var HistorySeverities = []string{severityCritical, severityHigh, severityModerate, severityLow, severityInfo}
+
+// HistoryRun is a run kept in the results directory with --keep-runs.
+type HistoryRun struct {
+	Name string    `json:"name"`
+	Dir  string    `json:"dir"`
+	Time time.Time `json:"time"`
+	// Linter is the tool of the SARIF report, empty if the run has no report.
+	Linter string `json:"linter,omitempty"`
+	// Complete tells if the run has the SARIF report, a failed or an interrupted run doesn't.
+	Complete bool `json:"complete"`
+	// Problems is the number of the problems by severity, suppressed and absent ones are not counted.
+	Problems    map[string]int `json:"problems"`
+	NewProblems map[string]int `json:"newProblems"`
+	// Inspections is the number of the problems by inspection.
+	Inspections map[string]int `json:"inspections"`
+}
+
+// Total is the number of the problems of the run.
+func (r HistoryRun) Total() int {
+	total := 0
+	for _, count := range r.Problems {
+		total += count
+	}
+	return total
+}
+
+// StartHistoryRun makes the run write to a new <results>/<start time> directory with --keep-runs, the oldest runs
+// are removed to keep the given number of runs and the latest symlink is pointed to the new one.
+func StartHistoryRun(c commoncontext.Context, keepRuns int) (commoncontext.Context, error) {
+	if keepRuns <= 0 || qdenv.IsContainer() {
+		return c, nil
+	}
+	if err := os.MkdirAll(c.ResultsDir, os.ModePerm); err != nil {
+		return c, fmt.Errorf("failed to create the results directory %s: %w", c.ResultsDir, err)
+	}
+	runs, err := ListHistoryRuns(c.ResultsDir)
+	if err != nil {
+		return c, err
+	}
+	for len(runs) >= keepRuns {
+		log.Debugf("Removing the run %s, --keep-runs %d", runs[0].Dir, keepRuns)
+		if err = os.RemoveAll(runs[0].Dir); err != nil {
+			return c, fmt.Errorf("failed to remove the old run %s: %w", runs[0].Dir, err)
+		}
+		runs = runs[1:]
+	}
+
+	name := time.Now().Format(runDirLayout)
+	for i := 2; ; i++ {
+		if _, err = os.Stat(filepath.Join(c.ResultsDir, name)); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", time.Now().Format(runDirLayout), i)
+	}
+	runDir := filepath.Join(c.ResultsDir, name)
+	if err = os.MkdirAll(runDir, os.ModePerm); err != nil {
+		return c, fmt.Errorf("failed to create the run directory %s: %w", runDir, err)
+	}
+	latest := filepath.Join(c.ResultsDir, LatestRunLink)
+	if err = os.Remove(latest); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("Failed to update %s: %s", latest, err)
+	} else if err = os.Symlink(name, latest); err != nil {
+		// e.g. Windows without the symlink privilege, the runs are still listed by qodana history
+		log.Warnf("Failed to link %s to the last run: %s", latest, err)
+	}
+
+	if c.ReportDir == filepath.Join(c.ResultsDir, "report") {
+		c.ReportDir = filepath.Join(runDir, "report")
+	}
+	c.ResultsDir = runDir
+	log.Debugf("The results of the run are saved to %s", runDir)
+	return c, nil
+}
+
+// StartHistoryRunOrFatal starts the run of StartHistoryRun before the run writes to the results directory.
+func StartHistoryRunOrFatal(c commoncontext.Context, keepRuns int) commoncontext.Context {
+	c, err := StartHistoryRun(c, keepRuns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
+// ListHistoryRuns returns the runs kept in the results directory, the oldest first.
+func ListHistoryRuns(resultsDir string) ([]HistoryRun, error) {
+	entries, err := os.ReadDir(resultsDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the runs in %s: %w", resultsDir, err)
+	}
+	runs := make([]HistoryRun, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || len(entry.Name()) < len(runDirLayout) {
+			continue
+		}
+		started, err := time.ParseInLocation(runDirLayout, entry.Name()[:len(runDirLayout)], time.Local)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, HistoryRun{Name: entry.Name(), Dir: filepath.Join(resultsDir, entry.Name()), Time: started})
+	}
+	sort.SliceStable(
+		runs, func(i, j int) bool {
+			if !runs[i].Time.Equal(runs[j].Time) {
+				return runs[i].Time.Before(runs[j].Time)
+			}
+			return len(runs[i].Name) < len(runs[j].Name) || len(runs[i].Name) == len(runs[j].Name) && runs[i].Name < runs[j].Name
+		},
+	)
+	return runs, nil
+}
+
+// LoadHistoryRuns returns the runs kept in the results directory with the problem counts of their reports.
+func LoadHistoryRuns(resultsDir string) ([]HistoryRun, error) {
+	runs, err := ListHistoryRuns(resultsDir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range runs {
+		loadHistoryRunReport(&runs[i])
+	}
+	return runs, nil
+}
+
+func loadHistoryRunReport(run *HistoryRun) {
+	loadHistoryRunSarif(run, filepath.Join(run.Dir, commoncontext.QodanaSarifName))
+}
+
+// loadHistoryRunSarif counts the problems of the SARIF report of the run, it returns nil if the run has no report.
+func loadHistoryRunSarif(run *HistoryRun, sarifPath string) *sarif.Report {
+	run.Problems, run.NewProblems, run.Inspections = map[string]int{}, map[string]int{}, map[string]int{}
+	if _, err := os.Stat(sarifPath); err != nil {
+		return nil
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		msg.WarningMessage("Failed to read the report of the run %s: %s", run.Name, err)
+		return nil
+	}
+	run.Complete = true
+	for _, r := range report.Runs {
+		if r.Tool != nil && r.Tool.Driver != nil && run.Linter == "" {
+			run.Linter = r.Tool.Driver.Name
+		}
+	}
+	run.Problems, run.NewProblems = countProblemsBySeverity(report)
+	for _, r := range report.Runs {
+		for i := range r.Results {
+			if _, ok := currentProblemState(&r.Results[i]); ok {
+				run.Inspections[r.Results[i].RuleId]++
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/stretchr/testify/assert"
+)
+
+const historyRunSarif = `{
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {"driver": {"name": "QDJVM"}},
+      "results": [
+        {"ruleId": "A", "message": {"text": "a"}, "properties": {"qodanaSeverity": "Critical"}, "baselineState": "new"},
+        {"ruleId": "B", "message": {"text": "b"}, "properties": {"qodanaSeverity": "High"}, "baselineState": "unchanged"}
+      ]
+    }
+  ]
+}`
+
+func TestStartHistoryRun(t *testing.T) {
+	resultsDir := filepath.Join(t.TempDir(), "results")
+	for _, name := range []string{"20240101-100000", "20240102-100000", "20240103-100000"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(resultsDir, name), os.ModePerm))
+	}
+	c := commoncontext.Context{ResultsDir: resultsDir, ReportDir: filepath.Join(resultsDir, "report")}
+
+	started, err := StartHistoryRun(c, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, resultsDir, filepath.Dir(started.ResultsDir))
+	assert.Equal(t, filepath.Join(started.ResultsDir, "report"), started.ReportDir)
+	assert.DirExists(t, started.ResultsDir)
+	latest, err := os.Readlink(filepath.Join(resultsDir, LatestRunLink))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Base(started.ResultsDir), latest)
+
+	runs, err := ListHistoryRuns(resultsDir)
+	assert.NoError(t, err)
+	names := make([]string, 0, len(runs))
+	for _, run := range runs {
+		names = append(names, run.Name)
+	}
+	assert.Equal(t, []string{"20240102-100000", "20240103-100000", filepath.Base(started.ResultsDir)}, names)
+
+	// the next run started in the same second gets its own directory
+	next, err := StartHistoryRun(c, 3)
+	assert.NoError(t, err)
+	assert.NotEqual(t, started.ResultsDir, next.ResultsDir)
+	latest, err = os.Readlink(filepath.Join(resultsDir, LatestRunLink))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Base(next.ResultsDir), latest)
+}
+
+func TestStartHistoryRunDisabled(t *testing.T) {
+	c := commoncontext.Context{ResultsDir: t.TempDir()}
+	started, err := StartHistoryRun(c, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, c, started)
+}
+
+func TestLoadHistoryRuns(t *testing.T) {
+	resultsDir := t.TempDir()
+	for _, name := range []string{"20240102-100000", "20240101-100000-2", "20240101-100000", "report", "log"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(resultsDir, name), os.ModePerm))
+	}
+	assert.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(resultsDir, "20240102-100000", commoncontext.QodanaSarifName),
+			[]byte(historyRunSarif),
+			0o644,
+		),
+	)
+
+	runs, err := LoadHistoryRuns(resultsDir)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 3)
+	assert.Equal(t, "20240101-100000", runs[0].Name)
+	assert.Equal(t, "20240101-100000-2", runs[1].Name)
+	assert.False(t, runs[1].Complete)
+
+	last := runs[2]
+	assert.True(t, last.Complete)
+	assert.Equal(t, "QDJVM", last.Linter)
+	assert.Equal(t, 2, last.Total())
+	assert.Equal(t, map[string]int{"critical": 1, "high": 1}, last.Problems)
+	assert.Equal(t, map[string]int{"critical": 1}, last.NewProblems)
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/fingerprint"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	log "github.com/sirupsen/logrus"
+)
+
+// RunMetadataFileName is the file in the results directory describing how the results were produced.
+const RunMetadataFileName = "run-metadata.json"
+
+// RunMetadata is the manifest of a scan, to audit and reproduce it: what ran on which revision, with which
+// configuration, in which environment and how long every stage took.
+type RunMetadata struct {
+	CliVersion    string `json:"cliVersion"`
+	AnalysisId    string `json:"analysisId,omitempty"`
+	Linter        string `json:"linter,omitempty"`
+	LinterVersion string `json:"linterVersion,omitempty"`
+	// ImageDigest is the repository digest of the linter image, or its ID if the image was never pushed.
+	ImageDigest  string          `json:"imageDigest,omitempty"`
+	Repository   string          `json:"repository,omitempty"`
+	Branch       string          `json:"branch,omitempty"`
+	Revision     string          `json:"revision,omitempty"`
+	StartedAt    time.Time       `json:"startedAt"`
+	FinishedAt   time.Time       `json:"finishedAt"`
+	TotalSeconds float64         `json:"totalSeconds"`
+	Stages       []timings.Phase `json:"stages"`
+	// ConfigHash is the SHA-256 of the resolved qodana.yaml configuration, after extends and the environment variables.
+	ConfigHash string `json:"configHash"`
+	// Environment is the fingerprint of the machine or the container engine, see fingerprint.FileName.
+	Environment     *fingerprint.Fingerprint `json:"environment,omitempty"`
+	EnvironmentHash string                   `json:"environmentHash,omitempty"`
+	ExitCode        int                      `json:"exitCode"`
+
+	// Command is the command of the scan, "qodana scan" for the scans qodana replay can run again.
+	Command string `json:"command,omitempty"`
+	// Args are the options of the command as --name=value, the secrets are masked.
+	Args []string `json:"args,omitempty"`
+	// QodanaYaml is the qodana.yaml of the scan as written in the project, the secrets are masked.
+	QodanaYaml string `json:"qodanaYaml,omitempty"`
+}
+
+// WriteRunMetadata writes RunMetadataFileName to the results directory, a failure is only reported. The metadata
+// known to the command (the analysis ID, the exit code, the command and its options) is completed with the versions,
+// the revision, the stages, the configuration hash and the environment.
+func WriteRunMetadata(resultsDir string, sarifPath string, config any, metadata RunMetadata) {
+	metadata = newRunMetadata(resultsDir, sarifPath, config, metadata, time.Now())
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(resultsDir, RunMetadataFileName), data, 0o644)
+	}
+	if err != nil {
+		log.Warnf("Failed to write %s: %s", RunMetadataFileName, err)
+	}
+}
+
+// ReadRunMetadata reads the RunMetadataFileName file.
+func ReadRunMetadata(path string) (RunMetadata, error) {
+	var metadata RunMetadata
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return metadata, err
+	}
+	if err = json.Unmarshal(data, &metadata); err != nil {
+		return metadata, fmt.Errorf("%s: %w", path, err)
+	}
+	return metadata, nil
+}
+
+// RunMetadataDrift returns the differences of the current scan from the previous one that can explain different
+// results, as "name: old → new".
+func RunMetadataDrift(previous RunMetadata, current RunMetadata) []string {
+	var changes []string
+	changed := func(name string, from string, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", name, unknownIfEmpty(from), unknownIfEmpty(to)))
+		}
+	}
+	changed("CLI version", previous.CliVersion, current.CliVersion)
+	changed("linter", strings.TrimSpace(previous.Linter+" "+previous.LinterVersion), strings.TrimSpace(current.Linter+" "+current.LinterVersion))
+	changed("image", previous.ImageDigest, current.ImageDigest)
+	changed("revision", previous.Revision, current.Revision)
+	changed("configuration hash", previous.ConfigHash, current.ConfigHash)
+	if previous.Environment != nil && current.Environment != nil {
+		changes = append(changes, fingerprint.Drift(*previous.Environment, *current.Environment)...)
+	}
+	changed("exit code", fmt.Sprint(previous.ExitCode), fmt.Sprint(current.ExitCode))
+	return changes
+}
+
+func unknownIfEmpty(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+func newRunMetadata(resultsDir string, sarifPath string, config any, metadata RunMetadata, now time.Time) RunMetadata {
+	phases, total := timings.Phases()
+	metadata.CliVersion = version.Version
+	metadata.StartedAt = now.Add(-total).UTC()
+	metadata.FinishedAt = now.UTC()
+	metadata.TotalSeconds = total.Seconds()
+	metadata.Stages = phases
+	metadata.ConfigHash = configHash(config)
+	if metadata.Stages == nil {
+		metadata.Stages = []timings.Phase{}
+	}
+	if report, err := ReadReport(sarifPath); err != nil {
+		log.Debugf("Failed to read %s for %s: %s", sarifPath, RunMetadataFileName, err)
+	} else {
+		for _, run := range report.Runs {
+			if metadata.Linter == "" && run.Tool != nil && run.Tool.Driver != nil {
+				metadata.Linter, metadata.LinterVersion = run.Tool.Driver.FullName, run.Tool.Driver.Version
+			}
+			if metadata.Repository == "" && len(run.VersionControlProvenance) > 0 {
+				vcs := run.VersionControlProvenance[0]
+				metadata.Repository, metadata.Branch, metadata.Revision = vcs.RepositoryUri, vcs.Branch, vcs.RevisionId
+			}
+		}
+	}
+	if environment, err := fingerprint.Load(resultsDir); err != nil {
+		log.Debugf("Failed to read the scan environment for %s: %s", RunMetadataFileName, err)
+	} else if environment != nil {
+		metadata.Environment = environment
+		metadata.EnvironmentHash = environment.Hash()
+		metadata.ImageDigest = environment.ImageDigest
+	}
+	return metadata
+}
+
+// configHash returns the SHA-256 of the JSON of the configuration, the same for the equal configurations as the JSON
+// fields are ordered by the struct and the map keys are sorted.
+func configHash(config any) string {
+	data, err := json.Marshal(config)
+	if err != nil {
+		log.Debugf("Failed to hash the configuration: %s", err)
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
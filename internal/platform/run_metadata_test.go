@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/fingerprint"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRunMetadata(t *testing.T) {
+	resultsDir := t.TempDir()
+	sarifPath := filepath.Join(resultsDir, "qodana.sarif.json")
+	assert.NoError(t, os.WriteFile(sarifPath, []byte(sarifFileData), 0o644))
+	environment := fingerprint.Environment{
+		Engine:      "docker",
+		Os:          "linux",
+		Arch:        "x86_64",
+		Cpus:        4,
+		MemoryBytes: 8 << 30,
+		ImageDigest: "jetbrains/qodana-jvm@sha256:0123",
+	}
+	fingerprint.Record(environment, resultsDir, t.TempDir(), "")
+	config := qdyaml.QodanaYaml{Version: "1.0", Linter: "jetbrains/qodana-jvm:2024.3"}
+
+	WriteRunMetadata(
+		resultsDir,
+		sarifPath,
+		config,
+		RunMetadata{AnalysisId: "analysis", ExitCode: 255, Command: "qodana scan", Args: []string{"--print-problems=true"}},
+	)
+	data, err := os.ReadFile(filepath.Join(resultsDir, RunMetadataFileName))
+	assert.NoError(t, err)
+	var metadata RunMetadata
+	assert.NoError(t, json.Unmarshal(data, &metadata))
+
+	assert.Equal(t, version.Version, metadata.CliVersion)
+	assert.Equal(t, "analysis", metadata.AnalysisId)
+	assert.Equal(t, 255, metadata.ExitCode)
+	assert.Equal(t, "qodana scan", metadata.Command)
+	assert.Equal(t, []string{"--print-problems=true"}, metadata.Args)
+	assert.Equal(t, "jetbrains/qodana-jvm@sha256:0123", metadata.ImageDigest)
+	if assert.NotNil(t, metadata.Environment) {
+		assert.Equal(t, "amd64", metadata.Environment.Arch)
+		assert.Equal(t, 8, metadata.Environment.MemoryGb)
+		assert.Equal(t, environment.Fingerprint().Hash(), metadata.EnvironmentHash)
+	}
+	assert.Len(t, metadata.ConfigHash, 64)
+	assert.Equal(t, configHash(config), metadata.ConfigHash)
+	assert.NotEqual(t, configHash(qdyaml.QodanaYaml{Version: "1.0"}), metadata.ConfigHash)
+	assert.NotNil(t, metadata.Stages)
+	assert.False(t, metadata.FinishedAt.Before(metadata.StartedAt))
+}
+
+func TestRunMetadataWithoutReport(t *testing.T) {
+	resultsDir := t.TempDir()
+	metadata := newRunMetadata(resultsDir, filepath.Join(resultsDir, "qodana.sarif.json"), nil, RunMetadata{ExitCode: 1}, time.Now())
+	assert.Equal(t, 1, metadata.ExitCode)
+	assert.Empty(t, metadata.Linter)
+	assert.Nil(t, metadata.Environment)
+	assert.Empty(t, metadata.EnvironmentHash)
+}
+
+func TestRunMetadataDrift(t *testing.T) {
+	environment := fingerprint.Fingerprint{Engine: "docker", Os: "linux", Arch: "amd64", Cpus: 4, MemoryGb: 8}
+	previous := RunMetadata{
+		CliVersion:  "2024.3.1",
+		Linter:      "Qodana for JVM",
+		ImageDigest: "jetbrains/qodana-jvm@sha256:0123",
+		Revision:    "abc",
+		ConfigHash:  "1",
+		Environment: &environment,
+	}
+	assert.Empty(t, RunMetadataDrift(previous, previous))
+
+	changedEnvironment := environment
+	changedEnvironment.Cpus = 2
+	current := previous
+	current.ImageDigest = "jetbrains/qodana-jvm@sha256:4567"
+	current.ConfigHash = "2"
+	current.Environment = &changedEnvironment
+	current.ExitCode = 255
+	assert.Equal(
+		t,
+		[]string{
+			"image: jetbrains/qodana-jvm@sha256:0123 → jetbrains/qodana-jvm@sha256:4567",
+			"configuration hash: 1 → 2",
+			"CPUs: 4 → 2",
+			"exit code: 0 → 255",
+		},
+		RunMetadataDrift(previous, current),
+	)
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/JetBrains/qodana-cli/internal/sarif"
 	"github.com/google/uuid"
 	bbapi "github.com/reviewdog/go-bitbucket"
@@ -50,7 +51,14 @@ const (
 	sarifNote              = "note"
 )
 
+// isNewProblem reports whether the result is a new problem: not in the baseline and not suppressed.
+func isNewProblem(r *sarif.Result) bool {
+	state, _ := r.BaselineState.(string)
+	return (state == baselineStateEmpty || state == baselineStateNew) && len(r.Suppressions) == 0
+}
+
 func MergeSarifReports(c thirdpartyscan.Context, deviceId string) (int, error) {
+	defer timings.Start(timings.Conversion)()
 	tmpResultsDir := GetTmpResultsDir(c.ResultsDir())
 	files, err := findSarifFiles(tmpResultsDir)
 	sort.Strings(files)
@@ -88,6 +96,7 @@ func MergeSarifReports(c thirdpartyscan.Context, deviceId string) (int, error) {
 	finalReport.Runs[0].Results = removeDuplicates(finalReport.Runs[0].Results)
 
 	SetVersionControlParams(c, deviceId, finalReport)
+	AddSecurityTaxonomies(finalReport)
 
 	totalProblems := len(finalReport.Runs[0].Results)
 
@@ -361,10 +370,10 @@ func ProcessSarif(sarifPath, analysisId, reportUrl string, printProblems, codeCl
 			if r.BaselineState != nil {
 				baselineState = r.BaselineState.(string)
 			}
-			if baselineState == baselineStateNew || baselineState == baselineStateEmpty {
+			if isNewProblem(&r) {
 				newProblems++
 			}
-			if len(r.Locations) > 0 && baselineState != baselineStateUnchanged {
+			if len(r.Locations) > 0 && baselineState != baselineStateUnchanged && len(r.Suppressions) == 0 {
 				if codeClimate {
 					codeClimateIssues = append(codeClimateIssues, sarifResultToCodeClimate(&r))
 				}
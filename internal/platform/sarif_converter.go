@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+const (
+	sarifSchema  = "https://raw.githubusercontent.com/schemastore/schemastore/master/src/schemas/json/sarif-2.1.0-rtm.5.json"
+	sarifVersion = "2.1.0"
+)
+
+// ThirdPartyProblem is a problem reported by a third-party linter in its own output format.
+type ThirdPartyProblem struct {
+	RuleId          string
+	RuleDescription string
+	Category        string // Category is the taxon of the rule in the Qodana taxonomy, optional.
+	Message         string
+	File            string // File is either absolute or relative to the project directory.
+	Line            int
+	Column          int
+	Level           string // Level is one of SARIF levels: error, warning or note.
+}
+
+// ConvertToSarif converts problems reported by a third-party linter to a Qodana SARIF report.
+func ConvertToSarif(toolName string, toolVersion string, problems []ThirdPartyProblem) *sarif.Report {
+	rules := make([]sarif.ReportingDescriptor, 0)
+	ruleIndexes := make(map[string]int)
+	taxa := make([]sarif.ReportingDescriptor, 0)
+	taxonIndexes := make(map[string]int)
+	results := make([]sarif.Result, 0, len(problems))
+	occurrences := make(map[string]int)
+
+	for _, p := range problems {
+		level := p.Level
+		if level != sarifError && level != sarifWarning {
+			level = sarifNote
+		}
+		if _, ok := ruleIndexes[p.RuleId]; !ok {
+			description := p.RuleDescription
+			if description == "" {
+				description = p.RuleId
+			}
+			rule := sarif.ReportingDescriptor{
+				Id:                   p.RuleId,
+				ShortDescription:     &sarif.MultiformatMessageString{Text: description},
+				FullDescription:      &sarif.MultiformatMessageString{Text: description},
+				DefaultConfiguration: &sarif.ReportingConfiguration{Enabled: true, Level: level},
+			}
+			if p.Category != "" {
+				if _, ok := taxonIndexes[p.Category]; !ok {
+					taxonIndexes[p.Category] = len(taxa)
+					taxa = append(taxa, sarif.ReportingDescriptor{Id: p.Category, Name: p.Category})
+				}
+				rule.Relationships = []sarif.ReportingDescriptorRelationship{
+					{
+						Kinds: []string{"superset"},
+						Target: &sarif.ReportingDescriptorReference{
+							Id:            p.Category,
+							Index:         int64(taxonIndexes[p.Category]),
+							ToolComponent: &sarif.ToolComponentReference{Name: toolName},
+						},
+					},
+				}
+			}
+			ruleIndexes[p.RuleId] = len(rules)
+			rules = append(rules, rule)
+		}
+
+		uri := filepath.ToSlash(p.File)
+		key := strings.Join([]string{p.RuleId, uri, p.Message}, "\x00")
+		occurrences[key]++
+		fingerprint := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, occurrences[key])))
+
+		results = append(
+			results, sarif.Result{
+				RuleId:    p.RuleId,
+				RuleIndex: int64(ruleIndexes[p.RuleId]),
+				Level:     level,
+				Message:   &sarif.Message{Text: p.Message},
+				Locations: []sarif.Location{
+					{
+						PhysicalLocation: &sarif.PhysicalLocation{
+							ArtifactLocation: &sarif.ArtifactLocation{Uri: uri},
+							Region: &sarif.Region{
+								StartLine:   int64(p.Line),
+								StartColumn: int64(p.Column),
+							},
+						},
+					},
+				},
+				PartialFingerprints: map[string]string{
+					"equalIndicator/v1": hex.EncodeToString(fingerprint[:]),
+				},
+				Properties: &sarif.PropertyBag{
+					AdditionalProperties: map[string]any{
+						"qodanaSeverity": qodanaSeverityOf(level),
+					},
+				},
+			},
+		)
+	}
+
+	return &sarif.Report{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{
+					Driver: &sarif.ToolComponent{
+						Name:    toolName,
+						Version: toolVersion,
+						Rules:   rules,
+						Taxa:    taxa,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+// qodanaSeverityOf maps a SARIF level to the Qodana severity.
+func qodanaSeverityOf(level string) string {
+	switch level {
+	case sarifError:
+		return qodanaHigh
+	case sarifWarning:
+		return qodanaModerate
+	default:
+		return qodanaLow
+	}
+}
@@ -0,0 +1,309 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+// mergedFingerprintKey is the partial fingerprint identifying a result among the results of all merged reports.
+const mergedFingerprintKey = "mergedIndicator/v1"
+
+// MergeSarifFiles merges the SARIF reports into one: the runs of the same tool are merged into one run with the rules
+// of all reports, the results are fingerprinted across the reports and the duplicates are removed.
+// It returns the merged report and the number of removed duplicates.
+func MergeSarifFiles(files []string) (*sarif.Report, int, error) {
+	var merged *sarif.Report
+	for _, file := range files {
+		report, err := ReadReport(file)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if merged == nil {
+			merged = &sarif.Report{Schema: report.Schema, Version: report.Version, Runs: make([]sarif.Run, 0)}
+		}
+		for _, run := range report.Runs {
+			mergeRun(merged, run)
+		}
+	}
+	if merged == nil {
+		return nil, 0, errors.New("no SARIF files to merge")
+	}
+	duplicates := 0
+	for i := range merged.Runs {
+		run := &merged.Runs[i]
+		results := len(run.Results)
+		run.Results = removeMergedDuplicates(run.Results, mergedRunKey(run))
+		duplicates += results - len(run.Results)
+	}
+	return merged, duplicates, nil
+}
+
+// mergedRunKey identifies the runs merged together: the runs of the same tool.
+func mergedRunKey(run *sarif.Run) string {
+	if run.Tool == nil || run.Tool.Driver == nil {
+		return ""
+	}
+	return run.Tool.Driver.Name
+}
+
+// mergeRun adds the run to the run of the same tool in the report, or to a new run.
+func mergeRun(report *sarif.Report, run sarif.Run) {
+	for i := range report.Runs {
+		if mergedRunKey(&report.Runs[i]) == mergedRunKey(&run) {
+			appendRun(&report.Runs[i], run)
+			return
+		}
+	}
+	target := run
+	target.Results = nil
+	target.Artifacts = nil
+	target.Invocations = nil
+	target.VersionControlProvenance = nil
+	if run.Tool != nil {
+		tool := *run.Tool
+		if tool.Driver != nil {
+			driver := *tool.Driver
+			driver.Rules = nil
+			tool.Driver = &driver
+		}
+		tool.Extensions = nil
+		target.Tool = &tool
+	}
+	appendRun(&target, run)
+	report.Runs = append(report.Runs, target)
+}
+
+// appendRun appends the rules, artifacts and results of the source run to the target run,
+// the rule and artifact indices of the results are updated to point to the merged lists.
+func appendRun(target *sarif.Run, source sarif.Run) {
+	if target.Tool == nil {
+		target.Tool = &sarif.Tool{}
+	}
+	if target.Tool.Driver == nil {
+		target.Tool.Driver = &sarif.ToolComponent{}
+	}
+	var sourceExtensions []sarif.ToolComponent
+	if source.Tool != nil {
+		if source.Tool.Driver != nil {
+			target.Tool.Driver.Rules = mergeRules(target.Tool.Driver.Rules, source.Tool.Driver.Rules)
+		}
+		sourceExtensions = source.Tool.Extensions
+		for _, extension := range sourceExtensions {
+			index := extensionIndex(target.Tool.Extensions, extension.Name)
+			if index < 0 {
+				extension.Rules = nil
+				target.Tool.Extensions = append(target.Tool.Extensions, extension)
+				index = len(target.Tool.Extensions) - 1
+			}
+			target.Tool.Extensions[index].Rules = mergeRules(target.Tool.Extensions[index].Rules, extension.Rules)
+		}
+	}
+
+	artifactsOffset := int64(len(target.Artifacts))
+	target.Artifacts = append(target.Artifacts, source.Artifacts...)
+	target.Invocations = append(target.Invocations, source.Invocations...)
+	for _, vcs := range source.VersionControlProvenance {
+		if !containsVersionControlDetails(target.VersionControlProvenance, vcs) {
+			target.VersionControlProvenance = append(target.VersionControlProvenance, vcs)
+		}
+	}
+	for name, location := range source.OriginalUriBaseIds {
+		if target.OriginalUriBaseIds == nil {
+			target.OriginalUriBaseIds = make(map[string]*sarif.ArtifactLocation)
+		}
+		if _, ok := target.OriginalUriBaseIds[name]; !ok {
+			target.OriginalUriBaseIds[name] = location
+		}
+	}
+
+	driverRules := ruleIndices(target.Tool.Driver.Rules)
+	extensionRules := make([]map[string]int, len(target.Tool.Extensions))
+	for i, extension := range target.Tool.Extensions {
+		extensionRules[i] = ruleIndices(extension.Rules)
+	}
+	for _, result := range source.Results {
+		remapRule(&result, target.Tool.Extensions, sourceExtensions, driverRules, extensionRules)
+		for i := range result.Locations {
+			remapArtifact(&result.Locations[i], source.Artifacts, artifactsOffset)
+		}
+		for i := range result.RelatedLocations {
+			remapArtifact(&result.RelatedLocations[i], source.Artifacts, artifactsOffset)
+		}
+		target.Results = append(target.Results, result)
+	}
+}
+
+// mergeRules returns the rules with the new ones appended, the rules are identified by id.
+func mergeRules(rules []sarif.ReportingDescriptor, newRules []sarif.ReportingDescriptor) []sarif.ReportingDescriptor {
+	indices := ruleIndices(rules)
+	for _, rule := range newRules {
+		if _, ok := indices[rule.Id]; !ok {
+			indices[rule.Id] = len(rules)
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func ruleIndices(rules []sarif.ReportingDescriptor) map[string]int {
+	indices := make(map[string]int, len(rules))
+	for i, rule := range rules {
+		if _, ok := indices[rule.Id]; !ok {
+			indices[rule.Id] = i
+		}
+	}
+	return indices
+}
+
+func extensionIndex(extensions []sarif.ToolComponent, name string) int {
+	for i, extension := range extensions {
+		if extension.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsVersionControlDetails(list []sarif.VersionControlDetails, vcs sarif.VersionControlDetails) bool {
+	for _, v := range list {
+		if v.RepositoryUri == vcs.RepositoryUri && v.RevisionId == vcs.RevisionId && v.Branch == vcs.Branch {
+			return true
+		}
+	}
+	return false
+}
+
+// remapRule points the result to its rule in the merged driver or extension.
+func remapRule(
+	result *sarif.Result,
+	extensions []sarif.ToolComponent,
+	sourceExtensions []sarif.ToolComponent,
+	driverRules map[string]int,
+	extensionRules []map[string]int,
+) {
+	id := result.RuleId
+	if id == "" && result.Rule != nil {
+		id = result.Rule.Id
+	}
+	if id == "" {
+		return
+	}
+	if result.Rule != nil && result.Rule.ToolComponent != nil {
+		component := result.Rule.ToolComponent
+		name := component.Name
+		if name == "" && component.Index >= 0 && int(component.Index) < len(sourceExtensions) {
+			name = sourceExtensions[component.Index].Name
+		}
+		if extension := extensionIndex(extensions, name); extension >= 0 {
+			component.Index = int64(extension)
+			if index, ok := extensionRules[extension][id]; ok {
+				result.Rule.Index = int64(index)
+				result.RuleIndex = int64(index)
+			}
+			return
+		}
+	}
+	if index, ok := driverRules[id]; ok {
+		result.RuleIndex = int64(index)
+		if result.Rule != nil && result.Rule.ToolComponent == nil {
+			result.Rule.Index = int64(index)
+		}
+	}
+}
+
+// remapArtifact points the location to its artifact in the merged artifacts list. The index 0 can't be told from
+// the absent one, so it's only remapped for the locations without uri, those resolve the uri from the artifact.
+func remapArtifact(location *sarif.Location, sourceArtifacts []sarif.Artifact, offset int64) {
+	if location.PhysicalLocation == nil || location.PhysicalLocation.ArtifactLocation == nil {
+		return
+	}
+	artifactLocation := location.PhysicalLocation.ArtifactLocation
+	index := artifactLocation.Index
+	if index < 0 || int(index) >= len(sourceArtifacts) || (index == 0 && artifactLocation.Uri != "") {
+		return
+	}
+	if artifactLocation.Uri == "" && sourceArtifacts[index].Location != nil {
+		artifactLocation.Uri = sourceArtifacts[index].Location.Uri
+		artifactLocation.UriBaseId = sourceArtifacts[index].Location.UriBaseId
+	}
+	artifactLocation.Index = index + offset
+}
+
+// removeMergedDuplicates sets the merged fingerprint of the results and removes the results found by several reports.
+func removeMergedDuplicates(results []sarif.Result, runKey string) []sarif.Result {
+	seen := make(map[string]struct{}, len(results))
+	unique := results[:0]
+	for _, result := range results {
+		fingerprint := mergedFingerprint(&result, runKey)
+		if _, ok := seen[fingerprint]; ok {
+			continue
+		}
+		seen[fingerprint] = struct{}{}
+		if result.PartialFingerprints == nil {
+			result.PartialFingerprints = make(map[string]string)
+		}
+		result.PartialFingerprints[mergedFingerprintKey] = fingerprint
+		unique = append(unique, result)
+	}
+	return unique
+}
+
+// mergedFingerprint identifies the result by the tool, rule, file and the fingerprint computed by the linter;
+// the message and the line are used if the linter didn't compute one.
+func mergedFingerprint(result *sarif.Result, runKey string) string {
+	ruleId := result.RuleId
+	if ruleId == "" && result.Rule != nil {
+		ruleId = result.Rule.Id
+	}
+	parts := []string{runKey, ruleId}
+	if len(result.Locations) > 0 && result.Locations[0].PhysicalLocation != nil {
+		physicalLocation := result.Locations[0].PhysicalLocation
+		if physicalLocation.ArtifactLocation != nil {
+			parts = append(parts, physicalLocation.ArtifactLocation.UriBaseId, physicalLocation.ArtifactLocation.Uri)
+		}
+		if physicalLocation.Region != nil && linterFingerprint(result) == "" {
+			parts = append(parts, strconv.Itoa(int(physicalLocation.Region.StartLine)))
+		}
+	}
+	if fingerprint := linterFingerprint(result); fingerprint != "" {
+		parts = append(parts, fingerprint)
+	} else if result.Message != nil && result.Message.Text != "" {
+		parts = append(parts, result.Message.Text)
+	}
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func linterFingerprint(result *sarif.Result) string {
+	for _, key := range []string{"equalIndicator/v2", "equalIndicator/v1"} {
+		if fingerprint, ok := result.PartialFingerprints[key]; ok {
+			return fingerprint
+		}
+	}
+	return ""
+}
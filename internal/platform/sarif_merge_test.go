@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mergeSarifFirst = `{
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {"name": "QDJVM", "rules": [{"id": "UnusedImport"}, {"id": "ConstantValue"}]},
+        "extensions": [{"name": "Kotlin", "rules": [{"id": "RedundantSemicolon"}]}]
+      },
+      "results": [
+        {
+          "ruleId": "ConstantValue",
+          "ruleIndex": 1,
+          "message": {"text": "Condition is always true"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "a/src/Main.java"}, "region": {"startLine": 3}}}],
+          "partialFingerprints": {"equalIndicator/v1": "first"}
+        },
+        {
+          "ruleId": "RedundantSemicolon",
+          "rule": {"id": "RedundantSemicolon", "toolComponent": {"name": "Kotlin"}},
+          "message": {"text": "Redundant semicolon"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "a/src/Main.kt"}}}],
+          "partialFingerprints": {"equalIndicator/v1": "second"}
+        }
+      ]
+    }
+  ]
+}`
+
+const mergeSarifSecond = `{
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {"name": "QDJVM", "rules": [{"id": "ConstantValue"}, {"id": "NullableProblems"}]},
+        "extensions": [{"name": "Kotlin", "rules": [{"id": "UnusedVariable"}, {"id": "RedundantSemicolon"}]}]
+      },
+      "results": [
+        {
+          "ruleId": "NullableProblems",
+          "ruleIndex": 1,
+          "message": {"text": "Not annotated parameter"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "b/src/Util.java"}}}],
+          "partialFingerprints": {"equalIndicator/v1": "third"}
+        },
+        {
+          "ruleId": "RedundantSemicolon",
+          "rule": {"id": "RedundantSemicolon", "index": 1, "toolComponent": {"index": 0}},
+          "message": {"text": "Redundant semicolon"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "b/src/Util.kt"}}}],
+          "partialFingerprints": {"equalIndicator/v1": "fourth"}
+        },
+        {
+          "ruleId": "ConstantValue",
+          "message": {"text": "Condition is always true"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "a/src/Main.java"}, "region": {"startLine": 5}}}],
+          "partialFingerprints": {"equalIndicator/v1": "first"}
+        }
+      ]
+    },
+    {
+      "tool": {"driver": {"name": "QDPY", "rules": [{"id": "PyUnresolvedReferences"}]}},
+      "results": [
+        {
+          "ruleId": "PyUnresolvedReferences",
+          "message": {"text": "Unresolved reference"},
+          "locations": [{"physicalLocation": {"artifactLocation": {"uri": "c/main.py"}}}]
+        }
+      ]
+    }
+  ]
+}`
+
+func TestMergeSarifFiles(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.sarif.json"), filepath.Join(dir, "second.sarif.json")
+	assert.NoError(t, os.WriteFile(first, []byte(mergeSarifFirst), 0o644))
+	assert.NoError(t, os.WriteFile(second, []byte(mergeSarifSecond), 0o644))
+
+	report, duplicates, err := MergeSarifFiles([]string{first, second})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, duplicates)
+	if !assert.Len(t, report.Runs, 2) {
+		return
+	}
+
+	jvm := report.Runs[0]
+	var rules []string
+	for _, rule := range jvm.Tool.Driver.Rules {
+		rules = append(rules, rule.Id)
+	}
+	assert.Equal(t, []string{"UnusedImport", "ConstantValue", "NullableProblems"}, rules)
+	assert.Len(t, jvm.Tool.Extensions, 1)
+	assert.Len(t, jvm.Tool.Extensions[0].Rules, 2)
+
+	if assert.Len(t, jvm.Results, 4) {
+		assert.Equal(t, int64(1), jvm.Results[0].RuleIndex)
+		assert.Equal(t, int64(2), jvm.Results[2].RuleIndex)
+		assert.Equal(t, int64(1), jvm.Results[3].Rule.Index)
+		assert.Equal(t, int64(0), jvm.Results[3].Rule.ToolComponent.Index)
+		for _, result := range jvm.Results {
+			assert.NotEmpty(t, result.PartialFingerprints[mergedFingerprintKey])
+		}
+	}
+
+	python := report.Runs[1]
+	assert.Equal(t, "QDPY", python.Tool.Driver.Name)
+	if assert.Len(t, python.Results, 1) {
+		assert.NotEmpty(t, python.Results[0].PartialFingerprints[mergedFingerprintKey])
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	"github.com/pterm/pterm"
+)
+
+const (
+	SarifQueryFormatTable = "table"
+	SarifQueryFormatJson  = "json"
+	SarifQueryFormatCsv   = "csv"
+
+	// problemStateSuppressed is the state of the suppressed problems, whatever their baseline state is.
+	problemStateSuppressed = "suppressed"
+)
+
+// sarifFilterKeys are the fields of the problems the filters compare.
+var sarifFilterKeys = []string{"severity", "inspection", "path", "state"}
+
+// SarifFilter selects the problems by a field: key=value1,value2 matches any of the values, key!=value1,value2 none
+// of them and severity>=value the severity and the more severe ones.
+type SarifFilter struct {
+	key      string
+	operator string
+	values   []string
+}
+
+// SarifProblem is a problem of the report as printed by qodana show sarif.
+type SarifProblem struct {
+	Severity string `json:"severity"`
+	State    string `json:"state"`
+	RuleId   string `json:"ruleId"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Message  string `json:"message"`
+}
+
+// ParseSarifFilters parses the --filter expressions, e.g. severity>=high, inspection=Kotlin*, path=src/**/*.kt or
+// state=new,unchanged. The problems must match all the filters.
+func ParseSarifFilters(expressions []string) ([]SarifFilter, error) {
+	filters := make([]SarifFilter, 0, len(expressions))
+	for _, expression := range expressions {
+		filter, err := parseSarifFilter(expression)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+func parseSarifFilter(expression string) (SarifFilter, error) {
+	index := strings.IndexAny(expression, "!>=")
+	if index <= 0 {
+		return SarifFilter{}, fmt.Errorf("invalid filter %q, expected <field>=<values>, e.g. severity=critical,high", expression)
+	}
+	key := strings.ToLower(strings.TrimSpace(expression[:index]))
+	rest := expression[index:]
+	var operator string
+	for _, op := range []string{"!=", ">=", "="} {
+		if strings.HasPrefix(rest, op) {
+			operator = op
+			break
+		}
+	}
+	if operator == "" {
+		return SarifFilter{}, fmt.Errorf("invalid filter %q, the operators are =, != and >=", expression)
+	}
+	if !slices.Contains(sarifFilterKeys, key) {
+		return SarifFilter{}, fmt.Errorf(
+			"unknown filter field %q, the fields are %s",
+			key,
+			strings.Join(sarifFilterKeys, ", "),
+		)
+	}
+	var values []string
+	for _, value := range strings.Split(rest[len(operator):], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			if key == "severity" || key == "state" {
+				value = strings.ToLower(value)
+			}
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return SarifFilter{}, fmt.Errorf("no values in the filter %q", expression)
+	}
+	if operator == ">=" {
+		if key != "severity" || len(values) != 1 {
+			return SarifFilter{}, fmt.Errorf("invalid filter %q, >= takes one severity", expression)
+		}
+		if severityRank(values[0]) == len(markdownSeverities) {
+			return SarifFilter{}, fmt.Errorf("unknown severity %q in the filter %q", values[0], expression)
+		}
+	}
+	return SarifFilter{key: key, operator: operator, values: values}, nil
+}
+
+func (f SarifFilter) matches(p SarifProblem) bool {
+	if f.operator == ">=" {
+		return severityRank(p.Severity) <= severityRank(f.values[0])
+	}
+	matched := slices.ContainsFunc(f.values, func(value string) bool { return f.matchesValue(p, value) })
+	return matched == (f.operator == "=")
+}
+
+func (f SarifFilter) matchesValue(p SarifProblem, value string) bool {
+	switch f.key {
+	case "severity":
+		return p.Severity == value
+	case "state":
+		return p.State == value
+	case "inspection":
+		matched, err := path.Match(value, p.RuleId)
+		return err == nil && matched
+	case "path":
+		return p.Path != "" && matchesIgnorePath(p.Path, value)
+	}
+	return false
+}
+
+// QuerySarif returns the problems of the report matching all the filters, from the most severe.
+func QuerySarif(report *sarif.Report, filters []SarifFilter) []SarifProblem {
+	problems := make([]SarifProblem, 0)
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			p := newSarifProblem(&run.Results[i])
+			if !slices.ContainsFunc(filters, func(f SarifFilter) bool { return !f.matches(p) }) {
+				problems = append(problems, p)
+			}
+		}
+	}
+	sort.SliceStable(
+		problems, func(i, j int) bool {
+			a, b := problems[i], problems[j]
+			if severityRank(a.Severity) != severityRank(b.Severity) {
+				return severityRank(a.Severity) < severityRank(b.Severity)
+			}
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			return a.Line < b.Line
+		},
+	)
+	return problems
+}
+
+func newSarifProblem(r *sarif.Result) SarifProblem {
+	state, _ := r.BaselineState.(string)
+	if state == baselineStateEmpty {
+		state = baselineStateNew
+	}
+	if len(r.Suppressions) > 0 {
+		state = problemStateSuppressed
+	}
+	_, line, column := liveProblemLocation(r)
+	p := SarifProblem{
+		Severity: thresholdSeverityOf(getSeverity(r)),
+		State:    state,
+		RuleId:   r.RuleId,
+		Path:     resultFile(r),
+		Line:     line,
+		Column:   column,
+	}
+	if r.Message != nil {
+		p.Message = strings.Join(strings.Fields(r.Message.Text), " ")
+	}
+	return p
+}
+
+// WriteSarifProblems writes the problems as a table, a JSON array or CSV with a header.
+func WriteSarifProblems(w io.Writer, problems []SarifProblem, format string) error {
+	switch format {
+	case SarifQueryFormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(problems)
+	case SarifQueryFormatCsv:
+		writer := csv.NewWriter(w)
+		_ = writer.Write([]string{"severity", "state", "ruleId", "path", "line", "column", "message"})
+		for _, p := range problems {
+			_ = writer.Write(
+				[]string{
+					p.Severity,
+					p.State,
+					p.RuleId,
+					p.Path,
+					strconv.Itoa(p.Line),
+					strconv.Itoa(p.Column),
+					p.Message,
+				},
+			)
+		}
+		writer.Flush()
+		return writer.Error()
+	case SarifQueryFormatTable:
+		if len(problems) == 0 {
+			_, err := fmt.Fprintln(w, "No problems found")
+			return err
+		}
+		tableData := pterm.TableData{{"Severity", "State", "Inspection", "Location", "Message"}}
+		for _, p := range problems {
+			location := p.Path
+			if p.Line > 0 {
+				location = fmt.Sprintf("%s:%d", p.Path, p.Line)
+			}
+			tableData = append(tableData, []string{p.Severity, p.State, p.RuleId, location, p.Message})
+		}
+		table := pterm.DefaultTable.WithHasHeader().WithData(tableData).WithWriter(w)
+		table.HeaderRowSeparator = ""
+		table.Separator = " "
+		table.Boxed = true
+		if err := table.Render(); err != nil {
+			return err
+		}
+		if len(problems) == 1 {
+			_, err := fmt.Fprintln(w, "1 problem")
+			return err
+		}
+		_, err := fmt.Fprintf(w, "%d problems\n", len(problems))
+		return err
+	default:
+		return fmt.Errorf(
+			"unsupported format %q, the supported ones are %s, %s and %s",
+			format,
+			SarifQueryFormatTable,
+			SarifQueryFormatJson,
+			SarifQueryFormatCsv,
+		)
+	}
+}
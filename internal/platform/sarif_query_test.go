@@ -0,0 +1,115 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sarifQueryData = `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}, "results": [
+  {"ruleId": "KotlinUnusedImport", "message": {"text": "Unused import"}, "properties": {"qodanaSeverity": "Low"},
+   "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/main/kotlin/App.kt"}, "region": {"startLine": 3}}}]},
+  {"ruleId": "NullableProblems", "message": {"text": "Nullable\nproblem"}, "properties": {"qodanaSeverity": "Critical"},
+   "baselineState": "unchanged",
+   "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/main/java/Main.java"}, "region": {"startLine": 10}}}]},
+  {"ruleId": "KotlinRedundantSemicolon", "message": {"text": "Redundant semicolon"}, "properties": {"qodanaSeverity": "High"},
+   "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/test/kotlin/AppTest.kt"}, "region": {"startLine": 5}}}]},
+  {"ruleId": "UnusedDeclaration", "message": {"text": "Unused"}, "properties": {"qodanaSeverity": "Moderate"},
+   "baselineState": "absent",
+   "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/main/java/Old.java"}, "region": {"startLine": 1}}}]},
+  {"ruleId": "UnusedDeclaration", "message": {"text": "Unused"}, "properties": {"qodanaSeverity": "Moderate"},
+   "suppressions": [{"kind": "inSource"}],
+   "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/main/java/Main.java"}, "region": {"startLine": 20}}}]}
+]}]}`
+
+func querySarifRuleIds(t *testing.T, expressions ...string) []string {
+	report, err := ReadReportFromString(sarifQueryData)
+	require.NoError(t, err)
+	filters, err := ParseSarifFilters(expressions)
+	require.NoError(t, err)
+	ids := make([]string, 0)
+	for _, p := range QuerySarif(report, filters) {
+		ids = append(ids, p.RuleId)
+	}
+	return ids
+}
+
+func TestQuerySarif(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{
+			"NullableProblems",
+			"KotlinRedundantSemicolon",
+			"UnusedDeclaration",
+			"UnusedDeclaration",
+			"KotlinUnusedImport",
+		},
+		querySarifRuleIds(t),
+	)
+	assert.Equal(t, []string{"NullableProblems", "KotlinRedundantSemicolon"}, querySarifRuleIds(t, "severity>=high"))
+	assert.Equal(t, []string{"NullableProblems", "KotlinUnusedImport"}, querySarifRuleIds(t, "severity=Critical,low"))
+	assert.Equal(t, []string{"KotlinRedundantSemicolon", "KotlinUnusedImport"}, querySarifRuleIds(t, "inspection=Kotlin*"))
+	assert.Equal(
+		t,
+		[]string{"KotlinRedundantSemicolon", "KotlinUnusedImport"},
+		querySarifRuleIds(t, "path=src/**/*.kt"),
+	)
+	assert.Equal(t, []string{"KotlinUnusedImport"}, querySarifRuleIds(t, "path=src/main", "inspection!=NullableProblems,Unused*"))
+	assert.Equal(t, []string{"UnusedDeclaration"}, querySarifRuleIds(t, "state=absent"))
+	assert.Equal(t, []string{"UnusedDeclaration"}, querySarifRuleIds(t, "state=suppressed"))
+	assert.Equal(t, []string{"KotlinRedundantSemicolon", "KotlinUnusedImport"}, querySarifRuleIds(t, "state=new"))
+}
+
+func TestParseSarifFiltersErrors(t *testing.T) {
+	for _, expression := range []string{"severity", "=high", "rule=A", "severity=", "severity>=urgent", "path>=src"} {
+		_, err := ParseSarifFilters([]string{expression})
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestWriteSarifProblems(t *testing.T) {
+	problems := []SarifProblem{
+		{Severity: "high", State: "new", RuleId: "A", Path: "a.kt", Line: 3, Column: 1, Message: "a, with a comma"},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteSarifProblems(&out, problems, SarifQueryFormatJson))
+	var decoded []SarifProblem
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, problems, decoded)
+
+	out.Reset()
+	require.NoError(t, WriteSarifProblems(&out, problems, SarifQueryFormatCsv))
+	assert.Equal(
+		t,
+		"severity,state,ruleId,path,line,column,message\nhigh,new,A,a.kt,3,1,\"a, with a comma\"\n",
+		out.String(),
+	)
+
+	out.Reset()
+	require.NoError(t, WriteSarifProblems(&out, problems, SarifQueryFormatTable))
+	assert.Contains(t, out.String(), "a.kt:3")
+	assert.True(t, strings.HasSuffix(out.String(), "1 problem\n"))
+
+	assert.Error(t, WriteSarifProblems(&out, problems, "xml"))
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	cweTaxonomy   = "CWE"
+	owaspTaxonomy = "OWASP Top 10"
+	securityTag   = "security"
+)
+
+// cweNames are the names of the weaknesses the security inspections detect, from CWE 4.13.
+var cweNames = map[int]string{
+	74:   "Improper Neutralization of Special Elements in Output Used by a Downstream Component ('Injection')",
+	78:   "Improper Neutralization of Special Elements used in an OS Command ('OS Command Injection')",
+	89:   "Improper Neutralization of Special Elements used in an SQL Command ('SQL Injection')",
+	114:  "Process Control",
+	319:  "Cleartext Transmission of Sensitive Information",
+	330:  "Use of Insufficiently Random Values",
+	499:  "Serializable Class Containing Sensitive Data",
+	502:  "Deserialization of Untrusted Data",
+	582:  "Array Declared Public, Final, and Static",
+	1395: "Dependency on Vulnerable Third-Party Component",
+}
+
+// owaspNames are the names of the OWASP Top 10 2021 categories the security inspections belong to.
+var owaspNames = map[string]string{
+	"A02:2021": "Cryptographic Failures",
+	"A03:2021": "Injection",
+	"A06:2021": "Vulnerable and Outdated Components",
+	"A08:2021": "Software and Data Integrity Failures",
+}
+
+// ApplySecurityTaxonomies classifies the security problems of the SARIF report at the path by CWE and OWASP Top 10.
+func ApplySecurityTaxonomies(sarifPath string) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Debugf("The security problems are not classified: %s", err)
+		return
+	}
+	if !AddSecurityTaxonomies(report) {
+		return
+	}
+	if err = WriteReport(sarifPath, report); err != nil {
+		log.Warnf("Failed to write the security classification to %s: %s", sarifPath, err)
+	}
+}
+
+// AddSecurityTaxonomies adds the CWE and OWASP Top 10 taxonomies to the runs with the rules of the security
+// inspections: the rules get the relationships to the taxa and the tags GitHub code scanning and DefectDojo classify
+// the problems by (security, external/cwe/cwe-089, external/owasp/a03:2021). It returns whether the report changed.
+func AddSecurityTaxonomies(report *sarif.Report) bool {
+	changed := false
+	for i := range report.Runs {
+		run := &report.Runs[i]
+		if run.Tool == nil {
+			continue
+		}
+		var components []*sarif.ToolComponent
+		if run.Tool.Driver != nil {
+			components = append(components, run.Tool.Driver)
+		}
+		for j := range run.Tool.Extensions {
+			components = append(components, &run.Tool.Extensions[j])
+		}
+		classified := false
+		for _, component := range components {
+			for j := range component.Rules {
+				if classifySecurityRule(run, &component.Rules[j]) {
+					classified = true
+				}
+			}
+		}
+		if !classified {
+			continue
+		}
+		changed = true
+		if run.Tool.Driver != nil {
+			for _, taxonomy := range run.Taxonomies {
+				if !slices.ContainsFunc(
+					run.Tool.Driver.SupportedTaxonomies,
+					func(r sarif.ToolComponentReference) bool { return r.Name == taxonomy.Name },
+				) {
+					run.Tool.Driver.SupportedTaxonomies = append(
+						run.Tool.Driver.SupportedTaxonomies,
+						sarif.ToolComponentReference{Name: taxonomy.Name},
+					)
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// classifySecurityRule adds the taxa of the rule of a security inspection to the run, it returns whether the rule
+// changed.
+func classifySecurityRule(run *sarif.Run, rule *sarif.ReportingDescriptor) bool {
+	classification, ok := inspections.SecurityClassificationOf(rule.Id)
+	if !ok {
+		return false
+	}
+	tags := []string{securityTag}
+	var targets []sarif.ReportingDescriptorReference
+	for _, cwe := range classification.Cwe {
+		tags = append(tags, fmt.Sprintf("external/cwe/cwe-%03d", cwe))
+		targets = append(targets, taxonReference(run, cweTaxonomy, strconv.Itoa(cwe), cweNames[cwe]))
+	}
+	for _, category := range classification.Owasp {
+		tags = append(tags, "external/owasp/"+strings.ToLower(category))
+		targets = append(targets, taxonReference(run, owaspTaxonomy, category, owaspNames[category]))
+	}
+
+	changed := false
+	if rule.Properties == nil {
+		rule.Properties = &sarif.PropertyBag{}
+	}
+	for _, tag := range tags {
+		if !slices.Contains(rule.Properties.Tags, tag) {
+			rule.Properties.Tags = append(rule.Properties.Tags, tag)
+			changed = true
+		}
+	}
+	for _, target := range targets {
+		related := slices.ContainsFunc(
+			rule.Relationships,
+			func(r sarif.ReportingDescriptorRelationship) bool {
+				return r.Target != nil && r.Target.Id == target.Id && r.Target.ToolComponent != nil &&
+					r.Target.ToolComponent.Name == target.ToolComponent.Name
+			},
+		)
+		if !related {
+			rule.Relationships = append(
+				rule.Relationships,
+				sarif.ReportingDescriptorRelationship{Kinds: []string{"relevant"}, Target: &target},
+			)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// taxonReference returns the reference to the taxon of the taxonomy of the run, the taxonomy and the taxon are added
+// to the run if it doesn't have them.
+func taxonReference(run *sarif.Run, taxonomyName string, id string, name string) sarif.ReportingDescriptorReference {
+	taxonomyIndex := slices.IndexFunc(run.Taxonomies, func(t sarif.ToolComponent) bool { return t.Name == taxonomyName })
+	if taxonomyIndex < 0 {
+		taxonomyIndex = len(run.Taxonomies)
+		run.Taxonomies = append(run.Taxonomies, newSecurityTaxonomy(taxonomyName))
+	}
+	taxonomy := &run.Taxonomies[taxonomyIndex]
+	taxonIndex := slices.IndexFunc(taxonomy.Taxa, func(t sarif.ReportingDescriptor) bool { return t.Id == id })
+	if taxonIndex < 0 {
+		taxonIndex = len(taxonomy.Taxa)
+		taxonomy.Taxa = append(
+			taxonomy.Taxa,
+			sarif.ReportingDescriptor{Id: id, Name: name, ShortDescription: &sarif.MultiformatMessageString{Text: name}},
+		)
+	}
+	return sarif.ReportingDescriptorReference{
+		Id:            id,
+		Index:         int64(taxonIndex),
+		ToolComponent: &sarif.ToolComponentReference{Name: taxonomyName, Index: int64(taxonomyIndex)},
+	}
+}
+
+func newSecurityTaxonomy(name string) sarif.ToolComponent {
+	if name == cweTaxonomy {
+		return sarif.ToolComponent{
+			Name:           cweTaxonomy,
+			Version:        "4.13",
+			Organization:   "MITRE",
+			InformationUri: "https://cwe.mitre.org/data/published/cwe_v4.13.pdf",
+		}
+	}
+	return sarif.ToolComponent{
+		Name:           owaspTaxonomy,
+		Version:        "2021",
+		Organization:   "OWASP",
+		InformationUri: "https://owasp.org/Top10/",
+	}
+}
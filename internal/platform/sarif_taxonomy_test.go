@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/inspections"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddSecurityTaxonomies(t *testing.T) {
+	report := &sarif.Report{
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{
+					Driver: &sarif.ToolComponent{Name: "QDJVM"},
+					Extensions: []sarif.ToolComponent{
+						{
+							Name: "com.intellij",
+							Rules: []sarif.ReportingDescriptor{
+								{Id: "unused"},
+								{Id: "JDBCExecuteWithNonConstantString"},
+								{Id: "VulnerableLibrariesLocal", Properties: &sarif.PropertyBag{Tags: []string{"Security"}}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.True(t, AddSecurityTaxonomies(report))
+
+	run := report.Runs[0]
+	rules := run.Tool.Extensions[0].Rules
+	assert.Nil(t, rules[0].Properties)
+	assert.Empty(t, rules[0].Relationships)
+	assert.Equal(t, []string{"security", "external/cwe/cwe-089", "external/owasp/a03:2021"}, rules[1].Properties.Tags)
+	assert.Equal(
+		t,
+		[]string{"Security", "security", "external/cwe/cwe-1395", "external/owasp/a06:2021"},
+		rules[2].Properties.Tags,
+	)
+
+	assert.Len(t, run.Taxonomies, 2)
+	cwe := run.Taxonomies[0]
+	assert.Equal(t, "CWE", cwe.Name)
+	assert.Equal(t, []string{"89", "1395"}, []string{cwe.Taxa[0].Id, cwe.Taxa[1].Id})
+	assert.Equal(t, "Dependency on Vulnerable Third-Party Component", cwe.Taxa[1].Name)
+	assert.Equal(t, "OWASP Top 10", run.Taxonomies[1].Name)
+	assert.Equal(t, "Vulnerable and Outdated Components", run.Taxonomies[1].Taxa[1].Name)
+	assert.Len(t, run.Tool.Driver.SupportedTaxonomies, 2)
+
+	relationship := rules[2].Relationships[0]
+	assert.Equal(t, "1395", relationship.Target.Id)
+	assert.Equal(t, int64(1), relationship.Target.Index)
+	assert.Equal(t, "CWE", relationship.Target.ToolComponent.Name)
+
+	assert.False(t, AddSecurityTaxonomies(report), "the classification is added once")
+	assert.Len(t, report.Runs[0].Tool.Extensions[0].Rules[1].Relationships, 2)
+}
+
+func TestApplySecurityTaxonomies(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	report := &sarif.Report{
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{
+					Driver: &sarif.ToolComponent{
+						Name:  "QDJVM",
+						Rules: []sarif.ReportingDescriptor{{Id: "UnsecureRandomNumberGeneration"}},
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, WriteReport(sarifPath, report))
+
+	ApplySecurityTaxonomies(sarifPath)
+	written, err := ReadReport(sarifPath)
+	assert.NoError(t, err)
+	assert.Contains(t, written.Runs[0].Tool.Driver.Rules[0].Properties.Tags, "external/cwe/cwe-330")
+	assert.Len(t, written.Runs[0].Taxonomies, 2)
+}
+
+func TestSecurityTaxaNames(t *testing.T) {
+	for _, id := range inspections.SecurityInspections() {
+		classification, _ := inspections.SecurityClassificationOf(id)
+		assert.NotEmpty(t, classification.Cwe, id)
+		for _, cwe := range classification.Cwe {
+			assert.NotEmpty(t, cweNames[cwe], "CWE-%d of %s", cwe, id)
+		}
+		for _, category := range classification.Owasp {
+			assert.NotEmpty(t, owaspNames[category], "%s of %s", category, id)
+		}
+	}
+}
@@ -549,3 +549,33 @@ func TestGetRuleDescription(t *testing.T) {
 		assert.Empty(t, desc)
 	})
 }
+
+func TestConvertToSarif(t *testing.T) {
+	problems := []ThirdPartyProblem{
+		{RuleId: "no-unused-vars", Message: "'a' is unused", File: "src/a.js", Line: 1, Column: 7, Level: sarifError},
+		{RuleId: "no-unused-vars", Message: "'a' is unused", File: "src/a.js", Line: 9, Column: 7, Level: sarifError},
+		{
+			RuleId:          "eqeqeq",
+			RuleDescription: "Require ===",
+			Category:        "ESLint",
+			Message:         "Expected '==='",
+			File:            "src/b.js",
+			Line:            3,
+		},
+	}
+	report := ConvertToSarif("eslint", "9.0.0", problems)
+
+	run := report.Runs[0]
+	assert.Equal(t, "eslint", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, "Require ===", run.Tool.Driver.Rules[1].ShortDescription.Text)
+	assert.Len(t, run.Tool.Driver.Taxa, 1)
+	assert.Equal(t, "ESLint", run.Tool.Driver.Rules[1].Relationships[0].Target.Id)
+	assert.Empty(t, run.Tool.Driver.Rules[0].Relationships)
+	assert.Len(t, run.Results, 3)
+	assert.Equal(t, int64(1), run.Results[2].RuleIndex)
+	assert.Equal(t, qodanaHigh, getSeverity(&run.Results[0]))
+	assert.Equal(t, qodanaLow, getSeverity(&run.Results[2]))
+	assert.NotEqual(t, getFingerprint(&run.Results[0]), getFingerprint(&run.Results[1]))
+	assert.Len(t, removeDuplicates(run.Results), 3)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+)
+
+// https://cyclonedx.org/docs/1.5/json/
+type cdxDocument struct {
+	BomFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     []cdxTool    `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cdxComponent struct {
+	Type               string           `json:"type"`
+	BomRef             string           `json:"bom-ref,omitempty"`
+	Name               string           `json:"name"`
+	Version            string           `json:"version,omitempty"`
+	Purl               string           `json:"purl,omitempty"`
+	Licenses           []cdxLicense     `json:"licenses,omitempty"`
+	ExternalReferences []cdxExternalRef `json:"externalReferences,omitempty"`
+}
+
+type cdxLicense struct {
+	License cdxLicenseChoice `json:"license"`
+}
+
+type cdxLicenseChoice struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	Url  string `json:"url,omitempty"`
+}
+
+type cdxExternalRef struct {
+	Type string `json:"type"`
+	Url  string `json:"url"`
+}
+
+func cycloneDx(project string, dependencies []Dependency, now time.Time) cdxDocument {
+	document := cdxDocument{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: newSerial(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: now.UTC().Format(time.RFC3339),
+			Tools:     []cdxTool{{Vendor: "JetBrains", Name: "qodana-cli", Version: version.Version}},
+			Component: cdxComponent{Type: "application", Name: project},
+		},
+		Components: make([]cdxComponent, 0, len(dependencies)),
+	}
+	for _, d := range dependencies {
+		component := cdxComponent{
+			Type:    "library",
+			BomRef:  d.Name + "@" + d.Version,
+			Name:    d.Name,
+			Version: d.Version,
+			Purl:    d.Purl(),
+		}
+		for _, l := range d.Licenses {
+			if isSpdxId(l.Key) {
+				component.Licenses = append(component.Licenses, cdxLicense{License: cdxLicenseChoice{Id: l.Key}})
+			} else {
+				name := l.Name
+				if name == "" {
+					name = l.Key
+				}
+				component.Licenses = append(component.Licenses, cdxLicense{License: cdxLicenseChoice{Name: name, Url: l.Url}})
+			}
+		}
+		if d.Url != "" {
+			component.ExternalReferences = []cdxExternalRef{{Type: "website", Url: d.Url}}
+		}
+		document.Components = append(document.Components, component)
+	}
+	return document
+}
+
+// https://spdx.github.io/spdx-spec/v2.3/
+type spdxDocument struct {
+	SpdxVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SpdxId            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SpdxId           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	Homepage         string            `json:"homepage,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+const (
+	spdxNoAssertion = "NOASSERTION"
+	spdxDocumentId  = "SPDXRef-DOCUMENT"
+	spdxProjectId   = "SPDXRef-Project"
+)
+
+var (
+	spdxIdPattern      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.+-]*$`)
+	spdxInvalidIdChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+)
+
+func spdx(project string, dependencies []Dependency, now time.Time) spdxDocument {
+	document := spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SpdxId:            spdxDocumentId,
+		Name:              project,
+		DocumentNamespace: "https://www.jetbrains.com/qodana/spdx/" + project + "-" + strings.TrimPrefix(newSerial(), "urn:uuid:"),
+		CreationInfo: spdxCreationInfo{
+			Created:  now.UTC().Format(time.RFC3339),
+			Creators: []string{"Organization: JetBrains", "Tool: " + toolName()},
+		},
+		Packages: []spdxPackage{
+			{
+				Name:             project,
+				SpdxId:           spdxProjectId,
+				DownloadLocation: spdxNoAssertion,
+				LicenseConcluded: spdxNoAssertion,
+				LicenseDeclared:  spdxNoAssertion,
+			},
+		},
+		Relationships: []spdxRelationship{
+			{SpdxElementId: spdxDocumentId, RelationshipType: "DESCRIBES", RelatedSpdxElement: spdxProjectId},
+		},
+	}
+	for i, d := range dependencies {
+		id := fmt.Sprintf("SPDXRef-Package-%d-%s", i+1, strings.Trim(spdxInvalidIdChars.ReplaceAllString(d.Name, "-"), "-"))
+		pkg := spdxPackage{
+			Name:             d.Name,
+			SpdxId:           id,
+			VersionInfo:      d.Version,
+			DownloadLocation: spdxNoAssertion,
+			Homepage:         d.Url,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxLicenseExpression(d.Licenses),
+		}
+		if purl := d.Purl(); purl != "" {
+			pkg.ExternalRefs = []spdxExternalRef{
+				{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: purl},
+			}
+		}
+		document.Packages = append(document.Packages, pkg)
+		document.Relationships = append(
+			document.Relationships,
+			spdxRelationship{SpdxElementId: spdxProjectId, RelationshipType: "DEPENDS_ON", RelatedSpdxElement: id},
+		)
+	}
+	return document
+}
+
+// spdxLicenseExpression returns the licenses of the dependency as an SPDX expression: the dependency is available
+// under any of them. The licenses without an SPDX ID make it NOASSERTION.
+func spdxLicenseExpression(licenses []License) string {
+	keys := make([]string, 0, len(licenses))
+	for _, l := range licenses {
+		if !isSpdxId(l.Key) {
+			return spdxNoAssertion
+		}
+		keys = append(keys, l.Key)
+	}
+	if len(keys) == 0 {
+		return spdxNoAssertion
+	}
+	return strings.Join(keys, " OR ")
+}
+
+// isSpdxId reports whether the license key looks like an SPDX license ID, the license audit uses them for the known
+// licenses and a name for the others.
+func isSpdxId(key string) bool {
+	return key != "" && spdxIdPattern.MatchString(key) && !strings.EqualFold(key, "unknown")
+}
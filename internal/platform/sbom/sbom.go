@@ -0,0 +1,184 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sbom converts the dependencies found by the license audit of a linter to a software bill of materials in
+// the CycloneDX or SPDX JSON format, written to the results directory for the SBOM tooling.
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	"github.com/google/uuid"
+)
+
+// Formats of the SBOM file.
+const (
+	FormatCycloneDx = "cyclonedx"
+	FormatSpdx      = "spdx"
+)
+
+// DependenciesPath is where the license audit of the linters writes the dependencies, relative to the results.
+var DependenciesPath = filepath.Join("projectStructure", "dependencies.json")
+
+// ErrNoDependencies is returned when the linter didn't write the dependencies, e.g. the license audit is disabled.
+var ErrNoDependencies = errors.New("no dependencies found by the license audit")
+
+// License is a license of a dependency, the key is the SPDX ID when the license is a known one.
+type License struct {
+	Key  string `json:"key"`
+	Name string `json:"name,omitempty"`
+	Url  string `json:"url,omitempty"`
+}
+
+// Dependency is a dependency of the project found by the license audit.
+type Dependency struct {
+	Name           string    `json:"name"`
+	Version        string    `json:"version"`
+	Url            string    `json:"url,omitempty"`
+	PackageManager string    `json:"packageManager,omitempty"`
+	Licenses       []License `json:"licenses,omitempty"`
+}
+
+// FileName returns the name of the SBOM file of the format.
+func FileName(format string) string {
+	if format == FormatSpdx {
+		return "qodana.sbom.spdx.json"
+	}
+	return "qodana.sbom.cdx.json"
+}
+
+// ValidateFormat checks the --sbom-format value, empty means no SBOM file.
+func ValidateFormat(format string) error {
+	switch format {
+	case "", FormatCycloneDx, FormatSpdx:
+		return nil
+	default:
+		return fmt.Errorf("unknown SBOM format %q, supported formats: %s, %s", format, FormatCycloneDx, FormatSpdx)
+	}
+}
+
+// ReadDependencies returns the dependencies written by the license audit to the results directory.
+func ReadDependencies(resultsDir string) ([]Dependency, error) {
+	data, err := os.ReadFile(filepath.Join(resultsDir, DependenciesPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoDependencies
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dependencies []Dependency
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &dependencies)
+	} else {
+		var file struct {
+			Dependencies []Dependency `json:"dependencies"`
+		}
+		err = json.Unmarshal(data, &file)
+		dependencies = file.Dependencies
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DependenciesPath, err)
+	}
+	return dependencies, nil
+}
+
+// Exclude returns the dependencies without the ones with the excluded names, the dependencySbomExclude of qodana.yaml.
+func Exclude(dependencies []Dependency, names []string) []Dependency {
+	return slices.DeleteFunc(
+		slices.Clone(dependencies), func(d Dependency) bool {
+			return slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, d.Name) })
+		},
+	)
+}
+
+// Write writes the SBOM of the project with the dependencies in the format to the results directory, it returns
+// the path of the file.
+func Write(resultsDir string, format string, project string, dependencies []Dependency) (string, error) {
+	var document any
+	switch format {
+	case FormatCycloneDx:
+		document = cycloneDx(project, dependencies, time.Now())
+	case FormatSpdx:
+		document = spdx(project, dependencies, time.Now())
+	default:
+		return "", ValidateFormat(format)
+	}
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(resultsDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	path := filepath.Join(resultsDir, FileName(format))
+	return path, os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// purlTypes maps the package managers reported by the license audit to the package URL types.
+var purlTypes = map[string]string{
+	"maven":    "maven",
+	"gradle":   "maven",
+	"npm":      "npm",
+	"yarn":     "npm",
+	"pnpm":     "npm",
+	"pip":      "pypi",
+	"pypi":     "pypi",
+	"poetry":   "pypi",
+	"composer": "composer",
+	"go":       "golang",
+	"golang":   "golang",
+	"nuget":    "nuget",
+	"cargo":    "cargo",
+	"gem":      "gem",
+}
+
+// Purl returns the package URL of the dependency, empty if its package manager is unknown.
+func (d Dependency) Purl() string {
+	purlType, ok := purlTypes[strings.ToLower(d.PackageManager)]
+	if !ok || d.Name == "" {
+		return ""
+	}
+	name := d.Name
+	switch purlType {
+	case "maven":
+		// group:artifact
+		name = strings.Replace(name, ":", "/", 1)
+	case "npm":
+		name = strings.Replace(name, "@", "%40", 1)
+	}
+	purl := "pkg:" + purlType + "/" + name
+	if d.Version != "" {
+		purl += "@" + d.Version
+	}
+	return purl
+}
+
+// toolName is the name of the tool in the SBOM metadata.
+func toolName() string {
+	return "qodana-cli-" + version.Version
+}
+
+func newSerial() string {
+	return "urn:uuid:" + uuid.NewString()
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDependencies = `{"dependencies": [
+  {"name": "org.apache.commons:commons-lang3", "version": "3.12.0", "url": "https://commons.apache.org/lang",
+   "packageManager": "maven", "licenses": [{"key": "Apache-2.0", "name": "Apache License 2.0"}]},
+  {"name": "@types/node", "version": "20.1.0", "packageManager": "npm", "licenses": [{"key": "MIT"}, {"key": "ISC"}]},
+  {"name": "internal-lib", "version": "1.0", "licenses": [{"key": "Custom license", "url": "https://example.com/license"}]},
+  {"name": "excluded", "version": "0.1", "packageManager": "pip"}
+]}`
+
+func writeDependencies(t *testing.T) string {
+	resultsDir := t.TempDir()
+	path := filepath.Join(resultsDir, DependenciesPath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(testDependencies), 0o644))
+	return resultsDir
+}
+
+func TestReadDependencies(t *testing.T) {
+	dependencies, err := ReadDependencies(writeDependencies(t))
+	require.NoError(t, err)
+	require.Len(t, dependencies, 4)
+	assert.Equal(t, "pkg:maven/org.apache.commons/commons-lang3@3.12.0", dependencies[0].Purl())
+	assert.Equal(t, "pkg:npm/%40types/node@20.1.0", dependencies[1].Purl())
+	assert.Equal(t, "", dependencies[2].Purl())
+	assert.Equal(t, "pkg:pypi/excluded@0.1", dependencies[3].Purl())
+
+	assert.Len(t, Exclude(dependencies, []string{"EXCLUDED"}), 3)
+	assert.Len(t, dependencies, 4)
+
+	_, err = ReadDependencies(t.TempDir())
+	assert.ErrorIs(t, err, ErrNoDependencies)
+}
+
+func TestWriteCycloneDx(t *testing.T) {
+	resultsDir := writeDependencies(t)
+	dependencies, err := ReadDependencies(resultsDir)
+	require.NoError(t, err)
+
+	path, err := Write(resultsDir, FormatCycloneDx, "project", dependencies[:3])
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(resultsDir, "qodana.sbom.cdx.json"), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var document cdxDocument
+	require.NoError(t, json.Unmarshal(data, &document))
+	assert.Equal(t, "CycloneDX", document.BomFormat)
+	assert.Equal(t, "project", document.Metadata.Component.Name)
+	require.Len(t, document.Components, 3)
+	assert.Equal(t, []cdxLicense{{License: cdxLicenseChoice{Id: "Apache-2.0"}}}, document.Components[0].Licenses)
+	assert.Equal(t, []cdxExternalRef{{Type: "website", Url: "https://commons.apache.org/lang"}}, document.Components[0].ExternalReferences)
+	assert.Equal(
+		t,
+		[]cdxLicense{{License: cdxLicenseChoice{Name: "Custom license", Url: "https://example.com/license"}}},
+		document.Components[2].Licenses,
+	)
+}
+
+func TestWriteSpdx(t *testing.T) {
+	resultsDir := writeDependencies(t)
+	dependencies, err := ReadDependencies(resultsDir)
+	require.NoError(t, err)
+
+	path, err := Write(resultsDir, FormatSpdx, "project", dependencies[:3])
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var document spdxDocument
+	require.NoError(t, json.Unmarshal(data, &document))
+	assert.Equal(t, "SPDX-2.3", document.SpdxVersion)
+	require.Len(t, document.Packages, 4)
+	assert.Equal(t, "SPDXRef-Package-1-org.apache.commons-commons-lang3", document.Packages[1].SpdxId)
+	assert.Equal(t, "Apache-2.0", document.Packages[1].LicenseDeclared)
+	assert.Equal(t, "MIT OR ISC", document.Packages[2].LicenseDeclared)
+	assert.Equal(t, spdxNoAssertion, document.Packages[3].LicenseDeclared)
+	assert.Len(t, document.Relationships, 4)
+
+	_, err = Write(resultsDir, "xml", "project", dependencies)
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/internal/platform/metrics"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+// SetupMetricsOrFatal checks the --metrics-format value and starts collecting the timings for the metrics file
+// and run-metadata.json.
+func SetupMetricsOrFatal(format string) {
+	if err := metrics.ValidateFormat(format); err != nil {
+		log.Fatal(err)
+	}
+	timings.Collect()
+}
+
+// WriteScanMetrics writes the metrics file of the scan to the results directory, if --metrics-format is set.
+func WriteScanMetrics(resultsDir string, sarifPath string, format string) {
+	if format == "" {
+		return
+	}
+	problems, newProblems, err := CountReportProblems(sarifPath)
+	if err != nil {
+		log.Warnf("Failed to read %s for the metrics: %s", sarifPath, err)
+	}
+	if err := metrics.Write(resultsDir, format, metrics.New(problems, newProblems)); err != nil {
+		log.Warnf("Failed to write %s: %s", metrics.FileName(format), err)
+	}
+}
+
+// CountReportProblems returns the number of the problems of the SARIF report and of the new ones by their threshold
+// severity, the counts are empty if the report can't be read.
+func CountReportProblems(sarifPath string) (map[string]int, map[string]int, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return make(map[string]int), make(map[string]int), err
+	}
+	problems, newProblems := countProblemsBySeverity(report)
+	return problems, newProblems, nil
+}
+
+// countProblemsBySeverity returns the number of the problems of the report and of the new ones by their threshold
+// severity, the problems absent from the current code and the suppressed ones are not counted.
+func countProblemsBySeverity(report *sarif.Report) (map[string]int, map[string]int) {
+	problems, newProblems := make(map[string]int), make(map[string]int)
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			state, ok := currentProblemState(r)
+			if !ok {
+				continue
+			}
+			severity := thresholdSeverityOf(getSeverity(r))
+			problems[severity]++
+			if state != baselineStateUnchanged {
+				newProblems[severity]++
+			}
+		}
+	}
+	return problems, newProblems
+}
+
+// currentProblemState returns the baseline state of the result, false for the problems absent from the current code
+// and the suppressed ones.
+func currentProblemState(r *sarif.Result) (string, bool) {
+	state, _ := r.BaselineState.(string)
+	if state != baselineStateEmpty && state != baselineStateNew && state != baselineStateUnchanged {
+		return state, false
+	}
+	return state, len(r.Suppressions) == 0
+}
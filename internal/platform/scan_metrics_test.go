@@ -0,0 +1,49 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCountProblemsBySeverity(t *testing.T) {
+	report, err := ReadReportFromString(`{
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {"driver": {"name": "QDJVM"}},
+      "results": [
+        {"ruleId": "A", "message": {"text": "a"}, "properties": {"qodanaSeverity": "Critical"}, "baselineState": "new"},
+        {"ruleId": "B", "message": {"text": "b"}, "properties": {"qodanaSeverity": "High"}, "baselineState": "unchanged"},
+        {"ruleId": "C", "message": {"text": "c"}, "level": "error"},
+        {"ruleId": "D", "message": {"text": "d"}, "properties": {"qodanaSeverity": "High"}, "baselineState": "absent"}
+      ]
+    }
+  ]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, newProblems := countProblemsBySeverity(report)
+	if expected := map[string]int{"critical": 1, "high": 2}; !reflect.DeepEqual(expected, problems) {
+		t.Errorf("expected %v got %v", expected, problems)
+	}
+	if expected := map[string]int{"critical": 1, "high": 1}; !reflect.DeepEqual(expected, newProblems) {
+		t.Errorf("expected %v got %v", expected, newProblems)
+	}
+}
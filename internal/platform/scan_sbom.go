@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/internal/platform/sbom"
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidateSbomFormatOrFatal checks the --sbom-format value before the analysis is started.
+func ValidateSbomFormatOrFatal(format string) {
+	if err := sbom.ValidateFormat(format); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// WriteScanSbom writes the SBOM of the dependencies found by the license audit to the results directory, if
+// --sbom-format is set. The dependencies of dependencySbomExclude of qodana.yaml are left out.
+func WriteScanSbom(resultsDir string, projectDir string, format string, excluded []qdyaml.DependencyIgnore) {
+	if format == "" {
+		return
+	}
+	dependencies, err := sbom.ReadDependencies(resultsDir)
+	if errors.Is(err, sbom.ErrNoDependencies) {
+		log.Warnf("The SBOM is not written: %s, check that the linter runs the license audit", err)
+		return
+	}
+	if err != nil {
+		log.Warnf("Failed to read the dependencies for the SBOM: %s", err)
+		return
+	}
+	names := make([]string, 0, len(excluded))
+	for _, dependency := range excluded {
+		names = append(names, dependency.Name)
+	}
+	dependencies = sbom.Exclude(dependencies, names)
+	project := filepath.Base(projectDir)
+	if absolute, err := filepath.Abs(projectDir); err == nil {
+		project = filepath.Base(absolute)
+	}
+	path, err := sbom.Write(resultsDir, format, project, dependencies)
+	if err != nil {
+		log.Warnf("Failed to write %s: %s", sbom.FileName(format), err)
+		return
+	}
+	msg.SuccessMessage("The SBOM of %d dependencies is written to %s", len(dependencies), path)
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+)
+
+// spreadsheetColumns are the header row of the CSV and XLSX reports.
+var spreadsheetColumns = []string{"rule", "severity", "file", "line", "message", "baselineState"}
+
+// spreadsheetColumnWidths are the widths of the XLSX columns in characters.
+var spreadsheetColumnWidths = []int{30, 10, 60, 8, 100, 14}
+
+// SpreadsheetProblems returns all the problems of the report for the CSV and XLSX exports, sorted like qodana show sarif.
+// Only the problems on the changed lines are returned, if they are given.
+func SpreadsheetProblems(report *sarif.Report, changed ChangedLines) []SarifProblem {
+	var problems []SarifProblem
+	for _, p := range QuerySarif(report, nil) {
+		if changed != nil && (p.Path == "" || !changed.contains(p.Path, p.Line)) {
+			continue
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+func spreadsheetRow(p SarifProblem) []string {
+	line := ""
+	if p.Line > 0 {
+		line = strconv.Itoa(p.Line)
+	}
+	return []string{p.RuleId, p.Severity, p.Path, line, p.Message, p.State}
+}
+
+// WriteCsvReport writes the problems as a flat CSV table, one problem per row.
+func WriteCsvReport(w io.Writer, problems []SarifProblem) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write(spreadsheetColumns)
+	for _, p := range problems {
+		_ = writer.Write(spreadsheetRow(p))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteXlsxReport writes the problems as an Excel workbook with a single sheet, the header row is frozen and filtered.
+// The workbook is the minimal SpreadsheetML package with inline strings, there is no shared strings table.
+func WriteXlsxReport(w io.Writer, problems []SarifProblem) error {
+	archive := zip.NewWriter(w)
+	lastCell := fmt.Sprintf("%s%d", xlsxColumn(len(spreadsheetColumns)-1), len(problems)+1)
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRootRels)},
+		{"xl/workbook.xml", []byte(fmt.Sprintf(xlsxWorkbook, lastCell))},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/styles.xml", []byte(xlsxStyles)},
+		{"xl/worksheets/sheet1.xml", xlsxSheet(problems, lastCell)},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = file.Write(part.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func xlsxSheet(problems []SarifProblem, lastCell string) []byte {
+	var sb bytes.Buffer
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sb.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	sb.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	sb.WriteString(`</sheetView></sheetViews>`)
+	sb.WriteString(`<cols>`)
+	for i, width := range spreadsheetColumnWidths {
+		_, _ = fmt.Fprintf(&sb, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+	}
+	sb.WriteString(`</cols><sheetData>`)
+	xlsxRow(&sb, 1, spreadsheetColumns, true)
+	for i, p := range problems {
+		xlsxRow(&sb, i+2, spreadsheetRow(p), false)
+	}
+	sb.WriteString(`</sheetData>`)
+	_, _ = fmt.Fprintf(&sb, `<autoFilter ref="A1:%s"/>`, lastCell)
+	sb.WriteString(`</worksheet>`)
+	return sb.Bytes()
+}
+
+// xlsxRow writes the row of cells, the line column is written as a number to sort the rows by it.
+func xlsxRow(sb *bytes.Buffer, row int, values []string, header bool) {
+	_, _ = fmt.Fprintf(sb, `<row r="%d">`, row)
+	for i, value := range values {
+		cell := fmt.Sprintf("%s%d", xlsxColumn(i), row)
+		switch {
+		case header:
+			_, _ = fmt.Fprintf(sb, `<c r="%s" s="1" t="inlineStr"><is><t>`, cell)
+		case value == "":
+			continue
+		case spreadsheetColumns[i] == "line":
+			_, _ = fmt.Fprintf(sb, `<c r="%s"><v>%s</v></c>`, cell, value)
+			continue
+		default:
+			_, _ = fmt.Fprintf(sb, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, cell)
+		}
+		_ = xml.EscapeText(sb, []byte(value))
+		sb.WriteString(`</t></is></c>`)
+	}
+	sb.WriteString(`</row>`)
+}
+
+func xlsxColumn(index int) string {
+	return string(rune('A' + index))
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Problems" sheetId="1" r:id="rId1"/></sheets>` +
+	`<definedNames><definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">Problems!$A$1:%s</definedName></definedNames>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles has the default cell style and the bold one of the header row.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCsvReport(t *testing.T) {
+	report, err := ReadReportFromString(sarifQueryData)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteCsvReport(&out, SpreadsheetProblems(report, nil)))
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[][]string{
+			{"rule", "severity", "file", "line", "message", "baselineState"},
+			{"NullableProblems", "critical", "src/main/java/Main.java", "10", "Nullable problem", "unchanged"},
+			{"KotlinRedundantSemicolon", "high", "src/test/kotlin/AppTest.kt", "5", "Redundant semicolon", "new"},
+			{"UnusedDeclaration", "moderate", "src/main/java/Main.java", "20", "Unused", "suppressed"},
+			{"UnusedDeclaration", "moderate", "src/main/java/Old.java", "1", "Unused", "absent"},
+			{"KotlinUnusedImport", "low", "src/main/kotlin/App.kt", "3", "Unused import", "new"},
+		},
+		records,
+	)
+}
+
+func TestSpreadsheetProblemsOnChangedLines(t *testing.T) {
+	report, err := ReadReportFromString(sarifQueryData)
+	require.NoError(t, err)
+	changed := ChangedLines{"src/main/java/Main.java": {&git.ChangedRegion{FirstLine: 8, Count: 5}}}
+
+	problems := SpreadsheetProblems(report, changed)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "NullableProblems", problems[0].RuleId)
+	assert.Empty(t, SpreadsheetProblems(report, ChangedLines{"README.md": {&git.ChangedRegion{FirstLine: 1, Count: 1}}}))
+}
+
+func TestWriteXlsxReport(t *testing.T) {
+	report, err := ReadReportFromString(sarifQueryData)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteXlsxReport(&out, SpreadsheetProblems(report, nil)))
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	require.NoError(t, err)
+	parts := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		parts[file.Name], err = io.ReadAll(reader)
+		require.NoError(t, err)
+		_ = reader.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.Contains(t, parts, name)
+		assert.NoError(t, xml.Unmarshal(parts[name], new(any)), name)
+	}
+	assert.Contains(t, string(parts["xl/workbook.xml"]), "Problems!$A$1:F6")
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Value  string `xml:"v"`
+				String string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 6)
+	assert.Equal(t, "baselineState", sheet.Rows[0].Cells[5].String)
+	row := sheet.Rows[1].Cells
+	assert.Equal(t, "A2", row[0].Ref)
+	assert.Equal(t, "NullableProblems", row[0].String)
+	assert.Equal(t, "10", row[3].Value)
+	assert.Equal(t, "unchanged", row[5].String)
+}
@@ -42,19 +42,71 @@ But you can always override qodana.yaml options with the following command-line
 `, linterInfo.LinterPresentableName,
 		),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			log.SetFormatter(&log.TextFormatter{DisableQuote: true, DisableTimestamp: true})
+			if !msg.IsJsonLog() {
+				log.SetFormatter(&log.TextFormatter{DisableQuote: true, DisableTimestamp: true})
+			}
 			exitCode, err := RunThirdPartyLinterAnalysis(*cliOptions, linter, linterInfo)
+			return exitThirdPartyScan(cliOptions, exitCode, err)
+		},
+	}
+
+	computeThirdPartyFlags(c, cliOptions)
+	return c
+}
 
-			log.Debug("exitCode: ", exitCode)
-			if exitCode == exitcodes.QodanaFailThresholdExitCode {
-				msg.EmptyMessage()
-				msg.ErrorMessage("The number of problems exceeds the fail threshold")
-				os.Exit(exitCode)
+// NewExternalLinterScanCommand returns a new instance of the command running a linter described by a manifest.
+func NewExternalLinterScanCommand() *cobra.Command {
+	cliOptions := &platformcmd.CliOptions{}
+	manifest := ""
+	c := &cobra.Command{
+		Use:   "external",
+		Short: "Scan project with an external linter",
+		Long: `Scan a project with a third-party linter described by a manifest and report the results the same way "qodana scan" does.
+
+The manifest is either a path to a YAML file or a name of a manifest registered in <userConfigDir>/JetBrains/Qodana/linters.
+It defines the linter name, the download url of the linter binary with its checksum and signature, the command-line arguments template and the SARIF post-processing.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !msg.IsJsonLog() {
+				log.SetFormatter(&log.TextFormatter{DisableQuote: true, DisableTimestamp: true})
 			}
-			return err
+			m, err := FindExternalLinterManifest(manifest)
+			if err != nil {
+				return err
+			}
+			linter := ExternalLinter{Manifest: m}
+			exitCode, err := RunThirdPartyLinterAnalysis(*cliOptions, linter, linter.LinterInfo())
+			return exitThirdPartyScan(cliOptions, exitCode, err)
 		},
 	}
 
+	c.Flags().StringVar(&manifest, "manifest", "", "Path to or name of the external linter manifest")
+	if err := c.MarkFlagRequired("manifest"); err != nil {
+		log.Fatal(err)
+	}
+	computeThirdPartyFlags(c, cliOptions)
+	return c
+}
+
+// exitThirdPartyScan exits with the exit code of the analysis, the failed analysis exits with the code
+// of --exit-code-policy if it's set, otherwise the error is returned to the command.
+func exitThirdPartyScan(cliOptions *platformcmd.CliOptions, exitCode int, err error) error {
+	log.Debug("exitCode: ", exitCode)
+	if err != nil {
+		policy, _ := ParseExitCodePolicy(cliOptions.ExitCodePolicy)
+		if policy.Failure == nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "error running command: %s\n", err)
+		os.Exit(*policy.Failure)
+	}
+	if exitCode != exitcodes.QodanaSuccessExitCode {
+		os.Exit(exitCode)
+	}
+	return nil
+}
+
+func computeThirdPartyFlags(c *cobra.Command, cliOptions *platformcmd.CliOptions) {
 	err := platformcmd.ComputeFlags(c, cliOptions)
 	if err != nil {
 		log.Fatal("Error while computing flags")
@@ -71,6 +123,4 @@ But you can always override qodana.yaml options with the following command-line
 	if cliOptions.WithinDocker != "" {
 		msg.WarningMessage("Warning: --within-docker option is ignored when running a third-party linter.")
 	}
-
-	return c
 }
@@ -22,6 +22,7 @@ import (
 
 	"github.com/JetBrains/qodana-cli/internal/platform/cmd"
 	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/projectstate"
 )
 
 func ComputeContext(
@@ -40,6 +41,11 @@ func ComputeContext(
 		clangCompileCommands = filepath.Clean(clangCompileCommands)
 	}
 
+	baseline := cliOptions.Baseline
+	if baseline == "" {
+		baseline = projectstate.Baseline(projectDir)
+	}
+
 	return ContextBuilder{
 		LinterInfo:                linterInfo,
 		MountInfo:                 mountInfo,
@@ -56,14 +62,18 @@ func ComputeContext(
 		CdnetProject:              cliOptions.CdnetProject,
 		CdnetConfiguration:        cliOptions.CdnetConfiguration,
 		CdnetPlatform:             cliOptions.CdnetPlatform,
-		NoStatistics:              cliOptions.NoStatistics,
+		NoStatistics:              cliOptions.NoStatistics || cliOptions.Offline,
 		CdnetNoBuild:              cliOptions.CdnetNoBuild,
 		AnalysisId:                cliOptions.AnalysisId,
-		Baseline:                  cliOptions.Baseline,
+		Baseline:                  baseline,
 		BaselineIncludeAbsent:     cliOptions.BaselineIncludeAbsent,
 		FailThreshold:             cliOptions.FailThreshold,
+		FailOn:                    cliOptions.FailOn,
 		GenerateCodeClimateReport: cliOptions.GenerateCodeClimateReport,
-		SendBitBucketInsights:     cliOptions.SendBitBucketInsights,
+		SendBitBucketInsights:     cliOptions.SendBitBucketInsights && !cliOptions.Offline,
+		Ci:                        cliOptions.Ci,
+		Publish:                   cliOptions.Publish,
+		Webhooks:                  cliOptions.Webhooks,
 		SaveReport:                cliOptions.SaveReport,
 		ShowReport:                cliOptions.ShowReport,
 		ShowReportPort:            cliOptions.GetShowReportPort(),
@@ -86,8 +86,12 @@ type Context struct {
 	baseline                  string
 	baselineIncludeAbsent     bool
 	failThreshold             string
+	failOn                    string
 	generateCodeClimateReport bool
 	sendBitBucketInsights     bool
+	ci                        string
+	publish                   []string
+	webhooks                  []string
 	saveReport                bool
 	showReport                bool
 	showReportPort            int
@@ -95,27 +99,46 @@ type Context struct {
 }
 
 type QodanaYamlConfig struct {
+	Prepare           []qdyaml.PrepareStep
 	Bootstrap         string
+	BootstrapRetry    qdyaml.Retry
 	Version           string
 	DotNet            qdyaml.DotNet
 	Includes          []qdyaml.Clude
 	Excludes          []qdyaml.Clude
+	Ignore            []qdyaml.IgnoreRule
+	SbomExclude       []qdyaml.DependencyIgnore
 	FailThreshold     *int
 	FailureConditions qdyaml.FailureConditions
+	EnforceAfter      string
+	Report            *qdyaml.Report
+	Notifications     *qdyaml.Notifications
 }
 
 func YamlConfig(yaml qdyaml.QodanaYaml) QodanaYamlConfig {
 	return QodanaYamlConfig{
+		Prepare:           yaml.Prepare,
 		Bootstrap:         yaml.Bootstrap,
+		BootstrapRetry:    yaml.BootstrapRetry,
 		Version:           yaml.Version,
 		DotNet:            yaml.DotNet,
 		Includes:          yaml.Includes,
 		Excludes:          yaml.Excludes,
+		Ignore:            yaml.Ignore,
+		SbomExclude:       yaml.DependencySbomExclude,
 		FailThreshold:     yaml.FailThreshold,
 		FailureConditions: yaml.FailureConditions,
+		EnforceAfter:      yaml.EnforceAfter,
+		Report:            yaml.Report,
+		Notifications:     yaml.Notifications,
 	}
 }
 
+// BootstrapSteps returns the prepare steps and the bootstrap command to run before the linter.
+func (y QodanaYamlConfig) BootstrapSteps() []qdyaml.PrepareStep {
+	return qdyaml.BootstrapSteps(y.Prepare, y.Bootstrap, y.BootstrapRetry)
+}
+
 type ContextBuilder struct {
 	LinterInfo                LinterInfo
 	MountInfo                 MountInfo
@@ -138,8 +161,12 @@ type ContextBuilder struct {
 	Baseline                  string
 	BaselineIncludeAbsent     bool
 	FailThreshold             string
+	FailOn                    string
 	GenerateCodeClimateReport bool
 	SendBitBucketInsights     bool
+	Ci                        string
+	Publish                   []string
+	Webhooks                  []string
 	SaveReport                bool
 	ShowReport                bool
 	ShowReportPort            int
@@ -170,7 +197,11 @@ func (b ContextBuilder) Build() Context {
 		baselineIncludeAbsent:     b.BaselineIncludeAbsent,
 		generateCodeClimateReport: b.GenerateCodeClimateReport,
 		sendBitBucketInsights:     b.SendBitBucketInsights,
+		ci:                        b.Ci,
+		publish:                   b.Publish,
+		webhooks:                  b.Webhooks,
 		failThreshold:             b.FailThreshold,
+		failOn:                    b.FailOn,
 		saveReport:                b.SaveReport,
 		showReport:                b.ShowReport,
 		showReportPort:            b.ShowReportPort,
@@ -198,8 +229,12 @@ func (c Context) AnalysisId() string                    { return c.analysisId }
 func (c Context) Baseline() string                      { return c.baseline }
 func (c Context) BaselineIncludeAbsent() bool           { return c.baselineIncludeAbsent }
 func (c Context) FailThreshold() string                 { return c.failThreshold }
+func (c Context) FailOn() string                        { return c.failOn }
 func (c Context) GenerateCodeClimateReport() bool       { return c.generateCodeClimateReport }
 func (c Context) SendBitBucketInsights() bool           { return c.sendBitBucketInsights }
+func (c Context) Ci() string                            { return c.ci }
+func (c Context) Publish() []string                     { return c.publish }
+func (c Context) Webhooks() []string                    { return c.webhooks }
 func (c Context) SaveReport() bool                      { return c.saveReport }
 func (c Context) ShowReport() bool                      { return c.showReport }
 func (c Context) ShowReportPort() int                   { return c.showReportPort }
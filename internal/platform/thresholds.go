@@ -19,8 +19,14 @@ package platform
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
 )
 
 const severityAny = "any"
@@ -32,36 +38,134 @@ const severityInfo = "info"
 
 func getFailureThresholds(c thirdpartyscan.Context) map[string]string {
 	yaml := c.QodanaYamlConfig()
+	return failureThresholds(yaml.FailThreshold, yaml.FailureConditions, c.FailThreshold(), c.FailOn())
+}
+
+// failureThresholds returns the fail thresholds by severity from qodana.yaml, overridden by the --fail-threshold and
+// --fail-on console options.
+func failureThresholds(
+	failThreshold *int,
+	conditions qdyaml.FailureConditions,
+	cliFailThreshold string,
+	cliFailOn string,
+) map[string]string {
 	ret := make(map[string]string)
-	if yaml.FailThreshold != nil {
-		ret[severityAny] = strconv.Itoa(*yaml.FailThreshold)
+	if failThreshold != nil {
+		ret[severityAny] = strconv.Itoa(*failThreshold)
 	}
-	if yaml.FailureConditions.SeverityThresholds != nil {
-		thresholds := *yaml.FailureConditions.SeverityThresholds
-		if thresholds.Any != nil {
-			ret[severityAny] = strconv.Itoa(*thresholds.Any)
-		}
-		if thresholds.Critical != nil {
-			ret[severityCritical] = strconv.Itoa(*thresholds.Critical)
-		}
-		if thresholds.High != nil {
-			ret[severityHigh] = strconv.Itoa(*thresholds.High)
-		}
-		if thresholds.Moderate != nil {
-			ret[severityModerate] = strconv.Itoa(*thresholds.Moderate)
+	if conditions.SeverityThresholds != nil {
+		addSeverityThresholds(ret, *conditions.SeverityThresholds)
+	}
+	if cliFailThreshold != "" || cliFailOn != "" { // console options override the behavior
+		ret = make(map[string]string)
+	}
+	if cliFailThreshold != "" {
+		ret[severityAny] = cliFailThreshold
+	}
+	if cliFailOn != "" {
+		// --fail-on is validated before the analysis
+		thresholds, _ := qdyaml.ParseSeverityThresholds(cliFailOn)
+		addSeverityThresholds(ret, thresholds)
+	}
+	return ret
+}
+
+func addSeverityThresholds(ret map[string]string, thresholds qdyaml.SeverityThresholds) {
+	if thresholds.Any != nil {
+		ret[severityAny] = strconv.Itoa(*thresholds.Any)
+	}
+	if thresholds.Critical != nil {
+		ret[severityCritical] = strconv.Itoa(*thresholds.Critical)
+	}
+	if thresholds.High != nil {
+		ret[severityHigh] = strconv.Itoa(*thresholds.High)
+	}
+	if thresholds.Moderate != nil {
+		ret[severityModerate] = strconv.Itoa(*thresholds.Moderate)
+	}
+	if thresholds.Low != nil {
+		ret[severityLow] = strconv.Itoa(*thresholds.Low)
+	}
+	if thresholds.Info != nil {
+		ret[severityInfo] = strconv.Itoa(*thresholds.Info)
+	}
+}
+
+// ParseFailOnOrFatal validates --fail-on before the analysis is started.
+func ParseFailOnOrFatal(failOn string) {
+	if _, err := qdyaml.ParseSeverityThresholds(failOn); err != nil {
+		log.Fatalf("Invalid --fail-on value: %s", err)
+	}
+}
+
+// ApplyFailOn checks the new problems of the SARIF report against the --fail-on thresholds, for the linters evaluating
+// only their own quality gates. It returns the fail threshold exit code if a threshold is exceeded.
+func ApplyFailOn(sarifPath string, failOn string, exitCode int) int {
+	if failOn == "" || exitCode != exitcodes.QodanaSuccessExitCode {
+		return exitCode
+	}
+	thresholds, err := qdyaml.ParseSeverityThresholds(failOn)
+	if err != nil {
+		log.Fatalf("Invalid --fail-on value: %s", err)
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Warnf("Failed to read %s to check --fail-on: %s", sarifPath, err)
+		return exitCode
+	}
+	exceeded := exceededSeverityThresholds(report, thresholds)
+	if len(exceeded) == 0 {
+		return exitCode
+	}
+	for _, problem := range exceeded {
+		msg.ErrorMessage(problem)
+	}
+	return exitcodes.QodanaFailThresholdExitCode
+}
+
+// exceededSeverityThresholds returns the descriptions of the thresholds exceeded by the new problems of the report.
+func exceededSeverityThresholds(report *sarif.Report, thresholds qdyaml.SeverityThresholds) []string {
+	limits := make(map[string]string)
+	addSeverityThresholds(limits, thresholds)
+	return exceededThresholds(report, limits)
+}
+
+// exceededThresholds returns the descriptions of the limits by severity exceeded by the new problems of the report.
+func exceededThresholds(report *sarif.Report, limits map[string]string) []string {
+	counts := make(map[string]int)
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if !isNewProblem(r) {
+				continue
+			}
+			counts[severityAny]++
+			counts[thresholdSeverityOf(getSeverity(r))]++
 		}
-		if thresholds.Low != nil {
-			ret[severityLow] = strconv.Itoa(*thresholds.Low)
+	}
+	exceeded := make([]string, 0)
+	for _, severity := range []string{severityAny, severityCritical, severityHigh, severityModerate, severityLow, severityInfo} {
+		limit, ok := limits[severity]
+		if !ok {
+			continue
 		}
-		if thresholds.Info != nil {
-			ret[severityInfo] = strconv.Itoa(*thresholds.Info)
+		if n, _ := strconv.Atoi(limit); counts[severity] > n {
+			exceeded = append(
+				exceeded,
+				fmt.Sprintf("The number of %s problems (%d) exceeds the threshold %d", severity, counts[severity], n),
+			)
 		}
 	}
-	if c.FailThreshold() != "" { // console option overrides the behavior
-		ret = make(map[string]string)
-		ret[severityAny] = c.FailThreshold()
+	return exceeded
+}
+
+// thresholdSeverityOf returns the threshold severity of the Qodana severity or SARIF level of a result.
+func thresholdSeverityOf(severity string) string {
+	switch severity {
+	case sarifError, sarifWarning, sarifNote:
+		severity = qodanaSeverityOf(severity)
 	}
-	return ret
+	return strings.ToLower(severity)
 }
 
 func thresholdsToArgs(thresholds map[string]string) []string {
@@ -19,6 +19,7 @@ package platform
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"testing"
 
@@ -31,6 +32,7 @@ func TestFailureThresholds(t *testing.T) {
 		name     string
 		yaml     string
 		option   string
+		failOn   string
 		expected string
 	}{
 		{
@@ -102,6 +104,22 @@ failThreshold: 123
 			option:   "123",
 			expected: " --threshold-any=123",
 		},
+		{
+			name: "fail-on overrides yaml settings",
+			yaml: `failureConditions:
+  severityThresholds:
+    any: 1
+    moderate: 4
+`,
+			failOn:   "critical=0,high=5",
+			expected: " --threshold-critical=0 --threshold-high=5",
+		},
+		{
+			name:     "fail-on with fail-threshold",
+			option:   "10",
+			failOn:   "critical=0",
+			expected: " --threshold-any=10 --threshold-critical=0",
+		},
 	} {
 		t.Run(
 			testData.name, func(t *testing.T) {
@@ -119,6 +137,7 @@ failThreshold: 123
 				yaml := qdyaml.TestOnlyLoadLocalNotEffectiveQodanaYaml(tempDir, "qodana.yaml")
 				c := thirdpartyscan.ContextBuilder{
 					FailThreshold:    testData.option,
+					FailOn:           testData.failOn,
 					QodanaYamlConfig: thirdpartyscan.YamlConfig(yaml),
 				}.Build()
 				thresholds := getFailureThresholds(c)
@@ -136,3 +155,47 @@ failThreshold: 123
 		)
 	}
 }
+
+func TestExceededSeverityThresholds(t *testing.T) {
+	report, err := ReadReportFromString(`{
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {"driver": {"name": "QDJVM"}},
+      "results": [
+        {"ruleId": "A", "message": {"text": "a"}, "properties": {"qodanaSeverity": "Critical"}},
+        {"ruleId": "B", "message": {"text": "b"}, "properties": {"qodanaSeverity": "High"}},
+        {"ruleId": "C", "message": {"text": "c"}, "level": "error"},
+        {"ruleId": "D", "message": {"text": "d"}, "properties": {"qodanaSeverity": "Critical"}, "baselineState": "unchanged"},
+        {"ruleId": "E", "message": {"text": "e"}, "properties": {"qodanaSeverity": "Info"}}
+      ]
+    }
+  ]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, testData := range []struct {
+		failOn   string
+		expected []string
+	}{
+		{"critical=1,high=2,info=5", []string{}},
+		{
+			"critical=0,high=1",
+			[]string{
+				"The number of critical problems (1) exceeds the threshold 0",
+				"The number of high problems (2) exceeds the threshold 1",
+			},
+		},
+		{"any=3,moderate=0", []string{"The number of any problems (4) exceeds the threshold 3"}},
+	} {
+		thresholds, err := qdyaml.ParseSeverityThresholds(testData.failOn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := exceededSeverityThresholds(report, thresholds)
+		if !reflect.DeepEqual(testData.expected, actual) {
+			t.Errorf("%s: expected %v got %v", testData.failOn, testData.expected, actual)
+		}
+	}
+}
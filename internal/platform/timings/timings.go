@@ -0,0 +1,260 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package timings measures how long the CLI spends in each phase of a command, enabled by the --timings flag.
+package timings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/tracing"
+	"github.com/pterm/pterm"
+)
+
+// Phases of a command reported in the timings summary.
+const (
+	ConfigResolution = "Configuration resolution"
+	ImagePull        = "Image pull"
+	ToolExtraction   = "Tool extraction"
+	Analysis         = "Analysis"
+	Conversion       = "Report conversion"
+	Baseline         = "Baseline"
+	Upload           = "Upload"
+)
+
+// MetadataFileName is the file in the results directory the timings are written to.
+const MetadataFileName = "scan-metadata.json"
+
+// Phase is the time spent in a phase, excluding the nested phases.
+type Phase struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+type frame struct {
+	phase string
+	since time.Time
+}
+
+type recorder struct {
+	mu         sync.Mutex
+	enabled    bool
+	collecting bool
+	finished   bool
+	started    time.Time
+	stack      []frame
+	totals     map[string]time.Duration
+	order      []string
+}
+
+var current = newRecorder()
+
+func newRecorder() *recorder {
+	return &recorder{totals: make(map[string]time.Duration)}
+}
+
+// Enable starts collecting the timings of the current command.
+func Enable() {
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	current.enabled = true
+	current.started = time.Now()
+}
+
+// Collect starts collecting the timings of the current command without printing them, e.g. for the metrics file.
+func Collect() {
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	current.collecting = true
+	if current.started.IsZero() {
+		current.started = time.Now()
+	}
+}
+
+// Phases returns the timings collected so far, the running phase is measured until now.
+func Phases() ([]Phase, time.Duration) {
+	return current.phases(time.Now())
+}
+
+// Start measures the phase until the returned function is called, usually as `defer timings.Start(phase)()`.
+// A phase started inside another one pauses the outer phase, so the phases never overlap.
+// The phase is traced as well when OpenTelemetry tracing is enabled.
+func Start(phase string) func() {
+	endSpan := tracing.Start(phase)
+	stop := current.start(phase, time.Now)
+	return func() {
+		stop()
+		endSpan()
+	}
+}
+
+// Finish prints the timings summary and writes it to scan-metadata.json in resultsDir, if it's not empty.
+// Only the first call has an effect.
+func Finish(resultsDir string) {
+	phases, total, ok := current.finish(time.Now())
+	if !ok {
+		return
+	}
+	printPhases(phases, total)
+	if resultsDir == "" {
+		return
+	}
+	if err := writeMetadata(filepath.Join(resultsDir, MetadataFileName), phases, total); err != nil {
+		msg.WarningMessage("Failed to write timings to %s: %s", MetadataFileName, err)
+	}
+}
+
+func (r *recorder) start(phase string, now func() time.Time) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.enabled && !r.collecting {
+		return func() {}
+	}
+	t := now()
+	r.pauseTop(t)
+	r.stack = append(r.stack, frame{phase: phase, since: t})
+	return func() {
+		r.stop(phase, now())
+	}
+}
+
+func (r *recorder) stop(phase string, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		if r.stack[i].phase != phase {
+			continue
+		}
+		if i == len(r.stack)-1 {
+			r.pauseTop(t)
+		}
+		r.stack = append(r.stack[:i], r.stack[i+1:]...)
+		if len(r.stack) > 0 {
+			r.stack[len(r.stack)-1].since = t
+		}
+		return
+	}
+}
+
+// pauseTop adds the time since the innermost phase was (re)started to its total.
+func (r *recorder) pauseTop(t time.Time) {
+	if len(r.stack) == 0 {
+		return
+	}
+	top := r.stack[len(r.stack)-1]
+	if _, ok := r.totals[top.phase]; !ok {
+		r.order = append(r.order, top.phase)
+	}
+	r.totals[top.phase] += t.Sub(top.since)
+}
+
+func (r *recorder) finish(t time.Time) ([]Phase, time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.enabled || r.finished {
+		return nil, 0, false
+	}
+	r.finished = true
+	r.pauseTop(t)
+	return phaseList(r.order, r.totals), t.Sub(r.started), true
+}
+
+func (r *recorder) phases(t time.Time) ([]Phase, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.enabled && !r.collecting {
+		return nil, 0
+	}
+	order := slices.Clone(r.order)
+	totals := maps.Clone(r.totals)
+	if len(r.stack) > 0 && !r.finished {
+		top := r.stack[len(r.stack)-1]
+		if _, ok := totals[top.phase]; !ok {
+			order = append(order, top.phase)
+		}
+		totals[top.phase] += t.Sub(top.since)
+	}
+	return phaseList(order, totals), t.Sub(r.started)
+}
+
+func phaseList(order []string, totals map[string]time.Duration) []Phase {
+	phases := make([]Phase, 0, len(order))
+	for _, phase := range order {
+		phases = append(phases, Phase{Name: phase, Seconds: totals[phase].Seconds()})
+	}
+	return phases
+}
+
+func printPhases(phases []Phase, total time.Duration) {
+	if msg.IsJsonLog() {
+		msg.PrintJsonLog("timings", map[string]any{"phases": phases, "totalSeconds": total.Seconds()})
+		return
+	}
+	tableData := pterm.TableData{
+		[]string{
+			msg.PrimaryBold("Phase"),
+			msg.PrimaryBold("Time"),
+		},
+	}
+	for _, phase := range phases {
+		tableData = append(tableData, []string{phase.Name, fmt.Sprintf("%.1fs", phase.Seconds)})
+	}
+	tableData = append(tableData, []string{msg.PrimaryBold("Total"), msg.PrimaryBold("%.1fs", total.Seconds())})
+
+	msg.EmptyMessage()
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	err := table.Render()
+	if err != nil {
+		return
+	}
+}
+
+// writeMetadata adds the timings to the metadata file, keeping the other fields written to it.
+func writeMetadata(path string, phases []Phase, total time.Duration) error {
+	metadata := make(map[string]any)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err = json.Unmarshal(data, &metadata); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	metadata["timings"] = map[string]any{
+		"phases":       phases,
+		"totalSeconds": total.Seconds(),
+	}
+	data, err = json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
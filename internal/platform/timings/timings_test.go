@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timings
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNestedPhases(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	now := func() time.Time { return clock }
+	r := newRecorder()
+	r.enabled = true
+	r.started = start
+
+	stopAnalysis := r.start(Analysis, now)
+	clock = clock.Add(10 * time.Second)
+	stopExtraction := r.start(ToolExtraction, now)
+	clock = clock.Add(2 * time.Second)
+	stopExtraction()
+	clock = clock.Add(5 * time.Second)
+	stopAnalysis()
+	clock = clock.Add(time.Second)
+	r.start(Baseline, now)()
+
+	phases, total, ok := r.finish(clock.Add(3 * time.Second))
+	assert.True(t, ok)
+	assert.Equal(
+		t, []Phase{
+			{Name: Analysis, Seconds: 15},
+			{Name: ToolExtraction, Seconds: 2},
+			{Name: Baseline, Seconds: 0},
+		}, phases,
+	)
+	assert.Equal(t, 21*time.Second, total)
+
+	_, _, ok = r.finish(clock)
+	assert.False(t, ok)
+}
+
+func TestDisabledRecorder(t *testing.T) {
+	r := newRecorder()
+	r.start(Analysis, time.Now)()
+	_, _, ok := r.finish(time.Now())
+	assert.False(t, ok)
+	assert.Empty(t, r.totals)
+}
+
+func TestCollectedPhases(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	now := func() time.Time { return clock }
+	r := newRecorder()
+	r.collecting = true
+	r.started = start
+
+	r.start(ImagePull, now)()
+	clock = clock.Add(4 * time.Second)
+	r.start(Analysis, now)
+	clock = clock.Add(6 * time.Second)
+
+	phases, total := r.phases(clock)
+	assert.Equal(t, []Phase{{Name: ImagePull, Seconds: 0}, {Name: Analysis, Seconds: 6}}, phases)
+	assert.Equal(t, 10*time.Second, total)
+
+	// the timings collected for the metrics file are not printed
+	_, _, ok := r.finish(clock)
+	assert.False(t, ok)
+}
+
+func TestWriteMetadataKeepsOtherFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), MetadataFileName)
+	err := os.WriteFile(path, []byte(`{"analysisId": "42"}`), 0o644)
+	assert.NoError(t, err)
+
+	err = writeMetadata(path, []Phase{{Name: Upload, Seconds: 1.5}}, 2*time.Second)
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var metadata map[string]any
+	assert.NoError(t, json.Unmarshal(data, &metadata))
+	assert.Equal(t, "42", metadata["analysisId"])
+	assert.Equal(
+		t, map[string]any{
+			"phases":       []any{map[string]any{"name": Upload, "seconds": 1.5}},
+			"totalSeconds": 2.0,
+		}, metadata["timings"],
+	)
+}
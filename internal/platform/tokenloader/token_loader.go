@@ -17,7 +17,9 @@
 package tokenloader
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/JetBrains/qodana-cli/internal/cloud"
@@ -25,13 +27,19 @@ import (
 	"github.com/JetBrains/qodana-cli/internal/platform/msg"
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/redact"
 	"github.com/JetBrains/qodana-cli/internal/platform/utils"
 	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
 	"github.com/zalando/go-keyring"
 )
 
-const keyringDefaultService = "qodana-cli"
+const (
+	keyringDefaultService = "qodana-cli"
+	// keyringGlobalTokenId is the keyring id of the token saved with qodana token set, it is used by all projects
+	// without a token of their own.
+	keyringGlobalTokenId = "global"
+)
 
 type CloudTokenLoader interface {
 	GetQodanaToken() string
@@ -45,6 +53,9 @@ type CloudTokenLoader interface {
 }
 
 func IsCloudTokenRequired(tokenLoader CloudTokenLoader) bool {
+	if qdenv.IsOffline() {
+		return false
+	}
 	if tokenLoader.GetQodanaToken() != "" || os.Getenv(qdenv.QodanaLicenseOnlyToken) != "" {
 		return true
 	}
@@ -58,8 +69,13 @@ func IsCloudTokenRequired(tokenLoader CloudTokenLoader) bool {
 }
 
 func LoadCloudUploadToken(tokenLoader CloudTokenLoader, refresh bool, requiresToken bool, interactive bool) string {
+	if qdenv.IsOffline() {
+		log.Debug("Qodana Cloud token is not used in the offline mode")
+		return ""
+	}
 	tokenFetchers := []func(bool) string{
 		func(_ bool) string { return tokenLoader.GetQodanaToken() },
+		func(_ bool) string { return getTokenFromOidc() },
 		func(refresh bool) string { return getTokenFromKeychain(refresh, tokenLoader.GetId()) },
 	}
 	if interactive && requiresToken {
@@ -76,6 +92,8 @@ func LoadCloudUploadToken(tokenLoader CloudTokenLoader, refresh bool, requiresTo
 
 	for _, fetcher := range tokenFetchers {
 		if token := fetcher(refresh); token != "" {
+			// the token can come from the keychain or OIDC, not only from the environment
+			redact.Add(token)
 			return token
 		}
 	}
@@ -95,13 +113,55 @@ func ValidateTokenPrintProject(token string) {
 	client := cloud.GetCloudApiEndpoints().NewCloudApiClient(token)
 	if projectName, err := client.RequestProjectName(); err != nil {
 		msg.ErrorMessage(cloud.InvalidTokenMessage)
-		os.Exit(1)
+		log.StandardLogger().Exit(1)
 	} else if !qdenv.IsContainer() {
 		msg.SuccessMessage("Linked %s project: %s", cloud.GetCloudRootEndpoint().Url, projectName)
 	}
 
 }
 
+// oidcToken is the Qodana Cloud token exchanged for the CI OIDC token, the exchange is done once per run.
+var oidcToken *string
+
+// getTokenFromOidc exchanges the OIDC token issued by the CI for the job for a Qodana Cloud token, so the project
+// doesn't have to keep a long-lived QODANA_TOKEN secret.
+func getTokenFromOidc() string {
+	if oidcToken != nil {
+		return *oidcToken
+	}
+	token := ""
+	oidcToken = &token
+	idToken, err := cloud.GetCiOidcToken()
+	if err != nil {
+		msg.WarningMessageCI("Failed to get the OIDC token of the CI job: %s", err)
+		return ""
+	}
+	if idToken == "" {
+		return ""
+	}
+	token, err = cloud.GetCloudApiEndpoints().NewCloudApiClient("").ExchangeOidcToken(idToken)
+	var apiErr *cloud.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		msg.ErrorMessage(
+			"Qodana Cloud at %s doesn't support the OIDC token exchange, declare %s instead",
+			cloud.GetCloudRootEndpoint().Url,
+			qdenv.QodanaToken,
+		)
+		token = ""
+		return ""
+	case err != nil:
+		msg.ErrorMessage(
+			"Qodana Cloud declined the OIDC token of the CI job, check the trusted CI identities of the project: %s",
+			err,
+		)
+		token = ""
+		return ""
+	}
+	log.Debugf("Exchanged the OIDC token of the CI job for a Qodana Cloud token")
+	return token
+}
+
 // saveCloudToken saves token to the system keyring
 func saveCloudToken(id string, token string) error {
 	err := keyring.Set(keyringDefaultService, id, token)
@@ -122,6 +182,29 @@ func getCloudToken(id string) (string, error) {
 	return secret, nil
 }
 
+// SaveGlobalToken saves the token used by all projects to the system keyring.
+func SaveGlobalToken(token string) error {
+	return saveCloudToken(keyringGlobalTokenId, token)
+}
+
+// GetGlobalToken returns the token saved with SaveGlobalToken, it is empty if no token is saved.
+func GetGlobalToken() (string, error) {
+	token, err := getCloudToken(keyringGlobalTokenId)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", nil
+	}
+	return token, err
+}
+
+// RemoveGlobalToken removes the token saved with SaveGlobalToken, it returns false if no token is saved.
+func RemoveGlobalToken() (bool, error) {
+	err := keyring.Delete(keyringDefaultService, keyringGlobalTokenId)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func setupToken(projectDir string, repositoryRoot, id string, logdir string) string {
 	openCloud := msg.AskUserConfirm("Do you want to open the team page to get the token?")
 	if openCloud {
@@ -184,6 +267,19 @@ func getTokenFromKeychain(refresh bool, id string) string {
 		log.Debugf("Loaded token from the system keyring with id %s", id)
 		return tokenFromKeychain
 	}
+	globalToken, err := GetGlobalToken()
+	if err != nil {
+		log.Debugf("Failed to get the global token from the system keyring: %s", err)
+	}
+	if globalToken != "" {
+		msg.WarningMessage(
+			"Got %s saved with %s from the system keyring, declare %s env variable to override it",
+			msg.PrimaryBold(qdenv.QodanaToken),
+			msg.PrimaryBold("qodana token set"),
+			msg.PrimaryBold(qdenv.QodanaToken),
+		)
+		return globalToken
+	}
 	return ""
 }
 
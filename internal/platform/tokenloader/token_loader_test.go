@@ -7,6 +7,7 @@ import (
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
 	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
 	"github.com/stretchr/testify/assert"
+	"github.com/zalando/go-keyring"
 )
 
 type mockTokenLoader struct {
@@ -88,6 +89,19 @@ func TestIsCloudTokenRequired(t *testing.T) {
 		}
 		assert.True(t, IsCloudTokenRequired(loader))
 	})
+
+	t.Run("offline", func(t *testing.T) {
+		t.Setenv(qdenv.QodanaOffline, "true")
+		loader := &mockTokenLoader{
+			qodanaToken: "test-token",
+			analyzer: &product.NativeAnalyzer{
+				Linter: product.Linter{
+					IsPaid: true,
+				},
+			},
+		}
+		assert.False(t, IsCloudTokenRequired(loader))
+	})
 }
 
 func TestLoadCloudUploadToken(t *testing.T) {
@@ -103,6 +117,11 @@ func TestLoadCloudUploadToken(t *testing.T) {
 	})
 
 	t.Run("no token available", func(t *testing.T) {
+		// the tests can run in a CI job issuing OIDC tokens
+		t.Setenv(qdenv.QodanaOidcToken, "")
+		t.Setenv(qdenv.GitHubOidcRequestUrl, "")
+		oidcToken = nil
+		defer func() { oidcToken = nil }()
 		loader := &mockTokenLoader{
 			id:         "nonexistent-id",
 			projectDir: "/test/project",
@@ -111,6 +130,16 @@ func TestLoadCloudUploadToken(t *testing.T) {
 		token := LoadCloudUploadToken(loader, false, false, false)
 		assert.Empty(t, token)
 	})
+
+	t.Run("offline", func(t *testing.T) {
+		t.Setenv(qdenv.QodanaOffline, "true")
+		loader := &mockTokenLoader{
+			qodanaToken: "test-token",
+			id:          "test-id",
+		}
+		token := LoadCloudUploadToken(loader, false, true, false)
+		assert.Empty(t, token)
+	})
 }
 
 func TestGetTokenFromKeychain(t *testing.T) {
@@ -153,3 +182,34 @@ func TestSaveAndGetCloudToken(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestGlobalToken(t *testing.T) {
+	// the global token of the developer running the tests must stay intact
+	keyring.MockInit()
+
+	token, err := GetGlobalToken()
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+	removed, err := RemoveGlobalToken()
+	assert.NoError(t, err)
+	assert.False(t, removed)
+
+	assert.NoError(t, SaveGlobalToken("global-token"))
+	token, err = GetGlobalToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "global-token", token)
+
+	t.Run("used without project token", func(t *testing.T) {
+		assert.Equal(t, "global-token", getTokenFromKeychain(false, "project-without-token"))
+	})
+
+	t.Run("project token preferred", func(t *testing.T) {
+		assert.NoError(t, saveCloudToken("project-with-token", "project-token"))
+		assert.Equal(t, "project-token", getTokenFromKeychain(false, "project-with-token"))
+	})
+
+	removed, err = RemoveGlobalToken()
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	assert.Empty(t, getTokenFromKeychain(false, "project-without-token"))
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing exports the phases of a command as OpenTelemetry spans, enabled by the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) variable.
+package tracing
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/JetBrains/qodana-cli"
+
+	// traceParentEnv is the W3C trace context of the CI job the CLI is run by, the command trace becomes its child.
+	traceParentEnv = "TRACEPARENT"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+type tracer struct {
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	root     trace.Span
+	stack    []context.Context
+}
+
+var current = &tracer{}
+
+// IsEnabled tells whether an OTLP endpoint is configured.
+func IsEnabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup starts the trace of the command, the exporter is configured by the standard OTEL_EXPORTER_OTLP_* variables.
+// The tracing failures are logged, they never fail the command.
+func Setup(ctx context.Context, command string) {
+	if !IsEnabled() {
+		return
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		log.Warnf("Failed to set up the OpenTelemetry exporter: %s", err)
+		return
+	}
+	res, err := resource.New(
+		ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", "qodana-cli"),
+			attribute.String("service.version", version.Version),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		log.Debugf("Failed to detect the OpenTelemetry resource: %s", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	if traceParent := os.Getenv(traceParentEnv); traceParent != "" {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+	}
+	current.init(ctx, command, provider)
+	log.Debugf("OpenTelemetry tracing of %s is enabled", command)
+}
+
+// Start starts the span of the phase as a child of the innermost running one, it ends when the returned function is
+// called.
+func Start(phase string) func() {
+	return current.start(phase)
+}
+
+// Finish ends the trace of the command and exports the remaining spans, only the first call has an effect.
+// It must be called before the process exits, the spans are exported in batches.
+func Finish() {
+	current.mu.Lock()
+	provider, root := current.provider, current.root
+	current.provider, current.root, current.stack = nil, nil, nil
+	current.mu.Unlock()
+	if provider == nil {
+		return
+	}
+	root.End()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Warnf("Failed to export the OpenTelemetry spans: %s", err)
+	}
+}
+
+func (t *tracer) init(ctx context.Context, command string, provider *sdktrace.TracerProvider) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.provider = provider
+	t.tracer = provider.Tracer(instrumentationName)
+	ctx, t.root = t.tracer.Start(ctx, command)
+	t.stack = []context.Context{ctx}
+}
+
+func (t *tracer) start(phase string) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.stack) == 0 {
+		return func() {}
+	}
+	ctx, span := t.tracer.Start(t.stack[len(t.stack)-1], phase)
+	t.stack = append(t.stack, ctx)
+	return func() {
+		span.End()
+		t.stop(ctx)
+	}
+}
+
+func (t *tracer) stop(ctx context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.stack) - 1; i > 0; i-- {
+		if t.stack[i] == ctx {
+			t.stack = append(t.stack[:i], t.stack[i+1:]...)
+			return
+		}
+	}
+}
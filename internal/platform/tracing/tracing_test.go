@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStart(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	current.init(context.Background(), "qodana scan", sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	Start("Image pull")()
+	stopAnalysis := Start("Analysis")
+	Start("Report conversion")()
+	stopAnalysis()
+	Start("Upload")()
+	Finish()
+	Start("After finish")()
+	Finish()
+
+	parents := make(map[string]string)
+	ids := make(map[string]string)
+	for _, span := range recorder.Ended() {
+		ids[span.SpanContext().SpanID().String()] = span.Name()
+	}
+	for _, span := range recorder.Ended() {
+		parents[span.Name()] = ids[span.Parent().SpanID().String()]
+	}
+	require.Len(t, parents, 5)
+	assert.Equal(t, "", parents["qodana scan"])
+	assert.Equal(t, "qodana scan", parents["Image pull"])
+	assert.Equal(t, "qodana scan", parents["Analysis"])
+	assert.Equal(t, "Analysis", parents["Report conversion"])
+	assert.Equal(t, "qodana scan", parents["Upload"])
+}
+
+func TestStartDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	assert.False(t, IsEnabled())
+
+	Setup(context.Background(), "qodana scan")
+	Start("Analysis")()
+	Finish()
+}
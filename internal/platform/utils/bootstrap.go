@@ -0,0 +1,190 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	fexec "github.com/JetBrains/qodana-cli/internal/foundation/exec"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPrepareStepBackoff = 10 * time.Second
+	// prepareStepTimeoutExitCode is the exit code of the timed out step, the same as of the timeout utility.
+	prepareStepTimeoutExitCode = 124
+	bootstrapLogDir            = "bootstrap"
+)
+
+var unsafeLogFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// sleep is replaced in tests not to wait for the backoff.
+var sleep = time.Sleep
+
+// Bootstrap runs the steps preparing the project before the analysis (the prepare steps and the bootstrap command
+// from qodana.yaml), it exits if any of them fails.
+func Bootstrap(steps []qdyaml.PrepareStep, project string, logDir string) {
+	for i, step := range steps {
+		if res, err := RunPrepareStep(step, i+1, project, logDir); res != 0 || err != nil {
+			if err != nil {
+				log.Error(err)
+			}
+			if res <= 0 {
+				res = 1
+			}
+			log.Printf("Provided %s command finished with error: %d. Exiting...", prepareStepName(step, i+1), res)
+			log.StandardLogger().Exit(res)
+		}
+	}
+}
+
+// RunPrepareStep runs the step until it succeeds or the attempts run out, it returns the exit code of the last attempt.
+// The output of every attempt is also written to <logDir>/bootstrap/<name>-<attempt>.log.
+func RunPrepareStep(step qdyaml.PrepareStep, position int, project string, logDir string) (int, error) {
+	name := prepareStepName(step, position)
+	attempts, backoff, timeout, err := parseRetry(step.Retry)
+	if err != nil {
+		return 1, fmt.Errorf("invalid retry of %s: %w", name, err)
+	}
+
+	res := 0
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			msg.WarningMessageCI(
+				"%s finished with exit code %d, retrying in %s (attempt %d of %d)",
+				name,
+				res,
+				backoff,
+				attempt,
+				attempts,
+			)
+			sleep(backoff)
+			backoff *= 2
+		}
+		res, err = runPrepareStepAttempt(step, name, attempt, project, logDir, timeout)
+		if err == nil && res == 0 {
+			return 0, nil
+		}
+		if res == prepareStepTimeoutExitCode && timeout != math.MaxInt64 {
+			msg.WarningMessageCI("%s timed out after %s", name, timeout)
+		}
+	}
+	return res, err
+}
+
+func runPrepareStepAttempt(
+	step qdyaml.PrepareStep,
+	name string,
+	attempt int,
+	project string,
+	logDir string,
+	timeout time.Duration,
+) (int, error) {
+	log.Printf("Running %s: %s", name, step.Run)
+	if logDir == "" {
+		return fexec.RunShellWithTimeout(project, os.Stdout, os.Stderr, timeout, prepareStepTimeoutExitCode, step.Run)
+	}
+	logFile, err := createPrepareStepLog(logDir, name, attempt)
+	if err != nil {
+		log.Warnf("Could not capture the output of %s: %s", name, err)
+		return fexec.RunShellWithTimeout(project, os.Stdout, os.Stderr, timeout, prepareStepTimeoutExitCode, step.Run)
+	}
+	defer func() { _ = logFile.Close() }()
+	stdout, stdoutCopied, err := teeToFile(os.Stdout, logFile)
+	if err != nil {
+		return 1, err
+	}
+	stderr, stderrCopied, err := teeToFile(os.Stderr, logFile)
+	if err != nil {
+		_ = stdout.Close()
+		return 1, err
+	}
+	res, err := fexec.RunShellWithTimeout(project, stdout, stderr, timeout, prepareStepTimeoutExitCode, step.Run)
+	_, _ = stdout.Close(), stderr.Close()
+	// the output left in the pipes is copied shortly, a process left in the background can hold them open forever
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, copied := range []<-chan struct{}{stdoutCopied, stderrCopied} {
+		select {
+		case <-copied:
+		case <-ctx.Done():
+		}
+	}
+	return res, err
+}
+
+// teeToFile returns a pipe copied to dst and the log file, and the channel closed when the pipe is copied. The step
+// gets the pipe instead of the writers themselves, otherwise waiting for the step would also wait for the processes
+// it left in the background holding the output.
+func teeToFile(dst io.Writer, logFile *os.File) (*os.File, <-chan struct{}, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	copied := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.MultiWriter(dst, logFile), reader)
+		_ = reader.Close()
+		close(copied)
+	}()
+	return writer, copied, nil
+}
+
+func createPrepareStepLog(logDir string, name string, attempt int) (*os.File, error) {
+	dir := filepath.Join(logDir, bootstrapLogDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	fileName := fmt.Sprintf("%s-%d.log", unsafeLogFileNameChars.ReplaceAllString(name, "-"), attempt)
+	return os.Create(filepath.Join(dir, fileName))
+}
+
+// parseRetry returns the number of attempts, the first backoff and the timeout of an attempt of a step.
+func parseRetry(retry qdyaml.Retry) (int, time.Duration, time.Duration, error) {
+	attempts := max(retry.Attempts, 1)
+	backoff := defaultPrepareStepBackoff
+	if retry.Backoff != "" {
+		var err error
+		if backoff, err = time.ParseDuration(retry.Backoff); err != nil || backoff < 0 {
+			return 0, 0, 0, fmt.Errorf("backoff %q is not a duration like 10s", retry.Backoff)
+		}
+	}
+	timeout := time.Duration(math.MaxInt64)
+	if retry.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(retry.Timeout); err != nil || timeout <= 0 {
+			return 0, 0, 0, fmt.Errorf("timeout %q is not a duration like 10m", retry.Timeout)
+		}
+	}
+	return attempts, backoff, timeout, nil
+}
+
+func prepareStepName(step qdyaml.PrepareStep, position int) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	return fmt.Sprintf("prepare-%d", position)
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPrepareStep(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the steps use sh")
+	}
+	var backoffs []time.Duration
+	sleep = func(d time.Duration) { backoffs = append(backoffs, d) }
+	defer func() { sleep = time.Sleep }()
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		backoffs = nil
+		projectDir, logDir := t.TempDir(), t.TempDir()
+		step := qdyaml.PrepareStep{
+			Name:  "restore",
+			Run:   "echo attempt >> attempts; [ $(wc -l < attempts) -ge 3 ]",
+			Retry: qdyaml.Retry{Attempts: 3, Backoff: "1s"},
+		}
+
+		res, err := RunPrepareStep(step, 1, projectDir, logDir)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, res)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, backoffs)
+		for _, attempt := range []string{"restore-1.log", "restore-2.log", "restore-3.log"} {
+			assert.FileExists(t, filepath.Join(logDir, bootstrapLogDir, attempt))
+		}
+	})
+
+	t.Run("fails when attempts run out", func(t *testing.T) {
+		backoffs = nil
+		step := qdyaml.PrepareStep{Run: "echo failed; exit 3", Retry: qdyaml.Retry{Attempts: 2}}
+
+		logDir := t.TempDir()
+		res, err := RunPrepareStep(step, 2, t.TempDir(), logDir)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, res)
+		assert.Equal(t, []time.Duration{defaultPrepareStepBackoff}, backoffs)
+		content, err := os.ReadFile(filepath.Join(logDir, bootstrapLogDir, "prepare-2-2.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, "failed\n", string(content))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		step := qdyaml.PrepareStep{Name: "install", Run: "sleep 5; echo done", Retry: qdyaml.Retry{Timeout: "100ms"}}
+
+		start := time.Now()
+		res, _ := RunPrepareStep(step, 1, t.TempDir(), t.TempDir())
+		assert.Equal(t, prepareStepTimeoutExitCode, res)
+		assert.True(t, time.Since(start) < 3*time.Second)
+	})
+
+	t.Run("invalid retry", func(t *testing.T) {
+		step := qdyaml.PrepareStep{Name: "install", Run: "true", Retry: qdyaml.Retry{Backoff: "soon"}}
+
+		res, err := RunPrepareStep(step, 1, t.TempDir(), "")
+		assert.Error(t, err)
+		assert.Equal(t, 1, res)
+	})
+}
+
+func TestParseRetry(t *testing.T) {
+	attempts, backoff, timeout, err := parseRetry(qdyaml.Retry{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, defaultPrepareStepBackoff, backoff)
+	assert.Equal(t, time.Duration(1<<63-1), timeout)
+
+	attempts, backoff, timeout, err = parseRetry(qdyaml.Retry{Attempts: 4, Backoff: "30s", Timeout: "15m"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, attempts)
+	assert.Equal(t, 30*time.Second, backoff)
+	assert.Equal(t, 15*time.Minute, timeout)
+
+	_, _, _, err = parseRetry(qdyaml.Retry{Timeout: "0s"})
+	assert.Error(t, err)
+}
@@ -38,17 +38,6 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// Bootstrap takes the given command (from CLI or qodana.yaml) and runs it.
-func Bootstrap(command string, project string) {
-	if command == "" {
-		return
-	}
-	if res, err := fexec.RunShell(project, command); res > 0 || err != nil {
-		log.Printf("Provided bootstrap command finished with error: %d. Exiting...", res)
-		os.Exit(res)
-	}
-}
-
 // FindFiles returns a slice of files with the given extensions from the given root (recursive).
 func FindFiles(root string, extensions []string) []string {
 	var files []string
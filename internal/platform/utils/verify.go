@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/hash"
+	"github.com/JetBrains/qodana-cli/internal/foundation/sigstore"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// verificationFileLimit is the size limit of the downloaded checksum and signature files.
+const verificationFileLimit = 1 << 20
+
+// errNotPublished is returned for a checksum or a signature that isn't published next to the download.
+var errNotPublished = errors.New("not published")
+
+// Verification describes how a downloaded file is checked before it's extracted or run.
+type Verification struct {
+	// Sha256 is the expected hex SHA-256 of the file.
+	Sha256 string
+	// ChecksumUrl is the URL of the checksum file in the sha256sum format, used when Sha256 is empty.
+	ChecksumUrl string
+	// ChecksumRequired fails the verification if the checksum file isn't found, otherwise only a warning is printed:
+	// ChecksumUrl is then a guess, e.g. the download URL with the .sha256 suffix.
+	ChecksumRequired bool
+	// SignatureUrl is the URL of the Sigstore bundle with the signature of the file, made with
+	// `cosign sign-blob --key ... --bundle ...` and checked with PublicKey.
+	SignatureUrl string
+	// PublicKey is the PEM ECDSA public key of the publisher.
+	PublicKey string
+	// Auth is the bearer token of the checksum and signature requests.
+	Auth string
+}
+
+// VerificationDisabled tells if the downloads are not verified, with --no-verify.
+func VerificationDisabled() bool {
+	return strings.EqualFold(os.Getenv(qdenv.QodanaNoVerify), "true")
+}
+
+// VerifyDownload checks the downloaded file against its published SHA-256 checksum and signature. With --no-verify
+// only a warning is printed.
+func VerifyDownload(path string, v Verification) error {
+	name := filepath.Base(path)
+	if VerificationDisabled() {
+		msg.WarningMessage("%s is not verified: --no-verify is set", name)
+		return nil
+	}
+	expected := v.Sha256
+	if expected == "" && v.ChecksumUrl != "" {
+		content, err := fetchVerificationFile(v.ChecksumUrl, v.Auth)
+		switch {
+		case errors.Is(err, errNotPublished) && !v.ChecksumRequired:
+			msg.WarningMessage("No checksum is published for %s, the download is not verified", name)
+		case err != nil:
+			return fmt.Errorf("failed to download the checksum of %s: %w", name, err)
+		default:
+			if expected, err = ParseChecksum(string(content), filepath.Base(v.ChecksumUrl), name); err != nil {
+				return err
+			}
+		}
+	}
+	digest, err := hash.GetFileSha256(path)
+	if err != nil {
+		return err
+	}
+	if expected != "" {
+		if actual := hex.EncodeToString(digest[:]); !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+		}
+		log.Debugf("The checksum of %s is verified", name)
+	}
+	if v.SignatureUrl != "" && v.PublicKey != "" {
+		bundle, err := fetchVerificationFile(v.SignatureUrl, v.Auth)
+		if err != nil {
+			return fmt.Errorf("failed to download the signature of %s: %w", name, err)
+		}
+		if err = sigstore.VerifyMessageSignature(digest, bundle, []byte(v.PublicKey)); err != nil {
+			return fmt.Errorf("the signature of %s is not trusted: %w", name, err)
+		}
+		log.Debugf("The signature of %s is verified", name)
+	}
+	return nil
+}
+
+// ParseChecksum returns the checksum of the file from the content of a checksum file: a bare hex digest, or
+// the "<digest>  <file name>" lines of sha256sum, where the line of the file (or of the only file) is taken.
+func ParseChecksum(content string, checksumFileName string, fileName string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	var only string
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		digest := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+			continue
+		}
+		if len(fields) == 1 || len(lines) == 1 {
+			only = digest
+			continue
+		}
+		if strings.TrimPrefix(fields[len(fields)-1], "*") == fileName {
+			return digest, nil
+		}
+	}
+	if only != "" {
+		return only, nil
+	}
+	return "", fmt.Errorf("no SHA-256 checksum of %s in %s", fileName, checksumFileName)
+}
+
+// fetchVerificationFile downloads a checksum or a signature, errNotPublished if there is none.
+func fetchVerificationFile(url string, auth string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", auth))
+	}
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden:
+		return nil, errNotPublished
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("response from %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, verificationFileLimit))
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChecksum(t *testing.T) {
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	other := "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"bare digest", digest + "\n", digest},
+		{"single line of another name", digest + "  renamed.tar.gz\n", digest},
+		{"sha256sum", other + "  other.zip\n" + digest + "  tool.zip\n", digest},
+		{"binary mode", other + " *other.zip\n" + digest + " *tool.zip\n", digest},
+		{"upper case", "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08", digest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseChecksum(tc.content, "tool.zip.sha256", "tool.zip")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := ParseChecksum(other+"  other.zip\n"+other+"  another.zip\n", "SHA256SUMS", "tool.zip")
+	assert.Error(t, err)
+	_, err = ParseChecksum("<html>not found</html>", "tool.zip.sha256", "tool.zip")
+	assert.Error(t, err)
+}
+
+func TestVerifyDownload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tool.zip")
+	content := []byte("tool")
+	assert.NoError(t, os.WriteFile(path, content, 0o644))
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/tool.zip.sha256":
+					_, _ = w.Write([]byte(digest + "  tool.zip\n"))
+				case "/wrong.sha256":
+					_, _ = w.Write([]byte("60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752  tool.zip\n"))
+				case "/error.sha256":
+					w.WriteHeader(http.StatusInternalServerError)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	t.Run("expected checksum", func(t *testing.T) {
+		assert.NoError(t, VerifyDownload(path, Verification{Sha256: digest}))
+		assert.ErrorContains(t, VerifyDownload(path, Verification{Sha256: "00" + digest[2:]}), "checksum mismatch")
+	})
+
+	t.Run("published checksum", func(t *testing.T) {
+		assert.NoError(t, VerifyDownload(path, Verification{ChecksumUrl: server.URL + "/tool.zip.sha256"}))
+		assert.ErrorContains(
+			t,
+			VerifyDownload(path, Verification{ChecksumUrl: server.URL + "/wrong.sha256"}),
+			"checksum mismatch",
+		)
+		assert.Error(t, VerifyDownload(path, Verification{ChecksumUrl: server.URL + "/error.sha256"}))
+	})
+
+	t.Run("missing checksum", func(t *testing.T) {
+		assert.NoError(t, VerifyDownload(path, Verification{ChecksumUrl: server.URL + "/missing.sha256"}))
+		assert.Error(
+			t,
+			VerifyDownload(path, Verification{ChecksumUrl: server.URL + "/missing.sha256", ChecksumRequired: true}),
+		)
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		assert.Error(
+			t,
+			VerifyDownload(
+				path,
+				Verification{Sha256: digest, SignatureUrl: server.URL + "/tool.zip.bundle", PublicKey: "key"},
+			),
+		)
+	})
+
+	t.Run("no verify", func(t *testing.T) {
+		t.Setenv(qdenv.QodanaNoVerify, "true")
+		assert.NoError(t, VerifyDownload(path, Verification{Sha256: "00" + digest[2:]}))
+	})
+}
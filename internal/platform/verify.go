@@ -0,0 +1,33 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/msg"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+)
+
+// SetupDownloadVerification turns off the verification of the downloaded IDEs and tools with --no-verify, for the run
+// and the processes it starts.
+func SetupDownloadVerification(cliOptions platformcmd.CliOptions) {
+	if !cliOptions.NoVerify {
+		return
+	}
+	qdenv.SetEnv(qdenv.QodanaNoVerify, "true")
+	msg.WarningMessage("The checksums and the signatures of the downloaded IDEs and tools are not verified")
+}
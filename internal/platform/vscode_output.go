@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// OutputText is the default output of the scan for the people reading it.
+	OutputText = "text"
+	// OutputVsCode adds the new problems in the single-line format matched by VsCodeProblemPattern, for the problem
+	// matcher of a VS Code task.
+	OutputVsCode = "vscode"
+)
+
+// VsCodeProblemPattern matches the problems printed with --output vscode: the severity (error, warning or info), the
+// absolute path of the file, the 1-based line and column, the inspection and the message.
+const VsCodeProblemPattern = `^\[qodana\] (error|warning|info) (.+):(\d+):(\d+) (\S+): (.*)$`
+
+// vsCodeSeverities maps the threshold severities to the severities of the VS Code problem matcher.
+var vsCodeSeverities = map[string]string{
+	"critical": "error",
+	"high":     "error",
+	"moderate": "warning",
+	"low":      "info",
+	"info":     "info",
+}
+
+// ValidateOutputOrFatal checks the --output value before the analysis is started.
+func ValidateOutputOrFatal(output string) {
+	if output != "" && output != OutputText && output != OutputVsCode {
+		log.Fatalf("Unsupported output %q, supported values: %s, %s", output, OutputText, OutputVsCode)
+	}
+}
+
+// ReportToOutput prints the new problems of the final SARIF report in the format of the output, nothing is printed
+// for the text output, the problems are printed with --print-problems.
+func ReportToOutput(sarifPath string, projectDir string, output string) {
+	if output != OutputVsCode {
+		return
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Warnf("Problems reading the report for the %s output: %v", output, err)
+		return
+	}
+	writeCiProblems(os.Stdout, report, newVsCodeProblemFormatter(projectDir))
+}
+
+// newVsCodeProblemFormatter formats the problem as one line with the absolute path, a problem without a location is
+// put on the first line of the file.
+func newVsCodeProblemFormatter(projectDir string) func(ciProblem) string {
+	if abs, err := filepath.Abs(projectDir); err == nil {
+		projectDir = abs
+	}
+	return func(p ciProblem) string {
+		path := filepath.FromSlash(p.path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+		severity, ok := vsCodeSeverities[p.severity]
+		if !ok {
+			severity = "warning"
+		}
+		return fmt.Sprintf(
+			"[qodana] %s %s:%d:%d %s: %s\n",
+			severity,
+			path,
+			max(p.line, 1),
+			max(p.column, 1),
+			p.ruleId,
+			strings.Join(strings.Fields(p.message), " "),
+		)
+	}
+}
@@ -0,0 +1,306 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/version"
+	"github.com/JetBrains/qodana-cli/internal/sarif"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// WebhookQueueFileName is the file in the Qodana system directory keeping the undelivered webhook events.
+	WebhookQueueFileName = "webhook-queue.json"
+
+	webhookQueueLimit = 100
+	webhookEventTtl   = 7 * 24 * time.Hour
+	webhookTimeout    = 10 * time.Second
+
+	// WebhookSignatureHeader is the HMAC-SHA256 of the payload made with QODANA_WEBHOOK_SECRET, "sha256=<hex>".
+	WebhookSignatureHeader = "X-Qodana-Signature-256"
+
+	webhookAnalysisFinished = "analysis.finished"
+	qualityGatePassed       = "passed"
+	qualityGateFailed       = "failed"
+)
+
+// WebhookEvent is a notification for one webhook, kept in the queue until delivered.
+type WebhookEvent struct {
+	Url       string          `json:"url"`
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"lastError,omitempty"`
+}
+
+// AnalysisResult is the payload sent to the webhooks when an analysis finishes.
+type AnalysisResult struct {
+	Event       string    `json:"event"`
+	AnalysisId  string    `json:"analysisId"`
+	Linter      string    `json:"linter,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	Repository  string    `json:"repository,omitempty"`
+	Branch      string    `json:"branch,omitempty"`
+	Revision    string    `json:"revision,omitempty"`
+	ReportUrl   string    `json:"reportUrl,omitempty"`
+	Problems    int       `json:"problems"`
+	QualityGate string    `json:"qualityGate"`
+	Observed    bool      `json:"observed,omitempty"`
+	ExitCode    int       `json:"exitCode"`
+	FinishedAt  time.Time `json:"finishedAt"`
+
+	// ProblemsBySeverity is the number of the new problems by severity, the severities without problems are left out.
+	ProblemsBySeverity map[string]int `json:"problemsBySeverity,omitempty"`
+}
+
+// NewAnalysisResult describes the finished analysis from its SARIF report, exitCode is the exit code before
+// the observe mode is applied, so the failed quality gate is reported in the observe mode too.
+func NewAnalysisResult(sarifPath, analysisId, reportUrl string, exitCode int, mode ObserveMode) AnalysisResult {
+	result := AnalysisResult{
+		Event:       webhookAnalysisFinished,
+		AnalysisId:  analysisId,
+		ReportUrl:   reportUrl,
+		QualityGate: qualityGatePassed,
+		ExitCode:    exitCode,
+		FinishedAt:  time.Now(),
+	}
+	if exitCode == exitcodes.QodanaFailThresholdExitCode {
+		result.QualityGate = qualityGateFailed
+		result.Observed = mode.Enabled
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		log.Debugf("Failed to read %s for the webhooks: %s", sarifPath, err)
+		return result
+	}
+	for _, run := range report.Runs {
+		if result.Linter == "" && run.Tool != nil && run.Tool.Driver != nil {
+			result.Linter = run.Tool.Driver.FullName
+		}
+		if result.Repository == "" && len(run.VersionControlProvenance) > 0 {
+			vcs := run.VersionControlProvenance[0]
+			result.Repository, result.Branch, result.Revision = vcs.RepositoryUri, vcs.Branch, vcs.RevisionId
+		}
+	}
+	result.Project = repositoryName(result.Repository)
+	result.Problems = countProblems(report.Runs)
+	_, result.ProblemsBySeverity = countProblemsBySeverity(report)
+	for severity, count := range result.ProblemsBySeverity {
+		if count == 0 {
+			delete(result.ProblemsBySeverity, severity)
+		}
+	}
+	return result
+}
+
+// repositoryName returns the name of the repository from its URI, e.g. "qodana-cli" for git@github.com:JetBrains/qodana-cli.git.
+func repositoryName(uri string) string {
+	uri = strings.TrimSuffix(strings.TrimRight(uri, "/"), ".git")
+	if uri == "" {
+		return ""
+	}
+	return path.Base(strings.ReplaceAll(uri, ":", "/"))
+}
+
+// countProblems returns the number of the new problems, the unchanged and absent ones from the baseline are not counted.
+func countProblems(runs []sarif.Run) int {
+	problems := 0
+	for _, run := range runs {
+		for _, r := range run.Results {
+			if isNewProblem(&r) {
+				problems++
+			}
+		}
+	}
+	return problems
+}
+
+// NotifyWebhooks sends the result of the analysis to the webhooks, together with the events left in the queue by the previous runs.
+func NotifyWebhooks(systemDir string, urls []string, sarifPath, analysisId, reportUrl string, exitCode int, mode ObserveMode) {
+	if len(urls) == 0 {
+		return
+	}
+	result := NewAnalysisResult(sarifPath, analysisId, reportUrl, exitCode, mode)
+	payload, err := json.Marshal(result)
+	if err != nil {
+		log.Warnf("Failed to marshal the webhook payload: %s", err)
+		return
+	}
+	events := make([]WebhookEvent, 0, len(urls))
+	for _, url := range urls {
+		events = append(events, newWebhookEvent(url, payload, result.FinishedAt))
+	}
+	deliverWebhookEvents(systemDir, events)
+}
+
+// newWebhookEvent returns the event for the URL, signed when QODANA_WEBHOOK_SECRET is set. The signature is kept
+// in the queue, so the queued events are delivered with it even if the secret isn't set for qodana notify --flush.
+func newWebhookEvent(url string, payload []byte, createdAt time.Time) WebhookEvent {
+	event := WebhookEvent{Url: url, Payload: payload, CreatedAt: createdAt}
+	if secret := os.Getenv(qdenv.QodanaWebhookSecret); secret != "" {
+		event.Signature = signWebhookPayload(secret, payload)
+	}
+	return event
+}
+
+// signWebhookPayload returns the value of WebhookSignatureHeader for the payload.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhookEvents sends the events with the queued ones, the undelivered events are kept in the queue.
+func deliverWebhookEvents(systemDir string, events []WebhookEvent) {
+	delivered, pending, err := FlushWebhooks(systemDir, events...)
+	if err != nil {
+		log.Warnf("Problems saving the webhook queue: %s", err)
+	}
+	log.Debugf("Webhook events delivered: %d, pending: %d", delivered, pending)
+	if pending > 0 {
+		log.Warnf("%d webhook events were not delivered, they will be retried on the next run or with `qodana notify --flush`", pending)
+	}
+}
+
+// FlushWebhooks delivers the queued events and the given new ones, the undelivered events are saved back to the queue.
+// The queue keeps at most webhookQueueLimit events, the oldest ones and the ones older than webhookEventTtl are dropped.
+func FlushWebhooks(systemDir string, events ...WebhookEvent) (int, int, error) {
+	queue, err := ReadWebhookQueue(systemDir)
+	if err != nil {
+		log.Warnf("Failed to read the webhook queue, the queued events are dropped: %s", err)
+	}
+	queue = append(queue, events...)
+	if len(queue) == 0 {
+		return 0, 0, nil
+	}
+
+	delivered := 0
+	pending := make([]WebhookEvent, 0)
+	// a webhook failed once is not retried in the same flush, so an outage doesn't delay the run for every queued event
+	failedUrls := make(map[string]bool)
+	for _, event := range queue {
+		if time.Since(event.CreatedAt) > webhookEventTtl {
+			log.Warnf("Dropping the webhook event for %s created at %s: %s", event.Url, event.CreatedAt.Format(time.RFC3339), event.LastError)
+			continue
+		}
+		if failedUrls[event.Url] {
+			pending = append(pending, event)
+			continue
+		}
+		event.Attempts++
+		if err := sendWebhook(event); err != nil {
+			log.Debugf("Failed to deliver the webhook event to %s: %s", event.Url, err)
+			event.LastError = err.Error()
+			failedUrls[event.Url] = true
+			pending = append(pending, event)
+			continue
+		}
+		delivered++
+	}
+	if len(pending) > webhookQueueLimit {
+		log.Warnf("Dropping %d oldest webhook events, the queue is full", len(pending)-webhookQueueLimit)
+		pending = pending[len(pending)-webhookQueueLimit:]
+	}
+	return delivered, len(pending), writeWebhookQueue(systemDir, pending)
+}
+
+func sendWebhook(event WebhookEvent) error {
+	req, err := http.NewRequest(http.MethodPost, event.Url, bytes.NewReader(event.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "qodana-cli/"+version.Version)
+	if event.Signature != "" {
+		req.Header.Set(WebhookSignatureHeader, event.Signature)
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+// ReadWebhookQueue returns the undelivered webhook events.
+func ReadWebhookQueue(systemDir string) ([]WebhookEvent, error) {
+	data, err := os.ReadFile(filepath.Join(systemDir, WebhookQueueFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var queue []WebhookEvent
+	if err = json.Unmarshal(data, &queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// writeWebhookQueue replaces the queue file atomically, so an interrupted run doesn't lose the queued events.
+func writeWebhookQueue(systemDir string, queue []WebhookEvent) error {
+	path := filepath.Join(systemDir, WebhookQueueFileName)
+	if len(queue) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(systemDir, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(systemDir, WebhookQueueFileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyWebhooksRetriesUndelivered(t *testing.T) {
+	systemDir := t.TempDir()
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	assert.NoError(t, os.WriteFile(sarifPath, []byte(sarifFileData), 0o644))
+
+	var available atomic.Bool
+	var received []AnalysisResult
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if !available.Load() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				var result AnalysisResult
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&result))
+				received = append(received, result)
+			},
+		),
+	)
+	defer server.Close()
+
+	mode := ObserveMode{Enabled: true}
+	NotifyWebhooks(systemDir, []string{server.URL}, sarifPath, "1", "", exitcodes.QodanaFailThresholdExitCode, mode)
+	NotifyWebhooks(systemDir, []string{server.URL}, sarifPath, "2", "", exitcodes.QodanaSuccessExitCode, mode)
+	queue, err := ReadWebhookQueue(systemDir)
+	assert.NoError(t, err)
+	if assert.Len(t, queue, 2) {
+		assert.Equal(t, 2, queue[0].Attempts)
+		assert.Equal(t, 0, queue[1].Attempts)
+		assert.Contains(t, queue[0].LastError, "503")
+	}
+
+	available.Store(true)
+	delivered, pending, err := FlushWebhooks(systemDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, 0, pending)
+	assert.NoFileExists(t, filepath.Join(systemDir, WebhookQueueFileName))
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, "1", received[0].AnalysisId)
+		assert.Equal(t, qualityGateFailed, received[0].QualityGate)
+		assert.True(t, received[0].Observed)
+		assert.Equal(t, 5, received[0].Problems)
+		assert.Equal(t, qualityGatePassed, received[1].QualityGate)
+	}
+}
+
+func TestFlushWebhooksDropsExpiredAndOverflow(t *testing.T) {
+	systemDir := t.TempDir()
+	events := []WebhookEvent{{Url: "http://127.0.0.1:0/expired", CreatedAt: time.Now().Add(-webhookEventTtl - time.Hour)}}
+	for i := 0; i < webhookQueueLimit+5; i++ {
+		events = append(events, WebhookEvent{Url: "http://127.0.0.1:0/unreachable", CreatedAt: time.Now()})
+	}
+
+	delivered, pending, err := FlushWebhooks(systemDir, events...)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, webhookQueueLimit, pending)
+	queue, err := ReadWebhookQueue(systemDir)
+	assert.NoError(t, err)
+	assert.Len(t, queue, webhookQueueLimit)
+	for _, event := range queue {
+		assert.Equal(t, "http://127.0.0.1:0/unreachable", event.Url)
+	}
+}
+
+func TestNotifyWebhooksSignsPayload(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	assert.NoError(t, os.WriteFile(sarifPath, []byte(sarifFileData), 0o644))
+	t.Setenv(qdenv.QodanaWebhookSecret, "secret")
+
+	var signature string
+	var payload []byte
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				signature = r.Header.Get(WebhookSignatureHeader)
+				payload, _ = io.ReadAll(r.Body)
+			},
+		),
+	)
+	defer server.Close()
+
+	NotifyWebhooks(t.TempDir(), []string{server.URL}, sarifPath, "1", "", exitcodes.QodanaSuccessExitCode, ObserveMode{})
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(payload)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+
+	var result AnalysisResult
+	assert.NoError(t, json.Unmarshal(payload, &result))
+	total := 0
+	for _, count := range result.ProblemsBySeverity {
+		assert.Positive(t, count)
+		total += count
+	}
+	assert.Equal(t, result.Problems, total)
+}
+
+func TestRepositoryName(t *testing.T) {
+	assert.Equal(t, "qodana-cli", repositoryName("https://github.com/JetBrains/qodana-cli.git"))
+	assert.Equal(t, "qodana-cli", repositoryName("git@github.com:JetBrains/qodana-cli.git"))
+	assert.Equal(t, "project", repositoryName("https://git.example.com/project/"))
+	assert.Equal(t, "", repositoryName(""))
+}
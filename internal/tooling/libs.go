@@ -24,7 +24,9 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/JetBrains/qodana-cli/internal/platform/metrics"
 	"github.com/JetBrains/qodana-cli/internal/platform/product"
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 )
 
 //go:generate go run scripts/download-libs.go
@@ -43,7 +45,12 @@ const (
 )
 
 func (library Library) GetLibPath(cacheDir string) string {
+	defer timings.Start(timings.ToolExtraction)()
 	matchedFile := findLibFile(library)
+	if libPath, ok := updatedLibPath(cacheDir, matchedFile); ok {
+		metrics.CacheHit(metrics.ToolsCache)
+		return libPath
+	}
 	libPath := extractLib(cacheDir, matchedFile)
 	return libPath
 }
@@ -63,16 +70,17 @@ func findLibFile(library Library) string {
 func extractLib(cacheDir string, matchedFile string) string {
 	libFileName := filepath.Base(matchedFile)
 	libPath := filepath.Join(GetToolsMountPath(cacheDir), libFileName)
-	if _, err := os.Stat(libPath); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			jarFileBytes, err := libs.ReadFile(matchedFile)
-			if err != nil {
-				log.Fatalf("Failed to read %s library: %s", libFileName, err)
-			}
-			err = os.WriteFile(libPath, jarFileBytes, 0644)
-			if err != nil {
-				log.Fatalf("Failed to write %s : %s", libFileName, err)
-			}
+	if _, err := os.Stat(libPath); err == nil {
+		metrics.CacheHit(metrics.ToolsCache)
+	} else if errors.Is(err, os.ErrNotExist) {
+		metrics.CacheMiss(metrics.ToolsCache)
+		jarFileBytes, err := libs.ReadFile(matchedFile)
+		if err != nil {
+			log.Fatalf("Failed to read %s library: %s", libFileName, err)
+		}
+		err = os.WriteFile(libPath, jarFileBytes, 0644)
+		if err != nil {
+			log.Fatalf("Failed to write %s : %s", libFileName, err)
 		}
 	}
 	return libPath
@@ -25,12 +25,14 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
 	"sync"
 
+	"github.com/JetBrains/qodana-cli/internal/platform/timings"
 	"github.com/codeclysm/extract/v4"
 )
 
@@ -44,6 +46,7 @@ var (
 )
 
 func GetQodanaJBRPath(cacheDir string) string {
+	defer timings.Start(timings.ToolExtraction)()
 	qodanaJBRPathOnce.Do(
 		func() {
 			path, err := computeQodanaJbrExecutablePath(cacheDir)
@@ -56,6 +59,20 @@ func GetQodanaJBRPath(cacheDir string) string {
 	return qodanaJBRPath
 }
 
+// QodanaJBRVersion extracts the Qodana JBR to cacheDir if needed and returns the version `java -version` reports.
+func QodanaJBRVersion(cacheDir string) (string, error) {
+	javaExec, err := computeQodanaJbrExecutablePath(cacheDir)
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.Command(javaExec, "-version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s: %w\n%s", javaExec, err, out)
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return version, nil
+}
+
 // computeQodanaJbrExecutablePath detects the system's GOOS/GOARCH, unpacks the appropriate JRE,
 // and returns the path to the java executable
 func computeQodanaJbrExecutablePath(cacheDir string) (string, error) {
@@ -0,0 +1,259 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tooling
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/foundation/hash"
+	"github.com/JetBrains/qodana-cli/internal/foundation/sigstore"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+)
+
+// Policies of updating the embedded tools, set by QODANA_TOOLS_UPDATE.
+const (
+	ToolsUpdateOff  = "off"  // ToolsUpdateOff uses only the tools embedded in the CLI, the default.
+	ToolsUpdateAuto = "auto" // ToolsUpdateAuto uses newer verified tools when available, falls back to the embedded ones.
+)
+
+const (
+	defaultToolsUpdateUrl = "https://download.jetbrains.com/qodana/cli-tools/index.json"
+	// toolsBundleSuffix is appended to the index URL to get its Sigstore bundle.
+	toolsBundleSuffix   = ".sigstore.json"
+	toolsUpdateTimeout  = 30 * time.Second
+	sigstoreSha256      = sigstore.Sha256
+	toolsStoreDirectory = "tools"
+)
+
+// toolUpdatesPublicKey is the key the tools index bundles are signed with.
+//
+//go:embed tool-updates.pub
+var toolUpdatesPublicKey []byte
+
+// toolsIndex is the list of the latest tool versions, published together with its Sigstore bundle.
+type toolsIndex struct {
+	Tools []toolUpdate `json:"tools"`
+}
+
+type toolUpdate struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Url     string `json:"url"`
+	Sha256  string `json:"sha256"`
+}
+
+var (
+	toolsIndexOnce   sync.Once
+	latestToolsIndex *toolsIndex
+)
+
+// updatedLibPath returns the path to a newer verified version of the embedded library, ok is false if there is none
+// or updates are disabled.
+func updatedLibPath(cacheDir string, embeddedFile string) (path string, ok bool) {
+	if toolsUpdatePolicy() != ToolsUpdateAuto {
+		return "", false
+	}
+	name, embeddedVersion := splitLibFileName(filepath.Base(embeddedFile))
+	toolsIndexOnce.Do(
+		func() {
+			url, publicKey, err := toolsUpdateSource()
+			if err == nil {
+				latestToolsIndex, err = fetchToolsIndex(url, publicKey)
+			}
+			if err != nil {
+				log.Warnf("Failed to check for tool updates, using the embedded tools: %s", err)
+			}
+		},
+	)
+	if latestToolsIndex == nil {
+		return "", false
+	}
+
+	for _, tool := range latestToolsIndex.Tools {
+		if tool.Name != name || compareToolVersions(tool.Version, embeddedVersion) <= 0 {
+			continue
+		}
+		path, err := storeTool(filepath.Join(cacheDir, toolsStoreDirectory), tool)
+		if err != nil {
+			log.Warnf("Failed to update %s to %s, using the embedded %s: %s", name, tool.Version, embeddedVersion, err)
+			return "", false
+		}
+		log.Debugf("Using %s %s instead of the embedded %s", name, tool.Version, embeddedVersion)
+		return path, true
+	}
+	return "", false
+}
+
+func toolsUpdatePolicy() string {
+	if qdenv.IsOffline() {
+		return ToolsUpdateOff
+	}
+	policy := strings.ToLower(os.Getenv(qdenv.QodanaToolsUpdate))
+	switch policy {
+	case "", ToolsUpdateOff:
+		return ToolsUpdateOff
+	case ToolsUpdateAuto:
+		return ToolsUpdateAuto
+	default:
+		log.Warnf("Unknown %s value %q, tool updates are disabled", qdenv.QodanaToolsUpdate, policy)
+		return ToolsUpdateOff
+	}
+}
+
+// toolsUpdateSource returns the URL of the tools index and the public key its bundle is signed with: the index
+// published by JetBrains and the embedded key by default. A mirror is set with QODANA_TOOLS_UPDATE_URL, and
+// QODANA_TOOLS_UPDATE_KEY is the path to the PEM public key of an index signed by someone else.
+func toolsUpdateSource() (string, []byte, error) {
+	url := os.Getenv(qdenv.QodanaToolsUpdateUrl)
+	if url == "" {
+		url = defaultToolsUpdateUrl
+	}
+	keyPath := os.Getenv(qdenv.QodanaToolsUpdateKey)
+	if keyPath == "" {
+		return url, toolUpdatesPublicKey, nil
+	}
+	publicKey, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read the tools index key: %w", err)
+	}
+	return url, publicKey, nil
+}
+
+// fetchToolsIndex downloads the tools index and accepts it only if its Sigstore bundle is signed with publicKey.
+func fetchToolsIndex(url string, publicKey []byte) (*toolsIndex, error) {
+	data, err := httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := httpGet(url + toolsBundleSuffix)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyIndexSignature(data, bundle, publicKey); err != nil {
+		return nil, fmt.Errorf("tools index %s is not trusted: %w", url, err)
+	}
+
+	index := &toolsIndex{}
+	if err = json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse tools index %s: %w", url, err)
+	}
+	return index, nil
+}
+
+// verifyIndexSignature checks that the bundle contains a signature of blob made with the key, as produced by
+// `cosign sign-blob --key ... --bundle ...`.
+func verifyIndexSignature(blob []byte, bundleData []byte, publicKey []byte) error {
+	return sigstore.VerifyMessageSignature(sha256.Sum256(blob), bundleData, publicKey)
+}
+
+// storeTool downloads the tool to the content-addressed store, the file is reused while its checksum matches.
+func storeTool(storeDir string, tool toolUpdate) (string, error) {
+	sha256sum := strings.ToLower(tool.Sha256)
+	if _, err := hex.DecodeString(sha256sum); err != nil || len(sha256sum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum %q", tool.Sha256)
+	}
+	path := filepath.Join(storeDir, "sha256", sha256sum, tool.Name+"-"+tool.Version+".jar")
+	if actual, err := hash.GetFileSha256(path); err == nil && hex.EncodeToString(actual[:]) == sha256sum {
+		return path, nil
+	}
+
+	data, err := httpGet(tool.Url)
+	if err != nil {
+		return "", err
+	}
+	actual := sha256.Sum256(data)
+	if hex.EncodeToString(actual[:]) != sha256sum {
+		return "", fmt.Errorf("checksum mismatch for %s", tool.Url)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	tmpPath := path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func httpGet(url string) ([]byte, error) {
+	client := http.Client{Timeout: toolsUpdateTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response from %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// splitLibFileName splits an embedded library file name (e.g. qodana-fuser-1.0.29.jar) into the name and version.
+func splitLibFileName(fileName string) (name string, version string) {
+	base := strings.TrimSuffix(fileName, ".jar")
+	for i := len(base) - 2; i > 0; i-- {
+		if base[i] == '-' && base[i+1] >= '0' && base[i+1] <= '9' {
+			return base[:i], base[i+1:]
+		}
+	}
+	return base, ""
+}
+
+// compareToolVersions compares dot-separated versions, numeric parts are compared as numbers.
+func compareToolVersions(a string, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		aNumber, aErr := strconv.Atoi(aPart)
+		bNumber, bErr := strconv.Atoi(bPart)
+		switch {
+		case aErr == nil && bErr == nil && aNumber != bNumber:
+			if aNumber < bNumber {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aPart != bPart:
+			return strings.Compare(aPart, bPart)
+		}
+	}
+	return 0
+}
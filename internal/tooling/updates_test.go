@@ -0,0 +1,212 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tooling
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+)
+
+func newTestSigningKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func signTestBundle(t *testing.T, key *ecdsa.PrivateKey, blob []byte) []byte {
+	digest := sha256.Sum256(blob)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := json.Marshal(
+		map[string]any{
+			"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+			"messageSignature": map[string]any{
+				"messageDigest": map[string]any{"algorithm": sigstoreSha256, "digest": digest[:]},
+				"signature":     signature,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle
+}
+
+func TestVerifySigstoreBundle(t *testing.T) {
+	key, publicKey := newTestSigningKey(t)
+	_, otherPublicKey := newTestSigningKey(t)
+	blob := []byte(`{"tools":[]}`)
+	bundle := signTestBundle(t, key, blob)
+
+	if err := verifyIndexSignature(blob, bundle, publicKey); err != nil {
+		t.Fatalf("expected a valid bundle, got %v", err)
+	}
+	if err := verifyIndexSignature([]byte(`{"tools":[{}]}`), bundle, publicKey); err == nil {
+		t.Fatal("expected a bundle of different content to be rejected")
+	}
+	if err := verifyIndexSignature(blob, bundle, otherPublicKey); err == nil {
+		t.Fatal("expected a bundle signed with another key to be rejected")
+	}
+	if err := verifyIndexSignature(blob, []byte(`{}`), publicKey); err == nil {
+		t.Fatal("expected a bundle without a signature to be rejected")
+	}
+}
+
+func TestUpdatedLibPath(t *testing.T) {
+	key, publicKey := newTestSigningKey(t)
+	jar := []byte("fuser jar")
+	jarSum := sha256.Sum256(jar)
+
+	var index []byte
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/index.json":
+					_, _ = w.Write(index)
+				case "/index.json" + toolsBundleSuffix:
+					_, _ = w.Write(signTestBundle(t, key, index))
+				case "/qodana-fuser-1.0.30.jar":
+					_, _ = w.Write(jar)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		),
+	)
+	defer server.Close()
+	index, _ = json.Marshal(
+		toolsIndex{
+			Tools: []toolUpdate{
+				{
+					Name:    "qodana-fuser",
+					Version: "1.0.30",
+					Url:     server.URL + "/qodana-fuser-1.0.30.jar",
+					Sha256:  hex.EncodeToString(jarSum[:]),
+				},
+			},
+		},
+	)
+
+	fetched, err := fetchToolsIndex(server.URL+"/index.json", publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latestToolsIndex = fetched
+	toolsIndexOnce.Do(func() {})
+	defer func() {
+		latestToolsIndex = nil
+	}()
+
+	t.Setenv(qdenv.QodanaToolsUpdate, ToolsUpdateOff)
+	if _, ok := updatedLibPath(t.TempDir(), "libs/qodana-fuser-1.0.29.jar"); ok {
+		t.Fatal("expected no updates when the policy is off")
+	}
+
+	t.Setenv(qdenv.QodanaToolsUpdate, ToolsUpdateAuto)
+	if _, ok := updatedLibPath(t.TempDir(), "libs/qodana-fuser-1.0.30.jar"); ok {
+		t.Fatal("expected no update of the same version")
+	}
+	path, ok := updatedLibPath(t.TempDir(), "libs/qodana-fuser-1.0.29.jar")
+	if !ok {
+		t.Fatal("expected the newer version to be used")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != string(jar) {
+		t.Fatalf("unexpected content of %s: %q, %v", path, data, err)
+	}
+}
+
+func TestCompareToolVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.29", "1.0.29", 0},
+		{"1.0.30", "1.0.29", 1},
+		{"1.0.9", "1.0.10", -1},
+		{"1.0", "1.0.0", 0},
+		{"0.12.6", "0.12.6-rc", -1},
+	} {
+		if actual := compareToolVersions(tc.a, tc.b); actual != tc.expected {
+			t.Errorf("compareToolVersions(%q, %q) = %d, expected %d", tc.a, tc.b, actual, tc.expected)
+		}
+	}
+}
+
+func TestSplitLibFileName(t *testing.T) {
+	name, version := splitLibFileName("intellij-report-converter-0.12.6.jar")
+	if name != "intellij-report-converter" || version != "0.12.6" {
+		t.Errorf("unexpected name %q and version %q", name, version)
+	}
+}
+
+func TestToolsUpdateSource(t *testing.T) {
+	_, publicKey := newTestSigningKey(t)
+	keyPath := filepath.Join(t.TempDir(), "tools.pub")
+	if err := os.WriteFile(keyPath, publicKey, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(qdenv.QodanaToolsUpdateUrl, "")
+	t.Setenv(qdenv.QodanaToolsUpdateKey, "")
+	url, key, err := toolsUpdateSource()
+	if err != nil || url != defaultToolsUpdateUrl || string(key) != string(toolUpdatesPublicKey) {
+		t.Fatalf("expected the default index and the embedded key, got %s, %q, %v", url, key, err)
+	}
+	if block, _ := pem.Decode(toolUpdatesPublicKey); block == nil || block.Type != "PUBLIC KEY" {
+		t.Fatal("the embedded key is not a PEM public key")
+	}
+
+	t.Setenv(qdenv.QodanaToolsUpdateUrl, "https://tools.example.com/index.json")
+	url, key, err = toolsUpdateSource()
+	if err != nil || url != "https://tools.example.com/index.json" || string(key) != string(toolUpdatesPublicKey) {
+		t.Fatalf("expected the mirror with the embedded key, got %s, %q, %v", url, key, err)
+	}
+
+	t.Setenv(qdenv.QodanaToolsUpdateKey, keyPath)
+	url, key, err = toolsUpdateSource()
+	if err != nil || url != "https://tools.example.com/index.json" || string(key) != string(publicKey) {
+		t.Fatalf("unexpected source %s, %q, %v", url, key, err)
+	}
+
+	t.Setenv(qdenv.QodanaToolsUpdateKey, filepath.Join(t.TempDir(), "missing.pub"))
+	if _, _, err = toolsUpdateSource(); err == nil {
+		t.Fatal("expected an error for a missing key")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	log "github.com/sirupsen/logrus"
+)
+
+// phpstanOutput is the output of `phpstan analyse --error-format=json`.
+type phpstanOutput struct {
+	Files  phpstanFiles `json:"files"`
+	Errors []string     `json:"errors"`
+}
+
+type phpstanFile struct {
+	Messages []struct {
+		Message    string `json:"message"`
+		Line       int    `json:"line"`
+		Identifier string `json:"identifier"`
+		Tip        string `json:"tip"`
+	} `json:"messages"`
+}
+
+// phpstanFiles handles PHPStan printing an empty array instead of an empty object when no problems are found.
+type phpstanFiles map[string]phpstanFile
+
+func (f *phpstanFiles) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "[]" {
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]phpstanFile)(f))
+}
+
+// psalmIssue is an element of the `psalm --output-format=json` output.
+type psalmIssue struct {
+	Severity   string `json:"severity"`
+	LineFrom   int    `json:"line_from"`
+	ColumnFrom int    `json:"column_from"`
+	Type       string `json:"type"`
+	Message    string `json:"message"`
+	FilePath   string `json:"file_path"`
+}
+
+func parsePhpstanOutput(output []byte, projectDir string) ([]platform.ThirdPartyProblem, error) {
+	var result phpstanOutput
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse PHPStan output: %w", err)
+	}
+	for _, e := range result.Errors {
+		log.Warnf("PHPStan: %s", e)
+	}
+
+	files := make([]string, 0, len(result.Files))
+	for file := range result.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	problems := make([]platform.ThirdPartyProblem, 0)
+	for _, file := range files {
+		for _, m := range result.Files[file].Messages {
+			ruleId := m.Identifier
+			if ruleId == "" {
+				ruleId = "phpstan"
+			}
+			message := m.Message
+			if m.Tip != "" {
+				message += "\n" + m.Tip
+			}
+			problems = append(
+				problems, platform.ThirdPartyProblem{
+					RuleId:  ruleId,
+					Message: message,
+					File:    relativePath(projectDir, file),
+					Line:    m.Line,
+					Level:   "error",
+				},
+			)
+		}
+	}
+	return problems, nil
+}
+
+func parsePsalmOutput(output []byte, projectDir string) ([]platform.ThirdPartyProblem, error) {
+	var issues []psalmIssue
+	if err := json.Unmarshal(output, &issues); err != nil {
+		return nil, fmt.Errorf("failed to parse Psalm output: %w", err)
+	}
+
+	problems := make([]platform.ThirdPartyProblem, 0, len(issues))
+	for _, issue := range issues {
+		level := "warning"
+		if issue.Severity == "error" {
+			level = "error"
+		}
+		problems = append(
+			problems, platform.ThirdPartyProblem{
+				RuleId:  issue.Type,
+				Message: issue.Message,
+				File:    relativePath(projectDir, issue.FilePath),
+				Line:    issue.LineFrom,
+				Column:  issue.ColumnFrom,
+				Level:   level,
+			},
+		)
+	}
+	return problems, nil
+}
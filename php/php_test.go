@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePhpstanOutput(t *testing.T) {
+	output := `{
+  "totals": {"errors": 0, "file_errors": 2},
+  "files": {
+    "/project/src/User.php": {
+      "errors": 2,
+      "messages": [
+        {"message": "Undefined variable: $name", "line": 12, "ignorable": true, "identifier": "variable.undefined"},
+        {"message": "Method User::id() has no return type specified.", "line": 20, "ignorable": true, "tip": "See docs"}
+      ]
+    }
+  },
+  "errors": []
+}`
+	problems, err := parsePhpstanOutput([]byte(output), "/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(
+		t, []platform.ThirdPartyProblem{
+			{
+				RuleId:  "variable.undefined",
+				Message: "Undefined variable: $name",
+				File:    filepath.Join("src", "User.php"),
+				Line:    12,
+				Level:   "error",
+			},
+			{
+				RuleId:  "phpstan",
+				Message: "Method User::id() has no return type specified.\nSee docs",
+				File:    filepath.Join("src", "User.php"),
+				Line:    20,
+				Level:   "error",
+			},
+		}, problems,
+	)
+}
+
+func TestParsePhpstanOutputNoProblems(t *testing.T) {
+	output := `{"totals": {"errors": 0, "file_errors": 0}, "files": [], "errors": []}`
+	problems, err := parsePhpstanOutput([]byte(output), "/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, problems)
+}
+
+func TestParsePsalmOutput(t *testing.T) {
+	output := `[
+  {"severity": "error", "line_from": 3, "column_from": 5, "type": "UndefinedVariable", "message": "Cannot find referenced variable $x", "file_path": "/project/src/a.php", "link": "https://psalm.dev/024"},
+  {"severity": "info", "line_from": 7, "column_from": 1, "type": "MissingReturnType", "message": "Method a has no return type", "file_path": "/project/src/a.php"}
+]`
+	problems, err := parsePsalmOutput([]byte(output), "/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, problems, 2)
+	assert.Equal(t, "UndefinedVariable", problems[0].RuleId)
+	assert.Equal(t, "error", problems[0].Level)
+	assert.Equal(t, 5, problems[0].Column)
+	assert.Equal(t, "warning", problems[1].Level)
+
+	_, err = parsePsalmOutput([]byte("not json"), "/project")
+	assert.Error(t, err)
+}
+
+func TestDetectPhpTool(t *testing.T) {
+	projectDir := t.TempDir()
+	assert.Equal(t, "phpstan", detectPhpTool(projectDir).name)
+
+	if err := os.WriteFile(filepath.Join(projectDir, "psalm.xml"), []byte("<psalm/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "psalm", detectPhpTool(projectDir).name)
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	fexec "github.com/JetBrains/qodana-cli/internal/foundation/exec"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/internal/platform/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+type PhpLinter struct {
+}
+
+// phpTool is a PHP static analyzer supported by the linter.
+type phpTool struct {
+	name      string
+	configs   []string
+	args      []string
+	exitCodes []int // exitCodes are the exit codes of a successful analysis, with or without problems found
+	parse     func(output []byte, projectDir string) ([]platform.ThirdPartyProblem, error)
+}
+
+var phpTools = []phpTool{
+	{
+		name:      "phpstan",
+		configs:   []string{"phpstan.neon", "phpstan.neon.dist", "phpstan.dist.neon"},
+		args:      []string{"analyse", "--error-format=json", "--no-progress", "--no-interaction"},
+		exitCodes: []int{0, 1},
+		parse:     parsePhpstanOutput,
+	},
+	{
+		name:      "psalm",
+		configs:   []string{"psalm.xml", "psalm.xml.dist"},
+		args:      []string{"--output-format=json", "--no-progress"},
+		exitCodes: []int{0, 2},
+		parse:     parsePsalmOutput,
+	},
+}
+
+func (l PhpLinter) RunAnalysis(c thirdpartyscan.Context) error {
+	utils.Bootstrap(c.QodanaYamlConfig().BootstrapSteps(), c.ProjectDir(), c.LogDir())
+
+	tool := detectPhpTool(c.ProjectDir())
+	binary, err := findPhpToolBinary(c.ProjectDir(), tool.name)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Running %s %v", binary, tool.args)
+	stdout, stderr, ret, err := fexec.ExecRedirectOutput(c.ProjectDir(), binary, tool.args...)
+	if err != nil {
+		return err
+	}
+	if stderr != "" {
+		log.Debug(stderr)
+	}
+	if err = os.WriteFile(filepath.Join(c.LogDir(), tool.name+".json"), []byte(stdout), 0o644); err != nil {
+		log.Warnf("Failed to save %s output: %s", tool.name, err)
+	}
+	if !slices.Contains(tool.exitCodes, ret) {
+		return fmt.Errorf("%s exited with code: %d\n%s", tool.name, ret, stderr)
+	}
+
+	problems, err := tool.parse([]byte(stdout), c.ProjectDir())
+	if err != nil {
+		return err
+	}
+	report := platform.ConvertToSarif(tool.name, "", problems)
+
+	tmpResultsDir := platform.GetTmpResultsDir(c.ResultsDir())
+	if err = os.MkdirAll(tmpResultsDir, os.ModePerm); err != nil {
+		return err
+	}
+	if err = platform.WriteReport(filepath.Join(tmpResultsDir, tool.name+".sarif.json"), report); err != nil {
+		return err
+	}
+	_, err = platform.MergeSarifReports(c, platform.GetDeviceIdSalt()[0])
+	return err
+}
+
+// MountTools does nothing: PHPStan and Psalm are used from the project dependencies or PATH.
+func (l PhpLinter) MountTools(_ string) (map[string]string, error) {
+	return nil, nil
+}
+
+// detectPhpTool returns the tool configured in the project, PHPStan is used by default.
+func detectPhpTool(projectDir string) phpTool {
+	for _, tool := range phpTools {
+		for _, config := range tool.configs {
+			if _, err := os.Stat(filepath.Join(projectDir, config)); err == nil {
+				return tool
+			}
+		}
+	}
+	return phpTools[0]
+}
+
+// findPhpToolBinary looks the tool up in vendor/bin of the project first, then in PATH.
+func findPhpToolBinary(projectDir string, name string) (string, error) {
+	vendorBinary := filepath.Join(projectDir, "vendor", "bin", name)
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		vendorBinary += ".bat"
+	}
+	if _, err := os.Stat(vendorBinary); err == nil {
+		return vendorBinary, nil
+	}
+	binary, err := exec.LookPath(name)
+	if err != nil {
+		return "", errors.Join(
+			fmt.Errorf("%s is not found in vendor/bin or PATH, install it with 'composer require --dev' first", name),
+			err,
+		)
+	}
+	return binary, nil
+}
+
+// relativePath returns the path relative to the project directory, when possible.
+func relativePath(projectDir string, path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	if rel, err := filepath.Rel(projectDir, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qodana
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+)
+
+// Problem is a problem reported by another tool, converted to a result of the SARIF report by Convert.
+type Problem struct {
+	RuleId          string
+	RuleDescription string
+	// Category is the taxon of the rule in the Qodana taxonomy, optional.
+	Category string
+	Message  string
+	// File is either absolute or relative to the project directory.
+	File   string
+	Line   int
+	Column int
+	// Level is one of SARIF levels: error, warning or note.
+	Level string
+}
+
+// Convert writes the problems reported by the tool as a Qodana SARIF report, it can be uploaded to Qodana Cloud
+// or merged with the reports of qodana scan.
+func Convert(toolName string, toolVersion string, problems []Problem, w io.Writer) error {
+	return serialized(
+		func() error {
+			thirdPartyProblems := make([]platform.ThirdPartyProblem, len(problems))
+			for i, p := range problems {
+				thirdPartyProblems[i] = platform.ThirdPartyProblem(p)
+			}
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(platform.ConvertToSarif(toolName, toolVersion, thirdPartyProblems))
+		},
+	)
+}
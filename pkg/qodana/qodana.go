@@ -0,0 +1,38 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qodana runs Qodana from Go programs without the qodana binary: RunScan analyzes a project like
+// qodana scan, Convert writes the problems of another tool as a Qodana SARIF report and Upload sends the results
+// of an analysis to Qodana Cloud like qodana upload.
+//
+// The functions return the failures of the analysis as errors. They share the process state of the CLI (the
+// environment and the working directories), so the calls are serialized: a call waits for the one in progress to
+// finish.
+package qodana
+
+import (
+	"sync"
+)
+
+// calls serializes the calls of the package, they change the process state of the CLI.
+var calls sync.Mutex
+
+// serialized runs the function once no other call of the package is in progress.
+func serialized(run func() error) error {
+	calls.Lock()
+	defer calls.Unlock()
+	return run()
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qodana
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunScanInvalidOptions(t *testing.T) {
+	t.Setenv(qdenv.QodanaToken, "")
+
+	_, err := RunScan(context.Background(), ScanOptions{ProjectDir: t.TempDir(), Args: []string{"--fail-on=severe:1"}})
+	assert.ErrorContains(t, err, "invalid --fail-on value")
+
+	_, err = RunScan(
+		context.Background(),
+		ScanOptions{ProjectDir: t.TempDir(), Args: []string{"--fail-on-license=" + filepath.Join(t.TempDir(), "none.yaml")}},
+	)
+	assert.ErrorContains(t, err, "invalid --fail-on-license file")
+}
+
+func TestConvert(t *testing.T) {
+	var out bytes.Buffer
+	err := Convert(
+		"lint",
+		"1.0",
+		[]Problem{{RuleId: "unused", Message: "x is unused", File: "main.go", Line: 3, Column: 2, Level: "warning"}},
+		&out,
+	)
+	assert.NoError(t, err)
+
+	report, err := platform.ReadReportFromString(out.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "lint", report.Runs[0].Tool.Driver.Name)
+	assert.Len(t, report.Runs[0].Results, 1)
+	assert.Equal(t, "unused", report.Runs[0].Results[0].RuleId)
+}
+
+func TestScanCliOptions(t *testing.T) {
+	options, err := scanCliOptions(
+		ScanOptions{
+			ProjectDir: "/project",
+			Linter:     "qodana-jvm-community",
+			Token:      "token",
+			Args:       []string{"--fail-threshold", "10"},
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "/project", options.ProjectDir)
+	assert.Equal(t, "qodana-jvm-community", options.Linter)
+	assert.Equal(t, "10", options.FailThreshold)
+	assert.Contains(t, options.Env(), qdenv.QodanaToken+"=token")
+
+	_, err = scanCliOptions(ScanOptions{Args: []string{"--no-such-option"}})
+	assert.Error(t, err)
+}
+
+func TestUploadWithoutReport(t *testing.T) {
+	_, err := Upload(context.Background(), UploadOptions{ResultsDir: filepath.Join(t.TempDir(), "results")})
+	assert.ErrorContains(t, err, "no report found")
+}
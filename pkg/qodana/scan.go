@@ -0,0 +1,192 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qodana
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/core"
+	"github.com/JetBrains/qodana-cli/internal/core/exitcodes"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	platformcmd "github.com/JetBrains/qodana-cli/internal/platform/cmd"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdyaml"
+	"github.com/spf13/cobra"
+)
+
+// ScanOptions are the options of RunScan, the empty ones have the defaults of qodana scan.
+type ScanOptions struct {
+	// ProjectDir is the root directory of the project to analyze, the current directory by default.
+	ProjectDir string
+	// Linter is the linter to run, e.g. "qodana-jvm-community". It's detected from the project files if empty.
+	Linter     string
+	ResultsDir string
+	CacheDir   string
+	// Token is the Qodana Cloud project token, QODANA_TOKEN by default.
+	Token string
+	// Args are other options of qodana scan in the command line form, e.g. []string{"--within-docker=false"}.
+	Args []string
+}
+
+// Result is the outcome of an analysis run by RunScan.
+type Result struct {
+	// ExitCode is the exit code qodana scan would exit with, e.g. 255 if the quality gates failed.
+	ExitCode   int
+	ResultsDir string
+	SarifPath  string
+	// ReportUrl is the report on Qodana Cloud, empty if the report wasn't uploaded.
+	ReportUrl string
+	// Problems is the number of the problems by severity: critical, high, moderate, low and info.
+	Problems    map[string]int
+	NewProblems map[string]int
+}
+
+// RunScan analyzes the project like qodana scan. A finished analysis returns its Result even if the quality gates
+// failed, an error is returned if the analysis couldn't be run. Cancelling the context stops the linter, the partial
+// results are kept and the context error is returned.
+func RunScan(ctx context.Context, opts ScanOptions) (Result, error) {
+	var result Result
+	err := serialized(
+		func() error {
+			var err error
+			result, err = runScan(ctx, opts)
+			return err
+		},
+	)
+	return result, err
+}
+
+func runScan(ctx context.Context, opts ScanOptions) (Result, error) {
+	cliOptions, err := scanCliOptions(opts)
+	if err != nil {
+		return Result{}, err
+	}
+	qdenv.InitializeQodanaGlobalEnv(cliOptions)
+	if _, err = qdyaml.ParseSeverityThresholds(cliOptions.FailOn); err != nil {
+		return Result{}, fmt.Errorf("invalid --fail-on value: %w", err)
+	}
+	if cliOptions.FailOnLicense != "" {
+		if _, err = platform.LoadLicenseDenylist(cliOptions.FailOnLicense); err != nil {
+			return Result{}, fmt.Errorf("invalid --fail-on-license file: %w", err)
+		}
+	}
+	if err = platform.SetupOfflineMode(*cliOptions); err != nil {
+		return Result{}, err
+	}
+	platform.SetupDownloadVerification(*cliOptions)
+	if err = platform.SetupRegistryCredentials(
+		cliOptions.Registry,
+		cliOptions.Image,
+		cliOptions.RegistryUsername,
+		cliOptions.RegistryPasswordStdin,
+		os.Stdin,
+	); err != nil {
+		return Result{}, err
+	}
+
+	commonCtx := commoncontext.Compute(
+		cliOptions.Linter,
+		cliOptions.Ide,
+		cliOptions.Image,
+		cliOptions.WithinDocker,
+		cliOptions.CacheDir,
+		cliOptions.ResultsDir,
+		cliOptions.ReportDir,
+		qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken),
+		cliOptions.ClearCache,
+		cliOptions.ProjectDir,
+		cliOptions.RepositoryRoot,
+		cliOptions.ConfigName,
+	)
+	if commonCtx, err = commoncontext.LockRunDirs(commonCtx, cliOptions.ConcurrentRun); err != nil {
+		return Result{}, err
+	}
+	defer commoncontext.ReleaseRunLocks()
+	if commonCtx, err = platform.StartHistoryRun(commonCtx, cliOptions.KeepRuns); err != nil {
+		return Result{}, err
+	}
+
+	scanContext, cleanup, err := core.PrepareScan(*cliOptions, commonCtx)
+	defer cleanup()
+	if err != nil {
+		return Result{}, err
+	}
+	if err = ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	sarifPath := platform.GetSarifPath(scanContext.ResultsDir())
+
+	exitCode, err := core.RunAnalysis(ctx, scanContext)
+	if ctx.Err() != nil {
+		core.FinishInterruptedAnalysis(scanContext)
+		return Result{
+			ExitCode:   exitcodes.QodanaInterruptedExitCode,
+			ResultsDir: scanContext.ResultsDir(),
+			SarifPath:  sarifPath,
+		}, ctx.Err()
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	platform.ApplySecurityTaxonomies(sarifPath)
+	exitCode = platform.ApplyFailOn(sarifPath, scanContext.FailOn(), exitCode)
+	exitCode = platform.ApplyLicenseGate(scanContext.ResultsDir(), sarifPath, cliOptions.FailOnLicense, exitCode)
+
+	result := Result{
+		ExitCode:   exitCode,
+		ResultsDir: scanContext.ResultsDir(),
+		SarifPath:  sarifPath,
+		ReportUrl:  cloud.GetReportUrl(scanContext.ResultsDir()),
+	}
+	result.Problems, result.NewProblems, err = platform.CountReportProblems(sarifPath)
+	if err != nil {
+		return result, fmt.Errorf("the analysis finished with exit code %d without a report: %w", exitCode, err)
+	}
+	return result, nil
+}
+
+// scanCliOptions returns the options of qodana scan with the defaults of its flags.
+func scanCliOptions(opts ScanOptions) (*platformcmd.CliOptions, error) {
+	cliOptions := &platformcmd.CliOptions{}
+	cmd := &cobra.Command{Use: "scan"}
+	if err := platformcmd.ComputeFlags(cmd, cliOptions); err != nil {
+		return nil, err
+	}
+	if err := cmd.Flags().Parse(opts.Args); err != nil {
+		return nil, fmt.Errorf("invalid scan options %q: %w", opts.Args, err)
+	}
+	if opts.ProjectDir != "" {
+		cliOptions.ProjectDir = opts.ProjectDir
+	}
+	if opts.Linter != "" {
+		cliOptions.Linter = opts.Linter
+	}
+	if opts.ResultsDir != "" {
+		cliOptions.ResultsDir = opts.ResultsDir
+	}
+	if opts.CacheDir != "" {
+		cliOptions.CacheDir = opts.CacheDir
+	}
+	if opts.Token != "" {
+		cliOptions.Env_ = append(cliOptions.Env_, qdenv.QodanaToken+"="+opts.Token)
+	}
+	return cliOptions, nil
+}
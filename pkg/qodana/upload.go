@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qodana
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JetBrains/qodana-cli/internal/cloud"
+	"github.com/JetBrains/qodana-cli/internal/platform"
+	"github.com/JetBrains/qodana-cli/internal/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/internal/platform/qdenv"
+	"github.com/google/uuid"
+)
+
+// UploadOptions are the options of Upload.
+type UploadOptions struct {
+	// ResultsDir is the results directory of a finished analysis, with its SARIF report.
+	ResultsDir string
+	// Token is the Qodana Cloud project token, QODANA_TOKEN by default.
+	Token string
+	// AnalysisId identifies the report on Qodana Cloud, a new one by default.
+	AnalysisId string
+	// CacheDir is the directory the upload tools are extracted to, <userCacheDir>/JetBrains/Qodana/upload by default.
+	CacheDir string
+	// Retries is the number of retries of a failed upload, the backoff before a retry is doubled after each attempt.
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// Upload sends the results of an analysis to Qodana Cloud like qodana upload, it returns the URL of the report.
+func Upload(ctx context.Context, opts UploadOptions) (string, error) {
+	var reportUrl string
+	err := serialized(
+		func() error {
+			var err error
+			reportUrl, err = upload(ctx, opts)
+			return err
+		},
+	)
+	return reportUrl, err
+}
+
+func upload(ctx context.Context, opts UploadOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	resultsDir, err := filepath.Abs(opts.ResultsDir)
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(platform.GetSarifPath(resultsDir)); err != nil {
+		return "", fmt.Errorf("no report found in %s: %w", resultsDir, err)
+	}
+	qdenv.InitializeQodanaGlobalEnv(qdenv.EmptyEnvProvider())
+	token := opts.Token
+	if token == "" {
+		token = qdenv.GetQodanaGlobalEnv(qdenv.QodanaToken)
+	}
+	if token == "" {
+		return "", fmt.Errorf("%s is required to upload the report to Qodana Cloud", qdenv.QodanaToken)
+	}
+	analysisId := opts.AnalysisId
+	if analysisId == "" {
+		analysisId = uuid.New().String()
+	}
+	cacheDir := opts.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(commoncontext.ComputeQodanaSystemDir(""), "upload")
+	}
+	if err = os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create the cache directory %s: %w", cacheDir, err)
+	}
+	publisher := platform.Publisher{
+		ResultsDir: resultsDir,
+		LogDir:     filepath.Join(resultsDir, "log"),
+		AnalysisId: analysisId,
+	}
+	if err = os.MkdirAll(publisher.LogDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create the log directory %s: %w", publisher.LogDir, err)
+	}
+
+	res, err := platform.UploadReportWithRetries(cacheDir, publisher, token, opts.Retries, opts.RetryBackoff)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload the report from %s: %w", resultsDir, err)
+	}
+	if res != 0 {
+		return "", fmt.Errorf("failed to upload the report from %s: publisher exited with code %d", resultsDir, res)
+	}
+	return cloud.GetReportUrl(resultsDir), nil
+}